@@ -9,12 +9,13 @@
 	"time"
 
 	"github.com/alecthomas/kingpin/v2"
+	atunits "github.com/alecthomas/units"
 	"github.com/fatih/color"
-	"github.com/mattn/go-colorable"
 	"github.com/pkg/errors"
 	"go.opentelemetry.io/otel"
 	"go.opentelemetry.io/otel/trace"
 
+	"github.com/kopia/kopia/fs"
 	"github.com/kopia/kopia/internal/apiclient"
 	"github.com/kopia/kopia/internal/gather"
 	"github.com/kopia/kopia/internal/passwordpersist"
@@ -88,14 +89,21 @@ type appServices interface {
 	repositoryConfigFileName() string
 	getProgress() *cliProgress
 	getRestoreProgress() restore.Progress
+	snapshotSourceHints() []string
+	policyTargetHints() []string
 
 	stdout() io.Writer
+	jsonStdout() io.Writer
 	Stderr() io.Writer
 	stdin() io.Reader
 	onTerminate(callback func())
 	onRepositoryFatalError(callback func(err error))
 	enableTestOnlyFlags() bool
 	EnvName(s string) string
+	getEnv(n string) string
+	getPrefixedEnv(n string) string
+	askPass(prompt string) (string, error)
+	wrapSnapshotSource(path string, e fs.Entry) fs.Entry
 }
 
 //nolint:interfacebloat
@@ -110,6 +118,7 @@ type advancedAppServices interface {
 	removeUpdateState()
 	passwordPersistenceStrategy() passwordpersist.Strategy
 	getPasswordFromFlags(ctx context.Context, isCreate, allowPersistent bool) (string, error)
+	askForChangedRepositoryPassword() (string, error)
 	optionsFromFlags(ctx context.Context) *repo.Options
 	runAppWithContext(command *kingpin.CmdClause, callback func(ctx context.Context) error) error
 }
@@ -125,12 +134,22 @@ type App struct {
 	updateCheckInterval           time.Duration
 	updateAvailableNotifyInterval time.Duration
 	password                      string
+	passwordFile                  string
+	passwordCommand               string
 	configPath                    string
 	traceStorage                  bool
+	traceStorageSampleEvery       int
+	traceStorageSlowThreshold     time.Duration
+	traceStorageRedactBlobIDs     bool
+	verifyBlobChecksums           bool
+	blobReadAhead                 atunits.Base2Bytes
+	storageCircuitBreakerCoolDown time.Duration
+	maxIndexMemory                atunits.Base2Bytes
 	keyRingEnabled                bool
 	persistCredentials            bool
 	disableInternalLog            bool
 	dumpAllocatorStats            bool
+	commandTimeout                time.Duration
 	AdvancedCommands              string
 	cliStorageProviders           []StorageProvider
 	trackReleasable               []string
@@ -141,12 +160,15 @@ type App struct {
 
 	currentAction         string
 	onExitCallbacks       []func()
+	logSpanIDs            bool
 	onFatalErrorCallbacks []func(err error)
 
 	// subcommands
 	blob        commandBlob
 	benchmark   commandBenchmark
+	debug       commandDebug
 	cache       commandCache
+	completion  commandCompletion
 	content     commandContent
 	diff        commandDiff
 	index       commandIndex
@@ -167,13 +189,8 @@ type App struct {
 	testonlyIgnoreMissingRequiredFeatures bool
 
 	isInProcessTest bool
-	exitWithError   func(err error) // os.Exit() with 1 or 0 based on err
-	stdinReader     io.Reader
-	stdoutWriter    io.Writer
-	stderrWriter    io.Writer
-	rootctx         context.Context //nolint:containedctx
+	inv             *invocation // output streams and hooks of the command being executed
 	loggerFactory   logging.LoggerFactory
-	simulatedCtrlC  chan bool
 	envNamePrefix   string
 }
 
@@ -195,16 +212,31 @@ func (c *App) getRestoreProgress() restore.Progress {
 }
 
 func (c *App) stdin() io.Reader {
-	return c.stdinReader
+	return c.inv.stdin
 }
 
 func (c *App) stdout() io.Writer {
-	return c.stdoutWriter
+	return c.inv.stdout
+}
+
+// jsonStdout returns the writer for machine-readable output of commands supporting --json.
+func (c *App) jsonStdout() io.Writer {
+	if c.inv.jsonOutput != nil {
+		return c.inv.jsonOutput
+	}
+
+	return c.inv.stdout
 }
 
 // Stderr returns the stderr writer.
 func (c *App) Stderr() io.Writer {
-	return c.stderrWriter
+	return c.inv.stderr
+}
+
+// JSONLProgressOnStderr returns true if the command writes JSON-lines progress events to standard error,
+// in which case console logs must be written as JSON as well.
+func (c *App) JSONLProgressOnStderr() bool {
+	return c.progress.jsonlOnStderr()
 }
 
 // SetLoggerFactory sets the logger factory to be used throughout the app.
@@ -212,6 +244,11 @@ func (c *App) SetLoggerFactory(loggerForModule logging.LoggerFactory) {
 	c.loggerFactory = loggerForModule
 }
 
+// SetLogSpanIDs enables including IDs of trace spans in log entries.
+func (c *App) SetLogSpanIDs(enabled bool) {
+	c.logSpanIDs = enabled
+}
+
 // RegisterOnExit registers the provided function to run before app exits.
 func (c *App) RegisterOnExit(f func()) {
 	c.onExitCallbacks = append(c.onExitCallbacks, f)
@@ -252,7 +289,7 @@ func (c *App) setup(app *kingpin.Application) {
 
 	_ = app.Flag("help-full", "Show help for all commands, including hidden").Action(func(pc *kingpin.ParseContext) error {
 		_ = app.UsageForContextWithTemplate(pc, 0, kingpin.DefaultUsageTemplate)
-		c.exitWithError(nil)
+		c.inv.exitWithError(nil)
 		return nil
 	}).Bool()
 
@@ -264,12 +301,22 @@ func (c *App) setup(app *kingpin.Application) {
 	app.Flag("update-available-notify-interval", "Interval between update notifications").Default("1h").Hidden().Envar(c.EnvName("KOPIA_UPDATE_NOTIFY_INTERVAL")).DurationVar(&c.updateAvailableNotifyInterval)
 	app.Flag("config-file", "Specify the config file to use").Default("repository.config").Envar(c.EnvName("KOPIA_CONFIG_PATH")).StringVar(&c.configPath)
 	app.Flag("trace-storage", "Enables tracing of storage operations.").Default("true").Hidden().BoolVar(&c.traceStorage)
+	app.Flag("trace-storage-sample-every", "Trace only one in every N successful calls of each storage operation.").Hidden().Envar(c.EnvName("KOPIA_TRACE_STORAGE_SAMPLE_EVERY")).IntVar(&c.traceStorageSampleEvery)
+	app.Flag("trace-storage-slow-threshold", "Trace only successful storage operations taking at least the provided duration.").Hidden().Envar(c.EnvName("KOPIA_TRACE_STORAGE_SLOW_THRESHOLD")).DurationVar(&c.traceStorageSlowThreshold)
+	app.Flag("trace-storage-redact-blob-ids", "Trace only the leading characters of blob IDs.").Hidden().Envar(c.EnvName("KOPIA_TRACE_STORAGE_REDACT_BLOB_IDS")).BoolVar(&c.traceStorageRedactBlobIDs)
+	app.Flag("verify-blob-checksums", "Record checksums of written blobs and verify them when blobs are read.").Hidden().Envar(c.EnvName("KOPIA_VERIFY_BLOB_CHECKSUMS")).BoolVar(&c.verifyBlobChecksums)
+	app.Flag("blob-read-ahead", "Amount of data prefetched into the content cache when sequential reads of the same pack blob are detected (e.g. 4MiB), 0 disables reading ahead.").PlaceHolder("BYTES").Hidden().Envar(c.EnvName("KOPIA_BLOB_READ_AHEAD")).BytesVar(&c.blobReadAhead)
+	app.Flag("storage-circuit-breaker-cool-down", "When most recent storage calls of the same kind keep failing, fail them immediately for the provided duration, 0 disables.").PlaceHolder("DURATION").Envar(c.EnvName("KOPIA_STORAGE_CIRCUIT_BREAKER_COOL_DOWN")).DurationVar(&c.storageCircuitBreakerCoolDown)
+	app.Flag("max-index-memory", "When caching is disabled, memory-map index blobs from temporary files once their total size exceeds the provided limit (e.g. 1GiB).").PlaceHolder("BYTES").Hidden().Envar(c.EnvName("KOPIA_MAX_INDEX_MEMORY")).BytesVar(&c.maxIndexMemory)
 	app.Flag("timezone", "Format time according to specified time zone (local, utc, original or time zone name)").Hidden().StringVar(&timeZone)
 	app.Flag("password", "Repository password.").Envar(c.EnvName("KOPIA_PASSWORD")).Short('p').StringVar(&c.password)
+	app.Flag("password-file", "Read repository password from the provided file.").Envar(c.EnvName("KOPIA_PASSWORD_FILE")).StringVar(&c.passwordFile)
+	app.Flag("password-command", "Read repository password from the output of the provided shell command.").Envar(c.EnvName("KOPIA_PASSWORD_COMMAND")).StringVar(&c.passwordCommand)
 	app.Flag("persist-credentials", "Persist credentials").Default("true").Envar(c.EnvName("KOPIA_PERSIST_CREDENTIALS_ON_CONNECT")).BoolVar(&c.persistCredentials)
 	app.Flag("disable-internal-log", "Disable internal log").Hidden().Envar(c.EnvName("KOPIA_DISABLE_INTERNAL_LOG")).BoolVar(&c.disableInternalLog)
 	app.Flag("advanced-commands", "Enable advanced (and potentially dangerous) commands.").Hidden().Envar(c.EnvName("KOPIA_ADVANCED_COMMANDS")).StringVar(&c.AdvancedCommands)
 	app.Flag("track-releasable", "Enable tracking of releasable resources.").Hidden().Envar(c.EnvName("KOPIA_TRACK_RELEASABLE")).StringsVar(&c.trackReleasable)
+	app.Flag("timeout", "Cancel the command if it does not complete within the provided duration.").Envar(c.EnvName("KOPIA_TIMEOUT")).DurationVar(&c.commandTimeout)
 	app.Flag("dump-allocator-stats", "Dump allocator stats at the end of execution.").Hidden().Envar(c.EnvName("KOPIA_DUMP_ALLOCATOR_STATS")).BoolVar(&c.dumpAllocatorStats)
 	app.Flag("upgrade-owner-id", "Repository format upgrade owner-id.").Hidden().Envar(c.EnvName("KOPIA_REPO_UPGRADE_OWNER_ID")).StringVar(&c.upgradeOwnerID)
 	app.Flag("upgrade-no-block", "Do not block when repository format upgrade is in progress, instead exit with a message.").Hidden().Default("false").Envar(c.EnvName("KOPIA_REPO_UPGRADE_NO_BLOCK")).BoolVar(&c.doNotWaitForUpgrade)
@@ -283,14 +330,14 @@ func (c *App) setup(app *kingpin.Application) {
 	c.setupOSSpecificKeychainFlags(c, app)
 
 	_ = app.Flag("caching", "Enables caching of objects (disable with --no-caching)").Default("true").Hidden().Action(
-		deprecatedFlag(c.stderrWriter, "The '--caching' flag is deprecated and has no effect, use 'kopia cache set' instead."),
+		deprecatedFlag(c.inv.stderr, "The '--caching' flag is deprecated and has no effect, use 'kopia cache set' instead."),
 	).Bool()
 
 	_ = app.Flag("list-caching", "Enables caching of list results (disable with --no-list-caching)").Default("true").Hidden().Action(
-		deprecatedFlag(c.stderrWriter, "The '--list-caching' flag is deprecated and has no effect, use 'kopia cache set' instead."),
+		deprecatedFlag(c.inv.stderr, "The '--list-caching' flag is deprecated and has no effect, use 'kopia cache set' instead."),
 	).Bool()
 
-	c.pf.setup(app)
+	c.pf.setup(c, app)
 	c.progress.setup(c, app)
 
 	if rp, ok := c.restoreProgress.(*cliRestoreProgress); ok {
@@ -300,7 +347,9 @@ func (c *App) setup(app *kingpin.Application) {
 	c.blob.setup(c, app)
 	c.benchmark.setup(c, app)
 	c.cache.setup(c, app)
+	c.completion.setup(c, app)
 	c.content.setup(c, app)
+	c.debug.setup(c, app)
 	c.diff.setup(c, app)
 	c.index.setup(c, app)
 	c.list.setup(c, app)
@@ -335,25 +384,16 @@ func NewApp() *App {
 			{"filesystem", "a filesystem", func() StorageFlags { return &storageFilesystemFlags{} }},
 			{"gcs", "a Google Cloud Storage bucket", func() StorageFlags { return &storageGCSFlags{} }},
 			{"gdrive", "a Google Drive folder", func() StorageFlags { return &storageGDriveFlags{} }},
+			{"mirror", "a pair of mirrored storages", func() StorageFlags { return &storageMirrorFlags{} }},
 
 			{"rclone", "a rclone-based provided", func() StorageFlags { return &storageRcloneFlags{} }},
 			{"s3", "an S3 bucket", func() StorageFlags { return &storageS3Flags{} }},
 			{"sftp", "an SFTP storage", func() StorageFlags { return &storageSFTPFlags{} }},
+			{"sqlite", "a SQLite database file", func() StorageFlags { return &storageSQLiteFlags{} }},
 			{"webdav", "a WebDAV storage", func() StorageFlags { return &storageWebDAVFlags{} }},
 		},
 
-		// testability hooks
-		exitWithError: func(err error) {
-			if err != nil {
-				os.Exit(1)
-			}
-
-			os.Exit(0)
-		},
-		stdoutWriter: colorable.NewColorableStdout(),
-		stderrWriter: colorable.NewColorableStderr(),
-		stdinReader:  os.Stdin,
-		rootctx:      context.Background(),
+		inv: processInvocation(),
 	}
 }
 
@@ -370,6 +410,7 @@ func (c *App) EnvName(n string) string {
 // Attach attaches the CLI parser to the application.
 func (c *App) Attach(app *kingpin.Application) {
 	c.setup(app)
+	c.applyFlagDefaults(app)
 }
 
 // safetyFlagVar defines c --safety=none|full flag that sets the SafetyParameters.
@@ -402,7 +443,7 @@ func (c *App) currentActionName() string {
 func (c *App) noRepositoryAction(act func(ctx context.Context) error) func(ctx *kingpin.ParseContext) error {
 	return func(kpc *kingpin.ParseContext) error {
 		return c.runAppWithContext(kpc.SelectedCommand, func(ctx context.Context) error {
-			return c.pf.withProfiling(func() error {
+			return c.pf.withProfiling(ctx, c.onProfileDumpRequest, func() error {
 				if c.dumpAllocatorStats {
 					defer gather.DumpStats(ctx)
 				}
@@ -450,10 +491,16 @@ func assertDirectRepository(act func(ctx context.Context, rep repo.DirectReposit
 
 func (c *App) directRepositoryWriteAction(act func(ctx context.Context, rep repo.DirectRepositoryWriter) error) func(ctx *kingpin.ParseContext) error {
 	return c.maybeRepositoryAction(assertDirectRepository(func(ctx context.Context, rep repo.DirectRepository) error {
-		return repo.DirectWriteSession(ctx, rep, repo.WriteSessionOptions{
+		// the session is flushed and closed even after --timeout, which only applies to the command itself.
+		return repo.DirectWriteSession(context.WithoutCancel(ctx), rep, repo.WriteSessionOptions{
 			Purpose:  "cli:" + c.currentActionName(),
 			OnUpload: c.progress.UploadedBytes,
-		}, func(ctx context.Context, dw repo.DirectRepositoryWriter) error { return act(ctx, dw) })
+		}, func(wctx context.Context, dw repo.DirectRepositoryWriter) error {
+			wctx, cancel := withCancelOf(wctx, ctx)
+			defer cancel()
+
+			return act(wctx, dw)
+		})
 	}), repositoryAccessMode{
 		mustBeConnected:    true,
 		disableMaintenance: true,
@@ -480,11 +527,15 @@ func (c *App) repositoryReaderAction(act func(ctx context.Context, rep repo.Repo
 
 func (c *App) repositoryWriterAction(act func(ctx context.Context, rep repo.RepositoryWriter) error) func(ctx *kingpin.ParseContext) error {
 	return c.maybeRepositoryAction(func(ctx context.Context, rep repo.Repository) error {
-		return repo.WriteSession(ctx, rep, repo.WriteSessionOptions{
+		// the session is flushed and closed even after --timeout, which only applies to the command itself.
+		return repo.WriteSession(context.WithoutCancel(ctx), rep, repo.WriteSessionOptions{
 			Purpose:  "cli:" + c.currentActionName(),
 			OnUpload: c.progress.UploadedBytes,
-		}, func(ctx context.Context, w repo.RepositoryWriter) error {
-			return act(ctx, w)
+		}, func(wctx context.Context, w repo.RepositoryWriter) error {
+			wctx, cancel := withCancelOf(wctx, ctx)
+			defer cancel()
+
+			return act(wctx, w)
 		})
 	}, repositoryAccessMode{
 		mustBeConnected: true,
@@ -492,16 +543,26 @@ func (c *App) repositoryWriterAction(act func(ctx context.Context, rep repo.Repo
 }
 
 func (c *App) runAppWithContext(command *kingpin.CmdClause, cb func(ctx context.Context) error) error {
-	ctx := c.rootctx
+	ctx := c.inv.ctx
 
 	if c.loggerFactory != nil {
 		ctx = logging.WithLogger(ctx, c.loggerFactory)
 	}
 
+	if c.logSpanIDs {
+		ctx = logging.WithSpanIDs(ctx)
+	}
+
 	for _, r := range c.trackReleasable {
 		releasable.EnableTracking(releasable.ItemKind(r))
 	}
 
+	if err := c.progress.openProgressOutput(); err != nil {
+		return err
+	}
+
+	defer c.progress.closeProgressOutput()
+
 	if err := c.observability.startMetrics(ctx); err != nil {
 		return errors.Wrap(err, "unable to start metrics")
 	}
@@ -511,7 +572,11 @@ func (c *App) runAppWithContext(command *kingpin.CmdClause, cb func(ctx context.
 		defer span.End()
 		defer c.runOnExit()
 
-		return cb(tctx)
+		if c.commandTimeout <= 0 {
+			return cb(tctx)
+		}
+
+		return c.runWithTimeout(tctx, cb)
 	}()
 
 	c.observability.stopMetrics(ctx)
@@ -519,19 +584,67 @@ func (c *App) runAppWithContext(command *kingpin.CmdClause, cb func(ctx context.
 	if err != nil {
 		// print error in red
 		log(ctx).Errorf("%v", err.Error())
-		c.exitWithError(err)
+		c.inv.exitWithError(err)
 	}
 
 	if len(c.trackReleasable) > 0 {
 		if err := releasable.Verify(); err != nil {
 			log(ctx).Warnf("%v", err.Error())
-			c.exitWithError(err)
+			c.inv.exitWithError(err)
 		}
 	}
 
 	return nil
 }
 
+// runWithTimeout runs the provided callback with a context that is canceled after --timeout elapses.
+// When that happens, termination handlers are also invoked, so that commands such as 'snapshot create'
+// can stop gracefully as if interrupted. Repository actions close sessions and the repository using
+// a context which is not canceled, see withCancelOf().
+func (c *App) runWithTimeout(ctx context.Context, cb func(ctx context.Context) error) error {
+	ctx, cancel := context.WithTimeout(ctx, c.commandTimeout)
+	defer cancel()
+
+	timedOut := make(chan struct{})
+	c.inv.commandTimedOut = timedOut
+
+	stop := context.AfterFunc(ctx, func() {
+		if errors.Is(ctx.Err(), context.DeadlineExceeded) {
+			log(ctx).Warnf("Command did not complete within %v, canceling.", c.commandTimeout)
+			close(timedOut)
+		}
+	})
+	defer stop()
+
+	err := cb(ctx)
+
+	if !errors.Is(ctx.Err(), context.DeadlineExceeded) {
+		return err
+	}
+
+	if err == nil {
+		err = errors.Errorf("command timed out after %v", c.commandTimeout)
+	} else {
+		err = errors.Wrapf(err, "command timed out after %v", c.commandTimeout)
+	}
+
+	return withExitCode(ExitCodeTimeout, err)
+}
+
+// withCancelOf returns a context carrying values of ctx, which is canceled when cancelCtx is.
+// This allows a callback to be subject to --timeout, while cleanup performed by its caller is not.
+func withCancelOf(ctx, cancelCtx context.Context) (context.Context, context.CancelFunc) {
+	ctx, cancel := context.WithCancelCause(ctx)
+	stop := context.AfterFunc(cancelCtx, func() {
+		cancel(context.Cause(cancelCtx))
+	})
+
+	return ctx, func() {
+		stop()
+		cancel(nil)
+	}
+}
+
 type repositoryAccessMode struct {
 	mustBeConnected    bool
 	disableMaintenance bool
@@ -540,7 +653,7 @@ type repositoryAccessMode struct {
 func (c *App) baseActionWithContext(act func(ctx context.Context) error) func(ctx *kingpin.ParseContext) error {
 	return func(kpc *kingpin.ParseContext) error {
 		return c.runAppWithContext(kpc.SelectedCommand, func(ctx context.Context) error {
-			return c.pf.withProfiling(func() error {
+			return c.pf.withProfiling(ctx, c.onProfileDumpRequest, func() error {
 				if c.dumpAllocatorStats {
 					defer gather.DumpStats(ctx)
 				}
@@ -560,14 +673,21 @@ func (c *App) maybeRepositoryAction(act func(ctx context.Context, rep repo.Repos
 
 		err = act(ctx, rep)
 
-		if rep != nil && !mode.disableMaintenance {
+		// do not start maintenance after the command has been canceled due to --timeout.
+		if rep != nil && !mode.disableMaintenance && ctx.Err() == nil {
 			if merr := c.maybeRunMaintenance(ctx, rep); merr != nil {
 				log(ctx).Errorf("error running maintenance: %v", merr)
 			}
 		}
 
-		if rep != nil && mode.mustBeConnected {
-			if cerr := rep.Close(ctx); cerr != nil {
+		if rep != nil {
+			// the repository is closed even after --timeout has elapsed.
+			cerr := rep.Close(context.WithoutCancel(ctx))
+
+			// metrics are captured after closing, so they include data flushed by Close().
+			c.maybeCaptureMetricsSnapshot(rep)
+
+			if cerr != nil {
 				return errors.Wrap(cerr, "unable to close repository")
 			}
 		}
@@ -609,7 +729,7 @@ func (c *App) maybeRunMaintenance(ctx context.Context, rep repo.Repository) erro
 
 func (c *App) advancedCommand(ctx context.Context) {
 	if c.AdvancedCommands != "enabled" {
-		_, _ = errorColor.Fprintf(c.stderrWriter, `
+		_, _ = errorColor.Fprintf(c.inv.stderr, `
 This command could be dangerous or lead to repository corruption when used improperly.
 
 Running this command is not needed for using Kopia. Instead, most users should rely on periodic repository maintenance. See https://kopia.io/docs/advanced/maintenance/ for more information.
@@ -617,7 +737,7 @@ func (c *App) advancedCommand(ctx context.Context) {
 
 `)
 
-		c.exitWithError(errors.Errorf("advanced commands are disabled"))
+		c.inv.exitWithError(errors.Errorf("advanced commands are disabled"))
 	}
 }
 
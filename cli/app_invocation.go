@@ -0,0 +1,71 @@
+package cli
+
+import (
+	"context"
+	"io"
+	"os"
+	"slices"
+
+	"github.com/mattn/go-colorable"
+
+	"github.com/kopia/kopia/internal/metrics"
+)
+
+// invocation holds the state of a single execution of a command, which is not bound to flags,
+// such as its output streams and hooks customizing its behavior in tests.
+type invocation struct {
+	ctx context.Context //nolint:containedctx
+
+	stdin      io.Reader
+	stdout     io.Writer
+	stderr     io.Writer
+	jsonOutput io.Writer // machine-readable output, defaults to stdout
+
+	exitWithError func(err error) // os.Exit() with the exit code based on err
+
+	// simulated signals delivered to in-process subcommands, nil when running as a process.
+	simulatedCtrlC   chan bool
+	simulatedSigDump chan bool
+
+	commandTimedOut chan struct{} // closed when the command exceeds --timeout
+
+	promptFunc            PromptFunc            // answers interactive prompts instead of the terminal, used by tests.
+	envOverrides          map[string]string     // keyed by prefixed name
+	metricsSnapshot       *metrics.Snapshot     // receives final repository metrics, used by tests.
+	snapshotSourceWrapper SnapshotSourceWrapper // wraps local snapshot sources, used by tests.
+}
+
+// processInvocation returns the invocation state of the kopia process.
+func processInvocation() *invocation {
+	return &invocation{
+		ctx:    context.Background(),
+		stdin:  os.Stdin,
+		stdout: colorable.NewColorableStdout(),
+		stderr: colorable.NewColorableStderr(),
+		exitWithError: func(err error) {
+			os.Exit(exitCodeForError(err))
+		},
+	}
+}
+
+// forInvocation returns a new App with the configuration of c, which parses flags and executes
+// a single in-process subcommand using the provided invocation state. Since flags are bound
+// to the App they are attached to, this allows subcommands sharing c to run concurrently.
+func (c *App) forInvocation(inv *invocation) *App {
+	ic := NewApp()
+
+	ic.inv = inv
+	ic.isInProcessTest = true
+	ic.envNamePrefix = c.envNamePrefix
+	ic.AdvancedCommands = c.AdvancedCommands
+	ic.cliStorageProviders = slices.Clone(c.cliStorageProviders)
+	ic.loggerFactory = c.loggerFactory
+	ic.logSpanIDs = c.logSpanIDs
+	ic.onExitCallbacks = slices.Clone(c.onExitCallbacks)
+
+	if _, ok := c.restoreProgress.(*cliRestoreProgress); !ok {
+		ic.restoreProgress = c.restoreProgress
+	}
+
+	return ic
+}
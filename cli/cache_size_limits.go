@@ -0,0 +1,62 @@
+package cli
+
+import (
+	"context"
+
+	"github.com/alecthomas/kingpin/v2"
+	"github.com/pkg/errors"
+
+	"github.com/kopia/kopia/internal/units"
+	"github.com/kopia/kopia/repo/content"
+)
+
+// cacheSizeLimitFlags holds human-readable cache size flags, which are shared between
+// 'cache set' and 'server cache set'.
+type cacheSizeLimitFlags struct {
+	contentMaxSize    string
+	contentSizeLimit  string
+	metadataMaxSize   string
+	metadataSizeLimit string
+}
+
+func (c *cacheSizeLimitFlags) setup(cmd *kingpin.CmdClause) {
+	cmd.Flag("content-max-size", "Desired size of local content cache (soft limit, e.g. 500MB, 2GiB)").StringVar(&c.contentMaxSize)
+	cmd.Flag("content-size-limit", "Maximum size of local content cache (hard limit, e.g. 500MB, 2GiB)").StringVar(&c.contentSizeLimit)
+	cmd.Flag("metadata-max-size", "Desired size of local metadata cache (soft limit, e.g. 500MB, 2GiB)").StringVar(&c.metadataMaxSize)
+	cmd.Flag("metadata-size-limit", "Maximum size of local metadata cache (hard limit, e.g. 500MB, 2GiB)").StringVar(&c.metadataSizeLimit)
+}
+
+func (c *cacheSizeLimitFlags) apply(ctx context.Context, limits *content.CacheSizeLimits, changeCount *int) error {
+	if err := setCacheSize(ctx, "content cache size", &limits.ContentCacheSizeBytes, c.contentMaxSize, changeCount); err != nil {
+		return err
+	}
+
+	if err := setCacheSize(ctx, "content cache size limit", &limits.ContentCacheSizeLimitBytes, c.contentSizeLimit, changeCount); err != nil {
+		return err
+	}
+
+	if err := setCacheSize(ctx, "metadata cache size", &limits.MetadataCacheSizeBytes, c.metadataMaxSize, changeCount); err != nil {
+		return err
+	}
+
+	return setCacheSize(ctx, "metadata cache size limit", &limits.MetadataCacheSizeLimitBytes, c.metadataSizeLimit, changeCount)
+}
+
+func setCacheSize(ctx context.Context, desc string, val *int64, str string, changeCount *int) error {
+	if str == "" {
+		// not changed
+		return nil
+	}
+
+	v, err := units.ParseBytes(str)
+	if err != nil {
+		return errors.Wrapf(err, "can't parse %v", desc)
+	}
+
+	log(ctx).Infof("changing %v to %v", desc, units.BytesString(v))
+
+	*val = v
+	*changeCount++
+
+	return nil
+}
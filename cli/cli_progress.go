@@ -10,6 +10,7 @@
 	"github.com/alecthomas/kingpin/v2"
 	"github.com/fatih/color"
 
+	"github.com/kopia/kopia/internal/clock"
 	"github.com/kopia/kopia/internal/timetrack"
 	"github.com/kopia/kopia/internal/units"
 	"github.com/kopia/kopia/snapshot/snapshotfs"
@@ -17,20 +18,40 @@
 
 const (
 	spinner = `|/-\`
+
+	progressFormatText  = "text"
+	progressFormatJSONL = "jsonl"
 )
 
 type progressFlags struct {
 	enableProgress         bool
 	progressUpdateInterval time.Duration
+	progressFormat         string
+	progressOutput         string
 	out                    textOutput
+
+	// jsonl is the destination of progress events when using --progress-format=jsonl.
+	jsonl *jsonlProgressWriter
 }
 
 func (p *progressFlags) setup(svc appServices, app *kingpin.Application) {
 	app.Flag("progress", "Enable progress bar").Hidden().Default("true").BoolVar(&p.enableProgress)
 	app.Flag("progress-update-interval", "How often to update progress information").Hidden().Default("300ms").DurationVar(&p.progressUpdateInterval)
+	app.Flag("progress-format", "Format of progress output (text - progress bar, jsonl - one JSON event per line)").Default(progressFormatText).EnumVar(&p.progressFormat, progressFormatText, progressFormatJSONL)
+	app.Flag("progress-output", "Write JSON-lines progress events to the provided file instead of standard error").StringVar(&p.progressOutput)
 	p.out.setup(svc)
 }
 
+func (p *progressFlags) jsonlEnabled() bool {
+	return p.enableProgress && p.progressFormat == progressFormatJSONL
+}
+
+// jsonlOnStderr returns true if JSON-lines progress events are written to standard error,
+// in which case nothing else may be written there as plain text.
+func (p *progressFlags) jsonlOnStderr() bool {
+	return p.jsonlEnabled() && p.progressOutput == ""
+}
+
 type cliProgress struct {
 	snapshotfs.NullUploadProgress
 
@@ -96,6 +117,16 @@ func (p *cliProgress) Error(path string, err error, isIgnored bool) {
 		p.fatalErrorCount.Add(1)
 		p.output(errorColor, fmt.Sprintf("Error when processing \"%v\": %v\n", path, err))
 	}
+
+	if p.jsonlEnabled() {
+		p.jsonl.emit(errorProgressEvent{
+			Type:    progressEventError,
+			Time:    clock.Now(),
+			Path:    path,
+			Error:   err.Error(),
+			Ignored: isIgnored,
+		})
+	}
 }
 
 func (p *cliProgress) CachedFile(_ string, numBytes int64) {
@@ -150,9 +181,10 @@ func (p *cliProgress) output(col *color.Color, msg string) {
 		line += fmt.Sprintf(" (%v errors ignored)", ignoredErrorCount)
 	}
 
-	if msg != "" {
+	// errors are emitted as separate events when standard error carries JSON-lines progress.
+	if msg != "" && !p.jsonlOnStderr() {
 		prefix := "\n ! "
-		if !p.enableProgress {
+		if !p.enableProgress || p.jsonlEnabled() {
 			prefix = ""
 		}
 
@@ -163,7 +195,40 @@ func (p *cliProgress) output(col *color.Color, msg string) {
 		return
 	}
 
-	if est, ok := p.uploadStartTime.Estimate(float64(hashedBytes+cachedBytes), float64(p.estimatedTotalBytes)); ok {
+	est, estOK := p.uploadStartTime.Estimate(float64(hashedBytes+cachedBytes), float64(p.estimatedTotalBytes))
+
+	if p.jsonlEnabled() {
+		if msg != "" {
+			return
+		}
+
+		ev := snapshotProgressEvent{
+			Type:           progressEventSnapshot,
+			Time:           clock.Now(),
+			Final:          p.uploadFinished.Load(),
+			HashingFiles:   inProgressHashing,
+			HashedFiles:    hashedFiles,
+			HashedBytes:    hashedBytes,
+			CachedFiles:    cachedFiles,
+			CachedBytes:    cachedBytes,
+			UploadedBytes:  uploadedBytes,
+			IgnoredErrors:  ignoredErrorCount,
+			FatalErrors:    fatalErrorCount,
+			EstimatedFiles: p.estimatedFileCount,
+			EstimatedBytes: p.estimatedTotalBytes,
+		}
+
+		if estOK {
+			ev.PercentComplete = est.PercentComplete
+			ev.RemainingSeconds = est.Remaining.Seconds()
+		}
+
+		p.jsonl.emit(ev)
+
+		return
+	}
+
+	if estOK {
 		line += fmt.Sprintf(", estimated %v", units.BytesString(p.estimatedTotalBytes))
 		line += fmt.Sprintf(" (%.1f%%)", est.PercentComplete)
 		line += fmt.Sprintf(" %v left", est.Remaining)
@@ -254,7 +319,7 @@ func (p *cliProgress) Finish() {
 
 	p.output(defaultColor, "")
 
-	if p.enableProgress {
+	if p.enableProgress && !p.jsonlEnabled() {
 		p.out.printStderr("\n")
 	}
 }
@@ -326,7 +391,8 @@ func (p *cliRestoreProgress) maybeOutput() {
 }
 
 func (p *cliRestoreProgress) output(suffix string) {
-	if !p.svc.getProgress().enableProgress {
+	cp := p.svc.getProgress()
+	if !cp.enableProgress {
 		return
 	}
 
@@ -342,12 +408,42 @@ func (p *cliRestoreProgress) output(suffix string) {
 	enqueuedSize := p.enqueuedTotalFileSize.Load()
 	skippedSize := p.skippedTotalFileSize.Load()
 
-	if restoredSize == 0 {
+	final := suffix != ""
+
+	// the final event is always emitted, so that consumers of JSON-lines progress can rely on it.
+	if restoredSize == 0 && !(final && cp.jsonlEnabled()) {
+		return
+	}
+
+	est, estOK := p.eta.Estimate(float64(restoredSize), float64(enqueuedSize))
+
+	if cp.jsonlEnabled() {
+		ev := restoreProgressEvent{
+			Type:          progressEventRestore,
+			Time:          clock.Now(),
+			Final:         final,
+			EnqueuedCount: enqueuedCount,
+			EnqueuedBytes: enqueuedSize,
+			RestoredCount: restoredCount,
+			RestoredBytes: restoredSize,
+			SkippedCount:  skippedCount,
+			SkippedBytes:  skippedSize,
+			IgnoredErrors: ignoredCount,
+		}
+
+		if estOK {
+			ev.PercentComplete = est.PercentComplete
+			ev.RemainingSeconds = est.Remaining.Seconds()
+			ev.BytesPerSecond = est.SpeedPerSecond
+		}
+
+		cp.jsonl.emit(ev)
+
 		return
 	}
 
 	var maybeRemaining, maybeSkipped, maybeErrors string
-	if est, ok := p.eta.Estimate(float64(restoredSize), float64(enqueuedSize)); ok {
+	if estOK {
 		maybeRemaining = fmt.Sprintf(" %v (%.1f%%) remaining %v",
 			units.BytesPerSecondsString(est.SpeedPerSecond),
 			est.PercentComplete,
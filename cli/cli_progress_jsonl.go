@@ -0,0 +1,128 @@
+package cli
+
+import (
+	"encoding/json"
+	"io"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// types of events emitted by --progress-format=jsonl.
+const (
+	progressEventSnapshot = "snapshot"
+	progressEventRestore  = "restore"
+	progressEventError    = "error"
+)
+
+// snapshotProgressEvent is emitted periodically while creating snapshots.
+type snapshotProgressEvent struct {
+	Type             string    `json:"type"`
+	Time             time.Time `json:"time"`
+	Final            bool      `json:"final"`
+	HashingFiles     int32     `json:"hashingFiles"`
+	HashedFiles      int32     `json:"hashedFiles"`
+	HashedBytes      int64     `json:"hashedBytes"`
+	CachedFiles      int32     `json:"cachedFiles"`
+	CachedBytes      int64     `json:"cachedBytes"`
+	UploadedBytes    int64     `json:"uploadedBytes"`
+	IgnoredErrors    int32     `json:"ignoredErrors"`
+	FatalErrors      int32     `json:"fatalErrors"`
+	EstimatedFiles   int       `json:"estimatedFiles,omitempty"`
+	EstimatedBytes   int64     `json:"estimatedBytes,omitempty"`
+	PercentComplete  float64   `json:"percentComplete,omitempty"`
+	RemainingSeconds float64   `json:"remainingSeconds,omitempty"`
+}
+
+// restoreProgressEvent is emitted periodically while restoring snapshots.
+type restoreProgressEvent struct {
+	Type             string    `json:"type"`
+	Time             time.Time `json:"time"`
+	Final            bool      `json:"final"`
+	EnqueuedCount    int32     `json:"enqueuedCount"`
+	EnqueuedBytes    int64     `json:"enqueuedBytes"`
+	RestoredCount    int32     `json:"restoredCount"`
+	RestoredBytes    int64     `json:"restoredBytes"`
+	SkippedCount     int32     `json:"skippedCount"`
+	SkippedBytes     int64     `json:"skippedBytes"`
+	IgnoredErrors    int32     `json:"ignoredErrors"`
+	PercentComplete  float64   `json:"percentComplete,omitempty"`
+	RemainingSeconds float64   `json:"remainingSeconds,omitempty"`
+	BytesPerSecond   float64   `json:"bytesPerSecond,omitempty"`
+}
+
+// errorProgressEvent is emitted for each error encountered while processing a file.
+type errorProgressEvent struct {
+	Type    string    `json:"type"`
+	Time    time.Time `json:"time"`
+	Path    string    `json:"path"`
+	Error   string    `json:"error"`
+	Ignored bool      `json:"ignored"`
+}
+
+// jsonlProgressWriter writes progress events as JSON objects, one per line.
+type jsonlProgressWriter struct {
+	mu sync.Mutex
+	// +checklocks:mu
+	out io.Writer
+	// +checklocks:mu
+	closer io.Closer
+}
+
+func (w *jsonlProgressWriter) emit(ev any) {
+	if w == nil {
+		return
+	}
+
+	b, err := json.Marshal(ev)
+	if err != nil {
+		return
+	}
+
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	w.out.Write(append(b, '\n')) //nolint:errcheck
+}
+
+func (w *jsonlProgressWriter) close() {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if w.closer != nil {
+		w.closer.Close() //nolint:errcheck
+		w.closer = nil
+	}
+}
+
+// openProgressOutput opens the destination of JSON-lines progress events, if enabled.
+func (p *cliProgress) openProgressOutput() error {
+	p.jsonl = nil
+
+	if !p.jsonlEnabled() {
+		return nil
+	}
+
+	if p.progressOutput == "" {
+		p.jsonl = &jsonlProgressWriter{out: p.out.stderr()}
+		return nil
+	}
+
+	f, err := os.OpenFile(p.progressOutput, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o600) //nolint:gosec,mnd
+	if err != nil {
+		return errors.Wrap(err, "unable to open progress output")
+	}
+
+	p.jsonl = &jsonlProgressWriter{out: f, closer: f}
+
+	return nil
+}
+
+// closeProgressOutput closes the destination of JSON-lines progress events.
+func (p *cliProgress) closeProgressOutput() {
+	if p.jsonl != nil {
+		p.jsonl.close()
+	}
+}
@@ -0,0 +1,101 @@
+package cli_test
+
+import (
+	"bufio"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/kopia/kopia/internal/testutil"
+	"github.com/kopia/kopia/tests/testenv"
+)
+
+func TestProgressFormatJSONL(t *testing.T) {
+	env := testenv.NewCLITest(t, testenv.RepoFormatNotImportant, testenv.NewInProcRunner(t))
+	env.RunAndExpectSuccess(t, "repo", "create", "filesystem", "--path", env.RepoDir)
+
+	srcDir := testutil.TempDirectory(t)
+	require.NoError(t, os.WriteFile(filepath.Join(srcDir, "file1"), []byte("some data"), 0o600))
+	require.NoError(t, os.WriteFile(filepath.Join(srcDir, "file2"), []byte("more data"), 0o600))
+
+	progressFile := filepath.Join(testutil.TempDirectory(t), "progress.jsonl")
+
+	env.RunAndExpectSuccess(t, "snapshot", "create", srcDir, "--progress-format=jsonl", "--progress-output", progressFile)
+
+	events := readProgressEvents(t, progressFile)
+	require.NotEmpty(t, events)
+
+	last := events[len(events)-1]
+	require.Equal(t, "snapshot", last["type"])
+	require.Equal(t, true, last["final"])
+	require.EqualValues(t, 2, last["hashedFiles"])
+	require.EqualValues(t, 18, last["hashedBytes"])
+
+	require.NoError(t, os.Remove(progressFile))
+
+	restoreDir := testutil.TempDirectory(t)
+	env.RunAndExpectSuccess(t, "snapshot", "restore", srcDir, restoreDir, "--progress-format=jsonl", "--progress-output", progressFile)
+
+	events = readProgressEvents(t, progressFile)
+	require.NotEmpty(t, events)
+
+	last = events[len(events)-1]
+	require.Equal(t, "restore", last["type"])
+	require.Equal(t, true, last["final"])
+	require.EqualValues(t, 18, last["restoredBytes"])
+
+	env.RunAndExpectFailure(t, "snapshot", "create", srcDir, "--progress-format=xml")
+}
+
+func TestProgressFormatJSONLOnStderr(t *testing.T) {
+	env := testenv.NewCLITest(t, testenv.RepoFormatNotImportant, testenv.NewInProcRunner(t))
+	env.RunAndExpectSuccess(t, "repo", "create", "filesystem", "--path", env.RepoDir)
+
+	srcDir := testutil.TempDirectory(t)
+	require.NoError(t, os.WriteFile(filepath.Join(srcDir, "file1"), []byte("some data"), 0o600))
+
+	_, stderr := env.RunAndExpectSuccessWithErrOut(t, "snapshot", "create", srcDir, "--progress-format=jsonl")
+	requireJSONLines(t, stderr)
+
+	// errors, including the one failing the command, are written as JSON as well.
+	_, stderr = env.RunAndExpectFailure(t, "snapshot", "create", filepath.Join(srcDir, "no-such-dir"), "--progress-format=jsonl")
+	requireJSONLines(t, stderr)
+	require.NotEmpty(t, stderr)
+}
+
+func requireJSONLines(t *testing.T, lines []string) {
+	t.Helper()
+
+	for _, l := range lines {
+		var v map[string]any
+
+		require.NoError(t, json.Unmarshal([]byte(l), &v), l)
+	}
+}
+
+func readProgressEvents(t *testing.T, fname string) []map[string]any {
+	t.Helper()
+
+	f, err := os.Open(fname)
+	require.NoError(t, err)
+
+	defer f.Close()
+
+	var events []map[string]any
+
+	s := bufio.NewScanner(f)
+	for s.Scan() {
+		var ev map[string]any
+
+		require.NoError(t, json.Unmarshal(s.Bytes(), &ev), s.Text())
+
+		events = append(events, ev)
+	}
+
+	require.NoError(t, s.Err())
+
+	return events
+}
@@ -3,8 +3,10 @@
 import (
 	"context"
 	"sort"
+	"strings"
 
 	atunits "github.com/alecthomas/units"
+	"github.com/pkg/errors"
 
 	"github.com/kopia/kopia/internal/gather"
 	"github.com/kopia/kopia/internal/timetrack"
@@ -12,6 +14,7 @@
 	"github.com/kopia/kopia/repo/encryption"
 	"github.com/kopia/kopia/repo/format"
 	"github.com/kopia/kopia/repo/hashing"
+	"github.com/kopia/kopia/repo/splitter"
 )
 
 type commandBenchmarkCrypto struct {
@@ -20,6 +23,8 @@ type commandBenchmarkCrypto struct {
 	deprecatedAlgorithms bool
 	optionPrint          bool
 	parallel             int
+	samplePath           string
+	maxSampleSize        atunits.Base2Bytes
 
 	out textOutput
 }
@@ -31,12 +36,35 @@ func (c *commandBenchmarkCrypto) setup(svc appServices, parent commandParent) {
 	cmd.Flag("deprecated", "Include deprecated algorithms").BoolVar(&c.deprecatedAlgorithms)
 	cmd.Flag("parallel", "Number of parallel goroutines").Default("1").IntVar(&c.parallel)
 	cmd.Flag("print-options", "Print out options usable for repository creation").BoolVar(&c.optionPrint)
+	cmd.Flag("path", "Benchmark using files sampled from the provided directory and recommend settings for them").StringVar(&c.samplePath)
+	cmd.Flag("max-sample-size", "Maximum amount of data sampled from --path").Default("64MB").BytesVar(&c.maxSampleSize)
 	cmd.Action(svc.noRepositoryAction(c.run))
 	c.out.setup(svc)
 }
 
 func (c *commandBenchmarkCrypto) run(ctx context.Context) error {
-	results := c.runBenchmark(ctx)
+	blocks := [][]byte{make([]byte, c.blockSize)}
+
+	var files [][]byte
+
+	if c.samplePath != "" {
+		// files are sampled whole, so that they can be split just like when they're being snapshotted.
+		sampled, fileCount, err := sampleFileBlocks(c.samplePath, int(c.maxSampleSize), int64(c.maxSampleSize))
+		if err != nil {
+			return errors.Wrap(err, "unable to sample files")
+		}
+
+		if len(sampled) == 0 {
+			return errors.Errorf("no data found in %v", c.samplePath)
+		}
+
+		c.out.printStdout("Sampled %v from %v files in %v\n", units.BytesString(totalBlockBytes(sampled)), fileCount, c.samplePath)
+
+		files = sampled
+		blocks = chopBlocks(sampled, int(c.blockSize))
+	}
+
+	results := c.runBenchmark(ctx, blocks)
 
 	sort.Slice(results, func(i, j int) bool {
 		return results[i].throughput > results[j].throughput
@@ -57,13 +85,62 @@ func (c *commandBenchmarkCrypto) run(ctx context.Context) error {
 	c.out.printStdout("-----------------------------------------------------------------\n")
 	c.out.printStdout("Fastest option for this machine is: --block-hash=%s --encryption=%s\n", results[0].hash, results[0].encryption)
 
+	if files == nil {
+		return nil
+	}
+
+	sp := c.recommendSplitter(ctx, files)
+
+	c.out.printStdout("Recommended options for this data: --block-hash=%s --encryption=%s --object-splitter=%s\n", results[0].hash, results[0].encryption, sp)
+
 	return nil
 }
 
-func (c *commandBenchmarkCrypto) runBenchmark(ctx context.Context) []cryptoBenchResult {
-	var results []cryptoBenchResult
+// recommendSplitter returns the dynamic splitter that leaves the least unique data after
+// deduplication of the provided files, preferring faster splitters when there's a tie.
+func (c *commandBenchmarkCrypto) recommendSplitter(ctx context.Context, files [][]byte) string {
+	var (
+		best           string
+		bestUnique     int64
+		bestThroughput float64
+	)
+
+	totalBytes := totalBlockBytes(files)
 
-	data := make([]byte, c.blockSize)
+	for _, sp := range splitter.SupportedAlgorithms() {
+		if strings.HasPrefix(sp, "FIXED") {
+			continue
+		}
+
+		log(ctx).Infof("Benchmarking splitter '%v' (%v bytes)", sp, totalBytes)
+
+		tt := timetrack.Start()
+		segmentLengths := splitBlocks(sp, files)
+		_, bytesPerSecond := tt.Completed(float64(totalBytes))
+
+		unique := uniqueSegmentBytes(files, segmentLengths)
+
+		c.out.printStdout("     %-25v %v / second, unique:%v\n", sp, units.BytesString(int64(bytesPerSecond)), units.BytesString(unique))
+
+		if best == "" || unique < bestUnique || (unique == bestUnique && bytesPerSecond > bestThroughput) {
+			best, bestUnique, bestThroughput = sp, unique, bytesPerSecond
+		}
+	}
+
+	return best
+}
+
+func (c *commandBenchmarkCrypto) runBenchmark(ctx context.Context, blocks [][]byte) []cryptoBenchResult {
+	var (
+		results    []cryptoBenchResult
+		inputs     []gather.Bytes
+		totalBytes int
+	)
+
+	for _, b := range blocks {
+		inputs = append(inputs, gather.FromSlice(b))
+		totalBytes += len(b)
+	}
 
 	for _, ha := range hashing.SupportedAlgorithms() {
 		for _, ea := range encryption.SupportedAlgorithms(c.deprecatedAlgorithms) {
@@ -84,9 +161,8 @@ func (c *commandBenchmarkCrypto) runBenchmark(ctx context.Context) []cryptoBench
 				continue
 			}
 
-			log(ctx).Infof("Benchmarking hash '%v' and encryption '%v'... (%v x %v bytes, parallelism %v)", ha, ea, c.repeat, len(data), c.parallel)
+			log(ctx).Infof("Benchmarking hash '%v' and encryption '%v'... (%v x %v bytes, parallelism %v)", ha, ea, c.repeat, totalBytes, c.parallel)
 
-			input := gather.FromSlice(data)
 			tt := timetrack.Start()
 
 			hashCount := c.repeat
@@ -98,16 +174,18 @@ func (c *commandBenchmarkCrypto) runBenchmark(ctx context.Context) []cryptoBench
 				defer encryptOutput.Close()
 
 				for range hashCount {
-					contentID := hf(hashOutput[:0], input)
+					for _, input := range inputs {
+						contentID := hf(hashOutput[:0], input)
 
-					if encerr := enc.Encrypt(input, contentID, &encryptOutput); encerr != nil {
-						log(ctx).Errorf("encryption failed: %v", encerr)
-						break
+						if encerr := enc.Encrypt(input, contentID, &encryptOutput); encerr != nil {
+							log(ctx).Errorf("encryption failed: %v", encerr)
+							return
+						}
 					}
 				}
 			})
 
-			_, bytesPerSecond := tt.Completed(float64(c.parallel) * float64(len(data)) * float64(hashCount))
+			_, bytesPerSecond := tt.Completed(float64(c.parallel) * float64(totalBytes) * float64(hashCount))
 
 			results = append(results, cryptoBenchResult{hash: ha, encryption: ea, throughput: bytesPerSecond})
 		}
@@ -2,9 +2,14 @@
 
 import (
 	"context"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
 	"sort"
 
 	atunits "github.com/alecthomas/units"
+	"github.com/pkg/errors"
 
 	"github.com/kopia/kopia/internal/gather"
 	"github.com/kopia/kopia/internal/timetrack"
@@ -14,10 +19,12 @@
 )
 
 type commandBenchmarkHashing struct {
-	blockSize   atunits.Base2Bytes
-	repeat      int
-	optionPrint bool
-	parallel    int
+	blockSize     atunits.Base2Bytes
+	repeat        int
+	optionPrint   bool
+	parallel      int
+	samplePath    string
+	maxSampleSize atunits.Base2Bytes
 
 	out textOutput
 }
@@ -28,12 +35,31 @@ func (c *commandBenchmarkHashing) setup(svc appServices, parent commandParent) {
 	cmd.Flag("repeat", "Number of repetitions").Default("100").IntVar(&c.repeat)
 	cmd.Flag("parallel", "Number of parallel goroutines").Default("1").IntVar(&c.parallel)
 	cmd.Flag("print-options", "Print out options usable for repository creation").BoolVar(&c.optionPrint)
+	cmd.Flag("path", "Hash blocks of files sampled from the provided directory instead of synthetic data").StringVar(&c.samplePath)
+	cmd.Flag("max-sample-size", "Maximum amount of data sampled from --path").Default("16MB").BytesVar(&c.maxSampleSize)
 	cmd.Action(svc.noRepositoryAction(c.run))
 	c.out.setup(svc)
 }
 
 func (c *commandBenchmarkHashing) run(ctx context.Context) error {
-	results := c.runBenchmark(ctx)
+	blocks := [][]byte{make([]byte, c.blockSize)}
+
+	if c.samplePath != "" {
+		sampled, fileCount, err := sampleFileBlocks(c.samplePath, int(c.blockSize), int64(c.maxSampleSize))
+		if err != nil {
+			return errors.Wrap(err, "unable to sample files")
+		}
+
+		if len(sampled) == 0 {
+			return errors.Errorf("no data found in %v", c.samplePath)
+		}
+
+		c.out.printStdout("Sampled %v blocks (%v) from %v files in %v\n", len(sampled), units.BytesString(totalBlockBytes(sampled)), fileCount, c.samplePath)
+
+		blocks = sampled
+	}
+
+	results := c.runBenchmark(ctx, blocks)
 
 	sort.Slice(results, func(i, j int) bool {
 		return results[i].throughput > results[j].throughput
@@ -57,10 +83,17 @@ func (c *commandBenchmarkHashing) run(ctx context.Context) error {
 	return nil
 }
 
-func (c *commandBenchmarkHashing) runBenchmark(ctx context.Context) []cryptoBenchResult {
-	var results []cryptoBenchResult
+func (c *commandBenchmarkHashing) runBenchmark(ctx context.Context, blocks [][]byte) []cryptoBenchResult {
+	var (
+		results    []cryptoBenchResult
+		inputs     []gather.Bytes
+		totalBytes int
+	)
 
-	data := make([]byte, c.blockSize)
+	for _, b := range blocks {
+		inputs = append(inputs, gather.FromSlice(b))
+		totalBytes += len(b)
+	}
 
 	for _, ha := range hashing.SupportedAlgorithms() {
 		hf, err := hashing.CreateHashFunc(&format.ContentFormat{
@@ -71,25 +104,107 @@ func (c *commandBenchmarkHashing) runBenchmark(ctx context.Context) []cryptoBenc
 			continue
 		}
 
-		log(ctx).Infof("Benchmarking hash '%v' (%v x %v bytes, parallelism %v)", ha, c.repeat, len(data), c.parallel)
+		log(ctx).Infof("Benchmarking hash '%v' (%v x %v bytes, parallelism %v)", ha, c.repeat, totalBytes, c.parallel)
 
-		input := gather.FromSlice(data)
 		tt := timetrack.Start()
 
-		hashCount := c.repeat
-
 		runInParallelNoInputNoResult(c.parallel, func() {
 			var hashOutput [hashing.MaxHashSize]byte
 
-			for range hashOutput {
-				hf(hashOutput[:0], input)
+			for range c.repeat {
+				for _, input := range inputs {
+					hf(hashOutput[:0], input)
+				}
 			}
 		})
 
-		_, bytesPerSecond := tt.Completed(float64(c.parallel) * float64(len(data)) * float64(hashCount))
+		_, bytesPerSecond := tt.Completed(float64(c.parallel) * float64(totalBytes) * float64(c.repeat))
 
 		results = append(results, cryptoBenchResult{hash: ha, encryption: "-", throughput: bytesPerSecond})
 	}
 
 	return results
 }
+
+// totalBlockBytes returns the combined length of the provided blocks.
+func totalBlockBytes(blocks [][]byte) int64 {
+	var total int64
+
+	for _, b := range blocks {
+		total += int64(len(b))
+	}
+
+	return total
+}
+
+// chopBlocks splits the provided blocks into blocks of at most the provided size, without copying.
+func chopBlocks(blocks [][]byte, blockSize int) [][]byte {
+	var result [][]byte
+
+	for _, b := range blocks {
+		for len(b) > blockSize {
+			result = append(result, b[:blockSize])
+			b = b[blockSize:]
+		}
+
+		if len(b) > 0 {
+			result = append(result, b)
+		}
+	}
+
+	return result
+}
+
+// sampleFileBlocks reads regular files found under the provided directory and returns their contents
+// split into blocks of the provided size, until maxBytes have been read. Each file is read into a single
+// buffer sized based on the length of the file, which the returned blocks share.
+func sampleFileBlocks(dir string, blockSize int, maxBytes int64) (blocks [][]byte, fileCount int, err error) {
+	var total int64
+
+	errSampleComplete := errors.New("sample complete")
+
+	err = filepath.WalkDir(dir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			// skip unreadable entries.
+			return nil
+		}
+
+		if !d.Type().IsRegular() {
+			return nil
+		}
+
+		if total >= maxBytes {
+			return errSampleComplete
+		}
+
+		f, err := os.Open(path) //nolint:gosec
+		if err != nil {
+			return nil
+		}
+
+		defer f.Close() //nolint:errcheck
+
+		fi, err := f.Stat()
+		if err != nil {
+			return nil
+		}
+
+		fileCount++
+
+		buf := make([]byte, min(fi.Size(), maxBytes-total))
+
+		// the file may have been truncated since.
+		n, _ := io.ReadFull(f, buf)
+
+		blocks = append(blocks, chopBlocks([][]byte{buf[:n]}, blockSize)...)
+		total += int64(n)
+
+		return nil
+	})
+
+	if errors.Is(err, errSampleComplete) {
+		err = nil
+	}
+
+	return blocks, fileCount, errors.Wrap(err, "error walking directory")
+}
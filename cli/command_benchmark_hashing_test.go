@@ -0,0 +1,45 @@
+package cli
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/kopia/kopia/internal/testutil"
+)
+
+func TestSampleFileBlocks(t *testing.T) {
+	dir := testutil.TempDirectory(t)
+
+	require.NoError(t, os.MkdirAll(filepath.Join(dir, "subdir"), 0o700))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "file1"), bytes.Repeat([]byte{1}, 2500), 0o600))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "subdir", "file2"), bytes.Repeat([]byte{2}, 100), 0o600))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "subdir", "empty"), nil, 0o600))
+
+	blocks, fileCount, err := sampleFileBlocks(dir, 1000, 1<<20)
+	require.NoError(t, err)
+	require.Equal(t, 3, fileCount)
+	require.Equal(t, [][]byte{
+		bytes.Repeat([]byte{1}, 1000),
+		bytes.Repeat([]byte{1}, 1000),
+		bytes.Repeat([]byte{1}, 500),
+		bytes.Repeat([]byte{2}, 100),
+	}, blocks)
+
+	// buffers are sized based on file lengths, not the block size.
+	blocks, _, err = sampleFileBlocks(dir, 256<<20, 1<<20)
+	require.NoError(t, err)
+	require.Len(t, blocks, 2)
+	require.Equal(t, 2500, cap(blocks[0]))
+	require.Equal(t, 100, cap(blocks[1]))
+
+	// sampling stops after the maximum number of bytes.
+	blocks, fileCount, err = sampleFileBlocks(dir, 1000, 1200)
+	require.NoError(t, err)
+	require.Equal(t, 1, fileCount)
+	require.Equal(t, int64(1200), totalBlockBytes(blocks))
+	require.Len(t, blocks, 2)
+}
@@ -0,0 +1,106 @@
+package cli
+
+import (
+	"context"
+	"runtime"
+	"time"
+
+	atunits "github.com/alecthomas/units"
+	"github.com/pkg/errors"
+
+	"github.com/kopia/kopia/internal/crypto"
+	"github.com/kopia/kopia/internal/timetrack"
+)
+
+const (
+	kdfBenchmarkKeySize        = 32
+	kdfBenchmarkMinMemory      = 16 << 20
+	kdfBenchmarkMaxParallelism = 4
+	kdfBenchmarkSeparator      = "-----------------------------------------------------------------\n"
+)
+
+type commandBenchmarkKDF struct {
+	targetDuration time.Duration
+	maxMemory      atunits.Base2Bytes
+	iterations     uint32
+	parallelism    uint8
+
+	out textOutput
+}
+
+func (c *commandBenchmarkKDF) setup(svc appServices, parent commandParent) {
+	cmd := parent.Command("kdf", "Run key derivation benchmarks and recommend argon2id parameters")
+	cmd.Flag("target-duration", "Desired duration of a single key derivation").Default("500ms").DurationVar(&c.targetDuration)
+	cmd.Flag("max-memory", "Maximum amount of memory to use for argon2id key derivation").Default("1GiB").BytesVar(&c.maxMemory)
+	cmd.Flag("iterations", "Number of argon2id iterations").Default("3").Uint32Var(&c.iterations)
+	cmd.Flag("parallelism", "Parallelism of argon2id, 0 == based on number of CPUs").Uint8Var(&c.parallelism)
+	cmd.Action(svc.noRepositoryAction(c.run))
+	c.out.setup(svc)
+}
+
+func (c *commandBenchmarkKDF) run(ctx context.Context) error {
+	parallelism := c.parallelism
+	if parallelism == 0 {
+		parallelism = uint8(min(runtime.NumCPU(), kdfBenchmarkMaxParallelism)) //nolint:gosec
+	}
+
+	c.out.printStdout("     %-40v %v\n", "Algorithm", "Duration")
+	c.out.printStdout(kdfBenchmarkSeparator)
+
+	for ndx, algo := range []string{crypto.ScryptAlgorithm, crypto.Pbkdf2Algorithm, crypto.Argon2idAlgorithm} {
+		dur, err := c.measure(ctx, algo)
+		if err != nil {
+			return err
+		}
+
+		c.out.printStdout("%3d. %-40v %v\n", ndx, algo, dur.Round(time.Millisecond))
+	}
+
+	c.out.printStdout(kdfBenchmarkSeparator)
+
+	var best atunits.Base2Bytes
+
+	for mem := atunits.Base2Bytes(kdfBenchmarkMinMemory); mem <= min(c.maxMemory, crypto.Argon2idMaxMemory); mem *= 2 {
+		algo, err := argon2idAlgorithmFromFlags(mem, c.iterations, parallelism)
+		if err != nil {
+			return err
+		}
+
+		dur, err := c.measure(ctx, algo)
+		if err != nil {
+			return err
+		}
+
+		c.out.printStdout("     %-40v %v\n", algo, dur.Round(time.Millisecond))
+
+		if dur > c.targetDuration {
+			break
+		}
+
+		best = mem
+	}
+
+	c.out.printStdout(kdfBenchmarkSeparator)
+
+	if best == 0 {
+		c.out.printStdout("No argon2id parameters completed within %v, consider more iterations with less memory or a longer target duration.\n", c.targetDuration)
+		return nil
+	}
+
+	c.out.printStdout("Recommended options for this machine are: --kdf=argon2id --kdf-argon2id-memory=%v --kdf-argon2id-iterations=%v --kdf-argon2id-parallelism=%v\n", best, c.iterations, parallelism)
+
+	return nil
+}
+
+func (c *commandBenchmarkKDF) measure(ctx context.Context, algo string) (time.Duration, error) {
+	log(ctx).Infof("Benchmarking key derivation '%v'...", algo)
+
+	salt := make([]byte, kdfBenchmarkKeySize)
+	tt := timetrack.StartTimer()
+
+	if _, err := crypto.DeriveKeyFromPassword("benchmark-password", salt, kdfBenchmarkKeySize, algo); err != nil {
+		return 0, errors.Wrapf(err, "error deriving key using %v", algo)
+	}
+
+	return tt.Elapsed(), nil
+}
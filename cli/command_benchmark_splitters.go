@@ -2,6 +2,8 @@
 
 import (
 	"context"
+	"crypto/sha256"
+	"fmt"
 	"math"
 	"math/rand"
 	"sort"
@@ -22,6 +24,8 @@ type commandBenchmarkSplitters struct {
 	blockCount  int
 	printOption bool
 	parallel    int
+	dataDir     string
+	maxDataSize atunits.Base2Bytes
 
 	out textOutput
 }
@@ -34,6 +38,8 @@ func (c *commandBenchmarkSplitters) setup(svc appServices, parent commandParent)
 	cmd.Flag("block-count", "Number of data blocks to split").Default("16").IntVar(&c.blockCount)
 	cmd.Flag("print-options", "Print out the fastest dynamic splitter option").BoolVar(&c.printOption)
 	cmd.Flag("parallel", "Number of parallel goroutines").Default("1").IntVar(&c.parallel)
+	cmd.Flag("data-dir", "Split files found in the provided directory instead of synthetic data").StringVar(&c.dataDir)
+	cmd.Flag("max-data-size", "Maximum amount of data read from --data-dir").Default("256MB").BytesVar(&c.maxDataSize)
 
 	cmd.Action(svc.noRepositoryAction(c.run))
 
@@ -53,6 +59,7 @@ type benchResult struct {
 		p90            int
 		max            int
 		bytesPerSecond int64
+		uniqueBytes    int64
 	}
 
 	var results []benchResult
@@ -61,52 +68,35 @@ type benchResult struct {
 
 	best.duration = math.MaxInt64
 
-	// generate data blocks
-	var dataBlocks [][]byte
+	dataBlocks, err := c.dataBlocks(ctx)
+	if err != nil {
+		return err
+	}
 
-	rnd := rand.New(rand.NewSource(c.randSeed)) //nolint:gosec
+	var totalBytes int64
 
-	for range c.blockCount {
-		b := make([]byte, c.blockSize)
-		if _, err := rnd.Read(b); err != nil {
-			return errors.Wrap(err, "error generating random data")
-		}
-
-		dataBlocks = append(dataBlocks, b)
+	for _, d := range dataBlocks {
+		totalBytes += int64(len(d))
 	}
 
-	log(ctx).Infof("splitting %v blocks of %v each, parallelism %v", c.blockCount, c.blockSize, c.parallel)
-
 	for _, sp := range splitter.SupportedAlgorithms() {
 		tt := timetrack.Start()
 
 		segmentLengths := runInParallelNoInput(c.parallel, func() []int {
-			fact := splitter.GetFactory(sp)
-
-			var segmentLengths []int
-
-			for _, d := range dataBlocks {
-				s := fact()
-
-				for len(d) > 0 {
-					n := s.NextSplitPoint(d)
-					if n < 0 {
-						segmentLengths = append(segmentLengths, len(d))
-						break
-					}
-
-					segmentLengths = append(segmentLengths, n)
-					d = d[n:]
-				}
-			}
-
-			return segmentLengths
+			return splitBlocks(sp, dataBlocks)
 		})
 
-		_, bytesPerSecond := tt.Completed(float64(c.parallel) * float64(c.blockCount) * float64(c.blockSize))
+		_, bytesPerSecond := tt.Completed(float64(c.parallel) * float64(totalBytes))
 
 		dur, _ := tt.Completed(0)
 
+		var uniqueBytes int64
+
+		if c.dataDir != "" {
+			// segments of real data are hashed to determine how much of it would be deduplicated.
+			uniqueBytes = uniqueSegmentBytes(dataBlocks, segmentLengths)
+		}
+
 		sort.Ints(segmentLengths)
 
 		r := benchResult{
@@ -121,13 +111,15 @@ type benchResult struct {
 			segmentLengths[len(segmentLengths)*90/100],
 			segmentLengths[len(segmentLengths)-1],
 			int64(bytesPerSecond),
+			uniqueBytes,
 		}
 
-		c.out.printStdout("%-25v %12v count:%v min:%v 10th:%v 25th:%v 50th:%v 75th:%v 90th:%v max:%v\n",
+		c.out.printStdout("%-25v %12v count:%v min:%v 10th:%v 25th:%v 50th:%v 75th:%v 90th:%v max:%v%v\n",
 			r.splitter,
 			units.BytesString(r.bytesPerSecond)+"/s",
 			r.segmentCount,
 			r.min, r.p10, r.p25, r.p50, r.p75, r.p90, r.max,
+			c.dedupSummary(r.uniqueBytes, totalBytes),
 		)
 
 		results = append(results, r)
@@ -139,12 +131,13 @@ type benchResult struct {
 	c.out.printStdout("-----------------------------------------------------------------\n")
 
 	for ndx, r := range results {
-		c.out.printStdout("%3v. %-25v %-12v count:%v min:%v 10th:%v 25th:%v 50th:%v 75th:%v 90th:%v max:%v\n",
+		c.out.printStdout("%3v. %-25v %-12v count:%v min:%v 10th:%v 25th:%v 50th:%v 75th:%v 90th:%v max:%v%v\n",
 			ndx,
 			r.splitter,
 			units.BytesString(r.bytesPerSecond)+"/s",
 			r.segmentCount,
-			r.min, r.p10, r.p25, r.p50, r.p75, r.p90, r.max)
+			r.min, r.p10, r.p25, r.p50, r.p75, r.p90, r.max,
+			c.dedupSummary(r.uniqueBytes, totalBytes))
 
 		if best.duration > r.duration && !strings.HasPrefix(r.splitter, "FIXED") {
 			best = r
@@ -157,3 +150,101 @@ type benchResult struct {
 
 	return nil
 }
+
+func (c *commandBenchmarkSplitters) dataBlocks(ctx context.Context) ([][]byte, error) {
+	if c.dataDir != "" {
+		// each file is split as a whole, just like when it's being snapshotted.
+		blocks, fileCount, err := sampleFileBlocks(c.dataDir, int(c.maxDataSize), int64(c.maxDataSize))
+		if err != nil {
+			return nil, errors.Wrap(err, "unable to read files")
+		}
+
+		if len(blocks) == 0 {
+			return nil, errors.Errorf("no data found in %v", c.dataDir)
+		}
+
+		log(ctx).Infof("splitting %v files from %v, parallelism %v", fileCount, c.dataDir, c.parallel)
+
+		return blocks, nil
+	}
+
+	var dataBlocks [][]byte
+
+	rnd := rand.New(rand.NewSource(c.randSeed)) //nolint:gosec
+
+	for range c.blockCount {
+		b := make([]byte, c.blockSize)
+		if _, err := rnd.Read(b); err != nil {
+			return nil, errors.Wrap(err, "error generating random data")
+		}
+
+		dataBlocks = append(dataBlocks, b)
+	}
+
+	log(ctx).Infof("splitting %v blocks of %v each, parallelism %v", c.blockCount, c.blockSize, c.parallel)
+
+	return dataBlocks, nil
+}
+
+func (c *commandBenchmarkSplitters) dedupSummary(uniqueBytes, totalBytes int64) string {
+	if c.dataDir == "" || totalBytes == 0 {
+		return ""
+	}
+
+	return fmt.Sprintf(" unique:%v (%.1f%%)", units.BytesString(uniqueBytes), 100*float64(uniqueBytes)/float64(totalBytes)) //nolint:mnd
+}
+
+// splitBlocks splits each of the provided data blocks using the provided splitter and
+// returns lengths of the resulting segments in the order they were produced.
+func splitBlocks(sp string, dataBlocks [][]byte) []int {
+	// creating some splitters is expensive, so a single instance is reset between blocks.
+	s := splitter.GetFactory(sp)()
+	defer s.Close()
+
+	var segmentLengths []int
+
+	for _, d := range dataBlocks {
+		s.Reset()
+
+		for len(d) > 0 {
+			n := s.NextSplitPoint(d)
+			if n < 0 {
+				segmentLengths = append(segmentLengths, len(d))
+				break
+			}
+
+			segmentLengths = append(segmentLengths, n)
+			d = d[n:]
+		}
+	}
+
+	return segmentLengths
+}
+
+// uniqueSegmentBytes returns the total size of distinct segments of the provided data blocks,
+// given lengths of consecutive segments in the order they were produced.
+func uniqueSegmentBytes(dataBlocks [][]byte, segmentLengths []int) int64 {
+	seen := map[[sha256.Size]byte]bool{}
+
+	var (
+		unique int64
+		ndx    int
+	)
+
+	for _, d := range dataBlocks {
+		for len(d) > 0 && ndx < len(segmentLengths) {
+			seg := d[:segmentLengths[ndx]]
+			ndx++
+
+			h := sha256.Sum256(seg)
+			if !seen[h] {
+				seen[h] = true
+				unique += int64(len(seg))
+			}
+
+			d = d[len(seg):]
+		}
+	}
+
+	return unique
+}
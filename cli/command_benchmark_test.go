@@ -6,6 +6,8 @@
 	"path/filepath"
 	"testing"
 
+	"github.com/stretchr/testify/require"
+
 	"github.com/kopia/kopia/internal/testutil"
 	"github.com/kopia/kopia/tests/testenv"
 )
@@ -19,6 +21,26 @@ func TestCommandBenchmarkCrypto(t *testing.T) {
 	e.RunAndExpectSuccess(t, "benchmark", "crypto", "--repeat=1", "--block-size=1KB", "--print-options")
 }
 
+func TestCommandBenchmarkCryptoWithPath(t *testing.T) {
+	t.Parallel()
+
+	runner := testenv.NewInProcRunner(t)
+	e := testenv.NewCLITest(t, testenv.RepoFormatNotImportant, runner)
+
+	dir := testutil.TempDirectory(t)
+	data := bytes.Repeat([]byte{1, 2, 3, 4, 5, 6, 7}, 10000)
+
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "file1"), data, 0o600))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "file2"), data, 0o600))
+
+	out := e.RunAndExpectSuccess(t, "benchmark", "crypto", "--repeat=1", "--block-size=16KB", "--path", dir)
+	require.Equal(t, "Sampled 140 KB from 2 files in "+dir, out[0])
+	require.Contains(t, out[len(out)-1], "Recommended options for this data: --block-hash=")
+	require.Contains(t, out[len(out)-1], " --object-splitter=DYNAMIC")
+
+	e.RunAndExpectFailure(t, "benchmark", "crypto", "--repeat=1", "--path", testutil.TempDirectory(t))
+}
+
 func TestCommandBenchmarkEncryption(t *testing.T) {
 	t.Parallel()
 
@@ -37,6 +59,26 @@ func TestCommandBenchmarkHashing(t *testing.T) {
 	e.RunAndExpectSuccess(t, "benchmark", "hashing", "--repeat=1", "--block-size=1KB", "--print-options")
 }
 
+func TestCommandBenchmarkHashingWithPath(t *testing.T) {
+	t.Parallel()
+
+	runner := testenv.NewInProcRunner(t)
+	e := testenv.NewCLITest(t, testenv.RepoFormatNotImportant, runner)
+
+	dir := testutil.TempDirectory(t)
+	require.NoError(t, os.MkdirAll(filepath.Join(dir, "subdir"), 0o700))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "file1"), bytes.Repeat([]byte{1, 2, 3}, 1000), 0o600))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "subdir", "file2"), bytes.Repeat([]byte{4, 5}, 1000), 0o600))
+
+	out := e.RunAndExpectSuccess(t, "benchmark", "hashing", "--repeat=1", "--block-size=1KB", "--path", dir)
+	require.Equal(t, "Sampled 5 blocks (5 KB) from 2 files in "+dir, out[0])
+
+	out = e.RunAndExpectSuccess(t, "benchmark", "hashing", "--repeat=1", "--block-size=1KB", "--path", dir, "--max-sample-size=2KB")
+	require.Equal(t, "Sampled 2 blocks (2 KB) from 1 files in "+dir, out[0])
+
+	e.RunAndExpectFailure(t, "benchmark", "hashing", "--repeat=1", "--path", testutil.TempDirectory(t))
+}
+
 func TestCommandBenchmarkSpliter(t *testing.T) {
 	t.Parallel()
 
@@ -46,6 +88,26 @@ func TestCommandBenchmarkSpliter(t *testing.T) {
 	e.RunAndExpectSuccess(t, "benchmark", "splitter", "--block-count=1", "--print-options")
 }
 
+func TestCommandBenchmarkSplitterWithDataDir(t *testing.T) {
+	t.Parallel()
+
+	runner := testenv.NewInProcRunner(t)
+	e := testenv.NewCLITest(t, testenv.RepoFormatNotImportant, runner)
+
+	dir := testutil.TempDirectory(t)
+	data := bytes.Repeat([]byte{1, 2, 3, 4, 5, 6, 7}, 10000)
+
+	// two identical files, so half of the data is expected to be deduplicated.
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "file1"), data, 0o600))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "file2"), data, 0o600))
+
+	out := e.RunAndExpectSuccess(t, "benchmark", "splitter", "--data-dir", dir, "--print-options")
+	require.Contains(t, out[0], "count:2 ")
+	require.Contains(t, out[0], "(50.0%)")
+
+	e.RunAndExpectFailure(t, "benchmark", "splitter", "--data-dir", testutil.TempDirectory(t))
+}
+
 func TestCommandBenchmarkCompression(t *testing.T) {
 	t.Parallel()
 
@@ -58,3 +120,13 @@ func TestCommandBenchmarkCompression(t *testing.T) {
 	e.RunAndExpectSuccess(t, "benchmark", "compression", "--data-file", testFile, "--repeat=2", "--verify-stable", "--print-options")
 	e.RunAndExpectSuccess(t, "benchmark", "compression", "--data-file", testFile, "--repeat=2", "--by-size")
 }
+
+func TestCommandBenchmarkKDF(t *testing.T) {
+	t.Parallel()
+
+	runner := testenv.NewInProcRunner(t)
+	e := testenv.NewCLITest(t, testenv.RepoFormatNotImportant, runner)
+
+	out := e.RunAndExpectSuccess(t, "benchmark", "kdf", "--max-memory=32MiB", "--iterations=1", "--parallelism=1", "--target-duration=1h")
+	require.Contains(t, out[len(out)-1], "--kdf=argon2id --kdf-argon2id-memory=32MiB --kdf-argon2id-iterations=1 --kdf-argon2id-parallelism=1")
+}
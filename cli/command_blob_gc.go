@@ -39,8 +39,18 @@ func (c *commandBlobGC) run(ctx context.Context, rep repo.DirectRepositoryWriter
 		Prefix:   blob.ID(c.prefix),
 	}
 
-	n, err := maintenance.DeleteUnreferencedBlobs(ctx, rep, opts, c.safety)
+	p, err := maintenance.GetParams(ctx, rep)
 	if err != nil {
+		return errors.Wrap(err, "unable to get maintenance params")
+	}
+
+	var n int
+
+	// apply the same limits as blob deletion performed as part of maintenance.
+	if err := maintenance.WithThrottling(ctx, p.Throttling.BlobDeletion, func(ctx context.Context) error {
+		n, err = maintenance.DeleteUnreferencedBlobs(ctx, rep, opts, c.safety)
+		return err
+	}); err != nil {
 		return errors.Wrap(err, "error deleting unreferenced blobs")
 	}
 
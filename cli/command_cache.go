@@ -5,6 +5,7 @@ type commandCache struct {
 	info     commandCacheInfo
 	prefetch commandCachePrefetch
 	set      commandCacheSetParams
+	stats    commandCacheStats
 	sync     commandCacheSync
 }
 
@@ -15,5 +16,6 @@ func (c *commandCache) setup(svc appServices, parent commandParent) {
 	c.info.setup(svc, cmd)
 	c.prefetch.setup(svc, cmd)
 	c.set.setup(svc, cmd)
+	c.stats.setup(svc, cmd)
 	c.sync.setup(svc, cmd)
 }
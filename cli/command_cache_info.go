@@ -9,6 +9,7 @@
 
 	"github.com/pkg/errors"
 
+	"github.com/kopia/kopia/internal/cache"
 	"github.com/kopia/kopia/internal/units"
 	"github.com/kopia/kopia/repo"
 	"github.com/kopia/kopia/repo/content"
@@ -65,6 +66,12 @@ func (c *commandCacheInfo) run(ctx context.Context, _ repo.Repository) error {
 		"server-contents": opts.MinContentSweepAge.DurationOrDefault(content.DefaultDataCacheSweepAge),
 	}
 
+	path2EvictionPolicy := map[string]string{
+		"contents":        opts.ContentCacheEvictionPolicy,
+		"metadata":        opts.MetadataCacheEvictionPolicy,
+		"server-contents": opts.ContentCacheEvictionPolicy,
+	}
+
 	for _, ent := range entries {
 		if !ent.IsDir() {
 			continue
@@ -88,10 +95,16 @@ func (c *commandCacheInfo) run(ctx context.Context, _ repo.Repository) error {
 				hardLimit = "none"
 			}
 
-			maybeLimit = fmt.Sprintf(" (soft limit: %v, hard limit: %v, min sweep age: %v)",
+			evictionPolicy := path2EvictionPolicy[ent.Name()]
+			if evictionPolicy == "" {
+				evictionPolicy = string(cache.DefaultEvictionPolicy)
+			}
+
+			maybeLimit = fmt.Sprintf(" (soft limit: %v, hard limit: %v, min sweep age: %v, eviction policy: %v)",
 				units.BytesString(l),
 				hardLimit,
-				path2SweepAgeSeconds[ent.Name()])
+				path2SweepAgeSeconds[ent.Name()],
+				evictionPolicy)
 		}
 
 		if ent.Name() == "blob-list" {
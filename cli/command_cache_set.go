@@ -7,6 +7,7 @@
 	"github.com/alecthomas/kingpin/v2"
 	"github.com/pkg/errors"
 
+	"github.com/kopia/kopia/internal/cache"
 	"github.com/kopia/kopia/internal/units"
 	"github.com/kopia/kopia/repo"
 	"github.com/kopia/kopia/repo/content"
@@ -23,6 +24,9 @@ type cacheSizeFlags struct {
 
 	maxListCacheDuration time.Duration
 	indexMinSweepAge     time.Duration
+
+	contentEvictionPolicy  string
+	metadataEvictionPolicy string
 }
 
 func (c *cacheSizeFlags) setup(cmd *kingpin.CmdClause) {
@@ -36,12 +40,15 @@ func (c *cacheSizeFlags) setup(cmd *kingpin.CmdClause) {
 	cmd.Flag("metadata-min-sweep-age", "Minimal age of metadata cache item to be subject to sweeping").DurationVar(&c.metadataMinSweepAge)
 	cmd.Flag("index-min-sweep-age", "Minimal age of index cache item to be subject to sweeping").DurationVar(&c.indexMinSweepAge)
 	cmd.Flag("max-list-cache-duration", "Duration of index cache").DurationVar(&c.maxListCacheDuration)
+	cmd.Flag("content-cache-eviction-policy", "Order in which items are removed from local content cache").EnumVar(&c.contentEvictionPolicy, cache.SupportedEvictionPolicies()...)
+	cmd.Flag("metadata-cache-eviction-policy", "Order in which items are removed from local metadata cache").EnumVar(&c.metadataEvictionPolicy, cache.SupportedEvictionPolicies()...)
 }
 
 type commandCacheSetParams struct {
 	directory string
 
 	cacheSizeFlags
+	sizeLimits cacheSizeLimitFlags
 
 	svc appServices
 }
@@ -58,6 +65,7 @@ func (c *commandCacheSetParams) setup(svc appServices, parent commandParent) {
 	c.metadataCacheSizeLimitMB = -1
 	c.metadataCacheSizeMB = -1
 	c.cacheSizeFlags.setup(cmd)
+	c.sizeLimits.setup(cmd)
 
 	cmd.Flag("cache-directory", "Directory where to store cache files").StringVar(&c.directory)
 
@@ -107,6 +115,13 @@ func (c *commandCacheSetParams) run(ctx context.Context, _ repo.RepositoryWriter
 		changed++
 	}
 
+	limits := opts.SizeLimits()
+	if err := c.sizeLimits.apply(ctx, &limits, &changed); err != nil {
+		return err
+	}
+
+	opts.SetSizeLimits(limits)
+
 	if v := c.maxListCacheDuration; v != -1 {
 		log(ctx).Infof("changing list cache duration to %v", v)
 		opts.MaxListCacheDuration = content.DurationSeconds(v.Seconds())
@@ -131,6 +146,18 @@ func (c *commandCacheSetParams) run(ctx context.Context, _ repo.RepositoryWriter
 		changed++
 	}
 
+	if v := c.contentEvictionPolicy; v != "" {
+		log(ctx).Infof("changing content cache eviction policy to %v", v)
+		opts.ContentCacheEvictionPolicy = v
+		changed++
+	}
+
+	if v := c.metadataEvictionPolicy; v != "" {
+		log(ctx).Infof("changing metadata cache eviction policy to %v", v)
+		opts.MetadataCacheEvictionPolicy = v
+		changed++
+	}
+
 	if changed == 0 {
 		return errors.Errorf("no changes")
 	}
@@ -46,6 +46,31 @@ func TestCacheSet(t *testing.T) {
 	require.Contains(t, mustGetLineContaining(t, out, "min sweep age: 24h0m0s"), "metadata")
 
 	require.Contains(t, mustGetLineContaining(t, out, "55s"), "blob-list")
+	require.Contains(t, mustGetLineContaining(t, out, "eviction policy: lru"), "contents")
+
+	env.RunAndExpectSuccess(t,
+		"cache", "set",
+		"--content-cache-eviction-policy=fifo",
+		"--metadata-cache-eviction-policy=cost-aware",
+	)
+
+	out = env.RunAndExpectSuccess(t, "cache", "info")
+	require.Contains(t, mustGetLineContaining(t, out, "eviction policy: fifo"), "contents")
+	require.Contains(t, mustGetLineContaining(t, out, "eviction policy: cost-aware"), "metadata")
+
+	env.RunAndExpectFailure(t, "cache", "set", "--content-cache-eviction-policy=random")
+
+	env.RunAndExpectSuccess(t,
+		"cache", "set",
+		"--content-max-size=1.5GB",
+		"--metadata-size-limit=500MB",
+	)
+
+	out = env.RunAndExpectSuccess(t, "cache", "info")
+	require.Contains(t, mustGetLineContaining(t, out, "soft limit: 1.5 GB"), "contents")
+	require.Contains(t, mustGetLineContaining(t, out, "hard limit: 500 MB"), "metadata")
+
+	env.RunAndExpectFailure(t, "cache", "set", "--content-max-size=lots")
 }
 
 func mustGetLineContaining(t *testing.T, lines []string, containing string) string {
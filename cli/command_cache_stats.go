@@ -0,0 +1,79 @@
+package cli
+
+import (
+	"context"
+	"sort"
+
+	"github.com/pkg/errors"
+
+	"github.com/kopia/kopia/internal/cache"
+	"github.com/kopia/kopia/internal/units"
+	"github.com/kopia/kopia/repo"
+)
+
+type commandCacheStats struct {
+	reset bool
+
+	svc appServices
+	out textOutput
+}
+
+func (c *commandCacheStats) setup(svc appServices, parent commandParent) {
+	cmd := parent.Command("stats", "Displays cache hit/miss statistics accumulated since they were last reset")
+	cmd.Flag("reset", "Reset accumulated statistics").BoolVar(&c.reset)
+	cmd.Action(svc.repositoryReaderAction(c.run))
+
+	c.svc = svc
+	c.out.setup(svc)
+}
+
+func (c *commandCacheStats) run(ctx context.Context, _ repo.Repository) error {
+	opts, err := repo.GetCachingOptions(ctx, c.svc.repositoryConfigFileName())
+	if err != nil {
+		return errors.Wrap(err, "error getting cache options")
+	}
+
+	if opts.CacheDirectory == "" {
+		return errors.New("caching is not enabled")
+	}
+
+	if c.reset {
+		return errors.Wrap(cache.ResetStats(opts.CacheDirectory), "error resetting cache statistics")
+	}
+
+	stats, err := cache.LoadStats(opts.CacheDirectory)
+	if err != nil {
+		return errors.Wrap(err, "error loading cache statistics")
+	}
+
+	if len(stats) == 0 {
+		c.out.printStderr("No cache statistics have been recorded yet.\n")
+		return nil
+	}
+
+	var names []string
+
+	for n := range stats {
+		names = append(names, n)
+	}
+
+	sort.Strings(names)
+
+	for _, n := range names {
+		s := stats[n]
+
+		c.out.printStdout("%v:\n", n)
+		c.out.printStdout("  hits:      %v (%v)\n", s.HitCount, units.BytesString(s.HitBytes))
+		c.out.printStdout("  misses:    %v (%v)\n", s.MissCount, units.BytesString(s.MissBytes))
+		c.out.printStdout("  hit ratio: %.1f%%\n", 100*s.HitRatio()) //nolint:mnd
+		c.out.printStdout("  evicted:   %v (%v)\n", s.EvictedCount, units.BytesString(s.EvictedBytes))
+
+		if errorCount := s.MissErrors + s.StoreErrors + s.MalformedCount; errorCount > 0 {
+			c.out.printStdout("  errors:    %v fetch, %v store, %v malformed\n", s.MissErrors, s.StoreErrors, s.MalformedCount)
+		}
+	}
+
+	c.out.printStderr("A low hit ratio with many evictions may indicate that the cache is too small, see 'kopia cache set'.\n")
+
+	return nil
+}
@@ -0,0 +1,27 @@
+package cli_test
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/kopia/kopia/internal/testutil"
+	"github.com/kopia/kopia/tests/testenv"
+)
+
+func TestCacheStats(t *testing.T) {
+	env := testenv.NewCLITest(t, testenv.RepoFormatNotImportant, testenv.NewInProcRunner(t))
+
+	env.RunAndExpectSuccess(t, "repo", "create", "filesystem", "--path", env.RepoDir)
+	env.RunAndExpectSuccess(t, "snapshot", "create", testutil.TempDirectory(t))
+	env.RunAndExpectSuccess(t, "snapshot", "list")
+
+	out := env.RunAndExpectSuccess(t, "cache", "stats")
+	require.Contains(t, out, "metadata:")
+	require.Contains(t, mustGetLineContaining(t, out, "hit ratio"), "%")
+
+	env.RunAndExpectSuccess(t, "cache", "stats", "--reset")
+
+	// only statistics of the 'cache stats --reset' command itself remain.
+	require.NotContains(t, env.RunAndExpectSuccess(t, "cache", "stats"), "metadata:")
+}
@@ -0,0 +1,143 @@
+package cli
+
+import (
+	"context"
+	"sort"
+	"text/template"
+
+	"github.com/alecthomas/kingpin/v2"
+	"github.com/pkg/errors"
+
+	"github.com/kopia/kopia/repo"
+	"github.com/kopia/kopia/snapshot"
+)
+
+// completion scripts invoke the binary with --completion-bash, which kingpin handles by printing
+// possible completions of the command line, including results of hint actions.
+//
+//nolint:gochecknoglobals
+var completionScriptTemplates = map[string]string{
+	"bash": `# bash completion for {{.Name}}
+_{{.Name}}_bash_autocomplete() {
+    local cur opts
+    COMPREPLY=()
+    cur="${COMP_WORDS[COMP_CWORD]}"
+    opts=$( ${COMP_WORDS[0]} --completion-bash "${COMP_WORDS[@]:1:$COMP_CWORD}" 2>/dev/null )
+    COMPREPLY=( $(compgen -W "${opts}" -- ${cur}) )
+    return 0
+}
+complete -F _{{.Name}}_bash_autocomplete -o default {{.Name}}
+`,
+	"zsh": `#compdef {{.Name}}
+
+_{{.Name}}() {
+    local matches=($(${words[1]} --completion-bash "${(@)words[2,$CURRENT]}" 2>/dev/null))
+    compadd -a matches
+
+    if [[ $compstate[nmatches] -eq 0 && $words[$CURRENT] != -* ]]; then
+        _files
+    fi
+}
+
+if [[ "$(basename -- ${(%):-%x})" != "_{{.Name}}" ]]; then
+    compdef _{{.Name}} {{.Name}}
+fi
+`,
+	"fish": `# fish completion for {{.Name}}
+function __complete_{{.Name}}
+    set -l tokens (commandline -opc)
+    {{.Name}} --completion-bash $tokens[2..-1] (commandline -ct) 2>/dev/null
+end
+
+complete -c {{.Name}} -a '(__complete_{{.Name}})'
+`,
+}
+
+type commandCompletion struct {
+	shell   string
+	appName string
+
+	out textOutput
+}
+
+func (c *commandCompletion) setup(svc appServices, app *kingpin.Application) {
+	cmd := app.Command("completion", "Generate shell completion script. For example, add 'source <(kopia completion bash)' to ~/.bashrc.")
+	cmd.Arg("shell", "Shell to generate completion script for").Required().EnumVar(&c.shell, "bash", "zsh", "fish")
+	cmd.Action(svc.noRepositoryAction(c.run))
+
+	c.appName = app.Name
+	c.out.setup(svc)
+}
+
+func (c *commandCompletion) run(_ context.Context) error {
+	t, err := template.New(c.shell).Parse(completionScriptTemplates[c.shell])
+	if err != nil {
+		return errors.Wrap(err, "invalid completion template")
+	}
+
+	return errors.Wrap(t.Execute(c.out.stdout(), struct{ Name string }{c.appName}), "error writing completion script")
+}
+
+// listSourcesForCompletion returns snapshot sources of the connected repository along with the current
+// user and host, or nil if the repository can't be opened without asking for a password.
+func (c *App) listSourcesForCompletion() (local snapshot.SourceInfo, sources []snapshot.SourceInfo) {
+	ctx := context.Background()
+
+	pass := c.password
+	if pass == "" {
+		p, err := c.passwordPersistenceStrategy().GetPassword(ctx, c.repositoryConfigFileName())
+		if err != nil {
+			return local, nil
+		}
+
+		pass = p
+	}
+
+	rep, err := repo.Open(ctx, c.repositoryConfigFileName(), pass, &repo.Options{DisableInternalLog: true})
+	if err != nil {
+		return local, nil
+	}
+
+	defer rep.Close(ctx) //nolint:errcheck
+
+	local = snapshot.SourceInfo{Host: rep.ClientOptions().Hostname, UserName: rep.ClientOptions().Username}
+
+	sources, err = snapshot.ListSources(ctx, rep)
+	if err != nil {
+		return local, nil
+	}
+
+	return local, sources
+}
+
+// snapshotSourceHints returns paths of snapshot sources of the current user and host for shell completion.
+func (c *App) snapshotSourceHints() []string {
+	var res []string
+
+	local, sources := c.listSourcesForCompletion()
+
+	for _, src := range sources {
+		if src.Host == local.Host && src.UserName == local.UserName && src.Path != "" {
+			res = append(res, src.Path)
+		}
+	}
+
+	sort.Strings(res)
+
+	return res
+}
+
+// policyTargetHints returns policy targets for shell completion.
+func (c *App) policyTargetHints() []string {
+	var res []string
+
+	_, sources := c.listSourcesForCompletion()
+
+	for _, src := range sources {
+		res = append(res, src.String())
+	}
+
+	sort.Strings(res)
+
+	return res
+}
@@ -0,0 +1,27 @@
+package cli_test
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/kopia/kopia/tests/testenv"
+)
+
+func TestCompletion(t *testing.T) {
+	env := testenv.NewCLITest(t, testenv.RepoFormatNotImportant, testenv.NewInProcRunner(t))
+
+	bash := strings.Join(env.RunAndExpectSuccess(t, "completion", "bash"), "\n")
+	require.Contains(t, bash, "--completion-bash")
+	require.Regexp(t, `complete -F _\w+_bash_autocomplete -o default`, bash)
+
+	zsh := strings.Join(env.RunAndExpectSuccess(t, "completion", "zsh"), "\n")
+	require.Contains(t, zsh, "#compdef ")
+
+	fish := strings.Join(env.RunAndExpectSuccess(t, "completion", "fish"), "\n")
+	require.Regexp(t, `complete -c \w+ -a '\(__complete_\w+\)'`, fish)
+
+	env.RunAndExpectFailure(t, "completion", "powershell")
+	env.RunAndExpectFailure(t, "completion")
+}
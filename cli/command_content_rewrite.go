@@ -48,16 +48,24 @@ func (c *commandContentRewrite) runContentRewriteCommand(ctx context.Context, re
 		return err
 	}
 
+	p, err := maintenance.GetParams(ctx, rep)
+	if err != nil {
+		return errors.Wrap(err, "unable to get maintenance params")
+	}
+
+	// apply the same limits as content rewrite performed as part of maintenance.
 	//nolint:wrapcheck
-	return maintenance.RewriteContents(ctx, rep, &maintenance.RewriteContentsOptions{
-		ContentIDRange: c.contentRange.contentIDRange(),
-		ContentIDs:     contentIDs,
-		FormatVersion:  c.contentRewriteFormatVersion,
-		PackPrefix:     blob.ID(c.contentRewritePackPrefix),
-		Parallel:       c.contentRewriteParallelism,
-		ShortPacks:     c.contentRewriteShortPacks,
-		DryRun:         c.contentRewriteDryRun,
-	}, c.contentRewriteSafety)
+	return maintenance.WithThrottling(ctx, p.Throttling.ContentRewrite, func(ctx context.Context) error {
+		return maintenance.RewriteContents(ctx, rep, &maintenance.RewriteContentsOptions{
+			ContentIDRange: c.contentRange.contentIDRange(),
+			ContentIDs:     contentIDs,
+			FormatVersion:  c.contentRewriteFormatVersion,
+			PackPrefix:     blob.ID(c.contentRewritePackPrefix),
+			Parallel:       c.contentRewriteParallelism,
+			ShortPacks:     c.contentRewriteShortPacks,
+			DryRun:         c.contentRewriteDryRun,
+		}, c.contentRewriteSafety)
+	})
 }
 
 func toContentIDs(s []string) ([]content.ID, error) {
@@ -13,6 +13,7 @@
 	"github.com/kopia/kopia/repo"
 	"github.com/kopia/kopia/repo/blob"
 	"github.com/kopia/kopia/repo/content"
+	"github.com/kopia/kopia/repo/content/index"
 )
 
 type commandContentVerify struct {
@@ -21,8 +22,11 @@ type commandContentVerify struct {
 	contentVerifyIncludeDeleted bool
 	contentVerifyPercent        float64
 	progressInterval            time.Duration
+	checkpointFile              string
 
 	contentRange contentRangeFlags
+
+	jo jsonOutput
 }
 
 func (c *commandContentVerify) setup(svc appServices, parent commandParent) {
@@ -33,30 +37,60 @@ func (c *commandContentVerify) setup(svc appServices, parent commandParent) {
 	cmd.Flag("include-deleted", "Include deleted contents").BoolVar(&c.contentVerifyIncludeDeleted)
 	cmd.Flag("download-percent", "Download a percentage of files [0.0 .. 100.0]").Float64Var(&c.contentVerifyPercent)
 	cmd.Flag("progress-interval", "Progress output interval").Default("3s").DurationVar(&c.progressInterval)
+	cmd.Flag("checkpoint-file", "Record progress in the provided file and resume interrupted verification from it").StringVar(&c.checkpointFile)
 	c.contentRange.setup(cmd)
+	c.jo.setup(svc, cmd)
 	cmd.Action(svc.directRepositoryReadAction(c.run))
 }
 
+// contentVerifyProgress is the structured progress of content verification emitted with --json.
+type contentVerifyProgress struct {
+	Verified         int64      `json:"verified"`
+	Total            int64      `json:"total,omitempty"`
+	Errors           int64      `json:"errors"`
+	PercentComplete  float64    `json:"percentComplete,omitempty"`
+	EstimatedEndTime *time.Time `json:"estimatedEndTime,omitempty"`
+	Finished         bool       `json:"finished,omitempty"`
+}
+
+// contentVerifyCheckpoint records the content ID prefix below which all contents have been verified,
+// so that verification can be resumed after interruption.
+type contentVerifyCheckpoint struct {
+	Range           content.IDRange  `json:"range"`
+	IncludeDeleted  bool             `json:"includeDeleted"`
+	DownloadPercent float64          `json:"downloadPercent"`
+	VerifiedBelow   content.IDPrefix `json:"verifiedBelow,omitempty"`
+	Verified        int64            `json:"verified"`
+	Errors          int64            `json:"errors"`
+}
+
+//nolint:funlen
 func (c *commandContentVerify) run(ctx context.Context, rep repo.DirectRepository) error {
-	blobMap := map[blob.ID]blob.Metadata{}
 	downloadPercent := c.contentVerifyPercent
 
 	if c.contentVerifyFull {
 		downloadPercent = 100.0
 	}
 
+	cp, err := c.loadCheckpoint(downloadPercent)
+	if err != nil {
+		return err
+	}
+
 	blobMap, err := blob.ReadBlobMap(ctx, rep.BlobReader())
 	if err != nil {
 		return errors.Wrap(err, "unable to read blob map")
 	}
 
 	var (
-		verifiedCount atomic.Int32
-		successCount  atomic.Int32
-		errorCount    atomic.Int32
-		totalCount    atomic.Int32
+		verifiedCount atomic.Int64
+		errorCount    atomic.Int64
+		totalCount    atomic.Int64
 	)
 
+	verifiedCount.Store(cp.Verified)
+	errorCount.Store(cp.Errors)
+
 	subctx, cancel := context.WithCancel(ctx)
 
 	var wg sync.WaitGroup
@@ -75,60 +109,293 @@ func (c *commandContentVerify) run(ctx context.Context, rep repo.DirectRepositor
 		c.getTotalContentCount(subctx, rep, &totalCount)
 	}()
 
-	log(ctx).Info("Verifying all contents...")
+	if cp.VerifiedBelow != "" {
+		log(ctx).Infof("Resuming verification of contents, %v already verified...", cp.Verified)
+	} else {
+		log(ctx).Info("Verifying all contents...")
+	}
 
 	rep.DisableIndexRefresh()
 
 	throttle := new(timetrack.Throttle)
 	est := timetrack.Start()
 
-	if err := rep.ContentReader().IterateContents(ctx, content.IterateOptions{
-		Range:          c.contentRange.contentIDRange(),
-		Parallel:       c.contentVerifyParallel,
+	parallel := max(1, c.contentVerifyParallel)
+	tracker := newContentVerifyTracker(cp.Range)
+	workch := make(chan content.Info, parallel)
+
+	var workersWG sync.WaitGroup
+
+	for range parallel {
+		workersWG.Add(1)
+
+		go func() {
+			defer workersWG.Done()
+
+			for ci := range workch {
+				if ctx.Err() != nil {
+					// leave the content unverified, so that it's verified again when resuming.
+					continue
+				}
+
+				err := c.contentVerify(ctx, rep.ContentReader(), ci, blobMap, downloadPercent)
+				if err != nil {
+					log(ctx).Errorf("error %v", err)
+					errorCount.Add(1)
+				}
+
+				verifiedCount.Add(1)
+				tracker.completed(ci.ContentID, err != nil)
+
+				if throttle.ShouldOutput(c.progressInterval) {
+					// estimate based on contents verified by this invocation.
+					timings, ok := est.Estimate(float64(verifiedCount.Load()-cp.Verified), float64(totalCount.Load()-cp.Verified))
+					c.outputProgress(ctx, verifiedCount.Load(), totalCount.Load(), errorCount.Load(), timings, ok)
+				}
+			}
+		}()
+	}
+
+	remaining := cp.Range
+	if cp.VerifiedBelow > remaining.StartID {
+		remaining.StartID = cp.VerifiedBelow
+	}
+
+	// contents are dispatched to workers in the order of their IDs, which allows checkpointing
+	// each time the iteration moves on to the next prefix range.
+	iterErr := rep.ContentReader().IterateContents(ctx, content.IterateOptions{
+		Range:          remaining,
 		IncludeDeleted: c.contentVerifyIncludeDeleted,
 	}, func(ci content.Info) error {
-		if err := c.contentVerify(ctx, rep.ContentReader(), ci, blobMap, downloadPercent); err != nil {
-			log(ctx).Errorf("error %v", err)
-			errorCount.Add(1)
-		} else {
-			successCount.Add(1)
+		if err := ctx.Err(); err != nil {
+			return errors.Wrap(err, "context error")
 		}
 
-		verifiedCount.Add(1)
-
-		if throttle.ShouldOutput(c.progressInterval) {
-			timings, ok := est.Estimate(float64(verifiedCount.Load()), float64(totalCount.Load()))
-			if ok {
-				log(ctx).Infof("  Verified %v of %v contents (%.1f%%), %v errors, remaining %v, ETA %v",
-					verifiedCount.Load(),
-					totalCount.Load(),
-					timings.PercentComplete,
-					errorCount.Load(),
-					timings.Remaining,
-					formatTimestamp(timings.EstimatedEndTime),
-				)
-			} else {
-				log(ctx).Infof("  Verified %v contents, %v errors, estimating...", verifiedCount.Load(), errorCount.Load())
+		if tracker.dispatched(ci.ContentID) {
+			if err := c.saveCheckpoint(cp, tracker); err != nil {
+				return err
 			}
 		}
 
+		workch <- ci
+
 		return nil
-	}); err != nil {
-		return errors.Wrap(err, "iterate contents")
+	})
+
+	close(workch)
+	workersWG.Wait()
+
+	if iterErr != nil {
+		if err := c.saveCheckpoint(cp, tracker); err != nil {
+			log(ctx).Errorf("%v", err)
+		}
+
+		return errors.Wrap(iterErr, "iterate contents")
 	}
 
 	log(ctx).Infof("Finished verifying %v contents, found %v errors.", verifiedCount.Load(), errorCount.Load())
 
+	if c.jo.jsonOutput {
+		c.jo.printJSON(contentVerifyProgress{
+			Verified:        verifiedCount.Load(),
+			Total:           verifiedCount.Load(),
+			Errors:          errorCount.Load(),
+			PercentComplete: 100, //nolint:mnd
+			Finished:        true,
+		})
+	}
+
+	if err := removeVerifyCheckpoint(c.checkpointFile); err != nil {
+		return err
+	}
+
 	ec := errorCount.Load()
 	if ec == 0 {
 		return nil
 	}
 
-	return errors.Errorf("encountered %v errors", ec)
+	return withExitCode(ExitCodeVerificationFailed, errors.Errorf("encountered %v errors", ec))
+}
+
+func (c *commandContentVerify) outputProgress(ctx context.Context, verified, total, errorCount int64, timings timetrack.Timings, ok bool) {
+	if c.jo.jsonOutput {
+		p := contentVerifyProgress{
+			Verified: verified,
+			Total:    total,
+			Errors:   errorCount,
+		}
+
+		if ok {
+			p.PercentComplete = 100 * float64(verified) / float64(total) //nolint:mnd
+			p.EstimatedEndTime = &timings.EstimatedEndTime
+		}
+
+		c.jo.printJSON(p)
+
+		return
+	}
+
+	if ok {
+		log(ctx).Infof("  Verified %v of %v contents (%.1f%%), %v errors, remaining %v, ETA %v",
+			verified,
+			total,
+			100*float64(verified)/float64(total), //nolint:mnd
+			errorCount,
+			timings.Remaining,
+			formatTimestamp(timings.EstimatedEndTime),
+		)
+	} else {
+		log(ctx).Infof("  Verified %v contents, %v errors, estimating...", verified, errorCount)
+	}
+}
+
+// contentVerifyTracker tracks verification of contents, which are dispatched in the order of their IDs,
+// grouped into prefix ranges, to determine the prefix below which all contents have been verified.
+// Prefix ranges of a prefix range are identified by the two characters following the prefix,
+// of other ranges by the first two characters of content IDs.
+type contentVerifyTracker struct {
+	prefixLength int
+
+	mu sync.Mutex
+	// +checklocks:mu
+	current content.IDPrefix // prefix range of the most recently dispatched content
+	// +checklocks:mu
+	ranges map[content.IDPrefix]*contentVerifyRangeState
+}
+
+type contentVerifyRangeState struct {
+	pending  int
+	verified int64
+	errors   int64
+}
+
+func (t *contentVerifyTracker) prefixOf(id content.ID) content.IDPrefix {
+	s := id.String()
+
+	return content.IDPrefix(s[:min(len(s), t.prefixLength)])
+}
+
+// dispatched records the content as being verified and returns true if it's the first content
+// of the next prefix range.
+func (t *contentVerifyTracker) dispatched(id content.ID) bool {
+	p := t.prefixOf(id)
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	st := t.ranges[p]
+	if st == nil {
+		st = &contentVerifyRangeState{}
+		t.ranges[p] = st
+	}
+
+	st.pending++
+
+	if p == t.current {
+		return false
+	}
+
+	t.current = p
+
+	return true
+}
+
+// completed records the content as verified.
+func (t *contentVerifyTracker) completed(id content.ID, failed bool) {
+	p := t.prefixOf(id)
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	st := t.ranges[p]
+	st.pending--
+	st.verified++
+
+	if failed {
+		st.errors++
+	}
+}
+
+// collectVerified returns the prefix below which all contents have been verified and the number of contents
+// and errors in prefix ranges which have been fully verified since the previous call.
+func (t *contentVerifyTracker) collectVerified() (below content.IDPrefix, verified, errorCount int64) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	// ranges before the current one have been fully dispatched.
+	below = t.current
+
+	for p, st := range t.ranges {
+		if st.pending > 0 && p < below {
+			below = p
+		}
+	}
+
+	for p, st := range t.ranges {
+		if p < below {
+			verified += st.verified
+			errorCount += st.errors
+
+			delete(t.ranges, p)
+		}
+	}
+
+	return below, verified, errorCount
+}
+
+func newContentVerifyTracker(r content.IDRange) *contentVerifyTracker {
+	var base content.IDPrefix
+
+	if r == index.PrefixRange(r.StartID) {
+		base = r.StartID
+	}
+
+	return &contentVerifyTracker{
+		prefixLength: len(base) + 2, //nolint:mnd
+		ranges:       map[content.IDPrefix]*contentVerifyRangeState{},
+	}
+}
+
+func (c *commandContentVerify) loadCheckpoint(downloadPercent float64) (*contentVerifyCheckpoint, error) {
+	cp := &contentVerifyCheckpoint{
+		Range:           c.contentRange.contentIDRange(),
+		IncludeDeleted:  c.contentVerifyIncludeDeleted,
+		DownloadPercent: downloadPercent,
+	}
+
+	var existing contentVerifyCheckpoint
+
+	found, err := loadVerifyCheckpoint(c.checkpointFile, &existing)
+	if err != nil || !found {
+		return cp, err
+	}
+
+	if existing.Range != cp.Range || existing.IncludeDeleted != cp.IncludeDeleted || existing.DownloadPercent != cp.DownloadPercent {
+		return nil, errors.New("checkpoint file was created with different verification parameters")
+	}
+
+	return &existing, nil
+}
+
+// saveCheckpoint advances the checkpoint to the prefix below which all contents have been verified
+// and saves it if it has changed.
+func (c *commandContentVerify) saveCheckpoint(cp *contentVerifyCheckpoint, tracker *contentVerifyTracker) error {
+	below, verified, errorCount := tracker.collectVerified()
+
+	cp.Verified += verified
+	cp.Errors += errorCount
+
+	if below <= cp.VerifiedBelow {
+		return nil
+	}
+
+	cp.VerifiedBelow = below
+
+	return saveVerifyCheckpoint(c.checkpointFile, cp)
 }
 
-func (c *commandContentVerify) getTotalContentCount(ctx context.Context, rep repo.DirectRepository, totalCount *atomic.Int32) {
-	var tc int32
+func (c *commandContentVerify) getTotalContentCount(ctx context.Context, rep repo.DirectRepository, totalCount *atomic.Int64) {
+	var tc int64
 
 	if err := rep.ContentReader().IterateContents(ctx, content.IterateOptions{
 		Range:          c.contentRange.contentIDRange(),
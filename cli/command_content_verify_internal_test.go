@@ -0,0 +1,57 @@
+package cli
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/kopia/kopia/repo/content"
+	"github.com/kopia/kopia/repo/content/index"
+)
+
+func TestContentVerifyTracker(t *testing.T) {
+	id := func(s string) content.ID {
+		t.Helper()
+
+		cid, err := index.ParseID(s)
+		require.NoError(t, err)
+
+		return cid
+	}
+
+	tr := newContentVerifyTracker(index.AllIDs)
+
+	require.True(t, tr.dispatched(id("0011")))
+	require.False(t, tr.dispatched(id("0022")))
+	require.True(t, tr.dispatched(id("1111")))
+
+	// contents of the first prefix range are still being verified.
+	below, verified, errorCount := tr.collectVerified()
+	require.Equal(t, content.IDPrefix("00"), below)
+	require.Zero(t, verified)
+	require.Zero(t, errorCount)
+
+	tr.completed(id("0022"), true)
+	tr.completed(id("1111"), false)
+	require.True(t, tr.dispatched(id("k2222")))
+
+	below, _, _ = tr.collectVerified()
+	require.Equal(t, content.IDPrefix("00"), below)
+
+	tr.completed(id("0011"), false)
+
+	// the most recent prefix range may have more contents to be dispatched.
+	below, verified, errorCount = tr.collectVerified()
+	require.Equal(t, content.IDPrefix("k2"), below)
+	require.EqualValues(t, 3, verified)
+	require.EqualValues(t, 1, errorCount)
+
+	// prefix ranges of a prefix range are identified by the following two characters.
+	tr = newContentVerifyTracker(index.PrefixRange("k"))
+	require.True(t, tr.dispatched(id("k1234")))
+	require.False(t, tr.dispatched(id("k1299")))
+	require.True(t, tr.dispatched(id("k1334")))
+
+	below, _, _ = tr.collectVerified()
+	require.Equal(t, content.IDPrefix("k12"), below)
+}
@@ -2,6 +2,7 @@
 
 import (
 	"bytes"
+	"encoding/json"
 	"os"
 	"path/filepath"
 	"strings"
@@ -24,6 +25,36 @@ func (s *formatSpecificTestSuite) TestContentVerify(t *testing.T) {
 	env.RunAndExpectSuccess(t, "snapshot", "create", dir)
 	env.RunAndExpectSuccess(t, "content", "verify", "--download-percent=30")
 
+	cpFile := filepath.Join(testutil.TempDirectory(t), "checkpoint.json")
+
+	progress := lastContentVerifyProgress(t, env.RunAndExpectSuccess(t, "content", "verify", "--json", "--checkpoint-file", cpFile))
+	require.True(t, progress.Finished)
+	require.Positive(t, progress.Verified)
+	require.Zero(t, progress.Errors)
+	require.NoFileExists(t, cpFile)
+
+	// resume from a checkpoint where all contents have been verified.
+	writeContentVerifyCheckpoint(t, cpFile, map[string]any{
+		"range":           map[string]string{"StartID": "", "EndID": "{"},
+		"downloadPercent": 0,
+		"verifiedBelow":   "{",
+		"verified":        12345,
+		"errors":          0,
+	})
+
+	progress = lastContentVerifyProgress(t, env.RunAndExpectSuccess(t, "content", "verify", "--json", "--checkpoint-file", cpFile))
+	require.True(t, progress.Finished)
+	require.EqualValues(t, 12345, progress.Verified)
+	require.NoFileExists(t, cpFile)
+
+	// checkpoint created with different parameters can't be resumed.
+	writeContentVerifyCheckpoint(t, cpFile, map[string]any{
+		"range":           map[string]string{"StartID": "", "EndID": "{"},
+		"downloadPercent": 100,
+	})
+
+	env.RunAndExpectFailure(t, "content", "verify", "--checkpoint-file", cpFile)
+
 	// delete one of 'p' blobs.
 	blobIDToDelete := strings.Split(env.RunAndExpectSuccess(t, "blob", "list", "--prefix=p")[0], " ")[0]
 	blobList := env.RunAndExpectSuccess(t, "blob", "list")
@@ -38,3 +69,29 @@ func (s *formatSpecificTestSuite) TestContentVerify(t *testing.T) {
 
 	env.RunAndExpectFailure(t, "content", "verify", "--full")
 }
+
+type contentVerifyProgress struct {
+	Verified int64 `json:"verified"`
+	Errors   int64 `json:"errors"`
+	Finished bool  `json:"finished"`
+}
+
+func lastContentVerifyProgress(t *testing.T, lines []string) contentVerifyProgress {
+	t.Helper()
+
+	require.NotEmpty(t, lines)
+
+	var p contentVerifyProgress
+
+	require.NoError(t, json.Unmarshal([]byte(lines[len(lines)-1]), &p))
+
+	return p
+}
+
+func writeContentVerifyCheckpoint(t *testing.T, fname string, cp map[string]any) {
+	t.Helper()
+
+	b, err := json.Marshal(cp)
+	require.NoError(t, err)
+	require.NoError(t, os.WriteFile(fname, b, 0o600))
+}
@@ -0,0 +1,17 @@
+package cli
+
+import "github.com/alecthomas/kingpin/v2"
+
+type commandDebug struct {
+	dumpConfig commandDebugDumpConfig
+	memory     commandDebugMemory
+	pprof      commandDebugPprof
+}
+
+func (c *commandDebug) setup(svc appServices, app *kingpin.Application) {
+	cmd := app.Command("debug", "Commands to help debugging Kopia.")
+
+	c.dumpConfig.setup(svc, app, cmd)
+	c.memory.setup(svc, cmd)
+	c.pprof.setup(svc, cmd)
+}
@@ -0,0 +1,155 @@
+package cli
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"reflect"
+	"regexp"
+	"sort"
+
+	"github.com/alecthomas/kingpin/v2"
+	"github.com/pkg/errors"
+
+	"github.com/kopia/kopia/internal/scrubber"
+	"github.com/kopia/kopia/repo"
+)
+
+// sources of flag values reported by 'kopia debug dump-config'.
+const (
+	flagSourceDefault = "default"
+	flagSourceEnv     = "env"
+	flagSourceFlag    = "flag"
+)
+
+// redactedValue replaces values of credentials, regardless of their length.
+const redactedValue = "<redacted>"
+
+// sensitiveFlagNameRegexp matches names of flags and environment variables, whose values must not be displayed.
+var sensitiveFlagNameRegexp = regexp.MustCompile(`(?i)(password|secret|token|credential)`) //nolint:gochecknoglobals
+
+// flags provided by kingpin, which are not part of the configuration.
+//
+//nolint:gochecknoglobals
+var builtinFlagNames = map[string]bool{
+	"help":                   true,
+	"help-long":              true,
+	"help-man":               true,
+	"version":                true,
+	"completion-bash":        true,
+	"completion-script-bash": true,
+	"completion-script-zsh":  true,
+}
+
+// DumpedConfig is the effective configuration displayed by 'kopia debug dump-config'.
+type DumpedConfig struct {
+	ConfigFile string            `json:"configFile"`
+	Repository *repo.LocalConfig `json:"repository,omitempty"`
+	Flags      []DumpedFlag      `json:"flags"`
+}
+
+// DumpedFlag describes the effective value of a global flag and where it came from.
+type DumpedFlag struct {
+	Name     string `json:"name"`
+	Value    string `json:"value"`
+	Source   string `json:"source"`
+	Envar    string `json:"envar,omitempty"`
+	Redacted bool   `json:"redacted,omitempty"`
+}
+
+type commandDebugDumpConfig struct {
+	app *kingpin.Application
+	svc appServices
+	out textOutput
+
+	// flags explicitly provided on the command line.
+	explicitFlags map[string]bool
+}
+
+func (c *commandDebugDumpConfig) setup(svc appServices, app *kingpin.Application, parent commandParent) {
+	cmd := parent.Command("dump-config", "Print the effective configuration (config file, environment and flags) in JSON, with credentials redacted.")
+
+	act := svc.noRepositoryAction(c.run)
+
+	cmd.Action(func(pc *kingpin.ParseContext) error {
+		c.explicitFlags = map[string]bool{}
+
+		for _, el := range pc.Elements {
+			if fc, ok := el.Clause.(*kingpin.FlagClause); ok {
+				c.explicitFlags[fc.Model().Name] = true
+			}
+		}
+
+		return act(pc)
+	})
+
+	c.app = app
+	c.svc = svc
+	c.out.setup(svc)
+}
+
+func (c *commandDebugDumpConfig) run(_ context.Context) error {
+	dc := DumpedConfig{
+		ConfigFile: c.svc.repositoryConfigFileName(),
+		Flags:      c.dumpFlags(),
+	}
+
+	lc, err := repo.LoadConfigFromFile(dc.ConfigFile)
+
+	switch {
+	case err == nil:
+		dc.Repository = scrubber.RedactSensitiveData(reflect.ValueOf(lc), redactedValue).Interface().(*repo.LocalConfig) //nolint:forcetypeassert
+
+	case errors.Is(err, os.ErrNotExist):
+		// not connected, only report flags.
+
+	default:
+		return errors.Wrap(err, "unable to load configuration file")
+	}
+
+	b, err := json.MarshalIndent(dc, "", "  ")
+	if err != nil {
+		return errors.Wrap(err, "unable to serialize configuration")
+	}
+
+	c.out.printStdout("%s\n", b)
+
+	return nil
+}
+
+func (c *commandDebugDumpConfig) dumpFlags() []DumpedFlag {
+	var res []DumpedFlag
+
+	for _, f := range c.app.Model().Flags {
+		if builtinFlagNames[f.Name] {
+			continue
+		}
+
+		df := DumpedFlag{
+			Name:   f.Name,
+			Value:  f.String(),
+			Source: flagSourceDefault,
+			Envar:  f.Envar,
+		}
+
+		switch {
+		case c.explicitFlags[f.Name]:
+			df.Source = flagSourceFlag
+		case f.Envar != "" && c.svc.getPrefixedEnv(f.Envar) != "":
+			df.Source = flagSourceEnv
+		}
+
+		if df.Value != "" && (sensitiveFlagNameRegexp.MatchString(f.Name) || sensitiveFlagNameRegexp.MatchString(f.Envar)) {
+			df.Value = redactedValue
+			df.Redacted = true
+		}
+
+		res = append(res, df)
+	}
+
+	sort.Slice(res, func(i, j int) bool {
+		return res[i].Name < res[j].Name
+	})
+
+	return res
+}
@@ -0,0 +1,59 @@
+package cli_test
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/kopia/kopia/cli"
+	"github.com/kopia/kopia/tests/testenv"
+)
+
+func TestDebugDumpConfig(t *testing.T) {
+	env := testenv.NewCLITest(t, testenv.RepoFormatNotImportant, testenv.NewInProcRunner(t))
+
+	var dc cli.DumpedConfig
+
+	require.NoError(t, json.Unmarshal([]byte(strings.Join(env.RunAndExpectSuccess(t, "debug", "dump-config"), "\n")), &dc))
+	require.Nil(t, dc.Repository)
+
+	env.RunAndExpectSuccess(t, "repo", "create", "filesystem", "--path", env.RepoDir)
+
+	out := strings.Join(env.RunAndExpectSuccess(t, "debug", "dump-config", "--progress-update-interval=1s"), "\n")
+	require.NotContains(t, out, env.Environment["KOPIA_PASSWORD"])
+
+	dc = cli.DumpedConfig{}
+
+	require.NoError(t, json.Unmarshal([]byte(out), &dc))
+	require.NotNil(t, dc.Repository)
+	require.NotNil(t, dc.Repository.Storage)
+	require.Equal(t, "filesystem", dc.Repository.Storage.Type)
+
+	flags := map[string]cli.DumpedFlag{}
+	for _, f := range dc.Flags {
+		flags[f.Name] = f
+	}
+
+	require.Equal(t, "1s", flags["progress-update-interval"].Value)
+	require.Equal(t, "flag", flags["progress-update-interval"].Source)
+	require.Equal(t, "default", flags["progress-format"].Source)
+	require.True(t, flags["password"].Redacted)
+	require.Equal(t, "<redacted>", flags["password"].Value)
+	require.NotContains(t, flags, "help")
+
+	// redacted values do not reveal the length of credentials.
+	for _, password := range []string{"x", strings.Repeat("long-password", 10)} {
+		dc = cli.DumpedConfig{}
+
+		require.NoError(t, json.Unmarshal([]byte(strings.Join(env.RunAndExpectSuccess(t, "debug", "dump-config", "--password", password), "\n")), &dc))
+
+		for _, f := range dc.Flags {
+			if f.Name == "password" {
+				require.True(t, f.Redacted)
+				require.Equal(t, "<redacted>", f.Value)
+			}
+		}
+	}
+}
@@ -0,0 +1,262 @@
+package cli
+
+import (
+	"context"
+	"math"
+	"os"
+	"path/filepath"
+	"runtime"
+	"runtime/debug"
+	"sort"
+	"strings"
+
+	"github.com/pkg/errors"
+
+	"github.com/kopia/kopia/internal/gather"
+	"github.com/kopia/kopia/internal/metrics"
+	"github.com/kopia/kopia/internal/units"
+	"github.com/kopia/kopia/repo"
+)
+
+type commandDebugMemory struct {
+	svc appServices
+	jo  jsonOutput
+	out textOutput
+}
+
+// MemoryStats describes memory used by kopia, displayed by 'kopia debug memory'.
+type MemoryStats struct {
+	Runtime    RuntimeMemoryStats      `json:"runtime"`
+	Allocators []gather.AllocatorStats `json:"allocators"`
+	Caches     []CacheMemoryStats      `json:"caches,omitempty"`
+	Index      *IndexMemoryStats       `json:"index,omitempty"`
+}
+
+// RuntimeMemoryStats describes memory used by the Go runtime.
+type RuntimeMemoryStats struct {
+	MemoryLimit  int64  `json:"memoryLimit"` // GOMEMLIMIT, math.MaxInt64 when not set
+	HeapAlloc    uint64 `json:"heapAlloc"`
+	HeapInuse    uint64 `json:"heapInuse"`
+	HeapIdle     uint64 `json:"heapIdle"`
+	HeapReleased uint64 `json:"heapReleased"`
+	HeapSys      uint64 `json:"heapSys"`
+	StackInuse   uint64 `json:"stackInuse"`
+	Sys          uint64 `json:"sys"`
+	NumGC        uint32 `json:"numGC"`
+	Goroutines   int    `json:"goroutines"`
+}
+
+// CacheMemoryStats describes the size of a persistent cache and its hit rate in the current process.
+type CacheMemoryStats struct {
+	Name       string  `json:"name"`
+	Files      int     `json:"files"`
+	TotalBytes int64   `json:"totalBytes"`
+	Hits       int64   `json:"hits"`
+	HitBytes   int64   `json:"hitBytes"`
+	Misses     int64   `json:"misses"`
+	MissBytes  int64   `json:"missBytes"`
+	HitRatio   float64 `json:"hitRatio"`
+}
+
+// IndexMemoryStats describes the index blobs, which are memory-mapped by the repository.
+type IndexMemoryStats struct {
+	Blobs      int   `json:"blobs"`
+	TotalBytes int64 `json:"totalBytes"`
+}
+
+func (c *commandDebugMemory) setup(svc appServices, parent commandParent) {
+	cmd := parent.Command("memory", "Show memory usage statistics of allocators, caches, indexes and the Go runtime.")
+	cmd.Action(svc.maybeRepositoryAction(c.run, repositoryAccessMode{disableMaintenance: true}))
+
+	c.svc = svc
+	c.jo.setup(svc, cmd)
+	c.out.setup(svc)
+}
+
+func (c *commandDebugMemory) run(ctx context.Context, rep repo.Repository) error {
+	st := MemoryStats{
+		Runtime:    getRuntimeMemoryStats(),
+		Allocators: gather.GetAllocatorStats(),
+	}
+
+	if rep != nil {
+		caches, err := c.getCacheStats(ctx, rep)
+		if err != nil {
+			return err
+		}
+
+		st.Caches = caches
+
+		if dr, ok := rep.(repo.DirectRepository); ok {
+			ibm, err := dr.IndexBlobs(ctx, false)
+			if err != nil {
+				return errors.Wrap(err, "unable to list index blobs")
+			}
+
+			st.Index = &IndexMemoryStats{}
+
+			for _, im := range ibm {
+				st.Index.Blobs++
+				st.Index.TotalBytes += im.Length
+			}
+		}
+	}
+
+	if c.jo.jsonOutput {
+		c.jo.printJSON(st)
+		return nil
+	}
+
+	c.printMemoryStats(&st)
+
+	return nil
+}
+
+func getRuntimeMemoryStats() RuntimeMemoryStats {
+	var ms runtime.MemStats
+
+	runtime.ReadMemStats(&ms)
+
+	return RuntimeMemoryStats{
+		// negative input returns the current limit without changing it.
+		MemoryLimit:  debug.SetMemoryLimit(-1),
+		HeapAlloc:    ms.HeapAlloc,
+		HeapInuse:    ms.HeapInuse,
+		HeapIdle:     ms.HeapIdle,
+		HeapReleased: ms.HeapReleased,
+		HeapSys:      ms.HeapSys,
+		StackInuse:   ms.StackInuse,
+		Sys:          ms.Sys,
+		NumGC:        ms.NumGC,
+		Goroutines:   runtime.NumGoroutine(),
+	}
+}
+
+// getCacheStats returns sizes of cache directories combined with hit rates reported by cache metrics.
+func (c *commandDebugMemory) getCacheStats(ctx context.Context, rep repo.Repository) ([]CacheMemoryStats, error) {
+	opts, err := repo.GetCachingOptions(ctx, c.svc.repositoryConfigFileName())
+	if err != nil {
+		return nil, errors.Wrap(err, "error getting cache options")
+	}
+
+	byName := map[string]*CacheMemoryStats{}
+
+	get := func(name string) *CacheMemoryStats {
+		if byName[name] == nil {
+			byName[name] = &CacheMemoryStats{Name: name}
+		}
+
+		return byName[name]
+	}
+
+	if opts.CacheDirectory != "" {
+		entries, err := os.ReadDir(opts.CacheDirectory)
+		if err != nil && !os.IsNotExist(err) {
+			return nil, errors.Wrap(err, "unable to scan cache directory")
+		}
+
+		for _, ent := range entries {
+			if !ent.IsDir() {
+				continue
+			}
+
+			fileCount, totalFileSize, err := scanCacheDir(filepath.Join(opts.CacheDirectory, ent.Name()))
+			if err != nil {
+				return nil, err
+			}
+
+			cs := get(ent.Name())
+			cs.Files = fileCount
+			cs.TotalBytes = totalFileSize
+		}
+	}
+
+	if mr, ok := rep.(interface{ Metrics() *metrics.Registry }); ok {
+		for k, v := range mr.Metrics().Snapshot(false).Counters {
+			name, cacheID, ok := parseCacheCounterName(k)
+			if !ok {
+				continue
+			}
+
+			cs := get(cacheID)
+
+			switch name {
+			case "cache_hit":
+				cs.Hits = v
+			case "cache_hit_bytes":
+				cs.HitBytes = v
+			case "cache_miss":
+				cs.Misses = v
+			case "cache_miss_bytes":
+				cs.MissBytes = v
+			}
+		}
+	}
+
+	var res []CacheMemoryStats
+
+	for _, cs := range byName {
+		if total := cs.Hits + cs.Misses; total > 0 {
+			cs.HitRatio = float64(cs.Hits) / float64(total)
+		}
+
+		res = append(res, *cs)
+	}
+
+	sort.Slice(res, func(i, j int) bool {
+		return res[i].Name < res[j].Name
+	})
+
+	return res, nil
+}
+
+// parseCacheCounterName parses names of cache counters, such as "cache_hit[cache:contents]".
+func parseCacheCounterName(k string) (name, cacheID string, ok bool) {
+	name, labels, ok := strings.Cut(k, "[cache:")
+	if !ok || !strings.HasPrefix(name, "cache_") || !strings.HasSuffix(labels, "]") {
+		return "", "", false
+	}
+
+	return name, strings.TrimSuffix(labels, "]"), true
+}
+
+//nolint:gosec
+func (c *commandDebugMemory) printMemoryStats(st *MemoryStats) {
+	memoryLimit := "none"
+	if st.Runtime.MemoryLimit != math.MaxInt64 {
+		memoryLimit = units.BytesString(int64(st.Runtime.MemoryLimit))
+	}
+
+	c.out.printStdout("Go runtime:\n")
+	c.out.printStdout("  Memory limit:   %v\n", memoryLimit)
+	c.out.printStdout("  Heap alloc:     %v\n", units.BytesString(int64(st.Runtime.HeapAlloc)))
+	c.out.printStdout("  Heap in use:    %v\n", units.BytesString(int64(st.Runtime.HeapInuse)))
+	c.out.printStdout("  Heap idle:      %v (released: %v)\n", units.BytesString(int64(st.Runtime.HeapIdle)), units.BytesString(int64(st.Runtime.HeapReleased)))
+	c.out.printStdout("  Heap sys:       %v\n", units.BytesString(int64(st.Runtime.HeapSys)))
+	c.out.printStdout("  Stack in use:   %v\n", units.BytesString(int64(st.Runtime.StackInuse)))
+	c.out.printStdout("  Total sys:      %v\n", units.BytesString(int64(st.Runtime.Sys)))
+	c.out.printStdout("  GC cycles:      %v\n", st.Runtime.NumGC)
+	c.out.printStdout("  Goroutines:     %v\n", st.Runtime.Goroutines)
+
+	c.out.printStdout("\nAllocators:\n")
+
+	for _, a := range st.Allocators {
+		c.out.printStdout("  %v (chunk size %v): alive=%v allocated=%v freed=%v free-list=%v high-water-mark=%v\n",
+			a.Name, units.BytesString(int64(a.ChunkSize)), a.ChunksAlive, a.ChunksAllocated, a.ChunksFreed, a.FreeListSize, a.AllocHighWaterMark)
+	}
+
+	if len(st.Caches) > 0 {
+		c.out.printStdout("\nCaches:\n")
+
+		for _, cs := range st.Caches {
+			c.out.printStdout("  %v: %v files %v, hits=%v (%v) misses=%v (%v) hit ratio=%.1f%%\n",
+				cs.Name, cs.Files, units.BytesString(cs.TotalBytes),
+				cs.Hits, units.BytesString(cs.HitBytes), cs.Misses, units.BytesString(cs.MissBytes), cs.HitRatio*oneHundredPercent)
+		}
+	}
+
+	if st.Index != nil {
+		c.out.printStdout("\nIndex:\n")
+		c.out.printStdout("  %v index blobs, %v\n", st.Index.Blobs, units.BytesString(st.Index.TotalBytes))
+	}
+}
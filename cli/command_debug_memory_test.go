@@ -0,0 +1,47 @@
+package cli_test
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/kopia/kopia/cli"
+	"github.com/kopia/kopia/internal/testutil"
+	"github.com/kopia/kopia/tests/testenv"
+)
+
+func TestDebugMemory(t *testing.T) {
+	env := testenv.NewCLITest(t, testenv.RepoFormatNotImportant, testenv.NewInProcRunner(t))
+
+	// works without a repository.
+	var st cli.MemoryStats
+
+	testutil.MustParseJSONLines(t, env.RunAndExpectSuccess(t, "debug", "memory", "--json"), &st)
+	require.NotEmpty(t, st.Allocators)
+	require.NotZero(t, st.Runtime.HeapAlloc)
+	require.Nil(t, st.Index)
+
+	env.RunAndExpectSuccess(t, "repo", "create", "filesystem", "--path", env.RepoDir)
+	env.RunAndExpectSuccess(t, "snapshot", "create", testutil.TempDirectory(t))
+
+	st = cli.MemoryStats{}
+
+	testutil.MustParseJSONLines(t, env.RunAndExpectSuccess(t, "debug", "memory", "--json"), &st)
+	require.NotNil(t, st.Index)
+	require.NotZero(t, st.Index.Blobs)
+	require.NotEmpty(t, st.Caches)
+
+	out := env.RunAndExpectSuccess(t, "debug", "memory")
+	require.Contains(t, out, "Allocators:")
+	require.Contains(t, out, "Caches:")
+	require.Contains(t, out, "Index:")
+}
+
+func TestDebugMemoryClosesRepository(t *testing.T) {
+	env := testenv.NewCLITest(t, testenv.RepoFormatNotImportant, testenv.NewInProcRunner(t))
+
+	env.RunAndExpectSuccess(t, "repo", "create", "filesystem", "--path", env.RepoDir)
+
+	// the repository is opened optionally, but must be closed, releasing its caches.
+	env.RunAndExpectSuccess(t, "debug", "memory", "--track-releasable=persistent-cache")
+}
@@ -0,0 +1,225 @@
+package cli
+
+import (
+	"context"
+	"strings"
+
+	"github.com/alecthomas/kingpin/v2"
+	"github.com/pkg/errors"
+
+	"github.com/kopia/kopia/internal/apiclient"
+	"github.com/kopia/kopia/internal/clock"
+	"github.com/kopia/kopia/internal/pproflogging"
+	"github.com/kopia/kopia/internal/serverapi"
+)
+
+const defaultProfilingConfig = "cpu:heap"
+
+type commandDebugPprof struct {
+	start  commandDebugPprofStart
+	stop   commandDebugPprofStop
+	dump   commandDebugPprofDump
+	status commandDebugPprofStatus
+}
+
+func (c *commandDebugPprof) setup(svc appServices, parent commandParent) {
+	cmd := parent.Command("pprof", "Control capturing of profiles by a running server or the current process.")
+
+	c.start.setup(svc, cmd)
+	c.stop.setup(svc, cmd)
+	c.dump.setup(svc, cmd)
+	c.status.setup(svc, cmd)
+}
+
+// pprofControlFlags selects whether profiling of a running server or the current process is controlled.
+type pprofControlFlags struct {
+	sf        serverClientFlags
+	inProcess bool
+}
+
+func (c *pprofControlFlags) setup(svc appServices, cmd *kingpin.CmdClause) {
+	c.sf.setup(svc, cmd)
+	cmd.Flag("in-process", "Control profiling of the current process instead of a running server").BoolVar(&c.inProcess)
+}
+
+func (c *pprofControlFlags) action(svc appServices, local func(ctx context.Context) error, remote func(ctx context.Context, cli *apiclient.KopiaAPIClient) error) func(ctx *kingpin.ParseContext) error {
+	localAction := svc.noRepositoryAction(local)
+	remoteAction := svc.serverAction(&c.sf, remote)
+
+	return func(kpc *kingpin.ParseContext) error {
+		if c.inProcess {
+			return localAction(kpc)
+		}
+
+		return remoteAction(kpc)
+	}
+}
+
+// pprofOutputFlags writes captured profiles to files.
+type pprofOutputFlags struct {
+	outputDir string
+
+	out textOutput
+}
+
+func (c *pprofOutputFlags) setup(svc appServices, cmd *kingpin.CmdClause) {
+	cmd.Flag("output-dir", "Directory where profiles are written").Default(".").StringVar(&c.outputDir)
+	c.out.setup(svc)
+}
+
+func (c *pprofOutputFlags) writeProfiles(profiles []pproflogging.Profile) error {
+	if len(profiles) == 0 {
+		c.out.printStderr("No profiles captured.\n")
+		return nil
+	}
+
+	fnames, err := pproflogging.WriteProfiles(c.outputDir, clock.Now(), profiles)
+	if err != nil {
+		return errors.Wrap(err, "unable to write profiles")
+	}
+
+	for _, fn := range fnames {
+		c.out.printStdout("%v\n", fn)
+	}
+
+	return nil
+}
+
+type commandDebugPprofStart struct {
+	cf     pprofControlFlags
+	config string
+
+	out textOutput
+}
+
+func (c *commandDebugPprofStart) setup(svc appServices, parent commandParent) {
+	cmd := parent.Command("start", "Start capturing profiles.")
+	cmd.Flag("config", "Profiles to capture, in the format of "+pproflogging.EnvVarKopiaDebugPprof).Default(defaultProfilingConfig).StringVar(&c.config)
+	c.cf.setup(svc, cmd)
+	c.out.setup(svc)
+	cmd.Action(c.cf.action(svc, c.runLocal, c.runRemote))
+}
+
+func (c *commandDebugPprofStart) runLocal(ctx context.Context) error {
+	if err := pproflogging.StartProfileBuffersWithConfig(ctx, c.config); err != nil {
+		return errors.Wrap(err, "unable to start profiling")
+	}
+
+	printActiveProfiles(&c.out, profileNames(pproflogging.ActiveProfiles()))
+
+	return nil
+}
+
+func (c *commandDebugPprofStart) runRemote(ctx context.Context, cli *apiclient.KopiaAPIClient) error {
+	var resp serverapi.ProfilingStatusResponse
+
+	if err := cli.Post(ctx, "control/pprof/start", &serverapi.StartProfilingRequest{Config: c.config}, &resp); err != nil {
+		return errors.Wrap(err, "unable to start profiling")
+	}
+
+	printActiveProfiles(&c.out, resp.Active)
+
+	return nil
+}
+
+type commandDebugPprofStop struct {
+	cf pprofControlFlags
+	of pprofOutputFlags
+}
+
+func (c *commandDebugPprofStop) setup(svc appServices, parent commandParent) {
+	cmd := parent.Command("stop", "Stop capturing profiles and write them to files.")
+	c.cf.setup(svc, cmd)
+	c.of.setup(svc, cmd)
+	cmd.Action(c.cf.action(svc, c.runLocal, c.runRemote))
+}
+
+func (c *commandDebugPprofStop) runLocal(ctx context.Context) error {
+	return c.of.writeProfiles(pproflogging.CollectAndStopProfileBuffers(ctx))
+}
+
+func (c *commandDebugPprofStop) runRemote(ctx context.Context, cli *apiclient.KopiaAPIClient) error {
+	var resp serverapi.ProfilesResponse
+
+	if err := cli.Post(ctx, "control/pprof/stop", &serverapi.Empty{}, &resp); err != nil {
+		return errors.Wrap(err, "unable to stop profiling")
+	}
+
+	return c.of.writeProfiles(resp.Profiles)
+}
+
+type commandDebugPprofDump struct {
+	cf pprofControlFlags
+	of pprofOutputFlags
+}
+
+func (c *commandDebugPprofDump) setup(svc appServices, parent commandParent) {
+	cmd := parent.Command("dump", "Write profiles captured so far to files and continue capturing.")
+	c.cf.setup(svc, cmd)
+	c.of.setup(svc, cmd)
+	cmd.Action(c.cf.action(svc, c.runLocal, c.runRemote))
+}
+
+func (c *commandDebugPprofDump) runLocal(ctx context.Context) error {
+	return c.of.writeProfiles(pproflogging.DumpProfileBuffers(ctx))
+}
+
+func (c *commandDebugPprofDump) runRemote(ctx context.Context, cli *apiclient.KopiaAPIClient) error {
+	var resp serverapi.ProfilesResponse
+
+	if err := cli.Post(ctx, "control/pprof/dump", &serverapi.Empty{}, &resp); err != nil {
+		return errors.Wrap(err, "unable to dump profiles")
+	}
+
+	return c.of.writeProfiles(resp.Profiles)
+}
+
+type commandDebugPprofStatus struct {
+	cf pprofControlFlags
+
+	out textOutput
+}
+
+func (c *commandDebugPprofStatus) setup(svc appServices, parent commandParent) {
+	cmd := parent.Command("status", "Show profiles being captured.")
+	c.cf.setup(svc, cmd)
+	c.out.setup(svc)
+	cmd.Action(c.cf.action(svc, c.runLocal, c.runRemote))
+}
+
+func (c *commandDebugPprofStatus) runLocal(_ context.Context) error {
+	printActiveProfiles(&c.out, profileNames(pproflogging.ActiveProfiles()))
+
+	return nil
+}
+
+func (c *commandDebugPprofStatus) runRemote(ctx context.Context, cli *apiclient.KopiaAPIClient) error {
+	var resp serverapi.ProfilingStatusResponse
+
+	if err := cli.Get(ctx, "control/pprof", nil, &resp); err != nil {
+		return errors.Wrap(err, "unable to get profiling status")
+	}
+
+	printActiveProfiles(&c.out, resp.Active)
+
+	return nil
+}
+
+func profileNames(names []pproflogging.ProfileName) []string {
+	var res []string
+
+	for _, n := range names {
+		res = append(res, string(n))
+	}
+
+	return res
+}
+
+func printActiveProfiles(out *textOutput, active []string) {
+	if len(active) == 0 {
+		out.printStdout("Profiling is not active.\n")
+		return
+	}
+
+	out.printStdout("Active profiles: %v\n", strings.Join(active, ", "))
+}
@@ -0,0 +1,104 @@
+package cli_test
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/kopia/kopia/internal/testutil"
+	"github.com/kopia/kopia/tests/testenv"
+)
+
+func TestDebugPprofInProcess(t *testing.T) {
+	env := testenv.NewCLITest(t, testenv.RepoFormatNotImportant, testenv.NewInProcRunner(t))
+	outputDir := testutil.TempDirectory(t)
+
+	require.Equal(t, []string{"Profiling is not active."}, env.RunAndExpectSuccess(t, "debug", "pprof", "status", "--in-process"))
+	require.Equal(t, []string{"Active profiles: heap, mutex"}, env.RunAndExpectSuccess(t, "debug", "pprof", "start", "--in-process", "--config=heap:mutex"))
+
+	// profiling is already active.
+	env.RunAndExpectFailure(t, "debug", "pprof", "start", "--in-process", "--config=heap")
+
+	require.Equal(t, []string{"Active profiles: heap, mutex"}, env.RunAndExpectSuccess(t, "debug", "pprof", "status", "--in-process"))
+
+	dumped := env.RunAndExpectSuccess(t, "debug", "pprof", "dump", "--in-process", "--output-dir", outputDir)
+	require.Len(t, dumped, 2)
+	verifyProfileFiles(t, outputDir, dumped)
+
+	stopped := env.RunAndExpectSuccess(t, "debug", "pprof", "stop", "--in-process", "--output-dir", outputDir)
+	require.Len(t, stopped, 2)
+	verifyProfileFiles(t, outputDir, stopped)
+
+	require.Equal(t, []string{"Profiling is not active."}, env.RunAndExpectSuccess(t, "debug", "pprof", "status", "--in-process"))
+}
+
+func TestDebugPprofServer(t *testing.T) {
+	env := testenv.NewCLITest(t, testenv.RepoFormatNotImportant, testenv.NewInProcRunner(t))
+	outputDir := testutil.TempDirectory(t)
+
+	env.RunAndExpectSuccess(t, "repo", "create", "filesystem", "--path", env.RepoDir)
+
+	serverStarted := make(chan struct{})
+	serverStopped := make(chan struct{})
+
+	var sp testutil.ServerParameters
+
+	go func() {
+		wait, _ := env.RunAndProcessStderr(t, sp.ProcessOutput,
+			"server", "start", "--insecure", "--random-server-control-password", "--address=127.0.0.1:0")
+
+		close(serverStarted)
+
+		wait()
+
+		close(serverStopped)
+	}()
+
+	select {
+	case <-serverStarted:
+		t.Logf("server started on %v", sp.BaseURL)
+
+	case <-time.After(5 * time.Second):
+		t.Fatalf("server did not start in time")
+	}
+
+	serverFlags := []string{"--address", sp.BaseURL, "--server-control-password", sp.ServerControlPassword}
+
+	require.Equal(t, []string{"Profiling is not active."}, env.RunAndExpectSuccess(t, append([]string{"debug", "pprof", "status"}, serverFlags...)...))
+	require.Equal(t, []string{"Active profiles: heap"}, env.RunAndExpectSuccess(t, append([]string{"debug", "pprof", "start", "--config=heap"}, serverFlags...)...))
+
+	dumped := env.RunAndExpectSuccess(t, append([]string{"debug", "pprof", "dump", "--output-dir", outputDir}, serverFlags...)...)
+	require.Len(t, dumped, 1)
+	verifyProfileFiles(t, outputDir, dumped)
+
+	stopped := env.RunAndExpectSuccess(t, append([]string{"debug", "pprof", "stop", "--output-dir", outputDir}, serverFlags...)...)
+	require.Len(t, stopped, 1)
+	verifyProfileFiles(t, outputDir, stopped)
+
+	require.Equal(t, []string{"Profiling is not active."}, env.RunAndExpectSuccess(t, append([]string{"debug", "pprof", "status"}, serverFlags...)...))
+
+	env.RunAndExpectSuccess(t, append([]string{"server", "shutdown"}, serverFlags...)...)
+
+	select {
+	case <-serverStopped:
+		t.Logf("server shut down")
+
+	case <-time.After(15 * time.Second):
+		t.Fatalf("server did not shutdown in time")
+	}
+}
+
+func verifyProfileFiles(t *testing.T, outputDir string, fnames []string) {
+	t.Helper()
+
+	for _, fn := range fnames {
+		require.Equal(t, outputDir, filepath.Dir(fn))
+
+		fi, err := os.Stat(fn)
+		require.NoError(t, err)
+		require.NotZero(t, fi.Size())
+	}
+}
@@ -18,6 +18,7 @@ type commandDiff struct {
 	diffCompareFiles     bool
 	diffCommandCommand   string
 
+	jo  jsonOutput
 	out textOutput
 }
 
@@ -29,6 +30,7 @@ func (c *commandDiff) setup(svc appServices, parent commandParent) {
 	cmd.Flag("diff-command", "Displays differences between two repository objects (files or directories)").Default(defaultDiffCommand()).Envar(svc.EnvName("KOPIA_DIFF")).StringVar(&c.diffCommandCommand)
 	cmd.Action(svc.repositoryReaderAction(c.run))
 
+	c.jo.setup(svc, cmd)
 	c.out.setup(svc)
 }
 
@@ -50,6 +52,10 @@ func (c *commandDiff) run(ctx context.Context, rep repo.Repository) error {
 		return errors.New("arguments do diff must both be directories or both non-directories")
 	}
 
+	if c.jo.jsonOutput {
+		return c.emitJSONChanges(ctx, ent1, ent2)
+	}
+
 	d, err := diff.NewComparer(c.out.stdout())
 	if err != nil {
 		return errors.Wrap(err, "error creating comparer")
@@ -69,6 +75,24 @@ func (c *commandDiff) run(ctx context.Context, rep repo.Repository) error {
 	return errors.New("comparing files not implemented yet")
 }
 
+func (c *commandDiff) emitJSONChanges(ctx context.Context, ent1, ent2 fs.Entry) error {
+	changes, err := snapshotfs.Diff(ctx, ent1, ent2)
+	if err != nil {
+		return errors.Wrap(err, "error comparing entries")
+	}
+
+	var jl jsonList
+
+	jl.begin(&c.jo)
+	defer jl.end()
+
+	for _, ch := range changes {
+		jl.emit(ch)
+	}
+
+	return nil
+}
+
 func defaultDiffCommand() string {
 	if isWindows() {
 		return "cmp"
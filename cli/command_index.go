@@ -5,16 +5,18 @@ type commandIndex struct {
 
 	inspect  commandIndexInspect
 	list     commandIndexList
+	migrate  commandIndexMigrate
 	optimize commandIndexOptimize
 	recover  commandIndexRecover
 }
 
-func (c *commandIndex) setup(svc appServices, parent commandParent) {
+func (c *commandIndex) setup(svc advancedAppServices, parent commandParent) {
 	cmd := parent.Command("index", "Commands to manipulate content index.").Hidden()
 
 	c.epoch.setup(svc, cmd)
 	c.inspect.setup(svc, cmd)
 	c.list.setup(svc, cmd)
+	c.migrate.setup(svc, cmd)
 	c.optimize.setup(svc, cmd)
 	c.recover.setup(svc, cmd)
 }
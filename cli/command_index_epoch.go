@@ -1,11 +1,13 @@
 package cli
 
 type commandIndexEpoch struct {
-	list commandIndexEpochList
+	list   commandIndexEpochList
+	status commandIndexEpochStatus
 }
 
 func (c *commandIndexEpoch) setup(svc appServices, parent commandParent) {
 	cmd := parent.Command("epoch", "Manage index manager epochs").Hidden()
 
 	c.list.setup(svc, cmd)
+	c.status.setup(svc, cmd)
 }
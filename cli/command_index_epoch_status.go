@@ -0,0 +1,147 @@
+package cli
+
+import (
+	"context"
+	"time"
+
+	"github.com/pkg/errors"
+
+	"github.com/kopia/kopia/internal/clock"
+	"github.com/kopia/kopia/internal/epoch"
+	"github.com/kopia/kopia/internal/units"
+	"github.com/kopia/kopia/repo"
+	"github.com/kopia/kopia/repo/maintenance"
+)
+
+type commandIndexEpochStatus struct {
+	jo  jsonOutput
+	out textOutput
+}
+
+// IndexEpochStatus is used to display the status of index epochs in JSON format.
+type IndexEpochStatus struct {
+	epoch.Status
+
+	// epochs are only advanced during quick maintenance.
+	NextQuickMaintenance time.Time            `json:"nextQuickMaintenance"`
+	LastAdvanceAttempt   *maintenance.RunInfo `json:"lastAdvanceAttempt,omitempty"`
+}
+
+func (c *commandIndexEpochStatus) setup(svc appServices, parent commandParent) {
+	cmd := parent.Command("status", "Display the status of epochs, including reasons why the current epoch is not advancing.")
+	cmd.Action(svc.directRepositoryReadAction(c.run))
+
+	c.jo.setup(svc, cmd)
+	c.out.setup(svc)
+}
+
+func (c *commandIndexEpochStatus) run(ctx context.Context, rep repo.DirectRepository) error {
+	emgr, ok, err := rep.ContentReader().EpochManager(ctx)
+	if err != nil {
+		return errors.Wrap(err, "epoch manager")
+	}
+
+	if !ok {
+		return errors.Errorf("epoch manager is not active")
+	}
+
+	st, err := emgr.Status(ctx)
+	if err != nil {
+		return errors.Wrap(err, "unable to determine epoch status")
+	}
+
+	sched, err := maintenance.GetSchedule(ctx, rep)
+	if err != nil {
+		return errors.Wrap(err, "unable to get maintenance schedule")
+	}
+
+	result := IndexEpochStatus{
+		Status:               st,
+		NextQuickMaintenance: sched.NextQuickMaintenanceTime,
+	}
+
+	if runs := sched.Runs[maintenance.TaskEpochAdvance]; len(runs) > 0 {
+		result.LastAdvanceAttempt = &runs[0]
+	}
+
+	if c.jo.jsonOutput {
+		c.jo.printJSON(result)
+		return nil
+	}
+
+	c.display(&result)
+
+	return nil
+}
+
+func (c *commandIndexEpochStatus) display(s *IndexEpochStatus) {
+	c.out.printStdout("Current epoch:          %v\n", s.WriteEpoch)
+
+	if !s.DeletionWatermark.IsZero() {
+		c.out.printStdout("Deletion watermark:     %v\n", formatTimestamp(s.DeletionWatermark))
+	}
+
+	c.out.printStdout("\nEpochs:\n")
+
+	for _, r := range s.RangeCheckpoints {
+		c.out.printStdout("  %v-%v: range checkpoint, %v blobs, %v\n", r.MinEpoch, r.MaxEpoch, r.Blobs, units.BytesString(r.TotalBytes))
+	}
+
+	for _, e := range s.Epochs {
+		c.out.printStdout("  %v:", e.Epoch)
+
+		if !e.StartTime.IsZero() {
+			c.out.printStdout(" started %v,", formatTimestamp(e.StartTime))
+		}
+
+		if e.Settled {
+			c.out.printStdout(" settled,")
+		}
+
+		c.out.printStdout(" %v uncompacted blobs, %v", e.UncompactedBlobs, units.BytesString(e.UncompactedBytes))
+
+		if e.UncompactedBlobs > 0 {
+			c.out.printStdout(" written %v ... %v", formatTimestamp(e.FirstWriteTime), formatTimestamp(e.LastWriteTime))
+		}
+
+		if e.SingleEpochCompactionBlobs > 0 {
+			c.out.printStdout(", %v compacted blobs, %v", e.SingleEpochCompactionBlobs, units.BytesString(e.SingleEpochCompactionBytes))
+		}
+
+		c.out.printStdout("\n")
+	}
+
+	p := s.Parameters
+
+	c.out.printStdout("\nEpoch advancement:\n")
+	c.out.printStdout("  criteria:             at least %v between first and last index write and either %v index blobs or %v\n",
+		p.MinEpochDuration, p.EpochAdvanceOnCountThreshold, units.BytesString(p.EpochAdvanceOnTotalSizeBytesThreshold))
+
+	if s.Advance.Ready {
+		c.out.printStdout("  status:               ready, will be advanced during next quick maintenance\n")
+	} else {
+		c.out.printStdout("  status:               not ready\n")
+
+		for _, b := range s.Advance.Blockers {
+			c.out.printStdout("    - %v\n", b)
+		}
+	}
+
+	if !s.Advance.EstimatedTime.IsZero() {
+		c.out.printStdout("  estimated:            %v (in %v)\n",
+			formatTimestamp(s.Advance.EstimatedTime), s.Advance.EstimatedTime.Sub(clock.Now()).Round(time.Minute))
+	}
+
+	if !s.NextQuickMaintenance.IsZero() {
+		c.out.printStdout("  next maintenance:     %v\n", formatTimestamp(s.NextQuickMaintenance))
+	}
+
+	switch r := s.LastAdvanceAttempt; {
+	case r == nil:
+		c.out.printStdout("  last attempt:         never, make sure maintenance is running\n")
+	case r.Success:
+		c.out.printStdout("  last attempt:         %v\n", formatTimestamp(r.Start))
+	default:
+		c.out.printStdout("  last attempt:         %v failed: %v\n", formatTimestamp(r.Start), r.Error)
+	}
+}
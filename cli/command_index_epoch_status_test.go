@@ -0,0 +1,42 @@
+package cli_test
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/kopia/kopia/cli"
+	"github.com/kopia/kopia/internal/testutil"
+	"github.com/kopia/kopia/tests/testenv"
+)
+
+func TestIndexEpochStatus(t *testing.T) {
+	t.Parallel()
+
+	env := testenv.NewCLITest(t, testenv.RepoFormatNotImportant, testenv.NewInProcRunner(t))
+
+	env.RunAndExpectSuccess(t, "repo", "create", "filesystem", "--path", env.RepoDir, "--format-version=2")
+	env.RunAndExpectSuccess(t, "snapshot", "create", testutil.TempDirectory(t))
+
+	out := env.RunAndExpectSuccess(t, "index", "epoch", "status")
+	require.Equal(t, "Current epoch:          0", out[0])
+	require.Contains(t, mustGetLineContaining(t, out, "status:"), "not ready")
+	require.NotEmpty(t, mustGetLineContaining(t, out, "minimum epoch duration"))
+
+	var st cli.IndexEpochStatus
+
+	require.NoError(t, json.Unmarshal([]byte(strings.Join(env.RunAndExpectSuccess(t, "index", "epoch", "status", "--json"), "\n")), &st))
+	require.Equal(t, 0, st.WriteEpoch)
+	require.Len(t, st.Epochs, 1)
+	require.Positive(t, st.Epochs[0].UncompactedBlobs)
+	require.False(t, st.Advance.Ready)
+	require.NotEmpty(t, st.Advance.Blockers)
+	require.False(t, st.Advance.EstimatedTime.IsZero())
+
+	// epoch manager is not used in format version 1.
+	env2 := testenv.NewCLITest(t, testenv.RepoFormatNotImportant, testenv.NewInProcRunner(t))
+	env2.RunAndExpectSuccess(t, "repo", "create", "filesystem", "--path", env2.RepoDir, "--format-version=1")
+	env2.RunAndExpectFailure(t, "index", "epoch", "status")
+}
@@ -0,0 +1,240 @@
+package cli
+
+import (
+	"context"
+	"slices"
+
+	"github.com/pkg/errors"
+
+	"github.com/kopia/kopia/internal/epoch"
+	"github.com/kopia/kopia/internal/units"
+	"github.com/kopia/kopia/repo"
+	"github.com/kopia/kopia/repo/blob"
+	"github.com/kopia/kopia/repo/content/index"
+	"github.com/kopia/kopia/repo/content/indexblob"
+	"github.com/kopia/kopia/repo/format"
+)
+
+type commandIndexMigrate struct {
+	dryRun         bool
+	discardPartial bool
+
+	// migration is performed under the repository upgrade lock, which drains all other clients.
+	upgrade commandRepositoryUpgrade
+
+	svc appServices
+	out textOutput
+}
+
+func (c *commandIndexMigrate) setup(svc advancedAppServices, parent commandParent) {
+	cmd := parent.Command("migrate", "Migrate legacy indexes to the epoch-based index format. All other clients are drained from the repository using the upgrade lock during migration.")
+	cmd.Flag("dry-run", "Only report what would be migrated").BoolVar(&c.dryRun)
+	cmd.Flag("discard-partial", "Delete epoch index blobs left behind by an interrupted migration before migrating").BoolVar(&c.discardPartial)
+	cmd.Flag("io-drain-timeout", "Max time it should take all other Kopia clients to drop repository connections").Default(format.DefaultRepositoryBlobCacheDuration.String()).DurationVar(&c.upgrade.ioDrainTimeout)
+	cmd.Flag("allow-unsafe-upgrade", "Force using an unsafe io-drain-timeout for the upgrade lock").Default("false").Hidden().BoolVar(&c.upgrade.allowUnsafeUpgradeTimings)
+	cmd.Flag("status-poll-interval", "An advisory polling interval to check for the status of upgrade").Default("60s").DurationVar(&c.upgrade.statusPollInterval)
+	cmd.Flag("max-permitted-clock-drift", "The maximum drift between repository and client clocks").Default(maxPermittedClockDriftDefault.String()).DurationVar(&c.upgrade.maxPermittedClockDrift)
+
+	// migration phases, the repository is reopened after each of them.
+
+	// Report what would be migrated.
+	cmd.Action(svc.directRepositoryWriteAction(c.upgrade.runPhase(c.plan)))
+	// Set the upgrade lock intent.
+	cmd.Action(svc.directRepositoryWriteAction(c.upgrade.runPhase(c.upgrade.setLockIntent)))
+	// Wait for all other clients to drain.
+	cmd.Action(svc.directRepositoryWriteAction(c.upgrade.runPhase(c.upgrade.drainOrCommit)))
+	// Migrate the indexes, rolling back the upgrade lock on failure.
+	cmd.Action(svc.directRepositoryWriteAction(c.upgrade.runPhase(c.migrate)))
+	// Commit the upgrade and revoke the lock.
+	cmd.Action(svc.directRepositoryWriteAction(c.upgrade.runPhase(c.upgrade.commitUpgrade)))
+
+	c.svc = svc
+	c.upgrade.svc = svc
+	c.out.setup(svc)
+}
+
+// plan is the migration phase which reports what would be migrated and skips all other phases
+// when there is nothing to migrate or only a dry run is requested.
+func (c *commandIndexMigrate) plan(ctx context.Context, rep repo.DirectRepositoryWriter) error {
+	mp, err := rep.ContentReader().ContentFormat().GetMutableParameters(ctx)
+	if err != nil {
+		return errors.Wrap(err, "mutable parameters")
+	}
+
+	if mp.EpochParameters.Enabled {
+		log(ctx).Info("Repository indexes are already in the epoch format, nothing to migrate.")
+
+		c.upgrade.skip = true
+
+		return nil
+	}
+
+	legacy, _, err := rep.ContentManager().SharedManager.IndexReaderV0().ListIndexBlobInfos(ctx)
+	if err != nil {
+		return errors.Wrap(err, "unable to list legacy index blobs")
+	}
+
+	partial, err := blob.ListAllBlobs(ctx, rep.BlobStorage(), epoch.EpochManagerIndexUberPrefix)
+	if err != nil {
+		return errors.Wrap(err, "unable to list epoch index blobs")
+	}
+
+	c.out.printStdout("Legacy index blobs:      %v (%v)\n", len(legacy), units.BytesString(totalIndexBlobLength(legacy)))
+	c.out.printStdout("Index format version:    %v -> %v\n", mp.IndexVersion, max(mp.IndexVersion, index.Version2))
+	c.out.printStdout("Repository format:       %v -> %v\n", mp.Version, format.MaxFormatVersion)
+
+	if len(partial) > 0 {
+		c.out.printStdout("Partial epoch indexes:   %v (%v) left behind by an interrupted migration\n", len(partial), units.BytesString(blob.TotalLength(partial)))
+
+		if !c.discardPartial {
+			return errors.New("epoch index blobs already exist, pass --discard-partial to delete them before migrating")
+		}
+	}
+
+	if c.dryRun {
+		c.out.printStdout("Dry run, not migrating.\n")
+
+		c.upgrade.skip = true
+
+		return nil
+	}
+
+	c.svc.advancedCommand(ctx)
+
+	return nil
+}
+
+// migrate is the migration phase which runs after all other clients have been drained and
+// rewrites legacy indexes in the epoch format.
+func (c *commandIndexMigrate) migrate(ctx context.Context, rep repo.DirectRepositoryWriter) error {
+	mp, err := rep.ContentReader().ContentFormat().GetMutableParameters(ctx)
+	if err != nil {
+		return errors.Wrap(err, "mutable parameters")
+	}
+
+	sm := rep.ContentManager().SharedManager
+
+	legacy, _, err := sm.IndexReaderV0().ListIndexBlobInfos(ctx)
+	if err != nil {
+		return c.rollback(ctx, rep, nil, errors.Wrap(err, "unable to list legacy index blobs"))
+	}
+
+	partial, err := blob.ListAllBlobs(ctx, rep.BlobStorage(), epoch.EpochManagerIndexUberPrefix)
+	if err != nil {
+		return c.rollback(ctx, rep, nil, errors.Wrap(err, "unable to list epoch index blobs"))
+	}
+
+	for _, bm := range partial {
+		if err := rep.BlobStorage().DeleteBlob(ctx, bm.BlobID); err != nil {
+			return c.rollback(ctx, rep, nil, errors.Wrapf(err, "unable to delete partial epoch index blob %v", bm.BlobID))
+		}
+	}
+
+	written, err := sm.MigrateIndexesToEpochFormat(ctx, func(done, total int) {
+		log(ctx).Infof("Migrated %v/%v legacy index blobs.", done, total)
+	})
+	if err != nil {
+		return c.rollback(ctx, rep, written, errors.Wrap(err, "error migrating indexes"))
+	}
+
+	if err := c.validate(ctx, rep, legacy, written); err != nil {
+		return c.rollback(ctx, rep, written, err)
+	}
+
+	mp.EpochParameters = epoch.DefaultParameters()
+	mp.IndexVersion = max(mp.IndexVersion, index.Version2)
+
+	blobcfg, err := rep.FormatManager().BlobCfgBlob(ctx)
+	if err != nil {
+		return c.rollback(ctx, rep, written, errors.Wrap(err, "unable to get blob configuration"))
+	}
+
+	requiredFeatures, err := rep.FormatManager().RequiredFeatures(ctx)
+	if err != nil {
+		return c.rollback(ctx, rep, written, errors.Wrap(err, "unable to get required features"))
+	}
+
+	if err := rep.FormatManager().SetParameters(ctx, mp, blobcfg, requiredFeatures); err != nil {
+		return c.rollback(ctx, rep, written, errors.Wrap(err, "error setting parameters"))
+	}
+
+	log(ctx).Infof("Migrated %v legacy index blobs into %v epoch index blobs.", len(legacy), len(written))
+
+	return nil
+}
+
+// validate ensures that the migrated index blobs have the same contents as the legacy ones and that
+// no legacy index blobs have been written in the meantime by other clients.
+func (c *commandIndexMigrate) validate(ctx context.Context, rep repo.DirectRepositoryWriter, legacy []indexblob.Metadata, written []blob.ID) error {
+	sm := rep.ContentManager().SharedManager
+
+	var migrated []indexblob.Metadata
+
+	for _, id := range written {
+		migrated = append(migrated, indexblob.Metadata{Metadata: blob.Metadata{BlobID: id}})
+	}
+
+	msgs, err := compareIndexBlobs(ctx, sm, legacy, migrated)
+	if err != nil {
+		return err
+	}
+
+	if len(msgs) > 0 {
+		for _, m := range msgs {
+			log(ctx).Error(m)
+		}
+
+		return errors.Errorf("found %v inconsistencies in migrated indexes", len(msgs))
+	}
+
+	current, _, err := sm.IndexReaderV0().ListIndexBlobInfos(ctx)
+	if err != nil {
+		return errors.Wrap(err, "unable to list legacy index blobs")
+	}
+
+	if !slices.Equal(indexBlobIDs(legacy), indexBlobIDs(current)) {
+		return errors.New("legacy indexes changed during migration, make sure no other clients are using the repository")
+	}
+
+	return nil
+}
+
+// rollback deletes index blobs written during failed migration and revokes the upgrade lock,
+// leaving the repository in its original state.
+func (c *commandIndexMigrate) rollback(ctx context.Context, rep repo.DirectRepositoryWriter, written []blob.ID, cause error) error {
+	log(ctx).Infof("Rolling back migration, deleting %v epoch index blobs.", len(written))
+
+	for _, id := range written {
+		if err := rep.BlobStorage().DeleteBlob(ctx, id); err != nil && !errors.Is(err, blob.ErrBlobNotFound) {
+			log(ctx).Errorf("unable to delete %v, delete it manually or use --discard-partial: %v", id, err)
+		}
+	}
+
+	if err := rep.FormatManager().RollbackUpgrade(ctx); err != nil {
+		log(ctx).Errorf("unable to revoke the upgrade lock, use 'repository upgrade rollback --force': %v", err)
+	}
+
+	return cause
+}
+
+func indexBlobIDs(bms []indexblob.Metadata) []blob.ID {
+	var result []blob.ID
+
+	for _, bm := range bms {
+		result = append(result, bm.BlobID)
+	}
+
+	slices.Sort(result)
+
+	return result
+}
+
+func totalIndexBlobLength(bms []indexblob.Metadata) int64 {
+	var total int64
+
+	for _, bm := range bms {
+		total += bm.Length
+	}
+
+	return total
+}
@@ -0,0 +1,82 @@
+package cli_test
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/kopia/kopia/internal/gather"
+	"github.com/kopia/kopia/internal/testlogging"
+	"github.com/kopia/kopia/internal/testutil"
+	"github.com/kopia/kopia/repo"
+	"github.com/kopia/kopia/repo/blob"
+	"github.com/kopia/kopia/tests/testenv"
+)
+
+func TestIndexMigrate(t *testing.T) {
+	t.Parallel()
+
+	ctx := testlogging.Context(t)
+	env := testenv.NewCLITest(t, testenv.RepoFormatNotImportant, testenv.NewInProcRunner(t))
+
+	env.RunAndExpectSuccess(t, "repo", "create", "filesystem", "--path", env.RepoDir, "--format-version=1")
+
+	dir := testutil.TempDirectory(t)
+
+	// each snapshot writes a separate legacy index blob.
+	for i := range 3 {
+		require.NoError(t, os.WriteFile(filepath.Join(dir, fmt.Sprintf("file%v", i)), []byte(fmt.Sprintf("contents %v", i)), 0o600))
+		env.RunAndExpectSuccess(t, "snapshot", "create", dir)
+	}
+
+	require.Contains(t, env.RunAndExpectSuccess(t, "repo", "status"), "Epoch Manager:       disabled")
+
+	// dry run does not change anything.
+	out := env.RunAndExpectSuccess(t, "index", "migrate", "--dry-run")
+	require.NotEmpty(t, mustGetLineContaining(t, out, "Legacy index blobs:"))
+	require.Contains(t, out, "Repository format:       1 -> 3")
+	require.Contains(t, env.RunAndExpectSuccess(t, "repo", "status"), "Epoch Manager:       disabled")
+
+	// simulate blobs left behind by an interrupted migration.
+	rep, err := repo.Open(ctx, filepath.Join(env.ConfigDir, ".kopia.config"), testenv.TestRepoPassword, &repo.Options{})
+	require.NoError(t, err)
+
+	_, w, err := rep.NewWriter(ctx, repo.WriteSessionOptions{Purpose: "test"})
+	require.NoError(t, err)
+	require.NoError(t, w.(repo.DirectRepositoryWriter).BlobStorage().PutBlob(ctx, "xn0_deadbeef", gather.FromSlice([]byte("partial")), blob.PutOptions{}))
+	require.NoError(t, w.Close(ctx))
+	require.NoError(t, rep.Close(ctx))
+
+	_, stderr := env.RunAndExpectFailure(t, "index", "migrate")
+	require.NotEmpty(t, mustGetLineContaining(t, stderr, "--discard-partial"))
+	require.Contains(t, env.RunAndExpectSuccess(t, "repo", "status"), "Epoch Manager:       disabled")
+
+	// the migration is performed under the upgrade lock, which requires an owner.
+	env.RunAndExpectFailure(t, "index", "migrate", "--discard-partial",
+		"--io-drain-timeout=1s", "--allow-unsafe-upgrade", "--status-poll-interval=1s")
+	require.Contains(t, env.RunAndExpectSuccess(t, "repo", "status"), "Epoch Manager:       disabled")
+
+	_, stderr = env.RunAndExpectSuccessWithErrOut(t, "index", "migrate", "--discard-partial",
+		"--upgrade-owner-id=owner",
+		"--io-drain-timeout=1s", "--allow-unsafe-upgrade",
+		"--status-poll-interval=1s",
+		"--max-permitted-clock-drift=1s")
+	require.NotEmpty(t, mustGetLineContaining(t, stderr, "Successfully drained all repository clients"))
+	require.NotEmpty(t, mustGetLineContaining(t, stderr, "Repository has been successfully upgraded."))
+
+	out = env.RunAndExpectSuccess(t, "repo", "status")
+	require.Contains(t, out, "Epoch Manager:       enabled")
+	require.Contains(t, out, "Format version:      3")
+
+	env.RunAndExpectSuccess(t, "index", "epoch", "list")
+	env.RunAndExpectSuccess(t, "snapshot", "verify")
+	env.RunAndExpectSuccess(t, "snapshot", "create", dir)
+	require.NotEmpty(t, mustGetLineContaining(t, env.RunAndExpectSuccess(t, "snapshot", "list", dir), "+ 1 identical snapshots"))
+
+	// second migration is a no-op.
+	_, stderr = env.RunAndExpectSuccessWithErrOut(t, "index", "migrate")
+	require.NotEmpty(t, mustGetLineContaining(t, stderr, "already in the epoch format"))
+}
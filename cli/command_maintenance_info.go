@@ -2,6 +2,8 @@
 
 import (
 	"context"
+	"fmt"
+	"strings"
 	"time"
 
 	"github.com/pkg/errors"
@@ -9,6 +11,7 @@
 	"github.com/kopia/kopia/internal/clock"
 	"github.com/kopia/kopia/internal/units"
 	"github.com/kopia/kopia/repo"
+	"github.com/kopia/kopia/repo/blob/throttling"
 	"github.com/kopia/kopia/repo/maintenance"
 )
 
@@ -47,7 +50,7 @@ func (c *commandMaintenanceInfo) run(ctx context.Context, rep repo.DirectReposit
 			Schedule: *s,
 		}
 
-		c.out.printStdout("%s\n", c.jo.jsonBytes(mi))
+		c.jo.printJSON(mi)
 
 		return nil
 	}
@@ -72,6 +75,10 @@ func (c *commandMaintenanceInfo) run(ctx context.Context, rep repo.DirectReposit
 		c.out.printStdout("Object Lock Extension: disabled\n")
 	}
 
+	c.displayThrottlingInfo("Content Rewrite", p.Throttling.ContentRewrite)
+	c.displayThrottlingInfo("Blob Deletion", p.Throttling.BlobDeletion)
+	c.displayThrottlingInfo("Index Compaction", p.Throttling.IndexCompaction)
+
 	c.out.printStdout("Recent Maintenance Runs:\n")
 
 	for run, timings := range s.Runs {
@@ -102,6 +109,21 @@ func (c *commandMaintenanceInfo) displayCycleInfo(cp *maintenance.CycleParams, t
 	if cp.Enabled {
 		c.out.printStdout("  interval: %v\n", cp.Interval)
 
+		if len(cp.Windows) > 0 {
+			var windows []string
+			for _, w := range cp.Windows {
+				windows = append(windows, w.String())
+			}
+
+			c.out.printStdout("  windows: %v\n", strings.Join(windows, ", "))
+
+			if now := rep.Time(); t.Before(now) {
+				t = now
+			}
+
+			t = cp.NextAllowedTime(t)
+		}
+
 		if rep.Time().Before(t) {
 			c.out.printStdout("  next run: %v (in %v)\n", formatTimestamp(t), t.Sub(clock.Now()).Truncate(time.Second))
 		} else {
@@ -109,3 +131,38 @@ func (c *commandMaintenanceInfo) displayCycleInfo(cp *maintenance.CycleParams, t
 		}
 	}
 }
+
+func (c *commandMaintenanceInfo) displayThrottlingInfo(phase string, l throttling.Limits) {
+	var lines []string
+
+	if v := l.UploadBytesPerSecond; v != 0 {
+		lines = append(lines, "  max upload speed:   "+units.BytesPerSecondsString(v))
+	}
+
+	if v := l.DownloadBytesPerSecond; v != 0 {
+		lines = append(lines, "  max download speed: "+units.BytesPerSecondsString(v))
+	}
+
+	if v := l.ReadsPerSecond; v != 0 {
+		lines = append(lines, fmt.Sprintf("  max reads/sec:      %v", v))
+	}
+
+	if v := l.WritesPerSecond; v != 0 {
+		lines = append(lines, fmt.Sprintf("  max writes/sec:     %v", v))
+	}
+
+	if v := l.ListsPerSecond; v != 0 {
+		lines = append(lines, fmt.Sprintf("  max lists/sec:      %v", v))
+	}
+
+	if len(lines) == 0 {
+		c.out.printStdout("%v Throttling: unlimited\n", phase)
+		return
+	}
+
+	c.out.printStdout("%v Throttling:\n", phase)
+
+	for _, l := range lines {
+		c.out.printStdout("%v\n", l)
+	}
+}
@@ -2,24 +2,28 @@
 
 import (
 	"context"
+	"os"
 
 	"github.com/pkg/errors"
 
 	"github.com/kopia/kopia/repo"
 	"github.com/kopia/kopia/repo/maintenance"
+	"github.com/kopia/kopia/snapshot/snapshotgc"
 	"github.com/kopia/kopia/snapshot/snapshotmaintenance"
 )
 
 type commandMaintenanceRun struct {
-	maintenanceRunFull  bool
-	maintenanceRunForce bool
-	safety              maintenance.SafetyParameters
+	maintenanceRunFull               bool
+	maintenanceRunForce              bool
+	maintenanceRunExportUnreferenced string
+	safety                           maintenance.SafetyParameters
 }
 
 func (c *commandMaintenanceRun) setup(svc appServices, parent commandParent) {
 	cmd := parent.Command("run", "Run repository maintenance")
 	cmd.Flag("full", "Full maintenance").BoolVar(&c.maintenanceRunFull)
 	cmd.Flag("force", "Run maintenance even if not owned (unsafe)").Hidden().BoolVar(&c.maintenanceRunForce)
+	cmd.Flag("export-unreferenced", "Write JSON report of contents not referenced by any snapshot to the provided file before deleting them").PlaceHolder("FILE").StringVar(&c.maintenanceRunExportUnreferenced)
 	safetyFlagVar(cmd, &c.safety)
 
 	cmd.Action(svc.directRepositoryWriteAction(c.run))
@@ -37,6 +41,22 @@ func (c *commandMaintenanceRun) run(ctx context.Context, rep repo.DirectReposito
 		mode = maintenance.ModeFull
 	}
 
+	if c.maintenanceRunExportUnreferenced == "" {
+		//nolint:wrapcheck
+		return snapshotmaintenance.Run(ctx, rep, mode, c.maintenanceRunForce, c.safety)
+	}
+
+	if mode != maintenance.ModeFull {
+		return errors.New("exporting unreferenced contents requires full maintenance")
+	}
+
+	f, err := os.Create(c.maintenanceRunExportUnreferenced)
+	if err != nil {
+		return errors.Wrap(err, "unable to create report file")
+	}
+
+	defer f.Close() //nolint:errcheck
+
 	//nolint:wrapcheck
-	return snapshotmaintenance.Run(ctx, rep, mode, c.maintenanceRunForce, c.safety)
+	return snapshotmaintenance.Run(ctx, rep, mode, c.maintenanceRunForce, c.safety, snapshotgc.WithUnreferencedContentReport(f))
 }
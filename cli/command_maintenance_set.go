@@ -2,12 +2,15 @@
 
 import (
 	"context"
+	"strconv"
 	"time"
 
+	"github.com/alecthomas/kingpin/v2"
 	"github.com/pkg/errors"
 
 	"github.com/kopia/kopia/internal/units"
 	"github.com/kopia/kopia/repo"
+	"github.com/kopia/kopia/repo/blob/throttling"
 	"github.com/kopia/kopia/repo/maintenance"
 )
 
@@ -19,12 +22,92 @@ type commandMaintenanceSet struct {
 	maintenanceSetFullFrequency  time.Duration
 	maintenanceSetPauseQuick     time.Duration
 	maintenanceSetPauseFull      time.Duration
+	maintenanceSetQuickWindows   []string
+	maintenanceSetFullWindows    []string
+	maintenanceClearQuickWindows bool
+	maintenanceClearFullWindows  bool
 
 	maxRetainedLogCount       int
 	maxRetainedLogAge         time.Duration
 	maxTotalRetainedLogSizeMB int64
 
 	extendObjectLocks []bool // optional boolean
+
+	contentRewriteThrottle  maintenancePhaseThrottleFlags
+	blobDeletionThrottle    maintenancePhaseThrottleFlags
+	indexCompactionThrottle maintenancePhaseThrottleFlags
+}
+
+// maintenancePhaseThrottleFlags sets throttling limits applied during a single maintenance phase.
+type maintenancePhaseThrottleFlags struct {
+	phase             string
+	bytesPerSecond    string
+	requestsPerSecond string
+}
+
+func (c *maintenancePhaseThrottleFlags) setup(cmd *kingpin.CmdClause, flagPrefix, phase string) {
+	c.phase = phase
+
+	cmd.Flag(flagPrefix+"-bytes-per-second", "Limit upload and download bytes per second during "+phase+" (e.g. 10MB, 1.5MiB/s or 'unlimited')").StringVar(&c.bytesPerSecond)
+	cmd.Flag(flagPrefix+"-requests-per-second", "Limit read, write and list requests per second during "+phase+" (or 'unlimited')").StringVar(&c.requestsPerSecond)
+}
+
+func (c *maintenancePhaseThrottleFlags) apply(ctx context.Context, limits *throttling.Limits, changed *bool) error {
+	if c.bytesPerSecond != "" {
+		v, err := parseMaintenanceThrottleValue(true, c.bytesPerSecond)
+		if err != nil {
+			return errors.Wrapf(err, "can't parse the %v bytes per second %q", c.phase, c.bytesPerSecond)
+		}
+
+		limits.UploadBytesPerSecond = v
+		limits.DownloadBytesPerSecond = v
+		*changed = true
+
+		log(ctx).Infof("Setting %v speed to %v.", c.phase, maintenanceThrottleString(v, units.BytesPerSecondsString))
+	}
+
+	if c.requestsPerSecond != "" {
+		v, err := parseMaintenanceThrottleValue(false, c.requestsPerSecond)
+		if err != nil {
+			return errors.Wrapf(err, "can't parse the %v requests per second %q", c.phase, c.requestsPerSecond)
+		}
+
+		limits.ReadsPerSecond = v
+		limits.WritesPerSecond = v
+		limits.ListsPerSecond = v
+		*changed = true
+
+		log(ctx).Infof("Setting %v requests per second to %v.", c.phase, maintenanceThrottleString(v, func(v float64) string {
+			return strconv.FormatFloat(v, 'f', -1, 64)
+		}))
+	}
+
+	return nil
+}
+
+func parseMaintenanceThrottleValue(bps bool, str string) (float64, error) {
+	if str == "unlimited" || str == "-" {
+		return 0, nil
+	}
+
+	v, err := parseThrottleFloat64(bps, str)
+	if err != nil {
+		return 0, err
+	}
+
+	if v < 0 {
+		return 0, errors.New("must not be negative")
+	}
+
+	return v, nil
+}
+
+func maintenanceThrottleString(v float64, convert func(v float64) string) string {
+	if v == 0 {
+		return "unlimited"
+	}
+
+	return convert(v)
 }
 
 func (c *commandMaintenanceSet) setup(svc appServices, parent commandParent) {
@@ -50,11 +133,20 @@ func (c *commandMaintenanceSet) setup(svc appServices, parent commandParent) {
 	cmd.Flag("pause-quick", "Pause quick maintenance for a specified duration").DurationVar(&c.maintenanceSetPauseQuick)
 	cmd.Flag("pause-full", "Pause full maintenance for a specified duration").DurationVar(&c.maintenanceSetPauseFull)
 
+	cmd.Flag("quick-window", "Replace time windows when quick maintenance may start automatically (e.g. 22:00-06:00)").StringsVar(&c.maintenanceSetQuickWindows)
+	cmd.Flag("full-window", "Replace time windows when full maintenance may start automatically (e.g. 22:00-06:00)").StringsVar(&c.maintenanceSetFullWindows)
+	cmd.Flag("clear-quick-windows", "Allow quick maintenance to start at any time").BoolVar(&c.maintenanceClearQuickWindows)
+	cmd.Flag("clear-full-windows", "Allow full maintenance to start at any time").BoolVar(&c.maintenanceClearFullWindows)
+
 	cmd.Flag("max-retained-log-count", "Set maximum number of log sessions to retain").IntVar(&c.maxRetainedLogCount)
 	cmd.Flag("max-retained-log-age", "Set maximum age of log sessions to retain").DurationVar(&c.maxRetainedLogAge)
 	cmd.Flag("max-retained-log-size-mb", "Set maximum total size of log sessions").Int64Var(&c.maxTotalRetainedLogSizeMB)
 	cmd.Flag("extend-object-locks", "Extend retention period of locked objects as part of full maintenance.").BoolListVar(&c.extendObjectLocks)
 
+	c.contentRewriteThrottle.setup(cmd, "content-rewrite", "content rewrite")
+	c.blobDeletionThrottle.setup(cmd, "blob-deletion", "blob deletion")
+	c.indexCompactionThrottle.setup(cmd, "index-compaction", "index compaction")
+
 	cmd.Action(svc.directRepositoryWriteAction(c.run))
 }
 
@@ -124,6 +216,43 @@ func (c *commandMaintenanceSet) setMaintenanceEnabledAndIntervalFromFlags(ctx co
 	}
 }
 
+func (c *commandMaintenanceSet) setMaintenanceWindowsFromFlags(ctx context.Context, cp *maintenance.CycleParams, cycleName string, windows []string, clearWindows bool, changed *bool) error {
+	if clearWindows {
+		if len(windows) > 0 {
+			return errors.Errorf("--%v-window and --clear-%v-windows are mutually exclusive", cycleName, cycleName)
+		}
+
+		cp.Windows = nil
+		*changed = true
+
+		log(ctx).Infof("Periodic %v maintenance may start at any time.", cycleName)
+
+		return nil
+	}
+
+	if len(windows) == 0 {
+		return nil
+	}
+
+	var result []maintenance.TimeWindow
+
+	for _, str := range windows {
+		w, err := maintenance.ParseTimeWindow(str)
+		if err != nil {
+			return errors.Wrapf(err, "can't parse the %v maintenance window %q", cycleName, str)
+		}
+
+		log(ctx).Infof("Periodic %v maintenance may start between %v and %v.", cycleName, w.Start, w.End)
+
+		result = append(result, w)
+	}
+
+	cp.Windows = result
+	*changed = true
+
+	return nil
+}
+
 func (c *commandMaintenanceSet) setMaintenanceObjectLockExtendFromFlags(ctx context.Context, p *maintenance.Params, changed *bool) {
 	// we use lists to distinguish between flag not set
 	// Zero elements == not set, more than zero - flag set, in which case we pick the last value
@@ -157,8 +286,28 @@ func (c *commandMaintenanceSet) run(ctx context.Context, rep repo.DirectReposito
 	c.setMaintenanceEnabledAndIntervalFromFlags(ctx, &p.QuickCycle, "quick", c.maintenanceSetEnableQuick, c.maintenanceSetQuickFrequency, &changedParams)
 	c.setMaintenanceEnabledAndIntervalFromFlags(ctx, &p.FullCycle, "full", c.maintenanceSetEnableFull, c.maintenanceSetFullFrequency, &changedParams)
 	c.setLogCleanupParametersFromFlags(ctx, p, &changedParams)
+
+	if err := c.setMaintenanceWindowsFromFlags(ctx, &p.QuickCycle, "quick", c.maintenanceSetQuickWindows, c.maintenanceClearQuickWindows, &changedParams); err != nil {
+		return err
+	}
+
+	if err := c.setMaintenanceWindowsFromFlags(ctx, &p.FullCycle, "full", c.maintenanceSetFullWindows, c.maintenanceClearFullWindows, &changedParams); err != nil {
+		return err
+	}
 	c.setMaintenanceObjectLockExtendFromFlags(ctx, p, &changedParams)
 
+	if err := c.contentRewriteThrottle.apply(ctx, &p.Throttling.ContentRewrite, &changedParams); err != nil {
+		return err
+	}
+
+	if err := c.blobDeletionThrottle.apply(ctx, &p.Throttling.BlobDeletion, &changedParams); err != nil {
+		return err
+	}
+
+	if err := c.indexCompactionThrottle.apply(ctx, &p.Throttling.IndexCompaction, &changedParams); err != nil {
+		return err
+	}
+
 	if pauseDuration := c.maintenanceSetPauseQuick; pauseDuration != -1 {
 		s.NextQuickMaintenanceTime = rep.Time().Add(pauseDuration)
 		changedSchedule = true
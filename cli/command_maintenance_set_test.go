@@ -39,6 +39,81 @@ func TestMaintenanceSetExtendObjectLocks(t *testing.T) {
 	require.False(t, mi.ExtendObjectLocks, "ExtendOjectLocks should be disabled.")
 }
 
+func TestMaintenanceSetThrottling(t *testing.T) {
+	t.Parallel()
+
+	e := testenv.NewCLITest(t, testenv.RepoFormatNotImportant, testenv.NewInProcRunner(t))
+	defer e.RunAndExpectSuccess(t, "repo", "disconnect")
+
+	var mi cli.MaintenanceInfo
+
+	e.RunAndExpectSuccess(t, "repo", "create", "filesystem", "--path", e.RepoDir)
+
+	e.RunAndExpectSuccess(t, "maintenance", "set",
+		"--content-rewrite-bytes-per-second", "1MiB",
+		"--blob-deletion-requests-per-second", "20",
+		"--index-compaction-bytes-per-second", "2MB",
+		"--index-compaction-requests-per-second", "5")
+
+	testutil.MustParseJSONLines(t, e.RunAndExpectSuccess(t, "maintenance", "info", "--json"), &mi)
+
+	require.InDelta(t, 1<<20, mi.Throttling.ContentRewrite.UploadBytesPerSecond, 1e-9)
+	require.InDelta(t, 1<<20, mi.Throttling.ContentRewrite.DownloadBytesPerSecond, 1e-9)
+	require.InDelta(t, 0, mi.Throttling.ContentRewrite.ReadsPerSecond, 1e-9)
+	require.InDelta(t, 20, mi.Throttling.BlobDeletion.WritesPerSecond, 1e-9)
+	require.InDelta(t, 0, mi.Throttling.BlobDeletion.UploadBytesPerSecond, 1e-9)
+	require.InDelta(t, 2e6, mi.Throttling.IndexCompaction.UploadBytesPerSecond, 1e-9)
+	require.InDelta(t, 5, mi.Throttling.IndexCompaction.ListsPerSecond, 1e-9)
+
+	infoLines := e.RunAndExpectSuccess(t, "maintenance", "info")
+	mustGetLineContaining(t, infoLines, "Content Rewrite Throttling:")
+	mustGetLineContaining(t, infoLines, "max writes/sec:     20")
+
+	// maintenance and commands performing individual phases run with the limits applied.
+	e.RunAndExpectSuccess(t, "maintenance", "run", "--full", "--safety=none")
+	e.RunAndExpectSuccess(t, "content", "rewrite", "--short", "--safety=none")
+	e.RunAndExpectSuccess(t, "blob", "gc", "--safety=none")
+
+	e.RunAndExpectSuccess(t, "maintenance", "set", "--content-rewrite-bytes-per-second", "unlimited")
+	e.RunAndExpectFailure(t, "maintenance", "set", "--blob-deletion-requests-per-second", "-5")
+
+	mi = cli.MaintenanceInfo{}
+	testutil.MustParseJSONLines(t, e.RunAndExpectSuccess(t, "maintenance", "info", "--json"), &mi)
+
+	require.InDelta(t, 0, mi.Throttling.ContentRewrite.UploadBytesPerSecond, 1e-9)
+	require.InDelta(t, 20, mi.Throttling.BlobDeletion.WritesPerSecond, 1e-9)
+}
+
+func TestMaintenanceSetWindows(t *testing.T) {
+	t.Parallel()
+
+	e := testenv.NewCLITest(t, testenv.RepoFormatNotImportant, testenv.NewInProcRunner(t))
+	defer e.RunAndExpectSuccess(t, "repo", "disconnect")
+
+	e.RunAndExpectSuccess(t, "repo", "create", "filesystem", "--path", e.RepoDir)
+
+	e.RunAndExpectSuccess(t, "maintenance", "set", "--full-window", "22:00-04:00", "--full-window", "12:00-13:00")
+
+	var mi cli.MaintenanceInfo
+
+	testutil.MustParseJSONLines(t, e.RunAndExpectSuccess(t, "maintenance", "info", "--json"), &mi)
+	require.Len(t, mi.FullCycle.Windows, 2)
+	require.Equal(t, "22:00-4:00", mi.FullCycle.Windows[0].String())
+	require.Equal(t, "12:00-13:00", mi.FullCycle.Windows[1].String())
+	require.Empty(t, mi.QuickCycle.Windows)
+
+	mustGetLineContaining(t, e.RunAndExpectSuccess(t, "maintenance", "info"), "windows: 22:00-4:00, 12:00-13:00")
+
+	e.RunAndExpectFailure(t, "maintenance", "set", "--full-window", "22:00")
+	e.RunAndExpectFailure(t, "maintenance", "set", "--full-window", "22:00-04:00", "--clear-full-windows")
+
+	e.RunAndExpectSuccess(t, "maintenance", "set", "--clear-full-windows")
+
+	mi = cli.MaintenanceInfo{}
+	testutil.MustParseJSONLines(t, e.RunAndExpectSuccess(t, "maintenance", "info", "--json"), &mi)
+	require.Empty(t, mi.FullCycle.Windows)
+}
+
 func (s *formatSpecificTestSuite) TestInvalidExtendRetainOptions(t *testing.T) {
 	var mi cli.MaintenanceInfo
 
@@ -0,0 +1,55 @@
+package cli
+
+import (
+	"context"
+	"io"
+	"os"
+
+	"github.com/pkg/errors"
+
+	"github.com/kopia/kopia/internal/units"
+	"github.com/kopia/kopia/repo"
+	"github.com/kopia/kopia/repo/maintenance"
+	"github.com/kopia/kopia/snapshot/snapshotgc"
+)
+
+type commandMaintenanceUnreferenced struct {
+	output string
+	safety maintenance.SafetyParameters
+
+	out textOutput
+}
+
+func (c *commandMaintenanceUnreferenced) setup(svc appServices, parent commandParent) {
+	cmd := parent.Command("unreferenced", "Write JSON report of contents not referenced by any snapshot, which full maintenance would delete")
+	cmd.Flag("output", "Output file (default is standard output)").Short('o').PlaceHolder("FILE").StringVar(&c.output)
+	safetyFlagVar(cmd, &c.safety)
+	cmd.Action(svc.directRepositoryReadAction(c.run))
+	c.out.setup(svc)
+}
+
+func (c *commandMaintenanceUnreferenced) run(ctx context.Context, rep repo.DirectRepository) error {
+	var w io.Writer = c.out.stdout()
+
+	if c.output != "" {
+		f, err := os.Create(c.output)
+		if err != nil {
+			return errors.Wrap(err, "unable to create report file")
+		}
+
+		defer f.Close() //nolint:errcheck
+
+		w = f
+	}
+
+	summary, err := snapshotgc.ExportUnreferencedContents(ctx, rep, c.safety, rep.Time(), w)
+	if err != nil {
+		return errors.Wrap(err, "unable to export unreferenced contents")
+	}
+
+	c.out.printStderr("Full maintenance would delete %v unreferenced contents (%v), %v more are too recent to delete (%v).\n",
+		summary.UnusedCount, units.BytesString(summary.UnusedBytes),
+		summary.TooRecentCount, units.BytesString(summary.TooRecentBytes))
+
+	return nil
+}
@@ -0,0 +1,89 @@
+package cli_test
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/kopia/kopia/internal/testutil"
+	"github.com/kopia/kopia/snapshot/snapshotgc"
+	"github.com/kopia/kopia/tests/testenv"
+)
+
+type unreferencedContentReport struct {
+	Contents []snapshotgc.UnreferencedContent      `json:"contents"`
+	Summary  snapshotgc.UnreferencedContentSummary `json:"summary"`
+}
+
+func TestMaintenanceUnreferenced(t *testing.T) {
+	t.Parallel()
+
+	e := testenv.NewCLITest(t, testenv.RepoFormatNotImportant, testenv.NewInProcRunner(t))
+	defer e.RunAndExpectSuccess(t, "repo", "disconnect")
+
+	e.RunAndExpectSuccess(t, "repo", "create", "filesystem", "--path", e.RepoDir)
+
+	srcDir := testutil.TempDirectory(t)
+	require.NoError(t, os.WriteFile(filepath.Join(srcDir, "a.txt"), []byte("some contents"), 0o644))
+
+	e.RunAndExpectSuccess(t, "snapshot", "create", srcDir)
+
+	// nothing is unreferenced while the snapshot exists.
+	var rep unreferencedContentReport
+
+	require.NoError(t, json.Unmarshal([]byte(strings.Join(e.RunAndExpectSuccess(t, "maintenance", "unreferenced", "--safety=none"), "\n")), &rep))
+	require.Empty(t, rep.Contents)
+
+	e.RunAndExpectSuccess(t, "snapshot", "delete", "--all-snapshots-for-source", srcDir, "--delete")
+
+	// with full safety, recently written contents are reported as too recent to delete.
+	reportFile := filepath.Join(testutil.TempDirectory(t), "unreferenced.json")
+	e.RunAndExpectSuccess(t, "maintenance", "unreferenced", "--output", reportFile)
+
+	rep = unreferencedContentReport{}
+	readUnreferencedContentReport(t, reportFile, &rep)
+	require.NotEmpty(t, rep.Contents)
+	require.Equal(t, len(rep.Contents), int(rep.Summary.TooRecentCount))
+	require.Zero(t, rep.Summary.UnusedCount)
+
+	for _, c := range rep.Contents {
+		require.True(t, c.TooRecent)
+		require.False(t, c.Deleted)
+	}
+
+	wantIDs := unreferencedContentIDs(rep)
+
+	// full maintenance writes the same report before deleting the contents.
+	e.RunAndExpectSuccess(t, "maintenance", "run", "--full", "--safety=none", "--export-unreferenced", reportFile)
+
+	rep = unreferencedContentReport{}
+	readUnreferencedContentReport(t, reportFile, &rep)
+	require.Equal(t, wantIDs, unreferencedContentIDs(rep))
+	require.Equal(t, len(rep.Contents), int(rep.Summary.UnusedCount))
+
+	for _, c := range rep.Contents {
+		require.False(t, c.TooRecent)
+	}
+}
+
+func readUnreferencedContentReport(t *testing.T, fname string, rep *unreferencedContentReport) {
+	t.Helper()
+
+	b, err := os.ReadFile(fname)
+	require.NoError(t, err)
+	require.NoError(t, json.Unmarshal(b, rep))
+}
+
+func unreferencedContentIDs(rep unreferencedContentReport) []string {
+	var result []string
+
+	for _, c := range rep.Contents {
+		result = append(result, c.ContentID.String())
+	}
+
+	return result
+}
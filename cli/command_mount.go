@@ -2,6 +2,7 @@
 
 import (
 	"context"
+	"strconv"
 
 	"github.com/pkg/errors"
 	"github.com/skratchdot/open-golang/open"
@@ -24,6 +25,8 @@ type commandMount struct {
 	mountPreferWebDAV           bool
 	maxCachedEntries            int
 	maxCachedDirectories        int
+	readAheadMB                 int64
+	readAheadParallelism        int
 
 	svc appServices
 }
@@ -42,6 +45,8 @@ func (c *commandMount) setup(svc appServices, parent commandParent) {
 
 	cmd.Flag("max-cached-entries", "Limit the number of cached directory entries").Default("100000").IntVar(&c.maxCachedEntries)
 	cmd.Flag("max-cached-dirs", "Limit the number of cached directories").Default("100").IntVar(&c.maxCachedDirectories)
+	cmd.Flag("read-ahead-mb", "Prefetch contents of files being read sequentially up to the specified amount of data (in MB) ahead (0 disables).").PlaceHolder("MB").Default(strconv.Itoa(snapshotfs.DefaultReadAheadWindow >> 20)).Int64Var(&c.readAheadMB)
+	cmd.Flag("read-ahead-parallelism", "Number of parallel prefetches for each file being read sequentially.").PlaceHolder("N").Default("0").IntVar(&c.readAheadParallelism)
 
 	c.svc = svc
 	cmd.Action(svc.repositoryReaderAction(c.run))
@@ -68,6 +73,12 @@ func (c *commandMount) run(ctx context.Context, rep repo.Repository) error {
 		}
 	}
 
+	//nolint:forcetypeassert
+	entry = snapshotfs.WithReadAhead(rep, entry, snapshotfs.ReadAheadOptions{
+		Window:      c.readAheadMB << 20, //nolint:mnd
+		Parallelism: c.readAheadParallelism,
+	}).(fs.Directory)
+
 	if c.mountTraceFS {
 		//nolint:forcetypeassert
 		entry = loggingfs.Wrap(entry, log(ctx).Debugf).(fs.Directory)
@@ -13,17 +13,19 @@
 )
 
 type commandPolicy struct {
-	edit   commandPolicyEdit
-	list   commandPolicyList
-	delete commandPolicyDelete
-	set    commandPolicySet
-	show   commandPolicyShow
+	edit    commandPolicyEdit
+	explain commandPolicyExplain
+	list    commandPolicyList
+	delete  commandPolicyDelete
+	set     commandPolicySet
+	show    commandPolicyShow
 }
 
 func (c *commandPolicy) setup(svc appServices, parent commandParent) {
 	cmd := parent.Command("policy", "Commands to manipulate snapshotting policies.").Alias("policies")
 
 	c.edit.setup(svc, cmd)
+	c.explain.setup(svc, cmd)
 	c.list.setup(svc, cmd)
 	c.delete.setup(svc, cmd)
 	c.set.setup(svc, cmd)
@@ -35,8 +37,8 @@ type policyTargetFlags struct {
 	global  bool
 }
 
-func (c *policyTargetFlags) setup(cmd *kingpin.CmdClause) {
-	cmd.Arg("target", "Select a particular policy ('user@host','@host','user@host:path' or a local path). Use --global to target the global policy.").StringsVar(&c.targets)
+func (c *policyTargetFlags) setup(svc appServices, cmd *kingpin.CmdClause) {
+	cmd.Arg("target", "Select a particular policy ('user@host','@host','user@host:path' or a local path). Use --global to target the global policy.").HintAction(svc.policyTargetHints).StringsVar(&c.targets)
 	cmd.Flag("global", "Select the global policy.").BoolVar(&c.global)
 }
 
@@ -58,7 +58,7 @@ type commandPolicyEdit struct {
 
 func (c *commandPolicyEdit) setup(svc appServices, parent commandParent) {
 	cmd := parent.Command("edit", "Set snapshot policy for a single directory, user@host or a global policy.")
-	c.policyTargetFlags.setup(cmd)
+	c.policyTargetFlags.setup(svc, cmd)
 	cmd.Action(svc.repositoryWriterAction(c.run))
 	c.out.setup(svc)
 }
@@ -0,0 +1,150 @@
+package cli
+
+import (
+	"context"
+	"path/filepath"
+	"strings"
+
+	"github.com/pkg/errors"
+
+	"github.com/kopia/kopia/fs"
+	"github.com/kopia/kopia/fs/ignorefs"
+	"github.com/kopia/kopia/repo"
+	"github.com/kopia/kopia/snapshot"
+	"github.com/kopia/kopia/snapshot/policy"
+)
+
+type commandPolicyExplain struct {
+	path   string
+	source string
+
+	jo  jsonOutput
+	out textOutput
+}
+
+func (c *commandPolicyExplain) setup(svc appServices, parent commandParent) {
+	cmd := parent.Command("explain", "Explain which policy or ignore rule causes a file or directory to be included in or excluded from snapshots.")
+	cmd.Arg("path", "File or directory to explain").Required().StringVar(&c.path)
+	cmd.Flag("source", "Snapshot source directory containing the path (default is the closest snapshotted parent directory)").StringVar(&c.source)
+	c.jo.setup(svc, cmd)
+	c.out.setup(svc)
+	cmd.Action(svc.repositoryReaderAction(c.run))
+}
+
+type policyExplanation struct {
+	Source snapshot.SourceInfo `json:"source"`
+	*ignorefs.Explanation
+}
+
+func (c *commandPolicyExplain) run(ctx context.Context, rep repo.Repository) error {
+	path, err := filepath.Abs(c.path)
+	if err != nil {
+		return errors.Wrapf(err, "invalid path: %v", c.path)
+	}
+
+	root, err := c.sourceRoot(ctx, rep, path)
+	if err != nil {
+		return err
+	}
+
+	rel, err := filepath.Rel(root, path)
+	if err != nil || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+		return errors.Errorf("%v is not inside of %v", path, root)
+	}
+
+	entry, err := getLocalFSEntry(ctx, root)
+	if err != nil {
+		return err
+	}
+
+	dir, ok := entry.(fs.Directory)
+	if !ok {
+		return errors.Errorf("%v is not a directory", root)
+	}
+
+	sourceInfo := snapshot.SourceInfo{
+		Path:     root,
+		Host:     rep.ClientOptions().Hostname,
+		UserName: rep.ClientOptions().Username,
+	}
+
+	policyTree, err := policy.TreeForSource(ctx, rep, sourceInfo)
+	if err != nil {
+		return errors.Wrapf(err, "error creating policy tree for %v", sourceInfo)
+	}
+
+	ex, err := ignorefs.Explain(ctx, dir, policyTree, filepath.ToSlash(rel))
+	if err != nil {
+		return errors.Wrap(err, "unable to explain")
+	}
+
+	if c.jo.jsonOutput {
+		c.jo.printJSON(&policyExplanation{sourceInfo, ex})
+		return nil
+	}
+
+	c.printExplanation(root, path, sourceInfo, ex)
+
+	return nil
+}
+
+func (c *commandPolicyExplain) printExplanation(root, path string, sourceInfo snapshot.SourceInfo, ex *ignorefs.Explanation) {
+	c.out.printStdout("Snapshot source: %v\n", sourceInfo)
+
+	if ex.Included {
+		c.out.printStdout("%v is included in snapshots.\n", path)
+	} else {
+		c.out.printStdout("%v is excluded from snapshots.\n", path)
+		c.out.printStdout("  Reason: %v\n", ex.Reason)
+
+		if excluded := filepath.Join(root, filepath.FromSlash(ex.ExcludedPath)); excluded != path {
+			c.out.printStdout("  Excluded parent directory: %v\n", excluded)
+		}
+	}
+
+	if ex.Rule == nil {
+		return
+	}
+
+	if ex.Rule.Line == 0 {
+		c.out.printStdout("  Rule: %q defined in policy for %v\n", ex.Rule.Pattern, filepath.Join(root, filepath.FromSlash(ex.Rule.Path)))
+	} else {
+		c.out.printStdout("  Rule: %q defined in %v, line %v\n", ex.Rule.Pattern, filepath.Join(root, filepath.FromSlash(ex.Rule.Path)), ex.Rule.Line)
+	}
+}
+
+// sourceRoot returns the directory that snapshots containing the provided path are taken of.
+func (c *commandPolicyExplain) sourceRoot(ctx context.Context, rep repo.Repository, path string) (string, error) {
+	if c.source != "" {
+		root, err := filepath.Abs(c.source)
+		return root, errors.Wrapf(err, "invalid source: %v", c.source)
+	}
+
+	sources, err := snapshot.ListSources(ctx, rep)
+	if err != nil {
+		return "", errors.Wrap(err, "unable to list sources")
+	}
+
+	best := ""
+
+	for _, si := range sources {
+		if si.Host != rep.ClientOptions().Hostname || si.UserName != rep.ClientOptions().Username {
+			continue
+		}
+
+		if si.Path != path && !strings.HasPrefix(path, strings.TrimSuffix(si.Path, string(filepath.Separator))+string(filepath.Separator)) {
+			continue
+		}
+
+		if len(si.Path) > len(best) {
+			best = si.Path
+		}
+	}
+
+	if best == "" {
+		// not snapshotted yet, assume the parent directory would be.
+		return filepath.Dir(path), nil
+	}
+
+	return best, nil
+}
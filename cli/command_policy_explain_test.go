@@ -0,0 +1,66 @@
+package cli_test
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/kopia/kopia/fs/ignorefs"
+	"github.com/kopia/kopia/internal/testutil"
+	"github.com/kopia/kopia/snapshot"
+	"github.com/kopia/kopia/tests/testenv"
+)
+
+func TestPolicyExplain(t *testing.T) {
+	t.Parallel()
+
+	env := testenv.NewCLITest(t, testenv.RepoFormatNotImportant, testenv.NewInProcRunner(t))
+
+	env.RunAndExpectSuccess(t, "repo", "create", "filesystem", "--path", env.RepoDir)
+
+	srcDir := testutil.TempDirectory(t)
+	require.NoError(t, os.MkdirAll(filepath.Join(srcDir, "build", "out"), 0o755))
+	require.NoError(t, os.WriteFile(filepath.Join(srcDir, ".kopiaignore"), []byte("# build outputs\n/build\n"), 0o644))
+	require.NoError(t, os.WriteFile(filepath.Join(srcDir, "build", "out", "a.o"), []byte("a"), 0o644))
+	require.NoError(t, os.WriteFile(filepath.Join(srcDir, "main.c"), []byte("int main() {}"), 0o644))
+	require.NoError(t, os.WriteFile(filepath.Join(srcDir, "main.tmp"), []byte("tmp"), 0o644))
+
+	env.RunAndExpectSuccess(t, "policy", "set", srcDir, "--add-ignore", "*.tmp")
+	env.RunAndExpectSuccess(t, "snapshot", "create", srcDir)
+
+	type explanation struct {
+		Source snapshot.SourceInfo `json:"source"`
+		ignorefs.Explanation
+	}
+
+	var ex explanation
+
+	testutil.MustParseJSONLines(t, env.RunAndExpectSuccess(t, "policy", "explain", "--json", filepath.Join(srcDir, "main.c")), &ex)
+	require.True(t, ex.Included)
+	require.Nil(t, ex.Rule)
+	require.Equal(t, srcDir, ex.Source.Path)
+
+	testutil.MustParseJSONLines(t, env.RunAndExpectSuccess(t, "policy", "explain", "--json", filepath.Join(srcDir, "main.tmp")), &ex)
+	require.False(t, ex.Included)
+	require.Equal(t, ignorefs.IgnoreReasonRule, ex.Reason)
+	require.Equal(t, &ignorefs.IgnoreRule{Pattern: "*.tmp", Path: "."}, ex.Rule)
+
+	ex = explanation{}
+	testutil.MustParseJSONLines(t, env.RunAndExpectSuccess(t, "policy", "explain", "--json", filepath.Join(srcDir, "build", "out", "a.o")), &ex)
+	require.False(t, ex.Included)
+	require.Equal(t, "build", ex.ExcludedPath)
+	require.Equal(t, &ignorefs.IgnoreRule{Pattern: "/build", Path: ".kopiaignore", Line: 2}, ex.Rule)
+
+	lines := env.RunAndExpectSuccess(t, "policy", "explain", filepath.Join(srcDir, "build", "out", "a.o"))
+	require.Contains(t, lines, "  Excluded parent directory: "+filepath.Join(srcDir, "build"))
+	require.Contains(t, lines, `  Rule: "/build" defined in `+filepath.Join(srcDir, ".kopiaignore")+", line 2")
+
+	// explicitly provided source must contain the path.
+	env.RunAndExpectFailure(t, "policy", "explain", "--source", filepath.Join(srcDir, "build"), filepath.Join(srcDir, "main.c"))
+
+	// excluded files are logged when creating snapshots.
+	_, stderr := env.RunAndExpectSuccessWithErrOut(t, "snapshot", "create", "--explain-excludes", srcDir)
+	require.Contains(t, stderr, `excluded main.tmp (ignore-rule): "*.tmp" in policy for .`)
+}
@@ -2,6 +2,7 @@
 
 import (
 	"context"
+	"strings"
 
 	"github.com/alecthomas/kingpin/v2"
 	"github.com/pkg/errors"
@@ -14,6 +15,7 @@ type policyCompressionFlags struct {
 	policySetCompressionAlgorithm string
 	policySetCompressionMinSize   string
 	policySetCompressionMaxSize   string
+	policySetCompressionAdaptive  string
 
 	policySetAddOnlyCompress    []string
 	policySetRemoveOnlyCompress []string
@@ -22,6 +24,10 @@ type policyCompressionFlags struct {
 	policySetAddNeverCompress    []string
 	policySetRemoveNeverCompress []string
 	policySetClearNeverCompress  bool
+
+	policySetAddCompressionRule    []string
+	policySetRemoveCompressionRule []string
+	policySetClearCompressionRules bool
 }
 
 func (c *policyCompressionFlags) setup(cmd *kingpin.CmdClause) {
@@ -29,6 +35,7 @@ func (c *policyCompressionFlags) setup(cmd *kingpin.CmdClause) {
 	cmd.Flag("compression", "Compression algorithm").EnumVar(&c.policySetCompressionAlgorithm, supportedCompressionAlgorithms()...)
 	cmd.Flag("compression-min-size", "Min size of file to attempt compression for").StringVar(&c.policySetCompressionMinSize)
 	cmd.Flag("compression-max-size", "Max size of file to attempt compression for").StringVar(&c.policySetCompressionMaxSize)
+	cmd.Flag("compression-adaptive", "Skip compression of data which appears incompressible ('true', 'false', 'inherit')").EnumVar(&c.policySetCompressionAdaptive, booleanEnumValues...)
 
 	// Files to only compress.
 	cmd.Flag("add-only-compress", "List of extensions to add to the only-compress list").PlaceHolder("PATTERN").StringsVar(&c.policySetAddOnlyCompress)
@@ -39,6 +46,11 @@ func (c *policyCompressionFlags) setup(cmd *kingpin.CmdClause) {
 	cmd.Flag("add-never-compress", "List of extensions to add to the never compress list").PlaceHolder("PATTERN").StringsVar(&c.policySetAddNeverCompress)
 	cmd.Flag("remove-never-compress", "List of extensions to remove from the never compress list").PlaceHolder("PATTERN").StringsVar(&c.policySetRemoveNeverCompress)
 	cmd.Flag("clear-never-compress", "Clear list of extensions in the never compress list").BoolVar(&c.policySetClearNeverCompress)
+
+	// Compression algorithms for file extensions or MIME types.
+	cmd.Flag("add-compression-rule", "Use compression algorithm (or 'none') for files with the extension (.ext) or MIME type (type/subtype or type/*)").PlaceHolder("PATTERN=ALGORITHM").StringsVar(&c.policySetAddCompressionRule)
+	cmd.Flag("remove-compression-rule", "Remove compression rule for the extension or MIME type").PlaceHolder("PATTERN").StringsVar(&c.policySetRemoveCompressionRule)
+	cmd.Flag("clear-compression-rules", "Clear list of compression rules").BoolVar(&c.policySetClearCompressionRules)
 }
 
 func (c *policyCompressionFlags) setCompressionPolicyFromFlags(ctx context.Context, p *policy.CompressionPolicy, changeCount *int) error {
@@ -50,6 +62,10 @@ func (c *policyCompressionFlags) setCompressionPolicyFromFlags(ctx context.Conte
 		return errors.Wrap(err, "maximum file size subject to compression")
 	}
 
+	if err := applyPolicyBoolPtr(ctx, "adaptive compression", &p.Adaptive, c.policySetCompressionAdaptive, changeCount); err != nil {
+		return errors.Wrap(err, "adaptive compression")
+	}
+
 	if v := c.policySetCompressionAlgorithm; v != "" {
 		*changeCount++
 
@@ -70,5 +86,56 @@ func (c *policyCompressionFlags) setCompressionPolicyFromFlags(ctx context.Conte
 	applyPolicyStringList(ctx, "never-compress extensions",
 		&p.NeverCompress, c.policySetAddNeverCompress, c.policySetRemoveNeverCompress, c.policySetClearNeverCompress, changeCount)
 
+	return c.applyCompressionRules(ctx, p, changeCount)
+}
+
+func (c *policyCompressionFlags) applyCompressionRules(ctx context.Context, p *policy.CompressionPolicy, changeCount *int) error {
+	if c.policySetClearCompressionRules {
+		log(ctx).Info(" - removing all compression rules")
+
+		*changeCount++
+
+		p.Rules = nil
+	}
+
+	for _, v := range c.policySetAddCompressionRule {
+		pattern, algorithm, ok := strings.Cut(v, "=")
+		if !ok || pattern == "" || algorithm == "" {
+			return errors.Errorf("invalid compression rule %q, expected PATTERN=ALGORITHM", v)
+		}
+
+		rule := policy.CompressionRule{Pattern: pattern, Compressor: compression.Name(algorithm)}
+
+		*changeCount++
+
+		log(ctx).Infof(" - setting compression algorithm for %v to %v", pattern, algorithm)
+
+		if i := findCompressionRule(p.Rules, pattern); i >= 0 {
+			p.Rules[i] = rule
+		} else {
+			p.Rules = append(p.Rules, rule)
+		}
+	}
+
+	for _, pattern := range c.policySetRemoveCompressionRule {
+		*changeCount++
+
+		log(ctx).Infof(" - removing compression rule for %v", pattern)
+
+		if i := findCompressionRule(p.Rules, pattern); i >= 0 {
+			p.Rules = append(p.Rules[:i], p.Rules[i+1:]...)
+		}
+	}
+
 	return nil
 }
+
+func findCompressionRule(rules []policy.CompressionRule, pattern string) int {
+	for i, r := range rules {
+		if strings.EqualFold(r.Pattern, pattern) {
+			return i
+		}
+	}
+
+	return -1
+}
@@ -4,7 +4,9 @@
 	"context"
 
 	"github.com/alecthomas/kingpin/v2"
+	"github.com/pkg/errors"
 
+	"github.com/kopia/kopia/internal/units"
 	"github.com/kopia/kopia/snapshot/policy"
 )
 
@@ -12,12 +14,16 @@ type policyUploadFlags struct {
 	maxParallelUploads            string
 	maxParallelFileReads          string
 	parallelizeUploadAboveSizeMiB string
+	maxUploadSpeed                string
+	maxConcurrentUploads          string
 }
 
 func (c *policyUploadFlags) setup(cmd *kingpin.CmdClause) {
 	cmd.Flag("max-parallel-file-reads", "Maximum number of parallel file reads").StringVar(&c.maxParallelFileReads)
 	cmd.Flag("max-parallel-snapshots", "Maximum number of parallel snapshots (server, KopiaUI only)").StringVar(&c.maxParallelUploads)
 	cmd.Flag("parallel-upload-above-size-mib", "Use parallel uploads above size").StringVar(&c.parallelizeUploadAboveSizeMiB)
+	cmd.Flag("max-upload-speed", "Maximum upload speed of snapshots of the source (e.g. 10MB, 1.5MiB/s)").StringVar(&c.maxUploadSpeed)
+	cmd.Flag("max-concurrent-uploads", "Maximum number of concurrent uploads of snapshots of the source").StringVar(&c.maxConcurrentUploads)
 }
 
 func (c *policyUploadFlags) setUploadPolicyFromFlags(ctx context.Context, up *policy.UploadPolicy, changeCount *int) error {
@@ -29,5 +35,44 @@ func (c *policyUploadFlags) setUploadPolicyFromFlags(ctx context.Context, up *po
 		return err
 	}
 
-	return applyOptionalInt64MiB(ctx, "parallel upload above size", &up.ParallelUploadAboveSize, c.parallelizeUploadAboveSizeMiB, changeCount)
+	if err := applyOptionalInt64MiB(ctx, "parallel upload above size", &up.ParallelUploadAboveSize, c.parallelizeUploadAboveSizeMiB, changeCount); err != nil {
+		return err
+	}
+
+	if err := applyOptionalInt64Speed(ctx, "max upload speed", &up.MaxUploadBytesPerSecond, c.maxUploadSpeed, changeCount); err != nil {
+		return err
+	}
+
+	return applyOptionalInt(ctx, "max concurrent uploads", &up.MaxConcurrentUploads, c.maxConcurrentUploads, changeCount)
+}
+
+func applyOptionalInt64Speed(ctx context.Context, desc string, val **policy.OptionalInt64, str string, changeCount *int) error {
+	if str == "" {
+		// not changed
+		return nil
+	}
+
+	if str == inheritPolicyString || str == defaultPolicyString {
+		*changeCount++
+
+		log(ctx).Infof(" - resetting %q to a default value inherited from parent.", desc)
+
+		*val = nil
+
+		return nil
+	}
+
+	v, err := parseThrottleFloat64(true, str)
+	if err != nil {
+		return errors.Wrapf(err, "can't parse the %v %q", desc, str)
+	}
+
+	i := policy.OptionalInt64(v)
+	*changeCount++
+
+	log(ctx).Infof(" - setting %q to %v.", desc, units.BytesPerSecondsString(v))
+
+	*val = &i
+
+	return nil
 }
@@ -47,4 +47,25 @@ func TestSetUploadPolicy(t *testing.T) {
 	require.Contains(t, lines, " Max parallel snapshots (server/UI): 1 inherited from (global)")
 	require.Contains(t, lines, " Max parallel file reads: - inherited from (global)")
 	require.Contains(t, lines, " Parallel upload above size: 2.1 GB inherited from (global)")
+
+	// per-source upload limits.
+	e.RunAndExpectSuccess(t, "policy", "set", td, "--max-upload-speed=10MB/s", "--max-concurrent-uploads=2")
+
+	lines = e.RunAndExpectSuccess(t, "policy", "show", td)
+	lines = compressSpaces(lines)
+
+	require.Contains(t, lines, " Max upload speed: 10 MB/s (defined for this target)")
+	require.Contains(t, lines, " Max concurrent uploads: 2 (defined for this target)")
+
+	e.RunAndExpectFailure(t, "policy", "set", td, "--max-concurrent-uploads=-1")
+
+	e.RunAndExpectSuccess(t, "snapshot", "create", td)
+
+	e.RunAndExpectSuccess(t, "policy", "set", td, "--max-upload-speed=default", "--max-concurrent-uploads=default")
+
+	lines = e.RunAndExpectSuccess(t, "policy", "show", td)
+	lines = compressSpaces(lines)
+
+	require.Contains(t, lines, " Max upload speed: - inherited from (global)")
+	require.Contains(t, lines, " Max concurrent uploads: - inherited from (global)")
 }
@@ -22,7 +22,7 @@ type commandPolicyShow struct {
 
 func (c *commandPolicyShow) setup(svc appServices, parent commandParent) {
 	cmd := parent.Command("show", "Show snapshot policy.").Alias("get")
-	c.policyTargetFlags.setup(cmd)
+	c.policyTargetFlags.setup(svc, cmd)
 	c.jo.setup(svc, cmd)
 	c.out.setup(svc)
 	cmd.Action(svc.repositoryReaderAction(c.run))
@@ -41,7 +41,7 @@ func (c *commandPolicyShow) run(ctx context.Context, rep repo.Repository) error
 		}
 
 		if c.jo.jsonOutput {
-			c.out.printStdout("%s\n", c.jo.jsonBytes(effective))
+			c.jo.printJSON(effective)
 		} else {
 			printPolicy(&c.out, effective, definition)
 		}
@@ -275,6 +275,8 @@ func appendUploadPolicyRows(rows []policyTableRow, p *policy.Policy, def *policy
 		policyTableRow{"  Max parallel snapshots (server/UI):", valueOrNotSet(p.UploadPolicy.MaxParallelSnapshots), definitionPointToString(p.Target(), def.UploadPolicy.MaxParallelSnapshots)},
 		policyTableRow{"  Max parallel file reads:", valueOrNotSet(p.UploadPolicy.MaxParallelFileReads), definitionPointToString(p.Target(), def.UploadPolicy.MaxParallelFileReads)},
 		policyTableRow{"  Parallel upload above size:", valueOrNotSetOptionalInt64Bytes(p.UploadPolicy.ParallelUploadAboveSize), definitionPointToString(p.Target(), def.UploadPolicy.ParallelUploadAboveSize)},
+		policyTableRow{"  Max upload speed:", valueOrNotSetOptionalInt64Speed(p.UploadPolicy.MaxUploadBytesPerSecond), definitionPointToString(p.Target(), def.UploadPolicy.MaxUploadBytesPerSecond)},
+		policyTableRow{"  Max concurrent uploads:", valueOrNotSet(p.UploadPolicy.MaxConcurrentUploads), definitionPointToString(p.Target(), def.UploadPolicy.MaxConcurrentUploads)},
 	)
 }
 
@@ -335,7 +337,7 @@ func appendSchedulingPolicyRows(rows []policyTableRow, p *policy.Policy, def *po
 }
 
 func appendCompressionPolicyRows(rows []policyTableRow, p *policy.Policy, def *policy.Definition) []policyTableRow {
-	if p.CompressionPolicy.CompressorName == "" || p.CompressionPolicy.CompressorName == "none" {
+	if (p.CompressionPolicy.CompressorName == "" || p.CompressionPolicy.CompressorName == "none") && len(p.CompressionPolicy.Rules) == 0 {
 		rows = append(rows, policyTableRow{"Compression disabled.", "", ""})
 		return rows
 	}
@@ -344,6 +346,17 @@ func appendCompressionPolicyRows(rows []policyTableRow, p *policy.Policy, def *p
 		policyTableRow{"Compression:", "", ""},
 		policyTableRow{"  Compressor:", string(p.CompressionPolicy.CompressorName), definitionPointToString(p.Target(), def.CompressionPolicy.CompressorName)})
 
+	if len(p.CompressionPolicy.Rules) > 0 {
+		rows = append(rows, policyTableRow{
+			"  Compression rules (first match applies):", "",
+			definitionPointToString(p.Target(), def.CompressionPolicy.Rules),
+		})
+
+		for _, rule := range p.CompressionPolicy.Rules {
+			rows = append(rows, policyTableRow{"    " + rule.Pattern, string(rule.Compressor), ""})
+		}
+	}
+
 	switch {
 	case len(p.CompressionPolicy.OnlyCompress) > 0:
 		rows = append(rows, policyTableRow{
@@ -385,6 +398,12 @@ func appendCompressionPolicyRows(rows []policyTableRow, p *policy.Policy, def *p
 		rows = append(rows, policyTableRow{"  Compress files of all sizes.", "", ""})
 	}
 
+	rows = append(rows, policyTableRow{
+		"  Skip incompressible data:",
+		boolToString(p.CompressionPolicy.Adaptive.OrDefault(false)),
+		definitionPointToString(p.Target(), def.CompressionPolicy.Adaptive),
+	})
+
 	return rows
 }
 
@@ -490,3 +509,15 @@ func valueOrNotSetOptionalInt64Bytes(p *policy.OptionalInt64) string {
 
 	return units.BytesString(int64(*p))
 }
+
+func valueOrNotSetOptionalInt64Speed(p *policy.OptionalInt64) string {
+	if p == nil {
+		return "-"
+	}
+
+	if *p == 0 {
+		return "unlimited"
+	}
+
+	return units.BytesPerSecondsString(float64(*p))
+}
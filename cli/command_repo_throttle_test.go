@@ -6,6 +6,7 @@
 	"github.com/stretchr/testify/require"
 
 	"github.com/kopia/kopia/internal/testutil"
+	"github.com/kopia/kopia/internal/timeofday"
 	"github.com/kopia/kopia/repo/blob/throttling"
 	"github.com/kopia/kopia/tests/testenv"
 )
@@ -78,4 +79,63 @@ func TestRepoThrottle(t *testing.T) {
 		ConcurrentReads:        300,
 		ConcurrentWrites:       400,
 	}, limits)
+
+	env.RunAndExpectSuccess(t, "repo", "throttle", "set",
+		"--download-bytes-per-second=10MB",
+		"--upload-bytes-per-second=1.5MiB/s",
+	)
+
+	env.RunAndExpectFailure(t, "repo", "throttle", "set", "--upload-bytes-per-second=10XB")
+
+	testutil.MustParseJSONLines(t, env.RunAndExpectSuccess(t, "repo", "throttle", "get", "--json"), &limits)
+	require.InDelta(t, 10e6, limits.DownloadBytesPerSecond, 0)
+	require.InDelta(t, 1.5*1024*1024, limits.UploadBytesPerSecond, 0)
+}
+
+func TestRepoThrottleSchedule(t *testing.T) {
+	t.Parallel()
+
+	env := testenv.NewCLITest(t, testenv.RepoFormatNotImportant, testenv.NewInProcRunner(t))
+
+	env.RunAndExpectSuccess(t, "repo", "create", "filesystem", "--path", env.RepoDir)
+	defer env.RunAndExpectSuccess(t, "repo", "disconnect")
+
+	env.RunAndExpectSuccess(t, "repo", "throttle", "set",
+		"--upload-bytes-per-second=10MB",
+		"--schedule=09:00-17:00=20%",
+		"--schedule=22:00-06:00=100",
+	)
+
+	env.RunAndExpectFailure(t, "repo", "throttle", "set", "--schedule=09:00-17:00")
+	env.RunAndExpectFailure(t, "repo", "throttle", "set", "--schedule=09:00-25:00=20%")
+	env.RunAndExpectFailure(t, "repo", "throttle", "set", "--schedule=09:00-17:00=0%")
+	env.RunAndExpectFailure(t, "repo", "throttle", "set", "--schedule=09:00-17:00=20%", "--clear-schedule")
+
+	require.Equal(t, []string{
+		"Max Download Speed:            (unlimited)",
+		"Max Upload Speed:              10 MB/s",
+		"Max Read Requests Per Second:  (unlimited)",
+		"Max Write Requests Per Second: (unlimited)",
+		"Max List Requests Per Second:  (unlimited)",
+		"Max Concurrent Reads:          (unlimited)",
+		"Max Concurrent Writes:         (unlimited)",
+		"Scheduled Limits:              20% of limits between 9:00 and 17:00",
+		"Scheduled Limits:              100% of limits between 22:00 and 6:00",
+	}, env.RunAndExpectSuccess(t, "repo", "throttle", "get"))
+
+	var limits throttling.Limits
+
+	testutil.MustParseJSONLines(t, env.RunAndExpectSuccess(t, "repo", "throttle", "get", "--json"), &limits)
+	require.Equal(t, []throttling.ScheduledLimit{
+		{Start: timeofday.TimeOfDay{Hour: 9}, End: timeofday.TimeOfDay{Hour: 17}, Percent: 20},
+		{Start: timeofday.TimeOfDay{Hour: 22}, End: timeofday.TimeOfDay{Hour: 6}, Percent: 100},
+	}, limits.Schedule)
+
+	env.RunAndExpectSuccess(t, "repo", "throttle", "set", "--clear-schedule")
+
+	limits = throttling.Limits{}
+
+	testutil.MustParseJSONLines(t, env.RunAndExpectSuccess(t, "repo", "throttle", "get", "--json"), &limits)
+	require.Empty(t, limits.Schedule)
+	require.InDelta(t, 10e6, limits.UploadBytesPerSecond, 0)
 }
@@ -8,6 +8,7 @@ type commandRepository struct {
 	setClient        commandRepositorySetClient
 	setParameters    commandRepositorySetParameters
 	changePassword   commandRepositoryChangePassword
+	sessions         commandRepositorySessions
 	status           commandRepositoryStatus
 	syncTo           commandRepositorySyncTo
 	throttle         commandRepositoryThrottle
@@ -24,6 +25,7 @@ func (c *commandRepository) setup(svc advancedAppServices, parent commandParent)
 	c.repair.setup(svc, cmd)
 	c.setClient.setup(svc, cmd)
 	c.setParameters.setup(svc, cmd)
+	c.sessions.setup(svc, cmd)
 	c.status.setup(svc, cmd)
 	c.syncTo.setup(svc, cmd)
 	c.throttle.setup(svc, cmd)
@@ -26,7 +26,7 @@ func (c *commandRepositoryChangePassword) run(ctx context.Context, rep repo.Dire
 	var newPass string
 
 	if c.newPassword == "" {
-		n, err := askForChangedRepositoryPassword(c.svc.stdout())
+		n, err := c.svc.askForChangedRepositoryPassword()
 		if err != nil {
 			return err
 		}
@@ -52,6 +52,7 @@ type connectOptions struct {
 	connectUsername               string
 	connectCheckForUpdates        bool
 	connectReadonly               bool
+	connectWritablePrefixes       []string
 	connectPermissiveCacheLoading bool
 	connectDescription            string
 	connectEnableActions          bool
@@ -74,6 +75,7 @@ func (c *connectOptions) setup(svc appServices, cmd *kingpin.CmdClause) {
 	cmd.Flag("override-username", "Override username used by this repository connection").Hidden().StringVar(&c.connectUsername)
 	cmd.Flag("check-for-updates", "Periodically check for Kopia updates on GitHub").Default("true").Envar(svc.EnvName(checkForUpdatesEnvar)).BoolVar(&c.connectCheckForUpdates)
 	cmd.Flag("readonly", "Make repository read-only to avoid accidental changes").BoolVar(&c.connectReadonly)
+	cmd.Flag("readonly-writable-prefix", "Allow writes to blobs with the provided prefix, such as '_log', when the repository is read-only").StringsVar(&c.connectWritablePrefixes)
 	cmd.Flag("permissive-cache-loading", "Do not fail when loading bad cache index entries.  Repository must be opened in read-only mode").Hidden().BoolVar(&c.connectPermissiveCacheLoading)
 	cmd.Flag("description", "Human-readable description of the repository").StringVar(&c.connectDescription)
 	cmd.Flag("enable-actions", "Allow snapshot actions").BoolVar(&c.connectEnableActions)
@@ -101,11 +103,14 @@ func (c *connectOptions) toRepoConnectOptions() *repo.ConnectOptions {
 			MinContentSweepAge:          content.DurationSeconds(c.contentMinSweepAge.Seconds()),
 			MinMetadataSweepAge:         content.DurationSeconds(c.metadataMinSweepAge.Seconds()),
 			MinIndexSweepAge:            content.DurationSeconds(c.indexMinSweepAge.Seconds()),
+			ContentCacheEvictionPolicy:  c.contentEvictionPolicy,
+			MetadataCacheEvictionPolicy: c.metadataEvictionPolicy,
 		},
 		ClientOptions: repo.ClientOptions{
 			Hostname:                c.connectHostname,
 			Username:                c.connectUsername,
 			ReadOnly:                c.connectReadonly,
+			WritablePrefixes:        c.connectWritablePrefixes,
 			PermissiveCacheLoading:  c.connectPermissiveCacheLoading,
 			Description:             c.connectDescription,
 			EnableActions:           c.connectEnableActions,
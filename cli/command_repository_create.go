@@ -5,8 +5,10 @@
 	"time"
 
 	"github.com/alecthomas/kingpin/v2"
+	atunits "github.com/alecthomas/units"
 	"github.com/pkg/errors"
 
+	"github.com/kopia/kopia/internal/crypto"
 	"github.com/kopia/kopia/repo"
 	"github.com/kopia/kopia/repo/blob"
 	"github.com/kopia/kopia/repo/ecc"
@@ -29,11 +31,16 @@ type commandRepositoryCreate struct {
 	createBlockECCFormat              string
 	createBlockECCOverheadPercent     int
 	createBlockKeyDerivationAlgorithm string
+	createKDF                         string
+	createArgon2idMemory              atunits.Base2Bytes
+	createArgon2idIterations          uint32
+	createArgon2idParallelism         uint8
 	createSplitter                    string
 	createOnly                        bool
 	createFormatVersion               int
 	retentionMode                     string
 	retentionPeriod                   time.Duration
+	prefixRetention                   []string
 
 	co  connectOptions
 	svc advancedAppServices
@@ -52,8 +59,13 @@ func (c *commandRepositoryCreate) setup(svc advancedAppServices, parent commandP
 	cmd.Flag("format-version", "Force a particular repository format version (1, 2 or 3, 0==default)").IntVar(&c.createFormatVersion)
 	cmd.Flag("retention-mode", "Set the blob retention-mode for supported storage backends.").EnumVar(&c.retentionMode, blob.Governance.String(), blob.Compliance.String())
 	cmd.Flag("retention-period", "Set the blob retention-period for supported storage backends.").DurationVar(&c.retentionPeriod)
+	cmd.Flag("prefix-retention", "Override the blob retention for blobs with a prefix (PREFIX=[MODE:]PERIOD), can be repeated.").PlaceHolder("PREFIX=[MODE:]PERIOD").StringsVar(&c.prefixRetention)
 	//nolint:lll
 	cmd.Flag("format-block-key-derivation-algorithm", "Algorithm to derive the encryption key for the format block from the repository password").Default(format.DefaultKeyDerivationAlgorithm).EnumVar(&c.createBlockKeyDerivationAlgorithm, format.SupportedFormatBlobKeyDerivationAlgorithms()...)
+	cmd.Flag("kdf", "Key derivation function for the format block, overrides --format-block-key-derivation-algorithm").EnumVar(&c.createKDF, kdfScrypt, kdfPbkdf2, kdfArgon2id)
+	cmd.Flag("kdf-argon2id-memory", "Amount of memory used by argon2id key derivation, requires --kdf=argon2id (default: 64MiB)").PlaceHolder("BYTES").BytesVar(&c.createArgon2idMemory)
+	cmd.Flag("kdf-argon2id-iterations", "Number of iterations of argon2id key derivation, requires --kdf=argon2id (default: 3)").Uint32Var(&c.createArgon2idIterations)
+	cmd.Flag("kdf-argon2id-parallelism", "Parallelism of argon2id key derivation, requires --kdf=argon2id (default: 4)").Uint8Var(&c.createArgon2idParallelism)
 
 	c.co.setup(svc, cmd)
 	c.svc = svc
@@ -77,7 +89,17 @@ func (c *commandRepositoryCreate) setup(svc advancedAppServices, parent commandP
 	}
 }
 
-func (c *commandRepositoryCreate) newRepositoryOptionsFromFlags() *repo.NewRepositoryOptions {
+func (c *commandRepositoryCreate) newRepositoryOptionsFromFlags() (*repo.NewRepositoryOptions, error) {
+	prefixRetention, err := parsePrefixRetention(c.prefixRetention)
+	if err != nil {
+		return nil, err
+	}
+
+	kdf, err := c.keyDerivationAlgorithm()
+	if err != nil {
+		return nil, err
+	}
+
 	return &repo.NewRepositoryOptions{
 		BlockFormat: format.ContentFormat{
 			MutableParameters: format.MutableParameters{
@@ -95,8 +117,80 @@ func (c *commandRepositoryCreate) newRepositoryOptionsFromFlags() *repo.NewRepos
 
 		RetentionMode:                     blob.RetentionMode(c.retentionMode),
 		RetentionPeriod:                   c.retentionPeriod,
-		FormatBlockKeyDerivationAlgorithm: c.createBlockKeyDerivationAlgorithm,
+		PrefixRetention:                   prefixRetention,
+		FormatBlockKeyDerivationAlgorithm: kdf,
+	}, nil
+}
+
+const (
+	kdfScrypt   = "scrypt"
+	kdfPbkdf2   = "pbkdf2"
+	kdfArgon2id = "argon2id"
+
+	// defaults of argon2id parameters, recommended by RFC 9106 for memory-constrained environments.
+	defaultArgon2idMemory      = 64 * atunits.MiB
+	defaultArgon2idIterations  = 3
+	defaultArgon2idParallelism = 4
+)
+
+func (c *commandRepositoryCreate) keyDerivationAlgorithm() (string, error) {
+	if c.createKDF != kdfArgon2id && (c.createArgon2idMemory != 0 || c.createArgon2idIterations != 0 || c.createArgon2idParallelism != 0) {
+		return "", errors.New("--kdf-argon2id-memory, --kdf-argon2id-iterations and --kdf-argon2id-parallelism require --kdf=argon2id")
+	}
+
+	switch c.createKDF {
+	case "":
+		return c.createBlockKeyDerivationAlgorithm, nil
+	case kdfScrypt:
+		return crypto.ScryptAlgorithm, nil
+	case kdfPbkdf2:
+		return crypto.Pbkdf2Algorithm, nil
+	default:
+		return argon2idAlgorithmFromFlags(
+			valueOrDefault(c.createArgon2idMemory, defaultArgon2idMemory),
+			valueOrDefault(c.createArgon2idIterations, defaultArgon2idIterations),
+			valueOrDefault(c.createArgon2idParallelism, defaultArgon2idParallelism))
+	}
+}
+
+func valueOrDefault[T comparable](v, def T) T {
+	var zero T
+
+	if v == zero {
+		return def
 	}
+
+	return v
+}
+
+func argon2idAlgorithmFromFlags(memory atunits.Base2Bytes, iterations uint32, parallelism uint8) (string, error) {
+	// validate before converting to KiB, which would silently overflow.
+	if memory < crypto.Argon2idMinMemory || memory > crypto.Argon2idMaxMemory {
+		return "", errors.Errorf("invalid argon2id memory %v, must be between %v and %v", memory, atunits.Base2Bytes(crypto.Argon2idMinMemory), atunits.Base2Bytes(crypto.Argon2idMaxMemory))
+	}
+
+	memoryKiB := uint32(memory / atunits.KiB) //nolint:gosec
+
+	if err := crypto.ValidateArgon2idParameters(memoryKiB, iterations, parallelism); err != nil {
+		return "", errors.Wrap(err, "invalid argon2id parameters")
+	}
+
+	return crypto.Argon2idAlgorithmName(memoryKiB, iterations, parallelism), nil
+}
+
+func parsePrefixRetention(values []string) ([]format.PrefixRetention, error) {
+	var result []format.PrefixRetention
+
+	for _, v := range values {
+		pr, err := format.ParsePrefixRetention(v)
+		if err != nil {
+			return nil, errors.Wrap(err, "invalid prefix retention")
+		}
+
+		result = append(result, pr)
+	}
+
+	return result, nil
 }
 
 func (c *commandRepositoryCreate) ensureEmpty(ctx context.Context, s blob.Storage) error {
@@ -119,7 +213,10 @@ func (c *commandRepositoryCreate) runCreateCommandWithStorage(ctx context.Contex
 		return errors.Wrap(err, "unable to get repository storage")
 	}
 
-	options := c.newRepositoryOptionsFromFlags()
+	options, err := c.newRepositoryOptionsFromFlags()
+	if err != nil {
+		return err
+	}
 
 	pass, err := c.svc.getPasswordFromFlags(ctx, true, false)
 	if err != nil {
@@ -0,0 +1,11 @@
+package cli
+
+type commandRepositorySessions struct {
+	list commandRepositorySessionsList
+}
+
+func (c *commandRepositorySessions) setup(svc appServices, parent commandParent) {
+	cmd := parent.Command("sessions", "Commands to inspect write sessions")
+
+	c.list.setup(svc, cmd)
+}
@@ -0,0 +1,82 @@
+package cli
+
+import (
+	"context"
+	"sort"
+
+	"github.com/pkg/errors"
+
+	"github.com/kopia/kopia/internal/clock"
+	"github.com/kopia/kopia/internal/serverapi"
+	"github.com/kopia/kopia/internal/units"
+	"github.com/kopia/kopia/repo"
+	"github.com/kopia/kopia/repo/maintenance"
+)
+
+type commandRepositorySessionsList struct {
+	expiredOnly bool
+
+	jo  jsonOutput
+	out textOutput
+}
+
+func (c *commandRepositorySessionsList) setup(svc appServices, parent commandParent) {
+	cmd := parent.Command("list", "List write sessions that have not been completed, including sessions of crashed clients").Alias("ls")
+	cmd.Flag("expired", "Only list sessions which have not been checkpointed recently and are subject to garbage collection").BoolVar(&c.expiredOnly)
+
+	c.jo.setup(svc, cmd)
+	c.out.setup(svc)
+	cmd.Action(svc.directRepositoryReadAction(c.run))
+}
+
+func (c *commandRepositorySessionsList) run(ctx context.Context, rep repo.DirectRepository) error {
+	sessions, err := rep.ContentReader().ListActiveSessions(ctx)
+	if err != nil {
+		return errors.Wrap(err, "error listing sessions")
+	}
+
+	now := clock.Now()
+
+	var result []*serverapi.WriteSession
+
+	for _, si := range sessions {
+		ws := &serverapi.WriteSession{
+			SessionInfo: *si,
+			Expired:     maintenance.IsSessionExpired(si, now, maintenance.SafetyFull),
+		}
+
+		if c.expiredOnly && !ws.Expired {
+			continue
+		}
+
+		result = append(result, ws)
+	}
+
+	sort.Slice(result, func(i, j int) bool {
+		return result[i].StartTime.Before(result[j].StartTime)
+	})
+
+	var jl jsonList
+
+	jl.begin(&c.jo)
+	defer jl.end()
+
+	for _, s := range result {
+		if c.jo.jsonOutput {
+			jl.emit(s)
+			continue
+		}
+
+		state := "active"
+		if s.Expired {
+			state = "expired"
+		}
+
+		c.out.printStdout("%v %v@%v started:%v checkpoint:%v written:%v %v\n",
+			s.ID, s.User, s.Host,
+			formatTimestamp(s.StartTime), formatTimestamp(s.CheckpointTime),
+			units.BytesString(s.BytesWritten), state)
+	}
+
+	return nil
+}
@@ -0,0 +1,56 @@
+package cli_test
+
+import (
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/kopia/kopia/internal/gather"
+	"github.com/kopia/kopia/internal/serverapi"
+	"github.com/kopia/kopia/internal/testlogging"
+	"github.com/kopia/kopia/internal/testutil"
+	"github.com/kopia/kopia/repo"
+	"github.com/kopia/kopia/repo/content"
+	"github.com/kopia/kopia/tests/testenv"
+)
+
+func TestRepositorySessionsList(t *testing.T) {
+	t.Parallel()
+
+	ctx := testlogging.Context(t)
+	env := testenv.NewCLITest(t, testenv.RepoFormatNotImportant, testenv.NewInProcRunner(t))
+
+	env.RunAndExpectSuccess(t, "repo", "create", "filesystem", "--path", env.RepoDir)
+	require.Empty(t, env.RunAndExpectSuccess(t, "repo", "sessions", "list"))
+
+	// simulate a crashed client by writing content and closing the repository without flushing.
+	rep, err := repo.Open(ctx, filepath.Join(env.ConfigDir, ".kopia.config"), testenv.TestRepoPassword, &repo.Options{})
+	require.NoError(t, err)
+
+	_, w, err := rep.NewWriter(ctx, repo.WriteSessionOptions{Purpose: "test"})
+	require.NoError(t, err)
+
+	_, err = w.(repo.DirectRepositoryWriter).ContentManager().WriteContent(ctx, gather.FromSlice([]byte("hello")), "", content.NoCompression)
+	require.NoError(t, err)
+
+	require.NoError(t, w.Close(ctx))
+	require.NoError(t, rep.Close(ctx))
+
+	lines := env.RunAndExpectSuccess(t, "repo", "sessions", "list")
+	require.Len(t, lines, 1)
+	require.Contains(t, lines[0], rep.ClientOptions().UsernameAtHost())
+	require.True(t, strings.HasSuffix(lines[0], " active"), lines[0])
+
+	// the session was just checkpointed, so it's not expired.
+	require.Empty(t, env.RunAndExpectSuccess(t, "repo", "sessions", "list", "--expired"))
+
+	var sessions []*serverapi.WriteSession
+
+	testutil.MustParseJSONLines(t, env.RunAndExpectSuccess(t, "repo", "sessions", "list", "--json"), &sessions)
+	require.Len(t, sessions, 1)
+	require.False(t, sessions[0].Expired)
+	require.Equal(t, rep.ClientOptions().Hostname, sessions[0].Host)
+	require.True(t, strings.HasPrefix(string(sessions[0].ID), string(content.BlobIDPrefixSession)))
+}
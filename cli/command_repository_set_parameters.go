@@ -21,6 +21,9 @@ type commandRepositorySetParameters struct {
 	retentionMode      string
 	retentionPeriod    time.Duration
 
+	prefixRetention      []string
+	clearPrefixRetention []string
+
 	epochRefreshFrequency    time.Duration
 	epochMinDuration         time.Duration
 	epochCleanupSafetyMargin time.Duration
@@ -29,6 +32,13 @@ type commandRepositorySetParameters struct {
 	epochDeleteParallelism   int
 	epochCheckpointFrequency int
 
+	indexCompactionMaxSmallBlobs int
+	indexCompactionSmallBlobSize string
+	indexCompactionMinAge        time.Duration
+
+	storageQuota         string
+	storageQuotaWarnOnly bool
+
 	upgradeRepositoryFormat bool
 
 	addRequiredFeature           string
@@ -45,6 +55,8 @@ func (c *commandRepositorySetParameters) setup(svc appServices, parent commandPa
 	cmd.Flag("index-version", "Set version of index format used for writing").IntVar(&c.indexFormatVersion)
 	cmd.Flag("retention-mode", "Set the blob retention-mode for supported storage backends.").EnumVar(&c.retentionMode, "none", blob.Governance.String(), blob.Compliance.String())
 	cmd.Flag("retention-period", "Set the blob retention-period for supported storage backends.").DurationVar(&c.retentionPeriod)
+	cmd.Flag("prefix-retention", "Override the blob retention for blobs with a prefix (PREFIX=[MODE:]PERIOD), can be repeated.").PlaceHolder("PREFIX=[MODE:]PERIOD").StringsVar(&c.prefixRetention)
+	cmd.Flag("clear-prefix-retention", "Remove the blob retention override for a prefix, can be repeated.").PlaceHolder("PREFIX").StringsVar(&c.clearPrefixRetention)
 
 	cmd.Flag("upgrade", "Upgrade repository to the latest stable format").BoolVar(&c.upgradeRepositoryFormat)
 
@@ -56,6 +68,13 @@ func (c *commandRepositorySetParameters) setup(svc appServices, parent commandPa
 	cmd.Flag("epoch-delete-parallelism", "Epoch delete parallelism").IntVar(&c.epochDeleteParallelism)
 	cmd.Flag("epoch-checkpoint-frequency", "Checkpoint frequency").IntVar(&c.epochCheckpointFrequency)
 
+	cmd.Flag("index-compaction-max-small-blobs", "Compact index blobs if their number reaches given threshold (non-epoch repositories)").IntVar(&c.indexCompactionMaxSmallBlobs)
+	cmd.Flag("index-compaction-small-blob-size", "Index blobs below this size are considered small, e.g. 512KiB (non-epoch repositories)").StringVar(&c.indexCompactionSmallBlobSize)
+	cmd.Flag("index-compaction-min-age", "Minimal age of index blob to be compacted (non-epoch repositories)").DurationVar(&c.indexCompactionMinAge)
+
+	cmd.Flag("storage-quota", "Fail writes of pack blobs when the size of the storage exceeds the quota (e.g. 500GiB), 0 removes the quota").PlaceHolder("BYTES").StringVar(&c.storageQuota)
+	cmd.Flag("storage-quota-warn-only", "Only warn when the size of the storage exceeds the quota").BoolVar(&c.storageQuotaWarnOnly)
+
 	if svc.enableTestOnlyFlags() {
 		cmd.Flag("add-required-feature", "Add required feature which must be present to open the repository").Hidden().StringVar(&c.addRequiredFeature)
 		cmd.Flag("remove-required-feature", "Remove required feature").Hidden().StringVar(&c.removeRequiredFeature)
@@ -170,9 +189,37 @@ func (c *commandRepositorySetParameters) disableBlobRetention(ctx context.Contex
 
 	blobcfg.RetentionMode = ""
 	blobcfg.RetentionPeriod = 0
+	blobcfg.PrefixRetention = nil
 	*anyChange = true
 }
 
+func (c *commandRepositorySetParameters) setPrefixRetention(ctx context.Context, blobcfg *format.BlobStorageConfiguration, anyChange *bool) error {
+	for _, prefix := range c.clearPrefixRetention {
+		log(ctx).Infof(" - removing blob retention override for prefix %q.\n", prefix)
+
+		blobcfg.RemovePrefixRetention(blob.ID(prefix))
+		*anyChange = true
+	}
+
+	prefixRetention, err := parsePrefixRetention(c.prefixRetention)
+	if err != nil {
+		return err
+	}
+
+	if err := repo.ValidatePrefixRetention(prefixRetention); err != nil {
+		return errors.Wrap(err, "invalid prefix retention")
+	}
+
+	for _, pr := range prefixRetention {
+		log(ctx).Infof(" - setting blob retention override %v.\n", pr)
+
+		blobcfg.SetPrefixRetention(pr)
+		*anyChange = true
+	}
+
+	return nil
+}
+
 func (c *commandRepositorySetParameters) run(ctx context.Context, rep repo.DirectRepositoryWriter) error {
 	mp, err := rep.FormatManager().GetMutableParameters(ctx)
 	if err != nil {
@@ -215,6 +262,10 @@ func (c *commandRepositorySetParameters) run(ctx context.Context, rep repo.Direc
 	} else {
 		c.setRetentionModeParameter(ctx, blob.RetentionMode(c.retentionMode), "storage backend blob retention mode", &blobcfg.RetentionMode, &anyChange)
 		c.setDurationParameter(ctx, c.retentionPeriod, "storage backend blob retention period", &blobcfg.RetentionPeriod, &anyChange)
+
+		if err := c.setPrefixRetention(ctx, &blobcfg, &anyChange); err != nil {
+			return err
+		}
 	}
 
 	c.setDurationParameter(ctx, c.epochMinDuration, "minimum epoch duration", &mp.EpochParameters.MinEpochDuration, &anyChange)
@@ -225,7 +276,16 @@ func (c *commandRepositorySetParameters) run(ctx context.Context, rep repo.Direc
 	c.setIntParameter(ctx, c.epochDeleteParallelism, "epoch delete parallelism", &mp.EpochParameters.DeleteParallelism, &anyChange)
 	c.setIntParameter(ctx, c.epochCheckpointFrequency, "epoch checkpoint frequency", &mp.EpochParameters.FullCheckpointFrequency, &anyChange)
 
+	if err := c.setIndexCompactionParameters(ctx, &mp, &anyChange); err != nil {
+		return err
+	}
+
+	if err := c.setStorageQuota(ctx, &mp, &anyChange); err != nil {
+		return err
+	}
+
 	requiredFeatures = c.addRemoveUpdateRequiredFeatures(requiredFeatures, &anyChange)
+	requiredFeatures = blobcfg.UpdateRequiredFeatures(requiredFeatures)
 
 	if !anyChange {
 		log(ctx).Info("no changes")
@@ -252,6 +312,84 @@ func (c *commandRepositorySetParameters) run(ctx context.Context, rep repo.Direc
 	return nil
 }
 
+func (c *commandRepositorySetParameters) setIndexCompactionParameters(ctx context.Context, mp *format.MutableParameters, anyChange *bool) error {
+	if c.indexCompactionMaxSmallBlobs == 0 && c.indexCompactionSmallBlobSize == "" && c.indexCompactionMinAge == 0 {
+		return nil
+	}
+
+	if mp.EpochParameters.Enabled {
+		return errors.New("index compaction parameters are not supported in repositories using epoch manager, use --epoch-* flags instead")
+	}
+
+	var p format.IndexCompactionParameters
+	if mp.IndexCompaction != nil {
+		p = *mp.IndexCompaction
+	}
+
+	c.setIntParameter(ctx, c.indexCompactionMaxSmallBlobs, "index compaction max small blobs", &p.MaxSmallBlobs, anyChange)
+	c.setDurationParameter(ctx, c.indexCompactionMinAge, "index compaction minimum age", &p.MinBlobAge, anyChange)
+
+	if c.indexCompactionSmallBlobSize != "" {
+		v, err := units.ParseBytes(c.indexCompactionSmallBlobSize)
+		if err != nil {
+			return errors.Wrap(err, "invalid index compaction small blob size")
+		}
+
+		p.SmallBlobSizeBytes = v
+		*anyChange = true
+
+		log(ctx).Infof(" - setting index compaction small blob size to %v.\n", units.BytesString(v))
+	}
+
+	if err := p.Validate(); err != nil {
+		return errors.Wrap(err, "invalid index compaction parameters")
+	}
+
+	mp.IndexCompaction = &p
+
+	return nil
+}
+
+func (c *commandRepositorySetParameters) setStorageQuota(ctx context.Context, mp *format.MutableParameters, anyChange *bool) error {
+	if c.storageQuota == "" {
+		if c.storageQuotaWarnOnly {
+			return errors.New("--storage-quota-warn-only requires --storage-quota")
+		}
+
+		return nil
+	}
+
+	v, err := units.ParseBytes(c.storageQuota)
+	if err != nil {
+		return errors.Wrap(err, "invalid storage quota")
+	}
+
+	p := &format.StorageQuotaParameters{
+		MaxBytes: v,
+		WarnOnly: c.storageQuotaWarnOnly,
+	}
+
+	if err := p.Validate(); err != nil {
+		return errors.Wrap(err, "invalid storage quota")
+	}
+
+	*anyChange = true
+
+	if !p.IsEnabled() {
+		log(ctx).Info(" - removing storage quota.\n")
+
+		mp.StorageQuota = nil
+
+		return nil
+	}
+
+	log(ctx).Infof(" - setting storage quota to %v (warn only: %v).\n", units.BytesString(v), p.WarnOnly)
+
+	mp.StorageQuota = p
+
+	return nil
+}
+
 func (c *commandRepositorySetParameters) addRemoveUpdateRequiredFeatures(orig []feature.Required, anyChange *bool) []feature.Required {
 	var result []feature.Required
 
@@ -3,9 +3,11 @@
 import (
 	"fmt"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/require"
 
+	"github.com/kopia/kopia/cli"
 	"github.com/kopia/kopia/internal/blobtesting"
 	"github.com/kopia/kopia/internal/repotesting"
 	"github.com/kopia/kopia/internal/testutil"
@@ -52,6 +54,37 @@ func (s *formatSpecificTestSuite) TestRepositorySetParameters(t *testing.T) {
 	require.Contains(t, out, "Max pack length:     46.1 MB")
 }
 
+func (s *formatSpecificTestSuite) TestRepositorySetIndexCompactionParameters(t *testing.T) {
+	env := s.setupInMemoryRepo(t)
+
+	if s.formatVersion != format.FormatVersion1 {
+		// epoch manager does not compact index blobs based on these parameters.
+		env.RunAndExpectFailure(t, "repository", "set-parameters", "--index-compaction-max-small-blobs=100")
+		env.RunAndExpectFailure(t, "repository", "set-parameters", "--index-compaction-small-blob-size=512KB")
+		env.RunAndExpectFailure(t, "repository", "set-parameters", "--index-compaction-min-age=30m")
+
+		return
+	}
+
+	env.RunAndExpectFailure(t, "repository", "set-parameters", "--index-compaction-max-small-blobs=-1")
+	env.RunAndExpectFailure(t, "repository", "set-parameters", "--index-compaction-small-blob-size=lots")
+
+	env.RunAndExpectSuccess(t, "repository", "set-parameters",
+		"--index-compaction-max-small-blobs=100",
+		"--index-compaction-small-blob-size=512KB",
+		"--index-compaction-min-age=30m",
+	)
+
+	// changing one parameter preserves the others.
+	env.RunAndExpectSuccess(t, "repository", "set-parameters", "--index-compaction-max-small-blobs=50")
+
+	out := env.RunAndExpectSuccess(t, "repository", "status")
+	require.Contains(t, out, "Index compaction:    at least 50 index blobs, small below 512 KB, minimum age 30m0s")
+
+	// upgrading to epoch manager in the same command is rejected as well.
+	env.RunAndExpectFailure(t, "repository", "set-parameters", "--upgrade", "--index-compaction-min-age=1h")
+}
+
 func (s *formatSpecificTestSuite) TestRepositorySetParametersRetention(t *testing.T) {
 	env := s.setupInMemoryRepo(t)
 
@@ -123,6 +156,46 @@ func (s *formatSpecificTestSuite) TestRepositorySetParametersRetention(t *testin
 	require.Contains(t, out, "Blob retention period:   168h0m0s")
 }
 
+func (s *formatSpecificTestSuite) TestRepositorySetParametersPrefixRetention(t *testing.T) {
+	env := s.setupInMemoryRepo(t)
+
+	// prefix retention requires retention to be enabled.
+	env.RunAndExpectFailure(t, "repository", "set-parameters", "--prefix-retention", "kopia.=720h")
+
+	env.RunAndExpectSuccess(t, "repository", "set-parameters", "--retention-mode", blob.Governance.String(),
+		"--retention-period", "24h", "--prefix-retention", "kopia.=COMPLIANCE:30d", "--prefix-retention", "q=48h")
+
+	out := env.RunAndExpectSuccess(t, "repository", "status")
+	require.Contains(t, out, "Blob retention mode:     GOVERNANCE")
+	require.Contains(t, out, "Blob prefix retention:   kopia.=COMPLIANCE:720h0m0s")
+	require.Contains(t, out, "Blob prefix retention:   q=48h0m0s")
+
+	// overrides require clients to understand them.
+	require.Contains(t, out, "Required Features:   prefix-retention")
+
+	// replace and remove overrides.
+	env.RunAndExpectSuccess(t, "repository", "set-parameters", "--prefix-retention", "kopia.=60d", "--clear-prefix-retention", "q")
+
+	out = env.RunAndExpectSuccess(t, "repository", "status")
+	require.Contains(t, out, "Blob prefix retention:   kopia.=1440h0m0s")
+	require.NotContains(t, out, "Blob prefix retention:   q=")
+
+	// invalid overrides.
+	env.RunAndExpectFailure(t, "repository", "set-parameters", "--prefix-retention", "p=1h")
+	env.RunAndExpectFailure(t, "repository", "set-parameters", "--prefix-retention", "p")
+
+	// overrides for blobs which are not protected by retention are rejected.
+	env.RunAndExpectFailure(t, "repository", "set-parameters", "--prefix-retention", "s=720h")
+	env.RunAndExpectFailure(t, "repository", "set-parameters", "--prefix-retention", "kopia.maintenance=720h")
+
+	// disabling retention removes overrides.
+	env.RunAndExpectSuccess(t, "repository", "set-parameters", "--retention-mode", "none")
+
+	out = env.RunAndExpectSuccess(t, "repository", "status")
+	require.NotContains(t, out, "Blob prefix retention")
+	require.NotContains(t, out, "Required Features:   prefix-retention")
+}
+
 func (s *formatSpecificTestSuite) TestRepositorySetParametersUpgrade(t *testing.T) {
 	env := s.setupInMemoryRepo(t)
 	out := env.RunAndExpectSuccess(t, "repository", "status")
@@ -171,6 +244,7 @@ func (s *formatSpecificTestSuite) TestRepositorySetParametersUpgrade(t *testing.
 	env.RunAndExpectSuccess(t, "repository", "set-parameters", "--epoch-advance-on-size-mb", "77")
 	env.RunAndExpectSuccess(t, "repository", "set-parameters", "--epoch-advance-on-count", "22")
 	env.RunAndExpectSuccess(t, "repository", "set-parameters", "--epoch-checkpoint-frequency", "9")
+	env.RunAndExpectSuccess(t, "repository", "set-parameters", "--epoch-delete-parallelism", "7")
 
 	env.RunAndExpectFailure(t, "repository", "set-parameters", "--epoch-min-duration", "1s")
 	env.RunAndExpectFailure(t, "repository", "set-parameters", "--epoch-refresh-frequency", "10h")
@@ -186,6 +260,19 @@ func (s *formatSpecificTestSuite) TestRepositorySetParametersUpgrade(t *testing.
 	require.Contains(t, out, "Epoch advance on:        22 blobs or 80.7 MB, minimum 3h0m0s")
 	require.Contains(t, out, "Epoch checkpoint every:  9 epochs")
 
+	var rs cli.RepositoryStatus
+
+	testutil.MustParseJSONLines(t, env.RunAndExpectSuccess(t, "repository", "status", "--json"), &rs)
+
+	ep := rs.ContentFormat.EpochParameters
+	require.True(t, ep.Enabled)
+	require.Equal(t, 3*time.Hour, ep.MinEpochDuration)
+	require.Equal(t, 23*time.Hour, ep.CleanupSafetyMargin)
+	require.Equal(t, int64(77<<20), ep.EpochAdvanceOnTotalSizeBytesThreshold)
+	require.Equal(t, 22, ep.EpochAdvanceOnCountThreshold)
+	require.Equal(t, 9, ep.FullCheckpointFrequency)
+	require.Equal(t, 7, ep.DeleteParallelism)
+
 	env.RunAndExpectSuccess(t, "index", "epoch", "list")
 }
 
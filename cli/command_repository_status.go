@@ -77,7 +77,7 @@ func (c *commandRepositoryStatus) outputJSON(ctx context.Context, r repo.Reposit
 		}
 	}
 
-	c.out.printStdout("%s\n", c.jo.jsonBytes(s))
+	c.jo.printJSON(s)
 
 	return nil
 }
@@ -125,6 +125,10 @@ func (c *commandRepositoryStatus) dumpRetentionStatus(ctx context.Context, dr re
 		c.out.printStdout("\n")
 		c.out.printStdout("Blob retention mode:     %s\n", blobcfg.RetentionMode)
 		c.out.printStdout("Blob retention period:   %s\n", blobcfg.RetentionPeriod)
+
+		for _, pr := range blobcfg.PrefixRetention {
+			c.out.printStdout("Blob prefix retention:   %s\n", pr)
+		}
 	}
 }
 
@@ -193,6 +197,10 @@ func (c *commandRepositoryStatus) run(ctx context.Context, rep repo.Repository)
 	c.out.printStdout("Max pack length:     %v\n", units.BytesString(int64(mp.MaxPackSize)))
 	c.out.printStdout("Index Format:        v%v\n", mp.IndexVersion)
 
+	if q := mp.StorageQuota; q.IsEnabled() {
+		c.out.printStdout("Storage quota:       %v (warn only: %v)\n", units.BytesString(q.MaxBytes), q.WarnOnly)
+	}
+
 	emgr, epochMgrEnabled, emerr := dr.ContentReader().EpochManager(ctx)
 	if emerr != nil {
 		return errors.Wrap(emerr, "epoch manager")
@@ -214,6 +222,10 @@ func (c *commandRepositoryStatus) run(ctx context.Context, rep repo.Repository)
 		c.out.printStdout("Epoch checkpoint every:  %v epochs\n", mp.EpochParameters.FullCheckpointFrequency)
 	} else {
 		c.out.printStdout("Epoch Manager:       disabled\n")
+		c.out.printStdout("Index compaction:    at least %v index blobs, small below %v, minimum age %v\n",
+			mp.IndexCompaction.EffectiveMaxSmallBlobs(),
+			units.BytesString(mp.IndexCompaction.EffectiveSmallBlobSizeBytes(mp.MaxPackSize)),
+			mp.IndexCompaction.EffectiveMinBlobAge())
 	}
 
 	c.dumpRetentionStatus(ctx, dr)
@@ -3,7 +3,6 @@
 import (
 	"context"
 	"fmt"
-	"os"
 	"time"
 
 	"github.com/alecthomas/kingpin/v2"
@@ -13,6 +12,7 @@
 	"github.com/kopia/kopia/internal/gather"
 	"github.com/kopia/kopia/repo"
 	"github.com/kopia/kopia/repo/content"
+	"github.com/kopia/kopia/repo/content/index"
 	"github.com/kopia/kopia/repo/content/indexblob"
 	"github.com/kopia/kopia/repo/format"
 )
@@ -50,7 +50,7 @@ func (c *commandRepositoryUpgrade) setup(svc advancedAppServices, parent command
 	// override the parent, the upgrade sub-command becomes the new parent here-onwards
 	parent = parent.Command("upgrade", "Upgrade repository format.\n\n"+warningColor.Sprint(experimentalWarning)).Hidden().
 		Validate(func(_ *kingpin.CmdClause) error {
-			if v := os.Getenv(c.svc.EnvName(upgradeLockFeatureEnv)); v == "" {
+			if v := c.svc.getEnv(upgradeLockFeatureEnv); v == "" {
 				return errors.Errorf("please set %q env variable to use this feature", upgradeLockFeatureEnv)
 			}
 
@@ -102,6 +102,9 @@ func assign(iif content.Info, i int, m map[content.ID][2]content.Info) {
 func loadIndexBlobs(ctx context.Context, indexEntries map[content.ID][2]content.Info, sm *content.SharedManager, which int, indexBlobInfos []indexblob.Metadata) error {
 	d := gather.WriteBuffer{}
 
+	// resolve multiple entries for the same content the same way as when reading indexes.
+	merged := index.Builder{}
+
 	for _, indexBlobInfo := range indexBlobInfos {
 		blobID := indexBlobInfo.BlobID
 
@@ -111,18 +114,20 @@ func loadIndexBlobs(ctx context.Context, indexEntries map[content.ID][2]content.
 		}
 
 		for _, indexInfo := range indexInfos {
-			assign(indexInfo, which, indexEntries)
+			merged.Add(indexInfo)
 		}
 	}
 
+	for _, indexInfo := range merged {
+		assign(indexInfo, which, indexEntries)
+	}
+
 	return nil
 }
 
 // validateAction returns an error if the new V1 index blob content does not match the source V0 index blob content.
 // This is used to check that the upgraded index (V1 index) reflects the content of the old V0 index.
 func (c *commandRepositoryUpgrade) validateAction(ctx context.Context, rep repo.DirectRepositoryWriter) error {
-	indexEntries := map[content.ID][2]content.Info{}
-
 	sm := rep.ContentManager().SharedManager
 
 	indexBlobInfos0, _, err := sm.IndexReaderV0().ListIndexBlobInfos(ctx)
@@ -141,16 +146,39 @@ func (c *commandRepositoryUpgrade) validateAction(ctx context.Context, rep repo.
 		return nil
 	}
 
-	// load index blobs into their appropriate positions inside the indexEntries map
-
-	err = loadIndexBlobs(ctx, indexEntries, sm, 0, indexBlobInfos0)
+	msgs, err := compareIndexBlobs(ctx, sm, indexBlobInfos0, indexBlobInfos1)
 	if err != nil {
-		return errors.Wrapf(err, "failed to load index entries for v0 index entry")
+		return err
 	}
 
-	err = loadIndexBlobs(ctx, indexEntries, sm, 1, indexBlobInfos1)
-	if err != nil {
-		return errors.Wrapf(err, "failed to load index entries for new index")
+	// no msgs means the check passed without finding anything wrong
+	if len(msgs) == 0 {
+		log(ctx).Info("index validation succeeded")
+		return nil
+	}
+
+	// otherwise there's a problem somewhere ... log the problems
+	log(ctx).Error("inconsistencies found in migrated index:")
+
+	for _, m := range msgs {
+		log(ctx).Error(m)
+	}
+
+	// and return an error that states something's wrong.
+	return errors.Wrap(err, "repository will remain locked until index differences are resolved")
+}
+
+// compareIndexBlobs compares the entries of two sets of index blobs and returns the descriptions of differences found.
+func compareIndexBlobs(ctx context.Context, sm *content.SharedManager, indexBlobInfos0, indexBlobInfos1 []indexblob.Metadata) ([]string, error) {
+	indexEntries := map[content.ID][2]content.Info{}
+
+	// load index blobs into their appropriate positions inside the indexEntries map
+	if err := loadIndexBlobs(ctx, indexEntries, sm, 0, indexBlobInfos0); err != nil {
+		return nil, errors.Wrapf(err, "failed to load index entries for v0 index entry")
+	}
+
+	if err := loadIndexBlobs(ctx, indexEntries, sm, 1, indexBlobInfos1); err != nil {
+		return nil, errors.Wrapf(err, "failed to load index entries for new index")
 	}
 
 	var msgs []string // a place to keep messages from the index comparison process
@@ -179,21 +207,7 @@ func (c *commandRepositoryUpgrade) validateAction(ctx context.Context, rep repo.
 		msgs = append(msgs, fmt.Sprintf("lop-sided index entries for contentID %q at blob %q", contentID, iep1.PackBlobID))
 	}
 
-	// no msgs means the check passed without finding anything wrong
-	if len(msgs) == 0 {
-		log(ctx).Info("index validation succeeded")
-		return nil
-	}
-
-	// otherwise there's a problem somewhere ... log the problems
-	log(ctx).Error("inconsistencies found in migrated index:")
-
-	for _, m := range msgs {
-		log(ctx).Error(m)
-	}
-
-	// and return an error that states something's wrong.
-	return errors.Wrap(err, "repository will remain locked until index differences are resolved")
+	return msgs, nil
 }
 
 // CheckIndexInfo compare two index infos.  If a mismatch exists, return an error with diagnostic information.
@@ -8,6 +8,7 @@
 	"github.com/stretchr/testify/require"
 
 	"github.com/kopia/kopia/cli"
+	"github.com/kopia/kopia/internal/testutil"
 	"github.com/kopia/kopia/repo/content"
 	"github.com/kopia/kopia/repo/format"
 	"github.com/kopia/kopia/tests/testenv"
@@ -56,6 +57,35 @@ func (s *formatSpecificTestSuite) TestRepositoryUpgrade(t *testing.T) {
 	require.Contains(t, out, "Format version:      3")
 }
 
+func (s *formatSpecificTestSuite) TestRepositoryUpgradeWithDeletedContents(t *testing.T) {
+	if s.formatVersion != format.FormatVersion1 {
+		t.Skip("only legacy indexes are migrated")
+	}
+
+	env := testenv.NewCLITest(t, s.formatFlags, testenv.NewInProcRunner(t))
+
+	env.RunAndExpectSuccess(t, "repo", "create", "filesystem", "--path", env.RepoDir)
+	env.RunAndExpectSuccess(t, "snapshot", "create", testutil.TempDirectory(t))
+
+	// deleting contents adds another entry for each of them in a new legacy index blob,
+	// and the validation must compare the entries which win when the indexes are read.
+	for i, contentID := range env.RunAndExpectSuccess(t, "content", "ls") {
+		if i%2 == 0 {
+			env.RunAndExpectSuccess(t, "content", "delete", contentID)
+		}
+	}
+
+	env.Environment["KOPIA_UPGRADE_LOCK_ENABLED"] = "1"
+
+	_, stderr := env.RunAndExpectSuccessWithErrOut(t, "repository", "upgrade", "begin",
+		"--upgrade-owner-id", "owner",
+		"--io-drain-timeout", "1s", "--allow-unsafe-upgrade",
+		"--status-poll-interval", "1s",
+		"--max-permitted-clock-drift", "1s")
+	require.Contains(t, stderr, "index validation succeeded")
+	require.Contains(t, stderr, "Repository has been successfully upgraded.")
+}
+
 func (s *formatSpecificTestSuite) TestRepositoryCorruptedUpgrade(t *testing.T) {
 	env := testenv.NewCLITest(t, s.formatFlags, testenv.NewInProcRunner(t))
 
@@ -110,6 +110,8 @@ type commandRestore struct {
 	restoreConsistentAttributes   bool
 	restoreMode                   string
 	restoreParallel               int
+	restoreParallelDownloads      int
+	restoreParallelWrites         int
 	restoreIgnorePermissionErrors bool
 	restoreWriteFilesAtomically   bool
 	restoreSkipTimes              bool
@@ -117,6 +119,7 @@ type commandRestore struct {
 	restoreSkipPermissions        bool
 	restoreIncremental            bool
 	restoreIgnoreErrors           bool
+	restorePrefetch               bool
 	restoreShallowAtDepth         int32
 	minSizeForPlaceholder         int32
 	snapshotTime                  string
@@ -139,12 +142,15 @@ func (c *commandRestore) setup(svc appServices, parent commandParent) {
 	cmd.Flag("consistent-attributes", "When multiple snapshots match, fail if they have inconsistent attributes").Envar(svc.EnvName("KOPIA_RESTORE_CONSISTENT_ATTRIBUTES")).BoolVar(&c.restoreConsistentAttributes)
 	cmd.Flag("mode", "Override restore mode").Default(restoreModeAuto).EnumVar(&c.restoreMode, restoreModeAuto, restoreModeLocal, restoreModeZip, restoreModeZipNoCompress, restoreModeTar, restoreModeTgz)
 	cmd.Flag("parallel", "Restore parallelism (1=disable)").Default("8").IntVar(&c.restoreParallel)
+	cmd.Flag("parallel-downloads", "Number of batches of file contents downloaded in parallel ahead of writing them, implies --prefetch (0=disable)").IntVar(&c.restoreParallelDownloads)
+	cmd.Flag("parallel-writes", "Number of the --parallel workers dedicated to writing files, the others create directories and symlinks (0=all workers do both)").IntVar(&c.restoreParallelWrites)
 	cmd.Flag("skip-owners", "Skip owners during restore").BoolVar(&c.restoreSkipOwners)
 	cmd.Flag("skip-permissions", "Skip permissions during restore").BoolVar(&c.restoreSkipPermissions)
 	cmd.Flag("skip-times", "Skip times during restore").BoolVar(&c.restoreSkipTimes)
 	cmd.Flag("ignore-permission-errors", "Ignore permission errors").Default("true").BoolVar(&c.restoreIgnorePermissionErrors)
 	cmd.Flag("write-files-atomically", "Write files atomically to disk, ensuring they are either fully committed, or not written at all, preventing partially written files").Default("false").BoolVar(&c.restoreWriteFilesAtomically)
 	cmd.Flag("ignore-errors", "Ignore all errors").BoolVar(&c.restoreIgnoreErrors)
+	cmd.Flag("prefetch", "Prefetch contents of files in each directory into the cache before restoring them").BoolVar(&c.restorePrefetch)
 	cmd.Flag("skip-existing", "Skip files and symlinks that exist in the output").BoolVar(&c.restoreIncremental)
 	cmd.Flag("shallow", "Shallow restore the directory hierarchy starting at this level (default is to deep restore the entire hierarchy.)").Int32Var(&c.restoreShallowAtDepth)
 	cmd.Flag("shallow-minsize", "When doing a shallow restore, write actual files instead of placeholders smaller than this size.").Int32Var(&c.minSizeForPlaceholder)
@@ -343,6 +349,19 @@ func printRestoreStats(ctx context.Context, st *restore.Stats) {
 		st.RestoredSymlinkCount,
 		units.BytesString(st.RestoredTotalFileSize),
 		maybeSkipped, maybeErrors)
+
+	for _, p := range []struct {
+		name string
+		ps   restore.PoolStats
+	}{
+		{"metadata", st.MetadataPool},
+		{"download", st.DownloadPool},
+		{"write", st.WritePool},
+	} {
+		if p.ps.Workers > 0 {
+			log(ctx).Debugf("%v pool: %v workers, completed %v of %v items", p.name, p.ps.Workers, p.ps.Completed, p.ps.Enqueued)
+		}
+	}
 }
 
 func (c *commandRestore) setupPlaceholderExpansion(ctx context.Context, rep repo.Repository, rstp restoreSourceTarget, output restore.Output) (fs.Entry, error) {
@@ -411,10 +430,14 @@ func (c *commandRestore) run(ctx context.Context, rep repo.Repository) error {
 
 		st, err := restore.Entry(ctx, rep, output, rootEntry, restore.Options{
 			Parallel:               c.restoreParallel,
+			ParallelDownloads:      c.restoreParallelDownloads,
+			ParallelWrites:         c.restoreParallelWrites,
+			MaxPrefetchBytes:       c.maxPrefetchBytes(ctx),
 			Incremental:            c.restoreIncremental,
 			IgnoreErrors:           c.restoreIgnoreErrors,
 			RestoreDirEntryAtDepth: c.restoreShallowAtDepth,
 			MinSizeForPlaceholder:  c.minSizeForPlaceholder,
+			Prefetch:               c.restorePrefetch,
 			ProgressCallback:       progressCallback,
 		})
 		if err != nil {
@@ -429,6 +452,17 @@ func (c *commandRestore) run(ctx context.Context, rep repo.Repository) error {
 	return nil
 }
 
+// maxPrefetchBytes returns the maximum total size of files prefetched ahead of being restored, which is
+// a fraction of the content cache size, so that prefetched contents are not evicted before they are read.
+func (c *commandRestore) maxPrefetchBytes(ctx context.Context) int64 {
+	opts, err := repo.GetCachingOptions(ctx, c.svc.repositoryConfigFileName())
+	if err != nil || opts.ContentCacheSizeBytes <= 0 {
+		return 0
+	}
+
+	return opts.ContentCacheSizeBytes / 2 //nolint:mnd
+}
+
 // tryToConvertPathToID checks if the source is a path and in this case returns the ID of the snapshot
 // containing the latest version available.
 func (c *commandRestore) tryToConvertPathToID(ctx context.Context, rep repo.Repository, source string) (string, error) {
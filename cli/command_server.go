@@ -10,7 +10,9 @@
 type commandServer struct {
 	acl      commandServerACL
 	user     commandServerUser
+	cache    commandServerCache
 	cancel   commandServerCancel
+	debug    commandServerDebug
 	flush    commandServerFlush
 	pause    commandServerPause
 	refresh  commandServerRefresh
@@ -72,6 +74,8 @@ func (c *commandServer) setup(svc advancedAppServices, parent commandParent) {
 	c.pause.setup(svc, cmd)
 	c.resume.setup(svc, cmd)
 	c.throttle.setup(svc, cmd)
+	c.cache.setup(svc, cmd)
+	c.debug.setup(svc, cmd)
 }
 
 func (c *serverClientFlags) serverAPIClientOptions() (apiclient.Options, error) {
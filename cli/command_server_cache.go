@@ -0,0 +1,12 @@
+package cli
+
+type commandServerCache struct {
+	get commandServerCacheGet
+	set commandServerCacheSet
+}
+
+func (c *commandServerCache) setup(svc appServices, parent commandParent) {
+	cmd := parent.Command("cache", "Control cache size limits for a running server")
+	c.get.setup(svc, cmd)
+	c.set.setup(svc, cmd)
+}
@@ -0,0 +1,54 @@
+package cli
+
+import (
+	"context"
+
+	"github.com/pkg/errors"
+
+	"github.com/kopia/kopia/internal/apiclient"
+	"github.com/kopia/kopia/internal/units"
+	"github.com/kopia/kopia/repo/content"
+)
+
+type commandServerCacheGet struct {
+	sf serverClientFlags
+
+	out textOutput
+	jo  jsonOutput
+}
+
+func (c *commandServerCacheGet) setup(svc appServices, parent commandParent) {
+	cmd := parent.Command("get", "Get cache size limits for a running server")
+	c.sf.setup(svc, cmd)
+	c.out.setup(svc)
+	c.jo.setup(svc, cmd)
+	cmd.Action(svc.serverAction(&c.sf, c.run))
+}
+
+func (c *commandServerCacheGet) run(ctx context.Context, cli *apiclient.KopiaAPIClient) error {
+	var limits content.CacheSizeLimits
+
+	if err := cli.Get(ctx, "control/cache-limits", nil, &limits); err != nil {
+		return errors.Wrap(err, "unable to get current cache size limits")
+	}
+
+	if c.jo.jsonOutput {
+		c.jo.printJSON(limits)
+		return nil
+	}
+
+	c.printSize("Content Cache Size:", limits.ContentCacheSizeBytes)
+	c.printSize("Content Cache Size Limit:", limits.ContentCacheSizeLimitBytes)
+	c.printSize("Metadata Cache Size:", limits.MetadataCacheSizeBytes)
+	c.printSize("Metadata Cache Size Limit:", limits.MetadataCacheSizeLimitBytes)
+
+	return nil
+}
+
+func (c *commandServerCacheGet) printSize(label string, v int64) {
+	if v != 0 {
+		c.out.printStdout("%-30v %v\n", label, units.BytesString(v))
+	} else {
+		c.out.printStdout("%-30v (default)\n", label)
+	}
+}
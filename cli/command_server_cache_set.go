@@ -0,0 +1,50 @@
+package cli
+
+import (
+	"context"
+
+	"github.com/pkg/errors"
+
+	"github.com/kopia/kopia/internal/apiclient"
+	"github.com/kopia/kopia/internal/serverapi"
+	"github.com/kopia/kopia/repo/content"
+)
+
+type commandServerCacheSet struct {
+	sf serverClientFlags
+
+	sizeLimits cacheSizeLimitFlags
+}
+
+func (c *commandServerCacheSet) setup(svc appServices, parent commandParent) {
+	cmd := parent.Command("set", "Set cache size limits for a running server")
+	c.sf.setup(svc, cmd)
+	c.sizeLimits.setup(cmd)
+
+	cmd.Action(svc.serverAction(&c.sf, c.run))
+}
+
+func (c *commandServerCacheSet) run(ctx context.Context, cli *apiclient.KopiaAPIClient) error {
+	var limits content.CacheSizeLimits
+
+	if err := cli.Get(ctx, "control/cache-limits", nil, &limits); err != nil {
+		return errors.Wrap(err, "unable to get current cache size limits")
+	}
+
+	var changeCount int
+
+	if err := c.sizeLimits.apply(ctx, &limits, &changeCount); err != nil {
+		return err
+	}
+
+	if changeCount == 0 {
+		log(ctx).Info("No changes made.")
+		return nil
+	}
+
+	if err := cli.Put(ctx, "control/cache-limits", &limits, &serverapi.Empty{}); err != nil {
+		return errors.Wrap(err, "unable to change cache size limits")
+	}
+
+	return nil
+}
@@ -9,6 +9,7 @@
 
 	"github.com/kopia/kopia/internal/testutil"
 	"github.com/kopia/kopia/repo/blob/throttling"
+	"github.com/kopia/kopia/repo/content"
 	"github.com/kopia/kopia/tests/testenv"
 )
 
@@ -146,6 +147,26 @@ func TestServerControl(t *testing.T) {
 		ConcurrentWrites:       400,
 	}, limits)
 
+	env.RunAndExpectSuccess(t, "server", "cache", "set", "--address", sp.BaseURL, "--server-control-password", sp.ServerControlPassword,
+		"--content-max-size=100MB",
+		"--content-size-limit=200MB",
+		"--metadata-max-size=300MB",
+	)
+
+	env.RunAndExpectFailure(t, "server", "cache", "set", "--address", sp.BaseURL, "--server-control-password", sp.ServerControlPassword,
+		"--metadata-size-limit=-10",
+	)
+
+	var cacheLimits content.CacheSizeLimits
+
+	testutil.MustParseJSONLines(t, env.RunAndExpectSuccess(t, "server", "cache", "get", "--address", sp.BaseURL, "--server-control-password", sp.ServerControlPassword, "--json"), &cacheLimits)
+	require.Equal(t, int64(100e6), cacheLimits.ContentCacheSizeBytes)
+	require.Equal(t, int64(200e6), cacheLimits.ContentCacheSizeLimitBytes)
+	require.Equal(t, int64(300e6), cacheLimits.MetadataCacheSizeBytes)
+
+	require.Contains(t, env.RunAndExpectSuccess(t, "server", "cache", "get", "--address", sp.BaseURL, "--server-control-password", sp.ServerControlPassword),
+		"Content Cache Size Limit:      200 MB")
+
 	env.RunAndExpectSuccess(t, "server", "shutdown", "--address", sp.BaseURL, "--server-control-password", sp.ServerControlPassword)
 
 	select {
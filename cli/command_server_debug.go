@@ -0,0 +1,181 @@
+package cli
+
+import (
+	"context"
+	"math"
+	"strconv"
+
+	"github.com/pkg/errors"
+
+	"github.com/kopia/kopia/internal/apiclient"
+	"github.com/kopia/kopia/internal/pproflogging"
+	"github.com/kopia/kopia/internal/units"
+)
+
+type commandServerDebug struct {
+	get commandServerDebugGet
+	set commandServerDebugSet
+}
+
+func (c *commandServerDebug) setup(svc appServices, parent commandParent) {
+	cmd := parent.Command("debug", "Control Go runtime settings of a running server")
+	c.get.setup(svc, cmd)
+	c.set.setup(svc, cmd)
+}
+
+type commandServerDebugGet struct {
+	sf serverClientFlags
+
+	jo  jsonOutput
+	out textOutput
+}
+
+func (c *commandServerDebugGet) setup(svc appServices, parent commandParent) {
+	cmd := parent.Command("get", "Show Go runtime settings of a running server")
+	c.sf.setup(svc, cmd)
+	c.jo.setup(svc, cmd)
+	c.out.setup(svc)
+	cmd.Action(svc.serverAction(&c.sf, c.run))
+}
+
+func (c *commandServerDebugGet) run(ctx context.Context, cli *apiclient.KopiaAPIClient) error {
+	var s pproflogging.RuntimeSettings
+
+	if err := cli.Get(ctx, "control/runtime", nil, &s); err != nil {
+		return errors.Wrap(err, "unable to get runtime settings")
+	}
+
+	printRuntimeSettings(&c.jo, &c.out, &s)
+
+	return nil
+}
+
+type commandServerDebugSet struct {
+	sf serverClientFlags
+
+	gcPercent            string
+	memoryLimit          string
+	mutexProfileFraction string
+	blockProfileRate     string
+
+	jo  jsonOutput
+	out textOutput
+}
+
+func (c *commandServerDebugSet) setup(svc appServices, parent commandParent) {
+	cmd := parent.Command("set", "Change Go runtime settings of a running server")
+	cmd.Flag("gogc", "Garbage collection target percentage (same as GOGC), 'off' disables garbage collection").StringVar(&c.gcPercent)
+	cmd.Flag("memory-limit", "Soft memory limit (same as GOMEMLIMIT), such as 4GiB, 'none' removes the limit").StringVar(&c.memoryLimit)
+	cmd.Flag("mutex-profile-fraction", "Fraction of mutex contention events reported in mutex profile, 0 disables").StringVar(&c.mutexProfileFraction)
+	cmd.Flag("block-profile-rate", "Rate of blocking events reported in block profile, 0 disables").StringVar(&c.blockProfileRate)
+	c.sf.setup(svc, cmd)
+	c.jo.setup(svc, cmd)
+	c.out.setup(svc)
+	cmd.Action(svc.serverAction(&c.sf, c.run))
+}
+
+func (c *commandServerDebugSet) run(ctx context.Context, cli *apiclient.KopiaAPIClient) error {
+	req, err := c.runtimeSettings()
+	if err != nil {
+		return err
+	}
+
+	if req == (pproflogging.RuntimeSettings{}) {
+		return errors.New("no runtime settings provided")
+	}
+
+	if err := req.Validate(); err != nil {
+		return errors.Wrap(err, "invalid runtime settings")
+	}
+
+	var resp pproflogging.RuntimeSettings
+
+	if err := cli.Put(ctx, "control/runtime", &req, &resp); err != nil {
+		return errors.Wrap(err, "unable to change runtime settings")
+	}
+
+	printRuntimeSettings(&c.jo, &c.out, &resp)
+
+	return nil
+}
+
+func (c *commandServerDebugSet) runtimeSettings() (pproflogging.RuntimeSettings, error) {
+	var (
+		s   pproflogging.RuntimeSettings
+		err error
+	)
+
+	if c.gcPercent != "" {
+		v := -1
+
+		if c.gcPercent != "off" {
+			if v, err = strconv.Atoi(c.gcPercent); err != nil {
+				return s, errors.Wrap(err, "invalid --gogc")
+			}
+		}
+
+		s.GCPercent = &v
+	}
+
+	if c.memoryLimit != "" {
+		v := int64(math.MaxInt64)
+
+		if c.memoryLimit != "none" {
+			if v, err = units.ParseBytes(c.memoryLimit); err != nil {
+				return s, errors.Wrap(err, "invalid --memory-limit")
+			}
+		}
+
+		s.MemoryLimit = &v
+	}
+
+	if c.mutexProfileFraction != "" {
+		v, err := strconv.Atoi(c.mutexProfileFraction)
+		if err != nil {
+			return s, errors.Wrap(err, "invalid --mutex-profile-fraction")
+		}
+
+		s.MutexProfileFraction = &v
+	}
+
+	if c.blockProfileRate != "" {
+		v, err := strconv.Atoi(c.blockProfileRate)
+		if err != nil {
+			return s, errors.Wrap(err, "invalid --block-profile-rate")
+		}
+
+		s.BlockProfileRate = &v
+	}
+
+	return s, nil
+}
+
+func printRuntimeSettings(jo *jsonOutput, out *textOutput, s *pproflogging.RuntimeSettings) {
+	if jo.jsonOutput {
+		jo.printJSON(s)
+		return
+	}
+
+	gcPercent := "off"
+	if s.GCPercent != nil && *s.GCPercent >= 0 {
+		gcPercent = strconv.Itoa(*s.GCPercent)
+	}
+
+	memoryLimit := "none"
+	if s.MemoryLimit != nil && *s.MemoryLimit != math.MaxInt64 {
+		memoryLimit = units.BytesString(*s.MemoryLimit)
+	}
+
+	out.printStdout("GOGC:                   %v\n", gcPercent)
+	out.printStdout("GOMEMLIMIT:             %v\n", memoryLimit)
+	out.printStdout("Mutex profile fraction: %v\n", intPtrString(s.MutexProfileFraction))
+	out.printStdout("Block profile rate:     %v\n", intPtrString(s.BlockProfileRate))
+}
+
+func intPtrString(v *int) string {
+	if v == nil {
+		return "unknown"
+	}
+
+	return strconv.Itoa(*v)
+}
@@ -0,0 +1,84 @@
+package cli_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/kopia/kopia/internal/pproflogging"
+	"github.com/kopia/kopia/internal/testutil"
+	"github.com/kopia/kopia/tests/testenv"
+)
+
+func TestServerDebugRuntimeSettings(t *testing.T) {
+	env := testenv.NewCLITest(t, testenv.RepoFormatNotImportant, testenv.NewInProcRunner(t))
+
+	// the server runs in the current process, restore runtime settings at the end.
+	orig := pproflogging.GetRuntimeSettings()
+	t.Cleanup(func() { pproflogging.ApplyRuntimeSettings(context.Background(), orig) })
+
+	env.RunAndExpectSuccess(t, "repo", "create", "filesystem", "--path", env.RepoDir)
+
+	serverStarted := make(chan struct{})
+	serverStopped := make(chan struct{})
+
+	var sp testutil.ServerParameters
+
+	go func() {
+		wait, _ := env.RunAndProcessStderr(t, sp.ProcessOutput,
+			"server", "start", "--insecure", "--random-server-control-password", "--address=127.0.0.1:0")
+
+		close(serverStarted)
+
+		wait()
+
+		close(serverStopped)
+	}()
+
+	select {
+	case <-serverStarted:
+		t.Logf("server started on %v", sp.BaseURL)
+
+	case <-time.After(5 * time.Second):
+		t.Fatalf("server did not start in time")
+	}
+
+	serverFlags := []string{"--address", sp.BaseURL, "--server-control-password", sp.ServerControlPassword}
+
+	var s pproflogging.RuntimeSettings
+
+	testutil.MustParseJSONLines(t, env.RunAndExpectSuccess(t, append([]string{"server", "debug", "set", "--gogc=150", "--memory-limit=2GiB", "--mutex-profile-fraction=5", "--block-profile-rate=7", "--json"}, serverFlags...)...), &s)
+	require.Equal(t, 150, *s.GCPercent)
+	require.Equal(t, int64(2<<30), *s.MemoryLimit)
+	require.Equal(t, 5, *s.MutexProfileFraction)
+	require.Equal(t, 7, *s.BlockProfileRate)
+
+	require.Equal(t, []string{
+		"GOGC:                   150",
+		"GOMEMLIMIT:             2.1 GB",
+		"Mutex profile fraction: 5",
+		"Block profile rate:     7",
+	}, env.RunAndExpectSuccess(t, append([]string{"server", "debug", "get"}, serverFlags...)...))
+
+	env.RunAndExpectSuccess(t, append([]string{"server", "debug", "set", "--gogc=off", "--memory-limit=none"}, serverFlags...)...)
+
+	testutil.MustParseJSONLines(t, env.RunAndExpectSuccess(t, append([]string{"server", "debug", "get", "--json"}, serverFlags...)...), &s)
+	require.Equal(t, -1, *s.GCPercent)
+
+	// nothing to change, invalid values.
+	env.RunAndExpectFailure(t, append([]string{"server", "debug", "set"}, serverFlags...)...)
+	env.RunAndExpectFailure(t, append([]string{"server", "debug", "set", "--mutex-profile-fraction=-1"}, serverFlags...)...)
+	env.RunAndExpectFailure(t, append([]string{"server", "debug", "set", "--gogc=abc"}, serverFlags...)...)
+
+	env.RunAndExpectSuccess(t, append([]string{"server", "shutdown"}, serverFlags...)...)
+
+	select {
+	case <-serverStopped:
+		t.Logf("server shut down")
+
+	case <-time.After(15 * time.Second):
+		t.Fatalf("server did not shutdown in time")
+	}
+}
@@ -2,16 +2,17 @@
 
 import (
 	"context"
-	"fmt"
 	"io"
 	"path/filepath"
 	"strings"
 	"time"
 
+	"github.com/alecthomas/kingpin/v2"
 	"github.com/pkg/errors"
 
 	"github.com/kopia/kopia/fs"
 	"github.com/kopia/kopia/fs/virtualfs"
+	"github.com/kopia/kopia/internal/units"
 	"github.com/kopia/kopia/repo"
 	"github.com/kopia/kopia/snapshot"
 	"github.com/kopia/kopia/snapshot/policy"
@@ -23,23 +24,36 @@
 	timeFormat                   = "2006-01-02 15:04:05 MST"
 )
 
+// supported formats of data read from stdin.
+const (
+	stdinFormatFile = "file"
+	stdinFormatTar  = "tar"
+)
+
 type commandSnapshotCreate struct {
 	snapshotCreateSources                 []string
 	snapshotCreateAll                     bool
 	snapshotCreateDescription             string
 	snapshotCreateCheckpointInterval      time.Duration
+	snapshotCreateCheckpointEntries       int
 	snapshotCreateFailFast                bool
+	snapshotCreateFailOnIgnoredErrors     bool
 	snapshotCreateForceHash               float64
+	snapshotCreateForceRehash             bool
 	snapshotCreateParallelUploads         int
 	snapshotCreateStartTime               string
 	snapshotCreateEndTime                 string
 	snapshotCreateForceEnableActions      bool
 	snapshotCreateForceDisableActions     bool
 	snapshotCreateStdinFileName           string
+	snapshotCreateStdinFormat             string
 	snapshotCreateCheckpointUploadLimitMB int64
 	snapshotCreateTags                    []string
 	flushPerSource                        bool
 	sourceOverride                        string
+	dryRun                                bool
+	explainExcludes                       bool
+	dryRunMaxExcluded                     int
 
 	pins []string
 
@@ -54,23 +68,30 @@ type commandSnapshotCreate struct {
 func (c *commandSnapshotCreate) setup(svc appServices, parent commandParent) {
 	cmd := parent.Command("create", "Creates a snapshot of local directory or file.")
 
-	cmd.Arg("source", "Files or directories to create snapshot(s) of.").StringsVar(&c.snapshotCreateSources)
+	cmd.Arg("source", "Files or directories to create snapshot(s) of.").HintAction(svc.snapshotSourceHints).StringsVar(&c.snapshotCreateSources)
 	cmd.Flag("all", "Create snapshots for files or directories previously backed up by this user on this computer. Cannot be used when a source path argument is also specified.").BoolVar(&c.snapshotCreateAll)
 	cmd.Flag("upload-limit-mb", "Stop the backup process after the specified amount of data (in MB) has been uploaded.").PlaceHolder("MB").Default("0").Int64Var(&c.snapshotCreateCheckpointUploadLimitMB)
 	cmd.Flag("checkpoint-interval", "Interval between periodic checkpoints (must be <= 45 minutes).").Hidden().DurationVar(&c.snapshotCreateCheckpointInterval)
+	cmd.Flag("checkpoint-entries", "Additionally create a checkpoint after every N entries of a single directory (0 disables).").PlaceHolder("N").Default("0").IntVar(&c.snapshotCreateCheckpointEntries)
 	cmd.Flag("description", "Free-form snapshot description.").StringVar(&c.snapshotCreateDescription)
 	cmd.Flag("fail-fast", "Fail fast when creating snapshot.").Envar(svc.EnvName("KOPIA_SNAPSHOT_FAIL_FAST")).BoolVar(&c.snapshotCreateFailFast)
+	cmd.Flag("fail-on-ignored-errors", "Exit with a distinct exit code when errors were ignored while creating snapshot.").Envar(svc.EnvName("KOPIA_SNAPSHOT_FAIL_ON_IGNORED_ERRORS")).BoolVar(&c.snapshotCreateFailOnIgnoredErrors)
 	cmd.Flag("force-hash", "Force hashing of source files for a given percentage of files [0.0 .. 100.0]").Default("0").Float64Var(&c.snapshotCreateForceHash)
+	cmd.Flag("force-rehash", "Ignore cached entries from previous snapshots and re-hash all files, warning about files whose contents changed without a change to their metadata").BoolVar(&c.snapshotCreateForceRehash)
 	cmd.Flag("parallel", "Upload N files in parallel").PlaceHolder("N").Default("0").IntVar(&c.snapshotCreateParallelUploads)
 	cmd.Flag("start-time", "Override snapshot start timestamp.").StringVar(&c.snapshotCreateStartTime)
 	cmd.Flag("end-time", "Override snapshot end timestamp.").StringVar(&c.snapshotCreateEndTime)
 	cmd.Flag("force-enable-actions", "Enable snapshot actions even if globally disabled on this client").Hidden().BoolVar(&c.snapshotCreateForceEnableActions)
 	cmd.Flag("force-disable-actions", "Disable snapshot actions even if globally enabled on this client").Hidden().BoolVar(&c.snapshotCreateForceDisableActions)
 	cmd.Flag("stdin-file", "File path to be used for stdin data snapshot.").StringVar(&c.snapshotCreateStdinFileName)
+	cmd.Flag("stdin-format", "Format of stdin data, 'tar' snapshots the contents of a tar stream as a directory named after --stdin-file (ignore files must precede subdirectories of their directory in the stream).").Default(stdinFormatFile).EnumVar(&c.snapshotCreateStdinFormat, stdinFormatFile, stdinFormatTar)
 	cmd.Flag("tags", "Tags applied on the snapshot. Must be provided in the <key>:<value> format.").StringsVar(&c.snapshotCreateTags)
 	cmd.Flag("pin", "Create a pinned snapshot that will not expire automatically").StringsVar(&c.pins)
 	cmd.Flag("flush-per-source", "Flush writes at the end of each source").Hidden().BoolVar(&c.flushPerSource)
 	cmd.Flag("override-source", "Override the source of the snapshot.").StringVar(&c.sourceOverride)
+	cmd.Flag("explain-excludes", "Log the reason and the ignore rule for every excluded file and directory.").BoolVar(&c.explainExcludes)
+	cmd.Flag("dry-run", "Report what would be uploaded without writing anything to the repository.").BoolVar(&c.dryRun)
+	cmd.Flag("dry-run-max-excluded", "Maximum number of excluded entries to report in dry-run mode.").Default("100").IntVar(&c.dryRunMaxExcluded)
 
 	c.logDirDetail = -1
 	c.logEntryDetail = -1
@@ -82,32 +103,47 @@ func (c *commandSnapshotCreate) setup(svc appServices, parent commandParent) {
 	c.out.setup(svc)
 
 	c.svc = svc
-	cmd.Action(svc.repositoryWriterAction(c.run))
+	cmd.Action(func(pc *kingpin.ParseContext) error {
+		if c.dryRun {
+			return svc.repositoryReaderAction(c.runDryRun)(pc)
+		}
+
+		return svc.repositoryWriterAction(c.run)(pc)
+	})
 }
 
-//nolint:gocyclo
-func (c *commandSnapshotCreate) run(ctx context.Context, rep repo.RepositoryWriter) error {
+func (c *commandSnapshotCreate) getSources(ctx context.Context, rep repo.Repository) ([]string, error) {
 	sources := c.snapshotCreateSources
 
 	if c.snapshotCreateAll && len(sources) > 0 {
-		return errors.New("cannot use --all when a source path argument is specified")
-	}
-
-	if err := maybeAutoUpgradeRepository(ctx, rep); err != nil {
-		return errors.Wrap(err, "error upgrading repository")
+		return nil, errors.New("cannot use --all when a source path argument is specified")
 	}
 
 	if c.snapshotCreateAll {
 		local, err := getLocalBackupPaths(ctx, rep)
 		if err != nil {
-			return err
+			return nil, err
 		}
 
 		sources = append(sources, local...)
 	}
 
 	if len(sources) == 0 {
-		return errors.New("no snapshot sources")
+		return nil, errors.New("no snapshot sources")
+	}
+
+	return sources, nil
+}
+
+//nolint:gocyclo
+func (c *commandSnapshotCreate) run(ctx context.Context, rep repo.RepositoryWriter) error {
+	sources, err := c.getSources(ctx, rep)
+	if err != nil {
+		return err
+	}
+
+	if err := maybeAutoUpgradeRepository(ctx, rep); err != nil {
+		return errors.Wrap(err, "error upgrading repository")
 	}
 
 	if err := validateStartEndTime(c.snapshotCreateStartTime, c.snapshotCreateEndTime); err != nil {
@@ -120,7 +156,7 @@ func (c *commandSnapshotCreate) run(ctx context.Context, rep repo.RepositoryWrit
 
 	u := c.setupUploader(rep)
 
-	var finalErrors []string
+	var finalErrors []error
 
 	tags, err := getTags(c.snapshotCreateTags)
 	if err != nil {
@@ -135,11 +171,11 @@ func (c *commandSnapshotCreate) run(ctx context.Context, rep repo.RepositoryWrit
 
 		fsEntry, sourceInfo, setManual, err := c.getContentToSnapshot(ctx, snapshotDir, rep)
 		if err != nil {
-			finalErrors = append(finalErrors, fmt.Sprintf("failed to prepare source: %s", err))
+			finalErrors = append(finalErrors, errors.Wrap(err, "failed to prepare source"))
 		}
 
 		if err := c.snapshotSingleSource(ctx, fsEntry, setManual, rep, u, sourceInfo, tags); err != nil {
-			finalErrors = append(finalErrors, err.Error())
+			finalErrors = append(finalErrors, err)
 		}
 	}
 
@@ -156,11 +192,115 @@ func (c *commandSnapshotCreate) run(ctx context.Context, rep repo.RepositoryWrit
 		return nil
 	}
 
+	return combineSnapshotErrors(finalErrors)
+}
+
+func (c *commandSnapshotCreate) runDryRun(ctx context.Context, rep repo.Repository) error {
+	sources, err := c.getSources(ctx, rep)
+	if err != nil {
+		return err
+	}
+
+	var results []*dryRunSourceResult
+
+	for _, snapshotDir := range sources {
+		fsEntry, sourceInfo, _, err := c.getContentToSnapshot(ctx, snapshotDir, rep)
+		if err != nil {
+			return errors.Wrap(err, "failed to prepare source")
+		}
+
+		previous, err := findPreviousSnapshotManifest(ctx, rep, sourceInfo, nil)
+		if err != nil {
+			return err
+		}
+
+		policyTree, err := policy.TreeForSource(ctx, rep, sourceInfo)
+		if err != nil {
+			return errors.Wrap(err, "unable to get policy tree")
+		}
+
+		log(ctx).Infof("Scanning %v ...", sourceInfo)
+
+		res, err := snapshotfs.DryRun(ctx, rep, fsEntry, policyTree, c.dryRunMaxExcluded, previous...)
+		if err != nil {
+			return errors.Wrapf(err, "error scanning %v", sourceInfo)
+		}
+
+		results = append(results, &dryRunSourceResult{Source: sourceInfo, DryRunResult: res})
+	}
+
+	if c.jo.jsonOutput {
+		c.jo.printJSON(results)
+		return nil
+	}
+
+	for _, r := range results {
+		c.printDryRunResult(r)
+	}
+
+	return nil
+}
+
+type dryRunSourceResult struct {
+	Source snapshot.SourceInfo `json:"source"`
+	*snapshotfs.DryRunResult
+}
+
+func (c *commandSnapshotCreate) printDryRunResult(r *dryRunSourceResult) {
+	c.out.printStdout("Dry run of %v (nothing was written to the repository):\n", r.Source)
+	c.out.printStdout("  Directories:        %v\n", r.DirectoryCount)
+	c.out.printStdout("  Files:              %v (%v)\n", r.FileCount, units.BytesString(r.TotalFileSize))
+	c.out.printStdout("  Unchanged files:    %v (%v)\n", r.UnchangedFileCount, units.BytesString(r.UnchangedFileSize))
+	c.out.printStdout("  New/changed files:  %v (up to %v before deduplication)\n", r.NewFileCount, units.BytesString(r.NewFileSize))
+
+	if r.DedupEstimated {
+		c.out.printStdout("  New data:           %v (estimated after deduplication)\n", units.BytesString(r.EstimatedUploadSize))
+	}
+
+	c.out.printStdout("  Excluded files:     %v (%v)\n", r.ExcludedFileCount, units.BytesString(r.ExcludedFileSize))
+	c.out.printStdout("  Excluded dirs:      %v\n", r.ExcludedDirCount)
+
+	if r.ErrorCount > 0 || r.IgnoredErrorCount > 0 {
+		c.out.printStdout("  Errors:             %v (ignored %v)\n", r.ErrorCount, r.IgnoredErrorCount)
+	}
+
+	if len(r.Excluded) > 0 {
+		c.out.printStdout("\nExcluded entries:\n")
+
+		for _, e := range r.Excluded {
+			if e.IsDir {
+				c.out.printStdout("  %v%c (%v)\n", e.Path, filepath.Separator, e.Reason)
+			} else {
+				c.out.printStdout("  %v (%v, %v)\n", e.Path, e.Reason, units.BytesString(e.Size))
+			}
+		}
+
+		if total := r.ExcludedFileCount + r.ExcludedDirCount; total > len(r.Excluded) {
+			c.out.printStdout("  ... and %v more\n", total-len(r.Excluded))
+		}
+	}
+}
+
+// combineSnapshotErrors returns a single error describing errors encountered when snapshotting multiple sources,
+// which produces the same exit code as the individual errors if they all agree.
+func combineSnapshotErrors(finalErrors []error) error {
 	if len(finalErrors) == 1 {
-		return errors.New(finalErrors[0])
+		return finalErrors[0]
+	}
+
+	var messages []string
+
+	exitCode := exitCodeForError(finalErrors[0])
+
+	for _, err := range finalErrors {
+		messages = append(messages, err.Error())
+
+		if exitCodeForError(err) != exitCode {
+			exitCode = ExitCodeRuntimeFailure
+		}
 	}
 
-	return errors.Errorf("encountered %v errors:\n%v", len(finalErrors), strings.Join(finalErrors, "\n"))
+	return withExitCode(exitCode, errors.Errorf("encountered %v errors:\n%v", len(finalErrors), strings.Join(messages, "\n")))
 }
 
 func getTags(tagStrings []string) (map[string]string, error) {
@@ -233,11 +373,21 @@ func (c *commandSnapshotCreate) setupUploader(rep repo.RepositoryWriter) *snapsh
 		u.CheckpointInterval = interval
 	}
 
+	u.CheckpointEntries = c.snapshotCreateCheckpointEntries
+
 	c.svc.onTerminate(u.Cancel)
 
 	u.ForceHashPercentage = c.snapshotCreateForceHash
+	u.ForceRehash = c.snapshotCreateForceRehash
 	u.ParallelUploads = c.snapshotCreateParallelUploads
 
+	if c.snapshotCreateStdinFileName != "" && c.snapshotCreateStdinFormat == stdinFormatTar {
+		// entries of a tar stream must be processed sequentially in the order they appear.
+		u.ParallelUploads = 1
+	}
+
+	u.ExplainExcludes = c.explainExcludes
+
 	u.FailFast = c.snapshotCreateFailFast
 	u.Progress = c.svc.getProgress()
 
@@ -352,19 +502,23 @@ func (c *commandSnapshotCreate) reportSnapshotStatus(ctx context.Context, manife
 	snapID := manifest.ID
 
 	if c.jo.jsonOutput {
-		c.out.printStdout("%s\n", c.jo.jsonIndentedBytes(manifest, "  "))
+		c.jo.printIndentedJSON(manifest, "  ")
 	} else {
 		log(ctx).Infof("Created%v snapshot with root %v and ID %v in %v", maybePartial, manifest.RootObjectID(), snapID, manifest.EndTime.Sub(manifest.StartTime).Truncate(time.Second))
 	}
 
 	if ds := manifest.RootEntry.DirSummary; ds != nil {
-		if ds.IgnoredErrorCount > 0 {
+		if ds.IgnoredErrorCount > 0 && !c.snapshotCreateFailOnIgnoredErrors {
 			log(ctx).Warnf("Ignored %v error(s) while snapshotting %v.", ds.IgnoredErrorCount, sourceInfo)
 		}
 
 		if ds.FatalErrorCount > 0 {
 			return errors.Errorf("Found %v fatal error(s) while snapshotting %v.", ds.FatalErrorCount, sourceInfo) //nolint:revive
 		}
+
+		if ds.IgnoredErrorCount > 0 && c.snapshotCreateFailOnIgnoredErrors {
+			return withExitCode(ExitCodePartialSnapshot, errors.Errorf("Ignored %v error(s) while snapshotting %v.", ds.IgnoredErrorCount, sourceInfo)) //nolint:revive
+		}
 	}
 
 	return nil
@@ -453,7 +607,7 @@ func shouldSnapshotSource(ctx context.Context, src snapshot.SourceInfo, rep repo
 
 // the setManual return value is true when a snapshot is manually created, such
 // as when overriding the source info or snapshotting from stdin.
-func (c *commandSnapshotCreate) getContentToSnapshot(ctx context.Context, dir string, rep repo.RepositoryWriter) (fsEntry fs.Entry, info snapshot.SourceInfo, setManual bool, err error) {
+func (c *commandSnapshotCreate) getContentToSnapshot(ctx context.Context, dir string, rep repo.Repository) (fsEntry fs.Entry, info snapshot.SourceInfo, setManual bool, err error) {
 	var absDir string
 
 	absDir, err = filepath.Abs(dir)
@@ -479,15 +633,22 @@ func (c *commandSnapshotCreate) getContentToSnapshot(ctx context.Context, dir st
 	if c.snapshotCreateStdinFileName != "" {
 		// stdin source will be snapshotted using a virtual static root directory with a single streaming file entry
 		// Create a new static directory with the given name and add a streaming file entry with os.Stdin reader
-		fsEntry = virtualfs.NewStaticDirectory(absDir, []fs.Entry{
-			virtualfs.StreamingFileFromReader(c.snapshotCreateStdinFileName, io.NopCloser(c.svc.stdin())),
-		})
+		var stdinEntry fs.Entry = virtualfs.StreamingFileFromReader(c.snapshotCreateStdinFileName, io.NopCloser(c.svc.stdin()))
+
+		if c.snapshotCreateStdinFormat == stdinFormatTar {
+			// tar stream is read sequentially as the directory tree gets uploaded.
+			stdinEntry = virtualfs.NewTarDirectory(c.snapshotCreateStdinFileName, c.svc.stdin())
+		}
+
+		fsEntry = virtualfs.NewStaticDirectory(absDir, []fs.Entry{stdinEntry})
 		setManual = true
 	} else {
 		fsEntry, err = getLocalFSEntry(ctx, absDir)
 		if err != nil {
 			return nil, info, false, errors.Wrap(err, "unable to get local filesystem entry")
 		}
+
+		fsEntry = c.svc.wrapSnapshotSource(absDir, fsEntry)
 	}
 
 	return fsEntry, info, setManual, nil
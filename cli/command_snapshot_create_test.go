@@ -0,0 +1,124 @@
+package cli_test
+
+import (
+	"bytes"
+	"crypto/rand"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/kopia/kopia/internal/metrics"
+	"github.com/kopia/kopia/internal/testutil"
+	"github.com/kopia/kopia/snapshot"
+	"github.com/kopia/kopia/snapshot/snapshotfs"
+	"github.com/kopia/kopia/tests/testenv"
+)
+
+func TestSnapshotCreateDryRun(t *testing.T) {
+	t.Parallel()
+
+	env := testenv.NewCLITest(t, testenv.RepoFormatNotImportant, testenv.NewInProcRunner(t))
+
+	dir := testutil.TempDirectory(t)
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "file1.txt"), bytes.Repeat([]byte{1, 2, 3, 4, 5}, 15000), 0o600))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "file2.txt"), bytes.Repeat([]byte{2, 3, 4, 5, 6}, 10000), 0o600))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "big.bin"), bytes.Repeat([]byte{3}, 200000), 0o600))
+	require.NoError(t, os.MkdirAll(filepath.Join(dir, "subdir"), 0o755))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "subdir", "file3.txt"), bytes.Repeat([]byte{3, 4, 5, 6, 7}, 5000), 0o600))
+
+	env.RunAndExpectSuccess(t, "repo", "create", "filesystem", "--path", env.RepoDir)
+	env.RunAndExpectSuccess(t, "policy", "set", "--add-ignore", "subdir", "--max-file-size", "100000", dir)
+
+	blobsBefore := env.RunAndExpectSuccess(t, "blob", "list")
+
+	out := env.RunAndExpectSuccess(t, "snapshot", "create", "--dry-run", dir)
+	require.Contains(t, out, "  Files:              2 (125 KB)")
+	require.Contains(t, out, "  New/changed files:  2 (up to 125 KB before deduplication)")
+	require.Contains(t, out, "  New data:           125 KB (estimated after deduplication)")
+	require.Contains(t, out, "  Excluded files:     1 (200 KB)")
+	require.Contains(t, out, "  Excluded dirs:      1")
+	require.Contains(t, out, "  big.bin (max-file-size, 200 KB)")
+	require.Contains(t, out, "  subdir"+string(filepath.Separator)+" (ignore-rule)")
+
+	// nothing was written to the repository.
+	require.Equal(t, blobsBefore, env.RunAndExpectSuccess(t, "blob", "list"))
+	require.Empty(t, env.RunAndExpectSuccess(t, "snapshot", "list", dir))
+
+	// after taking a snapshot, unchanged files are not uploaded again.
+	env.RunAndExpectSuccess(t, "snapshot", "create", dir)
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "file2.txt"), bytes.Repeat([]byte{9}, 1000), 0o600))
+
+	// a copy of a file that has been snapshotted does not add new data.
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "copy.txt"), bytes.Repeat([]byte{1, 2, 3, 4, 5}, 15000), 0o600))
+
+	var results []struct {
+		snapshotfs.DryRunResult
+
+		Source snapshot.SourceInfo `json:"source"`
+	}
+
+	testutil.MustParseJSONLines(t, env.RunAndExpectSuccess(t, "snapshot", "create", "--dry-run", "--json", "--dry-run-max-excluded=1", dir), &results)
+	require.Len(t, results, 1)
+	require.Equal(t, 3, results[0].FileCount)
+	require.Equal(t, 1, results[0].UnchangedFileCount)
+	require.Equal(t, 2, results[0].NewFileCount)
+	require.Equal(t, int64(76000), results[0].NewFileSize)
+	require.True(t, results[0].DedupEstimated)
+	require.Equal(t, int64(1000), results[0].EstimatedUploadSize)
+	require.Len(t, results[0].Excluded, 1)
+}
+
+func TestSnapshotCreateStorageQuota(t *testing.T) {
+	t.Parallel()
+
+	env := testenv.NewCLITest(t, testenv.RepoFormatNotImportant, testenv.NewInProcRunner(t))
+
+	dir := testutil.TempDirectory(t)
+	data := make([]byte, 1<<20)
+	_, err := rand.Read(data)
+	require.NoError(t, err)
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "random.bin"), data, 0o600))
+
+	env.RunAndExpectSuccess(t, "repo", "create", "filesystem", "--path", env.RepoDir)
+
+	env.RunAndExpectSuccess(t, "repo", "set-parameters", "--storage-quota=100KiB")
+	require.Contains(t, env.RunAndExpectSuccess(t, "repo", "status"), "Storage quota:       102.4 KB (warn only: false)")
+
+	_, stderr := env.RunAndExpectFailure(t, "snapshot", "create", dir)
+	require.Contains(t, strings.Join(stderr, "\n"), "storage quota exceeded")
+
+	env.RunAndExpectSuccess(t, "repo", "set-parameters", "--storage-quota=100KiB", "--storage-quota-warn-only")
+	env.RunAndExpectSuccess(t, "snapshot", "create", dir)
+
+	env.RunAndExpectSuccess(t, "repo", "set-parameters", "--storage-quota=0")
+	env.RunAndExpectSuccess(t, "snapshot", "create", dir)
+	env.RunAndExpectFailure(t, "repo", "set-parameters", "--storage-quota-warn-only")
+}
+
+func TestSnapshotCreateStorageCircuitBreaker(t *testing.T) {
+	t.Parallel()
+
+	runner := testenv.NewInProcRunner(t)
+	env := testenv.NewCLITest(t, testenv.RepoFormatNotImportant, runner)
+
+	dir := testutil.TempDirectory(t)
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "file1.txt"), bytes.Repeat([]byte{1, 2, 3, 4, 5}, 15000), 0o600))
+
+	env.RunAndExpectSuccess(t, "repo", "create", "filesystem", "--path", env.RepoDir)
+
+	var ms metrics.Snapshot
+
+	runner.SetNextMetricsSnapshot(&ms)
+	env.RunAndExpectSuccess(t, "snapshot", "create", dir, "--storage-circuit-breaker-cool-down=1m")
+
+	require.Contains(t, ms.Counters, "blob_circuit_breaker_rejected[method:PutBlob]")
+	require.Zero(t, ms.Counters["blob_circuit_breaker_opened[method:PutBlob]"])
+
+	runner.SetNextMetricsSnapshot(&ms)
+	env.RunAndExpectSuccess(t, "snapshot", "create", dir)
+
+	require.NotContains(t, ms.Counters, "blob_circuit_breaker_rejected[method:PutBlob]")
+}
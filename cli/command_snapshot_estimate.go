@@ -21,6 +21,7 @@ type commandSnapshotEstimate struct {
 	snapshotEstimateShowFiles   bool
 	snapshotEstimateQuiet       bool
 	snapshotEstimateUploadSpeed float64
+	snapshotEstimateUpload      bool
 	maxExamplesPerBucket        int
 
 	out textOutput
@@ -33,6 +34,7 @@ func (c *commandSnapshotEstimate) setup(svc appServices, parent commandParent) {
 	cmd.Flag("quiet", "Do not display scanning progress").Short('q').BoolVar(&c.snapshotEstimateQuiet)
 	cmd.Flag("upload-speed", "Upload speed to use for estimation").Default("10").PlaceHolder("mbit/s").Float64Var(&c.snapshotEstimateUploadSpeed)
 	cmd.Flag("max-examples-per-bucket", "Max examples per bucket").Default("10").IntVar(&c.maxExamplesPerBucket)
+	cmd.Flag("upload", "Estimate the amount of new data to upload by hashing files changed since the previous snapshot and looking up their contents in the repository").BoolVar(&c.snapshotEstimateUpload)
 	cmd.Action(svc.repositoryReaderAction(c.run))
 	c.out.setup(svc)
 }
@@ -128,15 +130,62 @@ func (c *commandSnapshotEstimate) run(ctx context.Context, rep repo.Repository)
 		c.out.printStdout("Encountered %v error(s).\n", ep.stats.ErrorCount)
 	}
 
-	megabits := float64(ep.stats.TotalFileSize) * 8 / 1000000 //nolint:mnd
-	seconds := megabits / c.snapshotEstimateUploadSpeed
+	uploadSize := ep.stats.TotalFileSize
+	uploadSpeed := c.snapshotEstimateUploadSpeed
+
+	if c.snapshotEstimateUpload {
+		dr, ok := rep.(repo.DirectRepository)
+		if !ok {
+			return errors.New("upload estimate requires direct repository connection")
+		}
+
+		ue, err := c.estimateUpload(ctx, dr, dir, policyTree, sourceInfo, &ep)
+		if err != nil {
+			return err
+		}
+
+		uploadSize = ue.NewBytes()
+
+		// upload throttling caps the effective upload speed.
+		if limit := dr.Throttler().Limits().UploadBytesPerSecond; limit > 0 {
+			uploadSpeed = min(uploadSpeed, limit*8/1000000) //nolint:mnd
+		}
+	}
+
+	megabits := float64(uploadSize) * 8 / 1000000 //nolint:mnd
+	seconds := megabits / uploadSpeed
 
 	c.out.printStdout("\n")
-	c.out.printStdout("Estimated upload time: %v at %v Mbit/s\n", time.Duration(seconds)*time.Second, c.snapshotEstimateUploadSpeed)
+	c.out.printStdout("Estimated upload time: %v at %v Mbit/s\n", time.Duration(seconds)*time.Second, uploadSpeed)
 
 	return nil
 }
 
+func (c *commandSnapshotEstimate) estimateUpload(ctx context.Context, rep repo.DirectRepository, dir fs.Directory, policyTree *policy.Tree, sourceInfo snapshot.SourceInfo, ep *estimateProgress) (*snapshotfs.UploadEstimate, error) {
+	previous, err := findPreviousSnapshotManifest(ctx, rep, sourceInfo, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	ue, err := snapshotfs.EstimateUpload(ctx, rep, dir, policyTree, previous, ep)
+	if err != nil {
+		return nil, errors.Wrap(err, "error estimating upload")
+	}
+
+	c.out.printStdout("\n")
+	c.out.printStdout("Upload estimate based on %v previous snapshot(s):\n", len(previous))
+	c.out.printStdout("  Unchanged files:   %7v files, total size %v\n", ue.UnchangedFileCount, units.BytesString(ue.UnchangedFileSize))
+	c.out.printStdout("  Hashed files:      %7v files, total size %v\n", ue.HashedFileCount, units.BytesString(ue.HashedFileSize))
+	c.out.printStdout("  Already stored:    %7v chunks, total size %v\n", ue.Chunks.DeduplicatedChunks, units.BytesString(ue.Chunks.DeduplicatedBytes))
+	c.out.printStdout("  New data:          %7v chunks, total size %v (before compression)\n", ue.Chunks.NewChunks(), units.BytesString(ue.NewBytes()))
+
+	if ue.IgnoredErrorCount > 0 {
+		c.out.printStdout("  Ignored %v error(s).\n", ue.IgnoredErrorCount)
+	}
+
+	return ue, nil
+}
+
 func (c *commandSnapshotEstimate) showBuckets(buckets snapshotfs.SampleBuckets, showFiles bool) {
 	for i, bucket := range buckets {
 		if bucket.Count == 0 {
@@ -2,6 +2,7 @@
 
 import (
 	"bytes"
+	"crypto/rand"
 	"os"
 	"path/filepath"
 	"testing"
@@ -46,6 +47,52 @@ func TestSnapshotEstimate(t *testing.T) {
 	require.Contains(t, out, "Snapshot excludes 1 directories. Examples:")
 }
 
+func TestSnapshotEstimate_Upload(t *testing.T) {
+	env := testenv.NewCLITest(t, testenv.RepoFormatNotImportant, testenv.NewInProcRunner(t))
+
+	dir := testutil.TempDirectory(t)
+
+	data1 := make([]byte, 100000)
+	data2 := make([]byte, 50000)
+	data3 := make([]byte, 30000)
+
+	for _, d := range [][]byte{data1, data2, data3} {
+		_, err := rand.Read(d)
+		require.NoError(t, err)
+	}
+
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "file1.bin"), data1, 0o600))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "file2.bin"), data2, 0o600))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "file2-copy.bin"), data2, 0o600))
+
+	env.RunAndExpectSuccess(t, "repo", "create", "filesystem", "--path", env.RepoDir)
+
+	// nothing uploaded yet, identical files are only counted once.
+	out := env.RunAndExpectSuccess(t, "snapshot", "estimate", "--upload", dir)
+	require.Contains(t, out, "Upload estimate based on 0 previous snapshot(s):")
+	require.Contains(t, mustGetLineContaining(t, out, "Unchanged files:"), " 0 files, total size 0 B")
+	require.Contains(t, mustGetLineContaining(t, out, "Hashed files:"), " 3 files, total size 200 KB")
+	require.Contains(t, mustGetLineContaining(t, out, "Already stored:"), " 1 chunks, total size 50 KB")
+	require.Contains(t, mustGetLineContaining(t, out, "New data:"), " 2 chunks, total size 150 KB")
+	require.Contains(t, out, "Estimated upload time: 0s at 10 Mbit/s")
+
+	env.RunAndExpectSuccess(t, "snapshot", "create", dir)
+
+	// add a file with new contents and one with contents already in the repository.
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "file3.bin"), data3, 0o600))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "file1-copy.bin"), data1, 0o600))
+
+	env.RunAndExpectSuccess(t, "repo", "throttle", "set", "--upload-bytes-per-second=250000")
+
+	out = env.RunAndExpectSuccess(t, "snapshot", "estimate", "--upload", "--upload-speed=100", dir)
+	require.Contains(t, out, "Upload estimate based on 1 previous snapshot(s):")
+	require.Contains(t, mustGetLineContaining(t, out, "Unchanged files:"), " 3 files, total size 200 KB")
+	require.Contains(t, mustGetLineContaining(t, out, "Hashed files:"), " 2 files, total size 130 KB")
+	require.Contains(t, mustGetLineContaining(t, out, "Already stored:"), " 1 chunks, total size 100 KB")
+	require.Contains(t, mustGetLineContaining(t, out, "New data:"), " 1 chunks, total size 30 KB")
+	require.Contains(t, out, "Estimated upload time: 0s at 2 Mbit/s")
+}
+
 func TestSnapshotEstimate_NotADirectory(t *testing.T) {
 	env := testenv.NewCLITest(t, testenv.RepoFormatNotImportant, testenv.NewInProcRunner(t))
 
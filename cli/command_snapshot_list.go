@@ -43,7 +43,7 @@ type commandSnapshotList struct {
 
 func (c *commandSnapshotList) setup(svc appServices, parent commandParent) {
 	cmd := parent.Command("list", "List snapshots of files and directories.").Alias("ls")
-	cmd.Arg("source", "File or directory to show history of.").StringVar(&c.snapshotListPath)
+	cmd.Arg("source", "File or directory to show history of.").HintAction(svc.snapshotSourceHints).StringVar(&c.snapshotListPath)
 	cmd.Flag("incomplete", "Include incomplete.").Short('i').BoolVar(&c.snapshotListIncludeIncomplete)
 	cmd.Flag("human-readable", "Show human-readable units").Default("true").BoolVar(&c.snapshotListShowHumanReadable)
 	cmd.Flag("delta", "Include deltas.").Short('d').BoolVar(&c.snapshotListShowDelta)
@@ -4,12 +4,17 @@
 	"context"
 	"fmt"
 	"runtime"
+	"slices"
+	"sync"
+	"time"
 
 	"github.com/pkg/errors"
 
+	"github.com/kopia/kopia/internal/timetrack"
 	"github.com/kopia/kopia/repo"
 	"github.com/kopia/kopia/repo/blob"
 	"github.com/kopia/kopia/repo/manifest"
+	"github.com/kopia/kopia/repo/object"
 	"github.com/kopia/kopia/snapshot"
 	"github.com/kopia/kopia/snapshot/snapshotfs"
 )
@@ -25,6 +30,42 @@ type commandSnapshotVerify struct {
 
 	fileQueueLength int
 	fileParallelism int
+	prefetch        bool
+	checkpointFile  string
+
+	jo jsonOutput
+}
+
+// snapshotVerifyCheckpointInterval is the minimum interval between writes of the checkpoint file.
+const snapshotVerifyCheckpointInterval = 10 * time.Second
+
+// snapshotVerifyCheckpoint records objects whose trees have been verified without errors,
+// so that verification can be resumed after interruption.
+type snapshotVerifyCheckpoint struct {
+	VerifyFilesPercent float64  `json:"verifyFilesPercent"`
+	Verified           []string `json:"verified"` // object IDs of verified directories and root files
+}
+
+// snapshotVerifyProgress is the structured progress of snapshot verification emitted with --json.
+type snapshotVerifyProgress struct {
+	snapshotfs.VerifierStats
+
+	CheckpointedObjects int  `json:"checkpointedObjects,omitempty"`
+	Finished            bool `json:"finished,omitempty"`
+}
+
+// snapshotVerifyCheckpointer records verified objects in the checkpoint, replacing subdirectories
+// of verified directories, and periodically saves it.
+type snapshotVerifyCheckpointer struct {
+	fname string
+
+	mu sync.Mutex
+	// +checklocks:mu
+	cp *snapshotVerifyCheckpoint
+	// +checklocks:mu
+	verified map[object.ID]bool
+	// +checklocks:mu
+	throttle timetrack.Throttle
 }
 
 func (c *commandSnapshotVerify) setup(svc appServices, parent commandParent) {
@@ -39,7 +80,10 @@ func (c *commandSnapshotVerify) setup(svc appServices, parent commandParent) {
 	cmd.Flag("parallel", "Parallelization").Default("8").IntVar(&c.verifyCommandParallel)
 	cmd.Flag("file-queue-length", "Queue length for file verification").Default("20000").IntVar(&c.fileQueueLength)
 	cmd.Flag("file-parallelism", "Parallelism for file verification").IntVar(&c.fileParallelism)
+	cmd.Flag("prefetch", "Prefetch contents of files selected by --verify-files-percent into the cache before reading them").BoolVar(&c.prefetch)
 	cmd.Flag("verify-files-percent", "Randomly verify a percentage of files by downloading them [0.0 .. 100.0]").Default("0").Float64Var(&c.verifyCommandFilesPercent)
+	cmd.Flag("checkpoint-file", "Record progress in the provided file and resume interrupted verification from it").StringVar(&c.checkpointFile)
+	c.jo.setup(svc, cmd)
 	cmd.Action(svc.repositoryReaderAction(c.run))
 }
 
@@ -48,6 +92,11 @@ func (c *commandSnapshotVerify) run(ctx context.Context, rep repo.Repository) er
 		log(ctx).Error("DEPRECATED: --all-sources flag has no effect and is the default when no sources are provided.")
 	}
 
+	cp, err := c.loadCheckpoint()
+	if err != nil {
+		return err
+	}
+
 	if dr, ok := rep.(repo.DirectRepositoryWriter); ok {
 		dr.DisableIndexRefresh()
 	}
@@ -56,6 +105,7 @@ func (c *commandSnapshotVerify) run(ctx context.Context, rep repo.Repository) er
 		VerifyFilesPercent: c.verifyCommandFilesPercent,
 		FileQueueLength:    c.fileQueueLength,
 		Parallelism:        c.fileParallelism,
+		Prefetch:           c.prefetch,
 		MaxErrors:          c.verifyCommandErrorThreshold,
 	}
 
@@ -68,57 +118,219 @@ func (c *commandSnapshotVerify) run(ctx context.Context, rep repo.Repository) er
 		opts.BlobMap = blobMap
 	}
 
+	var cpr *snapshotVerifyCheckpointer
+
+	if c.checkpointFile != "" {
+		cpr, err = newSnapshotVerifyCheckpointer(c.checkpointFile, cp)
+		if err != nil {
+			return err
+		}
+
+		opts.Verified = cpr.verifiedObjectIDs()
+		opts.OnVerified = func(ctx context.Context, oid object.ID, entryPath string, covers []object.ID) {
+			if err := cpr.add(oid, covers); err != nil {
+				log(ctx).Errorf("%v", err)
+			}
+		}
+	}
+
+	if c.jo.jsonOutput {
+		opts.OnProgress = func(_ context.Context, stats snapshotfs.VerifierStats) {
+			c.jo.printJSON(snapshotVerifyProgress{
+				VerifierStats:       stats,
+				CheckpointedObjects: cpr.count(),
+			})
+		}
+	}
+
 	v := snapshotfs.NewVerifier(ctx, rep, opts)
 	defer v.ShowFinalStats(ctx)
 
-	//nolint:wrapcheck
-	return v.InParallel(ctx, func(tw *snapshotfs.TreeWalker) error {
-		manifests, err := c.loadSourceManifests(ctx, rep, c.verifyCommandSources)
+	var enqueueErr error
+
+	err = v.InParallel(ctx, func(tw *snapshotfs.TreeWalker) error {
+		enqueueErr = c.enqueueVerification(ctx, rep, tw)
+		return enqueueErr
+	})
+
+	if c.jo.jsonOutput {
+		c.jo.printJSON(snapshotVerifyProgress{
+			VerifierStats:       v.Stats(),
+			CheckpointedObjects: cpr.count(),
+			Finished:            true,
+		})
+	}
+
+	if err != nil {
+		// keep the objects verified so far.
+		if serr := cpr.save(); serr != nil {
+			log(ctx).Errorf("%v", serr)
+		}
+	}
+
+	if err != nil && enqueueErr == nil {
+		// errors were found while verifying the data.
+		return withExitCode(ExitCodeVerificationFailed, err)
+	}
+
+	if err != nil {
+		return err //nolint:wrapcheck
+	}
+
+	return removeVerifyCheckpoint(c.checkpointFile)
+}
+
+func (c *commandSnapshotVerify) loadCheckpoint() (*snapshotVerifyCheckpoint, error) {
+	cp := &snapshotVerifyCheckpoint{
+		VerifyFilesPercent: c.verifyCommandFilesPercent,
+	}
+
+	var existing snapshotVerifyCheckpoint
+
+	found, err := loadVerifyCheckpoint(c.checkpointFile, &existing)
+	if err != nil || !found {
+		return cp, err
+	}
+
+	if existing.VerifyFilesPercent != cp.VerifyFilesPercent {
+		return nil, errors.New("checkpoint file was created with different verification parameters")
+	}
+
+	return &existing, nil
+}
+
+func newSnapshotVerifyCheckpointer(fname string, cp *snapshotVerifyCheckpoint) (*snapshotVerifyCheckpointer, error) {
+	verified := map[object.ID]bool{}
+
+	for _, s := range cp.Verified {
+		oid, err := object.ParseID(s)
 		if err != nil {
-			return err
+			return nil, errors.Wrapf(err, "invalid object ID in checkpoint file: %q", s)
 		}
 
-		for _, man := range manifests {
-			rootPath := fmt.Sprintf("%v@%v", man.Source, formatTimestamp(man.StartTime.ToTime()))
+		verified[oid] = true
+	}
 
-			if man.RootEntry == nil {
-				continue
-			}
+	return &snapshotVerifyCheckpointer{
+		fname:    fname,
+		cp:       cp,
+		verified: verified,
+	}, nil
+}
 
-			root, err := snapshotfs.SnapshotRoot(rep, man)
-			if err != nil {
-				return errors.Wrapf(err, "unable to get snapshot root: %q", rootPath)
-			}
+func (p *snapshotVerifyCheckpointer) verifiedObjectIDs() []object.ID {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	var result []object.ID
+
+	for oid := range p.verified {
+		result = append(result, oid)
+	}
+
+	return result
+}
+
+// add records the verified object, which replaces the provided subdirectories, and saves the checkpoint
+// unless it's been saved recently.
+func (p *snapshotVerifyCheckpointer) add(oid object.ID, covers []object.ID) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	for _, sub := range covers {
+		delete(p.verified, sub)
+	}
+
+	p.verified[oid] = true
+
+	if !p.throttle.ShouldOutput(snapshotVerifyCheckpointInterval) {
+		return nil
+	}
+
+	return p.saveLocked()
+}
+
+func (p *snapshotVerifyCheckpointer) count() int {
+	if p == nil {
+		return 0
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	return len(p.verified)
+}
+
+func (p *snapshotVerifyCheckpointer) save() error {
+	if p == nil {
+		return nil
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
 
-			// ignore error now, return aggregate error at a higher level.
-			//nolint:errcheck
-			tw.Process(ctx, root, rootPath)
+	return p.saveLocked()
+}
+
+// +checklocks:p.mu
+func (p *snapshotVerifyCheckpointer) saveLocked() error {
+	p.cp.Verified = p.cp.Verified[:0]
+
+	for oid := range p.verified {
+		p.cp.Verified = append(p.cp.Verified, oid.String())
+	}
+
+	slices.Sort(p.cp.Verified)
+
+	return saveVerifyCheckpoint(p.fname, p.cp)
+}
+
+func (c *commandSnapshotVerify) enqueueVerification(ctx context.Context, rep repo.Repository, tw *snapshotfs.TreeWalker) error {
+	manifests, err := c.loadSourceManifests(ctx, rep, c.verifyCommandSources)
+	if err != nil {
+		return err
+	}
+
+	for _, man := range manifests {
+		rootPath := fmt.Sprintf("%v@%v", man.Source, formatTimestamp(man.StartTime.ToTime()))
+
+		if man.RootEntry == nil {
+			continue
 		}
 
-		for _, oidStr := range c.verifyCommandDirObjectIDs {
-			oid, err := snapshotfs.ParseObjectIDWithPath(ctx, rep, oidStr)
-			if err != nil {
-				return errors.Wrapf(err, "unable to parse: %q", oidStr)
-			}
+		root, err := snapshotfs.SnapshotRoot(rep, man)
+		if err != nil {
+			return errors.Wrapf(err, "unable to get snapshot root: %q", rootPath)
+		}
+
+		// ignore error now, return aggregate error at a higher level.
+		//nolint:errcheck
+		tw.Process(ctx, root, rootPath)
+	}
 
-			// ignore error now, return aggregate error at a higher level.
-			//nolint:errcheck
-			tw.Process(ctx, snapshotfs.DirectoryEntry(rep, oid, nil), oidStr)
+	for _, oidStr := range c.verifyCommandDirObjectIDs {
+		oid, err := snapshotfs.ParseObjectIDWithPath(ctx, rep, oidStr)
+		if err != nil {
+			return errors.Wrapf(err, "unable to parse: %q", oidStr)
 		}
 
-		for _, oidStr := range c.verifyCommandFileObjectIDs {
-			oid, err := snapshotfs.ParseObjectIDWithPath(ctx, rep, oidStr)
-			if err != nil {
-				return errors.Wrapf(err, "unable to parse %q", oidStr)
-			}
+		// ignore error now, return aggregate error at a higher level.
+		//nolint:errcheck
+		tw.Process(ctx, snapshotfs.DirectoryEntry(rep, oid, nil), oidStr)
+	}
 
-			// ignore error now, return aggregate error at a higher level.
-			//nolint:errcheck
-			tw.Process(ctx, snapshotfs.AutoDetectEntryFromObjectID(ctx, rep, oid, oidStr), oidStr)
+	for _, oidStr := range c.verifyCommandFileObjectIDs {
+		oid, err := snapshotfs.ParseObjectIDWithPath(ctx, rep, oidStr)
+		if err != nil {
+			return errors.Wrapf(err, "unable to parse %q", oidStr)
 		}
 
-		return nil
-	})
+		// ignore error now, return aggregate error at a higher level.
+		//nolint:errcheck
+		tw.Process(ctx, snapshotfs.AutoDetectEntryFromObjectID(ctx, rep, oid, oidStr), oidStr)
+	}
+
+	return nil
 }
 
 func (c *commandSnapshotVerify) loadSourceManifests(ctx context.Context, rep repo.Repository, sources []string) ([]*snapshot.Manifest, error) {
@@ -0,0 +1,137 @@
+package cli_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/kopia/kopia/internal/testutil"
+	"github.com/kopia/kopia/tests/testenv"
+)
+
+func TestSnapshotVerifyCheckpoint(t *testing.T) {
+	t.Parallel()
+
+	e := testenv.NewCLITest(t, testenv.RepoFormatNotImportant, testenv.NewInProcRunner(t))
+
+	e.RunAndExpectSuccess(t, "repo", "create", "filesystem", "--path", e.RepoDir)
+
+	for i := range 2 {
+		dir := testutil.TempDirectory(t)
+		require.NoError(t, os.WriteFile(filepath.Join(dir, "file1.txt"), bytes.Repeat([]byte{1, 2, 3, byte(i)}, 15000), 0o600))
+		e.RunAndExpectSuccess(t, "snapshot", "create", dir)
+	}
+
+	cpFile := filepath.Join(testutil.TempDirectory(t), "checkpoint.json")
+
+	e.RunAndExpectSuccess(t, "snapshot", "verify", "--checkpoint-file", cpFile)
+	require.NoFileExists(t, cpFile)
+
+	var verified []string
+
+	for _, man := range mustListSnapshots(t, e) {
+		verified = append(verified, man.RootObjectID().String())
+	}
+
+	// delete one of 'p' blobs, which fails verification unless all snapshots have been verified before.
+	e.RunAndExpectSuccess(t, "blob", "delete", strings.Split(e.RunAndExpectSuccess(t, "blob", "list", "--prefix=p")[0], " ")[0])
+	e.RunAndExpectFailure(t, "snapshot", "verify")
+
+	writeSnapshotVerifyCheckpoint(t, cpFile, map[string]any{
+		"verifyFilesPercent": 0,
+		"verified":           verified,
+	})
+
+	e.RunAndExpectSuccess(t, "snapshot", "verify", "--checkpoint-file", cpFile)
+	require.NoFileExists(t, cpFile)
+
+	// checkpoint created with different parameters can't be resumed.
+	writeSnapshotVerifyCheckpoint(t, cpFile, map[string]any{
+		"verifyFilesPercent": 100,
+		"verified":           verified,
+	})
+
+	e.RunAndExpectFailure(t, "snapshot", "verify", "--checkpoint-file", cpFile)
+}
+
+func TestSnapshotVerifyCheckpointsDirectoriesWithinSnapshot(t *testing.T) {
+	t.Parallel()
+
+	e := testenv.NewCLITest(t, testenv.RepoFormatNotImportant, testenv.NewInProcRunner(t))
+
+	e.RunAndExpectSuccess(t, "repo", "create", "filesystem", "--path", e.RepoDir)
+
+	dir := testutil.TempDirectory(t)
+	require.NoError(t, os.MkdirAll(filepath.Join(dir, "good", "sub"), 0o755))
+	require.NoError(t, os.MkdirAll(filepath.Join(dir, "bad"), 0o755))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "good", "sub", "file1.txt"), bytes.Repeat([]byte{1, 2, 3}, 15000), 0o600))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "good", "file2.txt"), bytes.Repeat([]byte{2, 3, 4}, 15000), 0o600))
+	e.RunAndExpectSuccess(t, "snapshot", "create", dir)
+
+	// contents of the file in 'bad' are written to a pack blob of their own.
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "bad", "file3.txt"), bytes.Repeat([]byte{3, 4, 5}, 15000), 0o600))
+	e.RunAndExpectSuccess(t, "snapshot", "create", dir)
+
+	snapshots := mustListSnapshots(t, e)
+	rootOID := snapshots[len(snapshots)-1].RootObjectID().String()
+
+	oids := map[string]string{}
+
+	for _, l := range e.RunAndExpectSuccess(t, "ls", "-r", "-o", rootOID) {
+		if f := strings.Fields(l); len(f) == 2 {
+			oids[strings.TrimSuffix(strings.TrimPrefix(f[1], rootOID+"/"), "/")] = f[0]
+		}
+	}
+
+	// remove the pack blob of the file in 'bad', which fails verification of the latest snapshot.
+	for _, l := range e.RunAndExpectSuccess(t, "content", "list", "-l") {
+		if f := strings.Fields(l); f[0] == oids["bad/file3.txt"] {
+			e.RunAndExpectSuccess(t, "blob", "delete", f[5])
+		}
+	}
+
+	cpFile := filepath.Join(testutil.TempDirectory(t), "checkpoint.json")
+
+	// verification continues after the error, so that 'good' is always verified.
+	stdout, _ := e.RunAndExpectFailure(t, "snapshot", "verify", "--directory-id", rootOID, "--max-errors", "10", "--checkpoint-file", cpFile, "--json")
+	require.NotEmpty(t, stdout)
+
+	var progress struct {
+		Errors              int  `json:"errors"`
+		CheckpointedObjects int  `json:"checkpointedObjects"`
+		Finished            bool `json:"finished"`
+	}
+
+	require.NoError(t, json.Unmarshal([]byte(stdout[len(stdout)-1]), &progress))
+	require.True(t, progress.Finished)
+	require.Equal(t, 1, progress.Errors)
+	require.Equal(t, 1, progress.CheckpointedObjects)
+
+	var cp struct {
+		Verified []string `json:"verified"`
+	}
+
+	b, err := os.ReadFile(cpFile)
+	require.NoError(t, err)
+	require.NoError(t, json.Unmarshal(b, &cp))
+
+	// 'good' is verified along with its subdirectory, which it replaces in the checkpoint.
+	require.Equal(t, []string{oids["good"]}, cp.Verified)
+
+	// resumed verification skips 'good' and fails again on 'bad'.
+	e.RunAndExpectFailure(t, "snapshot", "verify", "--directory-id", rootOID, "--max-errors", "10", "--checkpoint-file", cpFile)
+	require.FileExists(t, cpFile)
+}
+
+func writeSnapshotVerifyCheckpoint(t *testing.T, fname string, cp map[string]any) {
+	t.Helper()
+
+	b, err := json.Marshal(cp)
+	require.NoError(t, err)
+	require.NoError(t, os.WriteFile(fname, b, 0o600))
+}
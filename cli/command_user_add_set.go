@@ -2,7 +2,6 @@
 
 import (
 	"context"
-	"io"
 
 	"github.com/alecthomas/kingpin/v2"
 	"github.com/pkg/errors"
@@ -19,6 +18,7 @@ type commandServerUserAddSet struct {
 
 	isNew bool // true == 'add', false == 'update'
 	out   textOutput
+	svc   appServices
 }
 
 func (c *commandServerUserAddSet) setup(svc appServices, parent commandParent, isNew bool) {
@@ -39,6 +39,7 @@ func (c *commandServerUserAddSet) setup(svc appServices, parent commandParent, i
 	cmd.Action(svc.repositoryWriterAction(c.runServerUserAddSet))
 
 	c.out.setup(svc)
+	c.svc = svc
 }
 
 func (c *commandServerUserAddSet) getExistingOrNewUserProfile(ctx context.Context, rep repo.Repository, username string) (*user.Profile, error) {
@@ -80,7 +81,7 @@ func (c *commandServerUserAddSet) runServerUserAddSet(ctx context.Context, rep r
 	}
 
 	if up.PasswordHash == nil || c.userAskPassword {
-		pwd, err := askConfirmPass(c.out.stdout(), "Enter new password for user "+username+": ")
+		pwd, err := askConfirmPass(c.svc, "Enter new password for user "+username+": ")
 		if err != nil {
 			return err
 		}
@@ -108,19 +109,19 @@ func (c *commandServerUserAddSet) runServerUserAddSet(ctx context.Context, rep r
 	return nil
 }
 
-func askConfirmPass(out io.Writer, initialPrompt string) (string, error) {
-	pwd, err := askPass(out, initialPrompt)
+func askConfirmPass(svc appServices, initialPrompt string) (string, error) {
+	pwd, err := svc.askPass(initialPrompt)
 	if err != nil {
 		return "", errors.Wrap(err, "error asking for password")
 	}
 
-	pwd2, err := askPass(out, "Re-enter password for verification: ")
+	pwd2, err := svc.askPass("Re-enter password for verification: ")
 	if err != nil {
 		return "", errors.Wrap(err, "error asking for password")
 	}
 
 	if pwd != pwd2 {
-		return "", errors.Wrap(err, "passwords don't match")
+		return "", errors.New("passwords don't match")
 	}
 
 	return pwd, nil
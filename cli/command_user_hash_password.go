@@ -13,6 +13,7 @@ type commandServerUserHashPassword struct {
 	password string
 
 	out textOutput
+	svc appServices
 }
 
 func (c *commandServerUserHashPassword) setup(svc appServices, parent commandParent) {
@@ -23,6 +24,7 @@ func (c *commandServerUserHashPassword) setup(svc appServices, parent commandPar
 	cmd.Action(svc.repositoryWriterAction(c.runServerUserHashPassword))
 
 	c.out.setup(svc)
+	c.svc = svc
 }
 
 // The current implementation does not require a connected repository, thus the
@@ -33,7 +35,7 @@ func (c *commandServerUserHashPassword) setup(svc appServices, parent commandPar
 func (c *commandServerUserHashPassword) runServerUserHashPassword(ctx context.Context, _ repo.RepositoryWriter) error {
 	if c.password == "" {
 		// when password hash is empty, ask for password
-		pwd, err := askConfirmPass(c.out.stdout(), "Enter password to hash: ")
+		pwd, err := askConfirmPass(c.svc, "Enter password to hash: ")
 		if err != nil {
 			return errors.Wrap(err, "error getting password")
 		}
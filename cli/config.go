@@ -34,20 +34,64 @@ func (c *App) onTerminate(f func()) {
 	s := make(chan os.Signal, 1)
 	signal.Notify(s, os.Interrupt, syscall.SIGTERM)
 
+	simulated := c.inv.simulatedCtrlC
+	timedOut := c.inv.commandTimedOut
+
 	go func() {
 		// invoke the function when either real or simulated Ctrl-C signal is delivered
+		// or the command exceeds --timeout.
 		select {
-		case v := <-c.simulatedCtrlC:
+		case v := <-simulated:
 			if !v {
 				return
 			}
 
 		case <-s:
+		case <-timedOut:
 		}
 		f()
 	}()
 }
 
+// onProfileDumpRequest invokes the provided function each time a real or simulated request to dump
+// profile buffers is delivered, until the returned function is called.
+func (c *App) onProfileDumpRequest(f func()) (stop func()) {
+	s := make(chan os.Signal, 1)
+	stopNotify := notifyProfileDumpSignals(s)
+
+	simulated := c.inv.simulatedSigDump
+	done := make(chan struct{})
+	stopped := make(chan struct{})
+
+	go func() {
+		defer close(stopped)
+
+		for {
+			select {
+			case v := <-simulated:
+				if !v {
+					// simulated signal channel closed at the end of subcommand.
+					simulated = nil
+					continue
+				}
+
+			case <-s:
+
+			case <-done:
+				return
+			}
+
+			f()
+		}
+	}()
+
+	return func() {
+		stopNotify()
+		close(done)
+		<-stopped
+	}
+}
+
 func (c *App) openRepository(ctx context.Context, required bool) (repo.Repository, error) {
 	if _, err := os.Stat(c.repositoryConfigFileName()); os.IsNotExist(err) {
 		if !required {
@@ -74,10 +118,22 @@ func (c *App) openRepository(ctx context.Context, required bool) (repo.Repositor
 
 func (c *App) optionsFromFlags(ctx context.Context) *repo.Options {
 	return &repo.Options{
-		TraceStorage:        c.traceStorage,
+		TraceStorage: repo.TraceStorageOptions{
+			Enabled:       c.traceStorage,
+			SampleEvery:   c.traceStorageSampleEvery,
+			SlowThreshold: c.traceStorageSlowThreshold,
+			RedactBlobIDs: c.traceStorageRedactBlobIDs,
+		},
 		DisableInternalLog:  c.disableInternalLog,
 		UpgradeOwnerID:      c.upgradeOwnerID,
 		DoNotWaitForUpgrade: c.doNotWaitForUpgrade,
+		VerifyBlobChecksums: c.verifyBlobChecksums,
+		BlobReadAheadBytes:  int64(c.blobReadAhead),
+		MaxIndexMemoryBytes: int64(c.maxIndexMemory),
+		StorageCircuitBreaker: repo.StorageCircuitBreakerOptions{
+			CoolDown: c.storageCircuitBreakerCoolDown,
+		},
+		OTLPMetrics: c.observability.otlpMetricsOptions,
 
 		// when a fatal error is encountered in the repository, run all registered callbacks
 		// and exit the program.
@@ -88,7 +144,7 @@ func (c *App) optionsFromFlags(ctx context.Context) *repo.Options {
 				cb(err)
 			}
 
-			c.exitWithError(err)
+			c.inv.exitWithError(err)
 		},
 
 		TestOnlyIgnoreMissingRequiredFeatures: c.testonlyIgnoreMissingRequiredFeatures,
@@ -0,0 +1,142 @@
+package cli
+
+import (
+	"net"
+	"os/exec"
+	"syscall"
+
+	"github.com/pkg/errors"
+
+	"github.com/kopia/kopia/repo"
+)
+
+// Exit codes produced by the kopia CLI. The values are stable and can be used by automation to
+// distinguish classes of failures.
+const (
+	// ExitCodeSuccess indicates that the command completed successfully.
+	ExitCodeSuccess = 0
+
+	// ExitCodeRuntimeFailure indicates a failure which does not belong to any other class.
+	ExitCodeRuntimeFailure = 1
+
+	// ExitCodeInvalidArgs indicates invalid usage, such as unknown commands or flags.
+	ExitCodeInvalidArgs = 2
+
+	// ExitCodeRepositoryNotFound indicates that the storage does not contain a repository.
+	ExitCodeRepositoryNotFound = 3
+
+	// ExitCodeInvalidPassword indicates that the repository password is incorrect.
+	ExitCodeInvalidPassword = 4
+
+	// ExitCodeStorageUnreachable indicates that the storage host could not be reached, because it
+	// could not be resolved, refused the connection or was unreachable.
+	ExitCodeStorageUnreachable = 5
+
+	// ExitCodeVerificationFailed indicates that verification found missing or corrupted data.
+	ExitCodeVerificationFailed = 6
+
+	// ExitCodePartialSnapshot indicates that a snapshot was created, but some errors were ignored
+	// while creating it. It is only produced by 'snapshot create --fail-on-ignored-errors'.
+	ExitCodePartialSnapshot = 7
+
+	// ExitCodeTimeout indicates that the command did not complete within the time limit set using --timeout.
+	ExitCodeTimeout = 8
+)
+
+// ExitError is returned by in-process subcommands and carries the exit code
+// the kopia binary would have produced.
+type ExitError struct {
+	Code int
+	Err  error
+}
+
+func (e *ExitError) Error() string {
+	return e.Err.Error()
+}
+
+func (e *ExitError) Unwrap() error {
+	return e.Err
+}
+
+// exitCodeError associates an error with the exit code it should produce.
+type exitCodeError struct {
+	code int
+	err  error
+}
+
+func (e *exitCodeError) Error() string {
+	return e.err.Error()
+}
+
+func (e *exitCodeError) Unwrap() error {
+	return e.err
+}
+
+// withExitCode returns an error which causes the command to exit with the provided code.
+func withExitCode(code int, err error) error {
+	if err == nil {
+		return nil
+	}
+
+	return &exitCodeError{code, err}
+}
+
+// exitCodeForError returns the exit code the CLI produces when a command fails with the provided error.
+func exitCodeForError(err error) int {
+	if err == nil {
+		return ExitCodeSuccess
+	}
+
+	var ece *exitCodeError
+	if errors.As(err, &ece) {
+		return ece.code
+	}
+
+	switch {
+	case errors.Is(err, repo.ErrRepositoryNotInitialized):
+		return ExitCodeRepositoryNotFound
+	case errors.Is(err, repo.ErrInvalidPassword):
+		return ExitCodeInvalidPassword
+	case isStorageUnreachable(err):
+		return ExitCodeStorageUnreachable
+	default:
+		return ExitCodeRuntimeFailure
+	}
+}
+
+// isStorageUnreachable determines whether the error indicates that the storage host could not be
+// reached at all. Other network errors, such as timeouts or connections reset while transferring
+// data, may be transient and are reported as runtime failures.
+func isStorageUnreachable(err error) bool {
+	var de *net.DNSError
+	if errors.As(err, &de) {
+		return de.IsNotFound
+	}
+
+	var oe *net.OpError
+	if !errors.As(err, &oe) {
+		return false
+	}
+
+	return errors.Is(oe, syscall.ECONNREFUSED) || errors.Is(oe, syscall.EHOSTUNREACH) || errors.Is(oe, syscall.ENETUNREACH)
+}
+
+// ExitCode returns the numeric exit code corresponding to the error returned by a subcommand,
+// either executed in-process using RunSubcommand() or as an external kopia process.
+func ExitCode(err error) int {
+	if err == nil {
+		return ExitCodeSuccess
+	}
+
+	var ee *ExitError
+	if errors.As(err, &ee) {
+		return ee.Code
+	}
+
+	var pe *exec.ExitError
+	if errors.As(err, &pe) {
+		return pe.ExitCode()
+	}
+
+	return ExitCodeRuntimeFailure
+}
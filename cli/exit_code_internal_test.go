@@ -0,0 +1,44 @@
+package cli
+
+import (
+	"context"
+	"net"
+	"os"
+	"syscall"
+	"testing"
+
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/require"
+
+	"github.com/kopia/kopia/repo"
+)
+
+func TestExitCodeForError(t *testing.T) {
+	opError := func(op string, err error) error {
+		return errors.Wrap(&net.OpError{Op: op, Net: "tcp", Err: &os.SyscallError{Syscall: op, Err: err}}, "unable to list blobs")
+	}
+
+	cases := []struct {
+		desc string
+		err  error
+		want int
+	}{
+		{"nil", nil, ExitCodeSuccess},
+		{"generic", errors.New("some error"), ExitCodeRuntimeFailure},
+		{"explicit", withExitCode(ExitCodeVerificationFailed, errors.New("some error")), ExitCodeVerificationFailed},
+		{"not initialized", errors.Wrap(repo.ErrRepositoryNotInitialized, "unable to connect"), ExitCodeRepositoryNotFound},
+		{"invalid password", errors.Wrap(repo.ErrInvalidPassword, "unable to connect"), ExitCodeInvalidPassword},
+		{"connection refused", opError("dial", syscall.ECONNREFUSED), ExitCodeStorageUnreachable},
+		{"host unreachable", opError("dial", syscall.EHOSTUNREACH), ExitCodeStorageUnreachable},
+		{"network unreachable", opError("dial", syscall.ENETUNREACH), ExitCodeStorageUnreachable},
+		{"unknown host", &net.DNSError{Err: "no such host", Name: "no-such-host", IsNotFound: true}, ExitCodeStorageUnreachable},
+		{"dns timeout", &net.DNSError{Err: "i/o timeout", Name: "some-host", IsTimeout: true}, ExitCodeRuntimeFailure},
+		{"connection reset", opError("read", syscall.ECONNRESET), ExitCodeRuntimeFailure},
+		{"broken pipe", opError("write", syscall.EPIPE), ExitCodeRuntimeFailure},
+		{"dial timeout", &net.OpError{Op: "dial", Net: "tcp", Err: context.DeadlineExceeded}, ExitCodeRuntimeFailure},
+	}
+
+	for _, tc := range cases {
+		require.Equal(t, tc.want, exitCodeForError(tc.err), tc.desc)
+	}
+}
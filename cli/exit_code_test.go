@@ -0,0 +1,80 @@
+package cli_test
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/kopia/kopia/cli"
+	"github.com/kopia/kopia/internal/testutil"
+	"github.com/kopia/kopia/tests/testenv"
+)
+
+func TestExitCodes(t *testing.T) {
+	t.Parallel()
+
+	runners := map[string]func(t *testing.T) testenv.CLIRunner{
+		"inproc": func(t *testing.T) testenv.CLIRunner {
+			t.Helper()
+			return testenv.NewInProcRunner(t)
+		},
+		"exe": func(t *testing.T) testenv.CLIRunner {
+			t.Helper()
+			return testenv.NewExeRunner(t)
+		},
+	}
+
+	for name, newRunner := range runners {
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			e := testenv.NewCLITest(t, testenv.RepoFormatNotImportant, newRunner(t))
+
+			e.RunAndExpectExitCode(t, cli.ExitCodeInvalidArgs, "no-such-command")
+			e.RunAndExpectExitCode(t, cli.ExitCodeInvalidArgs, "snapshot", "list", "--no-such-flag")
+
+			// not connected to a repository.
+			e.RunAndExpectExitCode(t, cli.ExitCodeRuntimeFailure, "snapshot", "list")
+
+			e.RunAndExpectExitCode(t, cli.ExitCodeSuccess, "repo", "create", "filesystem", "--path", e.RepoDir)
+			e.RunAndExpectExitCode(t, cli.ExitCodeSuccess, "snapshot", "list")
+		})
+	}
+}
+
+func TestExitCodeTaxonomy(t *testing.T) {
+	t.Parallel()
+
+	e := testenv.NewCLITest(t, testenv.RepoFormatNotImportant, testenv.NewInProcRunner(t))
+
+	// storage does not contain a repository.
+	e.RunAndExpectExitCode(t, cli.ExitCodeRepositoryNotFound, "repo", "connect", "filesystem", "--path", e.RepoDir)
+
+	e.RunAndExpectExitCode(t, cli.ExitCodeSuccess, "repo", "create", "filesystem", "--path", e.RepoDir)
+	e.RunAndExpectExitCode(t, cli.ExitCodeSuccess, "repo", "disconnect")
+	e.RunAndExpectExitCode(t, cli.ExitCodeInvalidPassword, "repo", "connect", "filesystem", "--path", e.RepoDir, "--password", "wrong-password")
+
+	// nothing is listening on port 1.
+	e.RunAndExpectExitCode(t, cli.ExitCodeStorageUnreachable, "repo", "connect", "webdav", "--url", "http://127.0.0.1:1/")
+
+	e.RunAndExpectExitCode(t, cli.ExitCodeSuccess, "repo", "connect", "filesystem", "--path", e.RepoDir)
+
+	dir := testutil.TempDirectory(t)
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "file1.txt"), bytes.Repeat([]byte{1, 2, 3, 4, 5}, 15000), 0o600))
+
+	e.RunAndExpectExitCode(t, cli.ExitCodeSuccess, "snapshot", "create", dir)
+
+	// delete one of 'p' blobs.
+	e.RunAndExpectExitCode(t, cli.ExitCodeSuccess, "blob", "delete", strings.Split(e.RunAndExpectSuccess(t, "blob", "list", "--prefix=p")[0], " ")[0])
+
+	e.RunAndExpectExitCode(t, cli.ExitCodeVerificationFailed, "content", "verify")
+	e.RunAndExpectExitCode(t, cli.ExitCodeVerificationFailed, "snapshot", "verify")
+
+	// command does not complete in time.
+	e.RunAndExpectExitCode(t, cli.ExitCodeTimeout, "snapshot", "create", dir, "--timeout=1ns")
+	e.RunAndExpectExitCode(t, cli.ExitCodeSuccess, "snapshot", "list", "--timeout=1m")
+}
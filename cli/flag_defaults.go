@@ -0,0 +1,182 @@
+package cli
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/alecthomas/kingpin/v2"
+	"github.com/pkg/errors"
+
+	"github.com/kopia/kopia/internal/ospath"
+)
+
+const (
+	flagDefaultsFileEnvar       = "KOPIA_FLAGS_FILE"
+	defaultFlagDefaultsFileName = "kopia.flags"
+)
+
+// flagDefaultsSection holds default values of flags for a single command, or global flags if the command is empty.
+type flagDefaultsSection struct {
+	command  []string
+	defaults map[string][]string
+	// order in which flags appear in the file, for deterministic processing.
+	names []string
+}
+
+// flagDefaultsFileName returns the name of the file providing flag defaults.
+func (c *App) flagDefaultsFileName() string {
+	if fn := c.getEnv(flagDefaultsFileEnvar); fn != "" {
+		return fn
+	}
+
+	if c.isInProcessTest {
+		// do not let files in the home directory of the user running tests affect the results.
+		return ""
+	}
+
+	return filepath.Join(ospath.ConfigDir(), defaultFlagDefaultsFileName)
+}
+
+// applyFlagDefaults overrides defaults of flags using values from the flag defaults file, so they apply
+// below values provided using environment variables and explicit flags.
+func (c *App) applyFlagDefaults(app *kingpin.Application) {
+	var warnings []string
+
+	// warnings are printed when parsing, since output streams may not be consumed until then.
+	app.PreAction(func(_ *kingpin.ParseContext) error {
+		for _, w := range warnings {
+			fmt.Fprintf(c.inv.stderr, "WARNING: %v\n", w) //nolint:errcheck
+		}
+
+		return nil
+	})
+
+	fname := c.flagDefaultsFileName()
+	if fname == "" {
+		return
+	}
+
+	sections, err := parseFlagDefaultsFile(fname)
+	if err != nil {
+		if !os.IsNotExist(errors.Cause(err)) {
+			warnings = append(warnings, fmt.Sprintf("unable to read flag defaults: %v", err))
+		}
+
+		return
+	}
+
+	for _, s := range sections {
+		for _, name := range s.names {
+			f := findFlagForDefaults(app, s.command, name)
+			if f == nil {
+				if len(s.command) > 0 && app.GetFlag(name) != nil {
+					// defaults of global flags apply to all commands, so they can't be provided per command.
+					warnings = append(warnings, fmt.Sprintf("%v: global flag %q must be set before the first command section, not for command %q", fname, name, strings.Join(s.command, " ")))
+					continue
+				}
+
+				warnings = append(warnings, fmt.Sprintf("%v: unknown flag %q for command %q", fname, name, strings.Join(s.command, " ")))
+
+				continue
+			}
+
+			f.Default(s.defaults[name]...)
+		}
+	}
+}
+
+// findFlagForDefaults returns the flag of the provided command with the provided name, or the global flag
+// if no command is provided.
+func findFlagForDefaults(app *kingpin.Application, command []string, name string) *kingpin.FlagClause {
+	if len(command) == 0 {
+		return app.GetFlag(name)
+	}
+
+	cmd := app.GetCommand(command[0])
+
+	for _, sub := range command[1:] {
+		if cmd == nil {
+			return nil
+		}
+
+		cmd = cmd.GetCommand(sub)
+	}
+
+	if cmd == nil {
+		return nil
+	}
+
+	return cmd.GetFlag(name)
+}
+
+// parseFlagDefaultsFile parses the file with flag defaults, which has the following format:
+//
+//	# global flags
+//	log-level=debug
+//
+//	[snapshot create]
+//	parallel=8
+//	no-progress
+//
+// Flags without a value are set to 'true', flags specified multiple times provide multiple values.
+func parseFlagDefaultsFile(fname string) ([]*flagDefaultsSection, error) {
+	f, err := os.Open(fname) //nolint:gosec
+	if err != nil {
+		return nil, errors.Wrap(err, "unable to open flag defaults file")
+	}
+
+	defer f.Close() //nolint:errcheck
+
+	current := &flagDefaultsSection{defaults: map[string][]string{}}
+	sections := []*flagDefaultsSection{current}
+
+	s := bufio.NewScanner(f)
+
+	for lineNumber := 1; s.Scan(); lineNumber++ {
+		line := strings.TrimSpace(s.Text())
+
+		switch {
+		case line == "" || strings.HasPrefix(line, "#"):
+			continue
+
+		case strings.HasPrefix(line, "["):
+			if !strings.HasSuffix(line, "]") {
+				return nil, errors.Errorf("%v:%v: invalid section header %q", fname, lineNumber, line)
+			}
+
+			current = &flagDefaultsSection{
+				command:  strings.Fields(strings.TrimSuffix(strings.TrimPrefix(line, "["), "]")),
+				defaults: map[string][]string{},
+			}
+			sections = append(sections, current)
+
+		default:
+			name, value, ok := strings.Cut(line, "=")
+			if !ok {
+				value = "true"
+			}
+
+			name = strings.TrimPrefix(strings.TrimSpace(name), "--")
+			value = strings.TrimSpace(value)
+
+			if negated, isNegated := strings.CutPrefix(name, "no-"); isNegated && !ok {
+				name, value = negated, "false"
+			}
+
+			if name == "" {
+				return nil, errors.Errorf("%v:%v: missing flag name", fname, lineNumber)
+			}
+
+			if _, exists := current.defaults[name]; !exists {
+				current.names = append(current.names, name)
+			}
+
+			current.defaults[name] = append(current.defaults[name], value)
+		}
+	}
+
+	return sections, errors.Wrap(s.Err(), "error reading flag defaults file")
+}
@@ -0,0 +1,53 @@
+package cli_test
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/kopia/kopia/internal/testutil"
+	"github.com/kopia/kopia/tests/testenv"
+)
+
+func TestFlagDefaultsFile(t *testing.T) {
+	env := testenv.NewCLITest(t, testenv.RepoFormatNotImportant, testenv.NewInProcRunner(t))
+	env.RunAndExpectSuccess(t, "repo", "create", "filesystem", "--path", env.RepoDir)
+
+	flagsFile := filepath.Join(testutil.TempDirectory(t), "kopia.flags")
+
+	require.NoError(t, os.WriteFile(flagsFile, []byte(`
+# global flags
+progress-update-interval=1s
+
+[repository throttle set]
+upload-bytes-per-second=10MB
+
+[repository throttle get]
+json
+`), 0o600))
+
+	env.Environment["KOPIA_FLAGS_FILE"] = flagsFile
+
+	env.RunAndExpectSuccess(t, "repo", "throttle", "set", "--download-bytes-per-second=1MB")
+
+	// defaults from the file apply below explicit flags.
+	out := env.RunAndExpectSuccess(t, "repo", "throttle", "get")
+	require.Equal(t, []string{`{"maxUploadSpeedBytesPerSecond":10000000,"maxDownloadSpeedBytesPerSecond":1000000}`}, out)
+
+	require.Contains(t, env.RunAndExpectSuccess(t, "repo", "throttle", "get", "--no-json"), "Max Upload Speed:              10 MB/s")
+
+	require.NoError(t, os.WriteFile(flagsFile, []byte(`
+[repository throttle get]
+no-such-flag=1
+progress-update-interval=5s
+`), 0o600))
+
+	_, stderr := env.RunAndExpectSuccessWithErrOut(t, "repo", "throttle", "get")
+	require.Contains(t, strings.Join(stderr, "\n"), `unknown flag "no-such-flag" for command "repository throttle get"`)
+
+	// global flags are not applied from command sections, since that would affect all commands.
+	require.Contains(t, strings.Join(stderr, "\n"), `global flag "progress-update-interval" must be set before the first command section`)
+}
@@ -0,0 +1,35 @@
+package cli_test
+
+import (
+	"path/filepath"
+	"slices"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/kopia/kopia/cli"
+	"github.com/kopia/kopia/internal/testutil"
+	"github.com/kopia/kopia/tests/testenv"
+)
+
+func TestGoldenOutput(t *testing.T) {
+	t.Parallel()
+
+	e := testenv.NewCLITest(t, testenv.RepoFormatNotImportant, testenv.NewInProcRunner(t))
+	source := e.UseRepositoryFixture(t, testenv.StandardRepositoryFixture)
+	sourceNormalizer := []testenv.GoldenNormalizer{testenv.ReplaceLiteral(source, "<SOURCE_DIR>")}
+
+	e.RunAndExpectGoldenOutput(t, filepath.Join("testdata", "policy_show_global.golden"), nil, "policy", "show", "--global")
+	e.RunAndExpectGoldenOutput(t, filepath.Join("testdata", "snapshot_list.golden"), sourceNormalizer, "snapshot", "list", "--all")
+
+	var snapshots []cli.SnapshotManifest
+
+	testutil.MustParseJSONLines(t, e.RunAndExpectSuccess(t, "snapshot", "list", "--json", source), &snapshots)
+	require.Len(t, snapshots, 1)
+
+	// order of directory entries returned by 'ls' is not stable.
+	lines := testenv.NormalizeOutput(e.RunAndExpectSuccess(t, "ls", "-l", snapshots[0].RootObjectID().String()), e.GoldenNormalizers()...)
+	slices.Sort(lines)
+
+	testenv.AssertGolden(t, filepath.Join("testdata", "ls.golden"), lines)
+}
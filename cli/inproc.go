@@ -4,6 +4,8 @@
 	"context"
 	"io"
 	"os"
+	"sync"
+	"syscall"
 
 	"github.com/alecthomas/kingpin/v2"
 
@@ -13,55 +15,166 @@
 
 // RunSubcommand executes the subcommand asynchronously in current process
 // with flags in an isolated CLI environment and returns standard output and standard error.
-func (c *App) RunSubcommand(ctx context.Context, kpapp *kingpin.Application, stdin io.Reader, argsAndFlags []string) (stdout, stderr io.Reader, wait func() error, interrupt func(os.Signal)) {
+//
+// The error returned by wait is an *ExitError, which carries the exit code the kopia binary would have
+// produced and can be retrieved using ExitCode().
+//
+// Options such as WithEnvironment() customize execution of the subcommand. Commands that prompt the user,
+// such as for a password, can be driven deterministically by providing responses using WithPrompts().
+// Final repository metrics of the subcommand can be collected using WithMetricsSnapshot().
+//
+// The returned interrupt function simulates delivery of a signal to the subcommand:
+//
+//   - os.Interrupt (SIGINT) cancels the context of the subcommand and invokes termination handlers,
+//   - SIGTERM invokes termination handlers for graceful shutdown, without cancelling the context,
+//   - SIGUSR1 and SIGUSR2 request a dump of profile buffers and let the subcommand continue,
+//   - any other signal is treated as a request for graceful shutdown.
+//
+// Flags of the subcommand are parsed into a new App with the configuration of c, such as storage
+// providers and the environment name prefix, and its output streams and hooks are private to the
+// invocation, so subcommands using the same App may run concurrently. Options are applied to that App
+// before flags are attached to kpapp.
+func (c *App) RunSubcommand(ctx context.Context, kpapp *kingpin.Application, stdin io.Reader, argsAndFlags []string, opts ...SubcommandOption) (stdout, stderr io.Reader, wait func() error, interrupt func(os.Signal)) {
 	stdoutReader, stdoutWriter := io.Pipe()
 	stderrReader, stderrWriter := io.Pipe()
 
-	c.stdinReader = stdin
-	c.stdoutWriter = stdoutWriter
-	c.stderrWriter = stderrWriter
-	c.rootctx = logging.WithLogger(ctx, logging.ToWriter(stderrWriter))
-	c.simulatedCtrlC = make(chan bool, 1)
-	c.isInProcessTest = true
+	ctx, cancel := context.WithCancel(ctx)
 
-	releasable.Created("simulated-ctrl-c", c.simulatedCtrlC)
+	// signal channels are captured by the interrupt function, so that it never affects
+	// other subcommands executed using the same App.
+	simulatedCtrlC := make(chan bool, 1)
+	simulatedSigDump := make(chan bool, 1)
 
-	c.Attach(kpapp)
+	releasable.Created("simulated-ctrl-c", simulatedCtrlC)
+	releasable.Created("simulated-sig-dump", simulatedSigDump)
 
-	var exitError error
+	var (
+		exitError error
 
-	resultErr := make(chan error, 1)
+		signalMu sync.Mutex
+		// +checklocks:signalMu
+		signalChannelsClosed bool
+	)
 
-	c.exitWithError = func(ec error) {
-		exitError = ec
-	}
+	resultErr := make(chan error, 1)
 
 	go func() {
 		defer func() {
-			close(c.simulatedCtrlC)
-			releasable.Released("simulated-ctrl-c", c.simulatedCtrlC)
+			signalMu.Lock()
+			defer signalMu.Unlock()
+
+			signalChannelsClosed = true
+
+			close(simulatedCtrlC)
+			releasable.Released("simulated-ctrl-c", simulatedCtrlC)
+
+			close(simulatedSigDump)
+			releasable.Released("simulated-sig-dump", simulatedSigDump)
 		}()
 
+		defer cancel()
 		defer close(resultErr)
 		defer stderrWriter.Close() //nolint:errcheck
 		defer stdoutWriter.Close() //nolint:errcheck
 
-		_, err := kpapp.Parse(argsAndFlags)
+		inv := &invocation{
+			stdin:            stdin,
+			stdout:           stdoutWriter,
+			stderr:           stderrWriter,
+			simulatedCtrlC:   simulatedCtrlC,
+			simulatedSigDump: simulatedSigDump,
+			exitWithError: func(ec error) {
+				exitError = ec
+			},
+		}
+
+		ic := c.forInvocation(inv)
+		inv.ctx = logging.WithLogger(ctx, inProcessLoggerFactory(ic, stderrWriter))
+
+		for _, o := range opts {
+			o(ic)
+		}
+
+		ic.Attach(kpapp)
+
+		if jw, ok := inv.jsonOutput.(io.Closer); ok {
+			defer jw.Close() //nolint:errcheck
+		}
+
+		_, err := kpapp.Parse(ic.injectEnvironmentFlags(kpapp, argsAndFlags))
 		if err != nil {
-			resultErr <- err
+			resultErr <- &ExitError{ExitCodeInvalidArgs, err}
 			return
 		}
 
 		if exitError != nil {
-			resultErr <- exitError
+			resultErr <- &ExitError{exitCodeForError(exitError), exitError}
 			return
 		}
 	}()
 
 	return stdoutReader, stderrReader, func() error {
 			return <-resultErr
-		}, func(_ os.Signal) {
-			// deliver simulated Ctrl-C to the app.
-			c.simulatedCtrlC <- true
+		}, func(sig os.Signal) {
+			signalMu.Lock()
+			defer signalMu.Unlock()
+
+			if signalChannelsClosed {
+				// subcommand has already finished.
+				return
+			}
+
+			switch {
+			case sig == os.Interrupt:
+				cancel()
+				requestSignal(simulatedCtrlC)
+
+			case sig == syscall.SIGTERM:
+				// deliver simulated graceful shutdown request to the app.
+				requestSignal(simulatedCtrlC)
+
+			case isProfileDumpSignal(sig):
+				// request profile dump, the subcommand continues running.
+				requestSignal(simulatedSigDump)
+
+			default:
+				requestSignal(simulatedCtrlC)
+			}
+		}
+}
+
+// inProcessLoggerFactory returns the logger factory writing to standard error of an in-process subcommand,
+// which emits JSON like the console logs of the kopia binary when JSON-lines progress is written there.
+func inProcessLoggerFactory(c *App, stderr io.Writer) logging.LoggerFactory {
+	text := logging.ToWriter(stderr)
+	json := logging.ToJSONWriter(stderr)
+
+	return func(module string) logging.Logger {
+		if c.JSONLProgressOnStderr() {
+			return json(module)
 		}
+
+		return text(module)
+	}
+}
+
+// requestSignal delivers a simulated signal without blocking if one is already pending.
+func requestSignal(ch chan bool) {
+	select {
+	case ch <- true:
+	default:
+	}
+}
+
+// RunSubcommandWithJSONOutput is like RunSubcommand but returns machine-readable output of commands
+// invoked with --json in a separate reader instead of standard output, so that it can be consumed
+// without parsing human-readable output. All returned readers must be consumed concurrently.
+func (c *App) RunSubcommandWithJSONOutput(ctx context.Context, kpapp *kingpin.Application, stdin io.Reader, argsAndFlags []string, opts ...SubcommandOption) (stdout, stderr, jsonOutput io.Reader, wait func() error, interrupt func(os.Signal)) {
+	jsonReader, jsonWriter := io.Pipe()
+
+	stdout, stderr, wait, interrupt = c.RunSubcommand(ctx, kpapp, stdin, argsAndFlags, append(opts, func(c *App) {
+		c.inv.jsonOutput = jsonWriter
+	})...)
+
+	return stdout, stderr, jsonReader, wait, interrupt
 }
@@ -0,0 +1,118 @@
+package cli
+
+import (
+	"os"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/alecthomas/kingpin/v2"
+)
+
+// SubcommandOption customizes execution of a subcommand by RunSubcommand.
+type SubcommandOption func(c *App)
+
+// WithEnvironment provides environment variables for the subcommand without modifying the process
+// environment. The names are not prefixed, values take precedence over the process environment
+// and are overridden by explicitly provided flags.
+func WithEnvironment(env map[string]string) SubcommandOption {
+	return func(c *App) {
+		c.inv.envOverrides = map[string]string{}
+
+		for k, v := range env {
+			c.inv.envOverrides[c.EnvName(k)] = v
+		}
+	}
+}
+
+// getEnv returns the value of the provided environment variable, after applying the name prefix
+// and per-invocation overrides.
+func (c *App) getEnv(n string) string {
+	return c.getPrefixedEnv(c.EnvName(n))
+}
+
+// getPrefixedEnv returns the value of the environment variable whose name already includes the prefix,
+// such as the name of environment variable associated with a flag.
+func (c *App) getPrefixedEnv(n string) string {
+	if v, ok := c.inv.envOverrides[n]; ok {
+		return v
+	}
+
+	return os.Getenv(n)
+}
+
+// injectEnvironmentFlags returns the arguments extended with flags whose environment variables
+// have been overridden using WithEnvironment(), unless such flags have been provided explicitly.
+func (c *App) injectEnvironmentFlags(kpapp *kingpin.Application, args []string) []string {
+	if len(c.inv.envOverrides) == 0 {
+		return args
+	}
+
+	pc, err := kpapp.ParseContext(args)
+	if err != nil {
+		// let the actual parse report the error.
+		return args
+	}
+
+	explicit := map[string]bool{}
+	flags := kpapp.Model().Flags
+
+	for _, el := range pc.Elements {
+		switch cl := el.Clause.(type) {
+		case *kingpin.FlagClause:
+			explicit[cl.Model().Name] = true
+		case *kingpin.CmdClause:
+			flags = append(flags, cl.Model().Flags...)
+		}
+	}
+
+	var injected []string
+
+	for _, f := range flags {
+		v := c.inv.envOverrides[f.Envar]
+		if f.Envar == "" || v == "" || explicit[f.Name] {
+			continue
+		}
+
+		injected = append(injected, envFlagArgs(f, v)...)
+	}
+
+	// flags must precede the '--' terminator, if present.
+	pos := slices.Index(args, "--")
+	if pos < 0 {
+		pos = len(args)
+	}
+
+	return slices.Concat(args[:pos], injected, args[pos:])
+}
+
+// envFlagArgs returns the command-line arguments equivalent to setting the flag's environment variable to the provided value.
+func envFlagArgs(f *kingpin.FlagModel, v string) []string {
+	if f.IsBoolFlag() {
+		b, err := strconv.ParseBool(v)
+		if err != nil {
+			return []string{"--" + f.Name + "=" + v}
+		}
+
+		if b {
+			return []string{"--" + f.Name}
+		}
+
+		return []string{"--no-" + f.Name}
+	}
+
+	if r, ok := f.Value.(interface{ IsCumulative() bool }); !ok || !r.IsCumulative() {
+		return []string{"--" + f.Name + "=" + v}
+	}
+
+	// cumulative flags accept multiple newline-separated values, matching kingpin.
+	var result []string
+
+	for _, line := range strings.Split(v, "\n") {
+		if line = strings.TrimSpace(line); line != "" {
+			result = append(result, "--"+f.Name+"="+line)
+		}
+	}
+
+	return result
+}
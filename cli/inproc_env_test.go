@@ -0,0 +1,65 @@
+package cli
+
+import (
+	"os"
+	"testing"
+
+	"github.com/alecthomas/kingpin/v2"
+	"github.com/stretchr/testify/require"
+)
+
+func TestInjectEnvironmentFlags(t *testing.T) {
+	c := NewApp()
+	c.SetEnvNamePrefixForTesting("TESTENV_")
+
+	var (
+		global, cmdValue, explicit string
+		enabled, disabled          bool
+		multi                      []string
+	)
+
+	kpapp := kingpin.New("test", "test")
+	kpapp.Flag("global", "").Envar(c.EnvName("GLOBAL")).StringVar(&global)
+
+	cmd := kpapp.Command("cmd", "")
+	cmd.Flag("value", "").Envar(c.EnvName("VALUE")).Required().StringVar(&cmdValue)
+	cmd.Flag("explicit", "").Envar(c.EnvName("EXPLICIT")).StringVar(&explicit)
+	cmd.Flag("enabled", "").Envar(c.EnvName("ENABLED")).BoolVar(&enabled)
+	cmd.Flag("disabled", "").Default("true").Envar(c.EnvName("DISABLED")).BoolVar(&disabled)
+	cmd.Flag("multi", "").Envar(c.EnvName("MULTI")).StringsVar(&multi)
+	cmd.Arg("args", "").Strings()
+
+	kpapp.Command("other", "").Flag("other", "").Envar(c.EnvName("OTHER")).String()
+
+	WithEnvironment(map[string]string{
+		"GLOBAL":   "g",
+		"VALUE":    "v",
+		"EXPLICIT": "from-env",
+		"ENABLED":  "true",
+		"DISABLED": "false",
+		"MULTI":    "a\nb",
+		"OTHER":    "not-applicable",
+	})(c)
+
+	args := c.injectEnvironmentFlags(kpapp, []string{"cmd", "--explicit=from-flag", "--", "--not-a-flag"})
+	require.Equal(t, []string{"cmd", "--explicit=from-flag"}, args[:2])
+	require.Equal(t, []string{"--", "--not-a-flag"}, args[len(args)-2:])
+	require.NotContains(t, args, "--other=not-applicable")
+
+	_, err := kpapp.Parse(args)
+	require.NoError(t, err)
+
+	require.Equal(t, "g", global)
+	require.Equal(t, "v", cmdValue)
+	require.Equal(t, "from-flag", explicit)
+	require.True(t, enabled)
+	require.False(t, disabled)
+	require.Equal(t, []string{"a", "b"}, multi)
+
+	// process environment is not modified.
+	_, ok := os.LookupEnv(c.EnvName("GLOBAL"))
+	require.False(t, ok)
+
+	require.Equal(t, "v", c.getEnv("VALUE"))
+	require.Equal(t, "", c.getEnv("NO_SUCH_VAR"))
+}
@@ -0,0 +1,27 @@
+package cli
+
+import (
+	"github.com/kopia/kopia/internal/metrics"
+	"github.com/kopia/kopia/repo"
+)
+
+// WithMetricsSnapshot causes the final state of repository metrics, such as number of uploaded bytes
+// and cache hits, to be stored in the provided snapshot after the subcommand closes the repository.
+// The snapshot is populated by the time wait() returns, it is left unchanged by subcommands that
+// do not open a repository.
+func WithMetricsSnapshot(dst *metrics.Snapshot) SubcommandOption {
+	return func(c *App) {
+		c.inv.metricsSnapshot = dst
+	}
+}
+
+// maybeCaptureMetricsSnapshot stores the snapshot of repository metrics if requested using WithMetricsSnapshot().
+func (c *App) maybeCaptureMetricsSnapshot(rep repo.Repository) {
+	if c.inv.metricsSnapshot == nil {
+		return
+	}
+
+	if mr, ok := rep.(interface{ Metrics() *metrics.Registry }); ok {
+		*c.inv.metricsSnapshot = mr.Metrics().Snapshot(false)
+	}
+}
@@ -0,0 +1,47 @@
+package cli_test
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/kopia/kopia/internal/metrics"
+	"github.com/kopia/kopia/internal/testutil"
+	"github.com/kopia/kopia/tests/testenv"
+)
+
+func TestRunSubcommandMetricsSnapshot(t *testing.T) {
+	runner := testenv.NewInProcRunner(t)
+	env := testenv.NewCLITest(t, testenv.RepoFormatNotImportant, runner)
+
+	env.RunAndExpectSuccess(t, "repo", "create", "filesystem", "--path", env.RepoDir)
+
+	dir := testutil.TempDirectory(t)
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "file1.txt"), bytes.Repeat([]byte{1, 2, 3, 4, 5}, 15000), 0o600))
+
+	var first metrics.Snapshot
+
+	runner.SetNextMetricsSnapshot(&first)
+	env.RunAndExpectSuccess(t, "snapshot", "create", dir)
+
+	require.Positive(t, first.Counters["blob_upload_bytes"])
+	require.Positive(t, first.Counters["content_uploaded_bytes"])
+
+	// second snapshot of the same data deduplicates file contents.
+	var second metrics.Snapshot
+
+	runner.SetNextMetricsSnapshot(&second)
+	env.RunAndExpectSuccess(t, "snapshot", "create", dir, "--force-hash=100")
+
+	require.Positive(t, second.Counters["content_deduplicated_bytes"])
+	require.Less(t, second.Counters["content_uploaded_bytes"], first.Counters["content_uploaded_bytes"])
+
+	// metrics are only collected for the next command.
+	endTime := second.EndTime
+
+	env.RunAndExpectSuccess(t, "snapshot", "create", dir)
+	require.Equal(t, endTime, second.EndTime)
+}
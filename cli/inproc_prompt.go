@@ -0,0 +1,13 @@
+package cli
+
+// PromptFunc answers an interactive prompt presented by a subcommand, such as a password prompt,
+// in place of the user typing the response at a terminal.
+type PromptFunc func(prompt string) (string, error)
+
+// WithPrompts runs the subcommand in interactive mode, where prompts are answered by the provided function
+// instead of being read from the terminal. Prompts are still written to the standard output.
+func WithPrompts(f PromptFunc) SubcommandOption {
+	return func(c *App) {
+		c.inv.promptFunc = f
+	}
+}
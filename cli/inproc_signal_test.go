@@ -0,0 +1,77 @@
+package cli
+
+import (
+	"os"
+	"path/filepath"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/kopia/kopia/internal/pproflogging"
+	"github.com/kopia/kopia/internal/testutil"
+)
+
+func TestRunSubcommandProfileDumpSignal(t *testing.T) {
+	c, wait, interrupt, reason := startSignalTestCommand(t)
+
+	interrupt(ProfileDumpSignal)
+
+	select {
+	case v := <-c.inv.simulatedSigDump:
+		require.True(t, v)
+	case <-time.After(5 * time.Second):
+		t.Fatal("profile dump not requested")
+	}
+
+	// the command continues running after the dump request.
+	select {
+	case r := <-reason:
+		t.Fatalf("unexpected command completion: %v", r)
+	default:
+	}
+
+	interrupt(syscall.SIGTERM)
+
+	require.Equal(t, "terminated", <-reason)
+	require.NoError(t, wait())
+}
+
+func TestRunSubcommandProfileDumpSignalWritesProfiles(t *testing.T) {
+	profileDir := testutil.TempDirectory(t)
+
+	_, wait, interrupt, reason := startSignalTestCommand(t, "--profile-buffers=heap", "--profile-dir", profileDir)
+
+	profiles := func() []string {
+		matches, err := filepath.Glob(filepath.Join(profileDir, "heap-*.pprof"))
+		require.NoError(t, err)
+
+		return matches
+	}
+
+	interrupt(ProfileDumpSignal)
+
+	require.Eventually(t, func() bool { return len(profiles()) == 1 }, 5*time.Second, 10*time.Millisecond)
+	require.NoError(t, os.Remove(profiles()[0]))
+
+	// real signal or event is handled the same way while profile buffers are active.
+	requestProcessProfileDump(t)
+	require.Eventually(t, func() bool { return len(profiles()) == 1 }, 5*time.Second, 10*time.Millisecond)
+
+	// the command continues running after the profiles have been dumped.
+	select {
+	case r := <-reason:
+		t.Fatalf("unexpected command completion: %v", r)
+	default:
+	}
+
+	interrupt(syscall.SIGTERM)
+
+	require.Equal(t, "terminated", <-reason)
+	require.NoError(t, wait())
+
+	// profile buffers are stopped and written again on exit.
+	require.NotEmpty(t, profiles())
+	require.Empty(t, pproflogging.ActiveProfiles())
+}
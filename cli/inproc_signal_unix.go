@@ -0,0 +1,31 @@
+//go:build !windows
+// +build !windows
+
+package cli
+
+import (
+	"os"
+	"os/signal"
+	"syscall"
+)
+
+// ProfileDumpSignal is the signal requesting a dump of profile buffers, which can be delivered
+// to the process or to an in-process subcommand.
+//
+//nolint:gochecknoglobals
+var ProfileDumpSignal os.Signal = syscall.SIGUSR1
+
+// isProfileDumpSignal returns true if the provided signal requests a dump of profile buffers.
+func isProfileDumpSignal(s os.Signal) bool {
+	return s == syscall.SIGUSR1 || s == syscall.SIGUSR2
+}
+
+// notifyProfileDumpSignals relays signals requesting a dump of profile buffers to the provided channel
+// until the returned function is called.
+func notifyProfileDumpSignals(ch chan<- os.Signal) (stop func()) {
+	signal.Notify(ch, syscall.SIGUSR1, syscall.SIGUSR2)
+
+	return func() {
+		signal.Stop(ch)
+	}
+}
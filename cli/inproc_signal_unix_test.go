@@ -0,0 +1,19 @@
+//go:build !windows
+// +build !windows
+
+package cli
+
+import (
+	"os"
+	"syscall"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+// requestProcessProfileDump requests the current process to dump profile buffers.
+func requestProcessProfileDump(t *testing.T) {
+	t.Helper()
+
+	require.NoError(t, syscall.Kill(os.Getpid(), syscall.SIGUSR1))
+}
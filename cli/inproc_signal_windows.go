@@ -0,0 +1,83 @@
+package cli
+
+import (
+	"fmt"
+	"os"
+
+	"golang.org/x/sys/windows"
+)
+
+// profileDumpSignal is a pseudo-signal requesting a dump of profile buffers, since SIGUSR1 and SIGUSR2
+// are not supported on Windows.
+type profileDumpSignal struct{}
+
+func (profileDumpSignal) String() string { return "profile dump request" }
+
+func (profileDumpSignal) Signal() {}
+
+// ProfileDumpSignal is the pseudo-signal requesting a dump of profile buffers, which can be delivered
+// to an in-process subcommand. Other processes request the dump by setting the event named
+// ProfileDumpEventName().
+//
+//nolint:gochecknoglobals
+var ProfileDumpSignal os.Signal = profileDumpSignal{}
+
+// ProfileDumpEventName returns the name of the Windows event, which when set requests the process
+// with the provided ID to dump profile buffers.
+func ProfileDumpEventName(pid int) string {
+	return fmt.Sprintf(`Local\kopia-profile-dump-%v`, pid)
+}
+
+// isProfileDumpSignal returns true if the provided signal requests a dump of profile buffers.
+func isProfileDumpSignal(s os.Signal) bool {
+	return s == ProfileDumpSignal
+}
+
+// notifyProfileDumpSignals relays requests to dump profile buffers delivered through the event
+// named ProfileDumpEventName() to the provided channel until the returned function is called.
+func notifyProfileDumpSignals(ch chan<- os.Signal) (stop func()) {
+	name, err := windows.UTF16PtrFromString(ProfileDumpEventName(os.Getpid()))
+	if err != nil {
+		return func() {}
+	}
+
+	// auto-reset event, which is set by processes requesting the dump.
+	dumpEvent, err := windows.CreateEvent(nil, 0, 0, name)
+	if err != nil {
+		return func() {}
+	}
+
+	// manual-reset event, which is set when relaying should stop.
+	stopEvent, err := windows.CreateEvent(nil, 1, 0, nil)
+	if err != nil {
+		windows.CloseHandle(dumpEvent) //nolint:errcheck
+
+		return func() {}
+	}
+
+	done := make(chan struct{})
+
+	go func() {
+		defer close(done)
+
+		for {
+			r, err := windows.WaitForMultipleObjects([]windows.Handle{dumpEvent, stopEvent}, false, windows.INFINITE)
+			if err != nil || r != windows.WAIT_OBJECT_0 {
+				return
+			}
+
+			select {
+			case ch <- ProfileDumpSignal:
+			default:
+			}
+		}
+	}()
+
+	return func() {
+		windows.SetEvent(stopEvent) //nolint:errcheck
+		<-done
+
+		windows.CloseHandle(dumpEvent) //nolint:errcheck
+		windows.CloseHandle(stopEvent) //nolint:errcheck
+	}
+}
@@ -0,0 +1,24 @@
+package cli
+
+import (
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"golang.org/x/sys/windows"
+)
+
+// requestProcessProfileDump requests the current process to dump profile buffers.
+func requestProcessProfileDump(t *testing.T) {
+	t.Helper()
+
+	name, err := windows.UTF16PtrFromString(ProfileDumpEventName(os.Getpid()))
+	require.NoError(t, err)
+
+	ev, err := windows.OpenEvent(windows.EVENT_MODIFY_STATE, false, name)
+	require.NoError(t, err)
+
+	defer windows.CloseHandle(ev) //nolint:errcheck
+
+	require.NoError(t, windows.SetEvent(ev))
+}
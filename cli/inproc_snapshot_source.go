@@ -0,0 +1,24 @@
+package cli
+
+import "github.com/kopia/kopia/fs"
+
+// SnapshotSourceWrapper replaces the filesystem entry of a local directory being snapshotted,
+// given its absolute path.
+type SnapshotSourceWrapper func(path string, e fs.Entry) fs.Entry
+
+// WithSnapshotSourceWrapper causes local directories snapshotted by the subcommand to be read through
+// the entries returned by the provided function, which allows tests to inject filesystem errors.
+func WithSnapshotSourceWrapper(f SnapshotSourceWrapper) SubcommandOption {
+	return func(c *App) {
+		c.inv.snapshotSourceWrapper = f
+	}
+}
+
+// wrapSnapshotSource returns the entry to snapshot for the local directory, as customized by WithSnapshotSourceWrapper().
+func (c *App) wrapSnapshotSource(path string, e fs.Entry) fs.Entry {
+	if c.inv.snapshotSourceWrapper == nil {
+		return e
+	}
+
+	return c.inv.snapshotSourceWrapper(path, e)
+}
@@ -0,0 +1,224 @@
+package cli
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"sync"
+	"syscall"
+	"testing"
+
+	"github.com/alecthomas/kingpin/v2"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/kopia/kopia/internal/testlogging"
+)
+
+// withTestCommand is a SubcommandOption registering a command on the App executing the subcommand,
+// which runs the provided action.
+func withTestCommand(kpapp *kingpin.Application, name string, act func(c *App, ctx context.Context) error) SubcommandOption {
+	return func(c *App) {
+		kpapp.Command(name, "").Action(c.baseActionWithContext(func(ctx context.Context) error {
+			return act(c, ctx)
+		}))
+	}
+}
+
+// startSignalTestCommand starts an in-process subcommand that blocks until it is either terminated
+// or its context is canceled and returns the reason. It returns once the subcommand has started,
+// along with the App executing it. Additional flags are passed to the subcommand.
+func startSignalTestCommand(t *testing.T, flags ...string) (app *App, wait func() error, interrupt func(os.Signal), reason <-chan string) {
+	t.Helper()
+
+	kpapp := kingpin.New("test", "test")
+	reasons := make(chan string, 1)
+	running := make(chan *App)
+
+	stdout, stderr, wait, interrupt := NewApp().RunSubcommand(testlogging.Context(t), kpapp, nil, append([]string{"wait-for-signal"}, flags...),
+		withTestCommand(kpapp, "wait-for-signal", func(c *App, ctx context.Context) error {
+			terminated := make(chan struct{})
+
+			c.onTerminate(func() { close(terminated) })
+			running <- c
+
+			select {
+			case <-ctx.Done():
+				reasons <- "canceled"
+			case <-terminated:
+				reasons <- "terminated"
+			}
+
+			return nil
+		}))
+
+	go io.Copy(io.Discard, stdout) //nolint:errcheck
+	go io.Copy(io.Discard, stderr) //nolint:errcheck
+
+	// subcommands start asynchronously, wait until this one is ready to receive signals.
+	return <-running, wait, interrupt, reasons
+}
+
+func TestRunSubcommandSignals(t *testing.T) {
+	cases := map[os.Signal]string{
+		os.Interrupt:    "canceled",
+		syscall.SIGTERM: "terminated",
+		os.Kill:         "terminated",
+	}
+
+	for sig, want := range cases {
+		t.Run(sig.String(), func(t *testing.T) {
+			_, wait, interrupt, reason := startSignalTestCommand(t)
+
+			interrupt(sig)
+
+			require.Equal(t, want, <-reason)
+			require.NoError(t, wait())
+		})
+	}
+}
+
+func TestRunSubcommandWithJSONOutput(t *testing.T) {
+	kpapp := kingpin.New("test", "test")
+
+	stdout, stderr, jsonOutput, wait, _ := NewApp().RunSubcommandWithJSONOutput(testlogging.Context(t), kpapp, nil, []string{"emit", "--json"},
+		func(c *App) {
+			var jo jsonOutput
+
+			cmd := kpapp.Command("emit", "")
+			jo.setup(c, cmd)
+			cmd.Action(c.baseActionWithContext(func(ctx context.Context) error {
+				fmt.Fprint(c.stdout(), "human-readable output\n") //nolint:errcheck
+				jo.printJSON(map[string]int{"value": 42})
+
+				return nil
+			}))
+		})
+
+	go io.Copy(io.Discard, stderr) //nolint:errcheck
+
+	stdoutData := make(chan []byte, 1)
+
+	go func() {
+		b, _ := io.ReadAll(stdout)
+		stdoutData <- b
+	}()
+
+	var got map[string]int
+
+	require.NoError(t, json.NewDecoder(jsonOutput).Decode(&got))
+	require.Equal(t, map[string]int{"value": 42}, got)
+
+	go io.Copy(io.Discard, jsonOutput) //nolint:errcheck
+
+	require.Equal(t, "human-readable output\n", string(<-stdoutData))
+	require.NoError(t, wait())
+}
+
+func TestRunSubcommandConcurrentApps(t *testing.T) {
+	const numApps = 10
+
+	var wg sync.WaitGroup
+
+	for i := range numApps {
+		wg.Add(1)
+
+		go func() {
+			defer wg.Done()
+
+			kpapp := kingpin.New("test", "test")
+			name := fmt.Sprintf("app-%v", i)
+
+			stdout, stderr, wait, _ := NewApp().RunSubcommand(testlogging.Context(t), kpapp, nil, []string{"hello"},
+				withTestCommand(kpapp, "hello", func(c *App, ctx context.Context) error {
+					for range 100 {
+						log(ctx).Info(name)
+						fmt.Fprintln(c.stdout(), name) //nolint:errcheck
+					}
+
+					return nil
+				}))
+
+			stderrData := make(chan []byte, 1)
+
+			go func() {
+				b, _ := io.ReadAll(stderr)
+				stderrData <- b
+			}()
+
+			stdoutData, _ := io.ReadAll(stdout)
+
+			assert.NoError(t, wait())
+			assert.Equal(t, strings.Repeat(name+"\n", 100), string(stdoutData))
+
+			for _, l := range strings.Split(strings.TrimSpace(string(<-stderrData)), "\n") {
+				assert.Contains(t, l, name)
+			}
+		}()
+	}
+
+	wg.Wait()
+}
+
+func TestRunSubcommandConcurrentlyUsingSameApp(t *testing.T) {
+	c := NewApp()
+
+	// starts a subcommand printing the value of --config-file, which is signaled on started
+	// and blocks until release is closed.
+	start := func(configFile string, started chan<- struct{}, release <-chan struct{}) (stdout io.Reader, wait func() error) {
+		kpapp := kingpin.New("test", "test")
+
+		stdout, stderr, wait, _ := c.RunSubcommand(testlogging.Context(t), kpapp, nil, []string{"print-config", "--config-file", configFile},
+			withTestCommand(kpapp, "print-config", func(ic *App, _ context.Context) error {
+				close(started)
+				<-release
+				fmt.Fprintln(ic.stdout(), ic.configPath) //nolint:errcheck
+
+				return nil
+			}))
+
+		go io.Copy(io.Discard, stderr) //nolint:errcheck
+
+		return stdout, wait
+	}
+
+	started1 := make(chan struct{})
+	release1 := make(chan struct{})
+	stdout1, wait1 := start("first.config", started1, release1)
+
+	<-started1
+
+	// the second subcommand completes while the first one is still running, which would deadlock
+	// if subcommands using the same App were executed one at a time.
+	released := make(chan struct{})
+	close(released)
+
+	stdout2, wait2 := start("second.config", make(chan struct{}), released)
+
+	out2, err := io.ReadAll(stdout2)
+	require.NoError(t, err)
+	require.NoError(t, wait2())
+	require.Equal(t, "second.config\n", string(out2))
+
+	close(release1)
+
+	out1, err := io.ReadAll(stdout1)
+	require.NoError(t, err)
+	require.NoError(t, wait1())
+	require.Equal(t, "first.config\n", string(out1))
+
+	// flags and output streams of the App itself are not modified.
+	require.Empty(t, c.configPath)
+	require.Equal(t, os.Stdin, c.stdin())
+}
+
+func TestRunSubcommandTimeout(t *testing.T) {
+	_, wait, _, reason := startSignalTestCommand(t, "--timeout=100ms")
+
+	// the command is interrupted by either cancellation or termination handler, whichever is first.
+	require.Contains(t, []string{"canceled", "terminated"}, <-reason)
+	require.Equal(t, ExitCodeTimeout, ExitCode(wait()))
+}
@@ -15,7 +15,7 @@ type jsonOutput struct {
 	jsonIndent  bool
 	jsonVerbose bool // output non-essential stats as part of JSON
 
-	out io.Writer
+	svc appServices
 }
 
 func (c *jsonOutput) setup(svc appServices, cmd *kingpin.CmdClause) {
@@ -23,7 +23,23 @@ func (c *jsonOutput) setup(svc appServices, cmd *kingpin.CmdClause) {
 	cmd.Flag("json-indent", "Output result in indented JSON format to stdout").Hidden().BoolVar(&c.jsonIndent)
 	cmd.Flag("json-verbose", "Output non-essential data (e.g. statistics) in JSON format").Hidden().BoolVar(&c.jsonVerbose)
 
-	c.out = svc.stdout()
+	c.svc = svc
+}
+
+// out returns the writer for JSON output, which is resolved on each use so that it reflects
+// the output streams of the current invocation.
+func (c *jsonOutput) out() io.Writer {
+	return c.svc.jsonStdout()
+}
+
+// printJSON writes the JSON representation of the provided value followed by a newline.
+func (c *jsonOutput) printJSON(v interface{}) {
+	c.printIndentedJSON(v, "")
+}
+
+// printIndentedJSON writes the JSON representation of the provided value using the provided indentation followed by a newline.
+func (c *jsonOutput) printIndentedJSON(v interface{}, indent string) {
+	fmt.Fprintf(c.out(), "%s\n", c.jsonIndentedBytes(v, indent)) //nolint:errcheck
 }
 
 func (c *jsonOutput) cleanupSnapshotManifestForJSON(v *snapshot.Manifest) interface{} {
@@ -96,7 +112,7 @@ func (l *jsonList) begin(o *jsonOutput) {
 	l.o = o
 
 	if o.jsonOutput {
-		fmt.Fprint(l.o.out, "[") //nolint:errcheck
+		fmt.Fprint(l.o.out(), "[") //nolint:errcheck
 
 		if !o.jsonIndent {
 			l.separator = "\n "
@@ -107,15 +123,15 @@ func (l *jsonList) begin(o *jsonOutput) {
 func (l *jsonList) end() {
 	if l.o.jsonOutput {
 		if !l.o.jsonIndent {
-			fmt.Fprint(l.o.out, "\n") //nolint:errcheck
+			fmt.Fprint(l.o.out(), "\n") //nolint:errcheck
 		}
 
-		fmt.Fprint(l.o.out, "]") //nolint:errcheck
+		fmt.Fprint(l.o.out(), "]") //nolint:errcheck
 	}
 }
 
 func (l *jsonList) emit(v interface{}) {
-	fmt.Fprintf(l.o.out, "%s%s", l.separator, l.o.jsonBytes(v)) //nolint:errcheck
+	fmt.Fprintf(l.o.out(), "%s%s", l.separator, l.o.jsonBytes(v)) //nolint:errcheck
 
 	if l.o.jsonIndent {
 		l.separator = ","
@@ -2,6 +2,7 @@
 
 import (
 	"context"
+	"net"
 	"net/http"
 	"net/http/pprof"
 	"os"
@@ -24,12 +25,15 @@
 	semconv "go.opentelemetry.io/otel/semconv/v1.4.0"
 
 	"github.com/kopia/kopia/internal/clock"
+	"github.com/kopia/kopia/internal/metrics"
 	"github.com/kopia/kopia/repo"
 )
 
 // DirMode is the directory mode for output directories.
 const DirMode = 0o700
 
+const metricsReadHeaderTimeout = 10 * time.Second
+
 //nolint:gochecknoglobals
 var metricsPushFormats = map[string]expfmt.Format{
 	"text":               expfmt.NewFormat(expfmt.TypeTextPlain),
@@ -56,14 +60,27 @@ type observabilityFlags struct {
 	enableJaeger bool
 	otlpTrace    bool
 
+	otlpMetrics         bool
+	otlpMetricsEndpoint string
+	otlpMetricsInsecure bool
+	otlpMetricsInterval time.Duration
+	otlpMetricsHeaders  []string
+
+	// options of OTLP metrics exporter for repositories opened by the command, nil if disabled.
+	otlpMetricsOptions *metrics.OTLPExporterOptions
+
 	stopPusher chan struct{}
 	pusherWG   sync.WaitGroup
 
 	traceProvider *trace.TracerProvider
+
+	metricsServer   *http.Server
+	metricsListener net.Listener
+	metricsServerWG sync.WaitGroup
 }
 
 func (c *observabilityFlags) setup(svc appServices, app *kingpin.Application) {
-	app.Flag("metrics-listen-addr", "Expose Prometheus metrics on a given host:port").Hidden().StringVar(&c.metricsListenAddr)
+	app.Flag("metrics-listen-addr", "Expose Prometheus metrics on a given host:port for the duration of the command").Envar(svc.EnvName("KOPIA_METRICS_LISTEN_ADDR")).Hidden().StringVar(&c.metricsListenAddr)
 	app.Flag("enable-pprof", "Expose pprof handlers").Hidden().BoolVar(&c.enablePProf)
 
 	// push gateway parameters
@@ -78,6 +95,13 @@ func (c *observabilityFlags) setup(svc appServices, app *kingpin.Application) {
 	app.Flag("enable-jaeger-collector", "(DEPRECATED) Emit OpenTelemetry traces to Jaeger collector").Hidden().Envar(svc.EnvName("KOPIA_ENABLE_JAEGER_COLLECTOR")).BoolVar(&c.enableJaeger)
 	app.Flag("otlp-trace", "Send OpenTelemetry traces to OTLP collector using gRPC").Hidden().Envar(svc.EnvName("KOPIA_ENABLE_OTLP_TRACE")).BoolVar(&c.otlpTrace)
 
+	// metrics (OTLP) parameters
+	app.Flag("otlp-metrics", "Push repository metrics to OTLP collector using gRPC").Hidden().Envar(svc.EnvName("KOPIA_ENABLE_OTLP_METRICS")).BoolVar(&c.otlpMetrics)
+	app.Flag("otlp-metrics-endpoint", "Address of OTLP collector").Hidden().Envar(svc.EnvName("KOPIA_OTLP_METRICS_ENDPOINT")).Default(metrics.DefaultOTLPEndpoint).StringVar(&c.otlpMetricsEndpoint)
+	app.Flag("otlp-metrics-insecure", "Connect to OTLP collector without TLS").Hidden().Envar(svc.EnvName("KOPIA_OTLP_METRICS_INSECURE")).BoolVar(&c.otlpMetricsInsecure)
+	app.Flag("otlp-metrics-interval", "Frequency of OTLP metrics push").Hidden().Envar(svc.EnvName("KOPIA_OTLP_METRICS_INTERVAL")).Default(metrics.DefaultOTLPExportInterval.String()).DurationVar(&c.otlpMetricsInterval)
+	app.Flag("otlp-metrics-header", "Header to send to OTLP collector (name=value)").Hidden().StringsVar(&c.otlpMetricsHeaders)
+
 	var formats []string
 
 	for k := range metricsPushFormats {
@@ -111,12 +135,18 @@ func (c *observabilityFlags) initialize(ctx *kingpin.ParseContext) error {
 }
 
 func (c *observabilityFlags) startMetrics(ctx context.Context) error {
-	c.maybeStartListener(ctx)
+	if err := c.maybeStartListener(ctx); err != nil {
+		return err
+	}
 
 	if err := c.maybeStartMetricsPusher(ctx); err != nil {
 		return err
 	}
 
+	if err := c.maybeSetupOTLPMetrics(); err != nil {
+		return err
+	}
+
 	if c.metricsOutputDir != "" {
 		c.metricsOutputDir = filepath.Clean(c.metricsOutputDir)
 
@@ -130,9 +160,10 @@ func (c *observabilityFlags) startMetrics(ctx context.Context) error {
 }
 
 // Starts observability listener when a listener address is specified.
-func (c *observabilityFlags) maybeStartListener(ctx context.Context) {
+// The listener serves repository and Go runtime metrics until stopMetrics() is called.
+func (c *observabilityFlags) maybeStartListener(ctx context.Context) error {
 	if c.metricsListenAddr == "" {
-		return
+		return nil
 	}
 
 	m := mux.NewRouter()
@@ -147,9 +178,30 @@ func (c *observabilityFlags) maybeStartListener(ctx context.Context) {
 		m.HandleFunc("/debug/pprof/{cmd}", pprof.Index) // special handling for Gorilla mux, see https://stackoverflow.com/questions/30560859/cant-use-go-tool-pprof-with-an-existing-server/71032595#71032595
 	}
 
-	log(ctx).Infof("starting prometheus metrics on %v", c.metricsListenAddr)
+	l, err := net.Listen("tcp", c.metricsListenAddr)
+	if err != nil {
+		return errors.Wrap(err, "unable to start metrics listener")
+	}
+
+	log(ctx).Infof("starting prometheus metrics on %v", l.Addr())
+
+	c.metricsListener = l
+	c.metricsServer = &http.Server{
+		Handler:           m,
+		ReadHeaderTimeout: metricsReadHeaderTimeout,
+	}
+
+	c.metricsServerWG.Add(1)
+
+	go func() {
+		defer c.metricsServerWG.Done()
+
+		if err := c.metricsServer.Serve(l); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			log(ctx).Warnf("metrics listener failed: %v", err)
+		}
+	}()
 
-	go http.ListenAndServe(c.metricsListenAddr, m) //nolint:errcheck,gosec
+	return nil
 }
 
 func (c *observabilityFlags) maybeStartMetricsPusher(ctx context.Context) error {
@@ -228,7 +280,44 @@ func (c *observabilityFlags) maybeStartTraceExporter(ctx context.Context) error
 	return nil
 }
 
+func (c *observabilityFlags) maybeSetupOTLPMetrics() error {
+	if !c.otlpMetrics {
+		return nil
+	}
+
+	headers := map[string]string{}
+
+	for _, h := range c.otlpMetricsHeaders {
+		name, value, ok := strings.Cut(h, "=")
+		if !ok {
+			return errors.Errorf("header must be name=value")
+		}
+
+		headers[name] = value
+	}
+
+	c.otlpMetricsOptions = &metrics.OTLPExporterOptions{
+		Endpoint: c.otlpMetricsEndpoint,
+		Insecure: c.otlpMetricsInsecure,
+		Interval: c.otlpMetricsInterval,
+		Headers:  headers,
+	}
+
+	return nil
+}
+
 func (c *observabilityFlags) stopMetrics(ctx context.Context) {
+	if c.metricsServer != nil {
+		if err := c.metricsServer.Close(); err != nil {
+			log(ctx).Warnf("unable to stop metrics listener: %v", err)
+		}
+
+		c.metricsServerWG.Wait()
+
+		c.metricsServer = nil
+		c.metricsListener = nil
+	}
+
 	if c.stopPusher != nil {
 		close(c.stopPusher)
 
@@ -0,0 +1,50 @@
+package cli
+
+import (
+	"io"
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/kopia/kopia/internal/metrics"
+	"github.com/kopia/kopia/internal/testlogging"
+)
+
+func TestMetricsListener(t *testing.T) {
+	ctx := testlogging.Context(t)
+
+	metrics.NewRegistry().CounterInt64("metrics_listener_test", "Test counter", nil).Add(1)
+
+	c := &observabilityFlags{
+		metricsListenAddr: "127.0.0.1:0",
+	}
+
+	require.NoError(t, c.startMetrics(ctx))
+
+	metricsURL := "http://" + c.metricsListener.Addr().String() + "/metrics"
+
+	resp, err := http.Get(metricsURL) //nolint:noctx
+	require.NoError(t, err)
+
+	body, err := io.ReadAll(resp.Body)
+	require.NoError(t, err)
+	require.NoError(t, resp.Body.Close())
+
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+	require.Contains(t, string(body), "go_goroutines")
+	require.Contains(t, string(body), "kopia_metrics_listener_test_total 1")
+
+	c.stopMetrics(ctx)
+
+	_, err = http.Get(metricsURL) //nolint:noctx,bodyclose
+	require.Error(t, err)
+}
+
+func TestMetricsListenerInvalidAddress(t *testing.T) {
+	c := &observabilityFlags{
+		metricsListenAddr: "no-such-host.invalid:-1",
+	}
+
+	require.Error(t, c.startMetrics(testlogging.Context(t)))
+}
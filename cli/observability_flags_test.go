@@ -1,14 +1,20 @@
 package cli_test
 
 import (
+	"context"
+	"encoding/json"
 	"io"
+	"net"
 	"net/http"
 	"net/http/httptest"
 	"os"
+	"strings"
 	"sync"
 	"testing"
 
 	"github.com/stretchr/testify/require"
+	collectorpb "go.opentelemetry.io/proto/otlp/collector/metrics/v1"
+	"google.golang.org/grpc"
 
 	"github.com/kopia/kopia/internal/testutil"
 	"github.com/kopia/kopia/tests/testenv"
@@ -88,6 +94,70 @@ func TestOTLPFlags(t *testing.T) {
 	env.RunAndExpectSuccess(t, "benchmark", "crypto", "--repeat=1", "--block-size=1KB", "--print-options", "--otlp-trace")
 }
 
+type fakeOTLPCollector struct {
+	collectorpb.UnimplementedMetricsServiceServer
+
+	mu       sync.Mutex
+	requests []*collectorpb.ExportMetricsServiceRequest
+}
+
+func (c *fakeOTLPCollector) Export(ctx context.Context, req *collectorpb.ExportMetricsServiceRequest) (*collectorpb.ExportMetricsServiceResponse, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.requests = append(c.requests, req)
+
+	return &collectorpb.ExportMetricsServiceResponse{}, nil
+}
+
+func TestOTLPMetricsFlags(t *testing.T) {
+	env := testenv.NewCLITest(t, testenv.RepoFormatNotImportant, testenv.NewInProcRunner(t))
+
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+
+	fc := &fakeOTLPCollector{}
+	srv := grpc.NewServer()
+	collectorpb.RegisterMetricsServiceServer(srv, fc)
+
+	go srv.Serve(l) //nolint:errcheck
+
+	defer srv.Stop()
+
+	tmp1 := testutil.TempDirectory(t)
+
+	env.RunAndExpectSuccess(t, "repo", "create", "filesystem", "--path", tmp1)
+
+	var status struct {
+		UniqueIDHex string `json:"uniqueIDHex"`
+	}
+
+	require.NoError(t, json.Unmarshal([]byte(strings.Join(env.RunAndExpectSuccess(t, "repo", "status", "--json"), "\n")), &status))
+
+	env.RunAndExpectSuccess(t, "repo", "status",
+		"--otlp-metrics",
+		"--otlp-metrics-endpoint="+l.Addr().String(),
+		"--otlp-metrics-insecure",
+		"--otlp-metrics-header=x-token=secret",
+	)
+
+	fc.mu.Lock()
+	require.Len(t, fc.requests, 1)
+
+	attrs := map[string]string{}
+	for _, kv := range fc.requests[0].GetResourceMetrics()[0].GetResource().GetAttributes() {
+		attrs[kv.GetKey()] = kv.GetValue().GetStringValue()
+	}
+	fc.mu.Unlock()
+
+	require.Equal(t, "kopia", attrs["service.name"])
+	require.Equal(t, status.UniqueIDHex, attrs["kopia.repository.id"])
+	require.NotEmpty(t, attrs["host.name"])
+	require.NotEmpty(t, attrs["kopia.user"])
+
+	env.RunAndExpectFailure(t, "repo", "status", "--otlp-metrics", "--otlp-metrics-header=no-value")
+}
+
 func TestMetricsSaveToOutputDirFlags(t *testing.T) {
 	env := testenv.NewCLITest(t, testenv.RepoFormatNotImportant, testenv.NewInProcRunner(t))
 
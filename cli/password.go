@@ -3,8 +3,9 @@
 import (
 	"context"
 	"fmt"
-	"io"
 	"os"
+	"os/exec"
+	"runtime"
 	"strings"
 
 	"github.com/pkg/errors"
@@ -13,53 +14,53 @@
 	"github.com/kopia/kopia/internal/passwordpersist"
 )
 
-func askForNewRepositoryPassword(out io.Writer) (string, error) {
+func (c *App) askForNewRepositoryPassword() (string, error) {
 	for {
-		p1, err := askPass(out, "Enter password to create new repository: ")
+		p1, err := c.askPass("Enter password to create new repository: ")
 		if err != nil {
 			return "", errors.Wrap(err, "password entry")
 		}
 
-		p2, err := askPass(out, "Re-enter password for verification: ")
+		p2, err := c.askPass("Re-enter password for verification: ")
 		if err != nil {
 			return "", errors.Wrap(err, "password verification")
 		}
 
 		if p1 != p2 {
-			fmt.Fprintln(out, "Passwords don't match!") //nolint:errcheck
+			fmt.Fprintln(c.inv.stdout, "Passwords don't match!") //nolint:errcheck
 		} else {
 			return p1, nil
 		}
 	}
 }
 
-func askForChangedRepositoryPassword(out io.Writer) (string, error) {
+func (c *App) askForChangedRepositoryPassword() (string, error) {
 	for {
-		p1, err := askPass(out, "Enter new password: ")
+		p1, err := c.askPass("Enter new password: ")
 		if err != nil {
 			return "", errors.Wrap(err, "password entry")
 		}
 
-		p2, err := askPass(out, "Re-enter password for verification: ")
+		p2, err := c.askPass("Re-enter password for verification: ")
 		if err != nil {
 			return "", errors.Wrap(err, "password verification")
 		}
 
 		if p1 != p2 {
-			fmt.Println("Passwords don't match!")
+			fmt.Fprintln(c.inv.stdout, "Passwords don't match!") //nolint:errcheck
 		} else {
 			return p1, nil
 		}
 	}
 }
 
-func askForExistingRepositoryPassword(out io.Writer) (string, error) {
-	p1, err := askPass(out, "Enter password to open repository: ")
+func (c *App) askForExistingRepositoryPassword() (string, error) {
+	p1, err := c.askPass("Enter password to open repository: ")
 	if err != nil {
 		return "", err
 	}
 
-	fmt.Fprintln(out) //nolint:errcheck
+	fmt.Fprintln(c.inv.stdout) //nolint:errcheck
 
 	return p1, nil
 }
@@ -68,14 +69,25 @@ func (c *App) setPasswordFromToken(pwd string) {
 	c.password = pwd
 }
 
+// errNoPasswordTerminal is returned when a password is needed, but it was not provided and can't be prompted for.
+var errNoPasswordTerminal = errors.New("repository password was not provided and standard input is not a terminal, use --password, --password-file or --password-command")
+
 func (c *App) getPasswordFromFlags(ctx context.Context, isCreate, allowPersistent bool) (string, error) {
 	switch {
 	case c.password != "":
 		// password provided via --password flag or KOPIA_PASSWORD environment variable
 		return strings.TrimSpace(c.password), nil
+	case c.passwordFile != "":
+		return readPasswordFile(c.passwordFile)
+	case c.passwordCommand != "":
+		return c.runPasswordCommand(ctx, c.passwordCommand)
 	case isCreate:
+		if !c.canPrompt() {
+			return "", errNoPasswordTerminal
+		}
+
 		// this is a new repository, ask for password
-		return askForNewRepositoryPassword(c.stdoutWriter)
+		return c.askForNewRepositoryPassword()
 	case allowPersistent:
 		// try fetching the password from persistent storage specific to the configuration file.
 		pass, err := c.passwordPersistenceStrategy().GetPassword(ctx, c.repositoryConfigFileName())
@@ -88,21 +100,78 @@ func (c *App) getPasswordFromFlags(ctx context.Context, isCreate, allowPersisten
 		}
 	}
 
+	if !c.canPrompt() {
+		// fail fast instead of waiting for input that will never arrive.
+		return "", errNoPasswordTerminal
+	}
+
 	// fall back to asking for existing password
-	return askForExistingRepositoryPassword(c.stdoutWriter)
+	return c.askForExistingRepositoryPassword()
+}
+
+// canPrompt returns true if the user can be prompted, either because the standard input is connected
+// to a terminal or because prompts are answered by the PromptFunc provided using WithPrompts().
+func (c *App) canPrompt() bool {
+	if c.inv.promptFunc != nil {
+		return true
+	}
+
+	f, ok := c.inv.stdin.(*os.File)
+
+	return ok && term.IsTerminal(int(f.Fd()))
+}
+
+// readPasswordFile returns the password stored in the provided file, ignoring surrounding whitespace.
+func readPasswordFile(fname string) (string, error) {
+	b, err := os.ReadFile(fname) //nolint:gosec
+	if err != nil {
+		return "", errors.Wrap(err, "unable to read password file")
+	}
+
+	pass := strings.TrimSpace(string(b))
+	if pass == "" {
+		return "", errors.Errorf("password file %v is empty", fname)
+	}
+
+	return pass, nil
+}
+
+// runPasswordCommand runs the provided command using the shell and returns its output as the password.
+func (c *App) runPasswordCommand(ctx context.Context, command string) (string, error) {
+	var cmd *exec.Cmd
+
+	if runtime.GOOS == "windows" {
+		cmd = exec.CommandContext(ctx, os.Getenv("COMSPEC"), "/c", command) //nolint:gosec
+	} else {
+		cmd = exec.CommandContext(ctx, "sh", "-c", command) //nolint:gosec
+	}
+
+	cmd.Stderr = c.inv.stderr
+
+	out, err := cmd.Output()
+	if err != nil {
+		return "", errors.Wrap(err, "error running password command")
+	}
+
+	pass := strings.TrimSpace(string(out))
+	if pass == "" {
+		return "", errors.New("password command returned empty password")
+	}
+
+	return pass, nil
 }
 
 // askPass presents a given prompt and asks the user for password.
-func askPass(out io.Writer, prompt string) (string, error) {
+func (c *App) askPass(prompt string) (string, error) {
 	for range 5 {
-		fmt.Fprint(out, prompt) //nolint:errcheck
+		fmt.Fprint(c.inv.stdout, prompt) //nolint:errcheck
 
-		passBytes, err := term.ReadPassword(int(os.Stdin.Fd()))
+		passBytes, err := c.readPassword(prompt)
 		if err != nil {
 			return "", errors.Wrap(err, "password prompt error")
 		}
 
-		fmt.Fprintln(out) //nolint:errcheck
+		fmt.Fprintln(c.inv.stdout) //nolint:errcheck
 
 		if len(passBytes) == 0 {
 			continue
@@ -113,3 +182,20 @@ func askPass(out io.Writer, prompt string) (string, error) {
 
 	return "", errors.New("can't get password")
 }
+
+// readPassword reads the password without echo from the terminal or using the PromptFunc provided using WithPrompts().
+func (c *App) readPassword(prompt string) ([]byte, error) {
+	if c.inv.promptFunc != nil {
+		s, err := c.inv.promptFunc(prompt)
+
+		return []byte(s), err
+	}
+
+	f, ok := c.inv.stdin.(*os.File)
+	if !ok {
+		f = os.Stdin
+	}
+
+	//nolint:wrapcheck
+	return term.ReadPassword(int(f.Fd()))
+}
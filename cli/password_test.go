@@ -0,0 +1,133 @@
+package cli_test
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/kopia/kopia/cli"
+	"github.com/kopia/kopia/internal/testutil"
+	"github.com/kopia/kopia/tests/testenv"
+)
+
+func TestPasswordFile(t *testing.T) {
+	t.Parallel()
+
+	env := testenv.NewCLITest(t, testenv.RepoFormatNotImportant, testenv.NewInProcRunner(t))
+	delete(env.Environment, "KOPIA_PASSWORD")
+
+	passwordFile := filepath.Join(testutil.TempDirectory(t), "password.txt")
+	require.NoError(t, os.WriteFile(passwordFile, []byte("file-password\n"), 0o600))
+
+	env.RunAndExpectSuccess(t, "repo", "create", "filesystem", "--path", env.RepoDir, "--password-file", passwordFile, "--no-persist-credentials")
+	env.RunAndExpectSuccess(t, "repo", "disconnect")
+
+	// wrong password
+	env.RunAndExpectFailure(t, "repo", "connect", "filesystem", "--path", env.RepoDir, "--password", "wrong-password")
+
+	env.Environment["KOPIA_PASSWORD_FILE"] = passwordFile
+	env.RunAndExpectSuccess(t, "repo", "connect", "filesystem", "--path", env.RepoDir, "--no-persist-credentials")
+	env.RunAndExpectSuccess(t, "snapshot", "ls")
+
+	// empty password file
+	require.NoError(t, os.WriteFile(passwordFile, []byte("\n"), 0o600))
+
+	_, stderr := env.RunAndExpectFailure(t, "snapshot", "ls")
+	require.Contains(t, stderr[len(stderr)-1], "is empty")
+}
+
+func TestPasswordCommand(t *testing.T) {
+	t.Parallel()
+
+	if runtime.GOOS == "windows" {
+		t.Skip("test uses unix shell")
+	}
+
+	env := testenv.NewCLITest(t, testenv.RepoFormatNotImportant, testenv.NewInProcRunner(t))
+	delete(env.Environment, "KOPIA_PASSWORD")
+
+	env.RunAndExpectSuccess(t, "repo", "create", "filesystem", "--path", env.RepoDir, "--password-command", "echo command-password", "--no-persist-credentials")
+	env.RunAndExpectSuccess(t, "repo", "disconnect")
+	env.RunAndExpectSuccess(t, "repo", "connect", "filesystem", "--path", env.RepoDir, "--password-command", "echo command-password", "--no-persist-credentials")
+	env.RunAndExpectSuccess(t, "snapshot", "ls", "--password-command", "echo command-password")
+
+	_, stderr := env.RunAndExpectFailure(t, "snapshot", "ls", "--password-command", "exit 1")
+	require.Contains(t, stderr[len(stderr)-1], "error running password command")
+}
+
+func TestPasswordNoTerminal(t *testing.T) {
+	t.Parallel()
+
+	env := testenv.NewCLITest(t, testenv.RepoFormatNotImportant, testenv.NewInProcRunner(t))
+	delete(env.Environment, "KOPIA_PASSWORD")
+
+	_, stderr := env.RunAndExpectFailure(t, "repo", "create", "filesystem", "--path", env.RepoDir)
+	require.Contains(t, stderr[len(stderr)-1], "standard input is not a terminal")
+}
+
+func TestPasswordInteractivePrompts(t *testing.T) {
+	t.Parallel()
+
+	runner := testenv.NewInProcRunner(t)
+	env := testenv.NewCLITest(t, testenv.RepoFormatNotImportant, runner)
+	delete(env.Environment, "KOPIA_PASSWORD")
+
+	var prompts []string
+
+	// responds with the provided answers in order.
+	respond := func(answers ...string) cli.PromptFunc {
+		prompts = nil
+
+		return func(prompt string) (string, error) {
+			prompts = append(prompts, prompt)
+
+			if len(answers) == 0 {
+				return "", errors.New("unexpected prompt")
+			}
+
+			a := answers[0]
+			answers = answers[1:]
+
+			return a, nil
+		}
+	}
+
+	// mismatched passwords are asked again.
+	runner.SetNextPrompts(respond("first-password", "mismatch", "first-password", "first-password"))
+	stdout := env.RunAndExpectSuccess(t, "repo", "create", "filesystem", "--path", env.RepoDir, "--no-persist-credentials")
+	require.Equal(t, []string{
+		"Enter password to create new repository: ",
+		"Re-enter password for verification: ",
+		"Enter password to create new repository: ",
+		"Re-enter password for verification: ",
+	}, prompts)
+	require.Contains(t, stdout, "Passwords don't match!")
+
+	runner.SetNextPrompts(respond("first-password"))
+	env.RunAndExpectSuccess(t, "snapshot", "ls")
+	require.Equal(t, []string{"Enter password to open repository: "}, prompts)
+
+	runner.SetNextPrompts(respond("first-password", "second-password", "second-password"))
+	env.RunAndExpectSuccess(t, "repo", "change-password")
+	require.Equal(t, []string{
+		"Enter password to open repository: ",
+		"Enter new password: ",
+		"Re-enter password for verification: ",
+	}, prompts)
+
+	// changed password is persisted, reconnect to be prompted again.
+	env.RunAndExpectSuccess(t, "repo", "disconnect")
+
+	runner.SetNextPrompts(respond("first-password"))
+	env.RunAndExpectFailure(t, "repo", "connect", "filesystem", "--path", env.RepoDir, "--no-persist-credentials")
+
+	// prompts are not carried over to the next command.
+	env.RunAndExpectFailure(t, "repo", "connect", "filesystem", "--path", env.RepoDir, "--no-persist-credentials")
+
+	runner.SetNextPrompts(respond("second-password"))
+	env.RunAndExpectSuccess(t, "repo", "connect", "filesystem", "--path", env.RepoDir, "--no-persist-credentials")
+}
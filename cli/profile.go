@@ -1,8 +1,12 @@
 package cli
 
 import (
+	"context"
+
 	"github.com/alecthomas/kingpin/v2"
 	"github.com/pkg/profile"
+
+	"github.com/kopia/kopia/internal/pproflogging"
 )
 
 type profileFlags struct {
@@ -11,18 +15,35 @@ type profileFlags struct {
 	profileMemory   int
 	profileBlocking bool
 	profileMutex    bool
+
+	profileBuffersConfig string
 }
 
-func (c *profileFlags) setup(app *kingpin.Application) {
-	app.Flag("profile-dir", "Write profile to the specified directory").Hidden().StringVar(&c.profileDir)
+func (c *profileFlags) setup(svc appServices, app *kingpin.Application) {
+	app.Flag("profile-dir", "Write profiles to the specified directory").Hidden().StringVar(&c.profileDir)
 	app.Flag("profile-cpu", "Enable CPU profiling").Hidden().BoolVar(&c.profileCPU)
 	app.Flag("profile-memory", "Enable memory profiling").Hidden().IntVar(&c.profileMemory)
 	app.Flag("profile-blocking", "Enable block profiling").Hidden().BoolVar(&c.profileBlocking)
 	app.Flag("profile-mutex", "Enable mutex profiling").Hidden().BoolVar(&c.profileMutex)
+	app.Flag("profile-buffers", "Capture profiles in memory and write them on exit (see "+pproflogging.EnvVarKopiaDebugPprof+")").Hidden().Envar(svc.EnvName(pproflogging.EnvVarKopiaDebugPprof)).StringVar(&c.profileBuffersConfig)
 }
 
 // withProfiling runs the given callback with profiling enabled, configured according to command line flags.
-func (c *profileFlags) withProfiling(callback func() error) error {
+// While profile buffers are active, their contents are written out whenever onDumpRequest delivers a request.
+func (c *profileFlags) withProfiling(ctx context.Context, onDumpRequest func(f func()) (stop func()), callback func() error) error {
+	if c.profileBuffersConfig != "" {
+		if err := pproflogging.StartProfileBuffersWithConfig(ctx, c.profileBuffersConfig); err != nil {
+			log(ctx).Warnf("unable to start profile buffers: %v", err)
+		} else {
+			// profiles are written as files to the profile directory, if provided, or dumped to the log.
+			defer pproflogging.MaybeStopProfileBuffers(ctx, c.profileDir)
+
+			defer onDumpRequest(func() {
+				pproflogging.MaybeDumpProfileBuffers(ctx, c.profileDir)
+			})()
+		}
+	}
+
 	if c.profileDir != "" {
 		pp := profile.ProfilePath(c.profileDir)
 		if c.profileMemory > 0 {
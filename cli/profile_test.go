@@ -0,0 +1,45 @@
+package cli_test
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/kopia/kopia/internal/testutil"
+	"github.com/kopia/kopia/tests/testenv"
+)
+
+func TestProfileBuffersWrittenToProfileDir(t *testing.T) {
+	env := testenv.NewCLITest(t, testenv.RepoFormatNotImportant, testenv.NewInProcRunner(t))
+	profileDir := testutil.TempDirectory(t)
+
+	env.RunAndExpectSuccess(t, "benchmark", "hashing", "--repeat=1", "--block-size=1KB", "--profile-buffers=heap:mutex", "--profile-dir", profileDir)
+
+	for _, name := range []string{"heap", "mutex"} {
+		matches, err := filepath.Glob(filepath.Join(profileDir, name+"-*.pprof"))
+		require.NoError(t, err)
+		require.Len(t, matches, 1, name)
+	}
+
+	// profile buffers are stopped on exit.
+	require.Equal(t, []string{"Profiling is not active."}, env.RunAndExpectSuccess(t, "debug", "pprof", "status", "--in-process"))
+}
+
+func TestInProcRunnerProfileCapture(t *testing.T) {
+	profilesDir := testutil.TempDirectory(t)
+	t.Setenv("KOPIA_PROFILES_DIR", profilesDir)
+
+	env := testenv.NewCLITest(t, testenv.RepoFormatNotImportant, testenv.NewInProcRunner(t, testenv.WithProfileCapture("heap")))
+
+	env.RunAndExpectSuccess(t, "repo", "create", "filesystem", "--path", env.RepoDir)
+	env.RunAndExpectSuccess(t, "snapshot", "list")
+	env.RunAndExpectSuccess(t, "repo", "status")
+
+	// profiles of each command are written into a directory named after the test.
+	for _, pattern := range []string{"002-snapshot-list/heap-*.pprof", "003-repo-status/heap-*.pprof"} {
+		matches, err := filepath.Glob(filepath.Join(profilesDir, "TestInProcRunnerProfileCapture.*", pattern))
+		require.NoError(t, err)
+		require.Len(t, matches, 1, pattern)
+	}
+}
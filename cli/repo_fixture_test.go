@@ -0,0 +1,41 @@
+package cli_test
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/kopia/kopia/cli"
+	"github.com/kopia/kopia/internal/testutil"
+	"github.com/kopia/kopia/tests/testenv"
+)
+
+func TestRepositoryFixture(t *testing.T) {
+	t.Parallel()
+
+	e1 := testenv.NewCLITest(t, testenv.RepoFormatNotImportant, testenv.NewInProcRunner(t))
+	e2 := testenv.NewCLITest(t, testenv.RepoFormatNotImportant, testenv.NewInProcRunner(t))
+
+	source1 := e1.UseRepositoryFixture(t, testenv.StandardRepositoryFixture)
+	source2 := e2.UseRepositoryFixture(t, testenv.StandardRepositoryFixture)
+	require.Equal(t, source1, source2)
+
+	require.Len(t, listSnapshots(t, e1, source1), 1)
+
+	e1.RunAndExpectSuccess(t, "snapshot", "verify")
+
+	// each test gets a private copy of the repository.
+	e1.RunAndExpectSuccess(t, "snapshot", "create", source1)
+	require.Len(t, listSnapshots(t, e1, source1), 2)
+	require.Len(t, listSnapshots(t, e2, source2), 1)
+}
+
+func listSnapshots(t *testing.T, e *testenv.CLITest, source string) []cli.SnapshotManifest {
+	t.Helper()
+
+	var snapshots []cli.SnapshotManifest
+
+	testutil.MustParseJSONLines(t, e.RunAndExpectSuccess(t, "snapshot", "list", "--json", source), &snapshots)
+
+	return snapshots
+}
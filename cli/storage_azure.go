@@ -26,7 +26,9 @@ func (c *storageAzureFlags) Setup(svc StorageProviderServices, cmd *kingpin.CmdC
 	cmd.Flag("client-id", "Azure service principle client ID (overrides AZURE_CLIENT_ID environment variable)").Envar(svc.EnvName("AZURE_CLIENT_ID")).StringVar(&c.azOptions.ClientID)
 	cmd.Flag("client-secret", "Azure service principle client secret (overrides AZURE_CLIENT_SECRET environment variable)").Envar(svc.EnvName("AZURE_CLIENT_SECRET")).StringVar(&c.azOptions.ClientSecret)
 
+	commonStorageClassFlags(cmd, &c.azOptions.StorageClasses)
 	commonThrottlingFlags(cmd, &c.azOptions.Limits)
+	commonRetryFlags(cmd, &c.azOptions.Policy)
 
 	var pointInTimeStr string
 
@@ -53,6 +55,8 @@ func (c *storageAzureFlags) Connect(ctx context.Context, isCreate bool, formatVe
 		return nil, errors.New("Cannot specify a 'point-in-time' option when creating a repository")
 	}
 
+	warnArchiveStorageClasses(ctx, c.azOptions.StorageClasses)
+
 	//nolint:wrapcheck
 	return azure.New(ctx, &c.azOptions, isCreate)
 }
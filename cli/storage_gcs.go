@@ -25,7 +25,9 @@ func (c *storageGCSFlags) Setup(_ StorageProviderServices, cmd *kingpin.CmdClaus
 	cmd.Flag("credentials-file", "Use the provided JSON file with credentials").ExistingFileVar(&c.options.ServiceAccountCredentialsFile)
 	cmd.Flag("embed-credentials", "Embed GCS credentials JSON in Kopia configuration").BoolVar(&c.embedCredentials)
 
+	commonStorageClassFlags(cmd, &c.options.StorageClasses)
 	commonThrottlingFlags(cmd, &c.options.Limits)
+	commonRetryFlags(cmd, &c.options.Policy)
 }
 
 func (c *storageGCSFlags) Connect(ctx context.Context, isCreate bool, formatVersion int) (blob.Storage, error) {
@@ -41,6 +43,8 @@ func (c *storageGCSFlags) Connect(ctx context.Context, isCreate bool, formatVers
 		c.options.ServiceAccountCredentialsFile = ""
 	}
 
+	warnArchiveStorageClasses(ctx, c.options.StorageClasses)
+
 	//nolint:wrapcheck
 	return gcs.New(ctx, &c.options, isCreate)
 }
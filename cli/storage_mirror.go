@@ -0,0 +1,65 @@
+package cli
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+
+	"github.com/alecthomas/kingpin/v2"
+	"github.com/pkg/errors"
+
+	"github.com/kopia/kopia/internal/ospath"
+	"github.com/kopia/kopia/repo/blob"
+	"github.com/kopia/kopia/repo/blob/mirror"
+)
+
+type storageMirrorFlags struct {
+	options mirror.Options
+
+	primaryConfigFile   string
+	secondaryConfigFile string
+}
+
+func (c *storageMirrorFlags) Setup(_ StorageProviderServices, cmd *kingpin.CmdClause) {
+	cmd.Flag("primary-config", "Path to the JSON file with connection info of the primary storage").Required().ExistingFileVar(&c.primaryConfigFile)
+	cmd.Flag("secondary-config", "Path to the JSON file with connection info of the secondary storage").Required().ExistingFileVar(&c.secondaryConfigFile)
+	cmd.Flag("async", "Replicate writes to the secondary storage in background").BoolVar(&c.options.Async)
+	cmd.Flag("journal-path", "Path to the journal of writes pending asynchronous replication").StringVar(&c.options.JournalPath)
+	cmd.Flag("replication-parallelism", "Number of blobs replicated in parallel in background").IntVar(&c.options.Concurrency)
+	cmd.Flag("replication-queue-size", "Number of writes queued for replication by each worker").IntVar(&c.options.QueueSize)
+	cmd.Flag("replication-enqueue-timeout", "How long writes wait for a full replication queue before leaving replication to the next session").DurationVar(&c.options.EnqueueTimeout)
+}
+
+func (c *storageMirrorFlags) Connect(ctx context.Context, isCreate bool, formatVersion int) (blob.Storage, error) {
+	_ = formatVersion
+
+	opt := c.options
+
+	if err := readConnectionInfoFile(c.primaryConfigFile, &opt.Primary); err != nil {
+		return nil, errors.Wrap(err, "invalid primary storage config")
+	}
+
+	if err := readConnectionInfoFile(c.secondaryConfigFile, &opt.Secondary); err != nil {
+		return nil, errors.Wrap(err, "invalid secondary storage config")
+	}
+
+	if opt.JournalPath != "" {
+		opt.JournalPath = ospath.ResolveUserFriendlyPath(opt.JournalPath, false)
+
+		if !ospath.IsAbs(opt.JournalPath) {
+			return nil, errors.Errorf("journal path must be absolute")
+		}
+	}
+
+	//nolint:wrapcheck
+	return mirror.New(ctx, &opt, isCreate)
+}
+
+func readConnectionInfoFile(fname string, ci *blob.ConnectionInfo) error {
+	b, err := os.ReadFile(fname) //nolint:gosec
+	if err != nil {
+		return errors.Wrap(err, "unable to read file")
+	}
+
+	return errors.Wrap(json.Unmarshal(b, ci), "unable to parse connection info")
+}
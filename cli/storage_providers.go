@@ -3,10 +3,13 @@
 import (
 	"context"
 	"io"
+	"strings"
 
 	"github.com/alecthomas/kingpin/v2"
+	"github.com/pkg/errors"
 
 	"github.com/kopia/kopia/repo/blob"
+	"github.com/kopia/kopia/repo/blob/retrypolicy"
 	"github.com/kopia/kopia/repo/blob/throttling"
 )
 
@@ -17,6 +20,7 @@ type StorageProviderServices interface {
 	setPasswordFromToken(pwd string)
 	storageProviders() []StorageProvider
 	stdin() io.Reader
+	askPass(prompt string) (string, error)
 }
 
 // StorageFlags is implemented by cli storage providers which need to support a
@@ -40,6 +44,57 @@ func commonThrottlingFlags(cmd *kingpin.CmdClause, limits *throttling.Limits) {
 	cmd.Flag("max-upload-speed", "Limit the upload speed.").PlaceHolder("BYTES_PER_SEC").FloatVar(&limits.UploadBytesPerSecond)
 }
 
+// prefixStorageClassesValue is a repeatable flag value which parses storage classes specified as PREFIX=CLASS.
+type prefixStorageClassesValue struct {
+	target *[]blob.PrefixStorageClass
+}
+
+func (v prefixStorageClassesValue) Set(s string) error {
+	p, err := blob.ParsePrefixStorageClass(s)
+	if err != nil {
+		return errors.Wrap(err, "invalid storage class")
+	}
+
+	*v.target = append(*v.target, p)
+
+	return nil
+}
+
+func (v prefixStorageClassesValue) String() string {
+	var parts []string
+
+	for _, p := range *v.target {
+		parts = append(parts, p.String())
+	}
+
+	return strings.Join(parts, ",")
+}
+
+func (v prefixStorageClassesValue) IsCumulative() bool {
+	return true
+}
+
+func commonStorageClassFlags(cmd *kingpin.CmdClause, classes *[]blob.PrefixStorageClass) {
+	cmd.Flag("storage-class", "Storage class of blobs with the given prefix, e.g. 'p=STANDARD_IA' (can be repeated).").PlaceHolder("PREFIX=CLASS").SetValue(prefixStorageClassesValue{classes})
+}
+
+// warnArchiveStorageClasses warns about storage classes of archive tiers, since kopia needs to read blobs
+// at any time, for example during maintenance, and reads of archived blobs fail until they are restored.
+func warnArchiveStorageClasses(ctx context.Context, classes []blob.PrefixStorageClass) {
+	for _, c := range classes {
+		if blob.IsArchiveStorageClass(c.StorageClass) {
+			log(ctx).Warnf("Storage class %v of blobs with prefix %q is an archive tier, reads of these blobs will fail until they are restored.", c.StorageClass, c.Prefix)
+		}
+	}
+}
+
+func commonRetryFlags(cmd *kingpin.CmdClause, p *retrypolicy.Policy) {
+	cmd.Flag("retry-max-attempts", "Maximum number of attempts of each storage operation.").IntVar(&p.RetryMaxAttempts)
+	cmd.Flag("retry-initial-delay", "Delay after the first failed attempt of a storage operation.").DurationVar(&p.RetryInitialDelay)
+	cmd.Flag("retry-max-delay", "Maximum delay between attempts of a storage operation.").DurationVar(&p.RetryMaxDelay)
+	cmd.Flag("retry-jitter", "Fraction of each delay between attempts which is randomized (0..1).").Float64Var(&p.RetryJitter)
+}
+
 // AddStorageProvider adds a new StorageProvider at runtime after the App has
 // been initialized with the default providers. This is used in tests which
 // require custom storage providers to simulate various edge cases.
@@ -7,6 +7,7 @@
 	"time"
 
 	"github.com/alecthomas/kingpin/v2"
+	atunits "github.com/alecthomas/units"
 	"github.com/pkg/errors"
 
 	"github.com/kopia/kopia/repo/blob"
@@ -14,9 +15,10 @@
 )
 
 type storageS3Flags struct {
-	s3options       s3.Options
-	rootCaPemBase64 string
-	rootCaPemPath   string
+	s3options         s3.Options
+	rootCaPemBase64   string
+	rootCaPemPath     string
+	multipartPartSize atunits.Base2Bytes
 }
 
 func (c *storageS3Flags) Setup(svc StorageProviderServices, cmd *kingpin.CmdClause) {
@@ -30,7 +32,14 @@ func (c *storageS3Flags) Setup(svc StorageProviderServices, cmd *kingpin.CmdClau
 	cmd.Flag("disable-tls", "Disable TLS security (HTTPS)").BoolVar(&c.s3options.DoNotUseTLS)
 	cmd.Flag("disable-tls-verification", "Disable TLS (HTTPS) certificate verification").BoolVar(&c.s3options.DoNotVerifyTLS)
 
+	cmd.Flag("multipart-part-size", "Upload blobs at least this large in multiple parts of this size (e.g. 16MiB), by default blobs are uploaded in a single request").PlaceHolder("BYTES").BytesVar(&c.multipartPartSize)
+	cmd.Flag("upload-concurrency", "Number of parts of a single blob uploaded in parallel").IntVar(&c.s3options.UploadConcurrency)
+	cmd.Flag("transfer-acceleration", "Use Amazon S3 Transfer Acceleration endpoint").BoolVar(&c.s3options.UseTransferAcceleration)
+	cmd.Flag("checksum-algorithm", "Checksum algorithm used to verify uploads").EnumVar(&c.s3options.ChecksumAlgorithm, s3.ChecksumMD5, s3.ChecksumCRC32, s3.ChecksumCRC32C, s3.ChecksumSHA1, s3.ChecksumSHA256)
+
+	commonStorageClassFlags(cmd, &c.s3options.StorageClasses)
 	commonThrottlingFlags(cmd, &c.s3options.Limits)
+	commonRetryFlags(cmd, &c.s3options.Policy)
 
 	var pointInTimeStr string
 
@@ -86,6 +95,10 @@ func (c *storageS3Flags) Connect(ctx context.Context, isCreate bool, formatVersi
 		return nil, errors.New("Cannot specify a 'point-in-time' option when creating a repository")
 	}
 
+	warnArchiveStorageClasses(ctx, c.s3options.StorageClasses)
+
+	c.s3options.MultipartPartSize = int64(c.multipartPartSize)
+
 	//nolint:wrapcheck
 	return s3.New(ctx, &c.s3options, isCreate)
 }
@@ -6,9 +6,11 @@
 	"path/filepath"
 	"testing"
 
+	"github.com/alecthomas/kingpin/v2"
 	"github.com/stretchr/testify/require"
 
 	"github.com/kopia/kopia/internal/testutil"
+	"github.com/kopia/kopia/repo/blob"
 )
 
 var (
@@ -62,3 +64,20 @@ func TestLoadPEMBoth(t *testing.T) {
 	require.Error(t, err)
 	require.Contains(t, err.Error(), "mutually exclusive")
 }
+
+func TestStorageClassFlags(t *testing.T) {
+	var s3flags storageS3Flags
+
+	app := kingpin.New("test", "")
+	s3flags.Setup(&App{}, app.Command("s3", ""))
+
+	_, err := app.Parse([]string{"s3", "--bucket=b", "--access-key=a", "--secret-access-key=s", "--storage-class=p=STANDARD_IA", "--storage-class=q=STANDARD"})
+	require.NoError(t, err)
+	require.Equal(t, []blob.PrefixStorageClass{
+		{Prefix: "p", StorageClass: "STANDARD_IA"},
+		{Prefix: "q", StorageClass: "STANDARD"},
+	}, s3flags.s3options.StorageClasses)
+
+	_, err = app.Parse([]string{"s3", "--bucket=b", "--access-key=a", "--secret-access-key=s", "--storage-class=STANDARD_IA"})
+	require.ErrorContains(t, err, "expected PREFIX=CLASS")
+}
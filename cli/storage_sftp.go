@@ -43,6 +43,7 @@ func (c *storageSFTPFlags) Setup(_ StorageProviderServices, cmd *kingpin.CmdClau
 	cmd.Flag("list-parallelism", "Set list parallelism").Hidden().IntVar(&c.options.ListParallelism)
 
 	commonThrottlingFlags(cmd, &c.options.Limits)
+	commonRetryFlags(cmd, &c.options.Policy)
 }
 
 func (c *storageSFTPFlags) getOptions(formatVersion int) (*sftp.Options, error) {
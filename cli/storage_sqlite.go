@@ -0,0 +1,38 @@
+package cli
+
+import (
+	"context"
+
+	"github.com/alecthomas/kingpin/v2"
+	"github.com/pkg/errors"
+
+	"github.com/kopia/kopia/internal/ospath"
+	"github.com/kopia/kopia/repo/blob"
+	"github.com/kopia/kopia/repo/blob/sqlite"
+)
+
+type storageSQLiteFlags struct {
+	options sqlite.Options
+}
+
+func (c *storageSQLiteFlags) Setup(_ StorageProviderServices, cmd *kingpin.CmdClause) {
+	cmd.Flag("path", "Path to the database file").Required().StringVar(&c.options.Path)
+
+	commonThrottlingFlags(cmd, &c.options.Limits)
+	commonRetryFlags(cmd, &c.options.Policy)
+}
+
+func (c *storageSQLiteFlags) Connect(ctx context.Context, isCreate bool, formatVersion int) (blob.Storage, error) {
+	_ = formatVersion
+
+	opt := c.options
+
+	opt.Path = ospath.ResolveUserFriendlyPath(opt.Path, false)
+
+	if !ospath.IsAbs(opt.Path) {
+		return nil, errors.Errorf("sqlite database path must be absolute")
+	}
+
+	//nolint:wrapcheck
+	return sqlite.New(ctx, &opt, isCreate)
+}
@@ -2,7 +2,6 @@
 
 import (
 	"context"
-	"os"
 
 	"github.com/alecthomas/kingpin/v2"
 
@@ -13,9 +12,13 @@
 type storageWebDAVFlags struct {
 	options     webdav.Options
 	connectFlat bool
+
+	sps StorageProviderServices
 }
 
 func (c *storageWebDAVFlags) Setup(svc StorageProviderServices, cmd *kingpin.CmdClause) {
+	c.sps = svc
+
 	cmd.Flag("url", "URL of WebDAV server").Required().StringVar(&c.options.URL)
 	cmd.Flag("flat", "Use flat directory structure").BoolVar(&c.connectFlat)
 	cmd.Flag("webdav-username", "WebDAV username").Envar(svc.EnvName("KOPIA_WEBDAV_USERNAME")).StringVar(&c.options.Username)
@@ -24,13 +27,14 @@ func (c *storageWebDAVFlags) Setup(svc StorageProviderServices, cmd *kingpin.Cmd
 	cmd.Flag("atomic-writes", "Assume WebDAV provider implements atomic writes").BoolVar(&c.options.AtomicWrites)
 
 	commonThrottlingFlags(cmd, &c.options.Limits)
+	commonRetryFlags(cmd, &c.options.Policy)
 }
 
 func (c *storageWebDAVFlags) Connect(ctx context.Context, isCreate bool, formatVersion int) (blob.Storage, error) {
 	wo := c.options
 
 	if wo.Username != "" && wo.Password == "" {
-		pass, err := askPass(os.Stdout, "Enter WebDAV password: ")
+		pass, err := c.sps.askPass("Enter WebDAV password: ")
 		if err != nil {
 			return nil, err
 		}
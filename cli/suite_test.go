@@ -5,9 +5,10 @@
 
 	"github.com/kopia/kopia/internal/testutil"
 	"github.com/kopia/kopia/repo/format"
+	"github.com/kopia/kopia/tests/testenv"
 )
 
-func TestMain(m *testing.M) { testutil.MyTestMain(m) }
+func TestMain(m *testing.M) { testutil.MyTestMain(m, testenv.CleanupRepositoryFixtures) }
 
 type formatSpecificTestSuite struct {
 	formatFlags   []string
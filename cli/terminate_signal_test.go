@@ -1,11 +1,14 @@
 package cli_test
 
 import (
+	"path/filepath"
 	"syscall"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/require"
 
+	"github.com/kopia/kopia/cli"
 	"github.com/kopia/kopia/internal/testutil"
 	"github.com/kopia/kopia/tests/testenv"
 )
@@ -25,3 +28,33 @@ func TestTerminate(t *testing.T) {
 
 	require.NoError(t, wait())
 }
+
+func TestProfileDumpSignal(t *testing.T) {
+	env := testenv.NewCLITest(t, testenv.RepoFormatNotImportant, testenv.NewExeRunner(t))
+
+	env.RunAndExpectSuccess(t, "repo", "create", "filesystem", "--path", env.RepoDir)
+
+	profileDir := testutil.TempDirectory(t)
+
+	profiles := func() []string {
+		matches, err := filepath.Glob(filepath.Join(profileDir, "heap-*.pprof"))
+		require.NoError(t, err)
+
+		return matches
+	}
+
+	var sp testutil.ServerParameters
+
+	wait, interrupt := env.RunAndProcessStderrInt(t, sp.ProcessOutput, "server", "start",
+		"--address=localhost:0",
+		"--insecure",
+		"--profile-buffers=heap",
+		"--profile-dir", profileDir)
+
+	interrupt(cli.ProfileDumpSignal)
+	require.Eventually(t, func() bool { return len(profiles()) == 1 }, 10*time.Second, 50*time.Millisecond)
+
+	interrupt(syscall.SIGTERM)
+
+	require.NoError(t, wait())
+}
@@ -21,7 +21,7 @@ func (c *commonThrottleGet) setup(svc appServices, cmd *kingpin.CmdClause) {
 
 func (c *commonThrottleGet) output(limits *throttling.Limits) error {
 	if c.jo.jsonOutput {
-		c.out.printStdout("%s\n", c.jo.jsonBytes(limits))
+		c.jo.printJSON(limits)
 		return nil
 	}
 
@@ -33,6 +33,10 @@ func (c *commonThrottleGet) output(limits *throttling.Limits) error {
 	c.printValueOrUnlimited("Max Concurrent Reads:", float64(limits.ConcurrentReads), c.floatToString)
 	c.printValueOrUnlimited("Max Concurrent Writes:", float64(limits.ConcurrentWrites), c.floatToString)
 
+	for _, sl := range limits.Schedule {
+		c.out.printStdout("%-30v %v%% of limits between %v and %v\n", "Scheduled Limits:", sl.Percent, sl.Start, sl.End)
+	}
+
 	return nil
 }
 
@@ -3,6 +3,7 @@
 import (
 	"context"
 	"strconv"
+	"strings"
 
 	"github.com/alecthomas/kingpin/v2"
 	"github.com/pkg/errors"
@@ -19,16 +20,20 @@ type commonThrottleSet struct {
 	setListsPerSecond         string
 	setConcurrentReads        string
 	setConcurrentWrites       string
+	setSchedule               []string
+	clearSchedule             bool
 }
 
 func (c *commonThrottleSet) setup(cmd *kingpin.CmdClause) {
-	cmd.Flag("download-bytes-per-second", "Set the download bytes per second").StringVar(&c.setDownloadBytesPerSecond)
-	cmd.Flag("upload-bytes-per-second", "Set the upload bytes per second").StringVar(&c.setUploadBytesPerSecond)
+	cmd.Flag("download-bytes-per-second", "Set the download bytes per second (e.g. 10MB, 1.5MiB/s)").StringVar(&c.setDownloadBytesPerSecond)
+	cmd.Flag("upload-bytes-per-second", "Set the upload bytes per second (e.g. 10MB, 1.5MiB/s)").StringVar(&c.setUploadBytesPerSecond)
 	cmd.Flag("read-requests-per-second", "Set max reads per second").StringVar(&c.setReadsPerSecond)
 	cmd.Flag("write-requests-per-second", "Set max writes per second").StringVar(&c.setWritesPerSecond)
 	cmd.Flag("list-requests-per-second", "Set max lists per second").StringVar(&c.setListsPerSecond)
 	cmd.Flag("concurrent-reads", "Set max concurrent reads").StringVar(&c.setConcurrentReads)
 	cmd.Flag("concurrent-writes", "Set max concurrent writes").StringVar(&c.setConcurrentWrites)
+	cmd.Flag("schedule", "Replace the schedule with limits scaled during daily time windows (e.g. 09:00-17:00=20%)").StringsVar(&c.setSchedule)
+	cmd.Flag("clear-schedule", "Remove the schedule of limits").BoolVar(&c.clearSchedule)
 }
 
 func (c *commonThrottleSet) apply(ctx context.Context, limits *throttling.Limits, changeCount *int) error {
@@ -56,7 +61,51 @@ func (c *commonThrottleSet) apply(ctx context.Context, limits *throttling.Limits
 		return err
 	}
 
-	return c.setThrottleInt(ctx, "concurrent writes", &limits.ConcurrentWrites, c.setConcurrentWrites, changeCount)
+	if err := c.setThrottleInt(ctx, "concurrent writes", &limits.ConcurrentWrites, c.setConcurrentWrites, changeCount); err != nil {
+		return err
+	}
+
+	return c.setThrottleSchedule(ctx, &limits.Schedule, changeCount)
+}
+
+func (c *commonThrottleSet) setThrottleSchedule(ctx context.Context, schedule *[]throttling.ScheduledLimit, changeCount *int) error {
+	if c.clearSchedule {
+		if len(c.setSchedule) > 0 {
+			return errors.New("--schedule and --clear-schedule are mutually exclusive")
+		}
+
+		*changeCount++
+
+		log(ctx).Info("Clearing the schedule.")
+
+		*schedule = nil
+
+		return nil
+	}
+
+	if len(c.setSchedule) == 0 {
+		// not changed
+		return nil
+	}
+
+	var result []throttling.ScheduledLimit
+
+	for _, str := range c.setSchedule {
+		sl, err := throttling.ParseScheduledLimit(str)
+		if err != nil {
+			return errors.Wrapf(err, "can't parse the schedule %q", str)
+		}
+
+		log(ctx).Infof("Scheduling limits to %v%% between %v and %v.", sl.Percent, sl.Start, sl.End)
+
+		result = append(result, sl)
+	}
+
+	*changeCount++
+
+	*schedule = result
+
+	return nil
 }
 
 func (c *commonThrottleSet) setThrottleFloat64(ctx context.Context, desc string, bps bool, val *float64, str string, changeCount *int) error {
@@ -75,7 +124,7 @@ func (c *commonThrottleSet) setThrottleFloat64(ctx context.Context, desc string,
 		return nil
 	}
 
-	v, err := strconv.ParseFloat(str, 64)
+	v, err := parseThrottleFloat64(bps, str)
 	if err != nil {
 		return errors.Wrapf(err, "can't parse the %v %q", desc, str)
 	}
@@ -93,6 +142,21 @@ func (c *commonThrottleSet) setThrottleFloat64(ctx context.Context, desc string,
 	return nil
 }
 
+// parseThrottleFloat64 parses the value of a throttle, speeds may be provided with units, such as "10MB" or "1.5MiB/s".
+func parseThrottleFloat64(bps bool, str string) (float64, error) {
+	if !bps {
+		//nolint:wrapcheck
+		return strconv.ParseFloat(str, 64)
+	}
+
+	v, err := units.ParseBytes(strings.TrimSuffix(str, "/s"))
+	if err != nil {
+		return 0, errors.Wrap(err, "invalid speed")
+	}
+
+	return float64(v), nil
+}
+
 func (c *commonThrottleSet) setThrottleInt(ctx context.Context, desc string, val *int, str string, changeCount *int) error {
 	if str == "" {
 		// not changed
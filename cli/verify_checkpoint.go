@@ -0,0 +1,61 @@
+package cli
+
+import (
+	"bytes"
+	"encoding/json"
+	"os"
+
+	"github.com/pkg/errors"
+
+	"github.com/kopia/kopia/internal/atomicfile"
+)
+
+// loadVerifyCheckpoint reads the verification checkpoint from the provided file into cp and returns true
+// if it exists. Empty file name disables checkpointing.
+func loadVerifyCheckpoint(fname string, cp any) (bool, error) {
+	if fname == "" {
+		return false, nil
+	}
+
+	b, err := os.ReadFile(fname) //nolint:gosec
+	if os.IsNotExist(err) {
+		return false, nil
+	}
+
+	if err != nil {
+		return false, errors.Wrap(err, "unable to read checkpoint file")
+	}
+
+	if err := json.Unmarshal(b, cp); err != nil {
+		return false, errors.Wrap(err, "invalid checkpoint file")
+	}
+
+	return true, nil
+}
+
+// saveVerifyCheckpoint atomically writes the verification checkpoint to the provided file.
+func saveVerifyCheckpoint(fname string, cp any) error {
+	if fname == "" {
+		return nil
+	}
+
+	b, err := json.Marshal(cp)
+	if err != nil {
+		return errors.Wrap(err, "unable to serialize checkpoint")
+	}
+
+	return errors.Wrap(atomicfile.Write(fname, bytes.NewReader(b)), "unable to write checkpoint file")
+}
+
+// removeVerifyCheckpoint removes the verification checkpoint once verification has finished.
+func removeVerifyCheckpoint(fname string) error {
+	if fname == "" {
+		return nil
+	}
+
+	if err := os.Remove(fname); err != nil && !os.IsNotExist(err) {
+		return errors.Wrap(err, "unable to remove checkpoint file")
+	}
+
+	return nil
+}
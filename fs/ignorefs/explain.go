@@ -0,0 +1,106 @@
+package ignorefs
+
+import (
+	"context"
+	"path"
+	"strings"
+
+	"github.com/pkg/errors"
+
+	"github.com/kopia/kopia/fs"
+	"github.com/kopia/kopia/snapshot/policy"
+)
+
+// Explanation describes whether a path would be included in a snapshot and why.
+type Explanation struct {
+	Path     string `json:"path"`
+	Included bool   `json:"included"`
+
+	// Reason and ExcludedPath are set when the entry is excluded, ExcludedPath may be a parent directory of Path.
+	Reason       IgnoreReason `json:"reason,omitempty"`
+	ExcludedPath string       `json:"excludedPath,omitempty"`
+
+	// Rule is the ignore rule that decided whether the entry is excluded, which for included entries
+	// is a negated rule overriding another one. Nil if no rule matched.
+	Rule *IgnoreRule `json:"rule,omitempty"`
+}
+
+// Explain evaluates ignore rules, dot-ignore files and policies the same way as the directory returned by New
+// for the entry at the provided path relative to the root and reports which of them caused it to be excluded.
+func Explain(ctx context.Context, root fs.Directory, policyTree *policy.Tree, relativePath string, options ...Option) (*Explanation, error) {
+	rootContext := &ignoreContext{}
+
+	for _, opt := range options {
+		opt(rootContext)
+	}
+
+	relativePath = strings.Trim(path.Clean("/"+relativePath), "/")
+
+	result := &Explanation{Path: relativePath, Included: true}
+	if relativePath == "" {
+		result.Path = "."
+		return result, nil
+	}
+
+	cur := &ignoreDirectory{".", rootContext, policyTree, root}
+	parts := strings.Split(relativePath, "/")
+
+	for i, name := range parts {
+		if cur.skipCacheDirectory(ctx, cur.relativePath, cur.policyTree) {
+			return result.excluded(cur.relativePath, IgnoreReasonCacheDirectory, nil), nil
+		}
+
+		ic, err := cur.buildContext(ctx)
+		if err != nil {
+			return nil, err
+		}
+
+		e, err := cur.Directory.Child(ctx, name)
+		if err != nil {
+			return nil, errors.Wrapf(err, "unable to find %v", path.Join(parts[:i+1]...))
+		}
+
+		s := cur.relativePath + "/" + name
+
+		ignored, rule := ic.matchByName(s, e)
+		result.Rule = rule
+
+		switch {
+		case ignored:
+			return result.excluded(s, IgnoreReasonRule, rule), nil
+
+		case ic.maxFileSize > 0 && e.Size() > ic.maxFileSize:
+			return result.excluded(s, IgnoreReasonMaxFileSize, nil), nil
+
+		case !ic.shouldIncludeByDevice(e, cur):
+			return result.excluded(s, IgnoreReasonOtherFilesystem, nil), nil
+		}
+
+		dir, ok := e.(fs.Directory)
+		if !ok {
+			if i < len(parts)-1 {
+				return nil, errors.Errorf("%v is not a directory", path.Join(parts[:i+1]...))
+			}
+
+			return result, nil
+		}
+
+		cur = &ignoreDirectory{s, ic, cur.policyTree.Child(name), dir}
+	}
+
+	// the entry itself is a directory, which may be a cache directory.
+	if cur.skipCacheDirectory(ctx, cur.relativePath, cur.policyTree) {
+		return result.excluded(cur.relativePath, IgnoreReasonCacheDirectory, nil), nil
+	}
+
+	return result, nil
+}
+
+func (e *Explanation) excluded(excludedPath string, reason IgnoreReason, rule *IgnoreRule) *Explanation {
+	e.Included = false
+	e.Reason = reason
+	e.ExcludedPath = displayPath(excludedPath)
+	e.Rule = rule
+
+	return e
+}
@@ -4,6 +4,7 @@
 import (
 	"bufio"
 	"context"
+	"fmt"
 	"strings"
 	"sync"
 
@@ -22,14 +23,56 @@
 // IgnoreCallback is a function called by ignorefs to report whenever a file or directory is being ignored while listing its parent.
 type IgnoreCallback func(ctx context.Context, path string, metadata fs.Entry, pol *policy.Tree)
 
+// IgnoreReason describes why a file or directory is being ignored.
+type IgnoreReason string
+
+// Reasons for ignoring files and directories.
+const (
+	IgnoreReasonRule            IgnoreReason = "ignore-rule"      // matched ignore rule in a policy or dot-ignore file
+	IgnoreReasonCacheDirectory  IgnoreReason = "cache-directory"  // directory marked with CACHEDIR.TAG
+	IgnoreReasonMaxFileSize     IgnoreReason = "max-file-size"    // file larger than maximum file size
+	IgnoreReasonOtherFilesystem IgnoreReason = "other-filesystem" // entry on a different filesystem
+)
+
+// IgnoreReasonCallback is like IgnoreCallback but also receives the reason for ignoring the entry.
+type IgnoreReasonCallback func(ctx context.Context, path string, metadata fs.Entry, pol *policy.Tree, reason IgnoreReason)
+
+// IgnoreRule describes an ignore rule and where it was defined.
+type IgnoreRule struct {
+	Pattern string `json:"pattern"`
+
+	// Path is the path of the dot-ignore file defining the rule or of the directory whose policy defines it,
+	// relative to the root directory.
+	Path string `json:"path"`
+	Line int    `json:"line,omitempty"` // line number in the dot-ignore file, 0 for policy rules
+}
+
+func (r *IgnoreRule) String() string {
+	if r.Line == 0 {
+		return fmt.Sprintf("%q in policy for %v", r.Pattern, r.Path)
+	}
+
+	return fmt.Sprintf("%q in %v:%v", r.Pattern, r.Path, r.Line)
+}
+
+// IgnoreExplanationCallback is like IgnoreReasonCallback but also receives the rule which caused
+// the entry to be ignored, which is nil unless the reason is IgnoreReasonRule.
+type IgnoreExplanationCallback func(ctx context.Context, path string, metadata fs.Entry, pol *policy.Tree, reason IgnoreReason, rule *IgnoreRule)
+
+type ignoreMatcher struct {
+	wcmatch.WildcardMatcher
+
+	rule IgnoreRule
+}
+
 type ignoreContext struct {
 	parent *ignoreContext
 
-	onIgnore []IgnoreCallback
+	onIgnore []IgnoreExplanationCallback
 
-	dotIgnoreFiles []string                  // which files to look for more ignore rules
-	matchers       []wcmatch.WildcardMatcher // current set of rules to ignore files
-	maxFileSize    int64                     // maximum size of file allowed
+	dotIgnoreFiles []string        // which files to look for more ignore rules
+	matchers       []ignoreMatcher // current set of rules to ignore files
+	maxFileSize    int64           // maximum size of file allowed
 
 	oneFileSystem bool // should we enter other mounted filesystems
 }
@@ -43,23 +86,47 @@ func (c *ignoreContext) shouldIncludeByName(ctx context.Context, path string, e
 		shouldIgnore = !c.parent.shouldIncludeByName(ctx, path, e, policyTree)
 	}
 
-	for _, m := range c.matchers {
+	shouldIgnore, decidedBy := c.applyMatchers(path, e, shouldIgnore, nil)
+	if shouldIgnore {
+		c.reportIgnored(ctx, path, e, policyTree, IgnoreReasonRule, decidedBy)
+
+		return false
+	}
+
+	return true
+}
+
+// matchByName is like shouldIncludeByName but does not report ignored entries and returns the rule which made the decision, if any.
+func (c *ignoreContext) matchByName(path string, e fs.Entry) (shouldIgnore bool, decidedBy *IgnoreRule) {
+	if c.parent != nil {
+		shouldIgnore, decidedBy = c.parent.matchByName(path, e)
+	}
+
+	return c.applyMatchers(path, e, shouldIgnore, decidedBy)
+}
+
+// applyMatchers applies the rules of this context on top of the decision of the parent contexts.
+func (c *ignoreContext) applyMatchers(path string, e fs.Entry, shouldIgnore bool, decidedBy *IgnoreRule) (bool, *IgnoreRule) {
+	for i := range c.matchers {
+		m := &c.matchers[i]
+
 		// If we already matched a pattern and concluded that the path should be ignored, we only check
 		// negated patterns (and vice versa)
 		if !shouldIgnore && !m.Negated() || shouldIgnore && m.Negated() {
-			shouldIgnore = m.Match(trimLeadingCurrentDir(path), e.IsDir())
+			if r := m.Match(trimLeadingCurrentDir(path), e.IsDir()); r != shouldIgnore {
+				shouldIgnore = r
+				decidedBy = &m.rule
+			}
 		}
 	}
 
-	if shouldIgnore {
-		for _, oi := range c.onIgnore {
-			oi(ctx, strings.TrimPrefix(path, "./"), e, policyTree)
-		}
+	return shouldIgnore, decidedBy
+}
 
-		return false
+func (c *ignoreContext) reportIgnored(ctx context.Context, path string, e fs.Entry, policyTree *policy.Tree, reason IgnoreReason, rule *IgnoreRule) {
+	for _, oi := range c.onIgnore {
+		oi(ctx, strings.TrimPrefix(path, "./"), e, policyTree, reason, rule)
 	}
-
-	return true
 }
 
 func (c *ignoreContext) shouldIncludeByDevice(e fs.Entry, parent *ignoreDirectory) bool {
@@ -129,9 +196,7 @@ func (d *ignoreDirectory) skipCacheDirectory(ctx context.Context, relativePath s
 	}
 
 	// if the given directory contains a marker file used for kopia cache, pretend the directory was empty.
-	for _, oi := range d.parentContext.onIgnore {
-		oi(ctx, strings.TrimPrefix(relativePath, "./"), d, policyTree)
-	}
+	d.parentContext.reportIgnored(ctx, relativePath, d, policyTree, IgnoreReasonCacheDirectory, nil)
 
 	return true
 }
@@ -227,10 +292,14 @@ func (d *ignoreDirectory) maybeWrappedChildEntry(ctx context.Context, ic *ignore
 	}
 
 	if maxSize := ic.maxFileSize; maxSize > 0 && e.Size() > maxSize {
+		ic.reportIgnored(ctx, s, e, d.policyTree, IgnoreReasonMaxFileSize, nil)
+
 		return nil, false
 	}
 
 	if !ic.shouldIncludeByDevice(e, d) {
+		ic.reportIgnored(ctx, s, e, d.policyTree, IgnoreReasonOtherFilesystem, nil)
+
 		return nil, false
 	}
 
@@ -338,7 +407,10 @@ func (c *ignoreContext) overrideFromPolicy(fp *policy.FilesPolicy, dirPath strin
 			return errors.Wrapf(err, "unable to parse ignore entry %v", dirPath)
 		}
 
-		c.matchers = append(c.matchers, *m)
+		c.matchers = append(c.matchers, ignoreMatcher{*m, IgnoreRule{
+			Pattern: rule,
+			Path:    displayPath(dirPath),
+		}})
 	}
 
 	return nil
@@ -377,14 +449,14 @@ func combineAndDedupe(slices ...[]string) []string {
 	return result
 }
 
-func parseIgnoreFile(ctx context.Context, baseDir string, file fs.File) ([]wcmatch.WildcardMatcher, error) {
+func parseIgnoreFile(ctx context.Context, baseDir string, file fs.File) ([]ignoreMatcher, error) {
 	f, err := file.Open(ctx)
 	if err != nil {
 		return nil, errors.Wrap(err, "unable to open ignore file")
 	}
 	defer f.Close() //nolint:errcheck
 
-	var matchers []wcmatch.WildcardMatcher
+	var matchers []ignoreMatcher
 
 	// Remove the "current directory" indicator from the baseDir if present, since wcmatch does
 	// not deal with that.
@@ -392,9 +464,14 @@ func parseIgnoreFile(ctx context.Context, baseDir string, file fs.File) ([]wcmat
 		baseDir = baseDir[1:]
 	}
 
+	filePath := strings.TrimPrefix(baseDir+"/"+file.Name(), "/")
+
+	lineNumber := 0
+
 	s := bufio.NewScanner(f)
 	for s.Scan() {
 		line := s.Text()
+		lineNumber++
 
 		if strings.HasPrefix(line, "#") {
 			// ignore comments
@@ -411,7 +488,11 @@ func parseIgnoreFile(ctx context.Context, baseDir string, file fs.File) ([]wcmat
 			return nil, errors.Wrapf(err, "unable to parse ignore entry %v", line)
 		}
 
-		matchers = append(matchers, *m)
+		matchers = append(matchers, ignoreMatcher{*m, IgnoreRule{
+			Pattern: line,
+			Path:    filePath,
+			Line:    lineNumber,
+		}})
 	}
 
 	return matchers, nil
@@ -426,6 +507,15 @@ func trimLeadingCurrentDir(dir string) string {
 	return dir
 }
 
+// displayPath returns the relative directory path without the leading "./".
+func displayPath(dir string) string {
+	if dir == "." {
+		return dir
+	}
+
+	return strings.TrimPrefix(dir, "./")
+}
+
 // Option modifies the behavior of ignorefs.
 type Option func(parentContext *ignoreContext)
 
@@ -442,8 +532,35 @@ func New(dir fs.Directory, policyTree *policy.Tree, options ...Option) fs.Direct
 
 var _ fs.Directory = &ignoreDirectory{}
 
-// ReportIgnoredFiles returns an Option causing ignorefs to call the provided function whenever a file or directory is ignored.
+// ReportIgnoredFiles returns an Option causing ignorefs to call the provided function whenever a file or directory
+// is ignored because of ignore rules or because it is a cache directory.
 func ReportIgnoredFiles(f IgnoreCallback) Option {
+	return func(ic *ignoreContext) {
+		if f != nil {
+			ic.onIgnore = append(ic.onIgnore, func(ctx context.Context, path string, metadata fs.Entry, pol *policy.Tree, reason IgnoreReason, _ *IgnoreRule) {
+				if reason == IgnoreReasonRule || reason == IgnoreReasonCacheDirectory {
+					f(ctx, path, metadata, pol)
+				}
+			})
+		}
+	}
+}
+
+// ReportIgnoredFilesWithReason returns an Option causing ignorefs to call the provided function whenever a file or
+// directory is ignored for any reason.
+func ReportIgnoredFilesWithReason(f IgnoreReasonCallback) Option {
+	return func(ic *ignoreContext) {
+		if f != nil {
+			ic.onIgnore = append(ic.onIgnore, func(ctx context.Context, path string, metadata fs.Entry, pol *policy.Tree, reason IgnoreReason, _ *IgnoreRule) {
+				f(ctx, path, metadata, pol, reason)
+			})
+		}
+	}
+}
+
+// ReportIgnoredFilesWithExplanation returns an Option causing ignorefs to call the provided function whenever a file
+// or directory is ignored for any reason, including the ignore rule responsible.
+func ReportIgnoredFilesWithExplanation(f IgnoreExplanationCallback) Option {
 	return func(ic *ignoreContext) {
 		if f != nil {
 			ic.onIgnore = append(ic.onIgnore, f)
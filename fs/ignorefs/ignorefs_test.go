@@ -4,6 +4,7 @@
 	"bytes"
 	"context"
 	"sort"
+	"strings"
 	"testing"
 
 	"github.com/kylelemons/godebug/pretty"
@@ -579,3 +580,121 @@ func verifyDirectoryTree(t *testing.T, dir fs.Directory, expected []string) {
 		t.Errorf("unexpected directory tree, diff(-got,+want): %v\n", diff)
 	}
 }
+
+func TestExplainMatchesIgnoreFS(t *testing.T) {
+	ctx := testlogging.Context(t)
+
+	for _, tc := range cases {
+		t.Run(tc.desc, func(t *testing.T) {
+			root := setupFilesystem(tc.skipDefaultFiles)
+			if tc.setup != nil {
+				tc.setup(root)
+			}
+
+			included := map[string]bool{}
+			for _, f := range walkTree(t, ignorefs.New(root, tc.policyTree)) {
+				included[f] = true
+			}
+
+			for _, f := range walkTree(t, root) {
+				if strings.HasSuffix(f, "/") {
+					continue
+				}
+
+				ex, err := ignorefs.Explain(ctx, root, tc.policyTree, strings.TrimPrefix(f, "./"))
+				if err != nil {
+					t.Fatalf("unable to explain %v: %v", f, err)
+				}
+
+				if ex.Included != included[f] {
+					t.Errorf("invalid explanation for %v: %+v", f, ex)
+				}
+			}
+		})
+	}
+}
+
+func TestExplain(t *testing.T) {
+	ctx := testlogging.Context(t)
+
+	root := setupFilesystem(false)
+	root.AddFileLines(".kopiaignore", []string{
+		"# comment",
+		"file[12]",
+		"!file2",
+	}, 0)
+	root.Subdir("src").AddFileLines(".newignore", []string{"f1"}, 0)
+
+	cases := []struct {
+		path string
+		want ignorefs.Explanation
+	}{
+		{"file1", ignorefs.Explanation{
+			Path: "file1", Reason: ignorefs.IgnoreReasonRule, ExcludedPath: "file1",
+			Rule: &ignorefs.IgnoreRule{Pattern: "file[12]", Path: ".kopiaignore", Line: 2},
+		}},
+		{"file2", ignorefs.Explanation{
+			Path: "file2", Included: true,
+			Rule: &ignorefs.IgnoreRule{Pattern: "!file2", Path: ".kopiaignore", Line: 3},
+		}},
+		{"file3", ignorefs.Explanation{Path: "file3", Included: true}},
+		{"./ignored-by-rule", ignorefs.Explanation{
+			Path: "ignored-by-rule", Reason: ignorefs.IgnoreReasonRule, ExcludedPath: "ignored-by-rule",
+			Rule: &ignorefs.IgnoreRule{Pattern: "*-by-rule", Path: "."},
+		}},
+		{"largefile1", ignorefs.Explanation{
+			Path: "largefile1", Reason: ignorefs.IgnoreReasonMaxFileSize, ExcludedPath: "largefile1",
+		}},
+		{"src/some-src/f1", ignorefs.Explanation{
+			Path: "src/some-src/f1", Reason: ignorefs.IgnoreReasonRule, ExcludedPath: "src/some-src",
+			Rule: &ignorefs.IgnoreRule{Pattern: "some-*", Path: "src"},
+		}},
+		{"bin", ignorefs.Explanation{Path: "bin", Included: true}},
+		{".", ignorefs.Explanation{Path: ".", Included: true}},
+	}
+
+	for _, tc := range cases {
+		got, err := ignorefs.Explain(ctx, root, rootAndSrcPolicy, tc.path)
+		if err != nil {
+			t.Fatalf("unable to explain %v: %v", tc.path, err)
+		}
+
+		if diff := pretty.Compare(got, &tc.want); diff != "" {
+			t.Errorf("unexpected explanation of %v, diff(-got,+want): %v\n", tc.path, diff)
+		}
+	}
+
+	if _, err := ignorefs.Explain(ctx, root, rootAndSrcPolicy, "no-such-file"); err == nil {
+		t.Fatalf("expected error explaining non-existent file")
+	}
+
+	if _, err := ignorefs.Explain(ctx, root, rootAndSrcPolicy, "file3/x"); err == nil {
+		t.Fatalf("expected error explaining path under a file")
+	}
+}
+
+func TestReportIgnoredFilesWithExplanation(t *testing.T) {
+	root := setupFilesystem(false)
+	root.AddFileLines(".kopiaignore", []string{"file1"}, 0)
+
+	reported := map[string]string{}
+
+	ifs := ignorefs.New(root, defaultPolicy, ignorefs.ReportIgnoredFilesWithExplanation(
+		func(ctx context.Context, path string, metadata fs.Entry, pol *policy.Tree, reason ignorefs.IgnoreReason, rule *ignorefs.IgnoreRule) {
+			if rule != nil {
+				reported[path] = string(reason) + " " + rule.String()
+			} else {
+				reported[path] = string(reason)
+			}
+		}))
+
+	walkTree(t, ifs)
+
+	if diff := pretty.Compare(reported, map[string]string{
+		"file1":           `ignore-rule "file1" in .kopiaignore:1`,
+		"ignored-by-rule": `ignore-rule "*-by-rule" in policy for .`,
+		"largefile1":      "max-file-size",
+	}); diff != "" {
+		t.Errorf("unexpected reported files, diff(-got,+want): %v\n", diff)
+	}
+}
@@ -2,14 +2,11 @@
 
 import (
 	"context"
-	"os"
-	"path/filepath"
 	"testing"
 
-	"github.com/google/uuid"
-
 	"github.com/kopia/kopia/fs"
 	"github.com/kopia/kopia/fs/localfs"
+	"github.com/kopia/kopia/internal/fsstress"
 )
 
 func BenchmarkReadDir0(b *testing.B) {
@@ -47,9 +44,7 @@ func benchmarkReadDirWithCount(b *testing.B, fileCount int) {
 
 	td := b.TempDir()
 
-	for range fileCount {
-		os.WriteFile(filepath.Join(td, uuid.NewString()), []byte{1, 2, 3, 4}, 0o644)
-	}
+	fsstress.NewGenerator(td, 1).CreateTree(fsstress.TreeSpec{FileCount: fileCount, MaxFileSize: 4})
 
 	b.StartTimer()
 
@@ -0,0 +1,388 @@
+package virtualfs
+
+import (
+	"archive/tar"
+	"bytes"
+	"context"
+	"io"
+	"os"
+	"path"
+	"strings"
+
+	"github.com/pkg/errors"
+
+	"github.com/kopia/kopia/fs"
+	"github.com/kopia/kopia/repo/logging"
+)
+
+var log = logging.Module("virtualfs")
+
+// limits of entries of a directory read ahead into memory to look up its children.
+const (
+	tarMaxReadAheadEntries = 100
+	tarMaxReadAheadBytes   = 1 << 20
+)
+
+// tarStream holds the state of a tar archive being read sequentially, shared by all its directories.
+type tarStream struct {
+	tr *tar.Reader
+
+	// header which has been read from the archive but not consumed yet, and its normalized path.
+	pending     *tar.Header
+	pendingPath string
+	eof         bool
+}
+
+// peek returns the next header in the archive without consuming it, nil at the end of the archive.
+func (s *tarStream) peek() (*tar.Header, string, error) {
+	for s.pending == nil && !s.eof {
+		h, err := s.tr.Next()
+		if errors.Is(err, io.EOF) {
+			s.eof = true
+			break
+		}
+
+		if err != nil {
+			return nil, "", errors.Wrap(err, "error reading tar stream")
+		}
+
+		p := strings.Trim(path.Clean("/"+h.Name), "/")
+		if p == "" {
+			// entry for the root of the archive.
+			continue
+		}
+
+		s.pending = h
+		s.pendingPath = p
+	}
+
+	return s.pending, s.pendingPath, nil
+}
+
+func (s *tarStream) consume() {
+	s.pending = nil
+	s.pendingPath = ""
+}
+
+// tarDirectory is a streaming directory whose entries are read from a tar archive.
+type tarDirectory struct {
+	virtualEntry
+
+	stream *tarStream
+	path   string
+
+	iterated bool
+
+	// leading entries of the directory read ahead by Child() before the directory is iterated.
+	readAhead     []fs.Entry
+	readAheadDone bool
+	// true when readAhead contains all entries of the directory.
+	readAheadComplete bool
+	// names looked up by Child() which were not among the entries read ahead.
+	missedLookups map[string]bool
+}
+
+// Child returns the named child of the directory, which must be looked up before the directory is iterated.
+// Since the archive is read sequentially, only small files and symlinks which precede subdirectories of
+// the directory in the archive can be found, which is sufficient for ignore files placed at the beginning
+// of each directory.
+func (d *tarDirectory) Child(ctx context.Context, name string) (fs.Entry, error) {
+	if d.iterated {
+		return nil, errChildNotSupported
+	}
+
+	if err := d.readAheadEntries(); err != nil {
+		return nil, err
+	}
+
+	for _, e := range d.readAhead {
+		if e.Name() == name {
+			return e, nil
+		}
+	}
+
+	if !d.readAheadComplete {
+		if d.missedLookups == nil {
+			d.missedLookups = map[string]bool{}
+		}
+
+		// remember the name to warn if it appears later.
+		d.missedLookups[name] = true
+	}
+
+	return nil, fs.ErrEntryNotFound
+}
+
+// readAheadEntries reads leading entries of the directory into memory, until a subdirectory, a file too large to
+// be kept in memory or the end of the directory.
+func (d *tarDirectory) readAheadEntries() error {
+	if d.readAheadDone {
+		return nil
+	}
+
+	d.readAheadDone = true
+
+	s := d.stream
+	remainingBytes := int64(tarMaxReadAheadBytes)
+
+	for len(d.readAhead) < tarMaxReadAheadEntries {
+		h, p, err := s.peek()
+		if err != nil {
+			return err
+		}
+
+		rel, ok := d.relativePath(p)
+		if h == nil || !ok {
+			d.readAheadComplete = true
+			return nil
+		}
+
+		if strings.Contains(rel, "/") || h.Typeflag == tar.TypeDir || h.Size > remainingBytes {
+			return nil
+		}
+
+		ve := newTarVirtualEntry(rel, h)
+
+		var data []byte
+
+		if isTarRegularFile(h) {
+			if data, err = io.ReadAll(s.tr); err != nil {
+				return errors.Wrap(err, "error reading tar stream")
+			}
+
+			remainingBytes -= h.Size
+		}
+
+		s.consume()
+
+		d.readAhead = append(d.readAhead, newTarEntry(ve, h, &tarBufferedFile{ve, data}))
+	}
+
+	return nil
+}
+
+// relativePath returns the path of the provided archive entry relative to the directory, false if it's not in the directory.
+func (d *tarDirectory) relativePath(p string) (string, bool) {
+	if d.path == "" {
+		return p, true
+	}
+
+	if !strings.HasPrefix(p, d.path+"/") {
+		return "", false
+	}
+
+	return p[len(d.path)+1:], true
+}
+
+func (d *tarDirectory) Iterate(ctx context.Context) (fs.DirectoryIterator, error) {
+	if d.iterated {
+		return nil, errIteratorAlreadyUsed
+	}
+
+	d.iterated = true
+
+	return &tarDirectoryIterator{dir: d, readAhead: d.readAhead, children: map[string]*tarDirectory{}}, nil
+}
+
+func (d *tarDirectory) SupportsMultipleIterations() bool {
+	return false
+}
+
+type tarDirectoryIterator struct {
+	dir *tarDirectory
+
+	// entries read ahead which have not been returned yet.
+	readAhead []fs.Entry
+
+	// names of entries returned so far, mapped to directories which may still have entries in the archive.
+	children map[string]*tarDirectory
+}
+
+func (it *tarDirectoryIterator) Next(ctx context.Context) (fs.Entry, error) {
+	if len(it.readAhead) > 0 {
+		e := it.readAhead[0]
+		it.readAhead = it.readAhead[1:]
+
+		if _, ok := it.children[e.Name()]; ok {
+			return nil, errors.Errorf("entries for %q are not contiguous in tar stream", path.Join(it.dir.path, e.Name()))
+		}
+
+		it.children[e.Name()] = nil
+
+		return e, nil
+	}
+
+	s := it.dir.stream
+
+	for {
+		h, p, err := s.peek()
+		if err != nil || h == nil {
+			return nil, err
+		}
+
+		rel, ok := it.dir.relativePath(p)
+		if !ok {
+			// entry belongs to another directory.
+			return nil, nil
+		}
+
+		name, _, nested := strings.Cut(rel, "/")
+
+		if it.dir.missedLookups[name] {
+			log(ctx).Warnf("%q was not found when it was looked up, for example as an ignore file, because it follows subdirectories or large files in the tar stream", path.Join(it.dir.path, name))
+			delete(it.dir.missedLookups, name)
+		}
+
+		if prev, ok := it.children[name]; ok {
+			if prev == nil || prev.iterated || !nested {
+				return nil, errors.Errorf("entries for %q are not contiguous in tar stream", path.Join(it.dir.path, name))
+			}
+
+			// directory has not been iterated, because it was excluded, skip its entries.
+			s.consume()
+
+			continue
+		}
+
+		if nested {
+			// directory without its own entry in the archive.
+			return it.newDirectory(name, virtualEntry{
+				name:    name,
+				mode:    defaultPermissions | os.ModeDir,
+				modTime: h.ModTime,
+			}), nil
+		}
+
+		s.consume()
+
+		return it.newEntry(name, h), nil
+	}
+}
+
+func (it *tarDirectoryIterator) newDirectory(name string, ve virtualEntry) fs.Directory {
+	d := &tarDirectory{
+		virtualEntry: ve,
+		stream:       it.dir.stream,
+		path:         path.Join(it.dir.path, name),
+	}
+
+	it.children[name] = d
+
+	return d
+}
+
+func (it *tarDirectoryIterator) newEntry(name string, h *tar.Header) fs.Entry {
+	ve := newTarVirtualEntry(name, h)
+
+	if h.Typeflag == tar.TypeDir {
+		ve.size = 0
+
+		return it.newDirectory(name, ve)
+	}
+
+	it.children[name] = nil
+
+	return newTarEntry(ve, h, &virtualFile{
+		virtualEntry: ve,
+		reader:       io.NopCloser(it.dir.stream.tr),
+	})
+}
+
+func (it *tarDirectoryIterator) Close() {
+}
+
+func newTarVirtualEntry(name string, h *tar.Header) virtualEntry {
+	return virtualEntry{
+		name:    name,
+		mode:    h.FileInfo().Mode(),
+		size:    h.Size,
+		modTime: h.ModTime,
+		owner: fs.OwnerInfo{
+			UserID:  uint32(h.Uid), //nolint:gosec
+			GroupID: uint32(h.Gid), //nolint:gosec
+		},
+	}
+}
+
+func isTarRegularFile(h *tar.Header) bool {
+	return h.Typeflag == tar.TypeReg || h.Typeflag == tar.TypeGNUSparse
+}
+
+// newTarEntry returns the entry for the provided header of an entry other than a directory, using the provided
+// file for regular files.
+func newTarEntry(ve virtualEntry, h *tar.Header, file fs.Entry) fs.Entry {
+	switch {
+	case isTarRegularFile(h):
+		return file
+
+	case h.Typeflag == tar.TypeSymlink:
+		return &tarSymlink{ve, h.Linkname}
+
+	default:
+		return &tarErrorEntry{ve, errors.Wrapf(fs.ErrUnknown, "unsupported tar entry type %q", h.Typeflag)}
+	}
+}
+
+// tarBufferedFile is a file from a tar archive whose contents have been read into memory.
+type tarBufferedFile struct {
+	virtualEntry
+	data []byte
+}
+
+func (f *tarBufferedFile) Open(ctx context.Context) (fs.Reader, error) {
+	return &tarBufferedFileReader{bytes.NewReader(f.data), f}, nil
+}
+
+type tarBufferedFileReader struct {
+	*bytes.Reader
+	f *tarBufferedFile
+}
+
+func (r *tarBufferedFileReader) Close() error {
+	return nil
+}
+
+func (r *tarBufferedFileReader) Entry() (fs.Entry, error) {
+	return r.f, nil
+}
+
+type tarSymlink struct {
+	virtualEntry
+	target string
+}
+
+func (sl *tarSymlink) Readlink(ctx context.Context) (string, error) {
+	return sl.target, nil
+}
+
+type tarErrorEntry struct {
+	virtualEntry
+	err error
+}
+
+func (e *tarErrorEntry) ErrorInfo() error {
+	return e.err
+}
+
+// NewTarDirectory returns a directory with the given name containing the tree of entries read from the provided tar stream.
+// Entries of each directory must be contiguous in the archive, and the returned directory and its subdirectories
+// must be iterated once, sequentially and depth-first, reading contents of each file before moving to the next entry.
+// Subdirectories which are not iterated and files which are not read are skipped. Children of a directory can only
+// be looked up before it's iterated and only among its small files and symlinks which precede its subdirectories,
+// so ignore files must be placed at the beginning of their directories in the archive.
+func NewTarDirectory(name string, r io.Reader) fs.Directory {
+	return &tarDirectory{
+		virtualEntry: virtualEntry{
+			name: name,
+			mode: defaultPermissions | os.ModeDir,
+		},
+		stream: &tarStream{tr: tar.NewReader(r)},
+	}
+}
+
+var (
+	_ fs.Directory  = &tarDirectory{}
+	_ fs.File       = &tarBufferedFile{}
+	_ fs.Symlink    = &tarSymlink{}
+	_ fs.ErrorEntry = &tarErrorEntry{}
+)
@@ -0,0 +1,271 @@
+package virtualfs
+
+import (
+	"archive/tar"
+	"bytes"
+	"context"
+	"io"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/kopia/kopia/fs"
+	"github.com/kopia/kopia/fs/ignorefs"
+	"github.com/kopia/kopia/internal/testlogging"
+	"github.com/kopia/kopia/snapshot/policy"
+)
+
+type tarTestEntry struct {
+	name     string
+	typeflag byte
+	contents string
+	linkname string
+}
+
+func makeTestTar(t *testing.T, entries []tarTestEntry) *bytes.Buffer {
+	t.Helper()
+
+	var buf bytes.Buffer
+
+	tw := tar.NewWriter(&buf)
+
+	for _, e := range entries {
+		require.NoError(t, tw.WriteHeader(&tar.Header{
+			Name:     e.name,
+			Typeflag: e.typeflag,
+			Size:     int64(len(e.contents)),
+			Linkname: e.linkname,
+			Mode:     0o640,
+			Uid:      1000,
+			Gid:      1001,
+			ModTime:  time.Date(2021, 1, 2, 3, 4, 5, 0, time.UTC),
+		}))
+
+		_, err := tw.Write([]byte(e.contents))
+		require.NoError(t, err)
+	}
+
+	require.NoError(t, tw.Close())
+
+	return &buf
+}
+
+// readTarTree reads the directory tree depth-first, skipping directories for which skip returns true.
+func readTarTree(ctx context.Context, t *testing.T, d fs.Directory, prefix string, skip func(string) bool, result map[string]string) error {
+	t.Helper()
+
+	return fs.IterateEntries(ctx, d, func(ctx context.Context, e fs.Entry) error {
+		p := prefix + e.Name()
+
+		switch e := e.(type) {
+		case fs.Directory:
+			result[p+"/"] = ""
+
+			if skip(p) {
+				return nil
+			}
+
+			return readTarTree(ctx, t, e, p+"/", skip, result)
+
+		case fs.StreamingFile:
+			r, err := e.GetReader(ctx)
+			require.NoError(t, err)
+
+			b, err := io.ReadAll(r)
+			require.NoError(t, err)
+
+			result[p] = string(b)
+
+		case fs.File:
+			r, err := e.Open(ctx)
+			require.NoError(t, err)
+
+			b, err := io.ReadAll(r)
+			require.NoError(t, err)
+			require.NoError(t, r.Close())
+
+			result[p] = string(b)
+
+		case fs.Symlink:
+			target, err := e.Readlink(ctx)
+			require.NoError(t, err)
+
+			result[p] = "->" + target
+
+		case fs.ErrorEntry:
+			require.ErrorIs(t, e.ErrorInfo(), fs.ErrUnknown)
+
+			result[p] = "error"
+		}
+
+		return nil
+	})
+}
+
+func TestTarDirectory(t *testing.T) {
+	ctx := testlogging.Context(t)
+
+	buf := makeTestTar(t, []tarTestEntry{
+		{name: "./", typeflag: tar.TypeDir},
+		{name: "./a.txt", typeflag: tar.TypeReg, contents: "aaa"},
+		{name: "./dir1/", typeflag: tar.TypeDir},
+		{name: "./dir1/b.txt", typeflag: tar.TypeReg, contents: "bbb"},
+		{name: "./dir1/sub/c.txt", typeflag: tar.TypeReg, contents: "ccc"},
+		{name: "./dir1/link", typeflag: tar.TypeSymlink, linkname: "b.txt"},
+		{name: "./dir2/d.txt", typeflag: tar.TypeReg, contents: "ddd"},
+		{name: "./dir2/fifo", typeflag: tar.TypeFifo},
+		{name: "./e.txt", typeflag: tar.TypeReg, contents: "eee"},
+	})
+
+	d := NewTarDirectory("root.tar", buf)
+	require.False(t, d.SupportsMultipleIterations())
+
+	result := map[string]string{}
+
+	require.NoError(t, readTarTree(ctx, t, d, "", func(string) bool { return false }, result))
+	require.Equal(t, map[string]string{
+		"a.txt":          "aaa",
+		"dir1/":          "",
+		"dir1/b.txt":     "bbb",
+		"dir1/sub/":      "",
+		"dir1/sub/c.txt": "ccc",
+		"dir1/link":      "->b.txt",
+		"dir2/":          "",
+		"dir2/d.txt":     "ddd",
+		"dir2/fifo":      "error",
+		"e.txt":          "eee",
+	}, result)
+
+	_, err := d.Iterate(ctx)
+	require.ErrorIs(t, err, errIteratorAlreadyUsed)
+}
+
+func TestTarDirectory_Metadata(t *testing.T) {
+	ctx := testlogging.Context(t)
+
+	entries, err := fs.GetAllEntries(ctx, NewTarDirectory("root.tar", makeTestTar(t, []tarTestEntry{
+		{name: "a.txt", typeflag: tar.TypeReg, contents: "aaa"},
+		{name: "dir/", typeflag: tar.TypeDir},
+	})))
+	require.NoError(t, err)
+	require.Len(t, entries, 2)
+
+	require.Equal(t, "a.txt", entries[0].Name())
+	require.Equal(t, os.FileMode(0o640), entries[0].Mode())
+	require.Equal(t, int64(3), entries[0].Size())
+	require.Equal(t, fs.OwnerInfo{UserID: 1000, GroupID: 1001}, entries[0].Owner())
+	require.Equal(t, time.Date(2021, 1, 2, 3, 4, 5, 0, time.UTC), entries[0].ModTime().UTC())
+
+	require.True(t, entries[1].IsDir())
+	require.Equal(t, os.ModeDir|0o640, entries[1].Mode())
+}
+
+func TestTarDirectory_SkippedDirectory(t *testing.T) {
+	ctx := testlogging.Context(t)
+
+	buf := makeTestTar(t, []tarTestEntry{
+		{name: "dir1/a.txt", typeflag: tar.TypeReg, contents: "aaa"},
+		{name: "dir1/sub/b.txt", typeflag: tar.TypeReg, contents: "bbb"},
+		{name: "dir2/c.txt", typeflag: tar.TypeReg, contents: "ccc"},
+	})
+
+	result := map[string]string{}
+
+	require.NoError(t, readTarTree(ctx, t, NewTarDirectory("root.tar", buf), "", func(p string) bool { return p == "dir1" }, result))
+	require.Equal(t, map[string]string{
+		"dir1/":      "",
+		"dir2/":      "",
+		"dir2/c.txt": "ccc",
+	}, result)
+}
+
+func TestTarDirectory_NonContiguous(t *testing.T) {
+	ctx := testlogging.Context(t)
+
+	buf := makeTestTar(t, []tarTestEntry{
+		{name: "dir1/a.txt", typeflag: tar.TypeReg, contents: "aaa"},
+		{name: "dir2/b.txt", typeflag: tar.TypeReg, contents: "bbb"},
+		{name: "dir1/c.txt", typeflag: tar.TypeReg, contents: "ccc"},
+	})
+
+	err := readTarTree(ctx, t, NewTarDirectory("root.tar", buf), "", func(string) bool { return false }, map[string]string{})
+	require.ErrorContains(t, err, `entries for "dir1" are not contiguous in tar stream`)
+}
+
+func TestTarDirectory_Child(t *testing.T) {
+	ctx := testlogging.Context(t)
+
+	buf := makeTestTar(t, []tarTestEntry{
+		{name: "a.txt", typeflag: tar.TypeReg, contents: "aaa"},
+		{name: "link", typeflag: tar.TypeSymlink, linkname: "a.txt"},
+		{name: "dir1/b.txt", typeflag: tar.TypeReg, contents: "bbb"},
+		{name: "dir1/c.txt", typeflag: tar.TypeReg, contents: "ccc"},
+		{name: "e.txt", typeflag: tar.TypeReg, contents: "eee"},
+	})
+
+	d := NewTarDirectory("root.tar", buf)
+
+	e, err := d.Child(ctx, "a.txt")
+	require.NoError(t, err)
+	require.Equal(t, int64(3), e.Size())
+
+	e, err = d.Child(ctx, "link")
+	require.NoError(t, err)
+	require.IsType(t, &tarSymlink{}, e)
+
+	// entries following a subdirectory can't be looked up.
+	_, err = d.Child(ctx, "e.txt")
+	require.ErrorIs(t, err, fs.ErrEntryNotFound)
+
+	_, err = d.Child(ctx, "dir1")
+	require.ErrorIs(t, err, fs.ErrEntryNotFound)
+
+	result := map[string]string{}
+
+	// entries read ahead are still returned when iterating.
+	require.NoError(t, readTarTree(ctx, t, d, "", func(string) bool { return false }, result))
+	require.Equal(t, map[string]string{
+		"a.txt":      "aaa",
+		"link":       "->a.txt",
+		"dir1/":      "",
+		"dir1/b.txt": "bbb",
+		"dir1/c.txt": "ccc",
+		"e.txt":      "eee",
+	}, result)
+
+	_, err = d.Child(ctx, "a.txt")
+	require.ErrorIs(t, err, errChildNotSupported)
+}
+
+func TestTarDirectory_IgnoreFiles(t *testing.T) {
+	ctx := testlogging.Context(t)
+
+	buf := makeTestTar(t, []tarTestEntry{
+		{name: ".kopiaignore", typeflag: tar.TypeReg, contents: "*.log\n"},
+		{name: "a.txt", typeflag: tar.TypeReg, contents: "aaa"},
+		{name: "a.log", typeflag: tar.TypeReg, contents: "log"},
+		{name: "dir1/.kopiaignore", typeflag: tar.TypeReg, contents: "b.txt\n"},
+		{name: "dir1/b.txt", typeflag: tar.TypeReg, contents: "bbb"},
+		{name: "dir1/c.log", typeflag: tar.TypeReg, contents: "ccc"},
+		{name: "dir1/d.txt", typeflag: tar.TypeReg, contents: "ddd"},
+	})
+
+	result := map[string]string{}
+
+	policyTree := policy.BuildTree(map[string]*policy.Policy{
+		".": {FilesPolicy: policy.FilesPolicy{DotIgnoreFiles: []string{".kopiaignore"}}},
+	}, policy.DefaultPolicy)
+
+	d := ignorefs.New(NewTarDirectory("root.tar", buf), policyTree)
+
+	require.NoError(t, readTarTree(ctx, t, d, "", func(string) bool { return false }, result))
+	require.Equal(t, map[string]string{
+		".kopiaignore":      "*.log\n",
+		"a.txt":             "aaa",
+		"dir1/":             "",
+		"dir1/.kopiaignore": "b.txt\n",
+		"dir1/d.txt":        "ddd",
+	}, result)
+}
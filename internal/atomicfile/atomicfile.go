@@ -49,3 +49,9 @@ func Write(filename string, r io.Reader) error {
 	//nolint:wrapcheck
 	return atomic.WriteFile(MaybePrefixLongFilenameOnWindows(filename), r)
 }
+
+// Replace atomically replaces the file with the provided temporary file, which must be on the same filesystem.
+func Replace(tempFilename, filename string) error {
+	//nolint:wrapcheck
+	return atomic.ReplaceFile(tempFilename, MaybePrefixLongFilenameOnWindows(filename))
+}
@@ -0,0 +1,137 @@
+package blobtesting
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+
+	"github.com/kopia/kopia/internal/gather"
+	"github.com/kopia/kopia/repo/blob"
+)
+
+// Journaled operations.
+const (
+	JournalOpPutBlob    = "PutBlob"
+	JournalOpDeleteBlob = "DeleteBlob"
+)
+
+// JournalEntry describes a single successful operation which modified the storage.
+type JournalEntry struct {
+	Seq       int       `json:"seq"`
+	Op        string    `json:"op"`
+	BlobID    blob.ID   `json:"id"`
+	Data      []byte    `json:"data,omitempty"`
+	Timestamp time.Time `json:"timestamp"`
+}
+
+type journalingStorage struct {
+	blob.Storage
+
+	mu sync.Mutex
+	// +checklocks:mu
+	enc *json.Encoder
+	// +checklocks:mu
+	nextSeq int
+}
+
+func (s *journalingStorage) PutBlob(ctx context.Context, id blob.ID, data blob.Bytes, opts blob.PutOptions) error {
+	var buf bytes.Buffer
+
+	if _, err := data.WriteTo(&buf); err != nil {
+		return errors.Wrap(err, "error reading blob data")
+	}
+
+	var mtime time.Time
+
+	callerGetModTime := opts.GetModTime
+	opts.GetModTime = &mtime
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	// journal entries are written while holding the lock, so that their order matches the order of writes.
+	if err := s.Storage.PutBlob(ctx, id, gather.FromSlice(buf.Bytes()), opts); err != nil {
+		//nolint:wrapcheck
+		return err
+	}
+
+	if callerGetModTime != nil {
+		*callerGetModTime = mtime
+	}
+
+	return s.appendLocked(JournalEntry{Op: JournalOpPutBlob, BlobID: id, Data: buf.Bytes(), Timestamp: mtime})
+}
+
+func (s *journalingStorage) DeleteBlob(ctx context.Context, id blob.ID) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if err := s.Storage.DeleteBlob(ctx, id); err != nil {
+		//nolint:wrapcheck
+		return err
+	}
+
+	return s.appendLocked(JournalEntry{Op: JournalOpDeleteBlob, BlobID: id})
+}
+
+// +checklocks:s.mu
+func (s *journalingStorage) appendLocked(e JournalEntry) error {
+	s.nextSeq++
+	e.Seq = s.nextSeq
+
+	return errors.Wrap(s.enc.Encode(e), "error writing journal entry")
+}
+
+// NewJournalingStorage returns a wrapper that writes all successful operations modifying the provided storage
+// to the journal, one JSON-encoded JournalEntry per line. Modifications are serialized, so that replaying
+// the journal using ReplayJournal() on top of the initial state of the storage reproduces its state exactly.
+func NewJournalingStorage(st blob.Storage, journal io.Writer) blob.Storage {
+	return &journalingStorage{
+		Storage: st,
+		enc:     json.NewEncoder(journal),
+	}
+}
+
+// ReplayJournal applies up to maxEntries operations from the journal written by NewJournalingStorage() to
+// the provided storage, or all of them if maxEntries is negative, and returns the number of replayed entries.
+func ReplayJournal(ctx context.Context, st blob.Storage, journal io.Reader, maxEntries int) (int, error) {
+	dec := json.NewDecoder(journal)
+
+	for n := 0; maxEntries < 0 || n < maxEntries; n++ {
+		var e JournalEntry
+
+		if err := dec.Decode(&e); err != nil {
+			if errors.Is(err, io.EOF) {
+				return n, nil
+			}
+
+			return n, errors.Wrapf(err, "error reading journal entry #%v", n+1)
+		}
+
+		if err := replayJournalEntry(ctx, st, e); err != nil {
+			return n, errors.Wrapf(err, "error replaying journal entry #%v", e.Seq)
+		}
+	}
+
+	return maxEntries, nil
+}
+
+func replayJournalEntry(ctx context.Context, st blob.Storage, e JournalEntry) error {
+	switch e.Op {
+	case JournalOpPutBlob:
+		//nolint:wrapcheck
+		return st.PutBlob(ctx, e.BlobID, gather.FromSlice(e.Data), blob.PutOptions{SetModTime: e.Timestamp})
+
+	case JournalOpDeleteBlob:
+		//nolint:wrapcheck
+		return st.DeleteBlob(ctx, e.BlobID)
+
+	default:
+		return errors.Errorf("unsupported operation %q", e.Op)
+	}
+}
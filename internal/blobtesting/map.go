@@ -197,6 +197,19 @@ func (s *mapStorage) TouchBlob(ctx context.Context, blobID blob.ID, threshold ti
 	return s.keyTime[blobID], nil
 }
 
+func (s *mapStorage) SetBlobModTime(ctx context.Context, blobID blob.ID, mtime time.Time) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	if _, ok := s.keyTime[blobID]; !ok {
+		return blob.ErrBlobNotFound
+	}
+
+	s.keyTime[blobID] = mtime
+
+	return nil
+}
+
 func (s *mapStorage) ConnectionInfo() blob.ConnectionInfo {
 	// unsupported
 	return blob.ConnectionInfo{}
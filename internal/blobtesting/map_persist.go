@@ -0,0 +1,108 @@
+package blobtesting
+
+import (
+	"encoding/json"
+	"io"
+	"os"
+	"sort"
+	"time"
+
+	"github.com/pkg/errors"
+
+	"github.com/kopia/kopia/repo/blob"
+)
+
+// persistedMapStorage is the serialized state of a map storage.
+type persistedMapStorage struct {
+	Limit int64              `json:"limit"`
+	Blobs []persistedMapBlob `json:"blobs"`
+}
+
+type persistedMapBlob struct {
+	ID        blob.ID   `json:"id"`
+	Data      []byte    `json:"data"`
+	Timestamp time.Time `json:"timestamp"`
+}
+
+// WriteMapStorageState writes the full state of the storage returned by NewMapStorage() to the provided writer.
+func WriteMapStorageState(st blob.Storage, w io.Writer) error {
+	ms, ok := st.(*mapStorage)
+	if !ok {
+		return errors.Errorf("unsupported storage type %T", st)
+	}
+
+	ms.mutex.RLock()
+
+	state := persistedMapStorage{Limit: ms.limit}
+
+	for id, data := range ms.data {
+		if data == nil {
+			data = []byte{}
+		}
+
+		state.Blobs = append(state.Blobs, persistedMapBlob{
+			ID:        id,
+			Data:      data,
+			Timestamp: ms.keyTime[id],
+		})
+	}
+
+	ms.mutex.RUnlock()
+
+	// sort blobs so that identical states are serialized identically.
+	sort.Slice(state.Blobs, func(i, j int) bool {
+		return state.Blobs[i].ID < state.Blobs[j].ID
+	})
+
+	return errors.Wrap(json.NewEncoder(w).Encode(state), "error writing map storage state")
+}
+
+// ReadMapStorageState returns new map storage with the state previously written using WriteMapStorageState().
+func ReadMapStorageState(r io.Reader, timeNow func() time.Time) (blob.Storage, error) {
+	var state persistedMapStorage
+
+	if err := json.NewDecoder(r).Decode(&state); err != nil {
+		return nil, errors.Wrap(err, "error reading map storage state")
+	}
+
+	data := DataMap{}
+	keyTime := map[blob.ID]time.Time{}
+
+	for _, b := range state.Blobs {
+		if b.Data == nil {
+			b.Data = []byte{}
+		}
+
+		data[b.ID] = b.Data
+		keyTime[b.ID] = b.Timestamp
+	}
+
+	return NewMapStorageWithLimit(data, keyTime, timeNow, state.Limit), nil
+}
+
+// SaveMapStorage saves the full state of the storage returned by NewMapStorage() to the provided file.
+func SaveMapStorage(st blob.Storage, fname string) error {
+	f, err := os.Create(fname) //nolint:gosec
+	if err != nil {
+		return errors.Wrap(err, "error creating file")
+	}
+
+	if err := WriteMapStorageState(st, f); err != nil {
+		f.Close() //nolint:errcheck
+		return err
+	}
+
+	return errors.Wrap(f.Close(), "error closing file")
+}
+
+// LoadMapStorage returns new map storage with the state previously saved to the provided file using SaveMapStorage().
+func LoadMapStorage(fname string, timeNow func() time.Time) (blob.Storage, error) {
+	f, err := os.Open(fname) //nolint:gosec
+	if err != nil {
+		return nil, errors.Wrap(err, "error opening file")
+	}
+
+	defer f.Close() //nolint:errcheck
+
+	return ReadMapStorageState(f, timeNow)
+}
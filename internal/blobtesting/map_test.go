@@ -1,10 +1,16 @@
 package blobtesting
 
 import (
+	"bytes"
+	"context"
+	"os"
+	"path/filepath"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/require"
 
+	"github.com/kopia/kopia/internal/faketime"
 	"github.com/kopia/kopia/internal/gather"
 	"github.com/kopia/kopia/internal/testlogging"
 	"github.com/kopia/kopia/repo/blob"
@@ -63,3 +69,116 @@ func verifyCapacityAndFreeSpace(t *testing.T, r blob.Storage, wantSize, wantFree
 	require.Equal(t, uint64(wantSize), c.SizeB)
 	require.Equal(t, uint64(wantFree), c.FreeB)
 }
+
+func TestMapStorageSaveLoad(t *testing.T) {
+	ctx := testlogging.Context(t)
+	t0 := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	r := NewMapStorageWithLimit(DataMap{}, nil, faketime.Frozen(t0), 100)
+	require.NoError(t, r.PutBlob(ctx, "foo", gather.FromSlice([]byte("foo")), blob.PutOptions{}))
+	require.NoError(t, r.PutBlob(ctx, "bar", gather.FromSlice([]byte("bar")), blob.PutOptions{SetModTime: t0.Add(time.Hour)}))
+	require.NoError(t, r.PutBlob(ctx, "empty", gather.FromSlice(nil), blob.PutOptions{}))
+
+	fname := filepath.Join(t.TempDir(), "state.json")
+	require.NoError(t, SaveMapStorage(r, fname))
+
+	r2, err := LoadMapStorage(fname, nil)
+	require.NoError(t, err)
+
+	requireSameBlobs(ctx, t, r, r2)
+	verifyCapacityAndFreeSpace(t, r2, 100, 94)
+
+	// saving restored storage produces identical state.
+	fname2 := filepath.Join(t.TempDir(), "state2.json")
+	require.NoError(t, SaveMapStorage(r2, fname2))
+
+	b1, err := os.ReadFile(fname)
+	require.NoError(t, err)
+
+	b2, err := os.ReadFile(fname2)
+	require.NoError(t, err)
+
+	require.Equal(t, b1, b2)
+
+	require.Error(t, SaveMapStorage(NewFaultyStorage(r), fname))
+}
+
+func TestJournalingStorage(t *testing.T) {
+	ctx := testlogging.Context(t)
+	ta := faketime.NewTimeAdvance(time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC))
+
+	initial := DataMap{"existing": []byte("existing")}
+	r := NewMapStorage(initial, nil, ta.NowFunc())
+
+	var checkpoint, journal bytes.Buffer
+
+	require.NoError(t, WriteMapStorageState(r, &checkpoint))
+
+	jr := NewJournalingStorage(r, &journal)
+
+	var mtime time.Time
+
+	ta.Advance(time.Minute)
+	require.NoError(t, jr.PutBlob(ctx, "foo", gather.FromSlice([]byte("foo")), blob.PutOptions{GetModTime: &mtime}))
+	require.Equal(t, ta.NowFunc()(), mtime)
+
+	ta.Advance(time.Minute)
+	require.NoError(t, jr.PutBlob(ctx, "bar", gather.FromSlice([]byte("bar")), blob.PutOptions{}))
+	require.NoError(t, jr.DeleteBlob(ctx, "existing"))
+
+	// failed operations are not journaled.
+	require.Error(t, jr.PutBlob(ctx, "baz", gather.FromSlice([]byte("baz")), blob.PutOptions{DoNotRecreate: true}))
+
+	ta.Advance(time.Minute)
+	require.NoError(t, jr.PutBlob(ctx, "foo", gather.FromSlice([]byte("foo2")), blob.PutOptions{}))
+
+	// replaying the journal on top of the checkpoint reproduces the final state.
+	journalData := journal.Bytes()
+
+	replayed, err := ReadMapStorageState(bytes.NewReader(checkpoint.Bytes()), nil)
+	require.NoError(t, err)
+
+	n, err := ReplayJournal(ctx, replayed, bytes.NewReader(journalData), -1)
+	require.NoError(t, err)
+	require.Equal(t, 4, n)
+
+	requireSameBlobs(ctx, t, r, replayed)
+
+	// partial replay.
+	replayed, err = ReadMapStorageState(bytes.NewReader(checkpoint.Bytes()), nil)
+	require.NoError(t, err)
+
+	n, err = ReplayJournal(ctx, replayed, bytes.NewReader(journalData), 2)
+	require.NoError(t, err)
+	require.Equal(t, 2, n)
+
+	all, err := blob.ListAllBlobs(ctx, replayed, "")
+	require.NoError(t, err)
+	require.Len(t, all, 3)
+
+	_, err = ReplayJournal(ctx, replayed, bytes.NewReader([]byte(`{"seq":1,"op":"Unknown"}`)), -1)
+	require.ErrorContains(t, err, "unsupported operation")
+}
+
+func requireSameBlobs(ctx context.Context, t *testing.T, want, got blob.Storage) {
+	t.Helper()
+
+	wantBlobs, err := blob.ListAllBlobs(ctx, want, "")
+	require.NoError(t, err)
+
+	gotBlobs, err := blob.ListAllBlobs(ctx, got, "")
+	require.NoError(t, err)
+
+	require.Equal(t, wantBlobs, gotBlobs)
+
+	for _, bm := range wantBlobs {
+		var w, g gather.WriteBuffer
+
+		require.NoError(t, want.GetBlob(ctx, bm.BlobID, 0, -1, &w))
+		require.NoError(t, got.GetBlob(ctx, bm.BlobID, 0, -1, &g))
+		require.Equal(t, w.ToByteSlice(), g.ToByteSlice())
+
+		w.Close()
+		g.Close()
+	}
+}
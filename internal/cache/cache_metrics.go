@@ -10,14 +10,18 @@ type metricsStruct struct {
 	metricMissBytes               *metrics.Counter
 	metricMissErrors              *metrics.Counter
 	metricStoreErrors             *metrics.Counter
+	metricEvictedCount            *metrics.Counter
+	metricEvictedBytes            *metrics.Counter
+
+	stats statsCounters
 }
 
-func initMetricsStruct(mr *metrics.Registry, cacheID string) metricsStruct {
+func (s *metricsStruct) initMetrics(mr *metrics.Registry, cacheID string) {
 	labels := map[string]string{
 		"cache": cacheID,
 	}
 
-	return metricsStruct{
+	*s = metricsStruct{
 		metricHitCount: mr.CounterInt64(
 			"cache_hit",
 			"Number of time content was retrieved from the cache", labels),
@@ -45,27 +49,49 @@ func initMetricsStruct(mr *metrics.Registry, cacheID string) metricsStruct {
 		metricStoreErrors: mr.CounterInt64(
 			"cache_store_errors",
 			"Number of time content could not be saved in the cache", labels),
+
+		metricEvictedCount: mr.CounterInt64(
+			"cache_evicted",
+			"Number of items removed from the cache to stay within its size limits", labels),
+
+		metricEvictedBytes: mr.CounterInt64(
+			"cache_evicted_bytes",
+			"Number of bytes removed from the cache to stay within its size limits", labels),
 	}
 }
 
 func (s *metricsStruct) reportMissError() {
 	s.metricMissErrors.Add(1)
+	s.stats.missErrors.Add(1)
 }
 
 func (s *metricsStruct) reportMissBytes(length int64) {
 	s.metricMissCount.Add(1)
 	s.metricMissBytes.Add(length)
+	s.stats.missCount.Add(1)
+	s.stats.missBytes.Add(length)
 }
 
 func (s *metricsStruct) reportHitBytes(length int64) {
 	s.metricHitCount.Add(1)
 	s.metricHitBytes.Add(length)
+	s.stats.hitCount.Add(1)
+	s.stats.hitBytes.Add(length)
 }
 
 func (s *metricsStruct) reportMalformedData() {
 	s.metricMalformedCacheDataCount.Add(1)
+	s.stats.malformedCount.Add(1)
 }
 
 func (s *metricsStruct) reportStoreError() {
 	s.metricStoreErrors.Add(1)
+	s.stats.storeErrors.Add(1)
+}
+
+func (s *metricsStruct) reportEvicted(length int64) {
+	s.metricEvictedCount.Add(1)
+	s.metricEvictedBytes.Add(length)
+	s.stats.evictedCount.Add(1)
+	s.stats.evictedBytes.Add(length)
 }
@@ -0,0 +1,149 @@
+package cache
+
+import (
+	"bytes"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"sync/atomic"
+
+	"github.com/gofrs/flock"
+	"github.com/pkg/errors"
+
+	"github.com/kopia/kopia/internal/atomicfile"
+)
+
+// StatsFileName is the name of the file in the cache directory which accumulates cache statistics
+// across all processes using the cache.
+const StatsFileName = "cache-stats.json"
+
+// Stats contains statistics of a single cache.
+type Stats struct {
+	HitCount       int64 `json:"hits,omitempty"`
+	HitBytes       int64 `json:"hitBytes,omitempty"`
+	MissCount      int64 `json:"misses,omitempty"`
+	MissBytes      int64 `json:"missBytes,omitempty"`
+	MissErrors     int64 `json:"missErrors,omitempty"`
+	StoreErrors    int64 `json:"storeErrors,omitempty"`
+	MalformedCount int64 `json:"malformed,omitempty"`
+	EvictedCount   int64 `json:"evicted,omitempty"`
+	EvictedBytes   int64 `json:"evictedBytes,omitempty"`
+}
+
+// Add adds the provided statistics to s.
+func (s *Stats) Add(other Stats) {
+	s.HitCount += other.HitCount
+	s.HitBytes += other.HitBytes
+	s.MissCount += other.MissCount
+	s.MissBytes += other.MissBytes
+	s.MissErrors += other.MissErrors
+	s.StoreErrors += other.StoreErrors
+	s.MalformedCount += other.MalformedCount
+	s.EvictedCount += other.EvictedCount
+	s.EvictedBytes += other.EvictedBytes
+}
+
+// IsZero returns true if no cache activity has been recorded.
+func (s Stats) IsZero() bool {
+	return s == Stats{}
+}
+
+// HitRatio returns the fraction of lookups which were served from the cache.
+func (s Stats) HitRatio() float64 {
+	if total := s.HitCount + s.MissCount; total > 0 {
+		return float64(s.HitCount) / float64(total)
+	}
+
+	return 0
+}
+
+// statsCounters tracks cache statistics of the current process, regardless of whether metrics registry is used.
+type statsCounters struct {
+	hitCount       atomic.Int64
+	hitBytes       atomic.Int64
+	missCount      atomic.Int64
+	missBytes      atomic.Int64
+	missErrors     atomic.Int64
+	storeErrors    atomic.Int64
+	malformedCount atomic.Int64
+	evictedCount   atomic.Int64
+	evictedBytes   atomic.Int64
+}
+
+func (s *statsCounters) snapshot() Stats {
+	return Stats{
+		HitCount:       s.hitCount.Load(),
+		HitBytes:       s.hitBytes.Load(),
+		MissCount:      s.missCount.Load(),
+		MissBytes:      s.missBytes.Load(),
+		MissErrors:     s.missErrors.Load(),
+		StoreErrors:    s.storeErrors.Load(),
+		MalformedCount: s.malformedCount.Load(),
+		EvictedCount:   s.evictedCount.Load(),
+		EvictedBytes:   s.evictedBytes.Load(),
+	}
+}
+
+// LoadStats returns statistics of all caches in the provided cache directory, keyed by cache name.
+func LoadStats(cacheDir string) (map[string]Stats, error) {
+	result := map[string]Stats{}
+
+	b, err := os.ReadFile(filepath.Join(cacheDir, StatsFileName)) //nolint:gosec
+	if os.IsNotExist(err) {
+		return result, nil
+	}
+
+	if err != nil {
+		return nil, errors.Wrap(err, "unable to read cache statistics")
+	}
+
+	if err := json.Unmarshal(b, &result); err != nil {
+		return nil, errors.Wrap(err, "invalid cache statistics")
+	}
+
+	return result, nil
+}
+
+// AddStats adds the provided statistics to the ones accumulated in the cache directory.
+func AddStats(cacheDir string, stats map[string]Stats) error {
+	return updateStats(cacheDir, func(all map[string]Stats) {
+		for k, v := range stats {
+			s := all[k]
+			s.Add(v)
+			all[k] = s
+		}
+	})
+}
+
+// ResetStats clears statistics accumulated in the cache directory.
+func ResetStats(cacheDir string) error {
+	return updateStats(cacheDir, func(all map[string]Stats) {
+		clear(all)
+	})
+}
+
+func updateStats(cacheDir string, update func(all map[string]Stats)) error {
+	fname := filepath.Join(cacheDir, StatsFileName)
+
+	// the file is shared by all processes using the cache.
+	l := flock.New(fname + ".lock")
+	if err := l.Lock(); err != nil {
+		return errors.Wrap(err, "unable to lock cache statistics")
+	}
+
+	defer l.Unlock() //nolint:errcheck
+
+	all, err := LoadStats(cacheDir)
+	if err != nil {
+		return err
+	}
+
+	update(all)
+
+	b, err := json.MarshalIndent(all, "", "  ")
+	if err != nil {
+		return errors.Wrap(err, "unable to serialize cache statistics")
+	}
+
+	return errors.Wrap(atomicfile.Write(fname, bytes.NewReader(b)), "unable to write cache statistics")
+}
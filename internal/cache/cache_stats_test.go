@@ -0,0 +1,81 @@
+package cache_test
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/kopia/kopia/internal/blobtesting"
+	"github.com/kopia/kopia/internal/cache"
+	"github.com/kopia/kopia/internal/clock"
+	"github.com/kopia/kopia/internal/gather"
+	"github.com/kopia/kopia/internal/testlogging"
+	"github.com/kopia/kopia/internal/testutil"
+)
+
+func TestPersistentCacheStats(t *testing.T) {
+	ctx := testlogging.Context(t)
+
+	cs := blobtesting.NewMapStorage(blobtesting.DataMap{}, nil, nil).(cache.Storage)
+
+	pc, err := cache.NewPersistentCache(ctx, "testing", cs, nil, cache.SweepSettings{
+		MaxSizeBytes: 500,
+	}, nil, clock.Now)
+	require.NoError(t, err)
+
+	var tmp gather.WriteBuffer
+	defer tmp.Close()
+
+	someData := bytes.Repeat([]byte{1}, 300)
+
+	require.False(t, pc.GetFull(ctx, "key1", &tmp))
+	pc.Put(ctx, "key1", gather.FromSlice(someData))
+	require.True(t, pc.GetFull(ctx, "key1", &tmp))
+	require.True(t, pc.GetFull(ctx, "key1", &tmp))
+
+	// exceeds the cache size, causing key1 to be evicted.
+	pc.Put(ctx, "key2", gather.FromSlice(someData))
+	pc.Close(ctx)
+
+	s := pc.Stats()
+	require.EqualValues(t, 2, s.HitCount)
+	require.EqualValues(t, 600, s.HitBytes)
+	require.EqualValues(t, 1, s.MissCount)
+	require.EqualValues(t, 1, s.EvictedCount)
+	require.EqualValues(t, 300, s.EvictedBytes)
+	require.InDelta(t, 2.0/3, s.HitRatio(), 0.001)
+
+	var nilCache *cache.PersistentCache
+
+	require.True(t, nilCache.Stats().IsZero())
+}
+
+func TestAccumulatedStats(t *testing.T) {
+	dir := testutil.TempDirectory(t)
+
+	stats, err := cache.LoadStats(dir)
+	require.NoError(t, err)
+	require.Empty(t, stats)
+
+	require.NoError(t, cache.AddStats(dir, map[string]cache.Stats{
+		"contents": {HitCount: 3, HitBytes: 300, MissCount: 1},
+		"metadata": {MissCount: 2},
+	}))
+	require.NoError(t, cache.AddStats(dir, map[string]cache.Stats{
+		"contents": {HitCount: 1, HitBytes: 100, EvictedCount: 1},
+	}))
+
+	stats, err = cache.LoadStats(dir)
+	require.NoError(t, err)
+	require.Equal(t, map[string]cache.Stats{
+		"contents": {HitCount: 4, HitBytes: 400, MissCount: 1, EvictedCount: 1},
+		"metadata": {MissCount: 2},
+	}, stats)
+
+	require.NoError(t, cache.ResetStats(dir))
+
+	stats, err = cache.LoadStats(dir)
+	require.NoError(t, err)
+	require.Empty(t, stats)
+}
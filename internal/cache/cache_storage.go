@@ -27,6 +27,12 @@ type Storage interface {
 	TouchBlob(ctx context.Context, contentID blob.ID, threshold time.Duration) (time.Time, error)
 }
 
+// modTimeSetter is implemented by cache storage that can set modification times of cached items to arbitrary
+// times, which allows the cost-aware eviction policy to persist the retention of frequently hit items.
+type modTimeSetter interface {
+	SetBlobModTime(ctx context.Context, blobID blob.ID, mtime time.Time) error
+}
+
 // NewStorageOrNil returns cache.Storage backed by the provided directory.
 func NewStorageOrNil(ctx context.Context, cacheDir string, maxBytes int64, subdir string) (Storage, error) {
 	if maxBytes <= 0 || cacheDir == "" {
@@ -19,6 +19,8 @@ type ContentCache interface {
 	GetContent(ctx context.Context, contentID string, blobID blob.ID, offset, length int64, output *gather.WriteBuffer) error
 	PrefetchBlob(ctx context.Context, blobID blob.ID) error
 	CacheStorage() Storage
+	Stats() Stats
+	SetSizeLimits(ctx context.Context, maxSizeBytes, limitBytes int64)
 }
 
 // Options encapsulates all content cache options.
@@ -28,6 +30,7 @@ type Options struct {
 	Storage            Storage // force particular storage, used for testing
 	HMACSecret         []byte
 	FetchFullBlobs     bool
+	ReadAheadBytes     int64 // prefetch this many bytes into the cache when sequential reads of a blob are detected
 	Sweep              SweepSettings
 	TimeNow            func() time.Time
 }
@@ -36,6 +39,7 @@ type contentCacheImpl struct {
 	pc             *PersistentCache
 	st             blob.Storage
 	fetchFullBlobs bool
+	readAhead      *readAhead // nil if disabled
 }
 
 // ContentIDCacheKey computes the cache key for the provided content ID.
@@ -120,6 +124,10 @@ func (c *contentCacheImpl) getContentFromFullOrPartialBlob(ctx context.Context,
 		return nil
 	}
 
+	if c.readAhead != nil && c.readAhead.getContent(ctx, blobID, offset, length, output) {
+		return nil
+	}
+
 	// acquire exclusive lock on the content
 	c.pc.exclusiveLock(contentID)
 	defer c.pc.exclusiveUnlock(contentID)
@@ -144,6 +152,10 @@ func (c *contentCacheImpl) getContentFromFullOrPartialBlob(ctx context.Context,
 }
 
 func (c *contentCacheImpl) Close(ctx context.Context) {
+	if c.readAhead != nil {
+		c.readAhead.close()
+	}
+
 	c.pc.Close(ctx)
 }
 
@@ -167,6 +179,14 @@ func (c *contentCacheImpl) PrefetchBlob(ctx context.Context, blobID blob.ID) err
 	return c.fetchBlobInternal(ctx, blobID, &blobData)
 }
 
+func (c *contentCacheImpl) Stats() Stats {
+	return c.pc.Stats()
+}
+
+func (c *contentCacheImpl) SetSizeLimits(ctx context.Context, maxSizeBytes, limitBytes int64) {
+	c.pc.SetSizeLimits(ctx, maxSizeBytes, limitBytes)
+}
+
 func (c *contentCacheImpl) CacheStorage() Storage {
 	return c.pc.cacheStorage
 }
@@ -192,9 +212,15 @@ func NewContentCache(ctx context.Context, st blob.Storage, opt Options, mr *metr
 		return nil, errors.Wrap(err, "unable to create base cache")
 	}
 
-	return &contentCacheImpl{
+	c := &contentCacheImpl{
 		st:             st,
 		pc:             pc,
 		fetchFullBlobs: opt.FetchFullBlobs,
-	}, nil
+	}
+
+	if !opt.FetchFullBlobs {
+		c.readAhead = newReadAhead(st, pc, opt.ReadAheadBytes)
+	}
+
+	return c, nil
 }
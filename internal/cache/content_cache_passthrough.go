@@ -33,6 +33,12 @@ func (c passthroughContentCache) Sync(ctx context.Context, blobPrefix blob.ID) e
 	return nil
 }
 
+func (c passthroughContentCache) Stats() Stats {
+	return Stats{}
+}
+
+func (c passthroughContentCache) SetSizeLimits(ctx context.Context, maxSizeBytes, limitBytes int64) {}
+
 func (c passthroughContentCache) CacheStorage() Storage {
 	return nil
 }
@@ -0,0 +1,233 @@
+package cache
+
+import (
+	"container/list"
+	"context"
+	"fmt"
+	"sync"
+
+	"github.com/kopia/kopia/internal/gather"
+	"github.com/kopia/kopia/repo/blob"
+)
+
+const (
+	readAheadMaxStreams         = 16
+	readAheadMinSequentialReads = 2
+	readAheadMaxGapBytes        = 64 << 10
+)
+
+// readAhead detects sequential reads of ranges of the same blob, such as reads of consecutive contents
+// of a pack blob during restore, and asynchronously prefetches the following range of the blob into the cache,
+// which reduces the number of round trips to high-latency storage.
+type readAhead struct {
+	st    blob.Storage
+	pc    *PersistentCache
+	bytes int64
+
+	wg sync.WaitGroup
+
+	mu sync.Mutex
+	// +checklocks:mu
+	closed bool
+	// +checklocks:mu
+	streams map[blob.ID]*list.Element
+	// +checklocks:mu
+	lru *list.List // of *readAheadStream, most recently used at the front
+}
+
+// readAheadStream tracks sequential reads of a single blob and the ranges prefetched into the cache.
+type readAheadStream struct {
+	id blob.ID
+
+	nextOffset      int64 // offset immediately after the last read, -1 if there was none
+	sequentialReads int
+	blobLength      int64 // -1 if not known
+
+	ranges   []readAheadRange // ranges prefetched into the cache, most recent last
+	fetching bool
+}
+
+type readAheadRange struct {
+	offset, length int64
+}
+
+func (r readAheadRange) contains(offset, length int64) bool {
+	return offset >= r.offset && offset+length <= r.offset+r.length
+}
+
+func (r readAheadRange) cacheKey(id blob.ID) string {
+	return fmt.Sprintf("%v-%x", BlobIDCacheKey(id), r.offset)
+}
+
+// getContent returns the provided range of the blob from the cache if it was prefetched before.
+// In any case, it records the read and starts prefetching the following range once sequential reads are detected.
+func (r *readAhead) getContent(ctx context.Context, id blob.ID, offset, length int64, output *gather.WriteBuffer) bool {
+	rng, ok := r.recordRead(ctx, id, offset, length)
+	if !ok {
+		return false
+	}
+
+	output.Reset()
+
+	return r.pc.GetPartial(ctx, rng.cacheKey(id), offset-rng.offset, length, output)
+}
+
+func (r *readAhead) recordRead(ctx context.Context, id blob.ID, offset, length int64) (readAheadRange, bool) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	st := r.getOrAddStreamLocked(id)
+
+	if st.nextOffset >= 0 && offset >= st.nextOffset && offset-st.nextOffset <= readAheadMaxGapBytes {
+		st.sequentialReads++
+	} else {
+		st.sequentialReads = 0
+	}
+
+	st.nextOffset = offset + length
+
+	if st.sequentialReads >= readAheadMinSequentialReads {
+		r.maybeStartFetchLocked(ctx, st)
+	}
+
+	for _, rng := range st.ranges {
+		if rng.contains(offset, length) {
+			return rng, true
+		}
+	}
+
+	return readAheadRange{}, false
+}
+
+// maybeStartFetchLocked starts prefetching the range following the most recent one, once fewer than half of
+// the read-ahead bytes remain to be read.
+//
+// +checklocks:r.mu
+func (r *readAhead) maybeStartFetchLocked(ctx context.Context, st *readAheadStream) {
+	if st.fetching || r.closed {
+		return
+	}
+
+	start := st.nextOffset
+
+	if n := len(st.ranges); n > 0 {
+		// continue after the prefetched ranges if the read is within them.
+		if end := st.ranges[n-1].offset + st.ranges[n-1].length; start < end && start >= st.ranges[0].offset {
+			if end-start > r.bytes/2 { //nolint:mnd
+				return
+			}
+
+			start = end
+		}
+	}
+
+	if st.blobLength >= 0 && start >= st.blobLength {
+		return
+	}
+
+	st.fetching = true
+
+	r.wg.Add(1)
+
+	go func() {
+		defer r.wg.Done()
+
+		r.fetch(context.WithoutCancel(ctx), st, start)
+	}()
+}
+
+func (r *readAhead) fetch(ctx context.Context, st *readAheadStream, start int64) {
+	rng, err := r.fetchRange(ctx, st, start)
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	st.fetching = false
+
+	if err != nil {
+		log(ctx).Debugf("read-ahead of %v failed: %v", st.id, err)
+		return
+	}
+
+	// keep the previous range, which may still be being read.
+	st.ranges = append(st.ranges[max(0, len(st.ranges)-1):], rng)
+}
+
+func (r *readAhead) fetchRange(ctx context.Context, st *readAheadStream, start int64) (readAheadRange, error) {
+	r.mu.Lock()
+	blobLength := st.blobLength
+	r.mu.Unlock()
+
+	if blobLength < 0 {
+		bm, err := r.st.GetMetadata(ctx, st.id)
+		if err != nil {
+			return readAheadRange{}, err //nolint:wrapcheck
+		}
+
+		blobLength = bm.Length
+
+		r.mu.Lock()
+		st.blobLength = blobLength
+		r.mu.Unlock()
+	}
+
+	rng := readAheadRange{start, min(r.bytes, blobLength-start)}
+	if rng.length <= 0 {
+		return readAheadRange{}, blob.ErrInvalidRange
+	}
+
+	var data gather.WriteBuffer
+	defer data.Close()
+
+	if err := r.st.GetBlob(ctx, st.id, rng.offset, rng.length, &data); err != nil {
+		return readAheadRange{}, err //nolint:wrapcheck
+	}
+
+	r.pc.reportMissBytes(int64(data.Length()))
+	r.pc.Put(ctx, rng.cacheKey(st.id), data.Bytes())
+
+	return rng, nil
+}
+
+// +checklocks:r.mu
+func (r *readAhead) getOrAddStreamLocked(id blob.ID) *readAheadStream {
+	if e, ok := r.streams[id]; ok {
+		r.lru.MoveToFront(e)
+
+		return e.Value.(*readAheadStream) //nolint:forcetypeassert
+	}
+
+	st := &readAheadStream{id: id, nextOffset: -1, blobLength: -1}
+	r.streams[id] = r.lru.PushFront(st)
+
+	for r.lru.Len() > readAheadMaxStreams {
+		oldest := r.lru.Back()
+		delete(r.streams, oldest.Value.(*readAheadStream).id) //nolint:forcetypeassert
+		r.lru.Remove(oldest)
+	}
+
+	return st
+}
+
+// close waits for prefetches in progress and prevents new ones from starting.
+func (r *readAhead) close() {
+	r.mu.Lock()
+	r.closed = true
+	r.mu.Unlock()
+
+	r.wg.Wait()
+}
+
+func newReadAhead(st blob.Storage, pc *PersistentCache, readAheadBytes int64) *readAhead {
+	if readAheadBytes <= 0 {
+		return nil
+	}
+
+	return &readAhead{
+		st:      st,
+		pc:      pc,
+		bytes:   readAheadBytes,
+		streams: map[blob.ID]*list.Element{},
+		lru:     list.New(),
+	}
+}
@@ -0,0 +1,122 @@
+package cache_test
+
+import (
+	"crypto/rand"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/kopia/kopia/internal/blobtesting"
+	"github.com/kopia/kopia/internal/cache"
+	"github.com/kopia/kopia/internal/gather"
+	"github.com/kopia/kopia/internal/testlogging"
+)
+
+const (
+	readAheadTimeout      = 5 * time.Second
+	readAheadPollInterval = 10 * time.Millisecond
+)
+
+func TestContentCacheReadAhead(t *testing.T) {
+	ctx := testlogging.Context(t)
+
+	data := make([]byte, 10000)
+	_, err := rand.Read(data)
+	require.NoError(t, err)
+
+	fs := blobtesting.NewFaultyStorage(blobtesting.NewMapStorage(blobtesting.DataMap{"p1": data}, nil, nil))
+
+	dataCache, err := cache.NewContentCache(ctx, fs, cache.Options{
+		Storage:        blobtesting.NewMapStorage(blobtesting.DataMap{}, nil, nil).(cache.Storage),
+		HMACSecret:     []byte{1, 2, 3, 4},
+		ReadAheadBytes: 3000,
+		Sweep: cache.SweepSettings{
+			MaxSizeBytes: 100000,
+		},
+	}, nil)
+	require.NoError(t, err)
+
+	defer dataCache.Close(ctx)
+
+	var tmp gather.WriteBuffer
+	defer tmp.Close()
+
+	read := func(offset int64) {
+		t.Helper()
+
+		require.NoError(t, dataCache.GetContent(ctx, fmt.Sprintf("c%v", offset), "p1", offset, 100, &tmp))
+		require.Equal(t, data[offset:offset+100], tmp.ToByteSlice())
+	}
+
+	getBlobCalls := func() int { return fs.NumCalls(blobtesting.MethodGetBlob) }
+
+	// the third sequential read starts prefetching the following 3000 bytes in the background.
+	read(0)
+	read(100)
+	read(200)
+	require.Eventually(t, func() bool { return getBlobCalls() == 4 }, readAheadTimeout, readAheadPollInterval)
+	require.Equal(t, 1, fs.NumCalls(blobtesting.MethodGetMetadata))
+
+	// following reads are served from the cache.
+	for off := int64(300); off < 1500; off += 100 {
+		read(off)
+	}
+
+	require.Equal(t, 4, getBlobCalls())
+
+	// once half of the prefetched data was read, the next range is prefetched.
+	read(1500)
+	read(1600)
+	read(1700)
+	require.Eventually(t, func() bool { return getBlobCalls() == 5 }, readAheadTimeout, readAheadPollInterval)
+
+	for off := int64(1800); off < 4800; off += 100 {
+		read(off)
+	}
+
+	require.Equal(t, 5, getBlobCalls())
+
+	// the remaining reads are correct, with or without read-ahead.
+	for off := int64(4800); off < 10000; off += 100 {
+		read(off)
+	}
+}
+
+func TestContentCacheReadAheadNonSequential(t *testing.T) {
+	for _, tc := range []struct {
+		name           string
+		readAheadBytes int64
+		offsets        []int64
+	}{
+		{"disabled", 0, []int64{0, 100, 200, 300, 400, 500}},
+		{"backward", 3000, []int64{2500, 2000, 1500, 1000, 500, 0}},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			ctx := testlogging.Context(t)
+
+			fs := blobtesting.NewFaultyStorage(blobtesting.NewMapStorage(blobtesting.DataMap{"p1": make([]byte, 10000)}, nil, nil))
+
+			dataCache, err := cache.NewContentCache(ctx, fs, cache.Options{
+				Storage:        blobtesting.NewMapStorage(blobtesting.DataMap{}, nil, nil).(cache.Storage),
+				HMACSecret:     []byte{1, 2, 3, 4},
+				ReadAheadBytes: tc.readAheadBytes,
+			}, nil)
+			require.NoError(t, err)
+
+			var tmp gather.WriteBuffer
+			defer tmp.Close()
+
+			for _, off := range tc.offsets {
+				require.NoError(t, dataCache.GetContent(ctx, fmt.Sprintf("c%v", off), "p1", off, 100, &tmp))
+			}
+
+			// waits for any reads ahead.
+			dataCache.Close(ctx)
+
+			require.Equal(t, len(tc.offsets), fs.NumCalls(blobtesting.MethodGetBlob))
+			require.Equal(t, 0, fs.NumCalls(blobtesting.MethodGetMetadata))
+		})
+	}
+}
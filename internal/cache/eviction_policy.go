@@ -0,0 +1,71 @@
+package cache
+
+import (
+	"time"
+)
+
+// EvictionPolicy determines the order in which items are removed from the cache when it is above its size limit.
+type EvictionPolicy string
+
+// Supported eviction policies.
+const (
+	// EvictionPolicyLRU evicts least recently used items first, based on the time of last access.
+	EvictionPolicyLRU EvictionPolicy = "lru"
+
+	// EvictionPolicyFIFO evicts items in the order they were added to the cache, regardless of access.
+	EvictionPolicyFIFO EvictionPolicy = "fifo"
+
+	// EvictionPolicyCostAware is like LRU, but each cache hit delays eviction of an item by an amount
+	// inversely proportional to its size, so that small frequently-hit entries (such as metadata) are kept
+	// in favor of large rarely-hit ones.
+	EvictionPolicyCostAware EvictionPolicy = "cost-aware"
+
+	// DefaultEvictionPolicy is the eviction policy used when none is specified.
+	DefaultEvictionPolicy = EvictionPolicyLRU
+)
+
+const (
+	// amount of time by which each cache hit delays eviction of an item no larger than costAwareReferenceSize.
+	costAwareHitRetention = time.Hour
+
+	// items larger than this get proportionally smaller retention bonus per hit.
+	costAwareReferenceSize = 64 << 10
+
+	// items are never retained for longer than this many hits from now, so that items that were hot once don't stay forever.
+	costAwareMaxHits = 24
+)
+
+// SupportedEvictionPolicies returns the names of supported eviction policies.
+func SupportedEvictionPolicies() []string {
+	return []string{
+		string(EvictionPolicyLRU),
+		string(EvictionPolicyFIFO),
+		string(EvictionPolicyCostAware),
+	}
+}
+
+// IsValid returns true if the eviction policy is supported.
+func (p EvictionPolicy) IsValid() bool {
+	switch p {
+	case EvictionPolicyLRU, EvictionPolicyFIFO, EvictionPolicyCostAware:
+		return true
+	default:
+		return false
+	}
+}
+
+// costAwareEvictionTime returns the new timestamp used to order an item of the provided length for eviction
+// with the cost-aware policy after a cache hit at the provided time, given its current timestamp.
+func costAwareEvictionTime(timestamp time.Time, length int64, now time.Time) time.Time {
+	bonus := costAwareHitRetention * costAwareReferenceSize / time.Duration(max(length, costAwareReferenceSize))
+
+	if timestamp.Before(now) {
+		timestamp = now
+	}
+
+	if limit := now.Add(costAwareMaxHits * bonus); timestamp.Add(bonus).After(limit) {
+		return limit
+	}
+
+	return timestamp.Add(bonus)
+}
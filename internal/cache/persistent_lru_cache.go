@@ -37,6 +37,12 @@ type PersistentCache struct {
 	// +checklocks:listCacheMutex
 	pendingWriteBytes int64
 
+	// size limits from sweep settings, which can be changed by SetSizeLimits() unlike other settings.
+	// +checklocks:listCacheMutex
+	maxSizeBytes int64
+	// +checklocks:listCacheMutex
+	limitBytes int64
+
 	cacheStorage      Storage
 	storageProtection cacheprot.StorageProtection
 	sweep             SweepSettings
@@ -50,6 +56,15 @@ type PersistentCache struct {
 	metricsStruct
 }
 
+// Stats returns statistics of the cache since it was opened.
+func (c *PersistentCache) Stats() Stats {
+	if c == nil {
+		return Stats{}
+	}
+
+	return c.stats.snapshot()
+}
+
 // CacheStorage returns cache storage.
 func (c *PersistentCache) CacheStorage() Storage {
 	return c.cacheStorage
@@ -99,16 +114,48 @@ func (c *PersistentCache) getPartialCacheHit(ctx context.Context, key string, le
 	// cache hit
 	c.reportHitBytes(int64(output.Length()))
 
+	if c.sweep.EvictionPolicy == EvictionPolicyFIFO {
+		// items are evicted in the order they were added, no need to touch them.
+		return
+	}
+
 	mtime, err := c.cacheStorage.TouchBlob(ctx, blob.ID(key), c.sweep.TouchThreshold)
+	if err != nil {
+		return
+	}
+
 	c.listCacheMutex.Lock()
-	defer c.listCacheMutex.Unlock()
+	c.listCache.AddOrUpdate(blob.Metadata{
+		BlobID:    blob.ID(key),
+		Length:    length,
+		Timestamp: mtime,
+	})
+	c.listCacheMutex.Unlock()
 
-	if err == nil {
-		c.listCache.AddOrUpdate(blob.Metadata{
-			BlobID:    blob.ID(key),
-			Length:    length,
-			Timestamp: mtime,
-		})
+	if c.sweep.EvictionPolicy == EvictionPolicyCostAware {
+		c.retainAfterHit(ctx, blob.ID(key), mtime)
+	}
+}
+
+// retainAfterHit delays eviction of an item after a cache hit with the cost-aware policy.
+// The new eviction time is persisted as the modification time of the cached item, so that it survives
+// reopening the cache, but only once it's at least TouchThreshold past the current one.
+func (c *PersistentCache) retainAfterHit(ctx context.Context, id blob.ID, mtime time.Time) {
+	c.listCacheMutex.Lock()
+	evictAfter, ok := c.listCache.retainAfterHit(id, c.timeNow())
+	c.listCacheMutex.Unlock()
+
+	if !ok || evictAfter.Sub(mtime) < c.sweep.TouchThreshold {
+		return
+	}
+
+	ms, ok := c.cacheStorage.(modTimeSetter)
+	if !ok {
+		return
+	}
+
+	if err := ms.SetBlobModTime(ctx, id, evictAfter); err != nil {
+		log(ctx).Debugf("unable to update modification time of %v entry %v: %v", c.description, id, err)
 	}
 }
 
@@ -145,7 +192,12 @@ func (c *PersistentCache) GetPartial(ctx context.Context, key string, offset, le
 		}
 
 		if err := sp.Verify(key, tmp.Bytes(), output); err == nil {
-			c.getPartialCacheHit(ctx, key, length, output)
+			cachedLength := length
+			if cachedLength < 0 {
+				cachedLength = int64(tmp.Length())
+			}
+
+			c.getPartialCacheHit(ctx, key, cachedLength, output)
 
 			return true
 		}
@@ -210,6 +262,22 @@ func (c *PersistentCache) Put(ctx context.Context, key string, data gather.Bytes
 	})
 }
 
+// SetSizeLimits changes the soft and hard size limits of the cache, immediately removing items
+// if the cache is above the new limits.
+func (c *PersistentCache) SetSizeLimits(ctx context.Context, maxSizeBytes, limitBytes int64) {
+	if c == nil {
+		return
+	}
+
+	c.listCacheMutex.Lock()
+	defer c.listCacheMutex.Unlock()
+
+	c.maxSizeBytes = maxSizeBytes
+	c.limitBytes = limitBytes
+
+	c.sweepLocked(ctx)
+}
+
 // Close closes the instance of persistent cache possibly waiting for at least one sweep to complete.
 func (c *PersistentCache) Close(ctx context.Context) {
 	if c == nil {
@@ -265,6 +333,22 @@ func (h *contentMetadataHeap) AddOrUpdate(bm blob.Metadata) {
 	}
 }
 
+// retainAfterHit delays eviction of an existing item after a cache hit according to the cost-aware policy
+// and returns its new timestamp.
+func (h *contentMetadataHeap) retainAfterHit(id blob.ID, now time.Time) (time.Time, bool) {
+	i, exists := h.index[id]
+	if !exists {
+		return time.Time{}, false
+	}
+
+	t := costAwareEvictionTime(h.data[i].Timestamp, h.data[i].Length, now)
+
+	h.data[i].Timestamp = t
+	heap.Fix(h, i)
+
+	return t, true
+}
+
 func (h *contentMetadataHeap) Pop() any {
 	old := h.data
 	n := len(old)
@@ -278,16 +362,16 @@ func (h *contentMetadataHeap) Pop() any {
 
 // +checklocks:c.listCacheMutex
 func (c *PersistentCache) aboveSoftLimit(extraBytes int64) bool {
-	return c.listCache.totalDataBytes+extraBytes+c.pendingWriteBytes > c.sweep.MaxSizeBytes
+	return c.listCache.totalDataBytes+extraBytes+c.pendingWriteBytes > c.maxSizeBytes
 }
 
 // +checklocks:c.listCacheMutex
 func (c *PersistentCache) aboveHardLimit(extraBytes int64) bool {
-	if c.sweep.LimitBytes <= 0 {
+	if c.limitBytes <= 0 {
 		return false
 	}
 
-	return c.listCache.totalDataBytes+extraBytes+c.pendingWriteBytes > c.sweep.LimitBytes
+	return c.listCache.totalDataBytes+extraBytes+c.pendingWriteBytes > c.limitBytes
 }
 
 // +checklocks:c.listCacheMutex
@@ -302,7 +386,9 @@ func (c *PersistentCache) sweepLocked(ctx context.Context) {
 		// examine the oldest cache item without removing it from the heap.
 		oldest := c.listCache.data[0]
 
-		if age := now.Sub(oldest.Timestamp); age < c.sweep.MinSweepAge && !c.aboveHardLimit(unsuccessfulDeleteBytes) {
+		// timestamps in the future are retention bonuses of the cost-aware policy rather than times of access,
+		// so they don't prevent sweeping.
+		if age := now.Sub(oldest.Timestamp); age >= 0 && age < c.sweep.MinSweepAge && !c.aboveHardLimit(unsuccessfulDeleteBytes) {
 			// the oldest item is below the specified minimal sweep age and we're below the hard limit, stop here
 			break
 		}
@@ -319,7 +405,11 @@ func (c *PersistentCache) sweepLocked(ctx context.Context) {
 			// c.listCache.DataSize() to zero
 			unsuccessfulDeletes = append(unsuccessfulDeletes, oldest)
 			unsuccessfulDeleteBytes += oldest.Length
+
+			continue
 		}
+
+		c.reportEvicted(oldest.Length)
 	}
 
 	// put all unsuccessful deletes back into the heap
@@ -363,8 +453,8 @@ func (c *PersistentCache) initialScan(ctx context.Context) error {
 
 	inUsePercent := int64(hundredPercent)
 
-	if c.sweep.MaxSizeBytes != 0 {
-		inUsePercent = hundredPercent * c.listCache.totalDataBytes / c.sweep.MaxSizeBytes
+	if c.maxSizeBytes != 0 {
+		inUsePercent = hundredPercent * c.listCache.totalDataBytes / c.maxSizeBytes
 	}
 
 	log(ctx).Debugw(
@@ -374,8 +464,8 @@ func (c *PersistentCache) initialScan(ctx context.Context) error {
 		"totalRetainedSize", c.listCache.totalDataBytes,
 		"tooRecentBytes", tooRecentBytes,
 		"tooRecentCount", tooRecentCount,
-		"maxSizeBytes", c.sweep.MaxSizeBytes,
-		"limitBytes", c.sweep.LimitBytes,
+		"maxSizeBytes", c.maxSizeBytes,
+		"limitBytes", c.limitBytes,
 		"inUsePercent", inUsePercent,
 	)
 
@@ -420,6 +510,9 @@ type SweepSettings struct {
 
 	// on each use, items will be touched if they have not been touched in this long.
 	TouchThreshold time.Duration
+
+	// determines which items are removed first when the cache is above its size limit.
+	EvictionPolicy EvictionPolicy
 }
 
 func (s SweepSettings) applyDefaults() SweepSettings {
@@ -427,6 +520,10 @@ func (s SweepSettings) applyDefaults() SweepSettings {
 		s.TouchThreshold = DefaultTouchThreshold
 	}
 
+	if s.EvictionPolicy == "" {
+		s.EvictionPolicy = DefaultEvictionPolicy
+	}
+
 	return s
 }
 
@@ -438,6 +535,10 @@ func NewPersistentCache(ctx context.Context, description string, cacheStorage St
 
 	sweep = sweep.applyDefaults()
 
+	if !sweep.EvictionPolicy.IsValid() {
+		return nil, errors.Errorf("unsupported cache eviction policy: %q", sweep.EvictionPolicy)
+	}
+
 	if storageProtection == nil {
 		storageProtection = cacheprot.NoProtection()
 	}
@@ -445,14 +546,17 @@ func NewPersistentCache(ctx context.Context, description string, cacheStorage St
 	c := &PersistentCache{
 		cacheStorage:      cacheStorage,
 		sweep:             sweep,
+		maxSizeBytes:      sweep.MaxSizeBytes,
+		limitBytes:        sweep.LimitBytes,
 		description:       description,
 		storageProtection: storageProtection,
-		metricsStruct:     initMetricsStruct(mr, description),
 		listCache:         newContentMetadataHeap(),
 		timeNow:           timeNow,
 		lastCacheWarning:  time.Time{},
 	}
 
+	c.initMetrics(mr, description)
+
 	if c.timeNow == nil {
 		c.timeNow = clock.Now
 	}
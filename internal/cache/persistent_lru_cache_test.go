@@ -3,6 +3,7 @@
 import (
 	"bytes"
 	"context"
+	"sync"
 	"testing"
 	"time"
 
@@ -13,6 +14,7 @@
 	"github.com/kopia/kopia/internal/cache"
 	"github.com/kopia/kopia/internal/cacheprot"
 	"github.com/kopia/kopia/internal/clock"
+	"github.com/kopia/kopia/internal/faketime"
 	"github.com/kopia/kopia/internal/fault"
 	"github.com/kopia/kopia/internal/gather"
 	"github.com/kopia/kopia/internal/testlogging"
@@ -113,6 +115,209 @@ func TestPersistentLRUCache(t *testing.T) {
 	}, &tmp2), someError)
 }
 
+func TestPersistentCache_EvictionPolicies(t *testing.T) {
+	cases := map[cache.EvictionPolicy]blob.ID{
+		cache.EvictionPolicyLRU:       "key2", // least recently used
+		cache.EvictionPolicyFIFO:      "key1", // added first
+		cache.EvictionPolicyCostAware: "key3", // fewest hits, since all items are small
+	}
+
+	for policy, wantEvicted := range cases {
+		t.Run(string(policy), func(t *testing.T) {
+			ctx := testlogging.ContextWithLevel(t, testlogging.LevelInfo)
+			ta := faketime.NewTimeAdvance(time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC))
+
+			cs := blobtesting.NewMapStorage(blobtesting.DataMap{}, nil, ta.NowFunc()).(cache.Storage)
+
+			pc, err := cache.NewPersistentCache(ctx, "testing", cs, nil, cache.SweepSettings{
+				MaxSizeBytes:   1000,
+				TouchThreshold: time.Nanosecond,
+				EvictionPolicy: policy,
+			}, nil, ta.NowFunc())
+			require.NoError(t, err)
+
+			defer pc.Close(ctx)
+
+			someData := bytes.Repeat([]byte{1}, 300)
+
+			for _, key := range []string{"key1", "key2", "key3"} {
+				pc.Put(ctx, key, gather.FromSlice(someData))
+				ta.Advance(time.Minute)
+			}
+
+			for _, key := range []string{"key2", "key2", "key3", "key1"} {
+				verifyCached(ctx, t, pc, key, someData)
+				ta.Advance(time.Minute)
+			}
+
+			// adding another item evicts exactly one of the existing ones.
+			pc.Put(ctx, "key4", gather.FromSlice(someData))
+
+			for _, key := range []blob.ID{"key1", "key2", "key3", "key4"} {
+				if key == wantEvicted {
+					verifyBlobDoesNotExist(ctx, t, cs, key)
+				} else {
+					verifyBlobExists(ctx, t, cs, key)
+				}
+			}
+		})
+	}
+}
+
+func TestPersistentCache_CostAwareRetentionIsPersisted(t *testing.T) {
+	ctx := testlogging.ContextWithLevel(t, testlogging.LevelInfo)
+	ta := faketime.NewTimeAdvance(time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC))
+
+	cs := blobtesting.NewMapStorage(blobtesting.DataMap{}, nil, ta.NowFunc()).(cache.Storage)
+
+	sweep := cache.SweepSettings{
+		MaxSizeBytes:   1000,
+		TouchThreshold: time.Nanosecond,
+		EvictionPolicy: cache.EvictionPolicyCostAware,
+	}
+
+	pc, err := cache.NewPersistentCache(ctx, "testing", cs, nil, sweep, nil, ta.NowFunc())
+	require.NoError(t, err)
+
+	someData := bytes.Repeat([]byte{1}, 300)
+
+	for _, key := range []string{"key1", "key2", "key3"} {
+		pc.Put(ctx, key, gather.FromSlice(someData))
+		ta.Advance(time.Minute)
+	}
+
+	for _, key := range []string{"key1", "key1", "key2", "key3"} {
+		verifyCached(ctx, t, pc, key, someData)
+		ta.Advance(time.Minute)
+	}
+
+	pc.Close(ctx)
+
+	// reopen the cache, the item with the most hits is still retained longest.
+	pc, err = cache.NewPersistentCache(ctx, "testing", cs, nil, sweep, nil, ta.NowFunc())
+	require.NoError(t, err)
+
+	defer pc.Close(ctx)
+
+	pc.Put(ctx, "key4", gather.FromSlice(someData))
+
+	verifyBlobExists(ctx, t, cs, "key1")
+	verifyBlobDoesNotExist(ctx, t, cs, "key2")
+	verifyBlobExists(ctx, t, cs, "key3")
+	verifyBlobExists(ctx, t, cs, "key4")
+}
+
+func TestPersistentCache_SetSizeLimits(t *testing.T) {
+	ctx := testlogging.ContextWithLevel(t, testlogging.LevelInfo)
+	ta := faketime.NewTimeAdvance(time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC))
+
+	cs := blobtesting.NewMapStorage(blobtesting.DataMap{}, nil, ta.NowFunc()).(cache.Storage)
+
+	pc, err := cache.NewPersistentCache(ctx, "testing", cs, nil, cache.SweepSettings{
+		MaxSizeBytes:   1000,
+		TouchThreshold: cache.DefaultTouchThreshold,
+	}, nil, ta.NowFunc())
+	require.NoError(t, err)
+
+	defer pc.Close(ctx)
+
+	someData := bytes.Repeat([]byte{1}, 300)
+
+	for _, key := range []string{"key1", "key2", "key3"} {
+		pc.Put(ctx, key, gather.FromSlice(someData))
+		ta.Advance(time.Minute)
+	}
+
+	// shrinking the cache immediately removes the oldest items.
+	pc.SetSizeLimits(ctx, 400, 0)
+
+	verifyBlobDoesNotExist(ctx, t, cs, "key1")
+	verifyBlobDoesNotExist(ctx, t, cs, "key2")
+	verifyBlobExists(ctx, t, cs, "key3")
+
+	// growing the cache allows more items to be added.
+	pc.SetSizeLimits(ctx, 1000, 0)
+	pc.Put(ctx, "key4", gather.FromSlice(someData))
+
+	verifyBlobExists(ctx, t, cs, "key3")
+	verifyBlobExists(ctx, t, cs, "key4")
+}
+
+func TestPersistentCache_SetSizeLimitsWhileReading(t *testing.T) {
+	ctx := testlogging.ContextWithLevel(t, testlogging.LevelInfo)
+	ta := faketime.NewTimeAdvance(time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC))
+
+	cs := blobtesting.NewMapStorage(blobtesting.DataMap{}, nil, ta.NowFunc()).(cache.Storage)
+
+	pc, err := cache.NewPersistentCache(ctx, "testing", cs, nil, cache.SweepSettings{
+		MaxSizeBytes:   1000,
+		TouchThreshold: cache.DefaultTouchThreshold,
+		EvictionPolicy: cache.EvictionPolicyCostAware,
+	}, nil, ta.NowFunc())
+	require.NoError(t, err)
+
+	defer pc.Close(ctx)
+
+	someData := bytes.Repeat([]byte{1}, 100)
+	keys := []string{"key1", "key2", "key3"}
+
+	for _, key := range keys {
+		pc.Put(ctx, key, gather.FromSlice(someData))
+	}
+
+	const numReaders = 4
+
+	var wg sync.WaitGroup
+
+	done := make(chan struct{})
+
+	for range numReaders {
+		wg.Add(1)
+
+		go func() {
+			defer wg.Done()
+
+			var tmp gather.WriteBuffer
+			defer tmp.Close()
+
+			for {
+				select {
+				case <-done:
+					return
+				default:
+				}
+
+				for _, key := range keys {
+					pc.GetFull(ctx, key, &tmp)
+					ta.Advance(time.Minute)
+				}
+			}
+		}()
+	}
+
+	// the limits remain above the size of the cache, so reads keep hitting.
+	for i := range 100 {
+		pc.SetSizeLimits(ctx, int64(1000+i), int64(2000+i))
+	}
+
+	close(done)
+	wg.Wait()
+
+	for _, key := range keys {
+		verifyBlobExists(ctx, t, cs, blob.ID(key))
+	}
+}
+
+func TestPersistentCache_InvalidEvictionPolicy(t *testing.T) {
+	ctx := testlogging.ContextWithLevel(t, testlogging.LevelInfo)
+	cs := blobtesting.NewMapStorage(blobtesting.DataMap{}, nil, nil).(cache.Storage)
+
+	_, err := cache.NewPersistentCache(ctx, "testing", cs, nil, cache.SweepSettings{
+		EvictionPolicy: "no-such-policy",
+	}, nil, clock.Now)
+	require.ErrorContains(t, err, "unsupported cache eviction policy")
+}
+
 type faultyCache struct {
 	*blobtesting.FaultyStorage
 }
@@ -33,7 +33,8 @@ func init() {
 // it will ask the server again for new timestamp.
 //
 // The server endpoint must be HTTP and be set using KOPIA_FAKE_CLOCK_ENDPOINT environment
-// variable.
+// variable. The server is implemented by faketime.Server, which cannot be used here directly
+// since the faketime package depends on this one.
 func getTimeFromServer(endpoint string) func() time.Time {
 	var mu sync.Mutex
 
@@ -36,3 +36,39 @@ func TestDeriveKeyFromMasterKey(t *testing.T) {
 		})
 	})
 }
+
+func TestDeriveKeyFromPassword_Argon2id(t *testing.T) {
+	key, err := crypto.DeriveKeyFromPassword("password", TestSalt, 32, crypto.Argon2idAlgorithm)
+	require.NoError(t, err)
+	require.Len(t, key, 32)
+
+	name := crypto.Argon2idAlgorithmName(8<<10, 1, 2)
+	require.Equal(t, "argon2id-8192-1-2", name)
+	require.NoError(t, crypto.ValidatePBKeyDerivationAlgorithm(name))
+
+	key2, err := crypto.DeriveKeyFromPassword("password", TestSalt, 32, name)
+	require.NoError(t, err)
+	require.Len(t, key2, 32)
+	require.NotEqual(t, key, key2)
+
+	key3, err := crypto.DeriveKeyFromPassword("password", TestSalt, 32, name)
+	require.NoError(t, err)
+	require.Equal(t, key2, key3)
+
+	_, err = crypto.DeriveKeyFromPassword("password", []byte("short"), 32, name)
+	require.Error(t, err)
+
+	for _, invalid := range []string{
+		"argon2id",
+		"argon2id-8192-1",
+		"argon2id-1024-1-1",       // too little memory
+		"argon2id-8192-0-1",       // no iterations
+		"argon2id-8192-1-0",       // no parallelism
+		"argon2id-8192-1-256",     // parallelism out of range
+		"argon2id-08192-1-1",      // not canonical
+		"argon2id-99999999-1-1",   // too much memory
+		"argon2id-8192-1000000-1", // too many iterations
+	} {
+		require.Error(t, crypto.ValidatePBKeyDerivationAlgorithm(invalid), invalid)
+	}
+}
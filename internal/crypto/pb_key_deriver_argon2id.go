@@ -0,0 +1,122 @@
+package crypto
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/pkg/errors"
+	"golang.org/x/crypto/argon2"
+)
+
+const (
+	// Argon2idAlgorithmPrefix is the prefix of argon2id algorithm names, which are followed by
+	// memory size in KiB, number of iterations and parallelism, separated by dashes.
+	Argon2idAlgorithmPrefix = "argon2id"
+
+	// Argon2idAlgorithm is the registration name for argon2id with parameters recommended by RFC 9106
+	// for memory-constrained environments (64 MiB, 3 iterations, 4 lanes).
+	Argon2idAlgorithm = "argon2id-65536-3-4"
+
+	// The recommended minimum size for a salt to be used for argon2id is 16 bytes.
+	argon2idMinSaltLength = 16 // 128 bits
+
+	// Argon2idMinMemory and Argon2idMaxMemory are the limits of the amount of memory used by argon2id, in bytes,
+	// which protect against format blobs requesting unreasonable amounts of memory.
+	Argon2idMinMemory = argon2idMinMemoryKiB << 10
+	Argon2idMaxMemory = argon2idMaxMemoryKiB << 10
+
+	argon2idMinMemoryKiB  = 8 << 10 // 8 MiB
+	argon2idMaxMemoryKiB  = 4 << 20 // 4 GiB
+	argon2idMaxIterations = 100
+)
+
+func init() {
+	kd, err := parseArgon2idAlgorithm(Argon2idAlgorithm)
+	if err != nil {
+		panic(err)
+	}
+
+	registerPBKeyDeriver(Argon2idAlgorithm, kd)
+}
+
+type argon2idKeyDeriver struct {
+	memoryKiB   uint32
+	iterations  uint32
+	parallelism uint8
+
+	minSaltLength int
+}
+
+func (s *argon2idKeyDeriver) deriveKeyFromPassword(password string, salt []byte, keySize int) ([]byte, error) {
+	if len(salt) < s.minSaltLength {
+		return nil, errors.Errorf("required salt size is at least %d bytes", s.minSaltLength)
+	}
+
+	return argon2.IDKey([]byte(password), salt, s.iterations, s.memoryKiB, s.parallelism, uint32(keySize)), nil //nolint:gosec
+}
+
+// Argon2idAlgorithmName returns the name of argon2id key derivation algorithm with the provided parameters.
+func Argon2idAlgorithmName(memoryKiB, iterations uint32, parallelism uint8) string {
+	return fmt.Sprintf("%v-%v-%v-%v", Argon2idAlgorithmPrefix, memoryKiB, iterations, parallelism)
+}
+
+// ValidateArgon2idParameters returns an error if the provided argon2id parameters are out of the supported range.
+func ValidateArgon2idParameters(memoryKiB, iterations uint32, parallelism uint8) error {
+	if memoryKiB < argon2idMinMemoryKiB || memoryKiB > argon2idMaxMemoryKiB {
+		return errors.Errorf("argon2id memory must be between %v and %v KiB", argon2idMinMemoryKiB, argon2idMaxMemoryKiB)
+	}
+
+	if iterations < 1 || iterations > argon2idMaxIterations {
+		return errors.Errorf("argon2id iterations must be between 1 and %v", argon2idMaxIterations)
+	}
+
+	if parallelism < 1 {
+		return errors.New("argon2id parallelism must be at least 1")
+	}
+
+	return nil
+}
+
+// parseArgon2idAlgorithm parses argon2id algorithm name with embedded parameters.
+func parseArgon2idAlgorithm(name string) (*argon2idKeyDeriver, error) {
+	parts := strings.Split(name, "-")
+
+	//nolint:mnd
+	if len(parts) != 4 || parts[0] != Argon2idAlgorithmPrefix {
+		return nil, errors.Errorf("invalid argon2id algorithm name: %v", name)
+	}
+
+	memoryKiB, err := strconv.ParseUint(parts[1], 10, 32)
+	if err != nil {
+		return nil, errors.Wrap(err, "invalid argon2id memory")
+	}
+
+	iterations, err := strconv.ParseUint(parts[2], 10, 32)
+	if err != nil {
+		return nil, errors.Wrap(err, "invalid argon2id iterations")
+	}
+
+	parallelism, err := strconv.ParseUint(parts[3], 10, 8)
+	if err != nil {
+		return nil, errors.Wrap(err, "invalid argon2id parallelism")
+	}
+
+	kd := &argon2idKeyDeriver{
+		memoryKiB:     uint32(memoryKiB),  //nolint:gosec
+		iterations:    uint32(iterations), //nolint:gosec
+		parallelism:   uint8(parallelism), //nolint:gosec
+		minSaltLength: argon2idMinSaltLength,
+	}
+
+	if err := ValidateArgon2idParameters(kd.memoryKiB, kd.iterations, kd.parallelism); err != nil {
+		return nil, err
+	}
+
+	// names must be canonical so that the same parameters are always stored the same way.
+	if Argon2idAlgorithmName(kd.memoryKiB, kd.iterations, kd.parallelism) != name {
+		return nil, errors.Errorf("non-canonical argon2id algorithm name: %v", name)
+	}
+
+	return kd, nil
+}
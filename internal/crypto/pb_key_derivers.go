@@ -2,6 +2,7 @@
 
 import (
 	"fmt"
+	"strings"
 
 	"github.com/pkg/errors"
 )
@@ -25,15 +26,35 @@ func registerPBKeyDeriver(name string, keyDeriver passwordBasedKeyDeriver) {
 
 // DeriveKeyFromPassword derives encryption key using the provided password and per-repository unique ID.
 func DeriveKeyFromPassword(password string, salt []byte, keySize int, algorithm string) ([]byte, error) {
-	kd, ok := keyDerivers[algorithm]
-	if !ok {
-		return nil, errors.Errorf("unsupported key derivation algorithm: %v, supported algorithms %v", algorithm, supportedPBKeyDerivationAlgorithms())
+	kd, err := keyDeriverForAlgorithm(algorithm)
+	if err != nil {
+		return nil, err
 	}
 
 	//nolint:wrapcheck
 	return kd.deriveKeyFromPassword(password, salt, keySize)
 }
 
+// ValidatePBKeyDerivationAlgorithm returns an error if the provided key derivation algorithm is not supported.
+func ValidatePBKeyDerivationAlgorithm(algorithm string) error {
+	_, err := keyDeriverForAlgorithm(algorithm)
+
+	return err
+}
+
+func keyDeriverForAlgorithm(algorithm string) (passwordBasedKeyDeriver, error) {
+	if kd, ok := keyDerivers[algorithm]; ok {
+		return kd, nil
+	}
+
+	// argon2id parameters are embedded in the algorithm name.
+	if strings.HasPrefix(algorithm, Argon2idAlgorithmPrefix+"-") {
+		return parseArgon2idAlgorithm(algorithm)
+	}
+
+	return nil, errors.Errorf("unsupported key derivation algorithm: %v, supported algorithms %v", algorithm, supportedPBKeyDerivationAlgorithms())
+}
+
 // supportedPBKeyDerivationAlgorithms returns a slice of the allowed key derivation algorithms.
 func supportedPBKeyDerivationAlgorithms() []string {
 	kdAlgorithms := make([]string, 0, len(keyDerivers))
@@ -1,11 +1,16 @@
 package epoch
 
 import (
+	"fmt"
 	"time"
 
+	"github.com/kopia/kopia/internal/units"
 	"github.com/kopia/kopia/repo/blob"
 )
 
+// maximum time in the future for which epoch advancement is estimated.
+const maxAdvanceEstimate = 10 * 365 * 24 * time.Hour
+
 // shouldAdvance determines if the current epoch should be advanced based on set of blobs in it.
 //
 // Epoch will be advanced if it's been more than 'minEpochDuration' between earliest and
@@ -14,40 +19,85 @@
 // - number of blobs in the epoch exceeds 'countThreshold'
 // - total size of blobs in the epoch exceeds 'totalSizeBytesThreshold'.
 func shouldAdvance(bms []blob.Metadata, minEpochDuration time.Duration, countThreshold int, totalSizeBytesThreshold int64) bool {
+	return len(advanceBlockers(bms, minEpochDuration, countThreshold, totalSizeBytesThreshold)) == 0
+}
+
+// advanceBlockers returns human-readable reasons why the epoch consisting of the provided blobs
+// should not be advanced yet or nil if it should be advanced, as described in shouldAdvance().
+func advanceBlockers(bms []blob.Metadata, minEpochDuration time.Duration, countThreshold int, totalSizeBytesThreshold int64) []string {
 	if len(bms) == 0 {
-		return false
+		return []string{"no index blobs have been written in the epoch"}
 	}
 
-	var (
-		minTime   = bms[0].Timestamp
-		maxTime   = bms[0].Timestamp
-		totalSize = int64(0)
-	)
+	var result []string
+
+	// not enough time between first and last write in an epoch.
+	if span := blob.MaxTimestamp(bms).Sub(blob.MinTimestamp(bms)); span < minEpochDuration {
+		result = append(result, fmt.Sprintf("time between first and last index write (%v) is below minimum epoch duration (%v)",
+			span.Round(time.Second), minEpochDuration))
+	}
 
-	for _, bm := range bms {
-		if bm.Timestamp.Before(minTime) {
-			minTime = bm.Timestamp
+	if totalSize := blob.TotalLength(bms); len(bms) < countThreshold && totalSize < totalSizeBytesThreshold {
+		result = append(result, fmt.Sprintf("neither the number of index blobs (%v<%v) nor their total size (%v<%v) reached the threshold",
+			len(bms), countThreshold, units.BytesString(totalSize), units.BytesString(totalSizeBytesThreshold)))
+	}
+
+	return result
+}
+
+// estimateAdvanceTime estimates the time at which the epoch consisting of the provided blobs will meet
+// the criteria of shouldAdvance(), assuming index blobs continue to be written at the average rate
+// observed since the first write. Returns zero time if there's not enough information for an estimate.
+func estimateAdvanceTime(bms []blob.Metadata, now time.Time, minEpochDuration time.Duration, countThreshold int, totalSizeBytesThreshold int64) time.Time {
+	if len(bms) == 0 {
+		return time.Time{}
+	}
+
+	firstWrite := blob.MinTimestamp(bms)
+
+	result := firstWrite.Add(minEpochDuration)
+
+	if count, totalSize := len(bms), blob.TotalLength(bms); count < countThreshold && totalSize < totalSizeBytesThreshold {
+		elapsed := now.Sub(firstWrite)
+		if elapsed <= 0 {
+			return time.Time{}
+		}
+
+		// time at which either the number of blobs or their total size reaches the threshold.
+		thresholdTime, ok := extrapolate(firstWrite, elapsed, float64(count), float64(countThreshold))
+		if t, ok2 := extrapolate(firstWrite, elapsed, float64(totalSize), float64(totalSizeBytesThreshold)); ok2 && (!ok || t.Before(thresholdTime)) {
+			thresholdTime, ok = t, true
 		}
 
-		if bm.Timestamp.After(maxTime) {
-			maxTime = bm.Timestamp
+		if !ok {
+			return time.Time{}
 		}
 
-		totalSize += bm.Length
+		if thresholdTime.After(result) {
+			result = thresholdTime
+		}
 	}
 
-	// not enough time between first and last write in an epoch.
-	if maxTime.Sub(minTime) < minEpochDuration {
-		return false
+	// minimum duration has passed, but another index write is needed.
+	if result.Before(now) {
+		result = now
 	}
 
-	if len(bms) >= countThreshold {
-		return true
+	return result
+}
+
+// extrapolate returns the time at which a value growing linearly from zero at the start time,
+// which reached the provided current value after the elapsed duration, will reach the target value.
+// Returns false if the target won't be reached in the foreseeable future.
+func extrapolate(start time.Time, elapsed time.Duration, current, target float64) (time.Time, bool) {
+	if current <= 0 {
+		return time.Time{}, false
 	}
 
-	if totalSize >= totalSizeBytesThreshold {
-		return true
+	d := float64(elapsed) * target / current
+	if d > float64(maxAdvanceEstimate) {
+		return time.Time{}, false
 	}
 
-	return false
+	return start.Add(time.Duration(d)), true
 }
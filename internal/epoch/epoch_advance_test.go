@@ -88,3 +88,67 @@ func TestShouldAdvanceEpoch(t *testing.T) {
 			tc.desc)
 	}
 }
+
+func TestAdvanceBlockers(t *testing.T) {
+	def := DefaultParameters()
+	t0 := time.Date(2000, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	blockers := func(bms ...blob.Metadata) []string {
+		return advanceBlockers(bms, def.MinEpochDuration, def.EpochAdvanceOnCountThreshold, def.EpochAdvanceOnTotalSizeBytesThreshold)
+	}
+
+	require.Equal(t, []string{"no index blobs have been written in the epoch"}, blockers())
+
+	b := blockers(blob.Metadata{Timestamp: t0, Length: 1})
+	require.Len(t, b, 2)
+	require.Contains(t, b[0], "minimum epoch duration")
+	require.Contains(t, b[1], "threshold")
+
+	b = blockers(
+		blob.Metadata{Timestamp: t0, Length: 1},
+		blob.Metadata{Timestamp: t0.Add(def.MinEpochDuration), Length: 1})
+	require.Len(t, b, 1)
+	require.Contains(t, b[0], "threshold")
+
+	b = blockers(
+		blob.Metadata{Timestamp: t0, Length: def.EpochAdvanceOnTotalSizeBytesThreshold},
+		blob.Metadata{Timestamp: t0.Add(time.Hour), Length: 1})
+	require.Len(t, b, 1)
+	require.Contains(t, b[0], "minimum epoch duration")
+
+	require.Empty(t, blockers(
+		blob.Metadata{Timestamp: t0, Length: def.EpochAdvanceOnTotalSizeBytesThreshold},
+		blob.Metadata{Timestamp: t0.Add(def.MinEpochDuration), Length: 1}))
+}
+
+func TestEstimateAdvanceTime(t *testing.T) {
+	def := DefaultParameters()
+	t0 := time.Date(2000, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	estimate := func(now time.Time, bms ...blob.Metadata) time.Time {
+		return estimateAdvanceTime(bms, now, def.MinEpochDuration, def.EpochAdvanceOnCountThreshold, def.EpochAdvanceOnTotalSizeBytesThreshold)
+	}
+
+	// no blobs, no estimate.
+	require.True(t, estimate(t0).IsZero())
+
+	// thresholds already met, limited by minimum duration.
+	require.Equal(t, t0.Add(def.MinEpochDuration),
+		estimate(t0.Add(time.Hour), blob.Metadata{Timestamp: t0, Length: def.EpochAdvanceOnTotalSizeBytesThreshold}))
+
+	// half of the count threshold reached over 2 days, will take 2 more days.
+	var bms []blob.Metadata
+	for range def.EpochAdvanceOnCountThreshold / 2 {
+		bms = append(bms, blob.Metadata{Timestamp: t0, Length: 1})
+	}
+
+	require.Equal(t, t0.Add(96*time.Hour), estimate(t0.Add(48*time.Hour), bms...))
+
+	// half of the size threshold reached over 2 days, will take 2 more days.
+	require.Equal(t, t0.Add(96*time.Hour),
+		estimate(t0.Add(48*time.Hour), blob.Metadata{Timestamp: t0, Length: def.EpochAdvanceOnTotalSizeBytesThreshold / 2}))
+
+	// all criteria met except for another write after minimum duration.
+	require.Equal(t, t0.Add(72*time.Hour),
+		estimate(t0.Add(72*time.Hour), blob.Metadata{Timestamp: t0, Length: def.EpochAdvanceOnTotalSizeBytesThreshold}))
+}
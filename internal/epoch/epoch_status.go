@@ -0,0 +1,110 @@
+package epoch
+
+import (
+	"context"
+	"time"
+
+	"github.com/kopia/kopia/repo/blob"
+)
+
+// Status describes the state of index epochs for diagnostic purposes.
+type Status struct {
+	WriteEpoch        int                     `json:"writeEpoch"`
+	Parameters        Parameters              `json:"parameters"`
+	Epochs            []EpochStatus           `json:"epochs"`
+	RangeCheckpoints  []RangeCheckpointStatus `json:"rangeCheckpoints"`
+	DeletionWatermark time.Time               `json:"deletionWatermark"`
+	Advance           AdvanceStatus           `json:"advance"`
+}
+
+// EpochStatus describes index blobs of a single epoch not covered by a range checkpoint.
+type EpochStatus struct {
+	Epoch     int       `json:"epoch"`
+	StartTime time.Time `json:"startTime"` // zero if the start time of the epoch is no longer known
+	Settled   bool      `json:"settled"`   // no new index blobs will be written in a settled epoch
+
+	UncompactedBlobs int       `json:"uncompactedBlobs"`
+	UncompactedBytes int64     `json:"uncompactedBytes"`
+	FirstWriteTime   time.Time `json:"firstWriteTime"`
+	LastWriteTime    time.Time `json:"lastWriteTime"`
+
+	SingleEpochCompactionBlobs int   `json:"singleEpochCompactionBlobs"`
+	SingleEpochCompactionBytes int64 `json:"singleEpochCompactionBytes"`
+}
+
+// RangeCheckpointStatus describes a range checkpoint covering multiple epochs.
+type RangeCheckpointStatus struct {
+	MinEpoch   int   `json:"minEpoch"`
+	MaxEpoch   int   `json:"maxEpoch"`
+	Blobs      int   `json:"blobs"`
+	TotalBytes int64 `json:"totalBytes"`
+}
+
+// AdvanceStatus describes whether the current write epoch can be advanced, which happens
+// during maintenance once all the criteria are met.
+type AdvanceStatus struct {
+	Ready         bool      `json:"ready"`
+	Blockers      []string  `json:"blockers,omitempty"`
+	EstimatedTime time.Time `json:"estimatedTime"` // zero if ready or unknown
+}
+
+// Status returns the current state of index epochs, including the reasons why the current write epoch
+// can't be advanced yet and the estimated time of next advancement.
+func (e *Manager) Status(ctx context.Context) (Status, error) {
+	p, err := e.getParameters(ctx)
+	if err != nil {
+		return Status{}, err
+	}
+
+	cs, err := e.committedState(ctx, 0)
+	if err != nil {
+		return Status{}, err
+	}
+
+	result := Status{
+		WriteEpoch:        cs.WriteEpoch,
+		Parameters:        *p,
+		DeletionWatermark: cs.DeletionWatermark,
+	}
+
+	firstNonRangeCompacted := 0
+
+	for _, r := range cs.LongestRangeCheckpointSets {
+		result.RangeCheckpoints = append(result.RangeCheckpoints, RangeCheckpointStatus{
+			MinEpoch:   r.MinEpoch,
+			MaxEpoch:   r.MaxEpoch,
+			Blobs:      len(r.Blobs),
+			TotalBytes: blob.TotalLength(r.Blobs),
+		})
+
+		firstNonRangeCompacted = r.MaxEpoch + 1
+	}
+
+	for n := firstNonRangeCompacted; n <= cs.WriteEpoch; n++ {
+		uces := cs.UncompactedEpochSets[n]
+		secs := cs.SingleEpochCompactionSets[n]
+
+		result.Epochs = append(result.Epochs, EpochStatus{
+			Epoch:                      n,
+			StartTime:                  cs.EpochStartTime[n],
+			Settled:                    cs.isSettledEpochNumber(n),
+			UncompactedBlobs:           len(uces),
+			UncompactedBytes:           blob.TotalLength(uces),
+			FirstWriteTime:             blob.MinTimestamp(uces),
+			LastWriteTime:              blob.MaxTimestamp(uces),
+			SingleEpochCompactionBlobs: len(secs),
+			SingleEpochCompactionBytes: blob.TotalLength(secs),
+		})
+	}
+
+	current := cs.UncompactedEpochSets[cs.WriteEpoch]
+
+	result.Advance.Blockers = advanceBlockers(current, p.MinEpochDuration, p.EpochAdvanceOnCountThreshold, p.EpochAdvanceOnTotalSizeBytesThreshold)
+	result.Advance.Ready = len(result.Advance.Blockers) == 0
+
+	if !result.Advance.Ready {
+		result.Advance.EstimatedTime = estimateAdvanceTime(current, e.timeFunc(), p.MinEpochDuration, p.EpochAdvanceOnCountThreshold, p.EpochAdvanceOnTotalSizeBytesThreshold)
+	}
+
+	return result, nil
+}
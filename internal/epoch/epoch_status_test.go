@@ -0,0 +1,70 @@
+package epoch
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/kopia/kopia/internal/testlogging"
+)
+
+func TestStatus(t *testing.T) {
+	t.Parallel()
+
+	ctx := testlogging.Context(t)
+	te := newTestEnv(t)
+
+	p, err := te.mgr.getParameters(ctx)
+	require.NoError(t, err)
+
+	st, err := te.mgr.Status(ctx)
+	require.NoError(t, err)
+	require.Equal(t, 0, st.WriteEpoch)
+	require.Equal(t, *p, st.Parameters)
+	require.Len(t, st.Epochs, 1)
+	require.Zero(t, st.Epochs[0].UncompactedBlobs)
+	require.False(t, st.Advance.Ready)
+	require.Len(t, st.Advance.Blockers, 1)
+	require.True(t, st.Advance.EstimatedTime.IsZero())
+
+	for i := range p.EpochAdvanceOnCountThreshold {
+		te.mustWriteIndexFiles(ctx, t, newFakeIndexWithEntries(i))
+	}
+
+	te.ft.Advance(time.Hour)
+	require.NoError(t, te.mgr.Refresh(ctx))
+
+	st, err = te.mgr.Status(ctx)
+	require.NoError(t, err)
+	require.Len(t, st.Epochs, 1)
+	require.Equal(t, p.EpochAdvanceOnCountThreshold, st.Epochs[0].UncompactedBlobs)
+	require.Positive(t, st.Epochs[0].UncompactedBytes)
+	require.False(t, st.Epochs[0].Settled)
+	require.False(t, st.Advance.Ready)
+	require.Len(t, st.Advance.Blockers, 1)
+	require.Contains(t, st.Advance.Blockers[0], "minimum epoch duration")
+	require.Equal(t, st.Epochs[0].FirstWriteTime.Add(p.MinEpochDuration), st.Advance.EstimatedTime)
+
+	// write after the minimum epoch duration makes the epoch ready to advance.
+	te.ft.Advance(p.MinEpochDuration)
+	te.mustWriteIndexFiles(ctx, t, newFakeIndexWithEntries(p.EpochAdvanceOnCountThreshold))
+	require.NoError(t, te.mgr.Refresh(ctx))
+
+	st, err = te.mgr.Status(ctx)
+	require.NoError(t, err)
+	require.True(t, st.Advance.Ready)
+	require.Empty(t, st.Advance.Blockers)
+	require.True(t, st.Advance.EstimatedTime.IsZero())
+
+	require.NoError(t, te.mgr.MaybeAdvanceWriteEpoch(ctx))
+	require.NoError(t, te.mgr.Refresh(ctx))
+
+	st, err = te.mgr.Status(ctx)
+	require.NoError(t, err)
+	require.Equal(t, 1, st.WriteEpoch)
+	require.Len(t, st.Epochs, 2)
+	require.Equal(t, 1, st.Epochs[1].Epoch)
+	require.False(t, st.Epochs[1].StartTime.IsZero())
+	require.Zero(t, st.Epochs[1].UncompactedBlobs)
+}
@@ -0,0 +1,164 @@
+package faketime
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// Client talks to a fake time Server over HTTP.
+type Client struct {
+	// Endpoint is the URL of the server, possibly including the client ID, in the same format
+	// as the value of EndpointEnvVar.
+	Endpoint string
+
+	// HTTPClient is used to make requests, defaults to http.DefaultClient.
+	HTTPClient *http.Client
+}
+
+// NewClient returns a client of the fake time server at the provided endpoint.
+func NewClient(endpoint string) *Client {
+	return &Client{Endpoint: endpoint}
+}
+
+func (c *Client) httpClient() *http.Client {
+	if c.HTTPClient != nil {
+		return c.HTTPClient
+	}
+
+	return http.DefaultClient
+}
+
+func (c *Client) get(ctx context.Context, u string, result *timeInfo) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, u, http.NoBody)
+	if err != nil {
+		return errors.Wrap(err, "unable to create request")
+	}
+
+	resp, err := c.httpClient().Do(req)
+	if err != nil {
+		return errors.Wrap(err, "unable to get fake time from server")
+	}
+	defer resp.Body.Close() //nolint:errcheck
+
+	if resp.StatusCode != http.StatusOK {
+		return errors.Errorf("unable to get fake time from server: %v", resp.Status)
+	}
+
+	return errors.Wrap(json.NewDecoder(resp.Body).Decode(result), "invalid time received from fake time server")
+}
+
+// Time returns the current time served to the client along with the duration for which
+// it can be advanced locally.
+func (c *Client) Time(ctx context.Context) (t time.Time, validFor time.Duration, err error) {
+	var ti timeInfo
+
+	if err := c.get(ctx, c.Endpoint, &ti); err != nil {
+		return time.Time{}, 0, err
+	}
+
+	return ti.Time, ti.ValidFor, nil
+}
+
+// NowFunc returns a time source, which returns the time served by the server, advancing it
+// locally until it is no longer valid, at which point it asks the server again. This is the
+// same behavior as that of kopia binaries consuming EndpointEnvVar.
+//
+// The returned function panics if the server cannot be reached.
+func (c *Client) NowFunc() func() time.Time {
+	var (
+		mu sync.Mutex
+
+		nextRefreshRealTime time.Time     //nolint:forbidigo
+		localTimeOffset     time.Duration // offset to be added to time.Now() to produce server time
+	)
+
+	return func() time.Time {
+		mu.Lock()
+		defer mu.Unlock()
+
+		localTime := time.Now() //nolint:forbidigo
+		if !localTime.Before(nextRefreshRealTime) {
+			serverTime, validFor, err := c.Time(context.Background())
+			if err != nil {
+				panic(err)
+			}
+
+			nextRefreshRealTime = localTime.Add(validFor)
+
+			// compute offset such that localTime + localTimeOffset == serverTime
+			localTimeOffset = serverTime.Sub(localTime)
+		}
+
+		return localTime.Add(localTimeOffset).Round(0)
+	}
+}
+
+// admin invokes the provided administrative command of the server and returns the resulting server time.
+func (c *Client) admin(ctx context.Context, command string, params url.Values) (time.Time, error) {
+	u, err := url.Parse(c.Endpoint)
+	if err != nil {
+		return time.Time{}, errors.Wrap(err, "invalid endpoint")
+	}
+
+	u.Path = strings.TrimSuffix(u.Path, "/") + AdminPathPrefix + command
+	u.RawQuery = params.Encode()
+
+	var ti timeInfo
+
+	if err := c.get(ctx, u.String(), &ti); err != nil {
+		return time.Time{}, errors.Wrapf(err, "error invoking %v", command)
+	}
+
+	return ti.Time, nil
+}
+
+// Pause stops the passage of time on the server until Resume() is called.
+func (c *Client) Pause(ctx context.Context) error {
+	_, err := c.admin(ctx, "pause", nil)
+	return err
+}
+
+// Resume resumes the passage of time on the server.
+func (c *Client) Resume(ctx context.Context) error {
+	_, err := c.admin(ctx, "resume", nil)
+	return err
+}
+
+// Step immediately moves the time of the server (and all clients) by the provided delta.
+func (c *Client) Step(ctx context.Context, delta time.Duration) (time.Time, error) {
+	return c.admin(ctx, "step", url.Values{"delta": {delta.String()}})
+}
+
+// StepAt schedules a time jump by the provided delta to happen when the server serves
+// its n-th time request.
+func (c *Client) StepAt(ctx context.Context, iteration int, delta time.Duration) error {
+	_, err := c.admin(ctx, "step", url.Values{"delta": {delta.String()}, "iteration": {strconv.Itoa(iteration)}})
+	return err
+}
+
+// SetScale sets the rate at which the time of the provided client advances relative to the server time.
+func (c *Client) SetScale(ctx context.Context, clientID string, factor float64) error {
+	_, err := c.admin(ctx, "scale", url.Values{ClientParam: {clientID}, "factor": {strconv.FormatFloat(factor, 'f', -1, 64)}})
+	return err
+}
+
+// SetSkew sets the constant offset of the time of the provided client relative to the server time.
+func (c *Client) SetSkew(ctx context.Context, clientID string, offset time.Duration) error {
+	_, err := c.admin(ctx, "skew", url.Values{ClientParam: {clientID}, "offset": {offset.String()}})
+	return err
+}
+
+// SetDrift makes the time of the provided client drift away from the server time by the provided
+// amount for each hour of server time.
+func (c *Client) SetDrift(ctx context.Context, clientID string, perHour time.Duration) error {
+	_, err := c.admin(ctx, "drift", url.Values{ClientParam: {clientID}, "perHour": {perHour.String()}})
+	return err
+}
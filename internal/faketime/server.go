@@ -0,0 +1,372 @@
+package faketime
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	// EndpointEnvVar is the name of the environment variable, which contains the URL of the fake time
+	// server used as the source of time by kopia binaries built with the 'testing' build tag.
+	EndpointEnvVar = "KOPIA_FAKE_CLOCK_ENDPOINT"
+
+	// DefaultValidFor is the default amount of time for which clients may advance the served time
+	// locally before asking the server again.
+	DefaultValidFor = 2 * time.Second
+
+	// AdminPathPrefix is the URL path prefix of the administrative endpoints
+	// of the Server.
+	AdminPathPrefix = "/admin/"
+
+	// ClientParam is the name of the query parameter that identifies the client
+	// requesting fake time, used to apply per-client settings.
+	ClientParam = "client"
+
+	// StorageClient is the client ID conventionally used for the clock of the storage
+	// backend, as opposed to the clocks of kopia clients.
+	StorageClient = "storage"
+)
+
+type timeInfo struct {
+	Time     time.Time     `json:"time"`
+	ValidFor time.Duration `json:"validFor"`
+}
+
+type timeStep struct {
+	iteration int
+	delta     time.Duration
+}
+
+// clientClock keeps the state of a client clock, such that
+// clientTime = base + skew + scale * elapsed + driftPerHour * elapsed / 1h,
+// where elapsed = serverTime - serverOrigin.
+type clientClock struct {
+	scale        float64
+	driftPerHour time.Duration
+	skew         time.Duration
+	base         time.Time
+	serverOrigin time.Time
+}
+
+func (c *clientClock) timeAt(serverTime time.Time) time.Time {
+	elapsed := float64(serverTime.Sub(c.serverOrigin))
+	drift := elapsed * float64(c.driftPerHour) / float64(time.Hour)
+
+	return c.base.Add(c.skew + time.Duration(c.scale*elapsed+drift))
+}
+
+// Server serves fake time signal to instances of Kopia.
+//
+// In addition to the base time source, the server supports:
+//
+//   - step jumps applied immediately or scripted to happen at a given iteration,
+//     where each time request served counts as one iteration,
+//   - per-client scale factors, where clients identify themselves using the
+//     'client' query parameter of the endpoint URL,
+//   - per-client constant skew and gradual drift, which can be used to simulate
+//     differences between the clocks of kopia clients and the storage,
+//   - pausing and resuming the passage of time.
+//
+// Clients request time using GET on any path outside of /admin/ and receive a JSON object with
+// the current 'time' and the 'validFor' duration, during which they may advance the received time
+// using their local clock before asking again. While time is paused, validFor is zero.
+//
+// All of the above can also be controlled over HTTP using the following endpoints, each of which
+// returns the resulting server time:
+//
+//   - /admin/pause and /admin/resume,
+//   - /admin/step?delta=<duration>[&iteration=<n>],
+//   - /admin/scale?client=<id>&factor=<float>,
+//   - /admin/skew?client=<id>&offset=<duration>,
+//   - /admin/drift?client=<id>&perHour=<duration>.
+//
+// Client provides a Go API for both consuming the time and the administrative endpoints.
+type Server struct {
+	Now func() time.Time
+
+	// ValidFor is the amount of time for which the clients may advance the
+	// served time locally before asking again.
+	ValidFor time.Duration
+
+	mu sync.Mutex
+	// +checklocks:mu
+	iteration int
+	// +checklocks:mu
+	offset time.Duration
+	// +checklocks:mu
+	pausedAt time.Time
+	// +checklocks:mu
+	pendingSteps []timeStep
+	// +checklocks:mu
+	clients map[string]*clientClock
+}
+
+func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if strings.HasPrefix(r.URL.Path, AdminPathPrefix) {
+		s.serveAdmin(w, r)
+		return
+	}
+
+	json.NewEncoder(w).Encode(s.nextTimeInfo(r.URL.Query().Get(ClientParam))) //nolint:errcheck,errchkjson
+}
+
+func (s *Server) serveAdmin(w http.ResponseWriter, r *http.Request) {
+	q := r.URL.Query()
+
+	switch strings.TrimPrefix(r.URL.Path, AdminPathPrefix) {
+	case "pause":
+		s.Pause()
+
+	case "resume":
+		s.Resume()
+
+	case "step":
+		delta, err := time.ParseDuration(q.Get("delta"))
+		if err != nil {
+			http.Error(w, "invalid delta", http.StatusBadRequest)
+			return
+		}
+
+		if it := q.Get("iteration"); it != "" {
+			n, err := strconv.Atoi(it)
+			if err != nil {
+				http.Error(w, "invalid iteration", http.StatusBadRequest)
+				return
+			}
+
+			s.StepAt(n, delta)
+		} else {
+			s.Step(delta)
+		}
+
+	case "scale":
+		factor, err := strconv.ParseFloat(q.Get("factor"), 64)
+		if err != nil || factor < 0 {
+			http.Error(w, "invalid factor", http.StatusBadRequest)
+			return
+		}
+
+		s.SetClientScale(q.Get(ClientParam), factor)
+
+	case "skew":
+		skew, err := time.ParseDuration(q.Get("offset"))
+		if err != nil {
+			http.Error(w, "invalid offset", http.StatusBadRequest)
+			return
+		}
+
+		s.SetClientSkew(q.Get(ClientParam), skew)
+
+	case "drift":
+		drift, err := time.ParseDuration(q.Get("perHour"))
+		if err != nil {
+			http.Error(w, "invalid drift", http.StatusBadRequest)
+			return
+		}
+
+		s.SetClientDrift(q.Get(ClientParam), drift)
+
+	default:
+		http.Error(w, "unknown admin command", http.StatusNotFound)
+		return
+	}
+
+	json.NewEncoder(w).Encode(timeInfo{Time: s.ServerTime()}) //nolint:errcheck,errchkjson
+}
+
+// nextTimeInfo advances the iteration counter, applies any scripted steps and returns
+// the time info for the provided client.
+func (s *Server) nextTimeInfo(clientID string) timeInfo {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.iteration++
+
+	remaining := s.pendingSteps[:0]
+
+	for _, st := range s.pendingSteps {
+		if st.iteration <= s.iteration {
+			s.stepLocked(st.delta)
+		} else {
+			remaining = append(remaining, st)
+		}
+	}
+
+	s.pendingSteps = remaining
+
+	validFor := s.ValidFor
+	if !s.pausedAt.IsZero() {
+		// force clients to ask for the time on every call while paused.
+		validFor = 0
+	}
+
+	return timeInfo{
+		Time:     s.clientTimeLocked(clientID),
+		ValidFor: validFor,
+	}
+}
+
+// +checklocks:s.mu
+func (s *Server) serverTimeLocked() time.Time {
+	if !s.pausedAt.IsZero() {
+		return s.pausedAt
+	}
+
+	return s.Now().Add(s.offset)
+}
+
+// +checklocks:s.mu
+func (s *Server) clientTimeLocked(clientID string) time.Time {
+	st := s.serverTimeLocked()
+
+	if c := s.clients[clientID]; c != nil {
+		return c.timeAt(st)
+	}
+
+	return st
+}
+
+// +checklocks:s.mu
+func (s *Server) stepLocked(delta time.Duration) {
+	s.offset += delta
+
+	if !s.pausedAt.IsZero() {
+		s.pausedAt = s.pausedAt.Add(delta)
+	}
+}
+
+// ServerTime returns the current time of the server, including all step jumps.
+func (s *Server) ServerTime() time.Time {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.serverTimeLocked()
+}
+
+// ClientTime returns the current time as seen by the provided client.
+func (s *Server) ClientTime(clientID string) time.Time {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.clientTimeLocked(clientID)
+}
+
+// Iteration returns the number of time requests served so far.
+func (s *Server) Iteration() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.iteration
+}
+
+// Step immediately moves the time of the server (and all clients) by the provided delta.
+func (s *Server) Step(delta time.Duration) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.stepLocked(delta)
+}
+
+// StepAt schedules a time jump by the provided delta to happen when the server
+// serves its n-th time request.
+func (s *Server) StepAt(iteration int, delta time.Duration) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.pendingSteps = append(s.pendingSteps, timeStep{iteration, delta})
+}
+
+// SetClientScale sets the rate at which the time of the provided client advances
+// relative to the server time. The client time remains continuous across changes.
+func (s *Server) SetClientScale(clientID string, scale float64) {
+	s.updateClient(clientID, func(c *clientClock) {
+		c.scale = scale
+	})
+}
+
+// SetClientSkew sets the constant offset of the time of the provided client
+// relative to the server time.
+func (s *Server) SetClientSkew(clientID string, skew time.Duration) {
+	s.updateClient(clientID, func(c *clientClock) {
+		c.skew = skew
+	})
+}
+
+// SetClientDrift makes the time of the provided client gradually drift away from the server
+// time by the provided amount for each hour of server time. The client time remains
+// continuous across changes.
+func (s *Server) SetClientDrift(clientID string, driftPerHour time.Duration) {
+	s.updateClient(clientID, func(c *clientClock) {
+		c.driftPerHour = driftPerHour
+	})
+}
+
+// updateClient rebases the clock of the provided client at the current server time
+// and applies the provided change to it.
+func (s *Server) updateClient(clientID string, change func(c *clientClock)) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := s.serverTimeLocked()
+
+	if s.clients == nil {
+		s.clients = map[string]*clientClock{}
+	}
+
+	c := s.clients[clientID]
+	if c == nil {
+		c = &clientClock{scale: 1}
+	}
+
+	updated := *c
+	updated.base = s.clientTimeLocked(clientID).Add(-c.skew)
+	updated.serverOrigin = now
+
+	change(&updated)
+
+	s.clients[clientID] = &updated
+}
+
+// Pause stops the passage of time until Resume() is called.
+func (s *Server) Pause() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.pausedAt.IsZero() {
+		s.pausedAt = s.serverTimeLocked()
+	}
+}
+
+// Resume resumes the passage of time from the point where it was paused.
+func (s *Server) Resume() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.pausedAt.IsZero() {
+		return
+	}
+
+	s.offset = s.pausedAt.Sub(s.Now())
+	s.pausedAt = time.Time{}
+}
+
+// NewServer creates new time server that serves time over HTTP and locally.
+func NewServer(now func() time.Time) *Server {
+	return &Server{
+		Now:      now,
+		ValidFor: DefaultValidFor,
+	}
+}
+
+// ClientEndpoint returns the value of EndpointEnvVar to be used by the provided
+// client of the fake time server available at the given base URL.
+func ClientEndpoint(baseURL, clientID string) string {
+	return baseURL + "/?" + url.Values{ClientParam: {clientID}}.Encode()
+}
+
+var _ http.Handler = (*Server)(nil)
@@ -0,0 +1,270 @@
+package faketime
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestServerSteps(t *testing.T) {
+	startTime := time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)
+	s := NewServer(Frozen(startTime))
+
+	require.Equal(t, startTime, s.ServerTime())
+
+	s.Step(time.Hour)
+	require.Equal(t, startTime.Add(time.Hour), s.ServerTime())
+
+	s.StepAt(2, time.Minute)
+
+	require.Equal(t, startTime.Add(time.Hour), s.nextTimeInfo("").Time)
+	require.Equal(t, startTime.Add(time.Hour+time.Minute), s.nextTimeInfo("").Time)
+	require.Equal(t, 2, s.Iteration())
+}
+
+func TestServerClients(t *testing.T) {
+	startTime := time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)
+	ta := NewTimeAdvance(startTime)
+	s := NewServer(ta.NowFunc())
+
+	s.SetClientScale("fast", 2)
+	s.SetClientSkew("skewed", 5*time.Minute)
+	s.SetClientDrift("drifting", time.Minute)
+
+	ta.Advance(time.Hour)
+
+	require.Equal(t, startTime.Add(time.Hour), s.ClientTime(StorageClient))
+	require.Equal(t, startTime.Add(2*time.Hour), s.ClientTime("fast"))
+	require.Equal(t, startTime.Add(time.Hour+5*time.Minute), s.ClientTime("skewed"))
+	require.Equal(t, startTime.Add(time.Hour+time.Minute), s.ClientTime("drifting"))
+
+	// changing scale keeps the client time continuous.
+	s.SetClientScale("fast", 1)
+	ta.Advance(time.Hour)
+	require.Equal(t, startTime.Add(3*time.Hour), s.ClientTime("fast"))
+}
+
+func TestServerPauseResume(t *testing.T) {
+	startTime := time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)
+	ta := NewTimeAdvance(startTime)
+	s := NewServer(ta.NowFunc())
+
+	s.Pause()
+	ta.Advance(time.Hour)
+	require.Equal(t, startTime, s.ServerTime())
+	require.Zero(t, s.nextTimeInfo("").ValidFor)
+
+	s.Resume()
+	ta.Advance(time.Hour)
+	require.Equal(t, startTime.Add(time.Hour), s.ServerTime())
+	require.Equal(t, DefaultValidFor, s.nextTimeInfo("").ValidFor)
+}
+
+func TestClient(t *testing.T) {
+	ctx := context.Background()
+	startTime := time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)
+	ta := NewTimeAdvance(startTime)
+	s := NewServer(ta.NowFunc())
+
+	hs := httptest.NewServer(s)
+	defer hs.Close()
+
+	storage := NewClient(ClientEndpoint(hs.URL, StorageClient))
+	fast := NewClient(ClientEndpoint(hs.URL, "fast"))
+
+	require.NoError(t, storage.Pause(ctx))
+
+	tm, validFor, err := storage.Time(ctx)
+	require.NoError(t, err)
+	require.Equal(t, startTime, tm)
+	require.Zero(t, validFor)
+
+	got, err := storage.Step(ctx, time.Hour)
+	require.NoError(t, err)
+	require.Equal(t, startTime.Add(time.Hour), got)
+
+	require.NoError(t, storage.StepAt(ctx, s.Iteration()+1, time.Minute))
+	require.True(t, startTime.Add(time.Hour+time.Minute).Equal(storage.NowFunc()()))
+
+	require.NoError(t, fast.SetScale(ctx, "fast", 2))
+	require.NoError(t, fast.SetSkew(ctx, "skewed", time.Second))
+	require.NoError(t, fast.SetDrift(ctx, "drifting", time.Second))
+	require.NoError(t, storage.Resume(ctx))
+
+	ta.Advance(time.Hour)
+
+	require.WithinDuration(t, startTime.Add(3*time.Hour+time.Minute), fast.NowFunc()(), time.Second)
+	require.Equal(t, time.Second, s.ClientTime("skewed").Sub(s.ClientTime(StorageClient)))
+
+	// invalid admin requests are rejected.
+	require.Error(t, fast.SetScale(ctx, "fast", -1))
+
+	resp, err := http.Get(hs.URL + AdminPathPrefix + "no-such-command") //nolint:noctx
+	require.NoError(t, err)
+	resp.Body.Close() //nolint:errcheck
+	require.Equal(t, http.StatusNotFound, resp.StatusCode)
+}
+
+func TestServerScheduledSteps(t *testing.T) {
+	startTime := time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)
+	s := NewServer(Frozen(startTime))
+
+	// steps scheduled out of order and for the same iteration are all applied.
+	s.StepAt(3, time.Hour)
+	s.StepAt(2, time.Minute)
+	s.StepAt(3, time.Second)
+
+	// step scheduled for an iteration which has already passed applies on the next request.
+	s.StepAt(0, time.Millisecond)
+
+	require.Equal(t, startTime.Add(time.Millisecond), s.nextTimeInfo("").Time)
+	require.Equal(t, startTime.Add(time.Minute+time.Millisecond), s.nextTimeInfo("").Time)
+	require.Equal(t, startTime.Add(time.Hour+time.Minute+time.Second+time.Millisecond), s.nextTimeInfo("").Time)
+	require.Equal(t, startTime.Add(time.Hour+time.Minute+time.Second+time.Millisecond), s.nextTimeInfo("").Time)
+
+	// local queries don't count as iterations.
+	s.ServerTime()
+	s.ClientTime("")
+	require.Equal(t, 4, s.Iteration())
+}
+
+func TestServerStepWhilePaused(t *testing.T) {
+	startTime := time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)
+	ta := NewTimeAdvance(startTime)
+	s := NewServer(ta.NowFunc())
+
+	s.Pause()
+	s.Pause() // pausing twice keeps the original pause time
+
+	ta.Advance(time.Hour)
+	s.Step(time.Minute)
+	require.Equal(t, startTime.Add(time.Minute), s.ServerTime())
+
+	s.StepAt(s.Iteration()+1, time.Second)
+	require.Equal(t, startTime.Add(time.Minute+time.Second), s.nextTimeInfo("").Time)
+
+	s.Resume()
+	s.Resume() // resuming when not paused does nothing
+	require.Equal(t, startTime.Add(time.Minute+time.Second), s.ServerTime())
+
+	ta.Advance(time.Hour)
+	require.Equal(t, startTime.Add(time.Hour+time.Minute+time.Second), s.ServerTime())
+}
+
+func TestServerClientScale(t *testing.T) {
+	startTime := time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)
+	ta := NewTimeAdvance(startTime)
+	s := NewServer(ta.NowFunc())
+
+	s.SetClientScale("frozen", 0)
+	s.SetClientScale("slow", 0.5)
+
+	ta.Advance(time.Hour)
+
+	require.Equal(t, startTime, s.ClientTime("frozen"))
+	require.Equal(t, startTime.Add(30*time.Minute), s.ClientTime("slow"))
+
+	// steps are scaled as well, since they move the server time.
+	s.Step(time.Hour)
+	require.Equal(t, startTime.Add(time.Hour), s.ClientTime("slow"))
+
+	// clients which are not configured follow the server time, including the one served over HTTP.
+	require.Equal(t, startTime.Add(2*time.Hour), s.ClientTime("other"))
+	require.Equal(t, startTime.Add(2*time.Hour), s.nextTimeInfo("other").Time)
+	require.Equal(t, startTime.Add(time.Hour), s.nextTimeInfo("slow").Time)
+}
+
+func TestServerAdminEndpoints(t *testing.T) {
+	startTime := time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)
+	s := NewServer(Frozen(startTime))
+
+	hs := httptest.NewServer(s)
+	defer hs.Close()
+
+	for _, tc := range []struct {
+		path       string
+		wantStatus int
+	}{
+		{"pause", http.StatusOK},
+		{"resume", http.StatusOK},
+		{"step?delta=1h", http.StatusOK},
+		{"step?delta=1m&iteration=1", http.StatusOK},
+		{"step?delta=bad", http.StatusBadRequest},
+		{"step?delta=1m&iteration=bad", http.StatusBadRequest},
+		{"scale?client=c&factor=2", http.StatusOK},
+		{"scale?client=c&factor=bad", http.StatusBadRequest},
+	} {
+		resp, err := http.Get(hs.URL + AdminPathPrefix + tc.path) //nolint:noctx
+		require.NoError(t, err)
+		resp.Body.Close() //nolint:errcheck
+		require.Equal(t, tc.wantStatus, resp.StatusCode, tc.path)
+	}
+
+	require.Equal(t, startTime.Add(time.Hour), s.ServerTime())
+	require.Equal(t, startTime.Add(time.Hour+time.Minute), s.nextTimeInfo("").Time)
+}
+
+func TestServerClientSkewAndDrift(t *testing.T) {
+	startTime := time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)
+	ta := NewTimeAdvance(startTime)
+	s := NewServer(ta.NowFunc())
+
+	s.SetClientSkew("behind", -10*time.Minute)
+	s.SetClientSkew("both", time.Minute)
+	s.SetClientDrift("both", -2*time.Minute)
+
+	ta.Advance(30 * time.Minute)
+
+	require.Equal(t, startTime.Add(20*time.Minute), s.ClientTime("behind"))
+	require.Equal(t, startTime.Add(30*time.Minute), s.ClientTime("both"))
+
+	// changing drift keeps the accumulated drift and the skew.
+	s.SetClientDrift("both", 0)
+	ta.Advance(time.Hour)
+	require.Equal(t, startTime.Add(90*time.Minute), s.ClientTime("both"))
+
+	// changing skew replaces the previous skew without affecting accumulated drift.
+	s.SetClientSkew("both", 0)
+	require.Equal(t, startTime.Add(89*time.Minute), s.ClientTime("both"))
+
+	// drift is proportional to the client scale-independent server time.
+	s.SetClientScale("scaled", 2)
+	s.SetClientDrift("scaled", time.Minute)
+	ta.Advance(time.Hour)
+	require.Equal(t, startTime.Add(90*time.Minute+2*time.Hour+time.Minute), s.ClientTime("scaled"))
+
+	// skew and drift are applied to the time served over HTTP.
+	require.Equal(t, startTime.Add(150*time.Minute-10*time.Minute), s.nextTimeInfo("behind").Time)
+}
+
+func TestServerSkewAndDriftAdminEndpoints(t *testing.T) {
+	startTime := time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)
+	ta := NewTimeAdvance(startTime)
+	s := NewServer(ta.NowFunc())
+
+	hs := httptest.NewServer(s)
+	defer hs.Close()
+
+	for _, tc := range []struct {
+		path       string
+		wantStatus int
+	}{
+		{"skew?client=c&offset=-5m", http.StatusOK},
+		{"skew?client=c&offset=bad", http.StatusBadRequest},
+		{"drift?client=c&perHour=1m", http.StatusOK},
+		{"drift?client=c&perHour=", http.StatusBadRequest},
+	} {
+		resp, err := http.Get(hs.URL + AdminPathPrefix + tc.path) //nolint:noctx
+		require.NoError(t, err)
+		resp.Body.Close() //nolint:errcheck
+		require.Equal(t, tc.wantStatus, resp.StatusCode, tc.path)
+	}
+
+	ta.Advance(time.Hour)
+	require.Equal(t, startTime.Add(time.Hour-5*time.Minute+time.Minute), s.ClientTime("c"))
+	require.Equal(t, startTime.Add(time.Hour), s.ClientTime(StorageClient))
+}
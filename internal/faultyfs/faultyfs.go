@@ -0,0 +1,203 @@
+// Package faultyfs implements a filesystem wrapper with fault injection, used for testing error handling
+// of snapshot uploads.
+package faultyfs
+
+import (
+	"context"
+	"path"
+	"sync"
+	"testing"
+
+	"github.com/kopia/kopia/fs"
+	"github.com/kopia/kopia/internal/fault"
+)
+
+// Supported faulty methods.
+const (
+	// MethodIterate is invoked when starting iteration of a directory.
+	MethodIterate fault.Method = iota
+	// MethodNext is invoked for each entry returned by directory iterator.
+	MethodNext
+	// MethodChild is invoked when looking up a directory entry by name.
+	MethodChild
+	// MethodOpen is invoked when opening a file.
+	MethodOpen
+	// MethodRead is invoked for each read from an opened file.
+	MethodRead
+)
+
+// FS injects faults into operations on entries of a wrapped filesystem tree. Faults are defined for entries
+// identified by their slash-separated path relative to the root of the tree ("." being the root itself),
+// and follow the semantics of fault.Set: faults for each entry and method are consumed in order, faults
+// without an error pass the call through, so SleepFor() can simulate slow operations and Before() can
+// modify the underlying file at a deterministic point, for example after a number of reads.
+type FS struct {
+	mu sync.Mutex
+	// +checklocks:mu
+	faults map[string]*fault.Set
+}
+
+// New returns a new FS without any faults.
+func New() *FS {
+	return &FS{
+		faults: map[string]*fault.Set{},
+	}
+}
+
+// AddFault adds a new fault for the given method of the entry with the provided relative path.
+func (f *FS) AddFault(relativePath string, method fault.Method) *fault.Fault {
+	return f.faultSet(relativePath).AddFault(method)
+}
+
+// NumCalls returns the number of calls of the given method of the entry with the provided relative path.
+func (f *FS) NumCalls(relativePath string, method fault.Method) int {
+	return f.faultSet(relativePath).NumCalls(method)
+}
+
+// VerifyAllFaultsExercised fails the test if some faults have not been exercised.
+func (f *FS) VerifyAllFaultsExercised(t *testing.T) {
+	t.Helper()
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	for _, s := range f.faults {
+		s.VerifyAllFaultsExercised(t)
+	}
+}
+
+// Wrap returns an entry which injects faults into operations on the provided entry and its descendants.
+func (f *FS) Wrap(root fs.Entry) fs.Entry {
+	return f.wrap(root, ".")
+}
+
+func (f *FS) faultSet(relativePath string) *fault.Set {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	s := f.faults[relativePath]
+	if s == nil {
+		s = fault.NewSet()
+		f.faults[relativePath] = s
+	}
+
+	return s
+}
+
+func (f *FS) nextFault(ctx context.Context, relativePath string, method fault.Method) (bool, error) {
+	//nolint:wrapcheck
+	return f.faultSet(relativePath).GetNextFault(ctx, method, relativePath)
+}
+
+func (f *FS) wrap(e fs.Entry, relativePath string) fs.Entry {
+	switch e := e.(type) {
+	case fs.Directory:
+		return fs.Directory(&faultyDirectory{e, f, relativePath})
+
+	case fs.File:
+		return fs.File(&faultyFile{e, f, relativePath})
+
+	default:
+		return e
+	}
+}
+
+type faultyDirectory struct {
+	fs.Directory
+
+	fs           *FS
+	relativePath string
+}
+
+func (d *faultyDirectory) Child(ctx context.Context, name string) (fs.Entry, error) {
+	if ok, err := d.fs.nextFault(ctx, d.relativePath, MethodChild); ok {
+		return nil, err
+	}
+
+	e, err := d.Directory.Child(ctx, name)
+	if err != nil {
+		//nolint:wrapcheck
+		return nil, err
+	}
+
+	return d.fs.wrap(e, path.Join(d.relativePath, name)), nil
+}
+
+func (d *faultyDirectory) Iterate(ctx context.Context) (fs.DirectoryIterator, error) {
+	if ok, err := d.fs.nextFault(ctx, d.relativePath, MethodIterate); ok {
+		return nil, err
+	}
+
+	iter, err := d.Directory.Iterate(ctx)
+	if err != nil {
+		//nolint:wrapcheck
+		return nil, err
+	}
+
+	return &faultyIterator{iter, d}, nil
+}
+
+type faultyIterator struct {
+	fs.DirectoryIterator
+
+	dir *faultyDirectory
+}
+
+func (it *faultyIterator) Next(ctx context.Context) (fs.Entry, error) {
+	if ok, err := it.dir.fs.nextFault(ctx, it.dir.relativePath, MethodNext); ok {
+		return nil, err
+	}
+
+	e, err := it.DirectoryIterator.Next(ctx)
+	if e == nil || err != nil {
+		//nolint:wrapcheck
+		return e, err
+	}
+
+	return it.dir.fs.wrap(e, path.Join(it.dir.relativePath, e.Name())), nil
+}
+
+type faultyFile struct {
+	fs.File
+
+	fs           *FS
+	relativePath string
+}
+
+func (f *faultyFile) Open(ctx context.Context) (fs.Reader, error) {
+	if ok, err := f.fs.nextFault(ctx, f.relativePath, MethodOpen); ok {
+		return nil, err
+	}
+
+	r, err := f.File.Open(ctx)
+	if err != nil {
+		//nolint:wrapcheck
+		return nil, err
+	}
+
+	return &faultyReader{r, ctx, f}, nil
+}
+
+type faultyReader struct {
+	fs.Reader
+
+	//nolint:containedctx
+	ctx  context.Context
+	file *faultyFile
+}
+
+func (r *faultyReader) Read(b []byte) (int, error) {
+	if ok, err := r.file.fs.nextFault(r.ctx, r.file.relativePath, MethodRead); ok {
+		return 0, err
+	}
+
+	//nolint:wrapcheck
+	return r.Reader.Read(b)
+}
+
+var (
+	_ fs.Directory         = &faultyDirectory{}
+	_ fs.DirectoryIterator = &faultyIterator{}
+	_ fs.File              = &faultyFile{}
+	_ fs.Reader            = &faultyReader{}
+)
@@ -0,0 +1,90 @@
+package faultyfs_test
+
+import (
+	"io"
+	"os"
+	"path/filepath"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/kopia/kopia/fs"
+	"github.com/kopia/kopia/fs/localfs"
+	"github.com/kopia/kopia/internal/faultyfs"
+	"github.com/kopia/kopia/internal/testlogging"
+	"github.com/kopia/kopia/internal/testutil"
+	"github.com/kopia/kopia/internal/timetrack"
+)
+
+func TestFaultyFS(t *testing.T) {
+	ctx := testlogging.Context(t)
+
+	dir := testutil.TempDirectory(t)
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "a"), []byte("aaa"), 0o600))
+	require.NoError(t, os.Mkdir(filepath.Join(dir, "sub"), 0o700))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "sub", "b"), []byte("bbbbbb"), 0o600))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "sub", "c"), []byte("ccc"), 0o600))
+
+	src, err := localfs.Directory(dir)
+	require.NoError(t, err)
+
+	ffs := faultyfs.New()
+	ffs.AddFault(".", faultyfs.MethodNext)
+	ffs.AddFault("sub", faultyfs.MethodIterate).ErrorInstead(syscall.EIO)
+	ffs.AddFault("sub/b", faultyfs.MethodOpen).ErrorInstead(&os.PathError{Op: "open", Path: "sub/b", Err: syscall.EACCES})
+	ffs.AddFault("sub/b", faultyfs.MethodRead).SleepFor(100 * time.Millisecond)
+	ffs.AddFault("sub/b", faultyfs.MethodRead).Before(func() {
+		// the file changes after it has been opened.
+		require.NoError(t, os.WriteFile(filepath.Join(dir, "sub", "b"), []byte("changed"), 0o600))
+	})
+
+	root, ok := ffs.Wrap(src).(fs.Directory)
+	require.True(t, ok)
+
+	entries, err := fs.GetAllEntries(ctx, root)
+	require.NoError(t, err)
+	require.Len(t, entries, 2)
+
+	e, err := root.Child(ctx, "sub")
+	require.NoError(t, err)
+
+	subdir, ok := e.(fs.Directory)
+	require.True(t, ok)
+
+	_, err = fs.GetAllEntries(ctx, subdir)
+	require.ErrorIs(t, err, syscall.EIO)
+
+	// subsequent iteration succeeds.
+	subEntries, err := fs.GetAllEntries(ctx, subdir)
+	require.NoError(t, err)
+	require.Len(t, subEntries, 2)
+
+	b, err := subdir.Child(ctx, "b")
+	require.NoError(t, err)
+
+	bf, ok := b.(fs.File)
+	require.True(t, ok)
+
+	_, err = bf.Open(ctx)
+	require.ErrorIs(t, err, syscall.EACCES)
+
+	r, err := bf.Open(ctx)
+	require.NoError(t, err)
+
+	defer r.Close()
+
+	timer := timetrack.StartTimer()
+	data, err := io.ReadAll(r)
+	require.NoError(t, err)
+	require.GreaterOrEqual(t, timer.Elapsed(), 100*time.Millisecond)
+	// the first read returned the original contents, the second one sees the contents appended by the change.
+	require.Equal(t, "bbbbbbd", string(data))
+
+	require.Equal(t, 3, ffs.NumCalls(".", faultyfs.MethodNext))
+	require.Equal(t, 2, ffs.NumCalls("sub", faultyfs.MethodIterate))
+	require.Equal(t, 2, ffs.NumCalls("sub/b", faultyfs.MethodOpen))
+
+	ffs.VerifyAllFaultsExercised(t)
+}
@@ -0,0 +1,346 @@
+// Package fsstress generates synthetic trees of files and mutates them deterministically,
+// for use in stress tests and benchmarks.
+//
+// A Generator is created for a directory and a seed. The tree is created from a TreeSpec
+// and subsequently mutated according to a ChurnModel. Given the same seed, spec and sequence
+// of churn models, the generator produces identical file names and contents.
+package fsstress
+
+import (
+	"fmt"
+	"math/rand"
+	"os"
+	"path/filepath"
+
+	"github.com/pkg/errors"
+)
+
+const (
+	defaultMaxFileSize   = 100000
+	defaultMaxModifySize = 10000
+
+	dirPermissions  = 0o700
+	filePermissions = 0o600
+
+	percent = 100
+)
+
+// TreeSpec describes the initial tree of files.
+type TreeSpec struct {
+	FileCount int `json:"fileCount"`
+
+	// DirCount is the number of subdirectories among which the files are randomly distributed.
+	// When zero, all files are created in the root directory.
+	DirCount int `json:"dirCount,omitempty"`
+
+	// MaxFileSize is the maximum size of newly created files.
+	MaxFileSize int `json:"maxFileSize"`
+}
+
+// ChurnModel describes how a tree of files changes in each iteration. All percentages are
+// relative to the number of files present at the start of the iteration.
+type ChurnModel struct {
+	AddPercent    float64 `json:"addPercent"`
+	DeletePercent float64 `json:"deletePercent"`
+	ModifyPercent float64 `json:"modifyPercent"`
+	RenamePercent float64 `json:"renamePercent"`
+
+	// AppendPercent is the percentage of modifications that append data at the end of the
+	// file, the remaining ones overwrite a random range of the file in place.
+	AppendPercent float64 `json:"appendPercent"`
+
+	// MaxModifySize is the maximum number of bytes written by a single modification.
+	MaxModifySize int `json:"maxModifySize"`
+}
+
+// ChurnStats describes the churn that was actually applied.
+type ChurnStats struct {
+	Added        int   `json:"added"`
+	Deleted      int   `json:"deleted"`
+	Appended     int   `json:"appended"`
+	Overwritten  int   `json:"overwritten"`
+	Renamed      int   `json:"renamed"`
+	BytesWritten int64 `json:"bytesWritten"`
+}
+
+func (s ChurnStats) String() string {
+	return fmt.Sprintf("added=%v deleted=%v appended=%v overwritten=%v renamed=%v bytes=%v",
+		s.Added, s.Deleted, s.Appended, s.Overwritten, s.Renamed, s.BytesWritten)
+}
+
+// Add adds the provided stats to s.
+func (s *ChurnStats) Add(o ChurnStats) {
+	s.Added += o.Added
+	s.Deleted += o.Deleted
+	s.Appended += o.Appended
+	s.Overwritten += o.Overwritten
+	s.Renamed += o.Renamed
+	s.BytesWritten += o.BytesWritten
+}
+
+// Generator creates and mutates a tree of files in a directory.
+type Generator struct {
+	dir   string
+	rnd   *rand.Rand
+	spec  TreeSpec
+	files []string // relative paths
+}
+
+// NewGenerator returns a Generator of the files in the provided directory using the provided seed.
+func NewGenerator(dir string, seed int64) *Generator {
+	return &Generator{
+		dir: dir,
+		rnd: rand.New(rand.NewSource(seed)), //nolint:gosec
+	}
+}
+
+// Dir returns the root directory of the tree.
+func (g *Generator) Dir() string {
+	return g.dir
+}
+
+// Files returns the relative paths of the files currently in the tree.
+func (g *Generator) Files() []string {
+	return append([]string(nil), g.files...)
+}
+
+// CreateTree creates the initial tree of files according to the spec.
+func (g *Generator) CreateTree(spec TreeSpec) error {
+	if spec.MaxFileSize <= 0 {
+		spec.MaxFileSize = defaultMaxFileSize
+	}
+
+	g.spec = spec
+
+	if err := os.MkdirAll(g.dir, dirPermissions); err != nil {
+		return errors.Wrap(err, "unable to create root directory")
+	}
+
+	for i := range spec.DirCount {
+		if err := os.MkdirAll(filepath.Join(g.dir, subdirName(i)), dirPermissions); err != nil {
+			return errors.Wrap(err, "unable to create subdirectory")
+		}
+	}
+
+	for range spec.FileCount {
+		if _, err := g.addFile(g.randomSubdir(), spec.MaxFileSize); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// AddFiles creates the provided number of new files of at most maxFileSize bytes in the
+// provided subdirectory of the tree, which is created if needed.
+func (g *Generator) AddFiles(subdir string, count, maxFileSize int) (ChurnStats, error) {
+	var stats ChurnStats
+
+	if err := os.MkdirAll(filepath.Join(g.dir, subdir), dirPermissions); err != nil {
+		return stats, errors.Wrap(err, "unable to create subdirectory")
+	}
+
+	for range count {
+		l, err := g.addFile(subdir, maxFileSize)
+		if err != nil {
+			return stats, err
+		}
+
+		stats.Added++
+		stats.BytesWritten += int64(l)
+	}
+
+	return stats, nil
+}
+
+// Rescan refreshes the list of files from the contents of the directory, which is needed
+// after the tree has been modified by means other than the generator.
+func (g *Generator) Rescan() error {
+	var files []string
+
+	err := filepath.WalkDir(g.dir, func(path string, d os.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+
+		if !d.Type().IsRegular() {
+			return nil
+		}
+
+		rel, err := filepath.Rel(g.dir, path)
+		if err != nil {
+			return errors.Wrap(err, "unable to determine relative path")
+		}
+
+		files = append(files, rel)
+
+		return nil
+	})
+	if err != nil {
+		return errors.Wrap(err, "unable to scan directory")
+	}
+
+	g.files = files
+
+	return nil
+}
+
+// Tweak mutates the tree according to the churn model and returns the churn that was applied.
+func (g *Generator) Tweak(m ChurnModel) (ChurnStats, error) {
+	var stats ChurnStats
+
+	if m.MaxModifySize <= 0 {
+		m.MaxModifySize = defaultMaxModifySize
+	}
+
+	n := len(g.files)
+
+	// shuffle, so that each kind of mutation applies to a different random subset of files.
+	g.rnd.Shuffle(len(g.files), func(i, j int) { g.files[i], g.files[j] = g.files[j], g.files[i] })
+
+	for range min(g.percentOf(n, m.DeletePercent), len(g.files)) {
+		last := len(g.files) - 1
+
+		if err := os.Remove(filepath.Join(g.dir, g.files[last])); err != nil {
+			return stats, errors.Wrap(err, "unable to delete file")
+		}
+
+		g.files = g.files[:last]
+		stats.Deleted++
+	}
+
+	for i := range min(g.percentOf(n, m.ModifyPercent), len(g.files)) {
+		data := g.randomData(m.MaxModifySize)
+
+		if g.rnd.Float64()*percent < m.AppendPercent {
+			if err := appendToFile(filepath.Join(g.dir, g.files[i]), data); err != nil {
+				return stats, err
+			}
+
+			stats.Appended++
+		} else {
+			if err := g.overwriteFile(filepath.Join(g.dir, g.files[i]), data); err != nil {
+				return stats, err
+			}
+
+			stats.Overwritten++
+		}
+
+		stats.BytesWritten += int64(len(data))
+	}
+
+	// rename files from the end of the list, which are the least likely to have been modified.
+	renameCount := min(g.percentOf(n, m.RenamePercent), len(g.files))
+	for i := len(g.files) - renameCount; i < len(g.files); i++ {
+		newName := filepath.Join(filepath.Dir(g.files[i]), g.randomFileName())
+
+		if err := os.Rename(filepath.Join(g.dir, g.files[i]), filepath.Join(g.dir, newName)); err != nil {
+			return stats, errors.Wrap(err, "unable to rename file")
+		}
+
+		g.files[i] = newName
+		stats.Renamed++
+	}
+
+	for range g.percentOf(n, m.AddPercent) {
+		l, err := g.addFile(g.randomSubdir(), g.spec.MaxFileSize)
+		if err != nil {
+			return stats, err
+		}
+
+		stats.Added++
+		stats.BytesWritten += int64(l)
+	}
+
+	return stats, nil
+}
+
+// randomSubdir returns a random subdirectory from the tree spec or an empty string
+// when the spec has no subdirectories.
+func (g *Generator) randomSubdir() string {
+	if g.spec.DirCount == 0 {
+		return ""
+	}
+
+	return subdirName(g.rnd.Intn(g.spec.DirCount))
+}
+
+func (g *Generator) addFile(subdir string, maxFileSize int) (int, error) {
+	if maxFileSize <= 0 {
+		maxFileSize = defaultMaxFileSize
+	}
+
+	name := filepath.Join(subdir, g.randomFileName())
+	data := g.randomData(maxFileSize)
+
+	if err := os.WriteFile(filepath.Join(g.dir, name), data, filePermissions); err != nil {
+		return 0, errors.Wrap(err, "unable to create file")
+	}
+
+	g.files = append(g.files, name)
+
+	return len(data), nil
+}
+
+func (g *Generator) overwriteFile(fname string, data []byte) error {
+	fi, err := os.Stat(fname)
+	if err != nil {
+		return errors.Wrap(err, "unable to stat file")
+	}
+
+	f, err := os.OpenFile(fname, os.O_WRONLY, 0) //nolint:gosec
+	if err != nil {
+		return errors.Wrap(err, "unable to open file")
+	}
+
+	defer f.Close() //nolint:errcheck
+
+	if _, err := f.WriteAt(data, g.rnd.Int63n(fi.Size()+1)); err != nil {
+		return errors.Wrap(err, "unable to overwrite file")
+	}
+
+	return errors.Wrap(f.Close(), "unable to close file")
+}
+
+func appendToFile(fname string, data []byte) error {
+	f, err := os.OpenFile(fname, os.O_APPEND|os.O_WRONLY, 0) //nolint:gosec
+	if err != nil {
+		return errors.Wrap(err, "unable to open file")
+	}
+
+	defer f.Close() //nolint:errcheck
+
+	if _, err := f.Write(data); err != nil {
+		return errors.Wrap(err, "unable to append to file")
+	}
+
+	return errors.Wrap(f.Close(), "unable to close file")
+}
+
+// percentOf returns the number of items corresponding to the given percentage of n,
+// randomly rounding the fractional part so that the expected value is exact.
+func (g *Generator) percentOf(n int, pct float64) int {
+	v := float64(n) * pct / percent
+	whole := int(v)
+
+	if g.rnd.Float64() < v-float64(whole) {
+		whole++
+	}
+
+	return whole
+}
+
+func (g *Generator) randomData(maxLength int) []byte {
+	data := make([]byte, g.rnd.Intn(maxLength)+1)
+	g.rnd.Read(data)
+
+	return data
+}
+
+func (g *Generator) randomFileName() string {
+	return fmt.Sprintf("file-%016x", g.rnd.Uint64())
+}
+
+func subdirName(i int) string {
+	return fmt.Sprintf("dir-%04d", i)
+}
@@ -0,0 +1,139 @@
+package fsstress_test
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/kopia/kopia/internal/fsstress"
+)
+
+func TestGeneratorIsDeterministic(t *testing.T) {
+	spec := fsstress.TreeSpec{FileCount: 100, DirCount: 5, MaxFileSize: 1000}
+	m := fsstress.ChurnModel{
+		AddPercent:    10,
+		DeletePercent: 10,
+		ModifyPercent: 20,
+		RenamePercent: 5,
+		AppendPercent: 50,
+		MaxModifySize: 100,
+	}
+
+	run := func(seed int64) (map[string]string, []fsstress.ChurnStats) {
+		dir := t.TempDir()
+		g := fsstress.NewGenerator(dir, seed)
+		require.NoError(t, g.CreateTree(spec))
+		require.Len(t, g.Files(), spec.FileCount)
+
+		var allStats []fsstress.ChurnStats
+
+		for range 3 {
+			stats, err := g.Tweak(m)
+			require.NoError(t, err)
+
+			allStats = append(allStats, stats)
+		}
+
+		verifyFiles(t, dir, g.Files())
+
+		return dirContents(t, dir, g.Files()), allStats
+	}
+
+	h1, s1 := run(1)
+	h2, s2 := run(1)
+	h3, _ := run(2)
+
+	require.Equal(t, h1, h2)
+	require.Equal(t, s1, s2)
+	require.NotEqual(t, h1, h3)
+}
+
+func TestTweakStats(t *testing.T) {
+	g := fsstress.NewGenerator(t.TempDir(), 1)
+	require.NoError(t, g.CreateTree(fsstress.TreeSpec{FileCount: 100, MaxFileSize: 100}))
+
+	stats, err := g.Tweak(fsstress.ChurnModel{
+		AddPercent:    10,
+		DeletePercent: 20,
+		ModifyPercent: 30,
+		RenamePercent: 5,
+		AppendPercent: 100,
+	})
+	require.NoError(t, err)
+
+	require.Equal(t, 10, stats.Added)
+	require.Equal(t, 20, stats.Deleted)
+	require.Equal(t, 30, stats.Appended)
+	require.Equal(t, 0, stats.Overwritten)
+	require.Equal(t, 5, stats.Renamed)
+	require.Len(t, g.Files(), 90)
+
+	var total fsstress.ChurnStats
+
+	total.Add(stats)
+	total.Add(stats)
+	require.Equal(t, 2*stats.BytesWritten, total.BytesWritten)
+}
+func TestAddFilesAndRescan(t *testing.T) {
+	dir := t.TempDir()
+	g := fsstress.NewGenerator(dir, 1)
+
+	stats, err := g.AddFiles(filepath.Join("a", "b"), 10, 100)
+	require.NoError(t, err)
+	require.Equal(t, 10, stats.Added)
+	require.Len(t, g.Files(), 10)
+	verifyFiles(t, dir, g.Files())
+
+	// files removed behind the generator's back are forgotten after rescan.
+	require.NoError(t, os.RemoveAll(filepath.Join(dir, "a")))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "other"), []byte{1}, 0o600))
+	require.NoError(t, g.Rescan())
+	require.Equal(t, []string{"other"}, g.Files())
+
+	_, err = g.Tweak(fsstress.ChurnModel{AddPercent: 100, ModifyPercent: 100})
+	require.NoError(t, err)
+	require.Len(t, g.Files(), 2)
+	verifyFiles(t, dir, g.Files())
+}
+
+//nolint:thelper
+func verifyFiles(t *testing.T, dir string, files []string) {
+	var found []string
+
+	require.NoError(t, filepath.Walk(dir, func(path string, fi os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+
+		if fi.Mode().IsRegular() {
+			rel, err := filepath.Rel(dir, path)
+			if err != nil {
+				return err
+			}
+
+			found = append(found, rel)
+		}
+
+		return nil
+	}))
+
+	require.ElementsMatch(t, files, found)
+}
+
+// dirContents returns the contents of all files in the directory keyed by relative path.
+//
+//nolint:thelper
+func dirContents(t *testing.T, dir string, files []string) map[string]string {
+	result := map[string]string{}
+
+	for _, f := range files {
+		b, err := os.ReadFile(filepath.Join(dir, f))
+		require.NoError(t, err)
+
+		result[f] = string(b)
+	}
+
+	return result
+}
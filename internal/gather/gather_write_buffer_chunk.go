@@ -167,6 +167,45 @@ func (a *chunkAllocator) dumpStats(ctx context.Context, prefix string) {
 	}
 }
 
+// AllocatorStats contains statistics of a chunk allocator.
+type AllocatorStats struct {
+	Name                  string `json:"name"`
+	ChunkSize             int    `json:"chunkSize"`
+	ChunksAllocated       int    `json:"chunksAllocated"`
+	ChunksFreed           int    `json:"chunksFreed"`
+	ChunksAlive           int    `json:"chunksAlive"`
+	FreeListSize          int    `json:"freeListSize"`
+	AllocHighWaterMark    int    `json:"allocHighWaterMark"`
+	FreeListHighWaterMark int    `json:"freeListHighWaterMark"`
+	SlicesAllocated       int    `json:"slicesAllocated"`
+}
+
+func (a *chunkAllocator) stats() AllocatorStats {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	return AllocatorStats{
+		Name:                  a.name,
+		ChunkSize:             a.chunkSize,
+		ChunksAllocated:       a.allocated,
+		ChunksFreed:           a.freed,
+		ChunksAlive:           a.allocated - a.freed,
+		FreeListSize:          len(a.freeList),
+		AllocHighWaterMark:    a.allocHighWaterMark,
+		FreeListHighWaterMark: a.freeListHighWaterMark,
+		SlicesAllocated:       a.slicesAllocated,
+	}
+}
+
+// GetAllocatorStats returns the statistics of all allocators.
+func GetAllocatorStats() []AllocatorStats {
+	return []AllocatorStats{
+		defaultAllocator.stats(),
+		typicalContiguousAllocator.stats(),
+		maxContiguousAllocator.stats(),
+	}
+}
+
 // DumpStats logs the allocator statistics.
 func DumpStats(ctx context.Context) {
 	defaultAllocator.dumpStats(ctx, "default")
@@ -111,3 +111,32 @@ func TestTrackAllocation(t *testing.T) {
 	require.Contains(t, log.String(), `"chunksAlive":0`)
 	require.NotContains(t, log.String(), "leaked chunk")
 }
+
+func TestAllocatorStats(t *testing.T) {
+	all := &chunkAllocator{
+		name:            "test",
+		chunkSize:       100,
+		maxFreeListSize: 10,
+	}
+
+	chunk1 := all.allocChunk()
+	chunk2 := all.allocChunk()
+
+	all.releaseChunk(chunk1)
+
+	require.Equal(t, AllocatorStats{
+		Name:                  "test",
+		ChunkSize:             100,
+		ChunksAllocated:       2,
+		ChunksFreed:           1,
+		ChunksAlive:           1,
+		FreeListSize:          1,
+		AllocHighWaterMark:    2,
+		FreeListHighWaterMark: 1,
+		SlicesAllocated:       2,
+	}, all.stats())
+
+	all.releaseChunk(chunk2)
+
+	require.Len(t, GetAllocatorStats(), 3)
+}
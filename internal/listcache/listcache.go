@@ -1,11 +1,15 @@
 // Package listcache defines a blob.Storage wrapper that caches results of list calls
 // for short duration of time.
+//
+// Cached results are persisted in the provided cache storage or, when there is none,
+// kept in memory for the lifetime of the wrapper.
 package listcache
 
 import (
 	"context"
 	"encoding/json"
 	"strings"
+	"sync"
 	"time"
 
 	"github.com/pkg/errors"
@@ -21,11 +25,19 @@
 
 type listCacheStorage struct {
 	blob.Storage
-	cacheStorage  blob.Storage
+	cacheStorage  blob.Storage // nil when caching in memory
 	cacheDuration time.Duration
 	cacheTimeFunc func() time.Time
 	hmacSecret    []byte
 	prefixes      []blob.ID
+
+	mu sync.Mutex
+	// +checklocks:mu
+	inMemory map[blob.ID]*cachedList
+	// generation is incremented on each in-memory invalidation, to avoid caching list results
+	// which may have been obtained before a concurrent write.
+	// +checklocks:mu
+	generation int64
 }
 
 type cachedList struct {
@@ -33,7 +45,18 @@ type cachedList struct {
 	Blobs       []blob.Metadata `json:"blobs"`
 }
 
-func (s *listCacheStorage) saveListToCache(ctx context.Context, prefix blob.ID, cl *cachedList) {
+func (s *listCacheStorage) saveListToCache(ctx context.Context, prefix blob.ID, cl *cachedList, generation int64) {
+	if s.cacheStorage == nil {
+		s.mu.Lock()
+		defer s.mu.Unlock()
+
+		if s.generation == generation {
+			s.inMemory[prefix] = cl
+		}
+
+		return
+	}
+
 	data, err := json.Marshal(cl)
 	if err != nil {
 		log(ctx).Debugf("unable to marshal list cache entry: %v", err)
@@ -50,7 +73,24 @@ func (s *listCacheStorage) saveListToCache(ctx context.Context, prefix blob.ID,
 	}
 }
 
-func (s *listCacheStorage) readBlobsFromCache(ctx context.Context, prefix blob.ID) *cachedList {
+func (s *listCacheStorage) readBlobsFromCache(ctx context.Context, prefix blob.ID) (*cachedList, int64) {
+	if s.cacheStorage == nil {
+		s.mu.Lock()
+		defer s.mu.Unlock()
+
+		if cl := s.inMemory[prefix]; cl != nil && s.cacheTimeFunc().Before(cl.ExpireAfter) {
+			return cl, s.generation
+		}
+
+		delete(s.inMemory, prefix)
+
+		return nil, s.generation
+	}
+
+	return s.readBlobsFromCacheStorage(ctx, prefix), 0
+}
+
+func (s *listCacheStorage) readBlobsFromCacheStorage(ctx context.Context, prefix blob.ID) *cachedList {
 	cl := &cachedList{}
 
 	var data gather.WriteBuffer
@@ -88,7 +128,7 @@ func (s *listCacheStorage) ListBlobs(ctx context.Context, prefix blob.ID, cb fun
 		return s.Storage.ListBlobs(ctx, prefix, cb)
 	}
 
-	cached := s.readBlobsFromCache(ctx, prefix)
+	cached, generation := s.readBlobsFromCache(ctx, prefix)
 	if cached == nil {
 		all, err := blob.ListAllBlobs(ctx, s.Storage, prefix)
 		if err != nil {
@@ -101,7 +141,7 @@ func (s *listCacheStorage) ListBlobs(ctx context.Context, prefix blob.ID, cb fun
 			Blobs:       all,
 		}
 
-		s.saveListToCache(ctx, prefix, cached)
+		s.saveListToCache(ctx, prefix, cached, generation)
 	}
 
 	for _, v := range cached.Blobs {
@@ -127,6 +167,16 @@ func (s *listCacheStorage) FlushCaches(ctx context.Context) error {
 		return errors.Wrap(err, "error flushing caches")
 	}
 
+	if s.cacheStorage == nil {
+		s.mu.Lock()
+		defer s.mu.Unlock()
+
+		clear(s.inMemory)
+		s.generation++
+
+		return nil
+	}
+
 	return errors.Wrap(blob.DeleteMultiple(ctx, s.cacheStorage, s.prefixes, len(s.prefixes)), "error deleting cached lists")
 }
 
@@ -150,6 +200,21 @@ func (s *listCacheStorage) isCachedPrefix(prefix blob.ID) bool {
 }
 
 func (s *listCacheStorage) invalidateAfterUpdate(ctx context.Context, blobID blob.ID) {
+	if s.cacheStorage == nil {
+		s.mu.Lock()
+		defer s.mu.Unlock()
+
+		s.generation++
+
+		for _, p := range s.prefixes {
+			if strings.HasPrefix(string(blobID), string(p)) {
+				delete(s.inMemory, p)
+			}
+		}
+
+		return
+	}
+
 	for _, p := range s.prefixes {
 		if strings.HasPrefix(string(blobID), string(p)) {
 			if err := s.cacheStorage.DeleteBlob(ctx, p); err != nil {
@@ -176,4 +241,25 @@ func NewWrapper(st, cacheStorage blob.Storage, prefixes []blob.ID, hmacSecret []
 	}
 }
 
+// NewInMemoryWrapper returns new wrapper that caches list results for the given set of blob prefixes in memory,
+// for use when there is no local cache storage. Cached results are discarded after local writes and deletions
+// of matching blobs. When the duration is not positive, the wrapped storage is returned as-is.
+func NewInMemoryWrapper(st blob.Storage, prefixes []blob.ID, duration time.Duration, timeNow func() time.Time) blob.Storage {
+	if duration <= 0 {
+		return st
+	}
+
+	if timeNow == nil {
+		timeNow = clock.Now
+	}
+
+	return &listCacheStorage{
+		Storage:       st,
+		prefixes:      prefixes,
+		cacheTimeFunc: timeNow,
+		cacheDuration: duration,
+		inMemory:      map[blob.ID]*cachedList{},
+	}
+}
+
 var _ blob.Storage = (*listCacheStorage)(nil)
@@ -90,3 +90,62 @@ func TestListCache(t *testing.T) {
 		return errFake
 	}), errFake)
 }
+
+func TestInMemoryListCache(t *testing.T) {
+	ctx := testlogging.Context(t)
+
+	ta := faketime.NewTimeAdvance(time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC))
+	ms := blobtesting.NewMapStorage(blobtesting.DataMap{}, nil, ta.NowFunc())
+	fs := blobtesting.NewFaultyStorage(ms)
+
+	lc := NewInMemoryWrapper(fs, []blob.ID{"n", "x"}, time.Minute, ta.NowFunc())
+
+	require.NoError(t, ms.PutBlob(ctx, "n1", gather.FromSlice([]byte{1}), blob.PutOptions{}))
+
+	// first listing goes to the underlying storage, next one is cached.
+	blobtesting.AssertListResultsIDs(ctx, t, lc, "n", "n1")
+	blobtesting.AssertListResultsIDs(ctx, t, lc, "n", "n1")
+	require.Equal(t, 1, fs.NumCalls(blobtesting.MethodListBlobs))
+
+	// writes by other clients are not visible until the cached results expire.
+	require.NoError(t, ms.PutBlob(ctx, "n2", gather.FromSlice([]byte{1}), blob.PutOptions{}))
+	blobtesting.AssertListResultsIDs(ctx, t, lc, "n", "n1")
+	require.Equal(t, 1, fs.NumCalls(blobtesting.MethodListBlobs))
+
+	ta.Advance(time.Minute)
+	blobtesting.AssertListResultsIDs(ctx, t, lc, "n", "n1", "n2")
+	require.Equal(t, 2, fs.NumCalls(blobtesting.MethodListBlobs))
+
+	// local writes and deletions invalidate cached results of matching prefixes only.
+	blobtesting.AssertListResultsIDs(ctx, t, lc, "x")
+	require.Equal(t, 3, fs.NumCalls(blobtesting.MethodListBlobs))
+
+	require.NoError(t, lc.PutBlob(ctx, "n3", gather.FromSlice([]byte{1}), blob.PutOptions{}))
+	blobtesting.AssertListResultsIDs(ctx, t, lc, "n", "n1", "n2", "n3")
+	blobtesting.AssertListResultsIDs(ctx, t, lc, "x")
+	require.Equal(t, 4, fs.NumCalls(blobtesting.MethodListBlobs))
+
+	require.NoError(t, lc.DeleteBlob(ctx, "n1"))
+	blobtesting.AssertListResultsIDs(ctx, t, lc, "n", "n2", "n3")
+	require.Equal(t, 5, fs.NumCalls(blobtesting.MethodListBlobs))
+
+	// prefixes which are not configured are never cached.
+	blobtesting.AssertListResultsIDs(ctx, t, lc, "", "n2", "n3")
+	blobtesting.AssertListResultsIDs(ctx, t, lc, "", "n2", "n3")
+	require.Equal(t, 7, fs.NumCalls(blobtesting.MethodListBlobs))
+
+	// flushing caches discards all cached results.
+	require.NoError(t, lc.FlushCaches(ctx))
+	blobtesting.AssertListResultsIDs(ctx, t, lc, "n", "n2", "n3")
+	require.Equal(t, 8, fs.NumCalls(blobtesting.MethodListBlobs))
+
+	// errors are not cached.
+	fs.AddFault(blobtesting.MethodListBlobs).ErrorInstead(errFake)
+	require.NoError(t, lc.FlushCaches(ctx))
+	require.ErrorIs(t, lc.ListBlobs(ctx, "n", func(blob.Metadata) error { return nil }), errFake)
+	blobtesting.AssertListResultsIDs(ctx, t, lc, "n", "n2", "n3")
+	fs.VerifyAllFaultsExercised(t)
+
+	// caching is disabled without duration.
+	require.Equal(t, blob.Storage(ms), NewInMemoryWrapper(ms, []blob.ID{"n"}, 0, nil))
+}
@@ -2,8 +2,10 @@
 package logfile
 
 import (
+	"compress/gzip"
 	"context"
 	"fmt"
+	"io"
 	"math"
 	"os"
 	"path/filepath"
@@ -31,6 +33,11 @@
 //nolint:gochecknoglobals
 var logLevels = []string{"debug", "info", "warning", "error"}
 
+const (
+	logFormatText = "text"
+	logFormatJSON = "json"
+)
+
 type loggingFlags struct {
 	logFile                     string
 	contentLogFile              string
@@ -42,7 +49,9 @@ type loggingFlags struct {
 	contentLogDirMaxAge         time.Duration
 	contentLogDirMaxTotalSizeMB float64
 	logFileMaxSegmentSize       int
+	compressRotatedLogs         bool
 	logLevel                    string
+	logFormat                   string
 	fileLogLevel                string
 	fileLogLocalTimezone        bool
 	jsonLogFile                 bool
@@ -60,15 +69,17 @@ func (c *loggingFlags) setup(cliApp *cli.App, app *kingpin.Application) {
 	app.Flag("content-log-file", "Override content log file.").Hidden().StringVar(&c.contentLogFile)
 
 	app.Flag("log-dir", "Directory where log files should be written.").Envar(cliApp.EnvName("KOPIA_LOG_DIR")).Default(ospath.LogsDir()).StringVar(&c.logDir)
-	app.Flag("log-dir-max-files", "Maximum number of log files to retain").Envar(cliApp.EnvName("KOPIA_LOG_DIR_MAX_FILES")).Default("1000").Hidden().IntVar(&c.logDirMaxFiles)
-	app.Flag("log-dir-max-age", "Maximum age of log files to retain").Envar(cliApp.EnvName("KOPIA_LOG_DIR_MAX_AGE")).Hidden().Default("720h").DurationVar(&c.logDirMaxAge)
-	app.Flag("log-dir-max-total-size-mb", "Maximum total size of log files to retain").Envar(cliApp.EnvName("KOPIA_LOG_DIR_MAX_SIZE_MB")).Hidden().Default("1000").Float64Var(&c.logDirMaxTotalSizeMB)
-	app.Flag("max-log-file-segment-size", "Maximum size of a single log file segment").Envar(cliApp.EnvName("KOPIA_LOG_FILE_MAX_SEGMENT_SIZE")).Default("50000000").Hidden().IntVar(&c.logFileMaxSegmentSize)
+	app.Flag("log-dir-max-files", "Maximum number of log files to retain").Envar(cliApp.EnvName("KOPIA_LOG_DIR_MAX_FILES")).Default("1000").IntVar(&c.logDirMaxFiles)
+	app.Flag("log-dir-max-age", "Maximum age of log files to retain").Envar(cliApp.EnvName("KOPIA_LOG_DIR_MAX_AGE")).Default("720h").DurationVar(&c.logDirMaxAge)
+	app.Flag("log-dir-max-total-size-mb", "Maximum total size of log files to retain").Envar(cliApp.EnvName("KOPIA_LOG_DIR_MAX_SIZE_MB")).Default("1000").Float64Var(&c.logDirMaxTotalSizeMB)
+	app.Flag("max-log-file-segment-size", "Maximum size of a single log file segment").Envar(cliApp.EnvName("KOPIA_LOG_FILE_MAX_SEGMENT_SIZE")).Default("50000000").IntVar(&c.logFileMaxSegmentSize)
+	app.Flag("log-compress", "Compress log file segments using gzip when they are rotated or closed").Envar(cliApp.EnvName("KOPIA_LOG_COMPRESS")).BoolVar(&c.compressRotatedLogs)
 	app.Flag("wait-for-log-sweep", "Wait for log sweep before program exit").Default("true").Hidden().BoolVar(&c.waitForLogSweep)
-	app.Flag("content-log-dir-max-files", "Maximum number of content log files to retain").Envar(cliApp.EnvName("KOPIA_CONTENT_LOG_DIR_MAX_FILES")).Default("5000").Hidden().IntVar(&c.contentLogDirMaxFiles)
-	app.Flag("content-log-dir-max-age", "Maximum age of content log files to retain").Envar(cliApp.EnvName("KOPIA_CONTENT_LOG_DIR_MAX_AGE")).Default("720h").Hidden().DurationVar(&c.contentLogDirMaxAge)
-	app.Flag("content-log-dir-max-total-size-mb", "Maximum total size of log files to retain").Envar(cliApp.EnvName("KOPIA_CONTENT_LOG_DIR_MAX_SIZE_MB")).Hidden().Default("1000").Float64Var(&c.contentLogDirMaxTotalSizeMB)
+	app.Flag("content-log-dir-max-files", "Maximum number of content log files to retain").Envar(cliApp.EnvName("KOPIA_CONTENT_LOG_DIR_MAX_FILES")).Default("5000").IntVar(&c.contentLogDirMaxFiles)
+	app.Flag("content-log-dir-max-age", "Maximum age of content log files to retain").Envar(cliApp.EnvName("KOPIA_CONTENT_LOG_DIR_MAX_AGE")).Default("720h").DurationVar(&c.contentLogDirMaxAge)
+	app.Flag("content-log-dir-max-total-size-mb", "Maximum total size of log files to retain").Envar(cliApp.EnvName("KOPIA_CONTENT_LOG_DIR_MAX_SIZE_MB")).Default("1000").Float64Var(&c.contentLogDirMaxTotalSizeMB)
 	app.Flag("log-level", "Console log level").Default("info").EnumVar(&c.logLevel, logLevels...)
+	app.Flag("log-format", "Format of console and file logs (text, json - one JSON object per log entry)").Envar(cliApp.EnvName("KOPIA_LOG_FORMAT")).Default(logFormatText).EnumVar(&c.logFormat, logFormatText, logFormatJSON)
 	app.Flag("json-log-console", "JSON log file").Hidden().BoolVar(&c.jsonLogConsole)
 	app.Flag("json-log-file", "JSON log file").Hidden().BoolVar(&c.jsonLogFile)
 	app.Flag("file-log-level", "File log level").Default("debug").EnumVar(&c.fileLogLevel, logLevels...)
@@ -92,6 +103,9 @@ func Attach(cliApp *cli.App, app *kingpin.Application) {
 const (
 	logFileNamePrefix = "kopia-"
 	logFileNameSuffix = ".log"
+
+	// compressedLogFileSuffix is appended to names of compressed log file segments.
+	compressedLogFileSuffix = ".gz"
 )
 
 // initialize is invoked as part of command execution to create log file just before it's needed.
@@ -107,6 +121,19 @@ func (c *loggingFlags) initialize(ctx *kingpin.ParseContext) error {
 		now = now.UTC()
 	}
 
+	if c.logFormat == logFormatJSON {
+		c.jsonLogConsole = true
+		c.jsonLogFile = true
+	}
+
+	// console logs share standard error with JSON-lines progress events, do not mix in plain text.
+	if c.cliApp.JSONLProgressOnStderr() {
+		c.jsonLogConsole = true
+	}
+
+	// span IDs allow correlating JSON log entries with traces.
+	c.cliApp.SetLogSpanIDs(c.jsonLogConsole || c.jsonLogFile)
+
 	suffix := "unknown"
 	if c := ctx.SelectedCommand; c != nil {
 		suffix = strings.ReplaceAll(c.FullCommand(), " ", "-")
@@ -216,6 +243,9 @@ func (c *loggingFlags) setupLogFileBasedLogger(now time.Time, subdir, suffix, lo
 	sweepLogWG := &sync.WaitGroup{}
 	doSweep := func() {}
 
+	// compression and sweeps are serialized so that sweeps never observe partially-compressed segments.
+	var sweepMu sync.Mutex
+
 	// do not scrub directory if custom log file has been provided.
 	if logFileOverride == "" && shouldSweepLog(maxFiles, maxAge) {
 		doSweep = func() {
@@ -228,12 +258,21 @@ func (c *loggingFlags) setupLogFileBasedLogger(now time.Time, subdir, suffix, lo
 		logFileBaseName: logFileBaseName,
 		symlinkName:     symlinkName,
 		maxSegmentSize:  c.logFileMaxSegmentSize,
-		startSweep: func() {
+		startSweep: func(closedSegment string) {
 			sweepLogWG.Add(1)
 
 			go func() {
 				defer sweepLogWG.Done()
 
+				sweepMu.Lock()
+				defer sweepMu.Unlock()
+
+				if closedSegment != "" && c.compressRotatedLogs {
+					if err := compressLogSegment(closedSegment, symlinkName); err != nil {
+						fmt.Fprintln(os.Stderr, "Unable to compress log file:", err)
+					}
+				}
+
 				doSweep()
 			}()
 		},
@@ -245,7 +284,7 @@ func (c *loggingFlags) setupLogFileBasedLogger(now time.Time, subdir, suffix, lo
 		c.cliApp.RegisterOnExit(sweepLogWG.Wait)
 	} else {
 		// old behavior: start log sweep in parallel to program but don't wait at the end.
-		odf.startSweep()
+		odf.startSweep("")
 	}
 
 	return odf
@@ -348,7 +387,7 @@ func sweepLogDir(ctx context.Context, dirname string, maxCount int, maxSizeMB fl
 			continue
 		}
 
-		if !strings.HasSuffix(fi.Name(), logFileNameSuffix) {
+		if !strings.HasSuffix(strings.TrimSuffix(fi.Name(), compressedLogFileSuffix), logFileNameSuffix) {
 			continue
 		}
 
@@ -401,7 +440,9 @@ type onDemandFile struct {
 	// +checklocks:mu
 	symlinkName string
 
-	startSweep func()
+	// startSweep is invoked after closing a segment with the name of the closed segment,
+	// or an empty string if no segment was open.
+	startSweep func(closedSegment string)
 
 	mu sync.Mutex
 	f  *os.File
@@ -423,16 +464,21 @@ func (w *onDemandFile) closeSegmentAndSweep() {
 	w.closeSegmentAndSweepLocked()
 }
 
+// +checklocks:w.mu
 func (w *onDemandFile) closeSegmentAndSweepLocked() {
+	var closedSegment string
+
 	if w.f != nil {
 		if err := w.f.Close(); err != nil {
 			fmt.Fprintf(os.Stderr, "warning: unable to close log segment: %v", err)
 		}
 
 		w.f = nil
+
+		closedSegment = filepath.Join(w.logDir, w.currentSegmentFilename)
 	}
 
-	w.startSweep()
+	w.startSweep(closedSegment)
 }
 
 func (w *onDemandFile) Write(b []byte) (int, error) {
@@ -483,3 +529,64 @@ func (w *onDemandFile) Write(b []byte) (int, error) {
 	//nolint:wrapcheck
 	return n, err
 }
+
+// compressLogSegment compresses the provided log segment and updates the symlink in the same
+// directory to point at the compressed file, if it pointed at the segment.
+func compressLogSegment(fname, symlinkName string) error {
+	if err := compressLogFile(fname); err != nil {
+		return err
+	}
+
+	if symlinkName == "" {
+		return nil
+	}
+
+	symlink := filepath.Join(filepath.Dir(fname), symlinkName)
+
+	if target, err := os.Readlink(symlink); err == nil && target == filepath.Base(fname) {
+		_ = os.Remove(symlink)                                                // best-effort remove
+		_ = os.Symlink(filepath.Base(fname)+compressedLogFileSuffix, symlink) // best-effort symlink
+	}
+
+	return nil
+}
+
+// compressLogFile replaces the provided log file with its gzip-compressed version.
+func compressLogFile(fname string) error {
+	src, err := os.Open(fname) //nolint:gosec
+	if err != nil {
+		return errors.Wrap(err, "unable to open log file")
+	}
+
+	defer src.Close() //nolint:errcheck
+
+	tmpName := fname + compressedLogFileSuffix + ".tmp"
+
+	dst, err := os.Create(tmpName) //nolint:gosec
+	if err != nil {
+		return errors.Wrap(err, "unable to create compressed log file")
+	}
+
+	gw := gzip.NewWriter(dst)
+
+	_, err = io.Copy(gw, src)
+	if err == nil {
+		err = gw.Close()
+	}
+
+	if cerr := dst.Close(); err == nil {
+		err = cerr
+	}
+
+	if err != nil {
+		os.Remove(tmpName) //nolint:errcheck
+
+		return errors.Wrap(err, "unable to compress log file")
+	}
+
+	if err := os.Rename(tmpName, fname+compressedLogFileSuffix); err != nil {
+		return errors.Wrap(err, "unable to rename compressed log file")
+	}
+
+	return errors.Wrap(os.Remove(fname), "unable to remove uncompressed log file")
+}
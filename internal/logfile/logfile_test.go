@@ -2,7 +2,10 @@
 
 import (
 	"bufio"
+	"compress/gzip"
+	"encoding/json"
 	"fmt"
+	"io"
 	"os"
 	"path/filepath"
 	"regexp"
@@ -98,6 +101,50 @@ func TestLoggingFlags(t *testing.T) {
 	require.Empty(t, stderr)
 }
 
+func TestLogFormatJSON(t *testing.T) {
+	runner := testenv.NewInProcRunner(t)
+	runner.CustomizeApp = logfile.Attach
+
+	env := testenv.NewCLITest(t, testenv.RepoFormatNotImportant, runner)
+	env.RunAndExpectSuccess(t, "repo", "create", "filesystem", "--path", env.RepoDir)
+
+	dir1 := testutil.TempDirectory(t)
+	tmpLogDir := testutil.TempDirectory(t)
+
+	_, stderr, err := env.Run(t, false, "snap", "create", dir1,
+		"--log-format=json", "--no-progress", "--log-level=debug",
+		"--no-auto-maintenance", "--log-dir", tmpLogDir)
+	require.NoError(t, err)
+	require.NotEmpty(t, stderr)
+
+	for _, l := range stderr {
+		verifyJSONLogEntry(t, l)
+	}
+
+	f, err := os.Open(filepath.Join(tmpLogDir, "cli-logs", "latest.log"))
+	require.NoError(t, err)
+
+	defer f.Close()
+
+	s := bufio.NewScanner(f)
+
+	for s.Scan() {
+		verifyJSONLogEntry(t, s.Text())
+	}
+
+	env.RunAndExpectFailure(t, "snap", "list", "--log-format=xml")
+}
+
+func verifyJSONLogEntry(t *testing.T, line string) {
+	t.Helper()
+
+	var entry map[string]any
+
+	require.NoError(t, json.Unmarshal([]byte(line), &entry), line)
+	require.Contains(t, entry, "l", line)
+	require.Contains(t, entry, "m", line)
+}
+
 func TestLogFileRotation(t *testing.T) {
 	runner := testenv.NewInProcRunner(t)
 	runner.CustomizeApp = logfile.Attach
@@ -145,6 +192,74 @@ func TestLogFileRotation(t *testing.T) {
 	}
 }
 
+func TestLogFileCompression(t *testing.T) {
+	runner := testenv.NewInProcRunner(t)
+	runner.CustomizeApp = logfile.Attach
+
+	env := testenv.NewCLITest(t, testenv.RepoFormatNotImportant, runner)
+	env.RunAndExpectSuccess(t, "repo", "create", "filesystem", "--path", env.RepoDir)
+
+	dir1 := testutil.TempDirectory(t)
+	tmpLogDir := testutil.TempDirectory(t)
+
+	env.RunAndExpectSuccess(t, "snap", "create", dir1,
+		"--log-level=error", "--file-log-level=debug", "--log-compress",
+		"--max-log-file-segment-size=1000", "--log-dir", tmpLogDir, "--log-dir-max-files=3", "--content-log-dir-max-files=4")
+
+	// expected number of files per directory
+	subdirs := map[string]int{
+		"cli-logs":     3,
+		"content-logs": 4,
+	}
+
+	for subdir, wantEntryCount := range subdirs {
+		logSubdir := filepath.Join(tmpLogDir, subdir)
+
+		t.Run(subdir, func(t *testing.T) {
+			entries, err := os.ReadDir(logSubdir)
+			require.NoError(t, err)
+
+			var gotEntryCount, compressedCount int
+
+			for _, ent := range entries {
+				if !ent.Type().IsRegular() {
+					continue
+				}
+
+				gotEntryCount++
+
+				if !strings.HasSuffix(ent.Name(), ".log.gz") {
+					continue
+				}
+
+				compressedCount++
+
+				f, err := os.Open(filepath.Join(logSubdir, ent.Name()))
+				require.NoError(t, err)
+
+				gr, err := gzip.NewReader(f)
+				require.NoError(t, err)
+
+				data, err := io.ReadAll(gr)
+				require.NoError(t, err)
+				require.NotEmpty(t, data)
+				require.NoError(t, f.Close())
+			}
+
+			require.Equal(t, wantEntryCount, gotEntryCount)
+
+			// the last segment is compressed when closed at exit, just like the rotated ones.
+			require.Equal(t, wantEntryCount, compressedCount)
+
+			if subdir == "cli-logs" {
+				target, err := os.Readlink(filepath.Join(logSubdir, "latest.log"))
+				require.NoError(t, err)
+				require.True(t, strings.HasSuffix(target, ".log.gz"), target)
+			}
+		})
+	}
+}
+
 func TestLogFileMaxTotalSize(t *testing.T) {
 	t.Parallel()
 
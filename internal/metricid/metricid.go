@@ -5,41 +5,81 @@
 //
 //nolint:gochecknoglobals,mnd
 var Counters = NewMapping(map[string]int{
-	"blob_download_full_blob_bytes":                1,
-	"blob_download_partial_blob_bytes":             2,
-	"blob_errors[method:Close]":                    3,
-	"blob_errors[method:DeleteBlob]":               4,
-	"blob_errors[method:FlushCaches]":              5,
-	"blob_errors[method:GetBlob]":                  6,
-	"blob_errors[method:GetCapacity]":              7,
-	"blob_errors[method:GetMetadata]":              8,
-	"blob_errors[method:ListBlobs]":                9,
-	"blob_errors[method:PutBlob]":                  10,
-	"blob_list_items":                              11,
-	"blob_upload_bytes":                            12,
-	"content_after_compression_bytes":              13,
-	"content_compressible_bytes":                   14,
-	"content_compression_attempted_bytes":          15,
-	"content_compression_attempted_duration_nanos": 16,
-	"content_compression_savings_bytes":            17,
-	"content_decompressed_bytes":                   18,
-	"content_decompressed_duration_nanos":          19,
-	"content_decrypted_bytes":                      20,
-	"content_decrypted_duration_nanos":             21,
-	"content_deduplicated":                         22,
-	"content_deduplicated_bytes":                   23,
-	"content_encrypted_bytes":                      24,
-	"content_encrypted_duration_nanos":             25,
-	"content_get_error_count":                      26,
-	"content_get_not_found_count":                  27,
-	"content_hashed_bytes":                         28,
-	"content_hashed_duration_nanos":                29,
-	"content_non_compressible_bytes":               30,
-	"content_read_bytes":                           31,
-	"content_read_duration_nanos":                  32,
-	"content_uploaded_bytes":                       33,
-	"content_write_bytes":                          34,
-	"content_write_duration_nanos":                 35,
+	"blob_download_full_blob_bytes":                             1,
+	"blob_download_partial_blob_bytes":                          2,
+	"blob_errors[method:Close]":                                 3,
+	"blob_errors[method:DeleteBlob]":                            4,
+	"blob_errors[method:FlushCaches]":                           5,
+	"blob_errors[method:GetBlob]":                               6,
+	"blob_errors[method:GetCapacity]":                           7,
+	"blob_errors[method:GetMetadata]":                           8,
+	"blob_errors[method:ListBlobs]":                             9,
+	"blob_errors[method:PutBlob]":                               10,
+	"blob_list_items":                                           11,
+	"blob_upload_bytes":                                         12,
+	"content_after_compression_bytes":                           13,
+	"content_compressible_bytes":                                14,
+	"content_compression_attempted_bytes":                       15,
+	"content_compression_attempted_duration_nanos":              16,
+	"content_compression_savings_bytes":                         17,
+	"content_decompressed_bytes":                                18,
+	"content_decompressed_duration_nanos":                       19,
+	"content_decrypted_bytes":                                   20,
+	"content_decrypted_duration_nanos":                          21,
+	"content_deduplicated":                                      22,
+	"content_deduplicated_bytes":                                23,
+	"content_encrypted_bytes":                                   24,
+	"content_encrypted_duration_nanos":                          25,
+	"content_get_error_count":                                   26,
+	"content_get_not_found_count":                               27,
+	"content_hashed_bytes":                                      28,
+	"content_hashed_duration_nanos":                             29,
+	"content_non_compressible_bytes":                            30,
+	"content_read_bytes":                                        31,
+	"content_read_duration_nanos":                               32,
+	"content_uploaded_bytes":                                    33,
+	"content_write_bytes":                                       34,
+	"content_write_duration_nanos":                              35,
+	"blob_download_bytes_by_class[class:data]":                  36,
+	"blob_download_bytes_by_class[class:metadata]":              37,
+	"blob_download_bytes_by_class[class:index]":                 38,
+	"blob_download_bytes_by_class[class:format]":                39,
+	"blob_download_bytes_by_class[class:log]":                   40,
+	"blob_download_bytes_by_class[class:session]":               41,
+	"blob_download_bytes_by_class[class:other]":                 42,
+	"blob_upload_bytes_by_class[class:data]":                    43,
+	"blob_upload_bytes_by_class[class:metadata]":                44,
+	"blob_upload_bytes_by_class[class:index]":                   45,
+	"blob_upload_bytes_by_class[class:format]":                  46,
+	"blob_upload_bytes_by_class[class:log]":                     47,
+	"blob_upload_bytes_by_class[class:session]":                 48,
+	"blob_upload_bytes_by_class[class:other]":                   49,
+	"blob_circuit_breaker_opened[method:DeleteBlob]":            50,
+	"blob_circuit_breaker_opened[method:ExtendBlobRetention]":   51,
+	"blob_circuit_breaker_opened[method:GetBlob]":               52,
+	"blob_circuit_breaker_opened[method:GetMetadata]":           53,
+	"blob_circuit_breaker_opened[method:ListBlobs]":             54,
+	"blob_circuit_breaker_opened[method:PutBlob]":               55,
+	"blob_circuit_breaker_closed[method:DeleteBlob]":            56,
+	"blob_circuit_breaker_closed[method:ExtendBlobRetention]":   57,
+	"blob_circuit_breaker_closed[method:GetBlob]":               58,
+	"blob_circuit_breaker_closed[method:GetMetadata]":           59,
+	"blob_circuit_breaker_closed[method:ListBlobs]":             60,
+	"blob_circuit_breaker_closed[method:PutBlob]":               61,
+	"blob_circuit_breaker_rejected[method:DeleteBlob]":          62,
+	"blob_circuit_breaker_rejected[method:ExtendBlobRetention]": 63,
+	"blob_circuit_breaker_rejected[method:GetBlob]":             64,
+	"blob_circuit_breaker_rejected[method:GetMetadata]":         65,
+	"blob_circuit_breaker_rejected[method:ListBlobs]":           66,
+	"blob_circuit_breaker_rejected[method:PutBlob]":             67,
+	"blob_throttler_wait_nanos[verb:DeleteBlob]":                68,
+	"blob_throttler_wait_nanos[verb:Download]":                  69,
+	"blob_throttler_wait_nanos[verb:ExtendBlobRetention]":       70,
+	"blob_throttler_wait_nanos[verb:GetBlob]":                   71,
+	"blob_throttler_wait_nanos[verb:GetMetadata]":               72,
+	"blob_throttler_wait_nanos[verb:ListBlobs]":                 73,
+	"blob_throttler_wait_nanos[verb:PutBlob]":                   74,
+	"blob_throttler_wait_nanos[verb:Upload]":                    75,
 	// add new items here, use consecutive values
 })
 
@@ -47,15 +87,43 @@
 //
 //nolint:gochecknoglobals,mnd
 var DurationDistributions = NewMapping(map[string]int{
-	"blob_storage_latency[method:Close]":           1,
-	"blob_storage_latency[method:DeleteBlob]":      2,
-	"blob_storage_latency[method:FlushCaches]":     3,
-	"blob_storage_latency[method:GetBlob-full]":    4,
-	"blob_storage_latency[method:GetBlob-partial]": 5,
-	"blob_storage_latency[method:GetCapacity]":     6,
-	"blob_storage_latency[method:GetMetadata]":     7,
-	"blob_storage_latency[method:ListBlobs]":       8,
-	"blob_storage_latency[method:PutBlob]":         9,
+	"blob_storage_latency[method:Close]":                               1,
+	"blob_storage_latency[method:DeleteBlob]":                          2,
+	"blob_storage_latency[method:FlushCaches]":                         3,
+	"blob_storage_latency[method:GetBlob-full]":                        4,
+	"blob_storage_latency[method:GetBlob-partial]":                     5,
+	"blob_storage_latency[method:GetCapacity]":                         6,
+	"blob_storage_latency[method:GetMetadata]":                         7,
+	"blob_storage_latency[method:ListBlobs]":                           8,
+	"blob_storage_latency[method:PutBlob]":                             9,
+	"blob_storage_latency_by_class[class:data;method:DeleteBlob]":      10,
+	"blob_storage_latency_by_class[class:data;method:GetBlob]":         11,
+	"blob_storage_latency_by_class[class:data;method:GetMetadata]":     12,
+	"blob_storage_latency_by_class[class:data;method:PutBlob]":         13,
+	"blob_storage_latency_by_class[class:metadata;method:DeleteBlob]":  14,
+	"blob_storage_latency_by_class[class:metadata;method:GetBlob]":     15,
+	"blob_storage_latency_by_class[class:metadata;method:GetMetadata]": 16,
+	"blob_storage_latency_by_class[class:metadata;method:PutBlob]":     17,
+	"blob_storage_latency_by_class[class:index;method:DeleteBlob]":     18,
+	"blob_storage_latency_by_class[class:index;method:GetBlob]":        19,
+	"blob_storage_latency_by_class[class:index;method:GetMetadata]":    20,
+	"blob_storage_latency_by_class[class:index;method:PutBlob]":        21,
+	"blob_storage_latency_by_class[class:format;method:DeleteBlob]":    22,
+	"blob_storage_latency_by_class[class:format;method:GetBlob]":       23,
+	"blob_storage_latency_by_class[class:format;method:GetMetadata]":   24,
+	"blob_storage_latency_by_class[class:format;method:PutBlob]":       25,
+	"blob_storage_latency_by_class[class:log;method:DeleteBlob]":       26,
+	"blob_storage_latency_by_class[class:log;method:GetBlob]":          27,
+	"blob_storage_latency_by_class[class:log;method:GetMetadata]":      28,
+	"blob_storage_latency_by_class[class:log;method:PutBlob]":          29,
+	"blob_storage_latency_by_class[class:session;method:DeleteBlob]":   30,
+	"blob_storage_latency_by_class[class:session;method:GetBlob]":      31,
+	"blob_storage_latency_by_class[class:session;method:GetMetadata]":  32,
+	"blob_storage_latency_by_class[class:session;method:PutBlob]":      33,
+	"blob_storage_latency_by_class[class:other;method:DeleteBlob]":     34,
+	"blob_storage_latency_by_class[class:other;method:GetBlob]":        35,
+	"blob_storage_latency_by_class[class:other;method:GetMetadata]":    36,
+	"blob_storage_latency_by_class[class:other;method:PutBlob]":        37,
 	// add new items here, use consecutive values
 })
 
@@ -0,0 +1,67 @@
+package metrics
+
+import (
+	"sync/atomic"
+)
+
+// Gauge represents an int64 value which can increase and decrease.
+type Gauge struct {
+	state     atomic.Int64
+	valueFunc atomic.Pointer[func() int64]
+}
+
+// Set sets the value of a gauge.
+func (g *Gauge) Set(v int64) {
+	if g == nil {
+		return
+	}
+
+	g.valueFunc.Store(nil)
+	g.state.Store(v)
+}
+
+// SetFunc causes the value of a gauge to be computed by the provided function whenever it is read,
+// which is useful for values that change with the passage of time, such as ages.
+func (g *Gauge) SetFunc(f func() int64) {
+	if g == nil {
+		return
+	}
+
+	g.valueFunc.Store(&f)
+}
+
+// Value returns the current value of a gauge.
+func (g *Gauge) Value() int64 {
+	if g == nil {
+		return 0
+	}
+
+	if f := g.valueFunc.Load(); f != nil {
+		return (*f)()
+	}
+
+	return g.state.Load()
+}
+
+// GaugeInt64 gets a persistent int64 gauge with the provided name.
+func (r *Registry) GaugeInt64(name, help string, labels map[string]string) *Gauge {
+	if r == nil {
+		return nil
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	fullName := name + labelsSuffix(labels)
+
+	g := r.allGauges[fullName]
+	if g == nil {
+		g = &Gauge{}
+
+		registerPrometheusGauge(prometheusPrefix+name, help, labels, g)
+
+		r.allGauges[fullName] = g
+	}
+
+	return g
+}
@@ -0,0 +1,68 @@
+package metrics_test
+
+import (
+	"testing"
+
+	prommodel "github.com/prometheus/client_model/go"
+	"github.com/stretchr/testify/require"
+
+	"github.com/kopia/kopia/internal/metrics"
+)
+
+func TestGauge_Nil(t *testing.T) {
+	var e *metrics.Registry
+	g := e.GaugeInt64("aaa", "bbb", nil)
+	require.Nil(t, g)
+	g.Set(33)
+	g.SetFunc(func() int64 { return 44 })
+	require.Equal(t, int64(0), g.Value())
+}
+
+func TestGauge_WithLabels(t *testing.T) {
+	e := metrics.NewRegistry()
+
+	labels1 := map[string]string{"host": "h1", "user": "u1", "path": "/p1"}
+	labels2 := map[string]string{"host": "h2", "user": "u2", "path": "/p2"}
+
+	g1 := e.GaugeInt64("some_gauge3", "some-help", labels1)
+	g2 := e.GaugeInt64("some_gauge3", "some-help", labels2)
+
+	require.Same(t, g1, e.GaugeInt64("some_gauge3", "some-help", labels1))
+
+	g1.Set(33)
+	g2.Set(44)
+	require.Equal(t, 33.0,
+		mustFindMetric(t, "kopia_some_gauge3", prommodel.MetricType_GAUGE, labels1).GetGauge().GetValue())
+	require.Equal(t, 44.0,
+		mustFindMetric(t, "kopia_some_gauge3", prommodel.MetricType_GAUGE, labels2).GetGauge().GetValue())
+
+	// gauges can decrease.
+	g1.Set(11)
+	require.Equal(t, 11.0,
+		mustFindMetric(t, "kopia_some_gauge3", prommodel.MetricType_GAUGE, labels1).GetGauge().GetValue())
+
+	// values of gauges backed by functions are computed when collected.
+	v := int64(100)
+
+	g2.SetFunc(func() int64 { return v })
+	require.Equal(t, 100.0,
+		mustFindMetric(t, "kopia_some_gauge3", prommodel.MetricType_GAUGE, labels2).GetGauge().GetValue())
+
+	v = 200
+	require.Equal(t, 200.0,
+		mustFindMetric(t, "kopia_some_gauge3", prommodel.MetricType_GAUGE, labels2).GetGauge().GetValue())
+
+	s := e.Snapshot(false)
+	require.Equal(t, int64(11), s.Gauges["some_gauge3[host:h1;path:/p1;user:u1]"])
+	require.Equal(t, int64(200), s.Gauges["some_gauge3[host:h2;path:/p2;user:u2]"])
+
+	// setting an explicit value replaces the function.
+	g2.Set(5)
+	require.Equal(t, int64(5), g2.Value())
+
+	// gauge with the same name and labels in another registry replaces the previous one.
+	e2 := metrics.NewRegistry()
+	e2.GaugeInt64("some_gauge3", "some-help", labels1).Set(77)
+	require.Equal(t, 77.0,
+		mustFindMetric(t, "kopia_some_gauge3", prommodel.MetricType_GAUGE, labels1).GetGauge().GetValue())
+}
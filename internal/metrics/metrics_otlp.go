@@ -0,0 +1,282 @@
+package metrics
+
+import (
+	"context"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/exporters/otlp/otlpmetric/otlpmetricgrpc"
+	"go.opentelemetry.io/otel/sdk/instrumentation"
+	sdkmetric "go.opentelemetry.io/otel/sdk/metric"
+	"go.opentelemetry.io/otel/sdk/metric/metricdata"
+	"go.opentelemetry.io/otel/sdk/resource"
+	"golang.org/x/exp/constraints"
+
+	"github.com/kopia/kopia/internal/clock"
+)
+
+// Resource attributes describing the source of metrics exported over OTLP.
+const (
+	OTLPAttributeServiceName    = "service.name"
+	OTLPAttributeServiceVersion = "service.version"
+	OTLPAttributeHostName       = "host.name"
+	OTLPAttributeUserName       = "kopia.user"
+	OTLPAttributeRepositoryID   = "kopia.repository.id"
+)
+
+// Defaults for OTLPExporterOptions.
+const (
+	DefaultOTLPEndpoint       = "localhost:4317"
+	DefaultOTLPExportInterval = 30 * time.Second
+)
+
+const (
+	otlpScopeName       = "github.com/kopia/kopia"
+	otlpExportTimeout   = 30 * time.Second
+	otlpShutdownTimeout = 10 * time.Second
+	nanosPerSecond      = float64(time.Second)
+)
+
+// OTLPExporterOptions specifies the options of the OpenTelemetry (OTLP) metrics exporter.
+type OTLPExporterOptions struct {
+	Endpoint           string            // host:port of the OTLP collector accepting gRPC connections
+	Insecure           bool              // connect without TLS
+	Headers            map[string]string // headers sent with each export request, such as authentication tokens
+	Interval           time.Duration     // frequency of metrics push
+	ResourceAttributes map[string]string // attributes identifying the source of metrics, such as host, user and repository ID
+}
+
+// StartOTLPExporter starts periodically pushing all metrics in the registry to the OTLP collector
+// using gRPC until the registry is closed, at which point the final values are pushed.
+func (r *Registry) StartOTLPExporter(ctx context.Context, opts OTLPExporterOptions) error {
+	if opts.Endpoint == "" {
+		opts.Endpoint = DefaultOTLPEndpoint
+	}
+
+	if opts.Interval <= 0 {
+		opts.Interval = DefaultOTLPExportInterval
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.otlpProvider != nil {
+		return errors.New("OTLP exporter already started")
+	}
+
+	exporterOptions := []otlpmetricgrpc.Option{
+		otlpmetricgrpc.WithEndpoint(opts.Endpoint),
+		otlpmetricgrpc.WithTimeout(otlpExportTimeout),
+	}
+
+	if opts.Insecure {
+		exporterOptions = append(exporterOptions, otlpmetricgrpc.WithInsecure())
+	}
+
+	if len(opts.Headers) > 0 {
+		exporterOptions = append(exporterOptions, otlpmetricgrpc.WithHeaders(opts.Headers))
+	}
+
+	exp, err := otlpmetricgrpc.New(ctx, exporterOptions...)
+	if err != nil {
+		return errors.Wrap(err, "unable to create OTLP exporter")
+	}
+
+	resourceAttributes := otlpAttributes(opts.ResourceAttributes)
+
+	r.otlpProvider = sdkmetric.NewMeterProvider(
+		sdkmetric.WithResource(resource.NewSchemaless(resourceAttributes.ToSlice()...)),
+		sdkmetric.WithReader(sdkmetric.NewPeriodicReader(exp,
+			sdkmetric.WithInterval(opts.Interval),
+			sdkmetric.WithTimeout(otlpExportTimeout),
+			sdkmetric.WithProducer(registryProducer{r}),
+		)),
+	)
+
+	log(ctx).Debugf("starting OTLP metrics exporter on %v every %v", opts.Endpoint, opts.Interval)
+
+	return nil
+}
+
+// stopOTLPExporter pushes the final values of metrics and stops the OTLP exporter, waiting for the collector
+// no longer than otlpShutdownTimeout or until the context is canceled.
+func (r *Registry) stopOTLPExporter(ctx context.Context) {
+	r.mu.Lock()
+	p := r.otlpProvider
+	r.otlpProvider = nil
+	r.mu.Unlock()
+
+	if p == nil {
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, otlpShutdownTimeout)
+	defer cancel()
+
+	if err := p.Shutdown(ctx); err != nil {
+		log(ctx).Debugw("error pushing final OTLP metrics", "err", err)
+	}
+}
+
+// registryProducer provides the current state of all metrics in the registry to the OpenTelemetry SDK.
+type registryProducer struct {
+	r *Registry
+}
+
+func (p registryProducer) Produce(ctx context.Context) ([]metricdata.ScopeMetrics, error) {
+	return []metricdata.ScopeMetrics{p.r.otlpScopeMetrics()}, nil
+}
+
+// otlpScopeMetrics converts the current state of all metrics into OpenTelemetry metric data.
+// All values are cumulative since the start time of the registry.
+func (r *Registry) otlpScopeMetrics() metricdata.ScopeMetrics {
+	r.mu.Lock()
+	start := r.startTime
+	counters := sortedValues(r.allCounters)
+	gauges := sortedValues(r.allGauges)
+	durations := sortedValues(r.allDurationDistributions)
+	sizes := sortedValues(r.allSizeDistributions)
+	r.mu.Unlock()
+
+	now := clock.Now()
+
+	var result []metricdata.Metrics
+
+	for _, c := range counters {
+		name, labels := parseFullName(c.key)
+
+		result = append(result, metricdata.Metrics{
+			Name: prometheusPrefix + name,
+			Data: metricdata.Sum[int64]{
+				Temporality: metricdata.CumulativeTemporality,
+				IsMonotonic: true,
+				DataPoints: []metricdata.DataPoint[int64]{{
+					Attributes: otlpAttributes(labels),
+					StartTime:  start,
+					Time:       now,
+					Value:      c.value.Snapshot(false),
+				}},
+			},
+		})
+	}
+
+	for _, g := range gauges {
+		name, labels := parseFullName(g.key)
+
+		result = append(result, metricdata.Metrics{
+			Name: prometheusPrefix + name,
+			Data: metricdata.Gauge[int64]{
+				DataPoints: []metricdata.DataPoint[int64]{{
+					Attributes: otlpAttributes(labels),
+					Time:       now,
+					Value:      g.value.Value(),
+				}},
+			},
+		})
+	}
+
+	for _, d := range durations {
+		result = append(result, otlpHistogram(d.key, "s", nanosPerSecond, d.value, start, now))
+	}
+
+	for _, d := range sizes {
+		result = append(result, otlpHistogram(d.key, "By", 1, d.value, start, now))
+	}
+
+	return metricdata.ScopeMetrics{
+		Scope:   instrumentation.Scope{Name: otlpScopeName},
+		Metrics: result,
+	}
+}
+
+func otlpHistogram[T constraints.Integer | constraints.Float](fullName, unit string, scale float64, d *Distribution[T], start, now time.Time) metricdata.Metrics {
+	name, labels := parseFullName(fullName)
+	st := d.Snapshot(false)
+
+	dp := metricdata.HistogramDataPoint[float64]{
+		Attributes: otlpAttributes(labels),
+		StartTime:  start,
+		Time:       now,
+		Count:      uint64(st.Count), //nolint:gosec
+		Sum:        float64(st.Sum) / scale,
+	}
+
+	if st.Count > 0 {
+		dp.Min = metricdata.NewExtrema(float64(st.Min) / scale)
+		dp.Max = metricdata.NewExtrema(float64(st.Max) / scale)
+	}
+
+	for _, v := range d.bucketThresholds {
+		dp.Bounds = append(dp.Bounds, float64(v)/scale)
+	}
+
+	for _, v := range st.BucketCounters {
+		dp.BucketCounts = append(dp.BucketCounts, uint64(v)) //nolint:gosec
+	}
+
+	return metricdata.Metrics{
+		Name: prometheusPrefix + name,
+		Unit: unit,
+		Data: metricdata.Histogram[float64]{
+			Temporality: metricdata.CumulativeTemporality,
+			DataPoints:  []metricdata.HistogramDataPoint[float64]{dp},
+		},
+	}
+}
+
+func otlpAttributes(attrs map[string]string) attribute.Set {
+	var kvs []attribute.KeyValue
+
+	for _, k := range sortedKeys(attrs) {
+		kvs = append(kvs, attribute.String(k, attrs[k]))
+	}
+
+	return attribute.NewSet(kvs...)
+}
+
+// parseFullName splits the full metric name, as produced by labelsSuffix(), into the name and labels.
+func parseFullName(fullName string) (name string, labels map[string]string) {
+	name, suffix, ok := strings.Cut(fullName, "[")
+	if !ok {
+		return fullName, nil
+	}
+
+	labels = map[string]string{}
+
+	for _, p := range strings.Split(strings.TrimSuffix(suffix, "]"), ";") {
+		k, v, _ := strings.Cut(p, ":")
+		labels[k] = v
+	}
+
+	return name, labels
+}
+
+type keyedValue[T any] struct {
+	key   string
+	value T
+}
+
+func sortedValues[T any](m map[string]T) []keyedValue[T] {
+	var result []keyedValue[T]
+
+	for _, k := range sortedKeys(m) {
+		result = append(result, keyedValue[T]{k, m[k]})
+	}
+
+	return result
+}
+
+func sortedKeys[T any](m map[string]T) []string {
+	var keys []string
+
+	for k := range m {
+		keys = append(keys, k)
+	}
+
+	sort.Strings(keys)
+
+	return keys
+}
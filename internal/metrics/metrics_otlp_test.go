@@ -0,0 +1,154 @@
+package metrics_test
+
+import (
+	"context"
+	"net"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	collectorpb "go.opentelemetry.io/proto/otlp/collector/metrics/v1"
+	metricspb "go.opentelemetry.io/proto/otlp/metrics/v1"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
+
+	"github.com/kopia/kopia/internal/metrics"
+	"github.com/kopia/kopia/internal/testlogging"
+)
+
+type fakeCollector struct {
+	collectorpb.UnimplementedMetricsServiceServer
+
+	hang bool // block exports until the client gives up
+
+	mu       sync.Mutex
+	requests []*collectorpb.ExportMetricsServiceRequest
+	tokens   []string
+}
+
+func (c *fakeCollector) Export(ctx context.Context, req *collectorpb.ExportMetricsServiceRequest) (*collectorpb.ExportMetricsServiceResponse, error) {
+	if c.hang {
+		<-ctx.Done()
+
+		return nil, ctx.Err()
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	md, _ := metadata.FromIncomingContext(ctx)
+
+	c.requests = append(c.requests, req)
+	c.tokens = append(c.tokens, md.Get("x-token")...)
+
+	return &collectorpb.ExportMetricsServiceResponse{}, nil
+}
+
+func startFakeCollector(t *testing.T, fc *fakeCollector) string {
+	t.Helper()
+
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+
+	srv := grpc.NewServer()
+	collectorpb.RegisterMetricsServiceServer(srv, fc)
+
+	go srv.Serve(l) //nolint:errcheck
+
+	t.Cleanup(srv.Stop)
+
+	return l.Addr().String()
+}
+
+func TestOTLPExporter(t *testing.T) {
+	ctx := testlogging.Context(t)
+	fc := &fakeCollector{}
+	addr := startFakeCollector(t, fc)
+
+	r := metrics.NewRegistry()
+
+	r.CounterInt64("some_counter", "some help", map[string]string{"a": "b", "c": "d"}).Add(33)
+	r.DurationDistribution("some_duration", "some help", metrics.IOLatencyThresholds, nil).Observe(3 * time.Millisecond)
+	r.SizeDistribution("some_size", "some help", metrics.ISOBytesThresholds, nil).Observe(777)
+
+	require.NoError(t, r.StartOTLPExporter(ctx, metrics.OTLPExporterOptions{
+		Endpoint: addr,
+		Insecure: true,
+		Interval: time.Hour,
+		Headers:  map[string]string{"x-token": "secret"},
+		ResourceAttributes: map[string]string{
+			metrics.OTLPAttributeHostName:     "some-host",
+			metrics.OTLPAttributeRepositoryID: "some-repo",
+		},
+	}))
+
+	require.Error(t, r.StartOTLPExporter(ctx, metrics.OTLPExporterOptions{Endpoint: addr, Insecure: true}))
+
+	// final values are pushed on close.
+	require.NoError(t, r.Close(ctx))
+
+	fc.mu.Lock()
+	defer fc.mu.Unlock()
+
+	require.Len(t, fc.requests, 1)
+	require.Equal(t, []string{"secret"}, fc.tokens)
+
+	rm := fc.requests[0].GetResourceMetrics()[0]
+
+	attrs := map[string]string{}
+	for _, kv := range rm.GetResource().GetAttributes() {
+		attrs[kv.GetKey()] = kv.GetValue().GetStringValue()
+	}
+
+	require.Equal(t, map[string]string{
+		metrics.OTLPAttributeHostName:     "some-host",
+		metrics.OTLPAttributeRepositoryID: "some-repo",
+	}, attrs)
+
+	byName := map[string]*metricspb.Metric{}
+	for _, m := range rm.GetScopeMetrics()[0].GetMetrics() {
+		byName[m.GetName()] = m
+	}
+
+	counter := byName["kopia_some_counter"].GetSum()
+	require.True(t, counter.GetIsMonotonic())
+	require.Equal(t, metricspb.AggregationTemporality_AGGREGATION_TEMPORALITY_CUMULATIVE, counter.GetAggregationTemporality())
+	require.Equal(t, int64(33), counter.GetDataPoints()[0].GetAsInt())
+	require.Len(t, counter.GetDataPoints()[0].GetAttributes(), 2)
+	require.Equal(t, "a", counter.GetDataPoints()[0].GetAttributes()[0].GetKey())
+	require.Equal(t, "b", counter.GetDataPoints()[0].GetAttributes()[0].GetValue().GetStringValue())
+
+	dur := byName["kopia_some_duration"]
+	require.Equal(t, "s", dur.GetUnit())
+	require.Equal(t, uint64(1), dur.GetHistogram().GetDataPoints()[0].GetCount())
+	require.InDelta(t, 0.003, dur.GetHistogram().GetDataPoints()[0].GetSum(), 1e-9)
+
+	size := byName["kopia_some_size"].GetHistogram().GetDataPoints()[0]
+	require.Equal(t, "By", byName["kopia_some_size"].GetUnit())
+	require.InDelta(t, 777.0, size.GetMax(), 1e-9)
+	require.Len(t, size.GetBucketCounts(), len(size.GetExplicitBounds())+1)
+}
+
+func TestOTLPExporter_CloseIsBounded(t *testing.T) {
+	ctx := testlogging.Context(t)
+	addr := startFakeCollector(t, &fakeCollector{hang: true})
+
+	r := metrics.NewRegistry()
+
+	r.CounterInt64("other_counter", "some help", nil).Add(33)
+
+	require.NoError(t, r.StartOTLPExporter(ctx, metrics.OTLPExporterOptions{
+		Endpoint: addr,
+		Insecure: true,
+		Interval: time.Hour,
+	}))
+
+	closeCtx, cancel := context.WithTimeout(ctx, time.Second)
+	defer cancel()
+
+	t0 := time.Now()
+
+	require.NoError(t, r.Close(closeCtx))
+	require.Less(t, time.Since(t0), 5*time.Second)
+}
@@ -3,10 +3,14 @@
 
 import (
 	"context"
+	"maps"
+	"sort"
 	"strings"
 	"sync"
 	"time"
 
+	sdkmetric "go.opentelemetry.io/otel/sdk/metric"
+
 	"github.com/kopia/kopia/internal/clock"
 	"github.com/kopia/kopia/internal/releasable"
 	"github.com/kopia/kopia/repo/logging"
@@ -22,9 +26,13 @@ type Registry struct {
 	startTime time.Time
 
 	allCounters              map[string]*Counter
+	allGauges                map[string]*Gauge
 	allThroughput            map[string]*Throughput
 	allDurationDistributions map[string]*Distribution[time.Duration]
 	allSizeDistributions     map[string]*Distribution[int64]
+
+	// +checklocks:mu
+	otlpProvider *sdkmetric.MeterProvider
 }
 
 // Snapshot captures the state of all metrics.
@@ -35,6 +43,7 @@ type Snapshot struct {
 	Hostname  string    `json:"hostname"`
 
 	Counters              map[string]int64                             `json:"counters"`
+	Gauges                map[string]int64                             `json:"gauges,omitempty"`
 	DurationDistributions map[string]*DistributionState[time.Duration] `json:"durationDistributions"`
 	SizeDistributions     map[string]*DistributionState[int64]         `json:"sizeDistributions"`
 }
@@ -44,6 +53,11 @@ func (s *Snapshot) mergeFrom(other Snapshot) {
 		s.Counters[k] += v
 	}
 
+	// gauges are not additive, keep the latest value.
+	for k, v := range other.Gauges {
+		s.Gauges[k] = v
+	}
+
 	for k, v := range other.DurationDistributions {
 		target := s.DurationDistributions[k]
 		if target == nil {
@@ -68,6 +82,7 @@ func (s *Snapshot) mergeFrom(other Snapshot) {
 func createSnapshot() Snapshot {
 	return Snapshot{
 		Counters:              map[string]int64{},
+		Gauges:                map[string]int64{},
 		DurationDistributions: map[string]*DistributionState[time.Duration]{},
 		SizeDistributions:     map[string]*DistributionState[int64]{},
 	}
@@ -81,6 +96,15 @@ func (r *Registry) Snapshot(reset bool) Snapshot {
 		s.Counters[k] = c.Snapshot(reset)
 	}
 
+	// gauges are registered while the registry is in use, read them under lock.
+	r.mu.Lock()
+	gauges := maps.Clone(r.allGauges)
+	r.mu.Unlock()
+
+	for k, g := range gauges {
+		s.Gauges[k] = g.Value()
+	}
+
 	for k, c := range r.allDurationDistributions {
 		s.DurationDistributions[k] = c.Snapshot(reset)
 	}
@@ -108,6 +132,8 @@ func (r *Registry) Close(ctx context.Context) error {
 		return nil
 	}
 
+	r.stopOTLPExporter(ctx)
+
 	releasable.Released("metric-registry", r)
 
 	return nil
@@ -125,6 +151,10 @@ func (r *Registry) Log(ctx context.Context) {
 		log(ctx).Debugw("COUNTER", "name", n, "value", val)
 	}
 
+	for n, val := range s.Gauges {
+		log(ctx).Debugw("GAUGE", "name", n, "value", val)
+	}
+
 	for n, st := range s.DurationDistributions {
 		log(ctx).Debugw("DURATION-DISTRIBUTION", "name", n, "counters", st.BucketCounters, "cnt", st.Count, "sum", st.Sum, "min", st.Min, "avg", st.Mean(), "max", st.Max)
 	}
@@ -142,6 +172,7 @@ func NewRegistry() *Registry {
 		startTime: clock.Now(),
 
 		allCounters:              map[string]*Counter{},
+		allGauges:                map[string]*Gauge{},
 		allDurationDistributions: map[string]*Distribution[time.Duration]{},
 		allSizeDistributions:     map[string]*Distribution[int64]{},
 		allThroughput:            map[string]*Throughput{},
@@ -162,5 +193,8 @@ func labelsSuffix(l map[string]string) string {
 		params = append(params, k+":"+v)
 	}
 
+	// ensure stable names of metrics with multiple labels.
+	sort.Strings(params)
+
 	return "[" + strings.Join(params, ";") + "]"
 }
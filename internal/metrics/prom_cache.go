@@ -1,6 +1,7 @@
 package metrics
 
 import (
+	"sort"
 	"sync"
 
 	"github.com/prometheus/client_golang/prometheus"
@@ -20,8 +21,72 @@
 	promCounters = map[string]*prometheus.CounterVec{}
 	// +checklocks:promCacheMutex
 	promHistograms = map[string]*prometheus.HistogramVec{}
+	// +checklocks:promCacheMutex
+	promGauges = map[string]*promGaugeCollector{}
 )
 
+// promGaugeCollector reports values of all gauges with a particular name when metrics are collected,
+// which allows values of gauges to be computed at that time.
+type promGaugeCollector struct {
+	desc       *prometheus.Desc
+	labelNames []string
+
+	mu sync.Mutex
+	// +checklocks:mu
+	gauges map[string]*promGaugeWithLabels
+}
+
+type promGaugeWithLabels struct {
+	gauge       *Gauge
+	labelValues []string
+}
+
+func (c *promGaugeCollector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- c.desc
+}
+
+func (c *promGaugeCollector) Collect(ch chan<- prometheus.Metric) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	for _, g := range c.gauges {
+		ch <- prometheus.MustNewConstMetric(c.desc, prometheus.GaugeValue, float64(g.gauge.Value()), g.labelValues...)
+	}
+}
+
+// registerPrometheusGauge reports the value of the provided gauge to prometheus, replacing any gauge
+// with the same name and labels registered previously.
+func registerPrometheusGauge(name, help string, labels map[string]string, g *Gauge) {
+	promCacheMutex.Lock()
+	defer promCacheMutex.Unlock()
+
+	c := promGauges[name]
+	if c == nil {
+		labelNames := maps.Keys(labels)
+		sort.Strings(labelNames)
+
+		c = &promGaugeCollector{
+			desc:       prometheus.NewDesc(name, help, labelNames, nil),
+			labelNames: labelNames,
+			gauges:     map[string]*promGaugeWithLabels{},
+		}
+
+		prometheus.MustRegister(c)
+
+		promGauges[name] = c
+	}
+
+	labelValues := make([]string, 0, len(c.labelNames))
+	for _, n := range c.labelNames {
+		labelValues = append(labelValues, labels[n])
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.gauges[labelsSuffix(labels)] = &promGaugeWithLabels{g, labelValues}
+}
+
 func getPrometheusCounter(opts prometheus.CounterOpts, labels map[string]string) prometheus.Counter {
 	promCacheMutex.Lock()
 	defer promCacheMutex.Unlock()
@@ -33,7 +98,8 @@ func getPrometheusCounter(opts prometheus.CounterOpts, labels map[string]string)
 		promCounters[opts.Name] = prom
 	}
 
-	return prom.WithLabelValues(maps.Values(labels)...)
+	// label values are matched by name, since the order of map iteration is not stable.
+	return prom.With(labels)
 }
 
 func getPrometheusHistogram(opts prometheus.HistogramOpts, labels map[string]string) prometheus.Observer { //nolint:gocritic
@@ -47,5 +113,5 @@ func getPrometheusHistogram(opts prometheus.HistogramOpts, labels map[string]str
 		promHistograms[opts.Name] = prom
 	}
 
-	return prom.WithLabelValues(maps.Values(labels)...)
+	return prom.With(labels)
 }
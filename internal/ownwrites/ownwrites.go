@@ -7,12 +7,15 @@
 	"context"
 	"strings"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/pkg/errors"
 
+	"github.com/kopia/kopia/internal/cache"
 	"github.com/kopia/kopia/internal/clock"
 	"github.com/kopia/kopia/internal/gather"
+	"github.com/kopia/kopia/internal/metrics"
 	"github.com/kopia/kopia/repo/blob"
 	"github.com/kopia/kopia/repo/logging"
 )
@@ -43,6 +46,32 @@ type CacheStorage struct {
 
 	// +checklocks:mu
 	nextSweepTime time.Time
+
+	// markers are used when they correct list results returned by the provider and unneeded
+	// when the provider already returned consistent results.
+	metricMarkerUsed     *metrics.Counter
+	metricMarkerUnneeded *metrics.Counter
+	markerUsed           atomic.Int64
+	markerUnneeded       atomic.Int64
+}
+
+// Stats returns statistics of the cache since it was opened, with markers which were used reported
+// as hits and markers which were unneeded reported as misses.
+func (s *CacheStorage) Stats() cache.Stats {
+	return cache.Stats{
+		HitCount:  s.markerUsed.Load(),
+		MissCount: s.markerUnneeded.Load(),
+	}
+}
+
+func (s *CacheStorage) reportMarkerUsed() {
+	s.metricMarkerUsed.Add(1)
+	s.markerUsed.Add(1)
+}
+
+func (s *CacheStorage) reportMarkerUnneeded() {
+	s.metricMarkerUnneeded.Add(1)
+	s.markerUnneeded.Add(1)
 }
 
 // ListBlobs implements blob.Storage and merges provider-returned results with cached ones.
@@ -86,12 +115,16 @@ func (s *CacheStorage) ListBlobs(ctx context.Context, prefix blob.ID, cb func(bl
 	if err := s.Storage.ListBlobs(ctx, prefix, func(bm blob.Metadata) error {
 		if _, ok := cachedDeletionsSet[bm.BlobID]; ok {
 			// blob was deleted locally but still exists on the server, don't invoke callback for it.
+			s.reportMarkerUsed()
 
 			return nil
 		}
 
 		// delete from 'cachedCreatedSet' since the provider and cache both agree on the fact that the blob exists.
-		delete(cachedCreatedSet, bm.BlobID)
+		if _, ok := cachedCreatedSet[bm.BlobID]; ok {
+			s.reportMarkerUnneeded()
+			delete(cachedCreatedSet, bm.BlobID)
+		}
 
 		return cb(bm)
 	}); err != nil {
@@ -116,6 +149,8 @@ func (s *CacheStorage) ListBlobs(ctx context.Context, prefix blob.ID, cb func(bl
 			return err
 		}
 
+		s.reportMarkerUsed()
+
 		if err := cb(bm); err != nil {
 			return err
 		}
@@ -189,7 +224,7 @@ func (s *CacheStorage) maybeSweepCache(ctx context.Context) {
 
 // NewWrapper returns new wrapper that ensures list consistency with local writes for the given set of blob prefixes.
 // It leverages the provided local cache storage to maintain markers keeping track of recently created and deleted blobs.
-func NewWrapper(st, cacheStorage blob.Storage, prefixes []blob.ID, cacheDuration time.Duration) blob.Storage {
+func NewWrapper(st, cacheStorage blob.Storage, prefixes []blob.ID, cacheDuration time.Duration, mr *metrics.Registry) blob.Storage {
 	if cacheStorage == nil {
 		return st
 	}
@@ -200,6 +235,9 @@ func NewWrapper(st, cacheStorage blob.Storage, prefixes []blob.ID, cacheDuration
 		prefixes:      prefixes,
 		cacheTimeFunc: clock.Now,
 		cacheDuration: cacheDuration,
+
+		metricMarkerUsed:     mr.CounterInt64("own_writes_marker_used", "Number of times list results were corrected using local markers", nil),
+		metricMarkerUnneeded: mr.CounterInt64("own_writes_marker_unneeded", "Number of local markers which were not needed because list results were consistent", nil),
 	}
 }
 
@@ -7,6 +7,7 @@
 	"github.com/stretchr/testify/require"
 
 	"github.com/kopia/kopia/internal/blobtesting"
+	"github.com/kopia/kopia/internal/cache"
 	"github.com/kopia/kopia/internal/faketime"
 	"github.com/kopia/kopia/internal/gather"
 	"github.com/kopia/kopia/internal/testlogging"
@@ -22,7 +23,7 @@ func TestOwnWrites(t *testing.T) {
 	cachest := blobtesting.NewMapStorage(blobtesting.DataMap{}, nil, cacheTime.NowFunc())
 
 	ec := blobtesting.NewEventuallyConsistentStorage(realStorage, 1*time.Hour, realStorageTime.NowFunc())
-	ow := NewWrapper(ec, cachest, []blob.ID{"n"}, testCacheDuration)
+	ow := NewWrapper(ec, cachest, []blob.ID{"n"}, testCacheDuration, nil)
 	ow.(*CacheStorage).cacheTimeFunc = cacheTime.NowFunc()
 
 	ctx := testlogging.Context(t)
@@ -73,4 +74,7 @@ func TestOwnWrites(t *testing.T) {
 
 	// make sure cache got sweeped
 	blobtesting.AssertListResultsIDs(ctx, t, cachest, "")
+
+	// markers were used to correct listings twice and were unneeded once.
+	require.Equal(t, cache.Stats{HitCount: 2, MissCount: 1}, ow.(*CacheStorage).Stats())
 }
@@ -20,6 +20,7 @@ type Queue struct {
 	enqueuedWork      int64
 	activeWorkerCount int64
 	completedWork     int64
+	holdCount         int64
 
 	nextReportTime time.Time
 
@@ -98,8 +99,8 @@ func (v *Queue) dequeue(ctx context.Context) CallbackFunc {
 	v.monitor.L.Lock()
 	defer v.monitor.L.Unlock()
 
-	for v.queueItems.Len() == 0 && v.activeWorkerCount > 0 {
-		// no items in queue, but some workers are active, they may add more.
+	for v.queueItems.Len() == 0 && (v.activeWorkerCount > 0 || v.holdCount > 0) {
+		// no items in queue, but some workers are active or the queue is held, they may add more.
 		v.monitor.Wait()
 	}
 
@@ -128,6 +129,28 @@ func (v *Queue) completed(ctx context.Context) {
 	v.monitor.Broadcast()
 }
 
+// Hold prevents workers from shutting down when the queue becomes empty until the returned
+// release function is called. This allows work to be added from outside of the queue's own workers,
+// for example from workers of another queue.
+func (v *Queue) Hold() (release func()) {
+	v.monitor.L.Lock()
+	defer v.monitor.L.Unlock()
+
+	v.holdCount++
+
+	var once sync.Once
+
+	return func() {
+		once.Do(func() {
+			v.monitor.L.Lock()
+			defer v.monitor.L.Unlock()
+
+			v.holdCount--
+			v.monitor.Broadcast()
+		})
+	}
+}
+
 func (v *Queue) reportProgress(ctx context.Context) {
 	cb := v.ProgressCallback
 	if cb != nil {
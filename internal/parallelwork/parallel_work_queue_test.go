@@ -127,6 +127,42 @@ func TestWaitForActiveWorkers(t *testing.T) {
 	require.Equal(t, 3, sum)
 }
 
+func TestHold(t *testing.T) {
+	queue := parallelwork.NewQueue()
+	release := queue.Hold()
+
+	results := make(chan int, 2)
+
+	go func() {
+		time.Sleep(100 * time.Millisecond)
+		queue.EnqueueBack(context.Background(), func() error {
+			results <- 1
+			return nil
+		})
+
+		time.Sleep(100 * time.Millisecond)
+		queue.EnqueueBack(context.Background(), func() error {
+			results <- 2
+			return nil
+		})
+
+		release()
+		release() // calling release again is a no-op
+	}()
+
+	err := queue.Process(context.Background(), 2)
+	require.NoError(t, err)
+
+	close(results)
+
+	var sum int
+	for res := range results {
+		sum += res
+	}
+
+	require.Equal(t, 3, sum)
+}
+
 func TestProgressCallback(t *testing.T) {
 	queue := parallelwork.NewQueue()
 
@@ -0,0 +1,261 @@
+package pproflogging
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"runtime"
+	"runtime/pprof"
+	"slices"
+	"time"
+
+	"github.com/kopia/kopia/internal/clock"
+)
+
+// ErrProfileBuffersActive returned when attempting to start profile buffers that are already started.
+var ErrProfileBuffersActive = errors.New("profile buffers already started")
+
+// Profile contents of a single captured profile in the format produced by runtime/pprof.
+type Profile struct {
+	Name ProfileName `json:"name"`
+	Data []byte      `json:"data"`
+}
+
+// StartProfileBuffersWithConfig start profile buffers using the provided configuration, which has the
+// same format as the value of EnvVarKopiaDebugPprof.
+func StartProfileBuffersWithConfig(ctx context.Context, ppconfigs string) error {
+	pcm, err := parseProfileConfigs(DefaultDebugProfileDumpBufferSizeB, ppconfigs)
+	if err != nil {
+		return fmt.Errorf("cannot parse PPROF config %q: %w", ppconfigs, err)
+	}
+
+	pprofConfigs.mu.Lock()
+	defer pprofConfigs.mu.Unlock()
+
+	if len(pprofConfigs.pcm) > 0 {
+		return ErrProfileBuffersActive
+	}
+
+	pprofConfigs.pcm = pcm
+
+	// profiling rates need to be set before starting profiling
+	setupProfileFractions(ctx, pprofConfigs.pcm)
+
+	// cpu has special initialization
+	if v, ok := pprofConfigs.pcm[ProfileNameCPU]; ok {
+		if err := pprof.StartCPUProfile(v.buf); err != nil {
+			delete(pprofConfigs.pcm, ProfileNameCPU)
+			return fmt.Errorf("cannot start cpu PPROF: %w", err)
+		}
+	}
+
+	return nil
+}
+
+// ActiveProfiles returns the sorted names of profiles being captured.
+func ActiveProfiles() []ProfileName {
+	pprofConfigs.mu.Lock()
+	defer pprofConfigs.mu.Unlock()
+
+	var res []ProfileName
+
+	for k := range pprofConfigs.pcm {
+		res = append(res, k)
+	}
+
+	slices.Sort(res)
+
+	return res
+}
+
+// DumpProfileBuffers returns the current contents of active profiles, without stopping them.
+// The CPU profile is restarted, so each dump contains samples collected since the previous one.
+func DumpProfileBuffers(ctx context.Context) []Profile {
+	pprofConfigs.mu.Lock()
+	defer pprofConfigs.mu.Unlock()
+
+	var res []Profile
+
+	for k, v := range pprofConfigs.pcm {
+		if v == nil {
+			continue
+		}
+
+		if k == ProfileNameCPU {
+			pprof.StopCPUProfile()
+
+			res = append(res, Profile{k, bytes.Clone(v.buf.Bytes())})
+
+			v.buf.Reset()
+
+			if err := pprof.StartCPUProfile(v.buf); err != nil {
+				log(ctx).With("cause", err).Warn("cannot restart cpu PPROF")
+				delete(pprofConfigs.pcm, ProfileNameCPU)
+			}
+
+			continue
+		}
+
+		if data, ok := captureProfile(ctx, k, v); ok {
+			res = append(res, Profile{k, data})
+		}
+	}
+
+	sortProfiles(res)
+
+	return res
+}
+
+// CollectAndStopProfileBuffers stop profile buffers and return the contents of all profiles.
+func CollectAndStopProfileBuffers(ctx context.Context) []Profile {
+	pprofConfigs.mu.Lock()
+	defer pprofConfigs.mu.Unlock()
+
+	res := collectAndStopLocked(ctx)
+
+	sortProfiles(res)
+
+	return res
+}
+
+// WriteProfiles writes each profile to a separate file named after the profile and the provided time
+// in dir, and returns the names of the files.
+func WriteProfiles(dir string, t time.Time, profiles []Profile) ([]string, error) {
+	if err := os.MkdirAll(dir, 0o700); err != nil { //nolint:mnd
+		return nil, fmt.Errorf("cannot create profile directory: %w", err)
+	}
+
+	var fnames []string
+
+	for _, p := range profiles {
+		fname := filepath.Join(dir, fmt.Sprintf("%v-%v.pprof", p.Name, t.UTC().Format("20060102-150405.000")))
+
+		if err := os.WriteFile(fname, p.Data, 0o600); err != nil { //nolint:mnd
+			return fnames, fmt.Errorf("cannot write profile: %w", err)
+		}
+
+		fnames = append(fnames, fname)
+	}
+
+	return fnames, nil
+}
+
+// MaybeStopProfileBuffers stop profile buffers, if started, and write the profiles as raw files into
+// profileDir.  When profileDir is empty, the profiles are dumped to the log as PEMs.
+func MaybeStopProfileBuffers(ctx context.Context, profileDir string) {
+	if len(ActiveProfiles()) == 0 {
+		return
+	}
+
+	if profileDir == "" {
+		StopProfileBuffers(ctx)
+		return
+	}
+
+	writeProfilesToDir(ctx, profileDir, CollectAndStopProfileBuffers(ctx))
+}
+
+// MaybeDumpProfileBuffers write the current contents of profile buffers, if started, without stopping them.
+// The profiles are written as raw files into profileDir or, when profileDir is empty, dumped to the log as PEMs.
+func MaybeDumpProfileBuffers(ctx context.Context, profileDir string) {
+	if len(ActiveProfiles()) == 0 {
+		return
+	}
+
+	log(ctx).Info("dumping profile buffers")
+
+	if profileDir == "" {
+		dumpPems(ctx, DumpProfileBuffers(ctx))
+		return
+	}
+
+	writeProfilesToDir(ctx, profileDir, DumpProfileBuffers(ctx))
+}
+
+func writeProfilesToDir(ctx context.Context, profileDir string, profiles []Profile) {
+	fnames, err := WriteProfiles(profileDir, clock.Now(), profiles)
+	if err != nil {
+		log(ctx).With("cause", err).Error("cannot write profiles")
+	}
+
+	for _, fn := range fnames {
+		log(ctx).Infof("wrote profile %v", fn)
+	}
+}
+
+// +checklocks:pprofConfigs.mu
+func collectAndStopLocked(ctx context.Context) []Profile {
+	var res []Profile
+
+	// cpu and heap profiles requires special handling
+	for k, v := range pprofConfigs.pcm {
+		log(ctx).Debugf("stopping PPROF profile %q", k)
+
+		if v == nil {
+			continue
+		}
+
+		if k == ProfileNameCPU {
+			pprof.StopCPUProfile()
+
+			res = append(res, Profile{k, v.buf.Bytes()})
+
+			continue
+		}
+
+		if data, ok := captureProfile(ctx, k, v); ok {
+			res = append(res, Profile{k, data})
+		}
+	}
+
+	// clear the profile rates and fractions to effectively stop profiling
+	clearProfileFractions(pprofConfigs.pcm)
+	pprofConfigs.pcm = map[ProfileName]*ProfileConfig{}
+
+	return res
+}
+
+// captureProfile returns the current contents of the named runtime profile.
+func captureProfile(ctx context.Context, k ProfileName, v *ProfileConfig) ([]byte, bool) {
+	if _, ok := v.GetValue(KopiaDebugFlagForceGc); ok {
+		log(ctx).Debug("performing GC before PPROF dump ...")
+		runtime.GC()
+	}
+
+	debug, err := parseDebugNumber(v)
+	if err != nil {
+		log(ctx).With("cause", err).Warn("invalid PPROF configuration debug number")
+		return nil, false
+	}
+
+	pent := pprof.Lookup(string(k))
+	if pent == nil {
+		log(ctx).Warnf("no system PPROF entry for %q", k)
+		return nil, false
+	}
+
+	var buf bytes.Buffer
+
+	if err := pent.WriteTo(&buf, debug); err != nil {
+		log(ctx).With("cause", err).Warn("error writing PPROF buffer")
+		return nil, false
+	}
+
+	return buf.Bytes(), true
+}
+
+func sortProfiles(profiles []Profile) {
+	slices.SortFunc(profiles, func(a, b Profile) int {
+		switch {
+		case a.Name < b.Name:
+			return -1
+		case a.Name > b.Name:
+			return 1
+		default:
+			return 0
+		}
+	})
+}
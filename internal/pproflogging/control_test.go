@@ -0,0 +1,54 @@
+package pproflogging
+
+import (
+	"context"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestProfileBufferControl(t *testing.T) {
+	ctx := context.Background()
+
+	require.Empty(t, ActiveProfiles())
+	require.NoError(t, StartProfileBuffersWithConfig(ctx, "cpu:heap=forcegc"))
+
+	t.Cleanup(func() { CollectAndStopProfileBuffers(ctx) })
+
+	require.Equal(t, []ProfileName{ProfileNameCPU, "heap"}, ActiveProfiles())
+	require.ErrorIs(t, StartProfileBuffersWithConfig(ctx, "heap"), ErrProfileBuffersActive)
+
+	dumped := DumpProfileBuffers(ctx)
+	require.Len(t, dumped, 2)
+	require.Equal(t, ProfileName(ProfileNameCPU), dumped[0].Name)
+	require.Equal(t, ProfileName("heap"), dumped[1].Name)
+	require.NotEmpty(t, dumped[1].Data)
+
+	// profiles continue after the dump.
+	require.Equal(t, []ProfileName{ProfileNameCPU, "heap"}, ActiveProfiles())
+
+	collected := CollectAndStopProfileBuffers(ctx)
+	require.Len(t, collected, 2)
+	require.Empty(t, ActiveProfiles())
+
+	dir := t.TempDir()
+
+	fnames, err := WriteProfiles(dir, time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC), collected)
+	require.NoError(t, err)
+	require.Len(t, fnames, 2)
+
+	for i, fname := range fnames {
+		data, err := os.ReadFile(fname)
+		require.NoError(t, err)
+		require.Equal(t, collected[i].Data, data)
+	}
+
+	require.Contains(t, fnames[0], "cpu-20240102-030405.000.pprof")
+}
+
+func TestStartProfileBuffersWithConfigInvalid(t *testing.T) {
+	require.ErrorIs(t, StartProfileBuffersWithConfig(context.Background(), ":"), ErrEmptyProfileName)
+	require.Empty(t, ActiveProfiles())
+}
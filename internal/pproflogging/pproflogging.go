@@ -11,7 +11,6 @@
 	"io"
 	"os"
 	"runtime"
-	"runtime/pprof"
 	"strconv"
 	"strings"
 	"sync"
@@ -98,7 +97,7 @@ type pprofSetRate struct {
 //nolint:gochecknoglobals
 var pprofProfileRates = map[ProfileName]pprofSetRate{
 	ProfileNameBlock: {
-		setter:       func(x int) { runtime.SetBlockProfileRate(x) },
+		setter:       setBlockProfileRate,
 		defaultValue: DefaultDebugProfileRate,
 	},
 	ProfileNameMutex: {
@@ -257,34 +256,11 @@ func StartProfileBuffers(ctx context.Context) {
 		return
 	}
 
-	bufSizeB := DefaultDebugProfileDumpBufferSizeB
-
 	// look for matching services.  "*" signals all services for profiling
 	log(ctx).Debug("configuring profile buffers")
 
-	// acquire global lock when performing operations with global side-effects
-	pprofConfigs.mu.Lock()
-	defer pprofConfigs.mu.Unlock()
-
-	var err error
-
-	pprofConfigs.pcm, err = parseProfileConfigs(bufSizeB, ppconfigs)
-	if err != nil {
+	if err := StartProfileBuffersWithConfig(ctx, ppconfigs); err != nil {
 		log(ctx).With("cause", err).Warnf("cannot start PPROF config, %q, due to parse error", ppconfigs)
-		return
-	}
-
-	// profiling rates need to be set before starting profiling
-	setupProfileFractions(ctx, pprofConfigs.pcm)
-
-	// cpu has special initialization
-	v, ok := pprofConfigs.pcm[ProfileNameCPU]
-	if ok {
-		err := pprof.StartCPUProfile(v.buf)
-		if err != nil {
-			log(ctx).With("cause", err).Warn("cannot start cpu PPROF")
-			delete(pprofConfigs.pcm, ProfileNameCPU)
-		}
 	}
 }
 
@@ -375,71 +351,20 @@ func parseDebugNumber(v *ProfileConfig) (int, error) {
 // StopProfileBuffers stop and dump the contents of the buffers to the log as PEMs.  Buffers
 // supplied here are from StartProfileBuffers.
 func StopProfileBuffers(ctx context.Context) {
-	pprofConfigs.mu.Lock()
-	defer pprofConfigs.mu.Unlock()
-
-	if pprofConfigs == nil {
-		log(ctx).Debug("profile buffers not configured")
-		return
-	}
-
 	log(ctx).Debug("saving PEM buffers for output")
-	// cpu and heap profiles requires special handling
-	for k, v := range pprofConfigs.pcm {
-		log(ctx).Debugf("stopping PPROF profile %q", k)
 
-		if v == nil {
-			continue
-		}
-
-		if k == ProfileNameCPU {
-			pprof.StopCPUProfile()
-			continue
-		}
-
-		_, ok := v.GetValue(KopiaDebugFlagForceGc)
-		if ok {
-			log(ctx).Debug("performing GC before PPROF dump ...")
-			runtime.GC()
-		}
-
-		debug, err := parseDebugNumber(v)
-		if err != nil {
-			log(ctx).With("cause", err).Warn("invalid PPROF configuration debug number")
-			continue
-		}
-
-		pent := pprof.Lookup(string(k))
-		if pent == nil {
-			log(ctx).Warnf("no system PPROF entry for %q", k)
-			delete(pprofConfigs.pcm, k)
-
-			continue
-		}
-
-		err = pent.WriteTo(v.buf, debug)
-		if err != nil {
-			log(ctx).With("cause", err).Warn("error writing PPROF buffer")
-
-			continue
-		}
-	}
-	// dump the profiles out into their respective PEMs
-	for k, v := range pprofConfigs.pcm {
-		if v == nil {
-			continue
-		}
+	dumpPems(ctx, CollectAndStopProfileBuffers(ctx))
+}
 
-		unm := strings.ToUpper(string(k))
+// dumpPems dump the provided profiles to the log as PEMs.
+func dumpPems(ctx context.Context, profiles []Profile) {
+	for _, p := range profiles {
+		unm := strings.ToUpper(string(p.Name))
 		log(ctx).Infof("dumping PEM for %q", unm)
 
-		err := DumpPem(v.buf.Bytes(), unm, os.Stderr)
+		err := DumpPem(p.Data, unm, os.Stderr)
 		if err != nil {
 			log(ctx).With("cause", err).Error("cannot write PEM")
 		}
 	}
-
-	// clear the profile rates and fractions to effectively stop profiling
-	clearProfileFractions(pprofConfigs.pcm)
-	pprofConfigs.pcm = map[ProfileName]*ProfileConfig{}
 }
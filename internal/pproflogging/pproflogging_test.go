@@ -5,18 +5,17 @@
 	"context"
 	"fmt"
 	"io"
-	"os"
 	"regexp"
 	"testing"
 
 	"github.com/stretchr/testify/require"
 	"golang.org/x/exp/maps"
 
+	"github.com/kopia/kopia/internal/testutil"
 	"github.com/kopia/kopia/repo/logging"
 )
 
 func TestDebug_StartProfileBuffers(t *testing.T) {
-	saveLockEnv(t)
 	// placeholder to make coverage happy
 	tcs := []struct {
 		in string
@@ -35,15 +34,13 @@ func TestDebug_StartProfileBuffers(t *testing.T) {
 		lg := &bytes.Buffer{}
 		ctx := logging.WithLogger(context.Background(), logging.ToWriter(lg))
 
-		t.Setenv(EnvVarKopiaDebugPprof, tc.in)
+		testutil.EnvScope(t, map[string]string{EnvVarKopiaDebugPprof: tc.in})
 		StartProfileBuffers(ctx)
 		require.Regexp(t, tc.rx, lg.String())
 	}
 }
 
 func TestDebug_parseProfileConfigs(t *testing.T) {
-	saveLockEnv(t)
-
 	tcs := []struct {
 		in            string
 		key           ProfileName
@@ -175,8 +172,6 @@ func TestDebug_parseProfileConfigs(t *testing.T) {
 }
 
 func TestDebug_newProfileConfigs(t *testing.T) {
-	saveLockEnv(t)
-
 	tcs := []struct {
 		in     string
 		key    string
@@ -221,9 +216,6 @@ func TestDebug_newProfileConfigs(t *testing.T) {
 }
 
 func TestDebug_LoadProfileConfigs(t *testing.T) {
-	// save environment and restore after testing
-	saveLockEnv(t)
-
 	ctx := context.Background()
 
 	tcs := []struct {
@@ -297,18 +289,6 @@ func TestDebug_LoadProfileConfigs(t *testing.T) {
 	}
 }
 
-//nolint:gocritic
-func saveLockEnv(t *testing.T) {
-	t.Helper()
-
-	oldEnv := os.Getenv(EnvVarKopiaDebugPprof)
-
-	t.Cleanup(func() {
-		// restore the old environment
-		t.Setenv(EnvVarKopiaDebugPprof, oldEnv)
-	})
-}
-
 func TestErrorWriter(t *testing.T) {
 	eww := &ErrorWriter{mx: 5, err: io.EOF}
 	n, err := eww.WriteString("Hello World")
@@ -0,0 +1,110 @@
+package pproflogging
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"runtime"
+	"runtime/debug"
+	"sync"
+	"sync/atomic"
+)
+
+// RuntimeSettings describes Go runtime knobs which can be adjusted in a running process.
+// Nil values are left unchanged when applying settings.
+type RuntimeSettings struct {
+	// GCPercent has the same meaning as GOGC, negative value disables garbage collection.
+	GCPercent *int `json:"gcPercent,omitempty"`
+
+	// MemoryLimit has the same meaning as GOMEMLIMIT, in bytes.
+	MemoryLimit *int64 `json:"memoryLimit,omitempty"`
+
+	// MutexProfileFraction is the rate of mutex contention events reported in the mutex profile.
+	MutexProfileFraction *int `json:"mutexProfileFraction,omitempty"`
+
+	// BlockProfileRate is the rate of blocking events reported in the block profile.
+	BlockProfileRate *int `json:"blockProfileRate,omitempty"`
+}
+
+var (
+	// runtime does not provide a way to query block profile rate, so we keep track of the value we set.
+	//nolint:gochecknoglobals
+	currentBlockProfileRate atomic.Int64
+
+	// serializes changes to runtime settings, since reading GC percent requires changing it.
+	//nolint:gochecknoglobals
+	runtimeSettingsMutex sync.Mutex
+)
+
+// ErrInvalidRuntimeSettings is returned when runtime settings contain invalid values.
+var ErrInvalidRuntimeSettings = errors.New("invalid runtime settings")
+
+// Validate checks whether the runtime settings are valid.
+func (s RuntimeSettings) Validate() error {
+	if s.MemoryLimit != nil && *s.MemoryLimit < 0 {
+		return fmt.Errorf("%w: memory limit must not be negative", ErrInvalidRuntimeSettings)
+	}
+
+	if s.MutexProfileFraction != nil && *s.MutexProfileFraction < 0 {
+		return fmt.Errorf("%w: mutex profile fraction must not be negative", ErrInvalidRuntimeSettings)
+	}
+
+	return nil
+}
+
+func setBlockProfileRate(rate int) {
+	currentBlockProfileRate.Store(int64(rate))
+	runtime.SetBlockProfileRate(rate)
+}
+
+// GetRuntimeSettings returns current values of Go runtime knobs.
+func GetRuntimeSettings() RuntimeSettings {
+	runtimeSettingsMutex.Lock()
+	defer runtimeSettingsMutex.Unlock()
+
+	return getRuntimeSettingsLocked()
+}
+
+// +checklocks:runtimeSettingsMutex
+func getRuntimeSettingsLocked() RuntimeSettings {
+	gcPercent := debug.SetGCPercent(-1)
+	debug.SetGCPercent(gcPercent)
+
+	// negative values return the current settings without changing them.
+	memoryLimit := debug.SetMemoryLimit(-1)
+	mutexProfileFraction := runtime.SetMutexProfileFraction(-1)
+	blockProfileRate := int(currentBlockProfileRate.Load())
+
+	return RuntimeSettings{
+		GCPercent:            &gcPercent,
+		MemoryLimit:          &memoryLimit,
+		MutexProfileFraction: &mutexProfileFraction,
+		BlockProfileRate:     &blockProfileRate,
+	}
+}
+
+// ApplyRuntimeSettings changes Go runtime knobs that are set in the provided settings, logs each change
+// and returns resulting values.
+func ApplyRuntimeSettings(ctx context.Context, s RuntimeSettings) RuntimeSettings {
+	runtimeSettingsMutex.Lock()
+	defer runtimeSettingsMutex.Unlock()
+
+	if v := s.GCPercent; v != nil {
+		log(ctx).Infof("changing GC percent from %v to %v", debug.SetGCPercent(*v), *v)
+	}
+
+	if v := s.MemoryLimit; v != nil {
+		log(ctx).Infof("changing memory limit from %v to %v", debug.SetMemoryLimit(*v), *v)
+	}
+
+	if v := s.MutexProfileFraction; v != nil {
+		log(ctx).Infof("changing mutex profile fraction from %v to %v", runtime.SetMutexProfileFraction(*v), *v)
+	}
+
+	if v := s.BlockProfileRate; v != nil {
+		log(ctx).Infof("changing block profile rate from %v to %v", currentBlockProfileRate.Load(), *v)
+		setBlockProfileRate(*v)
+	}
+
+	return getRuntimeSettingsLocked()
+}
@@ -0,0 +1,47 @@
+package pproflogging
+
+import (
+	"context"
+	"runtime/debug"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestRuntimeSettings(t *testing.T) {
+	ctx := context.Background()
+
+	orig := GetRuntimeSettings()
+	t.Cleanup(func() { ApplyRuntimeSettings(ctx, orig) })
+
+	gcPercent := 150
+	memoryLimit := int64(1 << 40)
+	mutexProfileFraction := 10
+	blockProfileRate := 20
+
+	s := ApplyRuntimeSettings(ctx, RuntimeSettings{
+		GCPercent:            &gcPercent,
+		MemoryLimit:          &memoryLimit,
+		MutexProfileFraction: &mutexProfileFraction,
+		BlockProfileRate:     &blockProfileRate,
+	})
+
+	require.Equal(t, gcPercent, *s.GCPercent)
+	require.Equal(t, memoryLimit, *s.MemoryLimit)
+	require.Equal(t, mutexProfileFraction, *s.MutexProfileFraction)
+	require.Equal(t, blockProfileRate, *s.BlockProfileRate)
+	require.Equal(t, gcPercent, debug.SetGCPercent(gcPercent))
+
+	// nil values are left unchanged.
+	newGCPercent := 200
+
+	s = ApplyRuntimeSettings(ctx, RuntimeSettings{GCPercent: &newGCPercent})
+	require.Equal(t, newGCPercent, *s.GCPercent)
+	require.Equal(t, memoryLimit, *s.MemoryLimit)
+	require.Equal(t, s, GetRuntimeSettings())
+
+	negative := -1
+
+	require.ErrorIs(t, RuntimeSettings{MutexProfileFraction: &negative}.Validate(), ErrInvalidRuntimeSettings)
+	require.NoError(t, RuntimeSettings{GCPercent: &negative}.Validate())
+}
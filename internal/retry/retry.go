@@ -3,6 +3,7 @@
 
 import (
 	"context"
+	"math/rand"
 	"time"
 
 	"github.com/pkg/errors"
@@ -24,23 +25,65 @@
 // IsRetriableFunc is a function that determines whether an error is retriable.
 type IsRetriableFunc func(err error) bool
 
+// Policy determines how many attempts are made and how long to wait between them.
+type Policy struct {
+	MaxAttempts  int           // maximum number of attempts, negative to retry forever
+	InitialDelay time.Duration // delay after the first failed attempt
+	MaxDelay     time.Duration // maximum delay between attempts
+	Multiplier   float64       // factor by which the delay grows after each attempt
+	Jitter       float64       // fraction of each delay which is randomized, between 0 and 1
+}
+
+// DefaultPolicy returns the policy used by WithExponentialBackoff.
+func DefaultPolicy() Policy {
+	return Policy{
+		MaxAttempts:  maxAttempts,
+		InitialDelay: retryInitialSleepAmount,
+		MaxDelay:     retryMaxSleepAmount,
+		Multiplier:   retryExponent,
+	}
+}
+
 // WithExponentialBackoff runs the provided attempt until it succeeds, retrying on all errors that are
 // deemed retriable by the provided function. The delay between retries grows exponentially up to
 // a certain limit.
 func WithExponentialBackoff[T any](ctx context.Context, desc string, attempt func() (T, error), isRetriableError IsRetriableFunc) (T, error) {
-	return internalRetry(ctx, desc, attempt, isRetriableError, retryInitialSleepAmount, retryMaxSleepAmount, maxAttempts, retryExponent)
+	return WithPolicy(ctx, DefaultPolicy(), desc, attempt, isRetriableError)
 }
 
 // WithExponentialBackoffMaxRetries is the same as WithExponentialBackoff,
 // additionally it allows customizing the max number of retries before giving
 // up (count parameter). A negative value for count would run this forever.
 func WithExponentialBackoffMaxRetries[T any](ctx context.Context, count int, desc string, attempt func() (T, error), isRetriableError IsRetriableFunc) (T, error) {
-	return internalRetry(ctx, desc, attempt, isRetriableError, retryInitialSleepAmount, retryMaxSleepAmount, count, retryExponent)
+	p := DefaultPolicy()
+	p.MaxAttempts = count
+
+	return WithPolicy(ctx, p, desc, attempt, isRetriableError)
+}
+
+// WithPolicy runs the provided attempt until it succeeds, retrying on all errors that are deemed
+// retriable by the provided function, according to the provided policy.
+func WithPolicy[T any](ctx context.Context, p Policy, desc string, attempt func() (T, error), isRetriableError IsRetriableFunc) (T, error) {
+	return internalRetry(ctx, desc, attempt, isRetriableError, p)
+}
+
+// WithPolicyNoValue is a shorthand for WithPolicy except the attempt function does not return any value.
+func WithPolicyNoValue(ctx context.Context, p Policy, desc string, attempt func() error, isRetriableError IsRetriableFunc) error {
+	_, err := WithPolicy(ctx, p, desc, func() (interface{}, error) {
+		return nil, attempt()
+	}, isRetriableError)
+
+	return err
 }
 
 // Periodically runs the provided attempt until it succeeds, waiting given fixed amount between attempts.
 func Periodically[T any](ctx context.Context, interval time.Duration, count int, desc string, attempt func() (T, error), isRetriableError IsRetriableFunc) (T, error) {
-	return internalRetry(ctx, desc, attempt, isRetriableError, interval, interval, count, 1)
+	return internalRetry(ctx, desc, attempt, isRetriableError, Policy{
+		MaxAttempts:  count,
+		InitialDelay: interval,
+		MaxDelay:     interval,
+		Multiplier:   1,
+	})
 }
 
 // PeriodicallyNoValue runs the provided attempt until it succeeds, waiting given fixed amount between attempts.
@@ -55,8 +98,8 @@ func PeriodicallyNoValue(ctx context.Context, interval time.Duration, count int,
 // internalRetry runs the provided attempt until it succeeds, retrying on all errors that are
 // deemed retriable by the provided function. The delay between retries grows exponentially up to
 // a certain limit.
-func internalRetry[T any](ctx context.Context, desc string, attempt func() (T, error), isRetriableError IsRetriableFunc, initial, maxSleep time.Duration, count int, factor float64) (T, error) {
-	sleepAmount := initial
+func internalRetry[T any](ctx context.Context, desc string, attempt func() (T, error), isRetriableError IsRetriableFunc, p Policy) (T, error) {
+	sleepAmount := p.InitialDelay
 
 	var (
 		lastError error
@@ -65,7 +108,7 @@ func internalRetry[T any](ctx context.Context, desc string, attempt func() (T, e
 
 	var defaultT T
 
-	for ; i < count || count < 0; i++ {
+	for ; i < p.MaxAttempts || p.MaxAttempts < 0; i++ {
 		if cerr := ctx.Err(); cerr != nil {
 			//nolint:wrapcheck
 			return defaultT, cerr
@@ -82,18 +125,29 @@ func internalRetry[T any](ctx context.Context, desc string, attempt func() (T, e
 			return v, err
 		}
 
-		log(ctx).Debugf("got error %v when %v (#%v), sleeping for %v before retrying", err, desc, i, sleepAmount)
-		time.Sleep(sleepAmount)
-		sleepAmount = time.Duration(float64(sleepAmount) * factor)
+		d := withJitter(sleepAmount, p.Jitter)
+
+		log(ctx).Debugf("got error %v when %v (#%v), sleeping for %v before retrying", err, desc, i, d)
+		time.Sleep(d)
+		sleepAmount = time.Duration(float64(sleepAmount) * p.Multiplier)
 
-		if sleepAmount > maxSleep {
-			sleepAmount = maxSleep
+		if sleepAmount > p.MaxDelay {
+			sleepAmount = p.MaxDelay
 		}
 	}
 
 	return defaultT, errors.Wrapf(lastError, "unable to complete %v despite %v retries", desc, i)
 }
 
+// withJitter returns the delay reduced by a random amount of up to the provided fraction of it.
+func withJitter(d time.Duration, jitter float64) time.Duration {
+	if jitter <= 0 || d <= 0 {
+		return d
+	}
+
+	return d - time.Duration(float64(d)*min(jitter, 1)*rand.Float64()) //nolint:gosec
+}
+
 // WithExponentialBackoffNoValue is a shorthand for WithExponentialBackoff except the
 // attempt function does not return any value.
 func WithExponentialBackoffNoValue(ctx context.Context, desc string, attempt func() error, isRetriableError IsRetriableFunc) error {
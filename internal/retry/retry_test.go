@@ -72,3 +72,33 @@ func TestRetryContextCancel(t *testing.T) {
 		return errRetriable
 	}, isRetriable))
 }
+
+func TestWithJitter(t *testing.T) {
+	require.Equal(t, time.Second, withJitter(time.Second, 0))
+	require.Equal(t, time.Duration(0), withJitter(0, 0.5))
+
+	for range 100 {
+		d := withJitter(time.Second, 0.5)
+		require.LessOrEqual(t, d, time.Second)
+		require.GreaterOrEqual(t, d, 500*time.Millisecond)
+	}
+}
+
+func TestWithPolicy(t *testing.T) {
+	ctx := testlogging.Context(t)
+	cnt := 0
+
+	_, err := WithPolicy(ctx, Policy{
+		MaxAttempts:  4,
+		InitialDelay: time.Millisecond,
+		MaxDelay:     2 * time.Millisecond,
+		Multiplier:   2,
+		Jitter:       0.5,
+	}, "always-fails", func() (int, error) {
+		cnt++
+		return 0, errRetriable
+	}, isRetriable)
+
+	require.ErrorIs(t, err, errRetriable)
+	require.Equal(t, 4, cnt)
+}
@@ -9,9 +9,27 @@
 // ScrubSensitiveData returns a copy of a given value with sensitive fields scrubbed.
 // Fields are marked as sensitive with truct field tag `kopia:"sensitive"`.
 func ScrubSensitiveData(v reflect.Value) reflect.Value {
+	return scrub(v, func(s string) string {
+		return strings.Repeat("*", len(s))
+	})
+}
+
+// RedactSensitiveData is like ScrubSensitiveData, but replaces non-empty sensitive fields with the provided
+// placeholder, which does not reveal their length.
+func RedactSensitiveData(v reflect.Value, placeholder string) reflect.Value {
+	return scrub(v, func(s string) string {
+		if s == "" {
+			return ""
+		}
+
+		return placeholder
+	})
+}
+
+func scrub(v reflect.Value, mask func(s string) string) reflect.Value {
 	switch v.Kind() {
 	case reflect.Ptr:
-		return ScrubSensitiveData(v.Elem()).Addr()
+		return scrub(v.Elem(), mask).Addr()
 
 	case reflect.Struct:
 		res := reflect.New(v.Type()).Elem()
@@ -23,21 +41,21 @@ func ScrubSensitiveData(v reflect.Value) reflect.Value {
 
 			if sf.Tag.Get("kopia") == "sensitive" {
 				if sf.Type.Kind() == reflect.String {
-					res.Field(i).SetString(strings.Repeat("*", fv.Len()))
+					res.Field(i).SetString(mask(fv.String()))
 				}
 			} else if sf.IsExported() {
 				switch fv.Kind() {
 				case reflect.Pointer:
 					if !fv.IsNil() {
-						fv = ScrubSensitiveData(fv.Elem()).Addr()
+						fv = scrub(fv.Elem(), mask).Addr()
 					}
 
 				case reflect.Struct:
-					fv = ScrubSensitiveData(fv)
+					fv = scrub(fv, mask)
 
 				case reflect.Interface:
 					if !fv.IsNil() {
-						fv = ScrubSensitiveData(fv.Elem())
+						fv = scrub(fv.Elem(), mask)
 					}
 
 				default: // Set the field as-is.
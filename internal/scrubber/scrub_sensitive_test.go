@@ -67,6 +67,33 @@ func TestScrubber(t *testing.T) {
 	require.Equal(t, want, output)
 }
 
+func TestRedactSensitiveData(t *testing.T) {
+	input := &S{
+		NonPassword: "bar",
+		InnerPtr: &Q{
+			SomePassword1: "foo",
+			NonPassword:   "bar",
+		},
+		InnerStruct: Q{
+			SomePassword1: "a-much-longer-password",
+		},
+	}
+
+	want := &S{
+		NonPassword: "bar",
+		InnerPtr: &Q{
+			SomePassword1: "<redacted>",
+			NonPassword:   "bar",
+		},
+		InnerStruct: Q{
+			SomePassword1: "<redacted>",
+		},
+	}
+
+	output := scrubber.RedactSensitiveData(reflect.ValueOf(input), "<redacted>").Interface()
+	require.Equal(t, want, output)
+}
+
 func TestScrubberPanicsOnNonStruct(t *testing.T) {
 	require.Panics(t, func() {
 		scrubber.ScrubSensitiveData(reflect.ValueOf(1))
@@ -0,0 +1,36 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+
+	"github.com/kopia/kopia/internal/serverapi"
+	"github.com/kopia/kopia/snapshot/snapshotfs"
+)
+
+func handleDiff(ctx context.Context, rc requestContext) (interface{}, *apiError) {
+	var req serverapi.DiffRequest
+
+	if err := json.Unmarshal(rc.body, &req); err != nil {
+		return nil, unableToDecodeRequest(err)
+	}
+
+	ent1, err := snapshotfs.FilesystemEntryFromIDWithPath(ctx, rc.rep, req.ObjectPath1, false)
+	if err != nil {
+		return nil, requestError(serverapi.ErrorMalformedRequest, "invalid first object")
+	}
+
+	ent2, err := snapshotfs.FilesystemEntryFromIDWithPath(ctx, rc.rep, req.ObjectPath2, false)
+	if err != nil {
+		return nil, requestError(serverapi.ErrorMalformedRequest, "invalid second object")
+	}
+
+	changes, err := snapshotfs.Diff(ctx, ent1, ent2)
+	if err != nil {
+		return nil, internalServerError(err)
+	}
+
+	return &serverapi.DiffResponse{
+		Changes: append([]snapshotfs.EntryChange{}, changes...),
+	}, nil
+}
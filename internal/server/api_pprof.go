@@ -0,0 +1,69 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+
+	"github.com/kopia/kopia/internal/pproflogging"
+	"github.com/kopia/kopia/internal/serverapi"
+)
+
+func handleProfilingStatus(ctx context.Context, rc requestContext) (interface{}, *apiError) {
+	resp := &serverapi.ProfilingStatusResponse{
+		Active: []string{},
+	}
+
+	for _, n := range pproflogging.ActiveProfiles() {
+		resp.Active = append(resp.Active, string(n))
+	}
+
+	return resp, nil
+}
+
+func handleProfilingStart(ctx context.Context, rc requestContext) (interface{}, *apiError) {
+	var req serverapi.StartProfilingRequest
+
+	if err := json.Unmarshal(rc.body, &req); err != nil {
+		return nil, unableToDecodeRequest(err)
+	}
+
+	if err := pproflogging.StartProfileBuffersWithConfig(ctx, req.Config); err != nil {
+		return nil, requestError(serverapi.ErrorMalformedRequest, "unable to start profiling: "+err.Error())
+	}
+
+	return handleProfilingStatus(ctx, rc)
+}
+
+func handleProfilingStop(ctx context.Context, rc requestContext) (interface{}, *apiError) {
+	return &serverapi.ProfilesResponse{
+		Profiles: pproflogging.CollectAndStopProfileBuffers(ctx),
+	}, nil
+}
+
+func handleProfilingDump(ctx context.Context, rc requestContext) (interface{}, *apiError) {
+	return &serverapi.ProfilesResponse{
+		Profiles: pproflogging.DumpProfileBuffers(ctx),
+	}, nil
+}
+
+func handleGetRuntimeSettings(ctx context.Context, rc requestContext) (interface{}, *apiError) {
+	s := pproflogging.GetRuntimeSettings()
+
+	return &s, nil
+}
+
+func handleSetRuntimeSettings(ctx context.Context, rc requestContext) (interface{}, *apiError) {
+	var req pproflogging.RuntimeSettings
+
+	if err := json.Unmarshal(rc.body, &req); err != nil {
+		return nil, unableToDecodeRequest(err)
+	}
+
+	if err := req.Validate(); err != nil {
+		return nil, requestError(serverapi.ErrorMalformedRequest, err.Error())
+	}
+
+	s := pproflogging.ApplyRuntimeSettings(ctx, req)
+
+	return &s, nil
+}
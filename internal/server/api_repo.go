@@ -8,6 +8,7 @@
 
 	"github.com/pkg/errors"
 
+	"github.com/kopia/kopia/internal/clock"
 	"github.com/kopia/kopia/internal/gather"
 	"github.com/kopia/kopia/internal/passwordpersist"
 	"github.com/kopia/kopia/internal/serverapi"
@@ -15,6 +16,7 @@
 	"github.com/kopia/kopia/repo/blob"
 	"github.com/kopia/kopia/repo/blob/throttling"
 	"github.com/kopia/kopia/repo/compression"
+	"github.com/kopia/kopia/repo/content"
 	"github.com/kopia/kopia/repo/ecc"
 	"github.com/kopia/kopia/repo/encryption"
 	"github.com/kopia/kopia/repo/format"
@@ -326,6 +328,65 @@ func handleRepoSetThrottle(ctx context.Context, rc requestContext) (interface{},
 	return &serverapi.Empty{}, nil
 }
 
+func handleRepoGetCacheLimits(ctx context.Context, rc requestContext) (interface{}, *apiError) {
+	dr, ok := rc.rep.(repo.DirectRepository)
+	if !ok {
+		return nil, requestError(serverapi.ErrorStorageConnection, "no direct storage connection")
+	}
+
+	l := dr.CacheSizeLimits()
+
+	return &l, nil
+}
+
+func handleRepoSetCacheLimits(ctx context.Context, rc requestContext) (interface{}, *apiError) {
+	dr, ok := rc.rep.(repo.DirectRepository)
+	if !ok {
+		return nil, requestError(serverapi.ErrorStorageConnection, "no direct storage connection")
+	}
+
+	var req content.CacheSizeLimits
+	if err := json.Unmarshal(rc.body, &req); err != nil {
+		return nil, unableToDecodeRequest(err)
+	}
+
+	if err := dr.SetCacheSizeLimits(ctx, req); err != nil {
+		return nil, requestError(serverapi.ErrorMalformedRequest, "unable to set cache limits: "+err.Error())
+	}
+
+	return &serverapi.Empty{}, nil
+}
+
+func handleRepoListSessions(ctx context.Context, rc requestContext) (interface{}, *apiError) {
+	dr, ok := rc.rep.(repo.DirectRepository)
+	if !ok {
+		return nil, requestError(serverapi.ErrorStorageConnection, "no direct storage connection")
+	}
+
+	sessions, err := dr.ContentReader().ListActiveSessions(ctx)
+	if err != nil {
+		return nil, internalServerError(err)
+	}
+
+	now := clock.Now()
+	resp := &serverapi.WriteSessionsResponse{
+		Sessions: []*serverapi.WriteSession{},
+	}
+
+	for _, si := range sessions {
+		resp.Sessions = append(resp.Sessions, &serverapi.WriteSession{
+			SessionInfo: *si,
+			Expired:     maintenance.IsSessionExpired(si, now, maintenance.SafetyFull),
+		})
+	}
+
+	sort.Slice(resp.Sessions, func(i, j int) bool {
+		return resp.Sessions[i].StartTime.Before(resp.Sessions[j].StartTime)
+	})
+
+	return resp, nil
+}
+
 func (s *Server) getConnectOptions(cliOpts repo.ClientOptions) *repo.ConnectOptions {
 	o := *s.options.ConnectOptions
 	o.ClientOptions = o.ClientOptions.Override(cliOpts)
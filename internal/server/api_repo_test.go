@@ -0,0 +1,49 @@
+package server_test
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/kopia/kopia/internal/apiclient"
+	"github.com/kopia/kopia/internal/gather"
+	"github.com/kopia/kopia/internal/repotesting"
+	"github.com/kopia/kopia/internal/serverapi"
+	"github.com/kopia/kopia/internal/servertesting"
+	"github.com/kopia/kopia/repo/content"
+)
+
+func TestListWriteSessions(t *testing.T) {
+	ctx, env := repotesting.NewEnvironment(t, repotesting.FormatNotImportant)
+	srvInfo := servertesting.StartServer(t, env, false)
+
+	cli, err := apiclient.NewKopiaAPIClient(apiclient.Options{
+		BaseURL:                             srvInfo.BaseURL,
+		TrustedServerCertificateFingerprint: srvInfo.TrustedServerCertificateFingerprint,
+		Username:                            servertesting.TestUIUsername,
+		Password:                            servertesting.TestUIPassword,
+	})
+
+	require.NoError(t, err)
+	require.NoError(t, cli.FetchCSRFTokenForTesting(ctx))
+
+	resp, err := serverapi.ListWriteSessions(ctx, cli)
+	require.NoError(t, err)
+	require.Empty(t, resp.Sessions)
+
+	// writing content starts a session, which remains open until flushed.
+	_, err = env.RepositoryWriter.ContentManager().WriteContent(ctx, gather.FromSlice([]byte("hello")), "", content.NoCompression)
+	require.NoError(t, err)
+
+	resp, err = serverapi.ListWriteSessions(ctx, cli)
+	require.NoError(t, err)
+	require.Len(t, resp.Sessions, 1)
+	require.False(t, resp.Sessions[0].Expired)
+	require.Equal(t, env.RepositoryWriter.ClientOptions().Hostname, resp.Sessions[0].Host)
+
+	require.NoError(t, env.RepositoryWriter.Flush(ctx))
+
+	resp, err = serverapi.ListWriteSessions(ctx, cli)
+	require.NoError(t, err)
+	require.Empty(t, resp.Sessions)
+}
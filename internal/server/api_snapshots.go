@@ -4,6 +4,7 @@
 	"context"
 	"encoding/json"
 	"net/url"
+	"strconv"
 
 	"github.com/pkg/errors"
 
@@ -17,6 +18,10 @@
 func handleListSnapshots(ctx context.Context, rc requestContext) (interface{}, *apiError) {
 	si := getSnapshotSourceFromURL(rc.req.URL)
 
+	if rc.queryParam("limit") != "" || rc.queryParam("pageToken") != "" {
+		return listSnapshotsPage(ctx, rc, si)
+	}
+
 	manifestIDs, err := snapshot.ListSnapshotManifests(ctx, rc.rep, &si, nil)
 	if err != nil {
 		return nil, internalServerError(err)
@@ -54,6 +59,58 @@ func handleListSnapshots(ctx context.Context, rc requestContext) (interface{}, *
 	return resp, nil
 }
 
+// listSnapshotsPage returns a single page of snapshots, which avoids loading all snapshot manifests of sources
+// with many snapshots. Because other snapshots are not loaded, all snapshots in the page are returned
+// and retention reasons are not computed.
+func listSnapshotsPage(ctx context.Context, rc requestContext, si snapshot.SourceInfo) (interface{}, *apiError) {
+	opts := manifest.FindOptions{
+		Descending: rc.queryParam("desc") != "",
+		PageToken:  rc.queryParam("pageToken"),
+	}
+
+	if v := rc.queryParam("limit"); v != "" {
+		limit, err := strconv.Atoi(v)
+		if err != nil || limit < 0 {
+			return nil, requestError(serverapi.ErrorMalformedRequest, "invalid limit")
+		}
+
+		opts.Limit = limit
+	}
+
+	page, err := snapshot.ListSnapshotManifestsPage(ctx, rc.rep, &si, opts)
+	if errors.Is(err, manifest.ErrInvalidPageToken) {
+		return nil, requestError(serverapi.ErrorMalformedRequest, "invalid page token")
+	}
+
+	if err != nil {
+		return nil, internalServerError(err)
+	}
+
+	var ids []manifest.ID
+	for _, e := range page.Entries {
+		ids = append(ids, e.ID)
+	}
+
+	manifests, err := snapshot.LoadSnapshots(ctx, rc.rep, ids)
+	if err != nil {
+		return nil, internalServerError(err)
+	}
+
+	resp := &serverapi.SnapshotsResponse{
+		Snapshots:     []*serverapi.Snapshot{},
+		NextPageToken: page.NextPageToken,
+	}
+
+	for _, m := range manifests {
+		resp.Snapshots = append(resp.Snapshots, convertSnapshotManifest(m))
+	}
+
+	resp.UnfilteredCount = len(resp.Snapshots)
+	resp.UniqueCount = len(uniqueSnapshots(resp.Snapshots))
+
+	return resp, nil
+}
+
 func handleDeleteSnapshots(ctx context.Context, rc requestContext) (interface{}, *apiError) {
 	var req serverapi.DeleteSnapshotsRequest
 
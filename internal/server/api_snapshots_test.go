@@ -107,6 +107,32 @@ func TestListAndDeleteSnapshots(t *testing.T) {
 	require.Equal(t, 3, resp.UniqueCount)
 	require.Equal(t, 4, resp.UnfilteredCount)
 
+	// list snapshots one page at a time, newest first.
+	var pagedIDs []manifest.ID
+
+	pageOpts := manifest.FindOptions{Limit: 3, Descending: true}
+
+	for {
+		resp, err = serverapi.ListSnapshotsPage(ctx, cli, si1, pageOpts)
+		require.NoError(t, err)
+		require.LessOrEqual(t, len(resp.Snapshots), 3)
+
+		for _, s := range resp.Snapshots {
+			pagedIDs = append(pagedIDs, s.ID)
+		}
+
+		if resp.NextPageToken == "" {
+			break
+		}
+
+		pageOpts.PageToken = resp.NextPageToken
+	}
+
+	require.Equal(t, []manifest.ID{id14, id13, id12, id11}, pagedIDs)
+
+	_, err = serverapi.ListSnapshotsPage(ctx, cli, si1, manifest.FindOptions{Limit: 3, PageToken: "invalid"})
+	require.Error(t, err)
+
 	// now delete id11 and id14 via the API
 	require.NoError(t, cli.Post(ctx, "snapshots/delete", &serverapi.DeleteSnapshotsRequest{
 		SourceInfo: si1,
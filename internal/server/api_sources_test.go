@@ -95,6 +95,18 @@ func TestSnapshotCounters(t *testing.T) {
 	require.Equal(t, ut.Counters["Excluded Directories"], uitask.SimpleCounter(1))
 	require.Equal(t, ut.Counters["Excluded Files"], uitask.SimpleCounter(1))
 	require.Equal(t, ut.Counters["Processed Files"], uitask.SimpleCounter(3))
+
+	// per-source metrics are updated once the source refreshes after the snapshot.
+	sourceLabels := "[host:" + si.Host + ";path:" + si.Path + ";user:" + si.UserName + "]"
+
+	require.Eventually(t, func() bool {
+		return env.RepositoryMetrics().Snapshot(false).Gauges["source_last_snapshot_time_seconds"+sourceLabels] > 0
+	}, 15*time.Second, 10*time.Millisecond)
+
+	ms := env.RepositoryMetrics().Snapshot(false)
+	require.GreaterOrEqual(t, ms.Gauges["source_last_snapshot_age_seconds"+sourceLabels], int64(0))
+	require.Equal(t, int64(0), ms.Gauges["source_last_snapshot_errors"+sourceLabels])
+	require.Positive(t, ms.Counters["source_uploaded_bytes"+sourceLabels])
 }
 
 func TestSourceRefreshesAfterPolicy(t *testing.T) {
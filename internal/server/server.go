@@ -132,6 +132,7 @@ func (s *Server) SetupHTMLUIAPIHandlers(m *mux.Router) {
 	m.HandleFunc("/api/v1/objects/{objectID}", s.requireAuth(csrfTokenNotRequired, handleObjectGet)).Methods(http.MethodGet)
 	m.HandleFunc("/api/v1/restore", s.handleUI(handleRestore)).Methods(http.MethodPost)
 	m.HandleFunc("/api/v1/estimate", s.handleUI(handleEstimate)).Methods(http.MethodPost)
+	m.HandleFunc("/api/v1/diff", s.handleUI(handleDiff)).Methods(http.MethodPost)
 	m.HandleFunc("/api/v1/paths/resolve", s.handleUI(handlePathResolve)).Methods(http.MethodPost)
 	m.HandleFunc("/api/v1/cli", s.handleUI(handleCLIInfo)).Methods(http.MethodGet)
 	m.HandleFunc("/api/v1/repo/status", s.handleUIPossiblyNotConnected(handleRepoStatus)).Methods(http.MethodGet)
@@ -144,6 +145,9 @@ func (s *Server) SetupHTMLUIAPIHandlers(m *mux.Router) {
 	m.HandleFunc("/api/v1/repo/algorithms", s.handleUIPossiblyNotConnected(handleRepoSupportedAlgorithms)).Methods(http.MethodGet)
 	m.HandleFunc("/api/v1/repo/throttle", s.handleUI(handleRepoGetThrottle)).Methods(http.MethodGet)
 	m.HandleFunc("/api/v1/repo/throttle", s.handleUI(handleRepoSetThrottle)).Methods(http.MethodPut)
+	m.HandleFunc("/api/v1/repo/cache-limits", s.handleUI(handleRepoGetCacheLimits)).Methods(http.MethodGet)
+	m.HandleFunc("/api/v1/repo/cache-limits", s.handleUI(handleRepoSetCacheLimits)).Methods(http.MethodPut)
+	m.HandleFunc("/api/v1/repo/sessions", s.handleUI(handleRepoListSessions)).Methods(http.MethodGet)
 
 	m.HandleFunc("/api/v1/mounts", s.handleUI(handleMountCreate)).Methods(http.MethodPost)
 	m.HandleFunc("/api/v1/mounts/{rootObjectID}", s.handleUI(handleMountDelete)).Methods(http.MethodDelete)
@@ -175,6 +179,14 @@ func (s *Server) SetupControlAPIHandlers(m *mux.Router) {
 	m.HandleFunc("/api/v1/control/resume-source", s.handleServerControlAPI(handleResume)).Methods(http.MethodPost)
 	m.HandleFunc("/api/v1/control/throttle", s.handleServerControlAPI(handleRepoGetThrottle)).Methods(http.MethodGet)
 	m.HandleFunc("/api/v1/control/throttle", s.handleServerControlAPI(handleRepoSetThrottle)).Methods(http.MethodPut)
+	m.HandleFunc("/api/v1/control/cache-limits", s.handleServerControlAPI(handleRepoGetCacheLimits)).Methods(http.MethodGet)
+	m.HandleFunc("/api/v1/control/cache-limits", s.handleServerControlAPI(handleRepoSetCacheLimits)).Methods(http.MethodPut)
+	m.HandleFunc("/api/v1/control/pprof", s.handleServerControlAPIPossiblyNotConnected(handleProfilingStatus)).Methods(http.MethodGet)
+	m.HandleFunc("/api/v1/control/pprof/start", s.handleServerControlAPIPossiblyNotConnected(handleProfilingStart)).Methods(http.MethodPost)
+	m.HandleFunc("/api/v1/control/pprof/stop", s.handleServerControlAPIPossiblyNotConnected(handleProfilingStop)).Methods(http.MethodPost)
+	m.HandleFunc("/api/v1/control/pprof/dump", s.handleServerControlAPIPossiblyNotConnected(handleProfilingDump)).Methods(http.MethodPost)
+	m.HandleFunc("/api/v1/control/runtime", s.handleServerControlAPIPossiblyNotConnected(handleGetRuntimeSettings)).Methods(http.MethodGet)
+	m.HandleFunc("/api/v1/control/runtime", s.handleServerControlAPIPossiblyNotConnected(handleSetRuntimeSettings)).Methods(http.MethodPut)
 }
 
 func isAuthenticated(rc requestContext) bool {
@@ -74,6 +74,7 @@ type sourceManager struct {
 
 	isReadOnly bool
 	progress   *snapshotfs.CountingUploadProgress
+	metrics    *sourceMetrics
 }
 
 func (s *sourceManager) Status() *serverapi.SourceStatus {
@@ -197,6 +198,8 @@ func (s *sourceManager) runLocal(ctx context.Context) {
 				if err := s.server.runSnapshotTask(ctx, s.src, s.snapshotInternal); err != nil {
 					log(ctx).Errorf("snapshot error: %v", err)
 
+					s.metrics.snapshotFailures.Add(1)
+
 					s.backoffBeforeNextSnapshot()
 				} else {
 					s.refreshStatus(ctx)
@@ -333,6 +336,7 @@ func (s *sourceManager) snapshotInternal(ctx context.Context, ctrl uitask.Contro
 			// extra indirection to allow changing onUpload function later
 			// once we have the uploader
 			onUpload(numBytes)
+			s.metrics.uploadedBytes.Add(numBytes)
 		},
 	}, func(ctx context.Context, w repo.RepositoryWriter) error {
 		log(ctx).Debugf("uploading %v", s.src)
@@ -450,6 +454,8 @@ func (s *sourceManager) refreshStatus(ctx context.Context) {
 		s.lastSnapshot = nil
 	}
 
+	s.metrics.update(s.lastCompleteSnapshot)
+
 	if s.paused {
 		s.nextSnapshotTime = nil
 	} else {
@@ -573,6 +579,7 @@ func newSourceManager(src snapshot.SourceInfo, server *Server, rep repo.Reposito
 		closed:           make(chan struct{}),
 		snapshotRequests: make(chan struct{}, 1),
 		progress:         &snapshotfs.CountingUploadProgress{},
+		metrics:          newSourceMetrics(rep, src),
 	}
 
 	return m
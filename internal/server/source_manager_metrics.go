@@ -0,0 +1,67 @@
+package server
+
+import (
+	"time"
+
+	"github.com/kopia/kopia/internal/clock"
+	"github.com/kopia/kopia/internal/metrics"
+	"github.com/kopia/kopia/repo"
+	"github.com/kopia/kopia/snapshot"
+)
+
+// sourceMetrics holds metrics of a single snapshot source, labeled with its host, user and path,
+// which allow alerting on sources that have not been snapshotted recently.
+type sourceMetrics struct {
+	lastSnapshotTime     *metrics.Gauge
+	lastSnapshotAge      *metrics.Gauge
+	lastSnapshotDuration *metrics.Gauge
+	lastSnapshotErrors   *metrics.Gauge
+
+	uploadedBytes    *metrics.Counter
+	snapshotFailures *metrics.Counter
+}
+
+// update sets metrics describing the last complete snapshot of the source.
+func (m *sourceMetrics) update(lastComplete *snapshot.Manifest) {
+	if lastComplete == nil {
+		return
+	}
+
+	startTime := lastComplete.StartTime.ToTime()
+
+	m.lastSnapshotTime.Set(startTime.Unix())
+	m.lastSnapshotAge.SetFunc(func() int64 {
+		return int64(clock.Now().Sub(startTime) / time.Second)
+	})
+	m.lastSnapshotDuration.Set(int64(lastComplete.EndTime.Sub(lastComplete.StartTime) / time.Second))
+	m.lastSnapshotErrors.Set(int64(lastComplete.Stats.ErrorCount))
+}
+
+func newSourceMetrics(rep repo.Repository, src snapshot.SourceInfo) *sourceMetrics {
+	var mr *metrics.Registry
+
+	if r, ok := rep.(interface{ Metrics() *metrics.Registry }); ok {
+		mr = r.Metrics()
+	}
+
+	labels := map[string]string{
+		"host": src.Host,
+		"user": src.UserName,
+		"path": src.Path,
+	}
+
+	return &sourceMetrics{
+		lastSnapshotTime: mr.GaugeInt64("source_last_snapshot_time_seconds",
+			"Start time of the last complete snapshot of the source as Unix timestamp.", labels),
+		lastSnapshotAge: mr.GaugeInt64("source_last_snapshot_age_seconds",
+			"Time elapsed since the start of the last complete snapshot of the source.", labels),
+		lastSnapshotDuration: mr.GaugeInt64("source_last_snapshot_duration_seconds",
+			"Duration of the last complete snapshot of the source.", labels),
+		lastSnapshotErrors: mr.GaugeInt64("source_last_snapshot_errors",
+			"Number of errors encountered by the last complete snapshot of the source.", labels),
+		uploadedBytes: mr.CounterInt64("source_uploaded_bytes",
+			"Number of bytes uploaded while snapshotting the source.", labels),
+		snapshotFailures: mr.CounterInt64("source_snapshot_failures",
+			"Number of failed snapshots of the source.", labels),
+	}
+}
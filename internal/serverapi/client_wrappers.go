@@ -3,6 +3,7 @@
 import (
 	"context"
 	"fmt"
+	"net/url"
 	"strings"
 
 	"github.com/pkg/errors"
@@ -10,6 +11,8 @@
 	"github.com/kopia/kopia/internal/apiclient"
 	"github.com/kopia/kopia/internal/uitask"
 	"github.com/kopia/kopia/repo/blob/throttling"
+	"github.com/kopia/kopia/repo/content"
+	"github.com/kopia/kopia/repo/manifest"
 	"github.com/kopia/kopia/repo/object"
 	"github.com/kopia/kopia/snapshot"
 	"github.com/kopia/kopia/snapshot/policy"
@@ -35,6 +38,16 @@ func Estimate(ctx context.Context, c *apiclient.KopiaAPIClient, req *EstimateReq
 	return resp, nil
 }
 
+// Diff compares two snapshot roots.
+func Diff(ctx context.Context, c *apiclient.KopiaAPIClient, req *DiffRequest) (*DiffResponse, error) {
+	resp := &DiffResponse{}
+	if err := c.Post(ctx, "diff", req, resp); err != nil {
+		return nil, errors.Wrap(err, "Diff")
+	}
+
+	return resp, nil
+}
+
 // Restore starts snapshot restore task for a given directory.
 func Restore(ctx context.Context, c *apiclient.KopiaAPIClient, req *RestoreRequest) (*uitask.Info, error) {
 	resp := &uitask.Info{}
@@ -138,6 +151,35 @@ func SetThrottlingLimits(ctx context.Context, c *apiclient.KopiaAPIClient, l thr
 	return nil
 }
 
+// GetCacheSizeLimits gets the size limits of local caches of the repository connection.
+func GetCacheSizeLimits(ctx context.Context, c *apiclient.KopiaAPIClient) (content.CacheSizeLimits, error) {
+	resp := content.CacheSizeLimits{}
+	if err := c.Get(ctx, "repo/cache-limits", nil, &resp); err != nil {
+		return content.CacheSizeLimits{}, errors.Wrap(err, "cache limits")
+	}
+
+	return resp, nil
+}
+
+// SetCacheSizeLimits changes the size limits of local caches of the repository connection.
+func SetCacheSizeLimits(ctx context.Context, c *apiclient.KopiaAPIClient, l content.CacheSizeLimits) error {
+	if err := c.Put(ctx, "repo/cache-limits", &l, &Empty{}); err != nil {
+		return errors.Wrap(err, "cache limits")
+	}
+
+	return nil
+}
+
+// ListWriteSessions lists write sessions currently found in the repository.
+func ListWriteSessions(ctx context.Context, c *apiclient.KopiaAPIClient) (*WriteSessionsResponse, error) {
+	resp := &WriteSessionsResponse{}
+	if err := c.Get(ctx, "repo/sessions", nil, resp); err != nil {
+		return nil, errors.Wrap(err, "write sessions")
+	}
+
+	return resp, nil
+}
+
 // ListSources lists the snapshot sources managed by the server.
 func ListSources(ctx context.Context, c *apiclient.KopiaAPIClient, match *snapshot.SourceInfo) (*SourcesResponse, error) {
 	resp := &SourcesResponse{}
@@ -164,6 +206,22 @@ func ListSnapshots(ctx context.Context, c *apiclient.KopiaAPIClient, src snapsho
 	return resp, nil
 }
 
+// ListSnapshotsPage lists a single page of snapshots for a given source, the labels in the provided options are ignored.
+func ListSnapshotsPage(ctx context.Context, c *apiclient.KopiaAPIClient, src snapshot.SourceInfo, opts manifest.FindOptions) (*SnapshotsResponse, error) {
+	resp := &SnapshotsResponse{}
+
+	u := "snapshots" + matchSourceParameters(&src) + fmt.Sprintf("&limit=%v&pageToken=%v", opts.Limit, url.QueryEscape(opts.PageToken))
+	if opts.Descending {
+		u += "&desc=1"
+	}
+
+	if err := c.Get(ctx, u, nil, resp); err != nil {
+		return nil, errors.Wrap(err, "ListSnapshotsPage")
+	}
+
+	return resp, nil
+}
+
 // ListPolicies lists the policies managed by the server for a given target filter.
 func ListPolicies(ctx context.Context, c *apiclient.KopiaAPIClient, match *snapshot.SourceInfo) (*PoliciesResponse, error) {
 	resp := &PoliciesResponse{}
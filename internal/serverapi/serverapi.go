@@ -6,9 +6,11 @@
 	"time"
 
 	"github.com/kopia/kopia/fs"
+	"github.com/kopia/kopia/internal/pproflogging"
 	"github.com/kopia/kopia/internal/uitask"
 	"github.com/kopia/kopia/repo"
 	"github.com/kopia/kopia/repo/blob"
+	"github.com/kopia/kopia/repo/content"
 	"github.com/kopia/kopia/repo/format"
 	"github.com/kopia/kopia/repo/manifest"
 	"github.com/kopia/kopia/repo/object"
@@ -182,6 +184,7 @@ type SnapshotsResponse struct {
 	Snapshots       []*Snapshot `json:"snapshots"`
 	UnfilteredCount int         `json:"unfilteredCount"`
 	UniqueCount     int         `json:"uniqueCount"`
+	NextPageToken   string      `json:"nextPageToken,omitempty"`
 }
 
 // DeleteSnapshotsRequest contains request to delete a number of snapshots and optionally the
@@ -250,6 +253,17 @@ type RestoreRequest struct {
 	Options restore.Options `json:"options"`
 }
 
+// DiffRequest contains request to compare two snapshot roots, identified by object IDs optionally followed by paths.
+type DiffRequest struct {
+	ObjectPath1 string `json:"objectPath1"`
+	ObjectPath2 string `json:"objectPath2"`
+}
+
+// DiffResponse contains the list of changes between two snapshot roots.
+type DiffResponse struct {
+	Changes []snapshotfs.EntryChange `json:"changes"`
+}
+
 // EstimateRequest contains request to estimate the size of the snapshot in a given root.
 type EstimateRequest struct {
 	Root                 string         `json:"root"`
@@ -272,6 +286,20 @@ type ResolvePolicyResponse struct {
 	SchedulingError       string             `json:"schedulingError,omitempty"`
 }
 
+// WriteSession describes a write session found in the repository.
+type WriteSession struct {
+	content.SessionInfo
+
+	// Expired is true when the session has not been checkpointed for long enough that
+	// its blobs are subject to garbage collection, which usually means the client has crashed.
+	Expired bool `json:"expired"`
+}
+
+// WriteSessionsResponse contains the list of write sessions.
+type WriteSessionsResponse struct {
+	Sessions []*WriteSession `json:"sessions"`
+}
+
 // ResolvePathRequest contains request to resolve a particular path to ResolvePathResponse.
 type ResolvePathRequest struct {
 	Path string `json:"path"`
@@ -296,3 +324,19 @@ type UIPreferences struct {
 	PageSize               int    `json:"pageSize"`               // A page size; the actual possible values will only be provided by the frontend
 	Language               string `json:"language"`               // Specifies the language used by the UI
 }
+
+// StartProfilingRequest contains request to start capturing profiles.
+type StartProfilingRequest struct {
+	// Config has the same format as the value of KOPIA_PPROF_LOGGING_CONFIG environment variable.
+	Config string `json:"config"`
+}
+
+// ProfilingStatusResponse contains the names of profiles being captured.
+type ProfilingStatusResponse struct {
+	Active []string `json:"active"`
+}
+
+// ProfilesResponse contains captured profiles.
+type ProfilesResponse struct {
+	Profiles []pproflogging.Profile `json:"profiles"`
+}
@@ -2,6 +2,7 @@
 package sparsefile
 
 import (
+	"encoding/binary"
 	"io"
 
 	"github.com/pkg/errors"
@@ -10,30 +11,66 @@
 )
 
 // Copy copies a file sparsely (omitting holes) from src to dst, while recycling
-// shared buffers.
-func Copy(dst io.WriteSeeker, src io.Reader, bufSize uint64) (int64, error) {
+// shared buffers. Blocks of blockSize bytes which contain only zeros are not written,
+// instead the position in dst is advanced past them. Trailing holes do not extend dst,
+// so the caller is expected to set the size of dst, typically by truncating it beforehand.
+func Copy(dst io.WriteSeeker, src io.Reader, blockSize uint64) (int64, error) {
+	if blockSize == 0 {
+		return 0, errors.New("invalid block size")
+	}
+
+	bs := int(blockSize) //nolint:gosec
+
 	buf := iocopy.GetBuffer()
 	defer iocopy.ReleaseBuffer(buf)
 
-	return copyBuffer(dst, src, buf[0:bufSize])
+	// read as many whole blocks at a time as fit in the buffer.
+	bufSize := len(buf) / bs * bs
+	if bufSize == 0 {
+		// blocks are larger than shared buffers.
+		return copyBuffer(dst, src, make([]byte, bs), bs)
+	}
+
+	return copyBuffer(dst, src, buf[0:bufSize], bs)
 }
 
-// Copy copies bits from src to dst, seeking past blocks of zero bits in src. These
-// blocks are omitted, creating a file with holes in dst.
-func copyBuffer(dst io.WriteSeeker, src io.Reader, buf []byte) (written int64, err error) {
+// copyBuffer copies bits from src to dst, seeking past blocks of zero bits in src. These
+// blocks are omitted, creating a file with holes in dst. Reads always fill the buffer, so that
+// blocks remain aligned regardless of the sizes of reads returned by src.
+func copyBuffer(dst io.WriteSeeker, src io.Reader, buf []byte, blockSize int) (written int64, err error) {
+	// length of the hole to create by seeking before the next write.
+	var hole int64
+
 	for {
-		nr, er := src.Read(buf)
-		if nr > 0 { //nolint:nestif
-			// If non-zero data is read, write it. Otherwise, skip forwards.
-			if isAllZero(buf) {
-				dst.Seek(int64(nr), io.SeekCurrent) //nolint:errcheck
-				written += int64(nr)
+		nr, er := io.ReadFull(src, buf)
+		data := buf[0:nr]
+
+		for off := 0; off < nr; {
+			end := min(off+blockSize, nr)
+
+			if isAllZero(data[off:end]) {
+				hole += int64(end - off)
+				off = end
 
 				continue
 			}
 
-			nw, ew := dst.Write(buf[0:nr])
-			if nw < 0 || nr < nw {
+			// write all consecutive blocks containing data at once.
+			for end < nr {
+				next := min(end+blockSize, nr)
+				if isAllZero(data[end:next]) {
+					break
+				}
+
+				end = next
+			}
+
+			if err := skipHole(dst, &hole, &written); err != nil {
+				return written, err
+			}
+
+			nw, ew := dst.Write(data[off:end])
+			if nw < 0 || nw > end-off {
 				nw = 0
 
 				if ew == nil {
@@ -44,29 +81,55 @@ func copyBuffer(dst io.WriteSeeker, src io.Reader, buf []byte) (written int64, e
 			written += int64(nw)
 
 			if ew != nil {
-				err = ew
-				break
+				return written, ew //nolint:wrapcheck
 			}
 
-			if nr != nw {
-				err = io.ErrShortWrite
-				break
+			if nw != end-off {
+				return written, io.ErrShortWrite
 			}
+
+			off = end
 		}
 
 		if er != nil {
-			if er != io.EOF {
-				err = er
+			if er != io.EOF && !errors.Is(er, io.ErrUnexpectedEOF) {
+				return written, er //nolint:wrapcheck
 			}
 
 			break
 		}
 	}
 
-	return written, err
+	return written, skipHole(dst, &hole, &written)
+}
+
+// skipHole advances the position of dst past the pending hole.
+func skipHole(dst io.Seeker, hole, written *int64) error {
+	if *hole == 0 {
+		return nil
+	}
+
+	if _, err := dst.Seek(*hole, io.SeekCurrent); err != nil {
+		return errors.Wrap(err, "unable to skip hole")
+	}
+
+	*written += *hole
+	*hole = 0
+
+	return nil
 }
 
 func isAllZero(buf []byte) bool {
+	const wordSize = 8
+
+	for len(buf) >= wordSize {
+		if binary.LittleEndian.Uint64(buf) != 0 {
+			return false
+		}
+
+		buf = buf[wordSize:]
+	}
+
 	for _, b := range buf {
 		if b != 0 {
 			return false
@@ -2,13 +2,17 @@
 
 import (
 	"bytes"
+	"errors"
+	"io"
 	"os"
 	"path/filepath"
 	"runtime"
 	"testing"
+	"testing/iotest"
 
 	"github.com/stretchr/testify/require"
 
+	"github.com/kopia/kopia/internal/iocopy"
 	"github.com/kopia/kopia/internal/stat"
 )
 
@@ -110,3 +114,63 @@ type chunk struct {
 		}
 	}
 }
+
+type recordingWriteSeeker struct {
+	pos     int64
+	written int64
+	data    []byte
+}
+
+func (w *recordingWriteSeeker) Write(p []byte) (int, error) {
+	if end := int(w.pos) + len(p); end > len(w.data) {
+		w.data = append(w.data, make([]byte, end-len(w.data))...)
+	}
+
+	copy(w.data[w.pos:], p)
+	w.pos += int64(len(p))
+	w.written += int64(len(p))
+
+	return len(p), nil
+}
+
+func (w *recordingWriteSeeker) Seek(offset int64, whence int) (int64, error) {
+	if whence != io.SeekCurrent {
+		return 0, errors.New("unsupported whence")
+	}
+
+	w.pos += offset
+
+	return w.pos, nil
+}
+
+func TestSparseCopy_UnalignedReads(t *testing.T) {
+	t.Parallel()
+
+	for _, blk := range []int{4096, 3 * iocopy.BufSize} {
+		ones := bytes.Repeat([]byte{1}, blk)
+		zeros := make([]byte, blk)
+
+		var src []byte
+
+		src = append(src, ones...)
+		src = append(src, zeros...)
+		src = append(src, zeros...)
+		src = append(src, ones...)
+		src = append(src, ones...)
+		src = append(src, zeros...)
+		src = append(src, 1)
+		src = append(src, zeros...)
+
+		w := &recordingWriteSeeker{}
+
+		// short reads from the source must not affect detection of zero blocks.
+		n, err := Copy(w, iotest.HalfReader(bytes.NewReader(src)), uint64(blk))
+		require.NoError(t, err)
+		require.Equal(t, int64(len(src)), n)
+		require.Equal(t, int64(len(src)), w.pos)
+
+		// only blocks containing data were written.
+		require.Equal(t, int64(4*blk), w.written)
+		require.True(t, bytes.Equal(src[:len(w.data)], w.data))
+	}
+}
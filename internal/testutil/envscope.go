@@ -0,0 +1,72 @@
+package testutil
+
+import (
+	"os"
+	"strings"
+	"sync"
+	"testing"
+)
+
+// EnvUnset can be passed as a value to EnvScope to unset the environment variable for the duration of the test.
+const EnvUnset = "\x00unset\x00"
+
+//nolint:gochecknoglobals
+var (
+	envScopeMu   sync.Mutex
+	envScopeCond = sync.NewCond(&envScopeMu)
+
+	// +checklocks:envScopeMu
+	envScopeOwner testing.TB
+)
+
+// EnvScope sets the provided environment variables (or unsets them when the value is EnvUnset) and restores
+// their previous values when the test completes.
+//
+// Unlike t.Setenv(), EnvScope can be used in parallel tests. Tests using it are serialized: the scope is held
+// by the test until it completes and other tests calling EnvScope wait for it, while the same test and its
+// subtests may call EnvScope multiple times. Tests which read the environment without calling EnvScope are not serialized.
+func EnvScope(tb testing.TB, vars map[string]string) {
+	tb.Helper()
+
+	envScopeMu.Lock()
+	defer envScopeMu.Unlock()
+
+	for envScopeOwner != nil && !isSameOrSubtest(tb, envScopeOwner) {
+		envScopeCond.Wait()
+	}
+
+	if envScopeOwner == nil {
+		envScopeOwner = tb
+
+		// registered first, so that it runs after all restores of this test.
+		tb.Cleanup(func() {
+			envScopeMu.Lock()
+			defer envScopeMu.Unlock()
+
+			envScopeOwner = nil
+			envScopeCond.Broadcast()
+		})
+	}
+
+	for k, v := range vars {
+		old, existed := os.LookupEnv(k)
+
+		tb.Cleanup(func() {
+			if existed {
+				os.Setenv(k, old) //nolint:errcheck
+			} else {
+				os.Unsetenv(k) //nolint:errcheck
+			}
+		})
+
+		if v == EnvUnset {
+			os.Unsetenv(k) //nolint:errcheck
+		} else {
+			os.Setenv(k, v) //nolint:errcheck
+		}
+	}
+}
+
+func isSameOrSubtest(tb, owner testing.TB) bool {
+	return tb == owner || strings.HasPrefix(tb.Name(), owner.Name()+"/")
+}
@@ -123,6 +123,35 @@ func TempLogDirectory(t *testing.T) string {
 	return logsDir
 }
 
+// TempProfileDirectory returns a directory named after the test, used for storing profiles captured
+// while the test runs. Profiles are stored in KOPIA_PROFILES_DIR, if provided, and are preserved
+// when the test fails or KOPIA_KEEP_PROFILES is set.
+func TempProfileDirectory(t *testing.T) string {
+	t.Helper()
+
+	cleanName := strings.NewReplacer("/", "_", "\\", "_", ":", "_").Replace(t.Name())
+
+	profilesBaseDir := os.Getenv("KOPIA_PROFILES_DIR")
+	if profilesBaseDir == "" {
+		profilesBaseDir = filepath.Join(os.TempDir(), "kopia-profiles")
+	}
+
+	profilesDir := filepath.Join(profilesBaseDir, cleanName+"."+clock.Now().Local().Format("20060102150405"))
+
+	require.NoError(t, os.MkdirAll(profilesDir, logsDirPermissions))
+
+	t.Cleanup(func() {
+		if t.Failed() || os.Getenv("KOPIA_KEEP_PROFILES") != "" {
+			t.Logf("profiles preserved in %v", profilesDir)
+			return
+		}
+
+		os.RemoveAll(profilesDir) //nolint:errcheck
+	})
+
+	return profilesDir
+}
+
 func dumpLogs(t *testing.T, dirname string) {
 	t.Helper()
 
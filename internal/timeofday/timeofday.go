@@ -0,0 +1,74 @@
+// Package timeofday represents times of day and daily time windows.
+package timeofday
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+const minutesPerHour = 60
+
+// TimeOfDay represents the time of day (hh:mm) using 24-hour time format.
+type TimeOfDay struct {
+	Hour   int `json:"hour"`
+	Minute int `json:"min"`
+}
+
+// Parse parses the time of day.
+func (t *TimeOfDay) Parse(s string) error {
+	if _, err := fmt.Sscanf(s, "%d:%d", &t.Hour, &t.Minute); err != nil {
+		return errors.New("invalid time of day, must be HH:MM")
+	}
+
+	if t.Hour < 0 || t.Hour > 23 {
+		return errors.Errorf("invalid hour %q, must be between 0 and 23", s)
+	}
+
+	if t.Minute < 0 || t.Minute > 59 {
+		return errors.Errorf("invalid minute %q, must be between 0 and 59", s)
+	}
+
+	return nil
+}
+
+// String returns string representation of time of day.
+func (t TimeOfDay) String() string {
+	return fmt.Sprintf("%v:%02v", t.Hour, t.Minute)
+}
+
+// Validate returns an error if the time of day is out of range.
+func (t TimeOfDay) Validate() error {
+	if t.Hour < 0 || t.Hour > 23 || t.Minute < 0 || t.Minute > 59 {
+		return errors.Errorf("invalid time of day %v", t)
+	}
+
+	return nil
+}
+
+// Minutes returns the number of minutes since midnight.
+func (t TimeOfDay) Minutes() int {
+	return t.Hour*minutesPerHour + t.Minute
+}
+
+// Of returns the time of day of the provided time, in its location.
+func Of(t time.Time) TimeOfDay {
+	return TimeOfDay{t.Hour(), t.Minute()}
+}
+
+// InWindow returns true if the time of day of the provided time is within the daily window between start
+// (inclusive) and end (exclusive). Windows where the end is before the start span midnight and windows
+// where both are equal span the entire day.
+func InWindow(start, end TimeOfDay, t time.Time) bool {
+	m, s, e := Of(t).Minutes(), start.Minutes(), end.Minutes()
+
+	switch {
+	case s < e:
+		return s <= m && m < e
+	case s > e:
+		return m >= s || m < e
+	default:
+		return true
+	}
+}
@@ -0,0 +1,52 @@
+package timeofday_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/kopia/kopia/internal/timeofday"
+)
+
+func TestParse(t *testing.T) {
+	for input, want := range map[string]timeofday.TimeOfDay{
+		"0:00":  {Hour: 0, Minute: 0},
+		"09:05": {Hour: 9, Minute: 5},
+		"23:59": {Hour: 23, Minute: 59},
+	} {
+		var got timeofday.TimeOfDay
+
+		require.NoError(t, got.Parse(input), input)
+		require.Equal(t, want, got, input)
+	}
+
+	for _, input := range []string{"", "12", "24:00", "12:60", "-1:00", "aa:bb"} {
+		var got timeofday.TimeOfDay
+
+		require.Error(t, got.Parse(input), input)
+	}
+
+	require.Equal(t, "9:05", timeofday.TimeOfDay{Hour: 9, Minute: 5}.String())
+}
+
+func TestInWindow(t *testing.T) {
+	at := func(h, m int) time.Time {
+		return time.Date(2020, 1, 1, h, m, 0, 0, time.UTC)
+	}
+
+	nine, five := timeofday.TimeOfDay{Hour: 9}, timeofday.TimeOfDay{Hour: 17}
+
+	require.True(t, timeofday.InWindow(nine, five, at(9, 0)))
+	require.True(t, timeofday.InWindow(nine, five, at(16, 59)))
+	require.False(t, timeofday.InWindow(nine, five, at(17, 0)))
+	require.False(t, timeofday.InWindow(nine, five, at(8, 59)))
+
+	// windows where the end is before the start span midnight.
+	require.True(t, timeofday.InWindow(five, nine, at(23, 0)))
+	require.True(t, timeofday.InWindow(five, nine, at(3, 0)))
+	require.False(t, timeofday.InWindow(five, nine, at(12, 0)))
+
+	// windows where both are equal span the entire day.
+	require.True(t, timeofday.InWindow(nine, nine, at(3, 0)))
+}
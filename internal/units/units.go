@@ -6,6 +6,8 @@
 	"os"
 	"strconv"
 	"strings"
+
+	"github.com/pkg/errors"
 )
 
 //nolint:gochecknoglobals
@@ -66,3 +68,60 @@ func Count(v int64) string {
 	//nolint:mnd
 	return toDecimalUnitString(float64(v), 1000, base10UnitPrefixes, "")
 }
+
+// ParseBytes parses the human-readable size such as "100", "1.5 KB", "10MB" or "2GiB" and returns the
+// number of bytes. Base-10 suffixes (KB, MB, ...) are multiples of 1000 and base-2 suffixes (KiB, MiB, ...)
+// are multiples of 1024. The trailing "B" is optional.
+func ParseBytes(s string) (int64, error) {
+	str := strings.TrimSpace(s)
+
+	numEnd := strings.IndexFunc(str, func(r rune) bool {
+		return (r < '0' || r > '9') && r != '.'
+	})
+	if numEnd < 0 {
+		numEnd = len(str)
+	}
+
+	v, err := strconv.ParseFloat(str[0:numEnd], 64)
+	if err != nil {
+		return 0, errors.Errorf("invalid number in %q", s)
+	}
+
+	suffix := strings.TrimSuffix(strings.ToUpper(strings.TrimSpace(str[numEnd:])), "B")
+	multiplier := 1.0
+
+	if suffix != "" {
+		var ok bool
+
+		multiplier, ok = unitMultiplier(suffix)
+		if !ok {
+			return 0, errors.Errorf("invalid unit in %q", s)
+		}
+	}
+
+	return int64(v * multiplier), nil
+}
+
+func unitMultiplier(prefix string) (float64, bool) {
+	m := 1.0
+
+	for i := 1; i < len(base10UnitPrefixes); i++ {
+		m *= 1000 //nolint:mnd
+
+		if prefix == base10UnitPrefixes[i] {
+			return m, true
+		}
+	}
+
+	m = 1.0
+
+	for i := 1; i < len(base2UnitPrefixes); i++ {
+		m *= 1024 //nolint:mnd
+
+		if prefix == strings.ToUpper(base2UnitPrefixes[i]) {
+			return m, true
+		}
+	}
+
+	return 0, false
+}
@@ -94,3 +94,39 @@ func TestBytesString(t *testing.T) {
 		}
 	}
 }
+
+func TestParseBytes(t *testing.T) {
+	cases := []struct {
+		input    string
+		expected int64
+	}{
+		{"0", 0},
+		{"123", 123},
+		{"100B", 100},
+		{"1.5 KB", 1500},
+		{"10MB", 10000000},
+		{"10mb", 10000000},
+		{"2 GB", 2000000000},
+		{"1T", 1000000000000},
+		{"1KiB", 1024},
+		{"2MiB", 2 << 20},
+		{"1.5gib", 3 << 29},
+	}
+
+	for _, c := range cases {
+		got, err := ParseBytes(c.input)
+		if err != nil {
+			t.Errorf("unexpected error parsing %q: %v", c.input, err)
+		}
+
+		if got != c.expected {
+			t.Errorf("invalid result of ParseBytes(%q): %v, wanted %v", c.input, got, c.expected)
+		}
+	}
+
+	for _, invalid := range []string{"", "MB", "10XB", "1..2KB", "-5MB", "10 MB/s"} {
+		if _, err := ParseBytes(invalid); err == nil {
+			t.Errorf("expected error parsing %q", invalid)
+		}
+	}
+}
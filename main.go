@@ -74,5 +74,9 @@ func main() {
 	kp.UsageTemplate(usageTemplate)
 
 	app.Attach(kp)
-	kingpin.MustParse(kp.Parse(os.Args[1:]))
+
+	if _, err := kp.Parse(os.Args[1:]); err != nil {
+		kp.Errorf("%s, try --help", err)
+		os.Exit(cli.ExitCodeInvalidArgs)
+	}
 }
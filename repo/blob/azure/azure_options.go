@@ -3,6 +3,8 @@
 import (
 	"time"
 
+	"github.com/kopia/kopia/repo/blob"
+	"github.com/kopia/kopia/repo/blob/retrypolicy"
 	"github.com/kopia/kopia/repo/blob/throttling"
 )
 
@@ -30,7 +32,11 @@ type Options struct {
 
 	StorageDomain string `json:"storageDomain,omitempty"`
 
+	// StorageClasses specifies access tiers of blobs by prefix (e.g. Hot, Cool, Cold, Archive).
+	StorageClasses []blob.PrefixStorageClass `json:"storageClasses,omitempty"`
+
 	throttling.Limits
+	retrypolicy.Policy
 
 	// PointInTime specifies a view of the (versioned) store at that time
 	PointInTime *time.Time `json:"pointInTime,omitempty"`
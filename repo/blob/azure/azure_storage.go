@@ -23,6 +23,7 @@
 	"github.com/kopia/kopia/internal/timestampmeta"
 	"github.com/kopia/kopia/repo/blob"
 	"github.com/kopia/kopia/repo/blob/retrying"
+	"github.com/kopia/kopia/repo/blob/throttling"
 	"github.com/kopia/kopia/repo/logging"
 )
 
@@ -104,6 +105,10 @@ func (az *azStorage) GetMetadata(ctx context.Context, b blob.ID) (blob.Metadata,
 		Timestamp: *fi.LastModified,
 	}
 
+	if fi.ETag != nil {
+		bm.Version = string(*fi.ETag)
+	}
+
 	if fi.Metadata[timeMapKey] != nil {
 		if t, ok := timestampmeta.FromValue(*fi.Metadata[timeMapKey]); ok {
 			bm.Timestamp = t
@@ -127,6 +132,15 @@ func translateError(err error) error {
 		case string(bloberror.InvalidRange):
 			return blob.ErrInvalidRange
 		}
+
+		if throttling.IsThrottlingHTTPStatus(re.StatusCode) {
+			te := &throttling.ThrottledError{Err: err}
+			if re.RawResponse != nil {
+				te.RetryAfter = throttling.ParseRetryAfter(re.RawResponse.Header.Get("Retry-After"), clock.Now())
+			}
+
+			return te
+		}
 	}
 
 	return err
@@ -141,6 +155,7 @@ func (az *azStorage) PutBlob(ctx context.Context, b blob.ID, data blob.Bytes, op
 		RetentionPeriod: opts.RetentionPeriod,
 		SetModTime:      opts.SetModTime,
 		GetModTime:      opts.GetModTime,
+		StorageClass:    blob.StorageClassForBlob(az.StorageClasses, b, opts),
 	}
 
 	if opts.HasRetentionOptions() {
@@ -279,6 +294,10 @@ func (az *azStorage) putBlob(ctx context.Context, b blob.ID, data blob.Bytes, op
 		Metadata: metadata,
 	}
 
+	if opts.StorageClass != "" {
+		uo.Tier = to.Ptr(azblobblob.AccessTier(opts.StorageClass))
+	}
+
 	if opts.HasRetentionOptions() {
 		// kopia delete marker blob must be "Unlocked", thus it cannot be overridden to "Locked" here.
 		mode := azblobblob.ImmutabilityPolicySetting(opts.RetentionMode)
@@ -422,7 +441,7 @@ func New(ctx context.Context, opt *Options, isCreate bool) (blob.Storage, error)
 		return nil, err
 	}
 
-	az := retrying.NewWrapper(st)
+	az := retrying.NewWrapperWithPolicy(st, opt.Policy, nil)
 
 	// verify Azure connection is functional by listing blobs in a bucket, which will fail if the container
 	// does not exist. We list with a prefix that will not exist, to avoid iterating through any objects.
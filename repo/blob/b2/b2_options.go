@@ -1,6 +1,9 @@
 package b2
 
-import "github.com/kopia/kopia/repo/blob/throttling"
+import (
+	"github.com/kopia/kopia/repo/blob/retrypolicy"
+	"github.com/kopia/kopia/repo/blob/throttling"
+)
 
 // Options defines options for B2-based storage.
 type Options struct {
@@ -14,4 +17,5 @@ type Options struct {
 	Key   string `json:"key"   kopia:"sensitive"`
 
 	throttling.Limits
+	retrypolicy.Policy
 }
@@ -104,6 +104,7 @@ func (s *b2Storage) GetMetadata(ctx context.Context, id blob.ID) (blob.Metadata,
 		BlobID:    id,
 		Length:    fi.ContentLength,
 		Timestamp: time.Unix(0, fi.UploadTimestamp*1e6),
+		Version:   fileID, // each upload of a file gets a new ID
 	}
 
 	if t, ok := timestampmeta.FromValue(fi.FileInfo[timeMapKey]); ok {
@@ -268,11 +269,11 @@ func New(ctx context.Context, opt *Options, isCreate bool) (blob.Storage, error)
 		return nil, errors.Errorf("bucket not found: %s", opt.BucketName)
 	}
 
-	return retrying.NewWrapper(&b2Storage{
+	return retrying.NewWrapperWithPolicy(&b2Storage{
 		Options: *opt,
 		cli:     cli,
 		bucket:  bucket,
-	}), nil
+	}, opt.Policy, nil), nil
 }
 
 func init() {
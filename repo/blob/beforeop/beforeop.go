@@ -1,12 +1,27 @@
-// Package beforeop implements wrapper around blob.Storage that run a given callback before all operations.
+// Package beforeop implements wrapper around blob.Storage that run a given callback before all operations,
+// and optionally after they complete.
 package beforeop
 
 import (
 	"context"
+	"time"
 
+	"github.com/kopia/kopia/internal/timetrack"
 	"github.com/kopia/kopia/repo/blob"
 )
 
+// Names of operations passed to AfterOpCallback.
+const (
+	OpGetBlob     = "GetBlob"
+	OpGetMetadata = "GetMetadata"
+	OpPutBlob     = "PutBlob"
+	OpDeleteBlob  = "DeleteBlob"
+)
+
+// AfterOpCallback is invoked after an operation on a blob completes, with the name of the operation,
+// ID of the blob, duration of the operation and the error it returned, if any.
+type AfterOpCallback func(ctx context.Context, op string, id blob.ID, dt time.Duration, err error)
+
 type (
 	callback          func(ctx context.Context) error
 	onGetBlobCallback func(ctx context.Context, id blob.ID) error
@@ -18,6 +33,23 @@ type beforeOp struct {
 	onGetMetadata, onDeleteBlob callback
 	onGetBlob                   onGetBlobCallback
 	onPutBlob                   onPutBlobCallback
+	afterOp                     AfterOpCallback
+}
+
+// startOp returns a function which invokes the after-operation callback, if any, with the duration
+// since startOp() was called.
+func (s beforeOp) startOp(ctx context.Context, op string, id blob.ID) func(err error) error {
+	if s.afterOp == nil {
+		return func(err error) error { return err }
+	}
+
+	timer := timetrack.StartTimer()
+
+	return func(err error) error {
+		s.afterOp(ctx, op, id, timer.Elapsed(), err)
+
+		return err
+	}
 }
 
 func (s beforeOp) GetBlob(ctx context.Context, id blob.ID, offset, length int64, output blob.OutputBuffer) error {
@@ -27,7 +59,9 @@ func (s beforeOp) GetBlob(ctx context.Context, id blob.ID, offset, length int64,
 		}
 	}
 
-	return s.Storage.GetBlob(ctx, id, offset, length, output) //nolint:wrapcheck
+	done := s.startOp(ctx, OpGetBlob, id)
+
+	return done(s.Storage.GetBlob(ctx, id, offset, length, output))
 }
 
 func (s beforeOp) GetMetadata(ctx context.Context, id blob.ID) (blob.Metadata, error) {
@@ -37,7 +71,11 @@ func (s beforeOp) GetMetadata(ctx context.Context, id blob.ID) (blob.Metadata, e
 		}
 	}
 
-	return s.Storage.GetMetadata(ctx, id) //nolint:wrapcheck
+	done := s.startOp(ctx, OpGetMetadata, id)
+
+	m, err := s.Storage.GetMetadata(ctx, id)
+
+	return m, done(err)
 }
 
 func (s beforeOp) PutBlob(ctx context.Context, id blob.ID, data blob.Bytes, opts blob.PutOptions) error {
@@ -47,7 +85,9 @@ func (s beforeOp) PutBlob(ctx context.Context, id blob.ID, data blob.Bytes, opts
 		}
 	}
 
-	return s.Storage.PutBlob(ctx, id, data, opts) //nolint:wrapcheck
+	done := s.startOp(ctx, OpPutBlob, id)
+
+	return done(s.Storage.PutBlob(ctx, id, data, opts))
 }
 
 func (s beforeOp) DeleteBlob(ctx context.Context, id blob.ID) error {
@@ -57,7 +97,9 @@ func (s beforeOp) DeleteBlob(ctx context.Context, id blob.ID) error {
 		}
 	}
 
-	return s.Storage.DeleteBlob(ctx, id) //nolint:wrapcheck
+	done := s.startOp(ctx, OpDeleteBlob, id)
+
+	return done(s.Storage.DeleteBlob(ctx, id))
 }
 
 // NewWrapper creates a wrapped storage interface for data operations that need
@@ -83,3 +125,12 @@ func NewUniformWrapper(wrapped blob.Storage, cb callback) blob.Storage {
 		onPutBlob:     func(ctx context.Context, _ blob.ID, _ *blob.PutOptions) error { return cb(ctx) },
 	}
 }
+
+// NewAfterOpWrapper creates a wrapped storage interface for data operations that need to run
+// a callback after the operation completes, such as auditing or invalidating caches.
+func NewAfterOpWrapper(wrapped blob.Storage, afterOp AfterOpCallback) blob.Storage {
+	return &beforeOp{
+		Storage: wrapped,
+		afterOp: afterOp,
+	}
+}
@@ -3,6 +3,7 @@
 import (
 	"context"
 	"testing"
+	"time"
 
 	"github.com/pkg/errors"
 	"github.com/stretchr/testify/require"
@@ -83,3 +84,45 @@ func(ctx context.Context, id blob.ID, opts *blob.PutOptions) error {
 	_, _ = r.GetMetadata(testlogging.Context(t), "id")
 	require.True(t, getBlobMetadataCbInvoked)
 }
+
+func TestAfterOpStorage(t *testing.T) {
+	ctx := testlogging.Context(t)
+
+	type afterOpCall struct {
+		op  string
+		id  blob.ID
+		err error
+	}
+
+	var calls []afterOpCall
+
+	r := NewAfterOpWrapper(blobtesting.NewMapStorage(blobtesting.DataMap{}, nil, clock.Now),
+		func(ctx context.Context, op string, id blob.ID, dt time.Duration, err error) {
+			require.GreaterOrEqual(t, dt, time.Duration(0))
+
+			calls = append(calls, afterOpCall{op, id, err})
+		},
+	)
+
+	var data gather.WriteBuffer
+	defer data.Close()
+
+	require.NoError(t, r.PutBlob(ctx, "id", gather.FromSlice([]byte{1, 2, 3}), blob.PutOptions{}))
+	require.NoError(t, r.GetBlob(ctx, "id", 0, -1, &data))
+
+	_, err := r.GetMetadata(ctx, "id")
+	require.NoError(t, err)
+
+	require.NoError(t, r.DeleteBlob(ctx, "id"))
+	require.ErrorIs(t, r.GetBlob(ctx, "id", 0, -1, &data), blob.ErrBlobNotFound)
+
+	require.Len(t, calls, 5)
+	require.Equal(t, []afterOpCall{
+		{OpPutBlob, "id", nil},
+		{OpGetBlob, "id", nil},
+		{OpGetMetadata, "id", nil},
+		{OpDeleteBlob, "id", nil},
+		{OpGetBlob, "id", calls[4].err},
+	}, calls)
+	require.ErrorIs(t, calls[4].err, blob.ErrBlobNotFound)
+}
@@ -0,0 +1,176 @@
+// Package checksum implements wrapper around blob.Storage that records SHA-256 digest of each blob
+// when it's written and verifies it when the blob is read, to detect silent corruption introduced
+// by the storage or anything between it and kopia.
+//
+// Digests are stored in sidecar blobs, whose IDs consist of the sidecar prefix followed by the ID of
+// the blob. Sidecar blobs are hidden from listings of other prefixes and deleted along with the blob.
+// Only reads of entire blobs are verified, and blobs without sidecar (such as those written without
+// the wrapper) are returned without verification.
+package checksum
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"strings"
+
+	"github.com/pkg/errors"
+
+	"github.com/kopia/kopia/internal/gather"
+	"github.com/kopia/kopia/repo/blob"
+	"github.com/kopia/kopia/repo/logging"
+)
+
+var log = logging.Module("checksum")
+
+// DefaultSidecarPrefix is the default prefix of blobs holding digests of other blobs.
+const DefaultSidecarPrefix blob.ID = "_checksum_"
+
+// ErrChecksumMismatch is returned when the contents of a blob don't match the digest recorded when it was written.
+var ErrChecksumMismatch = errors.New("blob checksum mismatch")
+
+// Options controls the behavior of the checksum wrapper.
+type Options struct {
+	// SidecarPrefix is the prefix of blobs holding digests of other blobs, defaults to DefaultSidecarPrefix.
+	SidecarPrefix blob.ID
+}
+
+type checksumStorage struct {
+	blob.Storage
+
+	sidecarPrefix blob.ID
+}
+
+// GetBlob implements blob.Storage and verifies the digest of the returned bytes when reading entire blobs.
+// Partial reads are passed through without verification, since recorded digests cover entire blobs.
+func (s *checksumStorage) GetBlob(ctx context.Context, id blob.ID, offset, length int64, output blob.OutputBuffer) error {
+	if length >= 0 || s.isSidecar(id) {
+		//nolint:wrapcheck
+		return s.Storage.GetBlob(ctx, id, offset, length, output)
+	}
+
+	var buf gather.WriteBuffer
+	defer buf.Close()
+
+	if err := s.Storage.GetBlob(ctx, id, offset, length, &buf); err != nil {
+		//nolint:wrapcheck
+		return err
+	}
+
+	want, err := s.readDigest(ctx, id)
+	if err != nil {
+		return err
+	}
+
+	if got := digestOf(buf.Bytes()); want != nil && !bytes.Equal(got, want) {
+		log(ctx).Errorw("blob checksum mismatch", "blobID", id, "want", hex.EncodeToString(want), "got", hex.EncodeToString(got))
+
+		return errors.Wrapf(ErrChecksumMismatch, "blob %v", id)
+	}
+
+	output.Reset()
+
+	_, err = buf.Bytes().WriteTo(output)
+
+	return errors.Wrap(err, "error writing blob data")
+}
+
+func (s *checksumStorage) PutBlob(ctx context.Context, id blob.ID, data blob.Bytes, opts blob.PutOptions) error {
+	if s.isSidecar(id) {
+		return errors.Errorf("blob %v uses the reserved checksum prefix", id)
+	}
+
+	if err := s.Storage.PutBlob(ctx, id, data, opts); err != nil {
+		//nolint:wrapcheck
+		return err
+	}
+
+	// sidecar is written without retention or conditions, since it's replaced along with the blob.
+	digest := hex.EncodeToString(digestOf(data))
+
+	return errors.Wrapf(s.Storage.PutBlob(ctx, s.sidecarID(id), gather.FromSlice([]byte(digest)), blob.PutOptions{}), "unable to write checksum of %v", id)
+}
+
+func (s *checksumStorage) DeleteBlob(ctx context.Context, id blob.ID) error {
+	if err := s.Storage.DeleteBlob(ctx, id); err != nil {
+		//nolint:wrapcheck
+		return err
+	}
+
+	if s.isSidecar(id) {
+		return nil
+	}
+
+	if err := s.Storage.DeleteBlob(ctx, s.sidecarID(id)); err != nil && !errors.Is(err, blob.ErrBlobNotFound) {
+		return errors.Wrapf(err, "unable to delete checksum of %v", id)
+	}
+
+	return nil
+}
+
+func (s *checksumStorage) ListBlobs(ctx context.Context, prefix blob.ID, callback func(blob.Metadata) error) error {
+	if s.isSidecar(prefix) {
+		//nolint:wrapcheck
+		return s.Storage.ListBlobs(ctx, prefix, callback)
+	}
+
+	//nolint:wrapcheck
+	return s.Storage.ListBlobs(ctx, prefix, func(bm blob.Metadata) error {
+		if s.isSidecar(bm.BlobID) {
+			return nil
+		}
+
+		return callback(bm)
+	})
+}
+
+// readDigest returns the digest recorded for the blob or nil if there's none.
+func (s *checksumStorage) readDigest(ctx context.Context, id blob.ID) ([]byte, error) {
+	var buf gather.WriteBuffer
+	defer buf.Close()
+
+	err := s.Storage.GetBlob(ctx, s.sidecarID(id), 0, -1, &buf)
+	if errors.Is(err, blob.ErrBlobNotFound) {
+		return nil, nil
+	}
+
+	if err != nil {
+		return nil, errors.Wrapf(err, "unable to read checksum of %v", id)
+	}
+
+	digest, err := hex.DecodeString(string(buf.ToByteSlice()))
+	if err != nil {
+		return nil, errors.Wrapf(ErrChecksumMismatch, "invalid checksum of %v", id)
+	}
+
+	return digest, nil
+}
+
+func (s *checksumStorage) sidecarID(id blob.ID) blob.ID {
+	return s.sidecarPrefix + id
+}
+
+func (s *checksumStorage) isSidecar(id blob.ID) bool {
+	return strings.HasPrefix(string(id), string(s.sidecarPrefix))
+}
+
+func digestOf(data blob.Bytes) []byte {
+	h := sha256.New()
+
+	data.WriteTo(h) //nolint:errcheck
+
+	return h.Sum(nil)
+}
+
+// NewWrapper returns a Storage wrapper that records digests of blobs when they are written and verifies them when they are read.
+func NewWrapper(wrapped blob.Storage, opts Options) blob.Storage {
+	if opts.SidecarPrefix == "" {
+		opts.SidecarPrefix = DefaultSidecarPrefix
+	}
+
+	return &checksumStorage{
+		Storage:       wrapped,
+		sidecarPrefix: opts.SidecarPrefix,
+	}
+}
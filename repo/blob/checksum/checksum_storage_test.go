@@ -0,0 +1,85 @@
+package checksum_test
+
+import (
+	"testing"
+
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/require"
+
+	"github.com/kopia/kopia/internal/blobtesting"
+	"github.com/kopia/kopia/internal/gather"
+	"github.com/kopia/kopia/internal/testlogging"
+	"github.com/kopia/kopia/repo/blob"
+	"github.com/kopia/kopia/repo/blob/checksum"
+)
+
+func TestChecksumStorage(t *testing.T) {
+	ctx := testlogging.Context(t)
+
+	data := blobtesting.DataMap{}
+	st := checksum.NewWrapper(blobtesting.NewMapStorage(data, nil, nil), checksum.Options{})
+
+	blobtesting.VerifyStorage(ctx, t, st, blob.PutOptions{})
+}
+
+func TestChecksumStorageDetectsCorruption(t *testing.T) {
+	ctx := testlogging.Context(t)
+
+	data := blobtesting.DataMap{}
+	st := checksum.NewWrapper(blobtesting.NewMapStorage(data, nil, nil), checksum.Options{})
+
+	require.NoError(t, st.PutBlob(ctx, "blob1", gather.FromSlice([]byte("hello world")), blob.PutOptions{}))
+	require.Contains(t, data, checksum.DefaultSidecarPrefix+"blob1")
+
+	var tmp gather.WriteBuffer
+	defer tmp.Close()
+
+	require.NoError(t, st.GetBlob(ctx, "blob1", 0, -1, &tmp))
+	require.Equal(t, []byte("hello world"), tmp.ToByteSlice())
+
+	// sidecar blobs are not visible in listings.
+	all, err := blob.ListAllBlobs(ctx, st, "")
+	require.NoError(t, err)
+	require.Len(t, all, 1)
+	require.Equal(t, blob.ID("blob1"), all[0].BlobID)
+
+	// flip one byte in the underlying storage.
+	data["blob1"][3] ^= 1
+
+	err = st.GetBlob(ctx, "blob1", 0, -1, &tmp)
+	require.ErrorIs(t, err, checksum.ErrChecksumMismatch)
+
+	// the returned bytes are verified, even if the corruption is transient.
+	data["blob1"][3] ^= 1
+
+	fs := blobtesting.NewFaultyStorage(blobtesting.NewMapStorage(data, nil, nil))
+	fst := checksum.NewWrapper(fs, checksum.Options{})
+
+	fs.AddFault(blobtesting.MethodGetBlob).Before(func() { data["blob1"][3] ^= 1 })
+	fs.AddFault(blobtesting.MethodGetBlob).Before(func() { data["blob1"][3] ^= 1 })
+
+	require.ErrorIs(t, fst.GetBlob(ctx, "blob1", 0, -1, &tmp), checksum.ErrChecksumMismatch)
+	fs.VerifyAllFaultsExercised(t)
+
+	require.NoError(t, fst.GetBlob(ctx, "blob1", 0, -1, &tmp))
+	require.Equal(t, []byte("hello world"), tmp.ToByteSlice())
+
+	data["blob1"][3] ^= 1
+
+	// partial reads are not verified.
+	require.NoError(t, st.GetBlob(ctx, "blob1", 0, 3, &tmp))
+
+	// blobs without recorded checksum are not verified.
+	data["blob2"] = []byte("other")
+	require.NoError(t, st.GetBlob(ctx, "blob2", 0, -1, &tmp))
+
+	// sidecar is deleted together with the blob.
+	require.NoError(t, st.DeleteBlob(ctx, "blob1"))
+	require.NotContains(t, data, checksum.DefaultSidecarPrefix+"blob1")
+	require.NoError(t, st.DeleteBlob(ctx, "blob2"))
+
+	// writing blobs in the reserved namespace is rejected.
+	err = st.PutBlob(ctx, checksum.DefaultSidecarPrefix+"x", gather.FromSlice([]byte{1}), blob.PutOptions{})
+	require.Error(t, err)
+	require.False(t, errors.Is(err, checksum.ErrChecksumMismatch))
+}
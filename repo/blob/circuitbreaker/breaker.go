@@ -0,0 +1,169 @@
+package circuitbreaker
+
+import (
+	"sync"
+	"time"
+)
+
+// state of a circuit breaker.
+type state int
+
+const (
+	// stateClosed passes all calls through and tracks their outcome.
+	stateClosed state = iota
+
+	// stateOpen fails all calls until the cool-down window elapses.
+	stateOpen
+
+	// stateHalfOpen passes a single trial call through, its outcome decides whether the breaker closes or opens again.
+	stateHalfOpen
+)
+
+func (s state) String() string {
+	switch s {
+	case stateClosed:
+		return "closed"
+	case stateOpen:
+		return "open"
+	case stateHalfOpen:
+		return "half-open"
+	default:
+		return "unknown"
+	}
+}
+
+// breaker tracks outcomes of recent calls of a single storage method.
+type breaker struct {
+	opts Options
+
+	mu sync.Mutex
+	// +checklocks:mu
+	state state
+	// +checklocks:mu
+	openedAt time.Time
+	// +checklocks:mu
+	trialInProgress bool
+
+	// ring buffer of outcomes of most recent calls.
+	// +checklocks:mu
+	outcomes []bool
+	// +checklocks:mu
+	next int
+	// +checklocks:mu
+	numCalls int
+	// +checklocks:mu
+	numFailures int
+}
+
+// stateChange describes a transition between circuit breaker states.
+type stateChange struct {
+	from, to    state
+	numCalls    int
+	numFailures int
+}
+
+// allow determines whether the call can proceed and returns the resulting state change, if any.
+func (b *breaker) allow(now time.Time) (ok bool, change *stateChange) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	switch b.state {
+	case stateOpen:
+		if now.Sub(b.openedAt) < b.opts.CoolDown {
+			return false, nil
+		}
+
+		b.state = stateHalfOpen
+		b.trialInProgress = true
+
+		return true, &stateChange{from: stateOpen, to: stateHalfOpen}
+
+	case stateHalfOpen:
+		if b.trialInProgress {
+			return false, nil
+		}
+
+		b.trialInProgress = true
+
+		return true, nil
+
+	default:
+		return true, nil
+	}
+}
+
+// record records the outcome of the call allowed by allow() and returns the resulting state change, if any.
+func (b *breaker) record(now time.Time, failed bool) *stateChange {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	switch b.state {
+	case stateHalfOpen:
+		b.trialInProgress = false
+
+		if failed {
+			b.state = stateOpen
+			b.openedAt = now
+
+			return &stateChange{from: stateHalfOpen, to: stateOpen, numCalls: 1, numFailures: 1}
+		}
+
+		b.state = stateClosed
+		b.resetOutcomes()
+
+		return &stateChange{from: stateHalfOpen, to: stateClosed, numCalls: 1}
+
+	case stateOpen:
+		// outcome of a call started before the breaker opened.
+		return nil
+
+	default:
+		b.addOutcome(failed)
+
+		if b.numCalls < b.opts.MinCalls || float64(b.numFailures) < b.opts.ErrorRateThreshold*float64(b.numCalls) {
+			return nil
+		}
+
+		change := &stateChange{from: stateClosed, to: stateOpen, numCalls: b.numCalls, numFailures: b.numFailures}
+
+		b.state = stateOpen
+		b.openedAt = now
+		b.resetOutcomes()
+
+		return change
+	}
+}
+
+// +checklocks:b.mu
+func (b *breaker) addOutcome(failed bool) {
+	if b.numCalls == len(b.outcomes) {
+		if b.outcomes[b.next] {
+			b.numFailures--
+		}
+	} else {
+		b.numCalls++
+	}
+
+	b.outcomes[b.next] = failed
+	if failed {
+		b.numFailures++
+	}
+
+	b.next = (b.next + 1) % len(b.outcomes)
+}
+
+// +checklocks:b.mu
+func (b *breaker) resetOutcomes() {
+	clear(b.outcomes)
+
+	b.next = 0
+	b.numCalls = 0
+	b.numFailures = 0
+}
+
+func newBreaker(opts Options) *breaker {
+	return &breaker{
+		opts:     opts,
+		outcomes: make([]bool, opts.WindowSize),
+	}
+}
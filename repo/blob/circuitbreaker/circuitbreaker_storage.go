@@ -0,0 +1,267 @@
+// Package circuitbreaker implements wrapper around blob.Storage that fails fast when the underlying
+// storage keeps failing, to avoid retry storms against storage that is not available.
+//
+// Outcomes of most recent calls are tracked separately for each method. When the rate of failed calls
+// of a method reaches the threshold, the circuit breaker for the method opens and calls fail immediately
+// with ErrCircuitOpen until the cool-down window elapses. After that a single trial call is let through,
+// which either closes the circuit breaker when it succeeds or opens it again when it fails.
+package circuitbreaker
+
+import (
+	"context"
+	"time"
+
+	"github.com/pkg/errors"
+
+	"github.com/kopia/kopia/internal/clock"
+	"github.com/kopia/kopia/internal/metrics"
+	"github.com/kopia/kopia/repo/blob"
+	"github.com/kopia/kopia/repo/logging"
+)
+
+var log = logging.Module("circuitbreaker")
+
+// ErrCircuitOpen is returned when the call was not attempted because the underlying storage keeps failing.
+var ErrCircuitOpen = errors.New("storage circuit breaker is open")
+
+// Default values of Options.
+const (
+	DefaultWindowSize         = 20
+	DefaultMinCalls           = 10
+	DefaultErrorRateThreshold = 0.5
+	DefaultCoolDown           = 30 * time.Second
+)
+
+// methods protected by the circuit breaker.
+const (
+	methodGetBlob             = "GetBlob"
+	methodGetMetadata         = "GetMetadata"
+	methodPutBlob             = "PutBlob"
+	methodDeleteBlob          = "DeleteBlob"
+	methodListBlobs           = "ListBlobs"
+	methodExtendBlobRetention = "ExtendBlobRetention"
+)
+
+//nolint:gochecknoglobals
+var allMethods = []string{
+	methodGetBlob,
+	methodGetMetadata,
+	methodPutBlob,
+	methodDeleteBlob,
+	methodListBlobs,
+	methodExtendBlobRetention,
+}
+
+// Options controls the behavior of the circuit breaker.
+type Options struct {
+	// WindowSize is the number of most recent calls of each method used to compute the error rate.
+	WindowSize int
+
+	// MinCalls is the minimum number of calls in the window before the circuit breaker can open.
+	MinCalls int
+
+	// ErrorRateThreshold is the fraction of failed calls in the window which opens the circuit breaker.
+	ErrorRateThreshold float64
+
+	// CoolDown is the duration during which calls fail immediately after the circuit breaker opens.
+	CoolDown time.Duration
+
+	// TimeNow provides the current time, defaults to clock.Now.
+	TimeNow func() time.Time
+}
+
+func (o Options) withDefaults() Options {
+	if o.WindowSize <= 0 {
+		o.WindowSize = DefaultWindowSize
+	}
+
+	if o.MinCalls <= 0 {
+		o.MinCalls = DefaultMinCalls
+	}
+
+	o.MinCalls = min(o.MinCalls, o.WindowSize)
+
+	if o.ErrorRateThreshold <= 0 || o.ErrorRateThreshold > 1 {
+		o.ErrorRateThreshold = DefaultErrorRateThreshold
+	}
+
+	if o.CoolDown <= 0 {
+		o.CoolDown = DefaultCoolDown
+	}
+
+	if o.TimeNow == nil {
+		o.TimeNow = clock.Now
+	}
+
+	return o
+}
+
+type methodState struct {
+	breaker *breaker
+
+	opened   *metrics.Counter
+	closed   *metrics.Counter
+	rejected *metrics.Counter
+}
+
+type circuitBreakerStorage struct {
+	blob.Storage
+
+	now     func() time.Time
+	methods map[string]*methodState
+}
+
+func (s *circuitBreakerStorage) GetBlob(ctx context.Context, id blob.ID, offset, length int64, output blob.OutputBuffer) error {
+	return s.call(ctx, methodGetBlob, func() error {
+		//nolint:wrapcheck
+		return s.Storage.GetBlob(ctx, id, offset, length, output)
+	})
+}
+
+func (s *circuitBreakerStorage) GetMetadata(ctx context.Context, id blob.ID) (blob.Metadata, error) {
+	var bm blob.Metadata
+
+	err := s.call(ctx, methodGetMetadata, func() error {
+		var err error
+
+		bm, err = s.Storage.GetMetadata(ctx, id)
+
+		//nolint:wrapcheck
+		return err
+	})
+
+	return bm, err
+}
+
+func (s *circuitBreakerStorage) PutBlob(ctx context.Context, id blob.ID, data blob.Bytes, opts blob.PutOptions) error {
+	return s.call(ctx, methodPutBlob, func() error {
+		//nolint:wrapcheck
+		return s.Storage.PutBlob(ctx, id, data, opts)
+	})
+}
+
+func (s *circuitBreakerStorage) DeleteBlob(ctx context.Context, id blob.ID) error {
+	return s.call(ctx, methodDeleteBlob, func() error {
+		//nolint:wrapcheck
+		return s.Storage.DeleteBlob(ctx, id)
+	})
+}
+
+func (s *circuitBreakerStorage) ListBlobs(ctx context.Context, prefix blob.ID, callback func(blob.Metadata) error) error {
+	var callbackErr error
+
+	return s.call(ctx, methodListBlobs, func() error {
+		//nolint:wrapcheck
+		return s.Storage.ListBlobs(ctx, prefix, func(bm blob.Metadata) error {
+			callbackErr = callback(bm)
+			return callbackErr
+		})
+	}, func(err error) bool {
+		// errors returned by the callback are not failures of the storage.
+		return callbackErr != nil && errors.Is(err, callbackErr)
+	})
+}
+
+func (s *circuitBreakerStorage) ExtendBlobRetention(ctx context.Context, id blob.ID, opts blob.ExtendOptions) error {
+	return s.call(ctx, methodExtendBlobRetention, func() error {
+		//nolint:wrapcheck
+		return s.Storage.ExtendBlobRetention(ctx, id, opts)
+	})
+}
+
+// call invokes the provided function unless the circuit breaker for the method is open and records its outcome.
+func (s *circuitBreakerStorage) call(ctx context.Context, method string, f func() error, expectedErrors ...func(err error) bool) error {
+	ms := s.methods[method]
+
+	ok, change := ms.breaker.allow(s.now())
+	s.reportStateChange(ctx, method, change)
+
+	if !ok {
+		ms.rejected.Add(1)
+
+		return errors.Wrapf(ErrCircuitOpen, "%v", method)
+	}
+
+	err := f()
+
+	s.reportStateChange(ctx, method, ms.breaker.record(s.now(), isFailure(err, expectedErrors)))
+
+	return err
+}
+
+func (s *circuitBreakerStorage) reportStateChange(ctx context.Context, method string, change *stateChange) {
+	if change == nil {
+		return
+	}
+
+	ms := s.methods[method]
+
+	switch change.to {
+	case stateOpen:
+		ms.opened.Add(1)
+
+		log(ctx).Warnw("storage circuit breaker opened",
+			"method", method,
+			"from", change.from.String(),
+			"calls", change.numCalls,
+			"failures", change.numFailures)
+
+	case stateClosed:
+		ms.closed.Add(1)
+
+		log(ctx).Infow("storage circuit breaker closed",
+			"method", method,
+			"from", change.from.String())
+
+	default:
+		log(ctx).Debugw("storage circuit breaker state changed",
+			"method", method,
+			"from", change.from.String(),
+			"to", change.to.String())
+	}
+}
+
+// isFailure determines whether the error indicates a failure of the underlying storage,
+// as opposed to the expected outcome of the call.
+func isFailure(err error, expectedErrors []func(err error) bool) bool {
+	if err == nil {
+		return false
+	}
+
+	if errors.Is(err, blob.ErrBlobNotFound) || errors.Is(err, blob.ErrBlobAlreadyExists) ||
+		errors.Is(err, blob.ErrInvalidRange) || errors.Is(err, context.Canceled) {
+		return false
+	}
+
+	for _, expected := range expectedErrors {
+		if expected(err) {
+			return false
+		}
+	}
+
+	return true
+}
+
+// NewWrapper returns a Storage wrapper that fails fast when calls of a method of the underlying storage keep failing.
+func NewWrapper(wrapped blob.Storage, mr *metrics.Registry, opts Options) blob.Storage {
+	opts = opts.withDefaults()
+
+	s := &circuitBreakerStorage{
+		Storage: wrapped,
+		now:     opts.TimeNow,
+		methods: map[string]*methodState{},
+	}
+
+	for _, method := range allMethods {
+		labels := map[string]string{"method": method}
+
+		s.methods[method] = &methodState{
+			breaker:  newBreaker(opts),
+			opened:   mr.CounterInt64("blob_circuit_breaker_opened", "Number of times the storage circuit breaker opened.", labels),
+			closed:   mr.CounterInt64("blob_circuit_breaker_closed", "Number of times the storage circuit breaker closed.", labels),
+			rejected: mr.CounterInt64("blob_circuit_breaker_rejected", "Number of calls rejected by the storage circuit breaker.", labels),
+		}
+	}
+
+	return s
+}
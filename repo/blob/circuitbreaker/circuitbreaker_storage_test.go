@@ -0,0 +1,112 @@
+package circuitbreaker_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/require"
+
+	"github.com/kopia/kopia/internal/blobtesting"
+	"github.com/kopia/kopia/internal/faketime"
+	"github.com/kopia/kopia/internal/fault"
+	"github.com/kopia/kopia/internal/gather"
+	"github.com/kopia/kopia/internal/metrics"
+	"github.com/kopia/kopia/internal/testlogging"
+	"github.com/kopia/kopia/repo/blob"
+	"github.com/kopia/kopia/repo/blob/circuitbreaker"
+)
+
+var errSomeError = errors.New("some error")
+
+func TestCircuitBreakerStorage(t *testing.T) {
+	ctx := testlogging.Context(t)
+
+	ta := faketime.NewTimeAdvance(time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC))
+	mr := metrics.NewRegistry()
+	fs := blobtesting.NewFaultyStorage(blobtesting.NewMapStorage(blobtesting.DataMap{}, nil, ta.NowFunc()))
+
+	st := circuitbreaker.NewWrapper(fs, mr, circuitbreaker.Options{
+		WindowSize:         4,
+		MinCalls:           4,
+		ErrorRateThreshold: 0.5,
+		CoolDown:           time.Minute,
+		TimeNow:            ta.NowFunc(),
+	})
+
+	putBlob := func() error {
+		return st.PutBlob(ctx, "p1", gather.FromSlice([]byte{1}), blob.PutOptions{})
+	}
+
+	var tmp gather.WriteBuffer
+	defer tmp.Close()
+
+	// missing blobs are not failures.
+	for range 10 {
+		require.ErrorIs(t, st.GetBlob(ctx, "no-such-blob", 0, -1, &tmp), blob.ErrBlobNotFound)
+	}
+
+	// 1 of 4 calls failed.
+	fs.AddFault(blobtesting.MethodPutBlob).ErrorInstead(errSomeError)
+	require.ErrorIs(t, putBlob(), errSomeError)
+	require.NoError(t, putBlob())
+	require.NoError(t, putBlob())
+	require.NoError(t, putBlob())
+
+	// 2 of 4 most recent calls failed, which opens the circuit breaker.
+	fs.AddFaults(blobtesting.MethodPutBlob, fault.New().ErrorInstead(errSomeError).Repeat(1))
+	require.ErrorIs(t, putBlob(), errSomeError)
+	require.ErrorIs(t, putBlob(), errSomeError)
+
+	require.ErrorIs(t, putBlob(), circuitbreaker.ErrCircuitOpen)
+	require.ErrorIs(t, putBlob(), circuitbreaker.ErrCircuitOpen)
+
+	// other methods are not affected.
+	require.NoError(t, st.GetBlob(ctx, "p1", 0, -1, &tmp))
+
+	// failed trial call after the cool-down opens the circuit breaker again.
+	ta.Advance(time.Minute)
+	fs.AddFault(blobtesting.MethodPutBlob).ErrorInstead(errSomeError)
+	require.ErrorIs(t, putBlob(), errSomeError)
+	require.ErrorIs(t, putBlob(), circuitbreaker.ErrCircuitOpen)
+
+	// successful trial call closes it.
+	ta.Advance(time.Minute)
+	require.NoError(t, putBlob())
+	require.NoError(t, putBlob())
+
+	fs.VerifyAllFaultsExercised(t)
+
+	snap := mr.Snapshot(false)
+	require.EqualValues(t, 2, snap.Counters["blob_circuit_breaker_opened[method:PutBlob]"])
+	require.EqualValues(t, 1, snap.Counters["blob_circuit_breaker_closed[method:PutBlob]"])
+	require.EqualValues(t, 3, snap.Counters["blob_circuit_breaker_rejected[method:PutBlob]"])
+	require.EqualValues(t, 0, snap.Counters["blob_circuit_breaker_opened[method:GetBlob]"])
+}
+
+func TestCircuitBreakerStorage_ListBlobsCallbackErrors(t *testing.T) {
+	ctx := testlogging.Context(t)
+
+	st := circuitbreaker.NewWrapper(blobtesting.NewMapStorage(blobtesting.DataMap{"a": nil}, nil, nil), nil, circuitbreaker.Options{
+		WindowSize: 2,
+		MinCalls:   2,
+	})
+
+	for range 5 {
+		require.ErrorIs(t, st.ListBlobs(ctx, "", func(blob.Metadata) error {
+			return errSomeError
+		}), errSomeError)
+	}
+
+	require.NoError(t, st.ListBlobs(ctx, "", func(blob.Metadata) error {
+		return nil
+	}))
+}
+
+func TestCircuitBreakerStorage_VerifyStorage(t *testing.T) {
+	ctx := testlogging.Context(t)
+
+	st := circuitbreaker.NewWrapper(blobtesting.NewMapStorage(blobtesting.DataMap{}, nil, nil), nil, circuitbreaker.Options{})
+
+	blobtesting.VerifyStorage(ctx, t, st, blob.PutOptions{})
+}
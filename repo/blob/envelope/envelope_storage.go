@@ -0,0 +1,507 @@
+// Package envelope implements wrapper around blob.Storage which encrypts blobs at rest using its own data key,
+// independent of the repository format encryption.
+//
+// The data key is generated when the storage is created and stored in a dedicated blob after being
+// encrypted by a KeyEncrypter, which typically delegates to a key management service holding the key-encryption key.
+//
+// Each blob is encrypted with AES-256-GCM using a key derived from the data key and a random per-blob salt,
+// in chunks of fixed size, so that ranges of blobs can be read and authenticated without reading entire blobs.
+package envelope
+
+import (
+	"bytes"
+	"container/list"
+	"context"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/binary"
+	"io"
+	"sync"
+
+	"github.com/pkg/errors"
+
+	"github.com/kopia/kopia/internal/crypto"
+	"github.com/kopia/kopia/internal/gather"
+	"github.com/kopia/kopia/repo/blob"
+)
+
+// DefaultKeyBlobID is the default ID of the blob holding the encrypted data key.
+const DefaultKeyBlobID blob.ID = "_envelope_key"
+
+const (
+	formatVersion = 1
+	saltLength    = 16
+	headerLength  = 1 + saltLength
+
+	chunkSize     = 64 << 10
+	tagLength     = 16
+	dataKeyLength = 32
+
+	minKeyEncryptionKeyLength = 32
+
+	// maxCachedBlobCiphers is the maximum number of blobs whose ciphers and lengths are cached for partial reads.
+	maxCachedBlobCiphers = 1000
+)
+
+// ErrDataKeyNotFound is returned when opening existing storage without the blob holding the encrypted data key.
+var ErrDataKeyNotFound = errors.New("envelope encryption data key not found")
+
+//nolint:gochecknoglobals
+var purposeBlobKey = []byte("envelope-blob")
+
+// Options controls the behavior of the envelope encryption wrapper.
+type Options struct {
+	// KeyEncrypter protects the data key, required.
+	KeyEncrypter KeyEncrypter
+
+	// KeyBlobID is the ID of the blob holding the encrypted data key, defaults to DefaultKeyBlobID.
+	KeyBlobID blob.ID
+}
+
+type envelopeStorage struct {
+	blob.Storage
+
+	keyBlobID blob.ID
+	dataKey   []byte
+
+	mu sync.Mutex
+	// +checklocks:mu
+	ciphers map[blob.ID]*list.Element
+	// +checklocks:mu
+	ciphersLRU *list.List // of *blobCipher, most recently used at the front
+}
+
+// blobCipher is the cipher of a single blob, derived from the data key and the blob header, along with the
+// encrypted length of the blob, which together allow reading ranges of the blob with a single request.
+type blobCipher struct {
+	id              blob.ID
+	aead            cipher.AEAD
+	encryptedLength int64
+}
+
+func (s *envelopeStorage) GetBlob(ctx context.Context, id blob.ID, offset, length int64, output blob.OutputBuffer) error {
+	if id == s.keyBlobID {
+		return blob.ErrBlobNotFound
+	}
+
+	output.Reset()
+
+	if length < 0 {
+		return s.getFullBlob(ctx, id, output)
+	}
+
+	if offset < 0 {
+		return errors.Wrap(blob.ErrInvalidRange, "invalid offset")
+	}
+
+	if bc := s.cachedBlobCipher(id); bc != nil {
+		if err := s.getBlobRange(ctx, bc, offset, length, output); err == nil {
+			return nil
+		}
+
+		// the blob may have been rewritten by another client, retry with the current header.
+		s.forgetBlobCipher(id)
+		output.Reset()
+	}
+
+	bc, err := s.readBlobCipher(ctx, id)
+	if err != nil {
+		return err
+	}
+
+	return s.getBlobRange(ctx, bc, offset, length, output)
+}
+
+// readBlobCipher reads the metadata and header of the blob and caches the resulting cipher.
+func (s *envelopeStorage) readBlobCipher(ctx context.Context, id blob.ID) (*blobCipher, error) {
+	bm, err := s.Storage.GetMetadata(ctx, id)
+	if err != nil {
+		//nolint:wrapcheck
+		return nil, err
+	}
+
+	var hdr gather.WriteBuffer
+	defer hdr.Close()
+
+	if err := s.Storage.GetBlob(ctx, id, 0, headerLength, &hdr); err != nil {
+		//nolint:wrapcheck
+		return nil, err
+	}
+
+	aead, err := s.newBlobCipher(hdr.ToByteSlice())
+	if err != nil {
+		return nil, errors.Wrapf(err, "invalid encrypted blob %v", id)
+	}
+
+	bc := &blobCipher{id: id, aead: aead, encryptedLength: bm.Length}
+
+	s.cacheBlobCipher(bc)
+
+	return bc, nil
+}
+
+func (s *envelopeStorage) getBlobRange(ctx context.Context, bc *blobCipher, offset, length int64, output blob.OutputBuffer) error {
+	plainLength, numChunks, err := plaintextLength(bc.encryptedLength)
+	if err != nil {
+		return errors.Wrapf(err, "invalid encrypted blob %v", bc.id)
+	}
+
+	if offset+length > plainLength {
+		return errors.Wrapf(blob.ErrInvalidRange, "invalid range %v+%v of blob with length %v", offset, length, plainLength)
+	}
+
+	if length == 0 {
+		return nil
+	}
+
+	firstChunk := offset / chunkSize
+	lastChunk := (offset + length - 1) / chunkSize
+
+	encStart := headerLength + firstChunk*(chunkSize+tagLength)
+	encEnd := min(headerLength+(lastChunk+1)*(chunkSize+tagLength), bc.encryptedLength)
+
+	var enc gather.WriteBuffer
+	defer enc.Close()
+
+	if err := s.Storage.GetBlob(ctx, bc.id, encStart, encEnd-encStart, &enc); err != nil {
+		//nolint:wrapcheck
+		return err
+	}
+
+	plain, err := decryptChunks(bc.aead, bc.id, enc.ToByteSlice(), firstChunk, numChunks)
+	if err != nil {
+		return err
+	}
+
+	skip := offset - firstChunk*chunkSize
+
+	_, err = output.Write(plain[skip : skip+length])
+
+	return errors.Wrap(err, "error writing data to output")
+}
+
+func (s *envelopeStorage) cachedBlobCipher(id blob.ID) *blobCipher {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	e := s.ciphers[id]
+	if e == nil {
+		return nil
+	}
+
+	s.ciphersLRU.MoveToFront(e)
+
+	return e.Value.(*blobCipher) //nolint:forcetypeassert
+}
+
+func (s *envelopeStorage) cacheBlobCipher(bc *blobCipher) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if e := s.ciphers[bc.id]; e != nil {
+		s.ciphersLRU.Remove(e)
+	}
+
+	s.ciphers[bc.id] = s.ciphersLRU.PushFront(bc)
+
+	for s.ciphersLRU.Len() > maxCachedBlobCiphers {
+		oldest := s.ciphersLRU.Back()
+
+		s.ciphersLRU.Remove(oldest)
+		delete(s.ciphers, oldest.Value.(*blobCipher).id) //nolint:forcetypeassert
+	}
+}
+
+func (s *envelopeStorage) forgetBlobCipher(id blob.ID) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if e := s.ciphers[id]; e != nil {
+		s.ciphersLRU.Remove(e)
+		delete(s.ciphers, id)
+	}
+}
+
+func (s *envelopeStorage) getFullBlob(ctx context.Context, id blob.ID, output blob.OutputBuffer) error {
+	var enc gather.WriteBuffer
+	defer enc.Close()
+
+	if err := s.Storage.GetBlob(ctx, id, 0, -1, &enc); err != nil {
+		//nolint:wrapcheck
+		return err
+	}
+
+	data := enc.ToByteSlice()
+
+	_, numChunks, err := plaintextLength(int64(len(data)))
+	if err != nil {
+		return errors.Wrapf(err, "invalid encrypted blob %v", id)
+	}
+
+	aead, err := s.newBlobCipher(data[:headerLength])
+	if err != nil {
+		return errors.Wrapf(err, "invalid encrypted blob %v", id)
+	}
+
+	plain, err := decryptChunks(aead, id, data[headerLength:], 0, numChunks)
+	if err != nil {
+		s.forgetBlobCipher(id)
+		return err
+	}
+
+	s.cacheBlobCipher(&blobCipher{id: id, aead: aead, encryptedLength: int64(len(data))})
+
+	_, err = output.Write(plain)
+
+	return errors.Wrap(err, "error writing data to output")
+}
+
+func (s *envelopeStorage) GetMetadata(ctx context.Context, id blob.ID) (blob.Metadata, error) {
+	if id == s.keyBlobID {
+		return blob.Metadata{}, blob.ErrBlobNotFound
+	}
+
+	bm, err := s.Storage.GetMetadata(ctx, id)
+	if err != nil {
+		//nolint:wrapcheck
+		return blob.Metadata{}, err
+	}
+
+	return withPlaintextLength(bm), nil
+}
+
+func (s *envelopeStorage) PutBlob(ctx context.Context, id blob.ID, data blob.Bytes, opts blob.PutOptions) error {
+	if id == s.keyBlobID {
+		return errors.Errorf("blob %v is reserved for the encryption key", id)
+	}
+
+	var plain bytes.Buffer
+
+	if _, err := data.WriteTo(&plain); err != nil {
+		return errors.Wrap(err, "error reading blob data")
+	}
+
+	enc, aead, err := s.encrypt(id, plain.Bytes())
+	if err != nil {
+		return err
+	}
+
+	if err := s.Storage.PutBlob(ctx, id, gather.FromSlice(enc), opts); err != nil {
+		s.forgetBlobCipher(id)
+
+		//nolint:wrapcheck
+		return err
+	}
+
+	s.cacheBlobCipher(&blobCipher{id: id, aead: aead, encryptedLength: int64(len(enc))})
+
+	return nil
+}
+
+func (s *envelopeStorage) DeleteBlob(ctx context.Context, id blob.ID) error {
+	if id == s.keyBlobID {
+		return errors.Errorf("blob %v is reserved for the encryption key", id)
+	}
+
+	s.forgetBlobCipher(id)
+
+	//nolint:wrapcheck
+	return s.Storage.DeleteBlob(ctx, id)
+}
+
+func (s *envelopeStorage) ListBlobs(ctx context.Context, prefix blob.ID, callback func(blob.Metadata) error) error {
+	//nolint:wrapcheck
+	return s.Storage.ListBlobs(ctx, prefix, func(bm blob.Metadata) error {
+		if bm.BlobID == s.keyBlobID {
+			return nil
+		}
+
+		return callback(withPlaintextLength(bm))
+	})
+}
+
+// encrypt returns the encrypted blob along with its cipher.
+func (s *envelopeStorage) encrypt(id blob.ID, plain []byte) ([]byte, cipher.AEAD, error) {
+	numChunks := max(1, (len(plain)+chunkSize-1)/chunkSize)
+
+	out := make([]byte, headerLength, headerLength+len(plain)+numChunks*tagLength)
+	out[0] = formatVersion
+
+	if _, err := io.ReadFull(rand.Reader, out[1:headerLength]); err != nil {
+		return nil, nil, errors.Wrap(err, "error generating salt")
+	}
+
+	aead, err := s.newBlobCipher(out[:headerLength])
+	if err != nil {
+		return nil, nil, err
+	}
+
+	for i := range numChunks {
+		chunk := plain[min(i*chunkSize, len(plain)):min((i+1)*chunkSize, len(plain))]
+		out = aead.Seal(out, chunkNonce(aead, int64(i)), chunk, chunkAuthData(id, int64(i), i == numChunks-1))
+	}
+
+	return out, aead, nil
+}
+
+func (s *envelopeStorage) newBlobCipher(header []byte) (cipher.AEAD, error) {
+	if len(header) != headerLength || header[0] != formatVersion {
+		return nil, errors.New("unsupported encryption header")
+	}
+
+	blk, err := aes.NewCipher(crypto.DeriveKeyFromMasterKey(s.dataKey, header[1:], purposeBlobKey, dataKeyLength))
+	if err != nil {
+		return nil, errors.Wrap(err, "cannot create cipher")
+	}
+
+	aead, err := cipher.NewGCM(blk)
+	if err != nil {
+		return nil, errors.Wrap(err, "cannot create cipher")
+	}
+
+	return aead, nil
+}
+
+// decryptChunks decrypts consecutive encrypted chunks starting at the provided index of the blob with numChunks chunks.
+func decryptChunks(aead cipher.AEAD, id blob.ID, enc []byte, firstChunk, numChunks int64) ([]byte, error) {
+	var result []byte
+
+	for i := firstChunk; len(enc) > 0; i++ {
+		n := min(len(enc), chunkSize+tagLength)
+
+		var err error
+
+		result, err = aead.Open(result, chunkNonce(aead, i), enc[:n], chunkAuthData(id, i, i == numChunks-1))
+		if err != nil {
+			return nil, errors.Errorf("unable to decrypt blob %v, invalid key or corrupted data", id)
+		}
+
+		enc = enc[n:]
+	}
+
+	return result, nil
+}
+
+// plaintextLength returns the length of the plaintext and the number of chunks of an encrypted blob with the provided length.
+func plaintextLength(encryptedLength int64) (plainLength, numChunks int64, err error) {
+	body := encryptedLength - headerLength
+	if body < tagLength {
+		return 0, 0, errors.New("encrypted blob too short")
+	}
+
+	n, rem := body/(chunkSize+tagLength), body%(chunkSize+tagLength)
+
+	switch {
+	case rem == 0:
+		return n * chunkSize, n, nil
+	case rem < tagLength:
+		return 0, 0, errors.New("invalid length of encrypted blob")
+	default:
+		return n*chunkSize + rem - tagLength, n + 1, nil
+	}
+}
+
+func withPlaintextLength(bm blob.Metadata) blob.Metadata {
+	// leave lengths of blobs which were not written by the wrapper unchanged.
+	if l, _, err := plaintextLength(bm.Length); err == nil {
+		bm.Length = l
+	}
+
+	return bm
+}
+
+func chunkNonce(aead cipher.AEAD, index int64) []byte {
+	// per-blob keys are unique, so chunk index is sufficient as nonce.
+	nonce := make([]byte, aead.NonceSize())
+	binary.BigEndian.PutUint64(nonce[len(nonce)-8:], uint64(index)) //nolint:gosec
+
+	return nonce
+}
+
+// chunkAuthData binds each chunk to the blob ID and its position, which prevents swapping, reordering and truncation.
+func chunkAuthData(id blob.ID, index int64, isLast bool) []byte {
+	ad := binary.BigEndian.AppendUint64([]byte(id), uint64(index)) //nolint:gosec
+
+	if isLast {
+		return append(ad, 1)
+	}
+
+	return append(ad, 0)
+}
+
+// loadOrCreateDataKey returns the data key of the storage, generating and storing it if it does not exist yet
+// and isCreate is true.
+func loadOrCreateDataKey(ctx context.Context, st blob.Storage, opts Options, isCreate bool) ([]byte, error) {
+	var buf gather.WriteBuffer
+	defer buf.Close()
+
+	err := st.GetBlob(ctx, opts.KeyBlobID, 0, -1, &buf)
+	if err == nil {
+		dataKey, derr := opts.KeyEncrypter.DecryptKey(ctx, buf.ToByteSlice())
+
+		return dataKey, errors.Wrap(derr, "unable to decrypt data key")
+	}
+
+	if !errors.Is(err, blob.ErrBlobNotFound) {
+		return nil, errors.Wrap(err, "unable to read data key")
+	}
+
+	if !isCreate {
+		// generating a new key would make all existing blobs unreadable.
+		return nil, errors.Wrapf(ErrDataKeyNotFound, "blob %v", opts.KeyBlobID)
+	}
+
+	dataKey := make([]byte, dataKeyLength)
+	if _, err := io.ReadFull(rand.Reader, dataKey); err != nil {
+		return nil, errors.Wrap(err, "error generating data key")
+	}
+
+	encryptedKey, err := opts.KeyEncrypter.EncryptKey(ctx, dataKey)
+	if err != nil {
+		return nil, errors.Wrap(err, "unable to encrypt data key")
+	}
+
+	err = st.PutBlob(ctx, opts.KeyBlobID, gather.FromSlice(encryptedKey), blob.PutOptions{DoNotRecreate: true})
+
+	switch {
+	case errors.Is(err, blob.ErrBlobAlreadyExists):
+		// another client has just created the key, use it instead.
+		return loadOrCreateDataKey(ctx, st, opts, false)
+
+	case errors.Is(err, blob.ErrUnsupportedPutBlobOption):
+		err = st.PutBlob(ctx, opts.KeyBlobID, gather.FromSlice(encryptedKey), blob.PutOptions{})
+	}
+
+	return dataKey, errors.Wrap(err, "unable to write data key")
+}
+
+// NewWrapper returns a Storage wrapper which encrypts blobs before writing them to the wrapped storage and decrypts
+// them when they are read. When isCreate is true, the data key is created in the wrapped storage if it does not
+// exist yet, otherwise ErrDataKeyNotFound is returned.
+func NewWrapper(ctx context.Context, wrapped blob.Storage, opts Options, isCreate bool) (blob.Storage, error) {
+	if opts.KeyEncrypter == nil {
+		return nil, errors.New("key encrypter must be provided")
+	}
+
+	if opts.KeyBlobID == "" {
+		opts.KeyBlobID = DefaultKeyBlobID
+	}
+
+	dataKey, err := loadOrCreateDataKey(ctx, wrapped, opts, isCreate)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(dataKey) != dataKeyLength {
+		return nil, errors.Errorf("invalid data key length %v", len(dataKey))
+	}
+
+	return &envelopeStorage{
+		Storage:    wrapped,
+		keyBlobID:  opts.KeyBlobID,
+		dataKey:    dataKey,
+		ciphers:    map[blob.ID]*list.Element{},
+		ciphersLRU: list.New(),
+	}, nil
+}
@@ -0,0 +1,151 @@
+package envelope_test
+
+import (
+	"bytes"
+	"crypto/rand"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/kopia/kopia/internal/blobtesting"
+	"github.com/kopia/kopia/internal/gather"
+	"github.com/kopia/kopia/internal/testlogging"
+	"github.com/kopia/kopia/repo/blob"
+	"github.com/kopia/kopia/repo/blob/envelope"
+)
+
+func newKeyEncrypter(t *testing.T, key byte) envelope.KeyEncrypter {
+	t.Helper()
+
+	ke, err := envelope.NewStaticKeyEncrypter(bytes.Repeat([]byte{key}, 32))
+	require.NoError(t, err)
+
+	return ke
+}
+
+func TestEnvelopeStorage(t *testing.T) {
+	ctx := testlogging.Context(t)
+
+	data := blobtesting.DataMap{}
+	st, err := envelope.NewWrapper(ctx, blobtesting.NewMapStorage(data, nil, nil), envelope.Options{
+		KeyEncrypter: newKeyEncrypter(t, 1),
+	}, true)
+	require.NoError(t, err)
+
+	blobtesting.VerifyStorage(ctx, t, st, blob.PutOptions{})
+}
+
+func TestEnvelopeStorageEncryptsData(t *testing.T) {
+	ctx := testlogging.Context(t)
+
+	data := blobtesting.DataMap{}
+	ms := blobtesting.NewMapStorage(data, nil, nil)
+
+	// existing storage can't be opened without the data key.
+	_, err := envelope.NewWrapper(ctx, ms, envelope.Options{KeyEncrypter: newKeyEncrypter(t, 1)}, false)
+	require.ErrorIs(t, err, envelope.ErrDataKeyNotFound)
+	require.Empty(t, data)
+
+	st, err := envelope.NewWrapper(ctx, ms, envelope.Options{KeyEncrypter: newKeyEncrypter(t, 1)}, true)
+	require.NoError(t, err)
+
+	// spans multiple encrypted chunks.
+	plain := make([]byte, 200000)
+	_, err = rand.Read(plain)
+	require.NoError(t, err)
+
+	require.NoError(t, st.PutBlob(ctx, "blob1", gather.FromSlice(plain), blob.PutOptions{}))
+	require.NotContains(t, string(data["blob1"]), string(plain[0:100]))
+
+	// encryption key is hidden.
+	all, err := blob.ListAllBlobs(ctx, st, "")
+	require.NoError(t, err)
+	require.Len(t, all, 1)
+	require.Equal(t, int64(len(plain)), all[0].Length)
+
+	var tmp gather.WriteBuffer
+	defer tmp.Close()
+
+	for _, r := range []struct{ offset, length int64 }{
+		{0, 1},
+		{65535, 2},
+		{100000, 100000},
+		{199999, 1},
+		{200000, 0},
+	} {
+		require.NoError(t, st.GetBlob(ctx, "blob1", r.offset, r.length, &tmp))
+		require.Equal(t, plain[r.offset:r.offset+r.length], tmp.ToByteSlice())
+	}
+
+	require.ErrorIs(t, st.GetBlob(ctx, "blob1", 199999, 2, &tmp), blob.ErrInvalidRange)
+
+	// the storage can be reopened with the same key-encryption key.
+	st2, err := envelope.NewWrapper(ctx, ms, envelope.Options{KeyEncrypter: newKeyEncrypter(t, 1)}, false)
+	require.NoError(t, err)
+	require.NoError(t, st2.GetBlob(ctx, "blob1", 0, -1, &tmp))
+	require.Equal(t, plain, tmp.ToByteSlice())
+
+	// but not with a different one.
+	_, err = envelope.NewWrapper(ctx, ms, envelope.Options{KeyEncrypter: newKeyEncrypter(t, 2)}, false)
+	require.Error(t, err)
+
+	// corruption is detected.
+	data["blob1"][70000] ^= 1
+	require.Error(t, st.GetBlob(ctx, "blob1", 0, -1, &tmp))
+	require.Error(t, st.GetBlob(ctx, "blob1", 65536, 10, &tmp))
+	require.NoError(t, st.GetBlob(ctx, "blob1", 0, 10, &tmp))
+
+	// truncation at chunk boundary is detected.
+	require.NoError(t, st.PutBlob(ctx, "blob2", gather.FromSlice(plain), blob.PutOptions{}))
+	data["blob2"] = data["blob2"][:17+65536+16]
+	require.Error(t, st.GetBlob(ctx, "blob2", 0, -1, &tmp))
+
+	// encrypted blobs can't be swapped.
+	require.NoError(t, st.PutBlob(ctx, "blob3", gather.FromSlice(plain[0:10]), blob.PutOptions{}))
+	data["blob4"] = data["blob3"]
+	require.Error(t, st.GetBlob(ctx, "blob4", 0, -1, &tmp))
+}
+
+func TestEnvelopeStoragePartialReads(t *testing.T) {
+	ctx := testlogging.Context(t)
+
+	data := blobtesting.DataMap{}
+	ms := blobtesting.NewMapStorage(data, nil, nil)
+
+	st, err := envelope.NewWrapper(ctx, ms, envelope.Options{KeyEncrypter: newKeyEncrypter(t, 1)}, true)
+	require.NoError(t, err)
+
+	plain := make([]byte, 200000)
+	_, err = rand.Read(plain)
+	require.NoError(t, err)
+
+	require.NoError(t, st.PutBlob(ctx, "blob1", gather.FromSlice(plain), blob.PutOptions{}))
+
+	fs := blobtesting.NewFaultyStorage(ms)
+
+	st2, err := envelope.NewWrapper(ctx, fs, envelope.Options{KeyEncrypter: newKeyEncrypter(t, 1)}, false)
+	require.NoError(t, err)
+
+	var tmp gather.WriteBuffer
+	defer tmp.Close()
+
+	// the first partial read fetches the metadata and header of the blob, subsequent ones read the range only.
+	for i := range 3 {
+		require.NoError(t, st2.GetBlob(ctx, "blob1", int64(i)*70000, 100, &tmp))
+		require.Equal(t, plain[i*70000:i*70000+100], tmp.ToByteSlice())
+	}
+
+	require.Equal(t, 1, fs.NumCalls(blobtesting.MethodGetMetadata))
+	require.Equal(t, 1+1+3, fs.NumCalls(blobtesting.MethodGetBlob))
+
+	// rewriting the blob by another client is detected and the new header is used.
+	plain2 := bytes.Repeat([]byte{1}, 300000)
+	require.NoError(t, st.PutBlob(ctx, "blob1", gather.FromSlice(plain2), blob.PutOptions{}))
+	require.NoError(t, st2.GetBlob(ctx, "blob1", 250000, 100, &tmp))
+	require.Equal(t, plain2[250000:250100], tmp.ToByteSlice())
+}
+
+func TestNewStaticKeyEncrypter(t *testing.T) {
+	_, err := envelope.NewStaticKeyEncrypter([]byte("too short"))
+	require.Error(t, err)
+}
@@ -0,0 +1,42 @@
+package envelope
+
+import (
+	"context"
+
+	"github.com/pkg/errors"
+
+	"github.com/kopia/kopia/internal/crypto"
+)
+
+// KeyEncrypter encrypts and decrypts the data key of the storage, typically by delegating
+// to a key management service which holds the key-encryption key.
+type KeyEncrypter interface {
+	EncryptKey(ctx context.Context, dataKey []byte) ([]byte, error)
+	DecryptKey(ctx context.Context, encryptedKey []byte) ([]byte, error)
+}
+
+//nolint:gochecknoglobals
+var staticKeyEncrypterSalt = []byte("kopia-envelope-key")
+
+type staticKeyEncrypter struct {
+	key []byte
+}
+
+func (e staticKeyEncrypter) EncryptKey(_ context.Context, dataKey []byte) ([]byte, error) {
+	//nolint:wrapcheck
+	return crypto.EncryptAes256Gcm(dataKey, e.key, staticKeyEncrypterSalt)
+}
+
+func (e staticKeyEncrypter) DecryptKey(_ context.Context, encryptedKey []byte) ([]byte, error) {
+	//nolint:wrapcheck
+	return crypto.DecryptAes256Gcm(encryptedKey, e.key, staticKeyEncrypterSalt)
+}
+
+// NewStaticKeyEncrypter returns KeyEncrypter which protects the data key using the provided key-encryption key.
+func NewStaticKeyEncrypter(key []byte) (KeyEncrypter, error) {
+	if len(key) < minKeyEncryptionKeyLength {
+		return nil, errors.Errorf("key-encryption key must be at least %v bytes long", minKeyEncryptionKeyLength)
+	}
+
+	return staticKeyEncrypter{append([]byte(nil), key...)}, nil
+}
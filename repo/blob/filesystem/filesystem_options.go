@@ -3,6 +3,7 @@
 import (
 	"os"
 
+	"github.com/kopia/kopia/repo/blob/retrypolicy"
 	"github.com/kopia/kopia/repo/blob/sharded"
 	"github.com/kopia/kopia/repo/blob/throttling"
 )
@@ -19,6 +20,7 @@ type Options struct {
 
 	sharded.Options
 	throttling.Limits
+	retrypolicy.Policy
 
 	osInterfaceOverride osInterface
 }
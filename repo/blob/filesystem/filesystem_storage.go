@@ -80,7 +80,7 @@ func (fs *fsImpl) isRetriable(err error) bool {
 func (fs *fsImpl) GetBlobFromPath(ctx context.Context, dirPath, path string, offset, length int64, output blob.OutputBuffer) error {
 	_ = dirPath
 
-	err := retry.WithExponentialBackoffNoValue(ctx, "GetBlobFromPath:"+path, func() error {
+	err := retry.WithPolicyNoValue(ctx, fs.Backoff(), "GetBlobFromPath:"+path, func() error {
 		output.Reset()
 
 		f, err := fs.osi.Open(path)
@@ -136,7 +136,7 @@ func (fs *fsImpl) GetMetadataFromPath(ctx context.Context, dirPath, path string)
 	_ = dirPath
 
 	//nolint:wrapcheck
-	return retry.WithExponentialBackoff(ctx, "GetMetadataFromPath:"+path, func() (blob.Metadata, error) {
+	return retry.WithPolicy(ctx, fs.Backoff(), "GetMetadataFromPath:"+path, func() (blob.Metadata, error) {
 		fi, err := fs.osi.Stat(path)
 		if err != nil {
 			if fs.osi.IsNotExist(err) {
@@ -165,7 +165,7 @@ func (fs *fsImpl) PutBlobInPath(ctx context.Context, dirPath, path string, data
 		return errors.Wrap(blob.ErrUnsupportedPutBlobOption, "do-not-recreate")
 	}
 
-	return retry.WithExponentialBackoffNoValue(ctx, "PutBlobInPath:"+path, func() error {
+	return retry.WithPolicyNoValue(ctx, fs.Backoff(), "PutBlobInPath:"+path, func() error {
 		randSuffix := make([]byte, tempFileRandomSuffixLen)
 		if _, err := rand.Read(randSuffix); err != nil {
 			return errors.Wrap(err, "can't get random bytes")
@@ -244,7 +244,7 @@ func (fs *fsImpl) DeleteBlobInPath(ctx context.Context, dirPath, path string) er
 	_ = dirPath
 
 	//nolint:wrapcheck
-	return retry.WithExponentialBackoffNoValue(ctx, "DeleteBlobInPath:"+path, func() error {
+	return retry.WithPolicyNoValue(ctx, fs.Backoff(), "DeleteBlobInPath:"+path, func() error {
 		err := fs.osi.Remove(path)
 		if err == nil || fs.osi.IsNotExist(err) {
 			return nil
@@ -256,7 +256,7 @@ func (fs *fsImpl) DeleteBlobInPath(ctx context.Context, dirPath, path string) er
 }
 
 func (fs *fsImpl) ReadDir(ctx context.Context, dirname string) ([]os.FileInfo, error) {
-	entries, err := retry.WithExponentialBackoff(ctx, "ReadDir:"+dirname, func() ([]os.DirEntry, error) {
+	entries, err := retry.WithPolicy(ctx, fs.Backoff(), "ReadDir:"+dirname, func() ([]os.DirEntry, error) {
 		v, err := fs.osi.ReadDir(dirname)
 		//nolint:wrapcheck
 		return v, err
@@ -291,7 +291,7 @@ func (fs *fsStorage) TouchBlob(ctx context.Context, blobID blob.ID, threshold ti
 	var mtime time.Time
 
 	//nolint:wrapcheck,forcetypeassert
-	err := retry.WithExponentialBackoffNoValue(ctx, "TouchBlob", func() error {
+	err := retry.WithPolicyNoValue(ctx, fs.Impl.(*fsImpl).Backoff(), "TouchBlob", func() error {
 		_, path, err := fs.Storage.GetShardedPathAndFilePath(ctx, blobID)
 		if err != nil {
 			return errors.Wrap(err, "error getting sharded path")
@@ -322,6 +322,20 @@ func (fs *fsStorage) TouchBlob(ctx context.Context, blobID blob.ID, threshold ti
 	return mtime, err
 }
 
+// SetBlobModTime sets file modification time to the provided time.
+func (fs *fsStorage) SetBlobModTime(ctx context.Context, blobID blob.ID, mtime time.Time) error {
+	//nolint:wrapcheck,forcetypeassert
+	return retry.WithPolicyNoValue(ctx, fs.Impl.(*fsImpl).Backoff(), "SetBlobModTime", func() error {
+		_, path, err := fs.Storage.GetShardedPathAndFilePath(ctx, blobID)
+		if err != nil {
+			return errors.Wrap(err, "error getting sharded path")
+		}
+
+		//nolint:wrapcheck,forcetypeassert
+		return fs.Impl.(*fsImpl).osi.Chtimes(path, mtime, mtime)
+	}, fs.Impl.(*fsImpl).isRetriable)
+}
+
 func (fs *fsStorage) ConnectionInfo() blob.ConnectionInfo {
 	return blob.ConnectionInfo{
 		Type:   fsStorageType,
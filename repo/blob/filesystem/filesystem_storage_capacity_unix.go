@@ -14,7 +14,9 @@
 )
 
 func (fs *fsStorage) GetCapacity(ctx context.Context) (blob.Capacity, error) {
-	return retry.WithExponentialBackoff(ctx, "GetCapacity", func() (blob.Capacity, error) {
+	impl := fs.Impl.(*fsImpl) //nolint:forcetypeassert
+
+	return retry.WithPolicy(ctx, impl.Backoff(), "GetCapacity", func() (blob.Capacity, error) {
 		var stat syscall.Statfs_t
 		if err := syscall.Statfs(fs.RootPath, &stat); err != nil {
 			return blob.Capacity{}, errors.Wrap(err, "GetCapacity")
@@ -24,5 +26,5 @@ func (fs *fsStorage) GetCapacity(ctx context.Context) (blob.Capacity, error) {
 			SizeB: uint64(stat.Blocks) * uint64(stat.Bsize), //nolint:unconvert
 			FreeB: uint64(stat.Bavail) * uint64(stat.Bsize), //nolint:unconvert
 		}, nil
-	}, fs.Impl.(*fsImpl).isRetriable) //nolint:forcetypeassert
+	}, impl.isRetriable)
 }
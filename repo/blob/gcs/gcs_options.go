@@ -3,6 +3,8 @@
 import (
 	"encoding/json"
 
+	"github.com/kopia/kopia/repo/blob"
+	"github.com/kopia/kopia/repo/blob/retrypolicy"
 	"github.com/kopia/kopia/repo/blob/throttling"
 )
 
@@ -23,5 +25,9 @@ type Options struct {
 	// ReadOnly causes GCS connection to be opened with read-only scope to prevent accidental mutations.
 	ReadOnly bool `json:"readOnly,omitempty"`
 
+	// StorageClasses specifies storage classes of blobs by prefix (e.g. NEARLINE, COLDLINE, ARCHIVE).
+	StorageClasses []blob.PrefixStorageClass `json:"storageClasses,omitempty"`
+
 	throttling.Limits
+	retrypolicy.Policy
 }
@@ -7,6 +7,7 @@
 	"fmt"
 	"net/http"
 	"os"
+	"strconv"
 
 	gcsclient "cloud.google.com/go/storage"
 	"github.com/pkg/errors"
@@ -21,6 +22,7 @@
 	"github.com/kopia/kopia/internal/timestampmeta"
 	"github.com/kopia/kopia/repo/blob"
 	"github.com/kopia/kopia/repo/blob/retrying"
+	"github.com/kopia/kopia/repo/blob/throttling"
 )
 
 const (
@@ -71,6 +73,7 @@ func (gcs *gcsStorage) GetMetadata(ctx context.Context, b blob.ID) (blob.Metadat
 		BlobID:    b,
 		Length:    attrs.Size,
 		Timestamp: attrs.Created,
+		Version:   strconv.FormatInt(attrs.Generation, 10),
 	}
 
 	if t, ok := timestampmeta.FromValue(attrs.Metadata[timeMapKey]); ok {
@@ -90,6 +93,13 @@ func translateError(err error) error {
 		case http.StatusPreconditionFailed:
 			return blob.ErrBlobAlreadyExists
 		}
+
+		if throttling.IsThrottlingHTTPStatus(ae.Code) {
+			return &throttling.ThrottledError{
+				RetryAfter: throttling.ParseRetryAfter(ae.Header.Get("Retry-After"), clock.Now()),
+				Err:        err,
+			}
+		}
 	}
 
 	switch {
@@ -102,6 +112,21 @@ func translateError(err error) error {
 	}
 }
 
+// isRetriableError determines whether the error returned by GCS is worth retrying, errors caused by
+// invalid requests or credentials are not.
+func isRetriableError(err error) bool {
+	var ae *googleapi.Error
+
+	if errors.As(err, &ae) {
+		switch ae.Code {
+		case http.StatusBadRequest, http.StatusUnauthorized, http.StatusForbidden:
+			return false
+		}
+	}
+
+	return true
+}
+
 func (gcs *gcsStorage) PutBlob(ctx context.Context, b blob.ID, data blob.Bytes, opts blob.PutOptions) error {
 	if opts.HasRetentionOptions() {
 		return errors.Wrap(blob.ErrUnsupportedPutBlobOption, "blob-retention")
@@ -120,6 +145,7 @@ func (gcs *gcsStorage) PutBlob(ctx context.Context, b blob.ID, data blob.Bytes,
 	writer.ChunkSize = writerChunkSize
 	writer.ContentType = "application/x-kopia"
 	writer.ObjectAttrs.Metadata = timestampmeta.ToMap(opts.SetModTime, timeMapKey)
+	writer.ObjectAttrs.StorageClass = blob.StorageClassForBlob(gcs.StorageClasses, b, opts)
 
 	err := iocopy.JustCopy(writer, data.Reader())
 	if err != nil {
@@ -280,7 +306,7 @@ func New(ctx context.Context, opt *Options, isCreate bool) (blob.Storage, error)
 		return nil, errors.Wrap(err, "unable to list from the bucket")
 	}
 
-	return retrying.NewWrapper(gcs), nil
+	return retrying.NewWrapperWithPolicy(gcs, opt.Policy, isRetriableError), nil
 }
 
 func init() {
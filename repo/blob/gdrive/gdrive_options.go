@@ -3,6 +3,7 @@
 import (
 	"encoding/json"
 
+	"github.com/kopia/kopia/repo/blob/retrypolicy"
 	"github.com/kopia/kopia/repo/blob/throttling"
 )
 
@@ -21,4 +22,5 @@ type Options struct {
 	ReadOnly bool `json:"readOnly,omitempty"`
 
 	throttling.Limits
+	retrypolicy.Policy
 }
@@ -576,7 +576,7 @@ func New(ctx context.Context, opt *Options, isCreate bool) (blob.Storage, error)
 		return nil, errors.Wrap(err, "unable to list from the folder")
 	}
 
-	return retrying.NewWrapper(gdrive), nil
+	return retrying.NewWrapperWithPolicy(gdrive, opt.Policy, nil), nil
 }
 
 func init() {
@@ -4,7 +4,10 @@
 import (
 	"context"
 	"errors"
+	"fmt"
+	"sync"
 	"sync/atomic"
+	"time"
 
 	"go.opentelemetry.io/otel"
 
@@ -15,13 +18,33 @@
 
 var tracer = otel.Tracer("BlobStorage")
 
+// number of leading characters of blob IDs retained when redacting them.
+const redactedBlobIDPrefixLength = 4
+
+// Options controls which storage operations are logged and how.
+type Options struct {
+	// SampleEvery causes only one in every N successful calls of each method to be logged, zero or one logs all calls.
+	SampleEvery int `json:"sampleEvery,omitempty"`
+
+	// SlowThreshold causes only successful calls taking at least this long to be logged.
+	SlowThreshold time.Duration `json:"slowThreshold,omitempty"`
+
+	// RedactBlobIDs causes only the leading characters of blob IDs and prefixes to be logged.
+	RedactBlobIDs bool `json:"redactBlobIDs,omitempty"`
+}
+
 type loggingStorage struct {
 	concurrency    atomic.Int32
 	maxConcurrency atomic.Int32
 
-	base   blob.Storage
-	prefix string
-	logger logging.Logger
+	base    blob.Storage
+	prefix  string
+	logger  logging.Logger
+	options Options
+
+	mu sync.Mutex
+	// +checklocks:mu
+	callCounts map[string]int
 }
 
 func (s *loggingStorage) beginConcurrency() {
@@ -50,13 +73,11 @@ func (s *loggingStorage) GetBlob(ctx context.Context, id blob.ID, offset, length
 	err := s.base.GetBlob(ctx, id, offset, length, output)
 	dt := timer.Elapsed()
 
-	s.logger.Debugw(s.prefix+"GetBlob",
-		"blobID", id,
+	s.log("GetBlob", dt, err,
+		"blobID", s.blobID(id),
 		"offset", offset,
 		"length", length,
 		"outputLength", output.Length(),
-		"error", s.translateError(err),
-		"duration", dt,
 	)
 
 	//nolint:wrapcheck
@@ -71,11 +92,9 @@ func (s *loggingStorage) GetCapacity(ctx context.Context) (blob.Capacity, error)
 	c, err := s.base.GetCapacity(ctx)
 	dt := timer.Elapsed()
 
-	s.logger.Debugw(s.prefix+"GetCapacity",
+	s.log("GetCapacity", dt, err,
 		"sizeBytes", c.SizeB,
 		"freeBytes", c.FreeB,
-		"error", s.translateError(err),
-		"duration", dt,
 	)
 
 	//nolint:wrapcheck
@@ -97,11 +116,9 @@ func (s *loggingStorage) GetMetadata(ctx context.Context, id blob.ID) (blob.Meta
 	result, err := s.base.GetMetadata(ctx, id)
 	dt := timer.Elapsed()
 
-	s.logger.Debugw(s.prefix+"GetMetadata",
-		"blobID", id,
-		"result", result,
-		"error", s.translateError(err),
-		"duration", dt,
+	s.log("GetMetadata", dt, err,
+		"blobID", s.blobID(id),
+		"result", s.metadata(result),
 	)
 
 	//nolint:wrapcheck
@@ -119,11 +136,9 @@ func (s *loggingStorage) PutBlob(ctx context.Context, id blob.ID, data blob.Byte
 	err := s.base.PutBlob(ctx, id, data, opts)
 	dt := timer.Elapsed()
 
-	s.logger.Debugw(s.prefix+"PutBlob",
-		"blobID", id,
+	s.log("PutBlob", dt, err,
+		"blobID", s.blobID(id),
 		"length", data.Length(),
-		"error", s.translateError(err),
-		"duration", dt,
 	)
 
 	//nolint:wrapcheck
@@ -141,10 +156,8 @@ func (s *loggingStorage) DeleteBlob(ctx context.Context, id blob.ID) error {
 	err := s.base.DeleteBlob(ctx, id)
 	dt := timer.Elapsed()
 
-	s.logger.Debugw(s.prefix+"DeleteBlob",
-		"blobID", id,
-		"error", s.translateError(err),
-		"duration", dt,
+	s.log("DeleteBlob", dt, err,
+		"blobID", s.blobID(id),
 	)
 	//nolint:wrapcheck
 	return err
@@ -165,11 +178,9 @@ func (s *loggingStorage) ListBlobs(ctx context.Context, prefix blob.ID, callback
 	})
 	dt := timer.Elapsed()
 
-	s.logger.Debugw(s.prefix+"ListBlobs",
-		"prefix", prefix,
+	s.log("ListBlobs", dt, err,
+		"prefix", s.blobID(prefix),
 		"resultCount", cnt,
-		"error", s.translateError(err),
-		"duration", dt,
 	)
 
 	//nolint:wrapcheck
@@ -184,10 +195,7 @@ func (s *loggingStorage) Close(ctx context.Context) error {
 	err := s.base.Close(ctx)
 	dt := timer.Elapsed()
 
-	s.logger.Debugw(s.prefix+"Close",
-		"error", s.translateError(err),
-		"duration", dt,
-	)
+	s.log("Close", dt, err)
 
 	//nolint:wrapcheck
 	return err
@@ -206,10 +214,7 @@ func (s *loggingStorage) FlushCaches(ctx context.Context) error {
 	err := s.base.FlushCaches(ctx)
 	dt := timer.Elapsed()
 
-	s.logger.Debugw(s.prefix+"FlushCaches",
-		"error", s.translateError(err),
-		"duration", dt,
-	)
+	s.log("FlushCaches", dt, err)
 
 	//nolint:wrapcheck
 	return err
@@ -226,15 +231,66 @@ func (s *loggingStorage) ExtendBlobRetention(ctx context.Context, b blob.ID, opt
 	err := s.base.ExtendBlobRetention(ctx, b, opts)
 	dt := timer.Elapsed()
 
-	s.logger.Debugw(s.prefix+"ExtendBlobRetention",
-		"blobID", b,
-		"error", err,
-		"duration", dt,
+	s.log("ExtendBlobRetention", dt, err,
+		"blobID", s.blobID(b),
 	)
 	//nolint:wrapcheck
 	return err
 }
 
+// log emits the log entry for the completed call unless filtered out by options.
+func (s *loggingStorage) log(method string, dt time.Duration, err error, keysAndValues ...interface{}) {
+	if !s.shouldLog(method, dt, err) {
+		return
+	}
+
+	s.logger.Debugw(s.prefix+method, append(keysAndValues,
+		"error", s.translateError(err),
+		"duration", dt,
+	)...)
+}
+
+// shouldLog determines whether the call should be logged, failed calls are always logged.
+func (s *loggingStorage) shouldLog(method string, dt time.Duration, err error) bool {
+	if err != nil {
+		return true
+	}
+
+	if dt < s.options.SlowThreshold {
+		return false
+	}
+
+	if s.options.SampleEvery <= 1 {
+		return true
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	n := s.callCounts[method]
+	s.callCounts[method] = n + 1
+
+	return n%s.options.SampleEvery == 0
+}
+
+func (s *loggingStorage) blobID(id blob.ID) blob.ID {
+	if !s.options.RedactBlobIDs || len(id) <= redactedBlobIDPrefixLength {
+		return id
+	}
+
+	return blob.ID(fmt.Sprintf("%v...(%v)", id[0:redactedBlobIDPrefixLength], len(id)))
+}
+
+func (s *loggingStorage) metadata(bm blob.Metadata) interface{} {
+	if !s.options.RedactBlobIDs {
+		return bm
+	}
+
+	bm.BlobID = s.blobID(bm.BlobID)
+
+	return bm
+}
+
 func (s *loggingStorage) translateError(err error) interface{} {
 	if err == nil {
 		return nil
@@ -249,5 +305,16 @@ func (s *loggingStorage) translateError(err error) interface{} {
 
 // NewWrapper returns a Storage wrapper that logs all storage commands.
 func NewWrapper(wrapped blob.Storage, logger logging.Logger, prefix string) blob.Storage {
-	return &loggingStorage{base: wrapped, logger: logger, prefix: prefix}
+	return NewWrapperWithOptions(wrapped, logger, prefix, Options{})
+}
+
+// NewWrapperWithOptions returns a Storage wrapper that logs storage commands selected by the provided options.
+func NewWrapperWithOptions(wrapped blob.Storage, logger logging.Logger, prefix string, opts Options) blob.Storage {
+	return &loggingStorage{
+		base:       wrapped,
+		logger:     logger,
+		prefix:     prefix,
+		options:    opts,
+		callCounts: map[string]int{},
+	}
 }
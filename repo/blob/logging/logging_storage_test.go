@@ -3,11 +3,16 @@
 import (
 	"fmt"
 	"strings"
+	"sync"
 	"sync/atomic"
 	"testing"
 	"time"
 
+	"github.com/stretchr/testify/require"
+
 	"github.com/kopia/kopia/internal/blobtesting"
+	"github.com/kopia/kopia/internal/clock"
+	"github.com/kopia/kopia/internal/gather"
 	"github.com/kopia/kopia/internal/testlogging"
 	"github.com/kopia/kopia/repo/blob"
 	"github.com/kopia/kopia/repo/blob/logging"
@@ -51,3 +56,87 @@ func TestLoggingStorage(t *testing.T) {
 		t.Errorf("unexpected connection infor %v, want %v", got, want)
 	}
 }
+
+func TestLoggingStorage_Options(t *testing.T) {
+	ctx := testlogging.Context(t)
+
+	// all cases include a single log entry about concurrency level.
+	cases := []struct {
+		desc    string
+		opts    logging.Options
+		wantLog []string
+		notLog  []string
+		count   int
+	}{
+		{
+			desc:    "default",
+			wantLog: []string{"PutBlob", "p0123456784"},
+			count:   8,
+		},
+		{
+			desc:    "sampled",
+			opts:    logging.Options{SampleEvery: 3},
+			wantLog: []string{"PutBlob", "GetBlob"},
+			// 2 of 5 puts, 1 of 1 get and 1 failed get
+			count: 5,
+		},
+		{
+			desc:    "slow-only",
+			opts:    logging.Options{SlowThreshold: time.Hour},
+			wantLog: []string{"GetBlob", "BLOB not found"},
+			notLog:  []string{"PutBlob"},
+			// only the failed get
+			count: 2,
+		},
+		{
+			desc:    "redacted",
+			opts:    logging.Options{RedactBlobIDs: true},
+			wantLog: []string{"p012...(11)"},
+			notLog:  []string{"p0123456784"},
+			count:   8,
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.desc, func(t *testing.T) {
+			var (
+				mu     sync.Mutex
+				output []string
+			)
+
+			st := logging.NewWrapperWithOptions(
+				blobtesting.NewMapStorage(blobtesting.DataMap{}, nil, clock.Now),
+				testlogging.Printf(func(msg string, args ...interface{}) {
+					mu.Lock()
+					defer mu.Unlock()
+
+					output = append(output, fmt.Sprintf(msg, args...))
+				}, ""), "", tc.opts)
+
+			for i := range 5 {
+				require.NoError(t, st.PutBlob(ctx, blob.ID(fmt.Sprintf("p012345678%v", i)), gather.FromSlice([]byte{1}), blob.PutOptions{}))
+			}
+
+			var tmp gather.WriteBuffer
+			defer tmp.Close()
+
+			require.NoError(t, st.GetBlob(ctx, "p0123456780", 0, -1, &tmp))
+			require.ErrorIs(t, st.GetBlob(ctx, "no-such-blob", 0, -1, &tmp), blob.ErrBlobNotFound)
+
+			mu.Lock()
+			defer mu.Unlock()
+
+			all := strings.Join(output, "\n")
+
+			require.Len(t, output, tc.count, all)
+
+			for _, w := range tc.wantLog {
+				require.Contains(t, all, w)
+			}
+
+			for _, w := range tc.notLog {
+				require.NotContains(t, all, w)
+			}
+		})
+	}
+}
@@ -0,0 +1,177 @@
+package mirror
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"os"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+
+	"github.com/kopia/kopia/internal/atomicfile"
+	"github.com/kopia/kopia/repo/blob"
+)
+
+// journal operations.
+const (
+	journalOpPut    = "put"
+	journalOpDelete = "delete"
+	journalOpDone   = "done"
+)
+
+// journalEntry describes an operation pending replication to the secondary storage or completion of one.
+type journalEntry struct {
+	Seq    int64   `json:"seq"`
+	Op     string  `json:"op"`
+	BlobID blob.ID `json:"id,omitempty"`
+
+	RetentionMode   blob.RetentionMode `json:"retentionMode,omitempty"`
+	RetentionPeriod time.Duration      `json:"retentionPeriod,omitempty"`
+}
+
+// journal is an append-only file recording operations which have not been replicated yet,
+// so that replication can be resumed after the process is restarted.
+type journal struct {
+	mu sync.Mutex
+	// +checklocks:mu
+	f *os.File
+	// +checklocks:mu
+	nextSeq int64
+}
+
+// add durably records the operation and assigns it a sequence number.
+func (j *journal) add(e journalEntry) (journalEntry, error) {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	e.Seq = j.nextSeq
+	j.nextSeq++
+
+	if err := j.writeLocked(e); err != nil {
+		return e, err
+	}
+
+	return e, errors.Wrap(j.f.Sync(), "error syncing replication journal")
+}
+
+// done records completion of the operation with the provided sequence number. It's not synced,
+// since losing it only causes the operation to be replicated again.
+func (j *journal) done(seq int64) error {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	return j.writeLocked(journalEntry{Seq: seq, Op: journalOpDone})
+}
+
+// +checklocks:j.mu
+func (j *journal) writeLocked(e journalEntry) error {
+	b, err := json.Marshal(e)
+	if err != nil {
+		return errors.Wrap(err, "error serializing journal entry")
+	}
+
+	_, err = j.f.Write(append(b, '\n'))
+
+	return errors.Wrap(err, "error writing replication journal")
+}
+
+func (j *journal) close() error {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	return errors.Wrap(j.f.Close(), "error closing replication journal")
+}
+
+// readPendingJournalEntries returns operations from the journal file which have not been completed, ordered by sequence number.
+func readPendingJournalEntries(path string) ([]journalEntry, error) {
+	f, err := os.Open(path) //nolint:gosec
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+
+	if err != nil {
+		return nil, errors.Wrap(err, "error opening replication journal")
+	}
+
+	defer f.Close() //nolint:errcheck
+
+	pending := map[int64]journalEntry{}
+
+	s := bufio.NewScanner(f)
+	s.Buffer(nil, 1<<20) //nolint:mnd
+
+	for s.Scan() {
+		var e journalEntry
+
+		if err := json.Unmarshal(s.Bytes(), &e); err != nil {
+			// the last entry may have been partially written when the process was interrupted.
+			break
+		}
+
+		if e.Op == journalOpDone {
+			delete(pending, e.Seq)
+		} else {
+			pending[e.Seq] = e
+		}
+	}
+
+	if err := s.Err(); err != nil {
+		return nil, errors.Wrap(err, "error reading replication journal")
+	}
+
+	var result []journalEntry
+
+	for _, e := range pending {
+		result = append(result, e)
+	}
+
+	sort.Slice(result, func(i, k int) bool {
+		return result[i].Seq < result[k].Seq
+	})
+
+	return result, nil
+}
+
+// openJournal opens the journal at the provided path, returning operations pending replication.
+// Completed operations are removed from the journal.
+func openJournal(path string) (*journal, []journalEntry, error) {
+	pending, err := readPendingJournalEntries(path)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	j := &journal{}
+
+	// rewrite the journal with only pending entries, renumbered from zero.
+	var buf []byte
+
+	for i := range pending {
+		pending[i].Seq = int64(i)
+
+		b, err := json.Marshal(pending[i])
+		if err != nil {
+			return nil, nil, errors.Wrap(err, "error serializing journal entry")
+		}
+
+		buf = append(append(buf, b...), '\n')
+	}
+
+	if err := atomicfile.Write(path, bytes.NewReader(buf)); err != nil {
+		return nil, nil, errors.Wrap(err, "error compacting replication journal")
+	}
+
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_APPEND, 0) //nolint:gosec
+	if err != nil {
+		return nil, nil, errors.Wrap(err, "error opening replication journal")
+	}
+
+	j.mu.Lock()
+	j.f = f
+	j.nextSeq = int64(len(pending))
+	j.mu.Unlock()
+
+	return j, pending, nil
+}
@@ -0,0 +1,38 @@
+package mirror
+
+import (
+	"time"
+
+	"github.com/kopia/kopia/repo/blob"
+)
+
+// ReplicationOptions controls how writes are replicated to the secondary storage.
+type ReplicationOptions struct {
+	// Async causes writes to complete once they're written to the primary storage and recorded in the
+	// journal, replicating them to the secondary storage in the background.
+	Async bool `json:"async,omitempty"`
+
+	// JournalPath is the path to the local file recording writes pending replication, required if Async is set.
+	JournalPath string `json:"journalPath,omitempty"`
+
+	// Concurrency is the number of blobs replicated in parallel in background, defaults to 4.
+	Concurrency int `json:"concurrency,omitempty"`
+
+	// QueueSize is the number of operations queued for replication by each worker, defaults to 1000.
+	QueueSize int `json:"queueSize,omitempty"`
+
+	// EnqueueTimeout is how long a write waits for space in a full replication queue, defaults to 10 seconds.
+	// Operations which can't be queued in time remain in the journal and are replicated after the storage is reopened.
+	EnqueueTimeout time.Duration `json:"enqueueTimeout,omitempty"`
+}
+
+// Options defines options for mirrored storage.
+type Options struct {
+	// Primary is the storage which all reads are served from, as long as it's available.
+	Primary blob.ConnectionInfo `json:"primary"`
+
+	// Secondary is the storage which receives copies of all writes.
+	Secondary blob.ConnectionInfo `json:"secondary"`
+
+	ReplicationOptions
+}
@@ -0,0 +1,451 @@
+// Package mirror implements Storage which writes all blobs to both primary and secondary storage
+// and reads them from the primary storage, falling back to the secondary one when the primary one
+// is unavailable.
+//
+// Writes are replicated synchronously or, when enabled, asynchronously with pending writes recorded
+// in a local journal, which allows replication to resume after the process is restarted.
+package mirror
+
+import (
+	"context"
+	"fmt"
+	"hash/fnv"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/pkg/errors"
+
+	"github.com/kopia/kopia/internal/gather"
+	"github.com/kopia/kopia/repo/blob"
+	"github.com/kopia/kopia/repo/logging"
+)
+
+var log = logging.Module("mirror")
+
+const (
+	mirrorStorageType = "mirror"
+
+	defaultConcurrency    = 4
+	defaultQueueSize      = 1000
+	defaultEnqueueTimeout = 10 * time.Second
+
+	minReplicationRetryDelay = time.Second
+	maxReplicationRetryDelay = time.Minute
+)
+
+type mirrorStorage struct {
+	blob.Storage // primary
+
+	secondary blob.Storage
+
+	// connectionInfo is set when the storage was created from Options.
+	connectionInfo *blob.ConnectionInfo
+
+	// async replication.
+	journal        *journal
+	queues         []chan journalEntry
+	enqueueTimeout time.Duration
+	spilled        atomic.Bool // some operations were left in the journal because queues were full
+	cancel         context.CancelFunc
+	wg             sync.WaitGroup
+}
+
+// shouldFallBack returns true if the error returned by the primary storage indicates that it's unavailable,
+// as opposed to errors such as a missing blob, which the secondary storage is expected to return as well.
+func shouldFallBack(ctx context.Context, err error) bool {
+	return ctx.Err() == nil && !errors.Is(err, blob.ErrBlobNotFound) && !errors.Is(err, blob.ErrInvalidRange)
+}
+
+func (s *mirrorStorage) GetBlob(ctx context.Context, id blob.ID, offset, length int64, output blob.OutputBuffer) error {
+	err := s.Storage.GetBlob(ctx, id, offset, length, output)
+	if err == nil || !shouldFallBack(ctx, err) {
+		//nolint:wrapcheck
+		return err
+	}
+
+	output.Reset()
+
+	if err2 := s.secondary.GetBlob(ctx, id, offset, length, output); err2 != nil {
+		//nolint:wrapcheck
+		return err
+	}
+
+	log(ctx).Debugf("read %v from secondary storage after primary failed: %v", id, err)
+
+	return nil
+}
+
+func (s *mirrorStorage) GetMetadata(ctx context.Context, id blob.ID) (blob.Metadata, error) {
+	bm, err := s.Storage.GetMetadata(ctx, id)
+	if err == nil || !shouldFallBack(ctx, err) {
+		//nolint:wrapcheck
+		return bm, err
+	}
+
+	bm2, err2 := s.secondary.GetMetadata(ctx, id)
+	if err2 != nil {
+		//nolint:wrapcheck
+		return bm, err
+	}
+
+	return bm2, nil
+}
+
+func (s *mirrorStorage) ListBlobs(ctx context.Context, prefix blob.ID, callback func(blob.Metadata) error) error {
+	listed := false
+
+	err := s.Storage.ListBlobs(ctx, prefix, func(bm blob.Metadata) error {
+		listed = true
+		return callback(bm)
+	})
+	if err == nil || listed || !shouldFallBack(ctx, err) {
+		//nolint:wrapcheck
+		return err
+	}
+
+	log(ctx).Debugf("listing %q from secondary storage after primary failed: %v", prefix, err)
+
+	//nolint:wrapcheck
+	return s.secondary.ListBlobs(ctx, prefix, callback)
+}
+
+func (s *mirrorStorage) PutBlob(ctx context.Context, id blob.ID, data blob.Bytes, opts blob.PutOptions) error {
+	if err := s.Storage.PutBlob(ctx, id, data, opts); err != nil {
+		//nolint:wrapcheck
+		return err
+	}
+
+	if s.journal != nil {
+		return s.enqueue(ctx, journalEntry{
+			Op:              journalOpPut,
+			BlobID:          id,
+			RetentionMode:   opts.RetentionMode,
+			RetentionPeriod: opts.RetentionPeriod,
+		})
+	}
+
+	opts2 := opts
+	opts2.GetModTime = nil
+
+	if err := s.secondary.PutBlob(ctx, id, data, opts2); err != nil && !(opts.DoNotRecreate && errors.Is(err, blob.ErrBlobAlreadyExists)) {
+		return errors.Wrapf(err, "error writing %v to secondary storage", id)
+	}
+
+	return nil
+}
+
+func (s *mirrorStorage) DeleteBlob(ctx context.Context, id blob.ID) error {
+	if err := s.Storage.DeleteBlob(ctx, id); err != nil {
+		//nolint:wrapcheck
+		return err
+	}
+
+	if s.journal != nil {
+		return s.enqueue(ctx, journalEntry{Op: journalOpDelete, BlobID: id})
+	}
+
+	return s.deleteFromSecondary(ctx, id)
+}
+
+func (s *mirrorStorage) ExtendBlobRetention(ctx context.Context, id blob.ID, opts blob.ExtendOptions) error {
+	if err := s.Storage.ExtendBlobRetention(ctx, id, opts); err != nil {
+		//nolint:wrapcheck
+		return err
+	}
+
+	err := s.secondary.ExtendBlobRetention(ctx, id, opts)
+	if err != nil && s.journal != nil {
+		// the blob may not have been replicated yet, in which case it will be written with retention.
+		log(ctx).Debugf("unable to extend retention of %v in secondary storage: %v", id, err)
+
+		return nil
+	}
+
+	return errors.Wrapf(err, "error extending retention of %v in secondary storage", id)
+}
+
+func (s *mirrorStorage) FlushCaches(ctx context.Context) error {
+	if err := s.Storage.FlushCaches(ctx); err != nil {
+		//nolint:wrapcheck
+		return err
+	}
+
+	//nolint:wrapcheck
+	return s.secondary.FlushCaches(ctx)
+}
+
+func (s *mirrorStorage) ConnectionInfo() blob.ConnectionInfo {
+	if s.connectionInfo != nil {
+		return *s.connectionInfo
+	}
+
+	return s.Storage.ConnectionInfo()
+}
+
+func (s *mirrorStorage) DisplayName() string {
+	return fmt.Sprintf("Mirror: %v, %v", s.Storage.DisplayName(), s.secondary.DisplayName())
+}
+
+func (s *mirrorStorage) Close(ctx context.Context) error {
+	if s.journal != nil {
+		// stop replication, writes which have not been replicated remain in the journal.
+		s.cancel()
+		s.wg.Wait()
+
+		if err := s.journal.close(); err != nil {
+			return err
+		}
+	}
+
+	err := s.Storage.Close(ctx)
+
+	if err2 := s.secondary.Close(ctx); err == nil {
+		err = err2
+	}
+
+	//nolint:wrapcheck
+	return err
+}
+
+func (s *mirrorStorage) deleteFromSecondary(ctx context.Context, id blob.ID) error {
+	if err := s.secondary.DeleteBlob(ctx, id); err != nil && !errors.Is(err, blob.ErrBlobNotFound) {
+		return errors.Wrapf(err, "error deleting %v from secondary storage", id)
+	}
+
+	return nil
+}
+
+// enqueue records the operation in the journal and schedules its replication. All operations on
+// the same blob are replicated by the same worker, so they're applied in order.
+//
+// When the queue remains full for longer than the enqueue timeout, for example because the secondary
+// storage is unavailable, the operation is only left in the journal and replicated after the storage is reopened.
+func (s *mirrorStorage) enqueue(ctx context.Context, e journalEntry) error {
+	e, err := s.journal.add(e)
+	if err != nil {
+		return err
+	}
+
+	q := s.queueFor(e.BlobID)
+
+	select {
+	case q <- e:
+		return nil
+	default:
+	}
+
+	t := time.NewTimer(s.enqueueTimeout)
+	defer t.Stop()
+
+	select {
+	case q <- e:
+	case <-t.C:
+		if !s.spilled.Swap(true) {
+			log(ctx).Warnf("replication to secondary storage is falling behind, remaining writes will be replicated after the storage is reopened")
+		}
+	case <-ctx.Done():
+		// the operation remains in the journal.
+	}
+
+	return nil
+}
+
+func (s *mirrorStorage) queueFor(id blob.ID) chan journalEntry {
+	h := fnv.New32a()
+	h.Write([]byte(id)) //nolint:errcheck
+
+	return s.queues[h.Sum32()%uint32(len(s.queues))] //nolint:gosec
+}
+
+func (s *mirrorStorage) replicationWorker(ctx context.Context, queue chan journalEntry) {
+	defer s.wg.Done()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+
+		case e := <-queue:
+			if !s.replicateWithRetry(ctx, e) {
+				return
+			}
+
+			if err := s.journal.done(e.Seq); err != nil {
+				log(ctx).Errorf("unable to record replication of %v: %v", e.BlobID, err)
+			}
+		}
+	}
+}
+
+// replicateWithRetry replicates the operation, retrying until it succeeds or the context is canceled.
+func (s *mirrorStorage) replicateWithRetry(ctx context.Context, e journalEntry) bool {
+	delay := minReplicationRetryDelay
+
+	for {
+		err := s.replicate(ctx, e)
+		if err == nil {
+			return true
+		}
+
+		log(ctx).Warnf("unable to replicate %v of %v to secondary storage, retrying in %v: %v", e.Op, e.BlobID, delay, err)
+
+		select {
+		case <-ctx.Done():
+			return false
+		case <-time.After(delay):
+		}
+
+		delay = min(2*delay, maxReplicationRetryDelay)
+	}
+}
+
+func (s *mirrorStorage) replicate(ctx context.Context, e journalEntry) error {
+	switch e.Op {
+	case journalOpPut:
+		var buf gather.WriteBuffer
+		defer buf.Close()
+
+		err := s.Storage.GetBlob(ctx, e.BlobID, 0, -1, &buf)
+		if errors.Is(err, blob.ErrBlobNotFound) {
+			// deleted since, the deletion is replicated separately.
+			return nil
+		}
+
+		if err != nil {
+			return errors.Wrap(err, "error reading from primary storage")
+		}
+
+		//nolint:wrapcheck
+		return s.secondary.PutBlob(ctx, e.BlobID, buf.Bytes(), blob.PutOptions{
+			RetentionMode:   e.RetentionMode,
+			RetentionPeriod: e.RetentionPeriod,
+		})
+
+	case journalOpDelete:
+		// the deletion may be replicated after the blob was written again, if it was left in the journal.
+		_, err := s.Storage.GetMetadata(ctx, e.BlobID)
+
+		switch {
+		case err == nil:
+			return nil
+		case !errors.Is(err, blob.ErrBlobNotFound):
+			return errors.Wrap(err, "error reading from primary storage")
+		}
+
+		return s.deleteFromSecondary(ctx, e.BlobID)
+
+	default:
+		log(ctx).Errorf("invalid replication journal entry: %v", e.Op)
+
+		return nil
+	}
+}
+
+// NewWrapper returns Storage which writes all blobs to both primary and secondary storage and reads them
+// from the primary storage, falling back to the secondary one. The returned storage owns both storages and
+// closes them when it's closed.
+func NewWrapper(ctx context.Context, primary, secondary blob.Storage, opts ReplicationOptions) (blob.Storage, error) {
+	s := &mirrorStorage{
+		Storage:   primary,
+		secondary: secondary,
+	}
+
+	if !opts.Async {
+		return s, nil
+	}
+
+	if opts.JournalPath == "" {
+		return nil, errors.New("journal path must be specified for asynchronous replication")
+	}
+
+	j, pending, err := openJournal(opts.JournalPath)
+	if err != nil {
+		return nil, err
+	}
+
+	concurrency := opts.Concurrency
+	if concurrency <= 0 {
+		concurrency = defaultConcurrency
+	}
+
+	queueSize := opts.QueueSize
+	if queueSize <= 0 {
+		queueSize = defaultQueueSize
+	}
+
+	s.enqueueTimeout = opts.EnqueueTimeout
+	if s.enqueueTimeout <= 0 {
+		s.enqueueTimeout = defaultEnqueueTimeout
+	}
+
+	// replication continues in background until the storage is closed.
+	ctx, cancel := context.WithCancel(context.WithoutCancel(ctx))
+
+	s.journal = j
+	s.cancel = cancel
+
+	for range concurrency {
+		q := make(chan journalEntry, queueSize)
+		s.queues = append(s.queues, q)
+
+		s.wg.Add(1)
+
+		go s.replicationWorker(ctx, q)
+	}
+
+	if len(pending) > 0 {
+		log(ctx).Infof("resuming replication of %v operations to secondary storage", len(pending))
+	}
+
+	// enqueue operations from the previous session in background, since they may exceed the capacity of queues.
+	s.wg.Add(1)
+
+	go func() {
+		defer s.wg.Done()
+
+		for _, e := range pending {
+			select {
+			case s.queueFor(e.BlobID) <- e:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+
+	return s, nil
+}
+
+// New creates new mirrored storage with the provided options.
+func New(ctx context.Context, opt *Options, isCreate bool) (blob.Storage, error) {
+	primary, err := blob.NewStorage(ctx, opt.Primary, isCreate)
+	if err != nil {
+		return nil, errors.Wrap(err, "unable to open primary storage")
+	}
+
+	secondary, err := blob.NewStorage(ctx, opt.Secondary, isCreate)
+	if err != nil {
+		primary.Close(ctx) //nolint:errcheck
+		return nil, errors.Wrap(err, "unable to open secondary storage")
+	}
+
+	st, err := NewWrapper(ctx, primary, secondary, opt.ReplicationOptions)
+	if err != nil {
+		primary.Close(ctx)   //nolint:errcheck
+		secondary.Close(ctx) //nolint:errcheck
+
+		return nil, err
+	}
+
+	//nolint:forcetypeassert
+	st.(*mirrorStorage).connectionInfo = &blob.ConnectionInfo{
+		Type:   mirrorStorageType,
+		Config: opt,
+	}
+
+	return st, nil
+}
+
+func init() {
+	blob.AddSupportedStorage(mirrorStorageType, Options{}, New)
+}
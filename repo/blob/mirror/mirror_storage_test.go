@@ -0,0 +1,207 @@
+package mirror_test
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/require"
+
+	"github.com/kopia/kopia/internal/blobtesting"
+	"github.com/kopia/kopia/internal/gather"
+	"github.com/kopia/kopia/internal/testlogging"
+	"github.com/kopia/kopia/repo/blob"
+	"github.com/kopia/kopia/repo/blob/mirror"
+)
+
+func TestMirrorStorage(t *testing.T) {
+	t.Parallel()
+
+	ctx := testlogging.Context(t)
+
+	primary := blobtesting.DataMap{}
+	secondary := blobtesting.DataMap{}
+
+	st, err := mirror.NewWrapper(ctx,
+		blobtesting.NewMapStorage(primary, nil, nil),
+		blobtesting.NewMapStorage(secondary, nil, nil),
+		mirror.ReplicationOptions{})
+	require.NoError(t, err)
+
+	defer st.Close(ctx)
+
+	blobtesting.VerifyStorage(ctx, t, st, blob.PutOptions{})
+	require.Equal(t, primary, secondary)
+
+	require.NoError(t, st.PutBlob(ctx, "foo", gather.FromSlice([]byte{1, 2, 3}), blob.PutOptions{}))
+	require.Equal(t, []byte{1, 2, 3}, secondary["foo"])
+
+	require.NoError(t, st.DeleteBlob(ctx, "foo"))
+	require.NotContains(t, secondary, "foo")
+}
+
+func TestMirrorStorageReadFallback(t *testing.T) {
+	t.Parallel()
+
+	ctx := testlogging.Context(t)
+
+	primary := blobtesting.NewFaultyStorage(blobtesting.NewMapStorage(blobtesting.DataMap{
+		"foo": []byte{1, 2, 3},
+	}, nil, nil))
+	secondary := blobtesting.NewMapStorage(blobtesting.DataMap{
+		"foo": []byte{1, 2, 3},
+		"bar": []byte{4, 5, 6},
+	}, nil, nil)
+
+	st, err := mirror.NewWrapper(ctx, primary, secondary, mirror.ReplicationOptions{})
+	require.NoError(t, err)
+
+	defer st.Close(ctx)
+
+	var tmp gather.WriteBuffer
+	defer tmp.Close()
+
+	// primary storage is unavailable.
+	someErr := errors.New("some error")
+	primary.AddFault(blobtesting.MethodGetBlob).ErrorInstead(someErr)
+	primary.AddFault(blobtesting.MethodGetMetadata).ErrorInstead(someErr)
+	primary.AddFault(blobtesting.MethodListBlobs).ErrorInstead(someErr)
+
+	require.NoError(t, st.GetBlob(ctx, "foo", 0, -1, &tmp))
+	require.Equal(t, []byte{1, 2, 3}, tmp.ToByteSlice())
+
+	bm, err := st.GetMetadata(ctx, "foo")
+	require.NoError(t, err)
+	require.EqualValues(t, 3, bm.Length)
+
+	bms, err := blob.ListAllBlobs(ctx, st, "")
+	require.NoError(t, err)
+	require.Equal(t, []blob.ID{"bar", "foo"}, blob.IDsFromMetadata(bms))
+
+	primary.VerifyAllFaultsExercised(t)
+
+	// blobs missing from the primary storage are not read from the secondary one.
+	require.ErrorIs(t, st.GetBlob(ctx, "bar", 0, -1, &tmp), blob.ErrBlobNotFound)
+
+	_, err = st.GetMetadata(ctx, "bar")
+	require.ErrorIs(t, err, blob.ErrBlobNotFound)
+
+	// errors from the primary storage are returned when both fail.
+	primary.AddFault(blobtesting.MethodGetBlob).ErrorInstead(someErr)
+	require.ErrorIs(t, st.GetBlob(ctx, "baz", 0, -1, &tmp), someErr)
+}
+
+func TestMirrorStorageAsync(t *testing.T) {
+	t.Parallel()
+
+	ctx := testlogging.Context(t)
+
+	journalPath := filepath.Join(t.TempDir(), "journal")
+
+	primary := blobtesting.DataMap{}
+	secondary := blobtesting.DataMap{}
+	secondarySt := blobtesting.NewFaultyStorage(blobtesting.NewMapStorage(secondary, nil, nil))
+
+	// secondary storage is unavailable.
+	someErr := errors.New("some error")
+	secondarySt.AddFault(blobtesting.MethodPutBlob).ErrorInstead(someErr).Repeat(1000)
+
+	st, err := mirror.NewWrapper(ctx,
+		blobtesting.NewMapStorage(primary, nil, nil),
+		secondarySt,
+		mirror.ReplicationOptions{Async: true, JournalPath: journalPath})
+	require.NoError(t, err)
+
+	require.NoError(t, st.PutBlob(ctx, "foo", gather.FromSlice([]byte{1, 2, 3}), blob.PutOptions{}))
+	require.NoError(t, st.PutBlob(ctx, "bar", gather.FromSlice([]byte{4, 5, 6}), blob.PutOptions{}))
+	require.NoError(t, st.Close(ctx))
+
+	require.Empty(t, secondary)
+
+	// replication is resumed from the journal after reopening.
+	secondarySt2 := blobtesting.NewMapStorage(secondary, nil, nil)
+
+	st, err = mirror.NewWrapper(ctx,
+		blobtesting.NewMapStorage(primary, nil, nil),
+		secondarySt2,
+		mirror.ReplicationOptions{Async: true, JournalPath: journalPath})
+	require.NoError(t, err)
+
+	defer st.Close(ctx)
+
+	require.NoError(t, st.DeleteBlob(ctx, "bar"))
+	require.NoError(t, st.PutBlob(ctx, "baz", gather.FromSlice([]byte{7, 8, 9}), blob.PutOptions{}))
+
+	require.Eventually(t, func() bool {
+		bms, err := blob.ListAllBlobs(ctx, secondarySt2, "")
+		require.NoError(t, err)
+
+		return len(bms) == 2 && bms[0].BlobID == "baz" && bms[1].BlobID == "foo"
+	}, 5*time.Second, 10*time.Millisecond)
+}
+
+func TestMirrorStorageAsyncQueueFull(t *testing.T) {
+	t.Parallel()
+
+	ctx := testlogging.Context(t)
+
+	journalPath := filepath.Join(t.TempDir(), "journal")
+
+	primary := blobtesting.DataMap{}
+	secondary := blobtesting.DataMap{}
+	secondarySt := blobtesting.NewFaultyStorage(blobtesting.NewMapStorage(secondary, nil, nil))
+
+	// secondary storage is unavailable.
+	secondarySt.AddFault(blobtesting.MethodPutBlob).ErrorInstead(errors.New("some error")).Repeat(1000)
+
+	st, err := mirror.NewWrapper(ctx,
+		blobtesting.NewMapStorage(primary, nil, nil),
+		secondarySt,
+		mirror.ReplicationOptions{
+			Async:          true,
+			JournalPath:    journalPath,
+			Concurrency:    1,
+			QueueSize:      1,
+			EnqueueTimeout: 10 * time.Millisecond,
+		})
+	require.NoError(t, err)
+
+	// writes don't block once the queue is full.
+	for _, id := range []blob.ID{"a", "b", "c", "d"} {
+		require.NoError(t, st.PutBlob(ctx, id, gather.FromSlice([]byte{1, 2, 3}), blob.PutOptions{}))
+	}
+
+	require.NoError(t, st.Close(ctx))
+	require.Empty(t, secondary)
+
+	// writes which were not queued are replicated after reopening.
+	secondarySt2 := blobtesting.NewMapStorage(secondary, nil, nil)
+
+	st, err = mirror.NewWrapper(ctx,
+		blobtesting.NewMapStorage(primary, nil, nil),
+		secondarySt2,
+		mirror.ReplicationOptions{Async: true, JournalPath: journalPath})
+	require.NoError(t, err)
+
+	defer st.Close(ctx)
+
+	require.Eventually(t, func() bool {
+		bms, err := blob.ListAllBlobs(ctx, secondarySt2, "")
+		require.NoError(t, err)
+
+		return len(bms) == 4
+	}, 5*time.Second, 10*time.Millisecond)
+}
+
+func TestMirrorStorageAsyncRequiresJournal(t *testing.T) {
+	t.Parallel()
+
+	ctx := testlogging.Context(t)
+
+	_, err := mirror.NewWrapper(ctx,
+		blobtesting.NewMapStorage(blobtesting.DataMap{}, nil, nil),
+		blobtesting.NewMapStorage(blobtesting.DataMap{}, nil, nil),
+		mirror.ReplicationOptions{Async: true})
+	require.Error(t, err)
+}
@@ -0,0 +1,215 @@
+// Package quota implements wrapper around blob.Storage which enforces the maximum size of the repository.
+//
+// The size of the storage is determined by listing all blobs when the quota is first checked and
+// tracked incrementally afterwards by adding the sizes of blobs written and subtracting the sizes of
+// blobs deleted. The storage is listed again periodically to account for changes made by other clients.
+package quota
+
+import (
+	"context"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/alecthomas/units"
+	"github.com/pkg/errors"
+
+	"github.com/kopia/kopia/internal/clock"
+	"github.com/kopia/kopia/repo/blob"
+	"github.com/kopia/kopia/repo/logging"
+)
+
+var log = logging.Module("quota")
+
+// ErrQuotaExceeded is returned by PutBlob when writing the blob would exceed the storage quota.
+var ErrQuotaExceeded = errors.New("storage quota exceeded")
+
+const defaultRefreshInterval = time.Hour
+
+// Options controls the behavior of the quota wrapper.
+type Options struct {
+	// MaxBytes is the maximum total size of all blobs in the storage.
+	MaxBytes int64
+
+	// WarnOnly causes writes exceeding the quota to only log a warning instead of failing.
+	WarnOnly bool
+
+	// Prefixes limits enforcement of the quota to writes of blobs with the provided prefixes,
+	// all writes are subject to the quota if empty. Blobs of all prefixes count towards the size.
+	Prefixes []blob.ID
+
+	// RefreshInterval is the interval between listings of all blobs, defaults to 1 hour.
+	RefreshInterval time.Duration
+
+	TimeNow func() time.Time
+}
+
+type quotaStorage struct {
+	blob.Storage
+
+	opts Options
+
+	// serializes listings of the storage, which are performed without holding mu.
+	listMu sync.Mutex
+
+	mu sync.Mutex
+	// +checklocks:mu
+	size int64 // approximate total size of blobs, valid once listed
+	// +checklocks:mu
+	lastListed time.Time // zero if never listed
+	// +checklocks:mu
+	warned bool
+}
+
+func (s *quotaStorage) PutBlob(ctx context.Context, id blob.ID, data blob.Bytes, opts blob.PutOptions) error {
+	if s.isEnforced(id) {
+		if err := s.checkQuota(ctx, id, int64(data.Length())); err != nil {
+			return err
+		}
+	}
+
+	if err := s.Storage.PutBlob(ctx, id, data, opts); err != nil {
+		//nolint:wrapcheck
+		return err
+	}
+
+	s.addSize(int64(data.Length()))
+
+	return nil
+}
+
+// DeleteBlob deletes the blob and subtracts its size, which requires getting its metadata first.
+func (s *quotaStorage) DeleteBlob(ctx context.Context, id blob.ID) error {
+	bm, err := s.Storage.GetMetadata(ctx, id)
+	if err != nil && !errors.Is(err, blob.ErrBlobNotFound) {
+		//nolint:wrapcheck
+		return err
+	}
+
+	if err := s.Storage.DeleteBlob(ctx, id); err != nil {
+		//nolint:wrapcheck
+		return err
+	}
+
+	s.addSize(-bm.Length)
+
+	return nil
+}
+
+func (s *quotaStorage) addSize(delta int64) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.size += delta
+}
+
+func (s *quotaStorage) isEnforced(id blob.ID) bool {
+	if len(s.opts.Prefixes) == 0 {
+		return true
+	}
+
+	for _, p := range s.opts.Prefixes {
+		if strings.HasPrefix(string(id), string(p)) {
+			return true
+		}
+	}
+
+	return false
+}
+
+func (s *quotaStorage) checkQuota(ctx context.Context, id blob.ID, length int64) error {
+	if err := s.maybeRefresh(ctx); err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.size+length <= s.opts.MaxBytes {
+		s.warned = false
+		return nil
+	}
+
+	size := units.Base2Bytes(s.size)
+	limit := units.Base2Bytes(s.opts.MaxBytes)
+
+	if s.opts.WarnOnly {
+		if !s.warned {
+			log(ctx).Warnf("storage size (approximately %v) exceeds the quota of %v", size, limit)
+
+			s.warned = true
+		}
+
+		return nil
+	}
+
+	return errors.Wrapf(ErrQuotaExceeded, "unable to write %v, storage size is approximately %v with quota of %v", id, size, limit)
+}
+
+// maybeRefresh lists the storage if it has never been listed or the refresh interval has elapsed.
+// Once the size is known, concurrent writes don't wait for a refresh in progress and use the current size.
+func (s *quotaStorage) maybeRefresh(ctx context.Context) error {
+	s.mu.Lock()
+	lastListed := s.lastListed
+	s.mu.Unlock()
+
+	switch {
+	case lastListed.IsZero():
+		s.listMu.Lock()
+	case s.opts.TimeNow().Sub(lastListed) < s.opts.RefreshInterval || !s.listMu.TryLock():
+		return nil
+	}
+
+	defer s.listMu.Unlock()
+
+	s.mu.Lock()
+	// another goroutine may have listed the storage while we were waiting.
+	listed := !s.lastListed.Equal(lastListed)
+	sizeBefore := s.size
+	s.mu.Unlock()
+
+	if listed {
+		return nil
+	}
+
+	var total int64
+
+	if err := s.Storage.ListBlobs(ctx, "", func(bm blob.Metadata) error {
+		total += bm.Length
+		return nil
+	}); err != nil {
+		return errors.Wrap(err, "unable to determine storage size")
+	}
+
+	log(ctx).Debugf("storage size is %v", units.Base2Bytes(total))
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	// writes and deletions concurrent with the listing may or may not have been listed,
+	// assume they were not.
+	s.size = total + s.size - sizeBefore
+	s.lastListed = s.opts.TimeNow()
+
+	return nil
+}
+
+// NewWrapper returns a Storage wrapper that fails or warns when writes exceed the configured storage quota.
+func NewWrapper(wrapped blob.Storage, opts Options) blob.Storage {
+	if opts.MaxBytes <= 0 {
+		return wrapped
+	}
+
+	if opts.RefreshInterval <= 0 {
+		opts.RefreshInterval = defaultRefreshInterval
+	}
+
+	if opts.TimeNow == nil {
+		opts.TimeNow = clock.Now
+	}
+
+	return &quotaStorage{
+		Storage: wrapped,
+		opts:    opts,
+	}
+}
@@ -0,0 +1,99 @@
+package quota_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/kopia/kopia/internal/blobtesting"
+	"github.com/kopia/kopia/internal/faketime"
+	"github.com/kopia/kopia/internal/gather"
+	"github.com/kopia/kopia/internal/testlogging"
+	"github.com/kopia/kopia/repo/blob"
+	"github.com/kopia/kopia/repo/blob/quota"
+)
+
+func TestQuotaStorage(t *testing.T) {
+	ctx := testlogging.Context(t)
+
+	ta := faketime.NewTimeAdvance(time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC))
+	ms := blobtesting.NewMapStorage(blobtesting.DataMap{
+		"p-existing": make([]byte, 400),
+		"n-existing": make([]byte, 100),
+	}, nil, ta.NowFunc())
+	fs := blobtesting.NewFaultyStorage(ms)
+
+	st := quota.NewWrapper(fs, quota.Options{
+		MaxBytes:        1000,
+		Prefixes:        []blob.ID{"p"},
+		RefreshInterval: time.Hour,
+		TimeNow:         ta.NowFunc(),
+	})
+
+	put := func(id blob.ID, n int) error {
+		return st.PutBlob(ctx, id, gather.FromSlice(make([]byte, n)), blob.PutOptions{})
+	}
+
+	// size is 500 after the initial listing.
+	require.NoError(t, put("p1", 300))
+	require.Equal(t, 1, fs.NumCalls(blobtesting.MethodListBlobs))
+
+	// 800 + 300 exceeds the quota.
+	require.ErrorIs(t, put("p2", 300), quota.ErrQuotaExceeded)
+
+	// writes of other prefixes are not subject to the quota but count towards it.
+	require.NoError(t, put("n1", 150))
+	require.ErrorIs(t, put("p2", 100), quota.ErrQuotaExceeded)
+	require.NoError(t, put("p2", 50))
+	require.Equal(t, 1, fs.NumCalls(blobtesting.MethodListBlobs))
+
+	// deletions are accounted for without listing the storage again.
+	require.NoError(t, st.DeleteBlob(ctx, "p-existing"))
+	require.Equal(t, 1, fs.NumCalls(blobtesting.MethodGetMetadata))
+	require.NoError(t, put("p3", 300))
+	require.Equal(t, 1, fs.NumCalls(blobtesting.MethodListBlobs))
+
+	// deleting a blob which does not exist does not change the size.
+	require.NoError(t, st.DeleteBlob(ctx, "p-no-such-blob"))
+
+	// changes made by other clients are picked up after the refresh interval.
+	require.NoError(t, ms.PutBlob(ctx, "p-other", gather.FromSlice(make([]byte, 100)), blob.PutOptions{}))
+	require.NoError(t, put("p4", 50))
+
+	ta.Advance(time.Hour)
+	require.ErrorIs(t, put("p5", 50), quota.ErrQuotaExceeded)
+	require.Equal(t, 2, fs.NumCalls(blobtesting.MethodListBlobs))
+}
+
+func TestQuotaStorageConcurrentListing(t *testing.T) {
+	ctx := testlogging.Context(t)
+
+	ms := blobtesting.NewMapStorage(blobtesting.DataMap{"p-existing": make([]byte, 400)}, nil, nil)
+	fs := blobtesting.NewFaultyStorage(ms)
+
+	st := quota.NewWrapper(fs, quota.Options{MaxBytes: 1000, Prefixes: []blob.ID{"p"}})
+
+	// a blob written while the storage is being listed is accounted for, even if the listing includes it.
+	fs.AddFault(blobtesting.MethodListBlobs).Before(func() {
+		require.NoError(t, st.PutBlob(ctx, "n1", gather.FromSlice(make([]byte, 500)), blob.PutOptions{}))
+	})
+
+	require.ErrorIs(t, st.PutBlob(ctx, "p1", gather.FromSlice(make([]byte, 200)), blob.PutOptions{}), quota.ErrQuotaExceeded)
+	fs.VerifyAllFaultsExercised(t)
+}
+
+func TestQuotaStorageWarnOnly(t *testing.T) {
+	ctx := testlogging.Context(t)
+
+	ms := blobtesting.NewMapStorage(blobtesting.DataMap{}, nil, nil)
+	st := quota.NewWrapper(ms, quota.Options{MaxBytes: 100, WarnOnly: true})
+
+	require.NoError(t, st.PutBlob(ctx, "p1", gather.FromSlice(make([]byte, 200)), blob.PutOptions{}))
+	require.NoError(t, st.PutBlob(ctx, "p2", gather.FromSlice(make([]byte, 200)), blob.PutOptions{}))
+}
+
+func TestQuotaStorageDisabled(t *testing.T) {
+	ms := blobtesting.NewMapStorage(blobtesting.DataMap{}, nil, nil)
+	require.Equal(t, ms, quota.NewWrapper(ms, quota.Options{}))
+}
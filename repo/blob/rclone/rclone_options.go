@@ -1,6 +1,7 @@
 package rclone
 
 import (
+	"github.com/kopia/kopia/repo/blob/retrypolicy"
 	"github.com/kopia/kopia/repo/blob/sharded"
 	"github.com/kopia/kopia/repo/blob/throttling"
 )
@@ -19,4 +20,5 @@ type Options struct {
 
 	sharded.Options
 	throttling.Limits
+	retrypolicy.Policy
 }
@@ -377,6 +377,7 @@ func New(ctx context.Context, opt *Options, isCreate bool) (blob.Storage, error)
 		TrustedServerCertificateFingerprint: fingerprintHexString,
 		AtomicWrites:                        opt.AtomicWrites,
 		Options:                             opt.Options,
+		Policy:                              opt.Policy,
 	}, isCreate)
 	if err != nil {
 		return nil, errors.Wrap(err, "error connecting to webdav storage")
@@ -3,6 +3,7 @@
 
 import (
 	"context"
+	"strings"
 
 	"github.com/pkg/errors"
 
@@ -12,9 +13,11 @@
 // ErrReadonly returns an error indicating that storage is read only.
 var ErrReadonly = errors.Errorf("storage is read-only")
 
-// readonlyStorage prevents all mutations on the underlying storage.
+// readonlyStorage prevents all mutations on the underlying storage, except for blobs
+// with one of the writable prefixes.
 type readonlyStorage struct {
-	base blob.Storage
+	base             blob.Storage
+	writablePrefixes []blob.ID
 	blob.DefaultProviderImplementation
 }
 
@@ -37,14 +40,32 @@ func (s readonlyStorage) GetMetadata(ctx context.Context, id blob.ID) (blob.Meta
 	return s.base.GetMetadata(ctx, id)
 }
 
-//nolint:revive
 func (s readonlyStorage) PutBlob(ctx context.Context, id blob.ID, data blob.Bytes, opts blob.PutOptions) error {
-	return ErrReadonly
+	if !s.isWritable(id) {
+		return ErrReadonly
+	}
+
+	//nolint:wrapcheck
+	return s.base.PutBlob(ctx, id, data, opts)
 }
 
-//nolint:revive
 func (s readonlyStorage) DeleteBlob(ctx context.Context, id blob.ID) error {
-	return ErrReadonly
+	if !s.isWritable(id) {
+		return ErrReadonly
+	}
+
+	//nolint:wrapcheck
+	return s.base.DeleteBlob(ctx, id)
+}
+
+func (s readonlyStorage) isWritable(id blob.ID) bool {
+	for _, p := range s.writablePrefixes {
+		if strings.HasPrefix(string(id), string(p)) {
+			return true
+		}
+	}
+
+	return false
 }
 
 func (s readonlyStorage) ListBlobs(ctx context.Context, prefix blob.ID, callback func(blob.Metadata) error) error {
@@ -74,3 +95,10 @@ func (s readonlyStorage) FlushCaches(ctx context.Context) error {
 func NewWrapper(wrapped blob.Storage) blob.Storage {
 	return &readonlyStorage{base: wrapped}
 }
+
+// NewWrapperWithWritablePrefixes returns a readonly Storage wrapper that prevents mutations to the underlying
+// storage, except for blobs whose IDs start with one of the provided prefixes, such as logs or session markers.
+// The returned storage still reports itself as read-only.
+func NewWrapperWithWritablePrefixes(wrapped blob.Storage, writablePrefixes ...blob.ID) blob.Storage {
+	return &readonlyStorage{base: wrapped, writablePrefixes: append([]blob.ID(nil), writablePrefixes...)}
+}
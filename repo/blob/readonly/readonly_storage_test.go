@@ -0,0 +1,55 @@
+package readonly_test
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/kopia/kopia/internal/blobtesting"
+	"github.com/kopia/kopia/internal/clock"
+	"github.com/kopia/kopia/internal/gather"
+	"github.com/kopia/kopia/internal/testlogging"
+	"github.com/kopia/kopia/repo/blob"
+	"github.com/kopia/kopia/repo/blob/readonly"
+)
+
+func TestReadonlyStorage(t *testing.T) {
+	ctx := testlogging.Context(t)
+
+	data := blobtesting.DataMap{"p1234": []byte{1, 2, 3}}
+	st := readonly.NewWrapper(blobtesting.NewMapStorage(data, nil, clock.Now))
+
+	require.True(t, st.IsReadOnly())
+	require.ErrorIs(t, st.PutBlob(ctx, "p2345", gather.FromSlice([]byte{1}), blob.PutOptions{}), readonly.ErrReadonly)
+	require.ErrorIs(t, st.DeleteBlob(ctx, "p1234"), readonly.ErrReadonly)
+
+	var tmp gather.WriteBuffer
+	defer tmp.Close()
+
+	require.NoError(t, st.GetBlob(ctx, "p1234", 0, -1, &tmp))
+	require.Equal(t, []byte{1, 2, 3}, tmp.ToByteSlice())
+	require.Len(t, data, 1)
+}
+
+func TestReadonlyStorage_WritablePrefixes(t *testing.T) {
+	ctx := testlogging.Context(t)
+
+	data := blobtesting.DataMap{"p1234": []byte{1, 2, 3}, "_log_old": []byte{4}}
+	st := readonly.NewWrapperWithWritablePrefixes(blobtesting.NewMapStorage(data, nil, clock.Now), "_log", "s")
+
+	require.True(t, st.IsReadOnly())
+
+	require.NoError(t, st.PutBlob(ctx, "_log_1234", gather.FromSlice([]byte{1}), blob.PutOptions{}))
+	require.NoError(t, st.PutBlob(ctx, "s1234", gather.FromSlice([]byte{1}), blob.PutOptions{}))
+	require.NoError(t, st.DeleteBlob(ctx, "_log_old"))
+
+	require.ErrorIs(t, st.PutBlob(ctx, "p2345", gather.FromSlice([]byte{1}), blob.PutOptions{}), readonly.ErrReadonly)
+	require.ErrorIs(t, st.PutBlob(ctx, "q2345", gather.FromSlice([]byte{1}), blob.PutOptions{}), readonly.ErrReadonly)
+	require.ErrorIs(t, st.DeleteBlob(ctx, "p1234"), readonly.ErrReadonly)
+
+	require.Contains(t, data, blob.ID("_log_1234"))
+	require.Contains(t, data, blob.ID("s1234"))
+	require.Contains(t, data, blob.ID("p1234"))
+	require.NotContains(t, data, blob.ID("_log_old"))
+	require.NotContains(t, data, blob.ID("p2345"))
+}
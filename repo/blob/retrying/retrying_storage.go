@@ -1,4 +1,5 @@
 // Package retrying implements wrapper around blob.Storage that adds retry loop around all operations in case they return unexpected errors.
+// Throttled attempts are reported to the throttler of the operation (see throttling.ReportIfThrottled).
 package retrying
 
 import (
@@ -9,42 +10,66 @@
 	"github.com/kopia/kopia/internal/retry"
 	"github.com/kopia/kopia/repo"
 	"github.com/kopia/kopia/repo/blob"
+	"github.com/kopia/kopia/repo/blob/retrypolicy"
+	"github.com/kopia/kopia/repo/blob/throttling"
 )
 
 // retryingStorage adds retry loop around all operations of the underlying storage.
 type retryingStorage struct {
 	blob.Storage
+
+	policy      retry.Policy
+	isRetriable retry.IsRetriableFunc
 }
 
 func (s retryingStorage) GetBlob(ctx context.Context, id blob.ID, offset, length int64, output blob.OutputBuffer) error {
-	return retry.WithExponentialBackoffNoValue(ctx, fmt.Sprintf("GetBlob(%v,%v,%v)", id, offset, length), func() error {
+	return retry.WithPolicyNoValue(ctx, s.policy, fmt.Sprintf("GetBlob(%v,%v,%v)", id, offset, length), func() error {
 		output.Reset()
 
-		return s.Storage.GetBlob(ctx, id, offset, length, output)
-	}, isRetriable)
+		return throttling.ReportIfThrottled(ctx, s.Storage.GetBlob(ctx, id, offset, length, output))
+	}, s.isRetriable)
 }
 
 func (s retryingStorage) GetMetadata(ctx context.Context, id blob.ID) (blob.Metadata, error) {
-	return retry.WithExponentialBackoff(ctx, "GetMetadata("+string(id)+")", func() (blob.Metadata, error) {
-		return s.Storage.GetMetadata(ctx, id)
-	}, isRetriable)
+	return retry.WithPolicy(ctx, s.policy, "GetMetadata("+string(id)+")", func() (blob.Metadata, error) {
+		m, err := s.Storage.GetMetadata(ctx, id)
+
+		return m, throttling.ReportIfThrottled(ctx, err)
+	}, s.isRetriable)
 }
 
 func (s retryingStorage) PutBlob(ctx context.Context, id blob.ID, data blob.Bytes, opts blob.PutOptions) error {
-	return retry.WithExponentialBackoffNoValue(ctx, "PutBlob("+string(id)+")", func() error {
-		return s.Storage.PutBlob(ctx, id, data, opts)
-	}, isRetriable)
+	return retry.WithPolicyNoValue(ctx, s.policy, "PutBlob("+string(id)+")", func() error {
+		return throttling.ReportIfThrottled(ctx, s.Storage.PutBlob(ctx, id, data, opts))
+	}, s.isRetriable)
 }
 
 func (s retryingStorage) DeleteBlob(ctx context.Context, id blob.ID) error {
-	return retry.WithExponentialBackoffNoValue(ctx, "DeleteBlob("+string(id)+")", func() error {
-		return s.Storage.DeleteBlob(ctx, id)
-	}, isRetriable)
+	return retry.WithPolicyNoValue(ctx, s.policy, "DeleteBlob("+string(id)+")", func() error {
+		return throttling.ReportIfThrottled(ctx, s.Storage.DeleteBlob(ctx, id))
+	}, s.isRetriable)
 }
 
 // NewWrapper returns a Storage wrapper that adds retry loop around all operations of the underlying storage.
 func NewWrapper(wrapped blob.Storage) blob.Storage {
-	return &retryingStorage{Storage: wrapped}
+	return NewWrapperWithPolicy(wrapped, retrypolicy.Policy{}, nil)
+}
+
+// NewWrapperWithPolicy returns a Storage wrapper that retries operations of the underlying storage according
+// to the provided policy. Errors which are not retriable regardless of the storage provider are never retried,
+// others are retried unless the provided provider-specific function (if any) determines they are not retriable.
+func NewWrapperWithPolicy(wrapped blob.Storage, p retrypolicy.Policy, isProviderRetriable retry.IsRetriableFunc) blob.Storage {
+	return &retryingStorage{
+		Storage: wrapped,
+		policy:  p.Backoff(),
+		isRetriable: func(err error) bool {
+			if !isRetriable(err) {
+				return false
+			}
+
+			return isProviderRetriable == nil || isProviderRetriable(err)
+		},
+	}
 }
 
 func isRetriable(err error) bool {
@@ -2,6 +2,7 @@
 
 import (
 	"testing"
+	"time"
 
 	"github.com/pkg/errors"
 	"github.com/stretchr/testify/require"
@@ -11,6 +12,7 @@
 	"github.com/kopia/kopia/internal/testlogging"
 	"github.com/kopia/kopia/repo/blob"
 	"github.com/kopia/kopia/repo/blob/retrying"
+	"github.com/kopia/kopia/repo/blob/retrypolicy"
 )
 
 func TestRetrying(t *testing.T) {
@@ -59,3 +61,43 @@ func TestRetrying(t *testing.T) {
 
 	fs.VerifyAllFaultsExercised(t)
 }
+
+func TestRetryingWithPolicy(t *testing.T) {
+	t.Parallel()
+
+	ctx := testlogging.Context(t)
+
+	someError := errors.New("some error")
+	permanentError := errors.New("permanent error")
+	ms := blobtesting.NewMapStorage(blobtesting.DataMap{}, nil, nil)
+	fs := blobtesting.NewFaultyStorage(ms)
+
+	rs := retrying.NewWrapperWithPolicy(fs, retrypolicy.Policy{
+		RetryMaxAttempts:  3,
+		RetryInitialDelay: time.Millisecond,
+		RetryMaxDelay:     2 * time.Millisecond,
+		RetryJitter:       0.5,
+	}, func(err error) bool {
+		return !errors.Is(err, permanentError)
+	})
+
+	blobID := blob.ID("deadcafe")
+
+	// retriable error persisting beyond max attempts.
+	fs.AddFault(blobtesting.MethodPutBlob).ErrorInstead(someError).Repeat(2)
+	require.ErrorIs(t, rs.PutBlob(ctx, blobID, gather.FromSlice([]byte{1, 2, 3}), blob.PutOptions{}), someError)
+	require.Equal(t, 3, fs.NumCalls(blobtesting.MethodPutBlob))
+
+	// retriable error succeeding within max attempts.
+	fs.AddFault(blobtesting.MethodPutBlob).ErrorInstead(someError)
+	require.NoError(t, rs.PutBlob(ctx, blobID, gather.FromSlice([]byte{1, 2, 3}), blob.PutOptions{}))
+	require.Equal(t, 5, fs.NumCalls(blobtesting.MethodPutBlob))
+
+	// error which is not retriable according to the provider is returned immediately.
+	fs.AddFault(blobtesting.MethodGetMetadata).ErrorInstead(permanentError)
+	_, err := rs.GetMetadata(ctx, blobID)
+	require.ErrorIs(t, err, permanentError)
+	require.Equal(t, 1, fs.NumCalls(blobtesting.MethodGetMetadata))
+
+	fs.VerifyAllFaultsExercised(t)
+}
@@ -0,0 +1,48 @@
+// Package retrypolicy defines the configurable policy of retrying failed operations of storage providers.
+package retrypolicy
+
+import (
+	"time"
+
+	"github.com/kopia/kopia/internal/retry"
+)
+
+// Policy determines how failed operations of a storage provider are retried, it's embedded in the options
+// of storage providers. Zero values use the defaults.
+type Policy struct {
+	// RetryMaxAttempts is the maximum number of attempts of each operation.
+	RetryMaxAttempts int `json:"retryMaxAttempts,omitempty"`
+
+	// RetryInitialDelay is the delay after the first failed attempt, which grows exponentially
+	// after each subsequent one up to RetryMaxDelay.
+	RetryInitialDelay time.Duration `json:"retryInitialDelay,omitempty"`
+	RetryMaxDelay     time.Duration `json:"retryMaxDelay,omitempty"`
+
+	// RetryJitter is the fraction of each delay which is randomized, between 0 and 1.
+	RetryJitter float64 `json:"retryJitter,omitempty"`
+}
+
+// Backoff returns the retry policy with defaults applied.
+func (p Policy) Backoff() retry.Policy {
+	result := retry.DefaultPolicy()
+
+	if p.RetryMaxAttempts > 0 {
+		result.MaxAttempts = p.RetryMaxAttempts
+	}
+
+	if p.RetryInitialDelay > 0 {
+		result.InitialDelay = p.RetryInitialDelay
+	}
+
+	if p.RetryMaxDelay > 0 {
+		result.MaxDelay = p.RetryMaxDelay
+	}
+
+	result.MaxDelay = max(result.MaxDelay, result.InitialDelay)
+
+	if p.RetryJitter > 0 {
+		result.Jitter = min(p.RetryJitter, 1)
+	}
+
+	return result
+}
@@ -0,0 +1,37 @@
+package retrypolicy_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/kopia/kopia/internal/retry"
+	"github.com/kopia/kopia/repo/blob/retrypolicy"
+)
+
+func TestBackoff(t *testing.T) {
+	require.Equal(t, retry.DefaultPolicy(), retrypolicy.Policy{}.Backoff())
+
+	p := retrypolicy.Policy{
+		RetryMaxAttempts:  5,
+		RetryInitialDelay: time.Second,
+		RetryMaxDelay:     10 * time.Second,
+		RetryJitter:       0.25,
+	}.Backoff()
+
+	require.Equal(t, 5, p.MaxAttempts)
+	require.Equal(t, time.Second, p.InitialDelay)
+	require.Equal(t, 10*time.Second, p.MaxDelay)
+	require.Equal(t, retry.DefaultPolicy().Multiplier, p.Multiplier)
+	require.InDelta(t, 0.25, p.Jitter, 1e-9)
+
+	// max delay is never below the initial delay and jitter is capped.
+	p = retrypolicy.Policy{
+		RetryInitialDelay: time.Hour,
+		RetryJitter:       3,
+	}.Backoff()
+
+	require.Equal(t, time.Hour, p.MaxDelay)
+	require.InDelta(t, 1.0, p.Jitter, 1e-9)
+}
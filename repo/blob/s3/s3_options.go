@@ -3,6 +3,8 @@
 import (
 	"time"
 
+	"github.com/kopia/kopia/repo/blob"
+	"github.com/kopia/kopia/repo/blob/retrypolicy"
 	"github.com/kopia/kopia/repo/blob/throttling"
 )
 
@@ -26,7 +28,25 @@ type Options struct {
 	// Region is an optional region to pass in authorization header.
 	Region string `json:"region,omitempty"`
 
+	// MultipartPartSize enables multipart uploads of blobs at least this large, uploading parts of this size.
+	// Zero uploads all blobs in a single request.
+	MultipartPartSize int64 `json:"multipartPartSize,omitempty"`
+
+	// UploadConcurrency is the number of parts of a single blob uploaded in parallel, zero uses the default.
+	UploadConcurrency int `json:"uploadConcurrency,omitempty"`
+
+	// UseTransferAcceleration routes requests to Amazon S3 through the transfer acceleration endpoint.
+	UseTransferAcceleration bool `json:"useTransferAcceleration,omitempty"`
+
+	// ChecksumAlgorithm used to verify integrity of uploads (MD5, CRC32, CRC32C, SHA1 or SHA256), defaults to MD5.
+	ChecksumAlgorithm string `json:"checksumAlgorithm,omitempty"`
+
+	// StorageClasses specifies storage classes of blobs by prefix, which take precedence over
+	// the storage config persisted in the bucket.
+	StorageClasses []blob.PrefixStorageClass `json:"storageClasses,omitempty"`
+
 	throttling.Limits
+	retrypolicy.Policy
 
 	// PointInTime specifies a view of the (versioned) store at that time
 	PointInTime *time.Time `json:"pointInTime,omitempty"`
@@ -21,6 +21,7 @@
 	"github.com/kopia/kopia/internal/iocopy"
 	"github.com/kopia/kopia/repo/blob"
 	"github.com/kopia/kopia/repo/blob/retrying"
+	"github.com/kopia/kopia/repo/blob/throttling"
 )
 
 const (
@@ -106,15 +107,47 @@ func translateError(err error) error {
 		case http.StatusRequestedRangeNotSatisfiable:
 			return blob.ErrInvalidRange
 		}
+
+		if me.Code == "SlowDown" || throttling.IsThrottlingHTTPStatus(me.StatusCode) {
+			return &throttling.ThrottledError{Err: err}
+		}
 	}
 
 	return err
 }
 
+// isRetriableError determines whether the error returned by S3 is worth retrying, errors caused by
+// invalid credentials or configuration are not.
+func isRetriableError(err error) bool {
+	var me minio.ErrorResponse
+
+	if isInvalidCredentials(err) {
+		return false
+	}
+
+	if errors.As(err, &me) {
+		switch me.Code {
+		case "AccessDenied", "NoSuchBucket", "InvalidAccessKeyId", "SignatureDoesNotMatch":
+			return false
+		}
+	}
+
+	return true
+}
+
 func (s *s3Storage) GetMetadata(ctx context.Context, b blob.ID) (blob.Metadata, error) {
-	vm, err := s.getVersionMetadata(ctx, b, "")
+	oi, err := s.cli.StatObject(ctx, s.BucketName, s.getObjectNameString(b), minio.GetObjectOptions{})
+	if err != nil {
+		return blob.Metadata{}, errors.Wrap(translateError(err), "StatObject")
+	}
 
-	return vm.Metadata, err
+	bm := infoToVersionMetadata(s.Prefix, &oi).Metadata
+
+	// ETag identifies the contents of the object, which allows revalidating cached format blobs.
+	// Object versions returned by getVersionMetadata() and version listings are identified by version IDs instead.
+	bm.Version = oi.ETag
+
+	return bm, nil
 }
 
 func (s *s3Storage) getVersionMetadata(ctx context.Context, b blob.ID, version string) (versionMetadata, error) {
@@ -154,11 +187,15 @@ func (s *s3Storage) PutBlob(ctx context.Context, b blob.ID, data blob.Bytes, opt
 
 func (s *s3Storage) putBlob(ctx context.Context, b blob.ID, data blob.Bytes, opts blob.PutOptions) (versionMetadata, error) {
 	var (
-		storageClass    = s.storageConfig.getStorageClassForBlobID(b)
+		storageClass    = blob.StorageClassForBlob(s.StorageClasses, b, opts)
 		retentionMode   minio.RetentionMode
 		retainUntilDate time.Time
 	)
 
+	if storageClass == "" {
+		storageClass = s.storageConfig.getStorageClassForBlobID(b)
+	}
+
 	if opts.RetentionPeriod != 0 {
 		retentionMode = minio.RetentionMode(opts.RetentionMode)
 		if !retentionMode.IsValid() {
@@ -168,21 +205,18 @@ func (s *s3Storage) putBlob(ctx context.Context, b blob.ID, data blob.Bytes, opt
 		retainUntilDate = clock.Now().Add(opts.RetentionPeriod).UTC()
 	}
 
-	uploadInfo, err := s.cli.PutObject(ctx, s.BucketName, s.getObjectNameString(b), data.Reader(), int64(data.Length()), minio.PutObjectOptions{
-		ContentType: "application/x-kopia",
-		// Kopia already splits snapshot contents into small blobs to improve
-		// upload throughput. There is no need for further splitting
-		// through multipart uploads.
-		DisableMultipart: true,
-		// The Content-MD5 header is required for any request to upload an object
-		// with a retention period configured using Amazon S3 Object Lock.
-		// Unconditionally computing the content MD5, potentially incurring
-		// a slightly higher CPU overhead.
-		SendContentMd5:  true,
+	putOpts := minio.PutObjectOptions{
+		ContentType:     "application/x-kopia",
 		StorageClass:    storageClass,
 		RetainUntilDate: retainUntilDate,
 		Mode:            retentionMode,
-	})
+	}
+
+	if err := s.applyUploadOptions(&putOpts, data); err != nil {
+		return versionMetadata{}, err
+	}
+
+	uploadInfo, err := s.cli.PutObject(ctx, s.BucketName, s.getObjectNameString(b), data.Reader(), int64(data.Length()), putOpts)
 
 	if isInvalidCredentials(err) {
 		return versionMetadata{}, blob.ErrInvalidCredentials
@@ -337,7 +371,7 @@ func New(ctx context.Context, opt *Options, isCreate bool) (blob.Storage, error)
 		return nil, err
 	}
 
-	return retrying.NewWrapper(s), nil
+	return retrying.NewWrapperWithPolicy(s, opt.Policy, isRetriableError), nil
 }
 
 func newStorage(ctx context.Context, opt *Options) (*s3Storage, error) {
@@ -368,6 +402,10 @@ func newStorageWithCredentials(ctx context.Context, creds *credentials.Credentia
 		return nil, errors.New("bucket name must be specified")
 	}
 
+	if err := opt.validateUploadOptions(); err != nil {
+		return nil, err
+	}
+
 	minioOpts := &minio.Options{
 		Creds:  creds,
 		Secure: !opt.DoNotUseTLS,
@@ -386,6 +424,10 @@ func newStorageWithCredentials(ctx context.Context, creds *credentials.Credentia
 		return nil, errors.Wrap(err, "unable to create client")
 	}
 
+	if opt.UseTransferAcceleration {
+		cli.SetS3TransferAccelerate(transferAccelerationEndpoint)
+	}
+
 	s := s3Storage{
 		Options:       *opt,
 		cli:           cli,
@@ -359,6 +359,88 @@ func TestS3StorageMinio(t *testing.T) {
 	testStorage(t, options, true, blob.PutOptions{})
 }
 
+func TestS3StorageMinioObjectLockedBucket(t *testing.T) {
+	t.Parallel()
+	testutil.ProviderTest(t)
+
+	minioEndpoint := startDockerMinioOrSkip(t, testutil.TempDirectory(t))
+
+	for _, mode := range []blob.RetentionMode{blob.Governance, blob.Compliance} {
+		t.Run(string(mode), func(t *testing.T) {
+			options := &Options{
+				Endpoint:        minioEndpoint,
+				AccessKeyID:     minioRootAccessKeyID,
+				SecretAccessKey: minioRootSecretAccessKey,
+				BucketName:      strings.ToLower("locked-" + string(mode)),
+				Region:          minioRegion,
+				DoNotUseTLS:     true,
+			}
+
+			createBucketWithOptions(t, options, bucketOptions{
+				ObjectLocking: true,
+				RetentionMode: mode,
+				RetentionDays: 1,
+			})
+
+			ctx := testlogging.Context(t)
+
+			got, gotMode, _, _, err := createClient(t, options).GetObjectLockConfig(ctx, options.BucketName)
+			require.NoError(t, err)
+			require.Equal(t, "Enabled", got)
+			require.NotNil(t, gotMode)
+			require.Equal(t, minio.RetentionMode(mode), *gotMode)
+
+			testStorage(t, options, false, blob.PutOptions{
+				RetentionMode:   mode,
+				RetentionPeriod: time.Hour,
+			})
+
+			// exercise the locked bucket with concurrent puts, deletes and lists.
+			options.Prefix = uuid.NewString() + "/"
+
+			st, err := New(ctx, options, false)
+			require.NoError(t, err)
+
+			defer st.Close(ctx)
+
+			blobtesting.VerifyConcurrentAccess(t, st, blobtesting.ConcurrentAccessOptions{
+				NumBlobs:                        16,
+				Getters:                         4,
+				Putters:                         4,
+				Deleters:                        4,
+				Listers:                         4,
+				Iterations:                      50,
+				RangeGetPercentage:              10,
+				NonExistentListPrefixPercentage: 10,
+			})
+		})
+	}
+}
+
+func TestS3StorageMinioVersionedBucket(t *testing.T) {
+	t.Parallel()
+	testutil.ProviderTest(t)
+
+	minioEndpoint := startDockerMinioOrSkip(t, testutil.TempDirectory(t))
+
+	options := &Options{
+		Endpoint:        minioEndpoint,
+		AccessKeyID:     minioRootAccessKeyID,
+		SecretAccessKey: minioRootSecretAccessKey,
+		BucketName:      minioBucketName,
+		Region:          minioRegion,
+		DoNotUseTLS:     true,
+	}
+
+	createBucketWithOptions(t, options, bucketOptions{Versioned: true})
+
+	vc, err := createClient(t, options).GetBucketVersioning(testlogging.Context(t), options.BucketName)
+	require.NoError(t, err)
+	require.True(t, vc.Enabled())
+
+	testStorage(t, options, true, blob.PutOptions{})
+}
+
 func TestS3StorageMinioSelfSignedCert(t *testing.T) {
 	t.Parallel()
 	testutil.ProviderTest(t)
@@ -512,7 +594,7 @@ func TestNeedMD5AWS(t *testing.T) {
 
 	ctx := testlogging.Context(t)
 	cli := createClient(t, options)
-	getOrMakeBucket(t, cli, options, true)
+	getOrMakeBucket(t, cli, options, bucketOptions{ObjectLocking: true})
 
 	// ensure it is a bucket with object locking enabled
 	want := "Enabled"
@@ -664,23 +746,43 @@ func createClient(tb testing.TB, opt *Options) *minio.Client {
 	return minioClient
 }
 
+// bucketOptions describes optional properties of a bucket created by the test helpers.
+type bucketOptions struct {
+	// Versioned enables versioning on the bucket, which is implied by ObjectLocking.
+	Versioned bool
+
+	// ObjectLocking creates the bucket with object locking enabled.
+	ObjectLocking bool
+
+	// RetentionMode and RetentionDays set the default retention of the object-locked bucket.
+	RetentionMode blob.RetentionMode
+	RetentionDays uint
+}
+
 func getOrCreateBucket(tb testing.TB, opt *Options) {
 	tb.Helper()
 
 	minioClient := createClient(tb, opt)
 
-	getOrMakeBucket(tb, minioClient, opt, false)
+	getOrMakeBucket(tb, minioClient, opt, bucketOptions{})
 }
 
 func createBucket(tb testing.TB, opt *Options) {
 	tb.Helper()
 
+	createBucketWithOptions(tb, opt, bucketOptions{})
+}
+
+// createBucketWithOptions creates a bucket, optionally versioned and object-locked with default retention.
+func createBucketWithOptions(tb testing.TB, opt *Options, bo bucketOptions) {
+	tb.Helper()
+
 	minioClient := createClient(tb, opt)
 
-	makeBucket(tb, minioClient, opt, false)
+	makeBucket(tb, minioClient, opt, bo)
 }
 
-func getOrMakeBucket(tb testing.TB, cli *minio.Client, opt *Options, objectLocking bool) {
+func getOrMakeBucket(tb testing.TB, cli *minio.Client, opt *Options, bo bucketOptions) {
 	tb.Helper()
 
 	ctx := testlogging.Context(tb)
@@ -694,17 +796,17 @@ func getOrMakeBucket(tb testing.TB, cli *minio.Client, opt *Options, objectLocki
 		return
 	}
 
-	makeBucket(tb, cli, opt, objectLocking)
+	makeBucket(tb, cli, opt, bo)
 }
 
-func makeBucket(tb testing.TB, cli *minio.Client, opt *Options, objectLocking bool) {
+func makeBucket(tb testing.TB, cli *minio.Client, opt *Options, bo bucketOptions) {
 	tb.Helper()
 
 	ctx := testlogging.Context(tb)
 
 	if err := cli.MakeBucket(ctx, opt.BucketName, minio.MakeBucketOptions{
 		Region:        opt.Region,
-		ObjectLocking: objectLocking,
+		ObjectLocking: bo.ObjectLocking,
 	}); err != nil {
 		var er minio.ErrorResponse
 
@@ -715,6 +817,23 @@ func makeBucket(tb testing.TB, cli *minio.Client, opt *Options, objectLocking bo
 
 		tb.Fatalf("unable to create bucket: %v", err)
 	}
+
+	// object locking implicitly enables versioning.
+	if bo.Versioned && !bo.ObjectLocking {
+		if err := cli.EnableVersioning(ctx, opt.BucketName); err != nil {
+			tb.Fatalf("unable to enable versioning: %v", err)
+		}
+	}
+
+	if bo.RetentionMode != "" {
+		mode := minio.RetentionMode(bo.RetentionMode)
+		validity := bo.RetentionDays
+		unit := minio.Days
+
+		if err := cli.SetBucketObjectLockConfig(ctx, opt.BucketName, &mode, &validity, &unit); err != nil {
+			tb.Fatalf("unable to set object lock config: %v", err)
+		}
+	}
 }
 
 func createMinioSessionToken(t *testing.T, minioEndpoint, kopiaUserName, kopiaUserPasswd, bucketName string) credentials.Value {
@@ -0,0 +1,90 @@
+package s3
+
+import (
+	"encoding/base64"
+	"strings"
+
+	"github.com/minio/minio-go/v7"
+	"github.com/pkg/errors"
+
+	"github.com/kopia/kopia/repo/blob"
+)
+
+// Supported checksum algorithms.
+const (
+	ChecksumMD5    = "MD5"
+	ChecksumCRC32  = "CRC32"
+	ChecksumCRC32C = "CRC32C"
+	ChecksumSHA1   = "SHA1"
+	ChecksumSHA256 = "SHA256"
+)
+
+const (
+	// limits of the part size of multipart uploads imposed by S3.
+	minMultipartPartSize       = 5 << 20
+	maxMultipartPartSize int64 = 5 << 30
+
+	transferAccelerationEndpoint = "s3-accelerate.amazonaws.com"
+)
+
+//nolint:gochecknoglobals
+var checksumTypes = map[string]minio.ChecksumType{
+	ChecksumCRC32:  minio.ChecksumCRC32,
+	ChecksumCRC32C: minio.ChecksumCRC32C,
+	ChecksumSHA1:   minio.ChecksumSHA1,
+	ChecksumSHA256: minio.ChecksumSHA256,
+}
+
+func (o *Options) validateUploadOptions() error {
+	if o.MultipartPartSize != 0 && (o.MultipartPartSize < minMultipartPartSize || o.MultipartPartSize > maxMultipartPartSize) {
+		return errors.Errorf("invalid multipart part size %v, must be between %v and %v", o.MultipartPartSize, minMultipartPartSize, maxMultipartPartSize)
+	}
+
+	if o.UploadConcurrency < 0 {
+		return errors.Errorf("invalid upload concurrency %v", o.UploadConcurrency)
+	}
+
+	if alg := strings.ToUpper(o.ChecksumAlgorithm); alg != "" && alg != ChecksumMD5 && checksumTypes[alg] == minio.ChecksumNone {
+		return errors.Errorf("unsupported checksum algorithm %q", o.ChecksumAlgorithm)
+	}
+
+	return nil
+}
+
+// applyUploadOptions configures multipart upload and checksum of the upload of the provided data.
+func (s *s3Storage) applyUploadOptions(opts *minio.PutObjectOptions, data blob.Bytes) error {
+	ct := checksumTypes[strings.ToUpper(s.ChecksumAlgorithm)]
+
+	// The Content-MD5 header (or another checksum) is required for any request to upload an object
+	// with a retention period configured using Amazon S3 Object Lock.
+	opts.SendContentMd5 = ct == minio.ChecksumNone
+
+	if s.MultipartPartSize > 0 && int64(data.Length()) >= s.MultipartPartSize {
+		// each part is verified using Content-MD5 or CRC32C checksum computed by the client library.
+		opts.PartSize = uint64(s.MultipartPartSize)
+		opts.NumThreads = uint(s.UploadConcurrency) //nolint:gosec
+
+		return nil
+	}
+
+	// Kopia already splits snapshot contents into small blobs to improve
+	// upload throughput. There is no need for further splitting
+	// through multipart uploads, unless explicitly configured.
+	opts.DisableMultipart = true
+
+	if ct == minio.ChecksumNone {
+		return nil
+	}
+
+	h := ct.Hasher()
+
+	if _, err := data.WriteTo(h); err != nil {
+		return errors.Wrap(err, "error computing checksum")
+	}
+
+	opts.UserMetadata = map[string]string{
+		ct.Key(): base64.StdEncoding.EncodeToString(h.Sum(nil)),
+	}
+
+	return nil
+}
@@ -0,0 +1,135 @@
+package s3
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/base64"
+	"testing"
+
+	"github.com/minio/minio-go/v7"
+	"github.com/stretchr/testify/require"
+
+	"github.com/kopia/kopia/internal/gather"
+	"github.com/kopia/kopia/internal/testlogging"
+	"github.com/kopia/kopia/internal/testutil"
+	"github.com/kopia/kopia/repo/blob"
+)
+
+func TestValidateUploadOptions(t *testing.T) {
+	cases := []struct {
+		opt     Options
+		wantErr bool
+	}{
+		{opt: Options{}},
+		{opt: Options{MultipartPartSize: 16 << 20, UploadConcurrency: 8}},
+		{opt: Options{ChecksumAlgorithm: "crc32c"}},
+		{opt: Options{ChecksumAlgorithm: ChecksumMD5}},
+		{opt: Options{ChecksumAlgorithm: ChecksumSHA256}},
+		{opt: Options{MultipartPartSize: 1 << 20}, wantErr: true},
+		{opt: Options{MultipartPartSize: 6 << 30}, wantErr: true},
+		{opt: Options{UploadConcurrency: -1}, wantErr: true},
+		{opt: Options{ChecksumAlgorithm: "XXH3"}, wantErr: true},
+	}
+
+	for _, tc := range cases {
+		err := tc.opt.validateUploadOptions()
+		if tc.wantErr {
+			require.Error(t, err, "%+v", tc.opt)
+		} else {
+			require.NoError(t, err, "%+v", tc.opt)
+		}
+	}
+}
+
+func TestApplyUploadOptions(t *testing.T) {
+	small := gather.FromSlice(bytes.Repeat([]byte{1}, 1000))
+	large := gather.FromSlice(bytes.Repeat([]byte{1}, minMultipartPartSize))
+
+	// defaults - single request with Content-MD5
+	var po minio.PutObjectOptions
+
+	s := &s3Storage{}
+	require.NoError(t, s.applyUploadOptions(&po, large))
+	require.True(t, po.DisableMultipart)
+	require.True(t, po.SendContentMd5)
+	require.Empty(t, po.UserMetadata)
+
+	// multipart only for blobs at least as large as part size
+	s = &s3Storage{Options: Options{MultipartPartSize: minMultipartPartSize, UploadConcurrency: 3}}
+
+	po = minio.PutObjectOptions{}
+	require.NoError(t, s.applyUploadOptions(&po, small))
+	require.True(t, po.DisableMultipart)
+
+	po = minio.PutObjectOptions{}
+	require.NoError(t, s.applyUploadOptions(&po, large))
+	require.False(t, po.DisableMultipart)
+	require.EqualValues(t, minMultipartPartSize, po.PartSize)
+	require.EqualValues(t, 3, po.NumThreads)
+	require.True(t, po.SendContentMd5)
+
+	// checksum of single request uploads
+	s = &s3Storage{Options: Options{ChecksumAlgorithm: "sha256"}}
+
+	h := sha256.Sum256(small.ToByteSlice())
+
+	po = minio.PutObjectOptions{}
+	require.NoError(t, s.applyUploadOptions(&po, small))
+	require.False(t, po.SendContentMd5)
+	require.Equal(t, map[string]string{
+		"x-amz-checksum-sha256": base64.StdEncoding.EncodeToString(h[:]),
+	}, po.UserMetadata)
+}
+
+func TestS3StorageMinioUploadOptions(t *testing.T) {
+	t.Parallel()
+	testutil.ProviderTest(t)
+
+	minioEndpoint := startDockerMinioOrSkip(t, testutil.TempDirectory(t))
+
+	createBucket(t, &Options{
+		Endpoint:        minioEndpoint,
+		AccessKeyID:     minioRootAccessKeyID,
+		SecretAccessKey: minioRootSecretAccessKey,
+		BucketName:      minioBucketName,
+		Region:          minioRegion,
+		DoNotUseTLS:     true,
+	})
+
+	for _, alg := range []string{ChecksumMD5, ChecksumCRC32C, ChecksumSHA256} {
+		t.Run(alg, func(t *testing.T) {
+			options := &Options{
+				Endpoint:          minioEndpoint,
+				AccessKeyID:       minioRootAccessKeyID,
+				SecretAccessKey:   minioRootSecretAccessKey,
+				BucketName:        minioBucketName,
+				Region:            minioRegion,
+				DoNotUseTLS:       true,
+				MultipartPartSize: minMultipartPartSize,
+				UploadConcurrency: 2,
+				ChecksumAlgorithm: alg,
+			}
+
+			testStorage(t, options, false, blob.PutOptions{})
+
+			ctx := testlogging.Context(t)
+
+			st, err := New(ctx, options, false)
+			require.NoError(t, err)
+
+			defer st.Close(ctx)
+
+			data := bytes.Repeat([]byte{1, 2, 3}, minMultipartPartSize)
+			id := blob.ID("multipart-" + alg)
+
+			require.NoError(t, st.PutBlob(ctx, id, gather.FromSlice(data), blob.PutOptions{}))
+
+			var tmp gather.WriteBuffer
+			defer tmp.Close()
+
+			require.NoError(t, st.GetBlob(ctx, id, 0, -1, &tmp))
+			require.Equal(t, data, tmp.ToByteSlice())
+			require.NoError(t, st.DeleteBlob(ctx, id))
+		})
+	}
+}
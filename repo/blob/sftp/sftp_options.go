@@ -4,6 +4,7 @@
 	"os"
 	"path/filepath"
 
+	"github.com/kopia/kopia/repo/blob/retrypolicy"
 	"github.com/kopia/kopia/repo/blob/sharded"
 	"github.com/kopia/kopia/repo/blob/throttling"
 )
@@ -28,6 +29,7 @@ type Options struct {
 
 	sharded.Options
 	throttling.Limits
+	retrypolicy.Policy
 }
 
 func (sftpo *Options) knownHostsFile() string {
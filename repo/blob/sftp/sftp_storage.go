@@ -568,7 +568,7 @@ func New(ctx context.Context, opts *Options, isCreate bool) (blob.Storage, error
 		}
 	}
 
-	return retrying.NewWrapper(r), nil
+	return retrying.NewWrapperWithPolicy(r, opts.Policy, nil), nil
 }
 
 func sftpClientFromConnection(conn connection.Connection) *sftp.Client {
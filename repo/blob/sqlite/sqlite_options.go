@@ -0,0 +1,15 @@
+package sqlite
+
+import (
+	"github.com/kopia/kopia/repo/blob/retrypolicy"
+	"github.com/kopia/kopia/repo/blob/throttling"
+)
+
+// Options defines options for SQLite-backed storage.
+type Options struct {
+	// Path is the path to the database file.
+	Path string `json:"path"`
+
+	throttling.Limits
+	retrypolicy.Policy
+}
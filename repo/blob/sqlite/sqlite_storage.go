@@ -0,0 +1,291 @@
+//go:build !nosqlite && ((darwin && (amd64 || arm64)) || (freebsd && (386 || amd64 || arm || arm64)) || (linux && (386 || amd64 || arm || arm64 || loong64 || ppc64le || riscv64 || s390x)) || (netbsd && amd64) || (openbsd && (amd64 || arm64)) || (windows && (386 || amd64 || arm64)))
+
+// Package sqlite implements Storage based on a single SQLite database file, storing each blob as a row.
+//
+// The storage is only available on platforms supported by the pure-Go SQLite driver and can be
+// excluded from the build using the 'nosqlite' build tag.
+package sqlite
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	"fmt"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+	_ "modernc.org/sqlite" // registers the "sqlite" database driver
+
+	"github.com/kopia/kopia/internal/clock"
+	"github.com/kopia/kopia/repo/blob"
+	"github.com/kopia/kopia/repo/blob/retrying"
+)
+
+// Supported indicates whether SQLite storage is supported on this platform.
+const Supported = true
+
+const (
+	sqliteStorageType = "sqlite"
+
+	// busyTimeoutMillis is the time SQLite waits for locks held by other connections or processes.
+	busyTimeoutMillis = 30000
+)
+
+const createTableSQL = `CREATE TABLE IF NOT EXISTS blobs (
+	id TEXT NOT NULL PRIMARY KEY,
+	data BLOB NOT NULL,
+	mtime INTEGER NOT NULL
+) WITHOUT ROWID`
+
+type sqliteStorage struct {
+	Options
+	blob.DefaultProviderImplementation
+
+	db *sql.DB
+}
+
+func (s *sqliteStorage) GetBlob(ctx context.Context, id blob.ID, offset, length int64, output blob.OutputBuffer) error {
+	output.Reset()
+
+	if length < 0 {
+		var data []byte
+
+		if err := s.db.QueryRowContext(ctx, "SELECT data FROM blobs WHERE id = ?", string(id)).Scan(&data); err != nil {
+			return translateError(err)
+		}
+
+		_, err := output.Write(data)
+
+		return errors.Wrap(err, "error writing data to output")
+	}
+
+	if offset < 0 {
+		return errors.Wrap(blob.ErrInvalidRange, "invalid offset")
+	}
+
+	var (
+		data     []byte
+		fullSize int64
+	)
+
+	// substr() operates on bytes when applied to BLOB values and uses 1-based offsets.
+	if err := s.db.QueryRowContext(ctx, "SELECT substr(data, ?, ?), length(data) FROM blobs WHERE id = ?",
+		offset+1, length, string(id)).Scan(&data, &fullSize); err != nil {
+		return translateError(err)
+	}
+
+	if offset > fullSize || offset+length > fullSize {
+		return errors.Wrapf(blob.ErrInvalidRange, "invalid range %v+%v of blob with length %v", offset, length, fullSize)
+	}
+
+	if _, err := output.Write(data); err != nil {
+		return errors.Wrap(err, "error writing data to output")
+	}
+
+	//nolint:wrapcheck
+	return blob.EnsureLengthExactly(output.Length(), length)
+}
+
+func (s *sqliteStorage) GetMetadata(ctx context.Context, id blob.ID) (blob.Metadata, error) {
+	bm := blob.Metadata{BlobID: id}
+
+	var mtime int64
+
+	if err := s.db.QueryRowContext(ctx, "SELECT length(data), mtime FROM blobs WHERE id = ?", string(id)).Scan(&bm.Length, &mtime); err != nil {
+		return blob.Metadata{}, translateError(err)
+	}
+
+	bm.Timestamp = time.Unix(0, mtime)
+
+	return bm, nil
+}
+
+func (s *sqliteStorage) PutBlob(ctx context.Context, id blob.ID, data blob.Bytes, opts blob.PutOptions) error {
+	if opts.HasRetentionOptions() {
+		return errors.Wrap(blob.ErrUnsupportedPutBlobOption, "blob-retention")
+	}
+
+	mtime := opts.SetModTime
+	if mtime.IsZero() {
+		mtime = clock.Now()
+	}
+
+	insertSQL := "INSERT INTO blobs (id, data, mtime) VALUES (?, ?, ?) ON CONFLICT (id) DO UPDATE SET data = excluded.data, mtime = excluded.mtime"
+	if opts.DoNotRecreate {
+		insertSQL = "INSERT INTO blobs (id, data, mtime) VALUES (?, ?, ?) ON CONFLICT (id) DO NOTHING"
+	}
+
+	// empty buffer, to store empty blobs as zero-length values rather than NULL.
+	b := bytes.NewBuffer([]byte{})
+
+	if _, err := data.WriteTo(b); err != nil {
+		return errors.Wrap(err, "error reading blob data")
+	}
+
+	res, err := s.db.ExecContext(ctx, insertSQL, string(id), b.Bytes(), mtime.UnixNano())
+	if err != nil {
+		return errors.Wrap(err, "error writing blob")
+	}
+
+	if opts.DoNotRecreate {
+		if n, err := res.RowsAffected(); err == nil && n == 0 {
+			return blob.ErrBlobAlreadyExists
+		}
+	}
+
+	if opts.GetModTime != nil {
+		*opts.GetModTime = time.Unix(0, mtime.UnixNano())
+	}
+
+	return nil
+}
+
+func (s *sqliteStorage) DeleteBlob(ctx context.Context, id blob.ID) error {
+	_, err := s.db.ExecContext(ctx, "DELETE FROM blobs WHERE id = ?", string(id))
+
+	return errors.Wrap(err, "error deleting blob")
+}
+
+func (s *sqliteStorage) ListBlobs(ctx context.Context, prefix blob.ID, callback func(blob.Metadata) error) error {
+	// express the prefix match as a range of IDs, so that it is served by the primary key index.
+	query, args := "SELECT id, length(data), mtime FROM blobs WHERE id >= ?", []any{string(prefix)}
+
+	if upper, ok := prefixUpperBound(string(prefix)); ok {
+		query += " AND id < ?"
+		args = append(args, upper)
+	}
+
+	rows, err := s.db.QueryContext(ctx, query+" ORDER BY id", args...)
+	if err != nil {
+		return errors.Wrap(err, "error listing blobs")
+	}
+
+	defer rows.Close() //nolint:errcheck
+
+	// read all results before invoking callbacks, so that they can access the storage.
+	var result []blob.Metadata
+
+	for rows.Next() {
+		var (
+			bm    blob.Metadata
+			id    string
+			mtime int64
+		)
+
+		if err := rows.Scan(&id, &bm.Length, &mtime); err != nil {
+			return errors.Wrap(err, "error reading blob list")
+		}
+
+		bm.BlobID = blob.ID(id)
+		bm.Timestamp = time.Unix(0, mtime)
+
+		result = append(result, bm)
+	}
+
+	if err := rows.Err(); err != nil {
+		return errors.Wrap(err, "error listing blobs")
+	}
+
+	for _, bm := range result {
+		if err := callback(bm); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (s *sqliteStorage) ConnectionInfo() blob.ConnectionInfo {
+	return blob.ConnectionInfo{
+		Type:   sqliteStorageType,
+		Config: &s.Options,
+	}
+}
+
+func (s *sqliteStorage) DisplayName() string {
+	return fmt.Sprintf("SQLite: %v", s.Path)
+}
+
+func (s *sqliteStorage) Close(_ context.Context) error {
+	return errors.Wrap(s.db.Close(), "error closing database")
+}
+
+// prefixUpperBound returns the smallest string greater than all strings starting with the provided prefix,
+// or false if there is no such string.
+func prefixUpperBound(prefix string) (string, bool) {
+	b := []byte(strings.TrimRight(prefix, "\xff"))
+	if len(b) == 0 {
+		return "", false
+	}
+
+	b[len(b)-1]++
+
+	return string(b), true
+}
+
+// dataSourceName returns the URI used to open the database file at the provided path.
+func dataSourceName(path string) string {
+	p := filepath.ToSlash(path)
+	if !strings.HasPrefix(p, "/") {
+		// Windows paths with drive letters, see https://www.sqlite.org/uri.html
+		p = "/" + p
+	}
+
+	u := url.URL{
+		Scheme: "file",
+		Path:   p,
+		RawQuery: url.Values{
+			"_pragma": {
+				fmt.Sprintf("busy_timeout(%v)", busyTimeoutMillis),
+				"journal_mode(WAL)",
+			},
+		}.Encode(),
+	}
+
+	return u.String()
+}
+
+func translateError(err error) error {
+	if errors.Is(err, sql.ErrNoRows) {
+		return blob.ErrBlobNotFound
+	}
+
+	return errors.Wrap(err, "error reading blob")
+}
+
+// New creates new SQLite-backed storage in the specified database file.
+func New(ctx context.Context, opt *Options, isCreate bool) (blob.Storage, error) {
+	if opt.Path == "" {
+		return nil, errors.New("database path must be specified")
+	}
+
+	if !isCreate {
+		if _, err := os.Stat(opt.Path); err != nil {
+			return nil, errors.Wrap(err, "cannot access storage path")
+		}
+	}
+
+	db, err := sql.Open("sqlite", dataSourceName(opt.Path))
+	if err != nil {
+		return nil, errors.Wrap(err, "error opening database")
+	}
+
+	if _, err := db.ExecContext(ctx, createTableSQL); err != nil {
+		db.Close() //nolint:errcheck
+
+		return nil, errors.Wrap(err, "error initializing database")
+	}
+
+	return retrying.NewWrapperWithPolicy(&sqliteStorage{
+		Options: *opt,
+		db:      db,
+	}, opt.Policy, nil), nil
+}
+
+func init() {
+	blob.AddSupportedStorage(sqliteStorageType, Options{}, New)
+}
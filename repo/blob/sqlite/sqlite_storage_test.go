@@ -0,0 +1,84 @@
+package sqlite_test
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/kopia/kopia/internal/blobtesting"
+	"github.com/kopia/kopia/internal/gather"
+	"github.com/kopia/kopia/internal/providervalidation"
+	"github.com/kopia/kopia/internal/testlogging"
+	"github.com/kopia/kopia/internal/testutil"
+	"github.com/kopia/kopia/repo/blob"
+	"github.com/kopia/kopia/repo/blob/sqlite"
+)
+
+func TestSQLiteStorage(t *testing.T) {
+	t.Parallel()
+
+	if !sqlite.Supported {
+		t.Skip("SQLite is not supported on this platform")
+	}
+
+	ctx := testlogging.Context(t)
+
+	// characters with special meaning in URIs are handled.
+	dir := filepath.Join(testutil.TempDirectory(t), "some dir#%")
+	require.NoError(t, os.Mkdir(dir, 0o700))
+
+	path := filepath.Join(dir, "repo.db")
+
+	// connecting to a database which does not exist fails.
+	_, err := sqlite.New(ctx, &sqlite.Options{Path: path}, false)
+	require.Error(t, err)
+
+	newctx, cancel := context.WithCancel(ctx)
+
+	// use context that gets canceled after opening storage to ensure it's not used beyond New().
+	st, err := sqlite.New(newctx, &sqlite.Options{Path: path}, true)
+
+	cancel()
+	require.NoError(t, err)
+
+	blobtesting.VerifyStorage(ctx, t, st, blob.PutOptions{})
+	blobtesting.AssertConnectionInfoRoundTrips(ctx, t, st)
+
+	require.NoError(t, st.PutBlob(ctx, "someblob", gather.FromSlice([]byte{1, 2, 3}), blob.PutOptions{}))
+	require.ErrorIs(t, st.PutBlob(ctx, "someblob", gather.FromSlice([]byte{4}), blob.PutOptions{DoNotRecreate: true}), blob.ErrBlobAlreadyExists)
+	require.NoError(t, st.Close(ctx))
+
+	// blobs are persisted in the database file.
+	st, err = sqlite.New(ctx, &sqlite.Options{Path: path}, false)
+	require.NoError(t, err)
+
+	defer st.Close(ctx)
+
+	var tmp gather.WriteBuffer
+	defer tmp.Close()
+
+	require.NoError(t, st.GetBlob(ctx, "someblob", 0, -1, &tmp))
+	require.Equal(t, []byte{1, 2, 3}, tmp.ToByteSlice())
+}
+
+func TestSQLiteStorageValidate(t *testing.T) {
+	t.Parallel()
+
+	testutil.ProviderTest(t)
+
+	if !sqlite.Supported {
+		t.Skip("SQLite is not supported on this platform")
+	}
+
+	ctx := testlogging.Context(t)
+
+	st, err := sqlite.New(ctx, &sqlite.Options{Path: filepath.Join(testutil.TempDirectory(t), "repo.db")}, true)
+	require.NoError(t, err)
+
+	defer st.Close(ctx)
+
+	require.NoError(t, providervalidation.ValidateProvider(ctx, st, blobtesting.TestValidationOptions))
+}
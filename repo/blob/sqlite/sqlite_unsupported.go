@@ -0,0 +1,23 @@
+//go:build nosqlite || !((darwin && (amd64 || arm64)) || (freebsd && (386 || amd64 || arm || arm64)) || (linux && (386 || amd64 || arm || arm64 || loong64 || ppc64le || riscv64 || s390x)) || (netbsd && amd64) || (openbsd && (amd64 || arm64)) || (windows && (386 || amd64 || arm64)))
+
+// Package sqlite implements Storage based on a single SQLite database file, storing each blob as a row.
+//
+// The storage is only available on platforms supported by the pure-Go SQLite driver and can be
+// excluded from the build using the 'nosqlite' build tag.
+package sqlite
+
+import (
+	"context"
+
+	"github.com/pkg/errors"
+
+	"github.com/kopia/kopia/repo/blob"
+)
+
+// Supported indicates whether SQLite storage is supported on this platform.
+const Supported = false
+
+// New returns an error since SQLite storage is not supported on this platform.
+func New(_ context.Context, _ *Options, _ bool) (blob.Storage, error) {
+	return nil, errors.New("SQLite storage is not supported on this platform")
+}
@@ -134,6 +134,9 @@ type PutOptions struct {
 	// if unsupported by the server return ErrSetTimeUnsupported
 	SetModTime time.Time
 	GetModTime *time.Time // if != nil, populate the value pointed at with the actual modification time
+
+	// if not empty, the storage class (or access tier) of the blob, ignored by providers which don't support storage classes.
+	StorageClass string
 }
 
 // ExtendOptions represents retention options for extending object locks.
@@ -225,6 +228,10 @@ type Metadata struct {
 	BlobID    ID        `json:"id"`
 	Length    int64     `json:"length"`
 	Timestamp time.Time `json:"timestamp"`
+
+	// Version is an opaque identifier of the blob contents, such as ETag or generation number,
+	// which changes whenever the blob is overwritten. Empty if not supported by the storage.
+	Version string `json:"version,omitempty"`
 }
 
 func (m *Metadata) String() string {
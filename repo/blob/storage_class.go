@@ -0,0 +1,63 @@
+package blob
+
+import (
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// PrefixStorageClass specifies the storage class (or access tier) of blobs with IDs starting with a prefix.
+type PrefixStorageClass struct {
+	Prefix       ID     `json:"prefix"`
+	StorageClass string `json:"storageClass"`
+}
+
+func (p PrefixStorageClass) String() string {
+	return string(p.Prefix) + "=" + p.StorageClass
+}
+
+// ParsePrefixStorageClass parses the storage class of a prefix specified as PREFIX=CLASS.
+func ParsePrefixStorageClass(s string) (PrefixStorageClass, error) {
+	prefix, class, ok := strings.Cut(s, "=")
+	if !ok || class == "" {
+		return PrefixStorageClass{}, errors.Errorf("invalid storage class %q, expected PREFIX=CLASS", s)
+	}
+
+	return PrefixStorageClass{ID(prefix), class}, nil
+}
+
+// StorageClassForBlob returns the storage class of the blob, which is the one specified in PutOptions or
+// the one configured for the longest matching prefix. Returns empty string if none applies.
+func StorageClassForBlob(classes []PrefixStorageClass, id ID, opts PutOptions) string {
+	if opts.StorageClass != "" {
+		return opts.StorageClass
+	}
+
+	var (
+		result     string
+		longestLen = -1
+	)
+
+	for _, c := range classes {
+		if strings.HasPrefix(string(id), string(c.Prefix)) && len(c.Prefix) > longestLen {
+			result = c.StorageClass
+			longestLen = len(c.Prefix)
+		}
+	}
+
+	return result
+}
+
+// archiveStorageClasses are storage classes (or access tiers) of S3, GCS and Azure, blobs in which
+// cannot be read without restoring them first.
+var archiveStorageClasses = map[string]bool{
+	"GLACIER":      true,
+	"DEEP_ARCHIVE": true,
+	"ARCHIVE":      true,
+}
+
+// IsArchiveStorageClass returns true if blobs in the provided storage class are archived and cannot be read
+// without restoring them first.
+func IsArchiveStorageClass(class string) bool {
+	return archiveStorageClasses[strings.ToUpper(class)]
+}
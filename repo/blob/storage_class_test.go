@@ -0,0 +1,63 @@
+package blob_test
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/kopia/kopia/repo/blob"
+)
+
+func TestStorageClassForBlob(t *testing.T) {
+	classes := []blob.PrefixStorageClass{
+		{"p", "STANDARD_IA"},
+		{"pa", "GLACIER_IR"},
+		{"q", "STANDARD"},
+	}
+
+	cases := []struct {
+		id   blob.ID
+		opts blob.PutOptions
+		want string
+	}{
+		{"p1234", blob.PutOptions{}, "STANDARD_IA"},
+		{"pa123", blob.PutOptions{}, "GLACIER_IR"},
+		{"q1234", blob.PutOptions{}, "STANDARD"},
+		{"n1234", blob.PutOptions{}, ""},
+		{"kopia.repository", blob.PutOptions{}, ""},
+		{"p1234", blob.PutOptions{StorageClass: "ONEZONE_IA"}, "ONEZONE_IA"},
+	}
+
+	for _, tc := range cases {
+		require.Equal(t, tc.want, blob.StorageClassForBlob(classes, tc.id, tc.opts), "%v", tc.id)
+	}
+
+	require.Empty(t, blob.StorageClassForBlob(nil, "p1234", blob.PutOptions{}))
+}
+
+func TestParsePrefixStorageClass(t *testing.T) {
+	p, err := blob.ParsePrefixStorageClass("p=STANDARD_IA")
+	require.NoError(t, err)
+	require.Equal(t, blob.PrefixStorageClass{Prefix: "p", StorageClass: "STANDARD_IA"}, p)
+	require.Equal(t, "p=STANDARD_IA", p.String())
+
+	// empty prefix applies to all blobs.
+	p, err = blob.ParsePrefixStorageClass("=Cool")
+	require.NoError(t, err)
+	require.Equal(t, blob.PrefixStorageClass{Prefix: "", StorageClass: "Cool"}, p)
+
+	for _, bad := range []string{"", "p", "p="} {
+		_, err := blob.ParsePrefixStorageClass(bad)
+		require.Error(t, err, bad)
+	}
+}
+
+func TestIsArchiveStorageClass(t *testing.T) {
+	for _, c := range []string{"GLACIER", "DEEP_ARCHIVE", "ARCHIVE", "Archive"} {
+		require.True(t, blob.IsArchiveStorageClass(c), c)
+	}
+
+	for _, c := range []string{"", "STANDARD", "STANDARD_IA", "GLACIER_IR", "COLDLINE", "Cool", "Cold"} {
+		require.False(t, blob.IsArchiveStorageClass(c), c)
+	}
+}
@@ -3,6 +3,7 @@
 
 import (
 	"context"
+	"strings"
 	"time"
 
 	"github.com/kopia/kopia/internal/metrics"
@@ -38,6 +39,64 @@ type blobMetrics struct {
 	listBlobsErrors           *metrics.Counter
 	closeErrors               *metrics.Counter
 	flushCachesErrors         *metrics.Counter
+
+	byClass map[string]*classMetrics
+}
+
+// classMetrics holds metrics of operations on blobs of a single class.
+type classMetrics struct {
+	downloadedBytes *metrics.Counter
+	uploadedBytes   *metrics.Counter
+
+	getBlobDuration     *metrics.Distribution[time.Duration]
+	getMetadataDuration *metrics.Distribution[time.Duration]
+	putBlobDuration     *metrics.Distribution[time.Duration]
+	deleteBlobDuration  *metrics.Distribution[time.Duration]
+}
+
+// Blob classes used to break down metrics by the kind of data stored in blobs.
+const (
+	ClassData     = "data"     // pack blobs with file contents
+	ClassMetadata = "metadata" // pack blobs with directory listings and manifests
+	ClassIndex    = "index"    // index blobs and compaction logs
+	ClassFormat   = "format"   // repository format, blob configuration and maintenance schedule
+	ClassLog      = "log"      // diagnostic logs
+	ClassSession  = "session"  // session markers
+	ClassOther    = "other"
+)
+
+//nolint:gochecknoglobals
+var (
+	blobClassPrefixes = []struct {
+		prefix blob.ID
+		class  string
+	}{
+		{"p", ClassData},
+		{"q", ClassMetadata},
+		{"n", ClassIndex},
+		{"x", ClassIndex},
+		{"m", ClassIndex},
+		{"kopia.", ClassFormat},
+		{"_log_", ClassLog},
+		{"s", ClassSession},
+	}
+
+	allClasses = []string{ClassData, ClassMetadata, ClassIndex, ClassFormat, ClassLog, ClassSession, ClassOther}
+)
+
+// BlobClass returns the class of the blob with the provided ID, which is determined by its prefix.
+func BlobClass(id blob.ID) string {
+	for _, c := range blobClassPrefixes {
+		if strings.HasPrefix(string(id), string(c.prefix)) {
+			return c.class
+		}
+	}
+
+	return ClassOther
+}
+
+func (s *blobMetrics) forBlob(id blob.ID) *classMetrics {
+	return s.byClass[BlobClass(id)]
 }
 
 func (s *blobMetrics) GetBlob(ctx context.Context, id blob.ID, offset, length int64, output blob.OutputBuffer) error {
@@ -45,6 +104,10 @@ func (s *blobMetrics) GetBlob(ctx context.Context, id blob.ID, offset, length in
 	err := s.base.GetBlob(ctx, id, offset, length, output)
 	dt := timer.Elapsed()
 
+	cm := s.forBlob(id)
+	cm.downloadedBytes.Add(int64(output.Length()))
+	cm.getBlobDuration.Observe(dt)
+
 	if length < 0 {
 		s.downloadedBytesFull.Add(int64(output.Length()))
 		s.getBlobFullDuration.Observe(dt)
@@ -86,6 +149,7 @@ func (s *blobMetrics) GetMetadata(ctx context.Context, id blob.ID) (blob.Metadat
 	dt := timer.Elapsed()
 
 	s.getMetadataDuration.Observe(dt)
+	s.forBlob(id).getMetadataDuration.Observe(dt)
 
 	if err != nil {
 		s.getMetadataErrors.Add(1)
@@ -102,10 +166,14 @@ func (s *blobMetrics) PutBlob(ctx context.Context, id blob.ID, data blob.Bytes,
 
 	s.putBlobDuration.Observe(dt)
 
+	cm := s.forBlob(id)
+	cm.putBlobDuration.Observe(dt)
+
 	if err != nil {
 		s.putBlobErrors.Add(1)
 	} else {
 		s.uploadedBytes.Add(int64(data.Length()))
+		cm.uploadedBytes.Add(int64(data.Length()))
 	}
 
 	//nolint:wrapcheck
@@ -118,6 +186,7 @@ func (s *blobMetrics) DeleteBlob(ctx context.Context, id blob.ID) error {
 	dt := timer.Elapsed()
 
 	s.deleteBlobDuration.Observe(dt)
+	s.forBlob(id).deleteBlobDuration.Observe(dt)
 
 	if err != nil {
 		s.deleteBlobErrors.Add(1)
@@ -200,7 +269,8 @@ func (s *blobMetrics) FlushCaches(ctx context.Context) error {
 	return err
 }
 
-// NewWrapper returns a Storage wrapper that logs all storage commands.
+// NewWrapper returns a Storage wrapper that records metrics of all storage operations, additionally
+// broken down by blob class (see BlobClass()) for operations on individual blobs.
 func NewWrapper(wrapped blob.Storage, mr *metrics.Registry) blob.Storage {
 	durationSummaryForMethod := func(m string) *metrics.Distribution[time.Duration] {
 		return mr.DurationDistribution(
@@ -219,8 +289,33 @@ func NewWrapper(wrapped blob.Storage, mr *metrics.Registry) blob.Storage {
 		)
 	}
 
+	classDurationForMethod := func(class, m string) *metrics.Distribution[time.Duration] {
+		return mr.DurationDistribution(
+			"blob_storage_latency_by_class",
+			"Latency of blob storage operation by blob class and method",
+			metrics.IOLatencyThresholds,
+			map[string]string{"class": class, "method": m},
+		)
+	}
+
+	byClass := map[string]*classMetrics{}
+
+	for _, c := range allClasses {
+		labels := map[string]string{"class": c}
+
+		byClass[c] = &classMetrics{
+			downloadedBytes:     mr.CounterInt64("blob_download_bytes_by_class", "Number of bytes downloaded by blob class", labels),
+			uploadedBytes:       mr.CounterInt64("blob_upload_bytes_by_class", "Number of bytes uploaded by blob class", labels),
+			getBlobDuration:     classDurationForMethod(c, "GetBlob"),
+			getMetadataDuration: classDurationForMethod(c, "GetMetadata"),
+			putBlobDuration:     classDurationForMethod(c, "PutBlob"),
+			deleteBlobDuration:  classDurationForMethod(c, "DeleteBlob"),
+		}
+	}
+
 	return &blobMetrics{
-		base: wrapped,
+		base:    wrapped,
+		byClass: byClass,
 
 		downloadedBytesPartial: mr.CounterInt64("blob_download_partial_blob_bytes", "Number of bytes downloaded as partial blobs", nil),
 		downloadedBytesFull:    mr.CounterInt64("blob_download_full_blob_bytes", "Number of bytes downloaded as full blobs", nil),
@@ -286,3 +286,66 @@ func requireCounterValue(t *testing.T, snap metrics.Snapshot, key string, want i
 	require.True(t, ok)
 	require.EqualValues(t, want, v)
 }
+
+func TestStorageMetrics_ByClass(t *testing.T) {
+	ctx := testlogging.Context(t)
+	st := blobtesting.NewMapStorage(blobtesting.DataMap{}, nil, nil)
+
+	mr := metrics.NewRegistry()
+	ms := storagemetrics.NewWrapper(st, mr)
+
+	require.NoError(t, ms.PutBlob(ctx, "pabcdef", gather.FromSlice([]byte{1, 2, 3, 4, 5}), blob.PutOptions{}))
+	require.NoError(t, ms.PutBlob(ctx, "xn0_abcdef", gather.FromSlice([]byte{1, 2, 3}), blob.PutOptions{}))
+	require.NoError(t, ms.PutBlob(ctx, "kopia.repository", gather.FromSlice([]byte{1, 2}), blob.PutOptions{}))
+
+	var tmp gather.WriteBuffer
+	defer tmp.Close()
+
+	require.NoError(t, ms.GetBlob(ctx, "pabcdef", 1, 2, &tmp))
+	require.NoError(t, ms.GetBlob(ctx, "pabcdef", 0, -1, &tmp))
+	require.NoError(t, ms.GetBlob(ctx, "kopia.repository", 0, -1, &tmp))
+
+	_, err := ms.GetMetadata(ctx, "xn0_abcdef")
+	require.NoError(t, err)
+
+	require.NoError(t, ms.DeleteBlob(ctx, "xn0_abcdef"))
+
+	snap := mr.Snapshot(false)
+
+	requireCounterValue(t, snap, "blob_upload_bytes", 10)
+	requireCounterValue(t, snap, "blob_upload_bytes_by_class[class:data]", 5)
+	requireCounterValue(t, snap, "blob_upload_bytes_by_class[class:index]", 3)
+	requireCounterValue(t, snap, "blob_upload_bytes_by_class[class:format]", 2)
+	requireCounterValue(t, snap, "blob_upload_bytes_by_class[class:log]", 0)
+
+	requireCounterValue(t, snap, "blob_download_bytes_by_class[class:data]", 7)
+	requireCounterValue(t, snap, "blob_download_bytes_by_class[class:format]", 2)
+	requireCounterValue(t, snap, "blob_download_bytes_by_class[class:index]", 0)
+
+	require.EqualValues(t, 2, snap.DurationDistributions["blob_storage_latency_by_class[class:data;method:GetBlob]"].Count)
+	require.EqualValues(t, 1, snap.DurationDistributions["blob_storage_latency_by_class[class:format;method:GetBlob]"].Count)
+	require.EqualValues(t, 1, snap.DurationDistributions["blob_storage_latency_by_class[class:index;method:PutBlob]"].Count)
+	require.EqualValues(t, 1, snap.DurationDistributions["blob_storage_latency_by_class[class:index;method:GetMetadata]"].Count)
+	require.EqualValues(t, 1, snap.DurationDistributions["blob_storage_latency_by_class[class:index;method:DeleteBlob]"].Count)
+}
+
+func TestBlobClass(t *testing.T) {
+	cases := map[blob.ID]string{
+		"pabcdef":           storagemetrics.ClassData,
+		"qabcdef":           storagemetrics.ClassMetadata,
+		"n0123":             storagemetrics.ClassIndex,
+		"xn0_abc":           storagemetrics.ClassIndex,
+		"m0123":             storagemetrics.ClassIndex,
+		"kopia.repository":  storagemetrics.ClassFormat,
+		"kopia.blobcfg":     storagemetrics.ClassFormat,
+		"kopia.maintenance": storagemetrics.ClassFormat,
+		"_log_20200101_abc": storagemetrics.ClassLog,
+		"s0123":             storagemetrics.ClassSession,
+		"zzz":               storagemetrics.ClassOther,
+		"":                  storagemetrics.ClassOther,
+	}
+
+	for id, want := range cases {
+		require.Equal(t, want, storagemetrics.BlobClass(id), id)
+	}
+}
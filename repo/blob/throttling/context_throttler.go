@@ -0,0 +1,18 @@
+package throttling
+
+import "context"
+
+type contextThrottlerKey struct{}
+
+// WithThrottler returns a context in which storage operations are subject to the provided throttler
+// in addition to the throttler of the storage. This allows limiting bandwidth and concurrency of a single
+// activity, such as a snapshot of one source or a maintenance task, without affecting other activities.
+func WithThrottler(ctx context.Context, t Throttler) context.Context {
+	return context.WithValue(ctx, contextThrottlerKey{}, t)
+}
+
+func throttlerFromContext(ctx context.Context) Throttler {
+	t, _ := ctx.Value(contextThrottlerKey{}).(Throttler)
+
+	return t
+}
@@ -0,0 +1,143 @@
+package throttling
+
+import (
+	"context"
+	"math"
+	"math/rand"
+	"sync"
+	"time"
+
+	"github.com/kopia/kopia/internal/clock"
+)
+
+const (
+	// initial and maximum delay before resuming operations after the provider throttled a request,
+	// doubled for each consecutive throttling signal and randomized by up to 50%.
+	backoffInitialDelay = 1 * time.Second
+	backoffMaxDelay     = 1 * time.Minute
+
+	// each throttling signal reduces rate limits by this factor, down to the minimum.
+	backoffRateDecrease  = 0.5
+	backoffMinRateFactor = 1.0 / 16
+
+	// signals received shortly after the rate was reduced are usually caused by requests issued
+	// before the reduction, so they don't reduce it further.
+	backoffMinDecreaseInterval = 1 * time.Second
+
+	// after operations were resumed for this much time without throttling signals, rate limits are doubled
+	// until fully recovered.
+	backoffRecoveryInterval = 30 * time.Second
+)
+
+// providerBackoff tracks throttling signals received from the storage provider, pauses operations
+// after each signal and computes the factor by which rate limits are reduced.
+type providerBackoff struct {
+	now    func() time.Time
+	sleep  func(ctx context.Context, d time.Duration)
+	jitter func(d time.Duration) time.Duration
+
+	mu sync.Mutex
+	// +checklocks:mu
+	rateFactor float64
+	// +checklocks:mu
+	consecutiveSignals int
+	// +checklocks:mu
+	lastSignal time.Time
+	// +checklocks:mu
+	lastDecrease time.Time
+	// +checklocks:mu
+	lastAdjustment time.Time
+	// +checklocks:mu
+	pausedUntil time.Time
+}
+
+// currentRateFactor returns the current factor by which rate limits are reduced.
+func (b *providerBackoff) currentRateFactor() float64 {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	return b.rateFactor
+}
+
+// onThrottled records the throttling signal and returns the pause before operations are resumed
+// and whether the rate factor has changed.
+func (b *providerBackoff) onThrottled(retryAfter time.Duration) (pause time.Duration, rateChanged bool) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	now := b.now()
+
+	if now.Sub(b.lastSignal) >= backoffRecoveryInterval {
+		b.consecutiveSignals = 0
+	}
+
+	b.lastSignal = now
+
+	pause = b.jitter(time.Duration(math.Min(
+		float64(backoffInitialDelay)*math.Pow(2, float64(b.consecutiveSignals)), //nolint:mnd
+		float64(backoffMaxDelay))))
+	pause = max(pause, retryAfter)
+
+	b.consecutiveSignals++
+
+	if until := now.Add(pause); until.After(b.pausedUntil) {
+		b.pausedUntil = until
+	}
+
+	if b.rateFactor > backoffMinRateFactor && now.Sub(b.lastDecrease) >= backoffMinDecreaseInterval {
+		b.rateFactor = max(b.rateFactor*backoffRateDecrease, backoffMinRateFactor)
+		b.lastDecrease = now
+		b.lastAdjustment = now
+		rateChanged = true
+	}
+
+	return pause, rateChanged
+}
+
+// waitAndRecover waits until operations are resumed and returns true if the rate factor has recovered.
+func (b *providerBackoff) waitAndRecover(ctx context.Context) (rateChanged bool) {
+	b.mu.Lock()
+	pausedFor := b.pausedUntil.Sub(b.now())
+	b.mu.Unlock()
+
+	if pausedFor > 0 {
+		log(ctx).Debugf("pausing for %v after storage provider throttled requests", pausedFor)
+		b.sleep(ctx, pausedFor)
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if b.rateFactor >= 1 {
+		return false
+	}
+
+	now := b.now()
+	// recover only after operations have been resumed for a while without further signals.
+	if now.Sub(b.pausedUntil) < backoffRecoveryInterval || now.Sub(b.lastAdjustment) < backoffRecoveryInterval {
+		return false
+	}
+
+	b.rateFactor = min(b.rateFactor/backoffRateDecrease, 1)
+	b.lastAdjustment = now
+
+	return true
+}
+
+func randomJitter(d time.Duration) time.Duration {
+	if d < 2 {
+		return d
+	}
+
+	//nolint:gosec,mnd
+	return d/2 + time.Duration(rand.Int63n(int64(d/2)))
+}
+
+func newProviderBackoff() *providerBackoff {
+	return &providerBackoff{
+		now:        clock.Now,
+		sleep:      sleepWithContext,
+		jitter:     randomJitter,
+		rateFactor: 1,
+	}
+}
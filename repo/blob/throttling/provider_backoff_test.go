@@ -0,0 +1,124 @@
+package throttling
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestThrottlerProviderBackoff(t *testing.T) {
+	limits := Limits{
+		ReadsPerSecond:   100,
+		WritesPerSecond:  10,
+		ConcurrentReads:  8,
+		ConcurrentWrites: 1,
+	}
+
+	ctx := context.Background()
+
+	th, err := NewThrottler(limits, time.Second, 1.0)
+	require.NoError(t, err)
+
+	tt, ok := th.(*tokenBucketBasedThrottler)
+	require.True(t, ok)
+
+	currentTime := time.Date(2020, 1, 1, 12, 0, 0, 0, time.UTC)
+
+	var slept []time.Duration
+
+	tt.backoff.now = func() time.Time { return currentTime }
+	tt.backoff.jitter = func(d time.Duration) time.Duration { return d }
+	tt.backoff.sleep = func(_ context.Context, d time.Duration) {
+		slept = append(slept, d)
+		currentTime = currentTime.Add(d)
+	}
+
+	verifyEffectiveLimits := func(f float64) {
+		t.Helper()
+
+		require.InDelta(t, limits.ReadsPerSecond*f, tt.readOps.maxTokens, 1e-9)
+		require.InDelta(t, limits.WritesPerSecond*f, tt.writeOps.maxTokens, 1e-9)
+		require.Equal(t, max(int(float64(limits.ConcurrentReads)*f), 1), cap(tt.concurrentReads.sem))
+		require.Equal(t, 1, cap(tt.concurrentWrites.sem))
+
+		// configured limits are not affected.
+		require.Equal(t, limits, th.Limits())
+	}
+
+	th.BeforeOperation(ctx, operationListBlobs)
+	require.Empty(t, slept)
+	verifyEffectiveLimits(1)
+
+	// the first signal pauses operations for the initial delay and halves the limits.
+	tt.OnProviderThrottled(ctx, 0)
+	verifyEffectiveLimits(0.5)
+
+	th.BeforeOperation(ctx, operationListBlobs)
+	require.Equal(t, []time.Duration{backoffInitialDelay}, slept)
+
+	// consecutive signal doubles the pause, the delay requested by the provider takes precedence if longer.
+	tt.OnProviderThrottled(ctx, 0)
+	verifyEffectiveLimits(0.25)
+
+	tt.OnProviderThrottled(ctx, 10*time.Second)
+	verifyEffectiveLimits(0.25) // signals received shortly after the reduction don't reduce the limits further.
+
+	slept = nil
+
+	th.BeforeOperation(ctx, operationListBlobs)
+	require.Equal(t, []time.Duration{10 * time.Second}, slept)
+
+	// limits never drop below the minimum.
+	for range 10 {
+		currentTime = currentTime.Add(backoffMinDecreaseInterval)
+		tt.OnProviderThrottled(ctx, 0)
+	}
+
+	verifyEffectiveLimits(backoffMinRateFactor)
+
+	// pause does not exceed the maximum.
+	slept = nil
+
+	th.BeforeOperation(ctx, operationListBlobs)
+	require.Len(t, slept, 1)
+	require.LessOrEqual(t, slept[0], backoffMaxDelay)
+
+	// limits are restored gradually when there are no more signals.
+	for _, f := range []float64{2, 4, 8, 16} {
+		th.BeforeOperation(ctx, operationListBlobs)
+		verifyEffectiveLimits(backoffMinRateFactor * f / 2)
+
+		currentTime = currentTime.Add(backoffRecoveryInterval)
+
+		th.BeforeOperation(ctx, operationListBlobs)
+		verifyEffectiveLimits(backoffMinRateFactor * f)
+	}
+
+	verifyEffectiveLimits(1)
+
+	// after recovery, the next signal starts with the initial delay again.
+	slept = nil
+
+	tt.OnProviderThrottled(ctx, 0)
+	th.BeforeOperation(ctx, operationListBlobs)
+	require.Equal(t, []time.Duration{backoffInitialDelay}, slept)
+	verifyEffectiveLimits(0.5)
+
+	// updated limits are reduced as well.
+	limits.ReadsPerSecond = 50
+	require.NoError(t, th.SetLimits(limits))
+	verifyEffectiveLimits(0.5)
+}
+
+func TestParseRetryAfter(t *testing.T) {
+	now := time.Date(2015, 10, 21, 7, 28, 0, 0, time.UTC)
+
+	require.Equal(t, time.Duration(0), ParseRetryAfter("", now))
+	require.Equal(t, time.Duration(0), ParseRetryAfter("garbage", now))
+	require.Equal(t, time.Duration(0), ParseRetryAfter("-5", now))
+	require.Equal(t, 120*time.Second, ParseRetryAfter(" 120 ", now))
+	require.Equal(t, 30*time.Second, ParseRetryAfter("Wed, 21 Oct 2015 07:28:30 GMT", now))
+	require.Equal(t, time.Duration(0), ParseRetryAfter("Wed, 21 Oct 2015 07:27:00 GMT", now))
+}
@@ -0,0 +1,102 @@
+package throttling
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+
+	"github.com/kopia/kopia/internal/timeofday"
+)
+
+const fullPercent = 100
+
+// ScheduledLimit scales all limits to the provided percentage of their values during a daily time window.
+// Windows where the end is before the start span midnight and windows where both are equal span the entire day.
+type ScheduledLimit struct {
+	Start   timeofday.TimeOfDay `json:"start"`
+	End     timeofday.TimeOfDay `json:"end"`
+	Percent float64             `json:"percent"`
+}
+
+// ParseScheduledLimit parses scheduled limit in the HH:MM-HH:MM=P% format, such as "09:00-17:00=20%".
+func ParseScheduledLimit(s string) (ScheduledLimit, error) {
+	var sl ScheduledLimit
+
+	window, percent, ok := strings.Cut(s, "=")
+	if !ok {
+		return sl, errors.Errorf("invalid scheduled limit %q, must be HH:MM-HH:MM=P%%", s)
+	}
+
+	start, end, ok := strings.Cut(window, "-")
+	if !ok {
+		return sl, errors.Errorf("invalid time window %q, must be HH:MM-HH:MM", window)
+	}
+
+	if err := sl.Start.Parse(strings.TrimSpace(start)); err != nil {
+		return sl, errors.Wrap(err, "start")
+	}
+
+	if err := sl.End.Parse(strings.TrimSpace(end)); err != nil {
+		return sl, errors.Wrap(err, "end")
+	}
+
+	p, err := strconv.ParseFloat(strings.TrimSuffix(strings.TrimSpace(percent), "%"), 64)
+	if err != nil {
+		return sl, errors.Errorf("invalid percentage %q", percent)
+	}
+
+	sl.Percent = p
+
+	return sl, sl.validate()
+}
+
+// String returns string representation of the scheduled limit, which can be parsed using ParseScheduledLimit().
+func (sl ScheduledLimit) String() string {
+	return fmt.Sprintf("%v-%v=%v%%", sl.Start, sl.End, strconv.FormatFloat(sl.Percent, 'f', -1, 64))
+}
+
+func (sl ScheduledLimit) validate() error {
+	for _, t := range []timeofday.TimeOfDay{sl.Start, sl.End} {
+		if err := t.Validate(); err != nil {
+			return err //nolint:wrapcheck
+		}
+	}
+
+	// zero limits are unlimited, so limits can't be scaled down to zero.
+	if sl.Percent <= 0 || sl.Percent > fullPercent {
+		return errors.Errorf("invalid percentage %v, must be greater than 0 and at most 100", sl.Percent)
+	}
+
+	return nil
+}
+
+// contains returns true if the provided time is within the time window.
+func (sl ScheduledLimit) contains(t time.Time) bool {
+	return timeofday.InWindow(sl.Start, sl.End, t)
+}
+
+// scheduleFactor returns the factor by which limits are scaled at the provided time according to the
+// first matching scheduled limit, or 1 if none matches.
+func scheduleFactor(schedule []ScheduledLimit, t time.Time) float64 {
+	for _, sl := range schedule {
+		if sl.contains(t) {
+			return sl.Percent / fullPercent
+		}
+	}
+
+	return 1
+}
+
+// validateSchedule ensures all scheduled limits are valid.
+func validateSchedule(schedule []ScheduledLimit) error {
+	for i, sl := range schedule {
+		if err := sl.validate(); err != nil {
+			return errors.Wrapf(err, "schedule[%v]", i)
+		}
+	}
+
+	return nil
+}
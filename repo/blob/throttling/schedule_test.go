@@ -0,0 +1,117 @@
+package throttling
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/kopia/kopia/internal/clock"
+	"github.com/kopia/kopia/internal/faketime"
+	"github.com/kopia/kopia/internal/testlogging"
+	"github.com/kopia/kopia/internal/timeofday"
+)
+
+func TestParseScheduledLimit(t *testing.T) {
+	cases := []struct {
+		input   string
+		want    ScheduledLimit
+		wantErr bool
+	}{
+		{input: "09:00-17:00=20%", want: ScheduledLimit{timeofday.TimeOfDay{Hour: 9, Minute: 0}, timeofday.TimeOfDay{Hour: 17, Minute: 0}, 20}},
+		{input: "22:30-6:15=50", want: ScheduledLimit{timeofday.TimeOfDay{Hour: 22, Minute: 30}, timeofday.TimeOfDay{Hour: 6, Minute: 15}, 50}},
+		{input: " 0:00 - 0:00 = 12.5% ", want: ScheduledLimit{timeofday.TimeOfDay{Hour: 0, Minute: 0}, timeofday.TimeOfDay{Hour: 0, Minute: 0}, 12.5}},
+		{input: "09:00-17:00", wantErr: true},
+		{input: "09:00=20%", wantErr: true},
+		{input: "24:00-17:00=20%", wantErr: true},
+		{input: "09:00-17:60=20%", wantErr: true},
+		{input: "09:00-17:00=x%", wantErr: true},
+		{input: "09:00-17:00=0%", wantErr: true},
+		{input: "09:00-17:00=101%", wantErr: true},
+	}
+
+	for _, tc := range cases {
+		sl, err := ParseScheduledLimit(tc.input)
+		if tc.wantErr {
+			require.Error(t, err, tc.input)
+			continue
+		}
+
+		require.NoError(t, err, tc.input)
+		require.Equal(t, tc.want, sl, tc.input)
+
+		// round-trip
+		sl2, err := ParseScheduledLimit(sl.String())
+		require.NoError(t, err)
+		require.Equal(t, sl, sl2)
+	}
+}
+
+func TestScheduleFactor(t *testing.T) {
+	schedule := []ScheduledLimit{
+		{timeofday.TimeOfDay{Hour: 22, Minute: 0}, timeofday.TimeOfDay{Hour: 6, Minute: 0}, 100},
+		{timeofday.TimeOfDay{Hour: 9, Minute: 0}, timeofday.TimeOfDay{Hour: 17, Minute: 0}, 20},
+		{timeofday.TimeOfDay{Hour: 0, Minute: 0}, timeofday.TimeOfDay{Hour: 0, Minute: 0}, 50},
+	}
+
+	cases := map[string]float64{
+		"21:59": 0.5,
+		"22:00": 1,
+		"23:59": 1,
+		"00:00": 1,
+		"05:59": 1,
+		"06:00": 0.5,
+		"08:59": 0.5,
+		"09:00": 0.2,
+		"16:59": 0.2,
+		"17:00": 0.5,
+	}
+
+	for tod, want := range cases {
+		tm, err := time.ParseInLocation("15:04", tod, time.Local)
+		require.NoError(t, err)
+
+		require.InDelta(t, want, scheduleFactor(schedule, tm), 1e-9, tod)
+	}
+
+	require.InDelta(t, 1.0, scheduleFactor(nil, clock.Now()), 0)
+}
+
+func TestThrottlerSchedule(t *testing.T) {
+	ctx := testlogging.Context(t)
+
+	ta := faketime.NewTimeAdvance(time.Date(2024, 1, 1, 8, 0, 0, 0, time.Local))
+
+	th, err := NewThrottlerWithClock(Limits{
+		ReadsPerSecond:   100,
+		ConcurrentWrites: 10,
+		Schedule: []ScheduledLimit{
+			{timeofday.TimeOfDay{Hour: 9, Minute: 0}, timeofday.TimeOfDay{Hour: 17, Minute: 0}, 20},
+		},
+	}, time.Second, 1.0, ta.NowFunc())
+	require.NoError(t, err)
+
+	tt := th.(*tokenBucketBasedThrottler)
+
+	require.InDelta(t, 100.0, tt.readOps.maxTokens, 1e-9)
+	require.Equal(t, 10, cap(tt.concurrentWrites.sem))
+
+	ta.Advance(time.Hour)
+	th.BeforeOperation(ctx, operationListBlobs)
+
+	require.InDelta(t, 20.0, tt.readOps.maxTokens, 1e-9)
+	require.Equal(t, 2, cap(tt.concurrentWrites.sem))
+
+	// configured limits are not affected.
+	require.InDelta(t, 100.0, th.Limits().ReadsPerSecond, 1e-9)
+
+	ta.Advance(8 * time.Hour)
+	th.BeforeOperation(ctx, operationListBlobs)
+
+	require.InDelta(t, 100.0, tt.readOps.maxTokens, 1e-9)
+	require.Equal(t, 10, cap(tt.concurrentWrites.sem))
+
+	require.Error(t, th.SetLimits(Limits{
+		Schedule: []ScheduledLimit{{timeofday.TimeOfDay{Hour: 9, Minute: 0}, timeofday.TimeOfDay{Hour: 17, Minute: 0}, 0}},
+	}))
+}
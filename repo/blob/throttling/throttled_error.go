@@ -0,0 +1,109 @@
+package throttling
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ThrottledError is returned by storage providers when the provider rejected the request, because
+// the client exceeds its request rate (such as HTTP 429, 503 or S3 SlowDown).
+type ThrottledError struct {
+	// RetryAfter is the delay requested by the provider, zero if not specified.
+	RetryAfter time.Duration
+	Err        error
+}
+
+func (e *ThrottledError) Error() string {
+	return "request throttled by storage provider: " + e.Err.Error()
+}
+
+func (e *ThrottledError) Unwrap() error {
+	return e.Err
+}
+
+// IsThrottled determines whether the provided error indicates that the request was throttled by the storage
+// provider and returns the delay requested by the provider.
+func IsThrottled(err error) (retryAfter time.Duration, ok bool) {
+	var te *ThrottledError
+
+	if errors.As(err, &te) {
+		return te.RetryAfter, true
+	}
+
+	return 0, false
+}
+
+// IsThrottlingHTTPStatus returns true if the provided HTTP status code is used by providers to indicate throttling.
+func IsThrottlingHTTPStatus(statusCode int) bool {
+	return statusCode == http.StatusTooManyRequests || statusCode == http.StatusServiceUnavailable
+}
+
+// ParseRetryAfter parses the value of Retry-After HTTP header, which is either a number of seconds or
+// an HTTP date, and returns the requested delay or zero if the value is missing or invalid.
+func ParseRetryAfter(value string, now time.Time) time.Duration {
+	value = strings.TrimSpace(value)
+	if value == "" {
+		return 0
+	}
+
+	if secs, err := strconv.Atoi(value); err == nil {
+		if secs < 0 {
+			return 0
+		}
+
+		return time.Duration(secs) * time.Second
+	}
+
+	if t, err := http.ParseTime(value); err == nil && t.After(now) {
+		return t.Sub(now)
+	}
+
+	return 0
+}
+
+// BackoffHandler is implemented by throttlers which adapt to throttling signals received from the storage provider.
+type BackoffHandler interface {
+	// OnProviderThrottled is invoked when the storage provider throttled an operation, with the delay
+	// requested by the provider, if any.
+	OnProviderThrottled(ctx context.Context, retryAfter time.Duration)
+}
+
+type backoffReporterKey struct{}
+
+// backoffReporter is attached to the context of each operation of throttling storage.
+type backoffReporter struct {
+	handler  BackoffHandler
+	reported bool
+}
+
+// reportIfThrottled reports the error returned by the operation unless throttled attempts have already been
+// reported by the wrapped storage.
+func (r *backoffReporter) reportIfThrottled(ctx context.Context, err error) error {
+	if r == nil || r.reported {
+		return err //nolint:wrapcheck
+	}
+
+	return ReportIfThrottled(ctx, err)
+}
+
+// ReportIfThrottled notifies the throttler of the operation in progress if the provided error indicates
+// that the operation was throttled by the storage provider. Storage providers which retry operations
+// internally use it to report each throttled attempt, so that the throttler reacts before retries
+// are exhausted. Returns the provided error.
+func ReportIfThrottled(ctx context.Context, err error) error {
+	retryAfter, ok := IsThrottled(err)
+	if !ok {
+		return err
+	}
+
+	if r, _ := ctx.Value(backoffReporterKey{}).(*backoffReporter); r != nil {
+		r.reported = true
+		r.handler.OnProviderThrottled(ctx, retryAfter)
+	}
+
+	return err
+}
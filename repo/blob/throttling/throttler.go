@@ -6,6 +6,9 @@
 	"time"
 
 	"github.com/pkg/errors"
+
+	"github.com/kopia/kopia/internal/clock"
+	"github.com/kopia/kopia/internal/metrics"
 )
 
 // SettableThrottler exposes methods to set throttling limits.
@@ -24,6 +27,8 @@ type tokenBucketBasedThrottler struct {
 	mu sync.Mutex
 	// +checklocks:mu
 	limits Limits
+	// +checklocks:mu
+	scheduleFactor float64
 
 	readOps  *tokenBucket
 	writeOps *tokenBucket
@@ -36,20 +41,28 @@ type tokenBucketBasedThrottler struct {
 
 	window time.Duration // +checklocksignore
 
+	backoff *providerBackoff
+	waits   *waitTracker
+	timeNow func() time.Time
+
 	onUpdate []UpdatedHandler
 }
 
 func (t *tokenBucketBasedThrottler) BeforeOperation(ctx context.Context, op string) {
-	switch op {
-	case operationListBlobs:
-		t.listOps.Take(ctx, 1)
-	case operationGetBlob, operationGetMetadata:
-		t.readOps.Take(ctx, 1)
-		t.concurrentReads.Acquire()
-	case operationPutBlob, operationDeleteBlob:
-		t.writeOps.Take(ctx, 1)
-		t.concurrentWrites.Acquire()
-	}
+	t.applyRateFactor(ctx, t.backoff.waitAndRecover(ctx))
+
+	t.waits.track(ctx, op, func() {
+		switch op {
+		case operationListBlobs:
+			t.listOps.Take(ctx, 1)
+		case operationGetBlob, operationGetMetadata:
+			t.readOps.Take(ctx, 1)
+			t.concurrentReads.Acquire()
+		case operationPutBlob, operationDeleteBlob:
+			t.writeOps.Take(ctx, 1)
+			t.concurrentWrites.Acquire()
+		}
+	})
 }
 
 func (t *tokenBucketBasedThrottler) AfterOperation(ctx context.Context, op string) {
@@ -63,7 +76,9 @@ func (t *tokenBucketBasedThrottler) AfterOperation(ctx context.Context, op strin
 }
 
 func (t *tokenBucketBasedThrottler) BeforeDownload(ctx context.Context, numBytes int64) {
-	t.download.Take(ctx, float64(numBytes))
+	t.waits.track(ctx, verbDownload, func() {
+		t.download.Take(ctx, float64(numBytes))
+	})
 }
 
 func (t *tokenBucketBasedThrottler) ReturnUnusedDownloadBytes(ctx context.Context, numBytes int64) {
@@ -71,7 +86,48 @@ func (t *tokenBucketBasedThrottler) ReturnUnusedDownloadBytes(ctx context.Contex
 }
 
 func (t *tokenBucketBasedThrottler) BeforeUpload(ctx context.Context, numBytes int64) {
-	t.upload.Take(ctx, float64(numBytes))
+	t.waits.track(ctx, verbUpload, func() {
+		t.upload.Take(ctx, float64(numBytes))
+	})
+}
+
+// OnProviderThrottled implements BackoffHandler by pausing operations and reducing rate limits,
+// which are gradually restored when the provider no longer throttles requests.
+func (t *tokenBucketBasedThrottler) OnProviderThrottled(ctx context.Context, retryAfter time.Duration) {
+	pause, rateChanged := t.backoff.onThrottled(retryAfter)
+
+	log(ctx).Debugf("storage provider throttled request, pausing operations for %v", pause)
+
+	t.applyRateFactor(ctx, rateChanged)
+}
+
+// applyRateFactor applies the current rate factor of provider backoff and the factor of scheduled limits
+// to the limits, when either has changed.
+func (t *tokenBucketBasedThrottler) applyRateFactor(ctx context.Context, backoffChanged bool) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	sf := scheduleFactor(t.limits.Schedule, t.timeNow())
+	if !backoffChanged && sf == t.scheduleFactor {
+		return
+	}
+
+	bf := t.backoff.currentRateFactor()
+
+	if err := t.setLimits(t.limits.scaled(bf * sf)); err != nil {
+		log(ctx).Errorf("unable to apply adjusted throttling limits: %v", err)
+		return
+	}
+
+	if sf != t.scheduleFactor {
+		log(ctx).Infof("adjusted throttling limits to %v%% of configured values according to schedule", fullPercent*sf)
+	}
+
+	if backoffChanged {
+		log(ctx).Infof("adjusted throttling limits to %v%% of configured values due to storage provider throttling", fullPercent*bf)
+	}
+
+	t.scheduleFactor = sf
 }
 
 func (t *tokenBucketBasedThrottler) Limits() Limits {
@@ -83,15 +139,23 @@ func (t *tokenBucketBasedThrottler) Limits() Limits {
 
 // SetLimits overrides limits.
 func (t *tokenBucketBasedThrottler) SetLimits(limits Limits) error {
+	if err := validateSchedule(limits.Schedule); err != nil {
+		return errors.Wrap(err, "Schedule")
+	}
+
 	t.mu.Lock()
 	defer t.mu.Unlock()
 
-	if err := t.setLimits(limits); err != nil {
-		_ = t.setLimits(t.limits)
+	bf := t.backoff.currentRateFactor()
+	sf := scheduleFactor(limits.Schedule, t.timeNow())
+
+	if err := t.setLimits(limits.scaled(bf * sf)); err != nil {
+		_ = t.setLimits(t.limits.scaled(bf * t.scheduleFactor))
 		return err
 	}
 
 	t.limits = limits
+	t.scheduleFactor = sf
 
 	for _, h := range t.onUpdate {
 		if err := h(limits); err != nil {
@@ -147,12 +211,73 @@ type Limits struct {
 	DownloadBytesPerSecond float64 `json:"maxDownloadSpeedBytesPerSecond,omitempty"`
 	ConcurrentReads        int     `json:"concurrentReads,omitempty"`
 	ConcurrentWrites       int     `json:"concurrentWrites,omitempty"`
+
+	// Schedule scales the limits above during daily time windows, the first matching window applies.
+	Schedule []ScheduledLimit `json:"schedule,omitempty"`
 }
 
-var _ Throttler = (*tokenBucketBasedThrottler)(nil)
+// scaled returns limits with rates and concurrency multiplied by the provided factor. Unlimited values
+// remain unlimited and concurrency is never reduced below 1.
+func (l Limits) scaled(f float64) Limits {
+	if f == 1 {
+		return l
+	}
+
+	scaleConcurrency := func(n int) int {
+		if n == 0 {
+			return 0
+		}
+
+		return max(int(float64(n)*f), 1)
+	}
+
+	return Limits{
+		ReadsPerSecond:         l.ReadsPerSecond * f,
+		WritesPerSecond:        l.WritesPerSecond * f,
+		ListsPerSecond:         l.ListsPerSecond * f,
+		UploadBytesPerSecond:   l.UploadBytesPerSecond * f,
+		DownloadBytesPerSecond: l.DownloadBytesPerSecond * f,
+		ConcurrentReads:        scaleConcurrency(l.ConcurrentReads),
+		ConcurrentWrites:       scaleConcurrency(l.ConcurrentWrites),
+	}
+}
+
+var (
+	_ Throttler      = (*tokenBucketBasedThrottler)(nil)
+	_ BackoffHandler = (*tokenBucketBasedThrottler)(nil)
+)
+
+// ThrottlerOptions provides optional parameters of a Throttler.
+type ThrottlerOptions struct {
+	TimeNow         func() time.Time  // time provider, defaults to clock.Now
+	WaitTimeNow     func() time.Time  // time provider measuring waits for limits, defaults to clock.Now
+	MetricsRegistry *metrics.Registry // registry of metrics of time spent waiting for limits, optional
+
+	// a warning is logged when the time spent waiting for limits within WaitWarningWindow exceeds
+	// WaitWarningThreshold, negative threshold disables the warning.
+	WaitWarningThreshold time.Duration
+	WaitWarningWindow    time.Duration
+}
 
 // NewThrottler returns a Throttler with provided limits.
 func NewThrottler(limits Limits, window time.Duration, initialFillRatio float64) (SettableThrottler, error) {
+	return NewThrottlerWithOptions(limits, window, initialFillRatio, ThrottlerOptions{})
+}
+
+// NewThrottlerWithClock returns a Throttler with provided limits, which evaluates scheduled limits
+// using the provided time function.
+func NewThrottlerWithClock(limits Limits, window time.Duration, initialFillRatio float64, timeNow func() time.Time) (SettableThrottler, error) {
+	return NewThrottlerWithOptions(limits, window, initialFillRatio, ThrottlerOptions{TimeNow: timeNow})
+}
+
+// NewThrottlerWithOptions returns a Throttler with provided limits and options.
+func NewThrottlerWithOptions(limits Limits, window time.Duration, initialFillRatio float64, opts ThrottlerOptions) (SettableThrottler, error) {
+	if opts.TimeNow == nil {
+		opts.TimeNow = clock.Now
+	}
+
+	timeNow := opts.TimeNow
+
 	t := &tokenBucketBasedThrottler{
 		readOps:          newTokenBucket("read-ops", initialFillRatio*limits.ReadsPerSecond*window.Seconds(), 0, window),
 		writeOps:         newTokenBucket("write-ops", initialFillRatio*limits.WritesPerSecond*window.Seconds(), 0, window),
@@ -162,6 +287,9 @@ func NewThrottler(limits Limits, window time.Duration, initialFillRatio float64)
 		concurrentReads:  newSemaphore(),
 		concurrentWrites: newSemaphore(),
 		window:           window,
+		backoff:          newProviderBackoff(),
+		waits:            newWaitTracker(opts),
+		timeNow:          timeNow,
 	}
 
 	if err := t.SetLimits(limits); err != nil {
@@ -0,0 +1,142 @@
+package throttling
+
+import (
+	"context"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/kopia/kopia/internal/clock"
+	"github.com/kopia/kopia/internal/metrics"
+)
+
+// verbs which can wait for throttling limits, in addition to operations.
+const (
+	verbDownload = "Download"
+	verbUpload   = "Upload"
+)
+
+//nolint:gochecknoglobals
+var allWaitVerbs = []string{
+	operationGetBlob,
+	operationGetMetadata,
+	operationListBlobs,
+	operationPutBlob,
+	operationDeleteBlob,
+	operationExtendBlobRetention,
+	verbDownload,
+	verbUpload,
+}
+
+const (
+	defaultWaitWarningThreshold = 30 * time.Second
+	defaultWaitWarningWindow    = time.Minute
+)
+
+// waitTracker records time spent waiting for throttling limits and warns when waits within a window
+// of time exceed the threshold, which indicates that throttling rather than the storage is the bottleneck.
+type waitTracker struct {
+	timeNow   func() time.Time
+	threshold time.Duration
+	window    time.Duration
+
+	waitNanos map[string]*metrics.Counter
+
+	mu sync.Mutex
+	// +checklocks:mu
+	windowStart time.Time
+	// +checklocks:mu
+	windowWaits map[string]time.Duration
+	// +checklocks:mu
+	warned bool
+}
+
+// track invokes the provided function, which may block for throttling limits, and records the time it took.
+func (w *waitTracker) track(ctx context.Context, verb string, f func()) {
+	start := w.timeNow()
+
+	f()
+
+	w.record(ctx, verb, w.timeNow().Sub(start))
+}
+
+func (w *waitTracker) record(ctx context.Context, verb string, d time.Duration) {
+	if d <= 0 {
+		return
+	}
+
+	w.waitNanos[verb].Add(d.Nanoseconds())
+
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	now := w.timeNow()
+	if now.Sub(w.windowStart) >= w.window {
+		w.windowStart = now
+		w.windowWaits = map[string]time.Duration{}
+		w.warned = false
+	}
+
+	w.windowWaits[verb] += d
+
+	var total time.Duration
+
+	for _, v := range w.windowWaits {
+		total += v
+	}
+
+	if w.warned || w.threshold < 0 || total < w.threshold {
+		return
+	}
+
+	w.warned = true
+
+	log(ctx).Warnf("storage operations waited %v for throttling limits in the last %v (%v), consider increasing the limits",
+		total.Round(time.Millisecond), now.Sub(w.windowStart).Round(time.Second), w.breakdownLocked())
+}
+
+// +checklocks:w.mu
+func (w *waitTracker) breakdownLocked() string {
+	var parts []string
+
+	for verb, d := range w.windowWaits {
+		parts = append(parts, verb+": "+d.Round(time.Millisecond).String())
+	}
+
+	sort.Strings(parts)
+
+	return strings.Join(parts, ", ")
+}
+
+func newWaitTracker(opts ThrottlerOptions) *waitTracker {
+	// waits are measured using real time even if limits are scheduled based on repository time,
+	// which may be simulated.
+	if opts.WaitTimeNow == nil {
+		opts.WaitTimeNow = clock.Now
+	}
+
+	w := &waitTracker{
+		timeNow:     opts.WaitTimeNow,
+		threshold:   opts.WaitWarningThreshold,
+		window:      opts.WaitWarningWindow,
+		waitNanos:   map[string]*metrics.Counter{},
+		windowStart: opts.WaitTimeNow(),
+		windowWaits: map[string]time.Duration{},
+	}
+
+	if w.threshold == 0 {
+		w.threshold = defaultWaitWarningThreshold
+	}
+
+	if w.window == 0 {
+		w.window = defaultWaitWarningWindow
+	}
+
+	for _, verb := range allWaitVerbs {
+		w.waitNanos[verb] = opts.MetricsRegistry.CounterInt64("blob_throttler_wait_nanos",
+			"Time spent waiting for throttling limits.", map[string]string{"verb": verb})
+	}
+
+	return w
+}
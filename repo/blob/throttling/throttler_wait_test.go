@@ -0,0 +1,72 @@
+package throttling
+
+import (
+	"bytes"
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/kopia/kopia/internal/faketime"
+	"github.com/kopia/kopia/internal/metrics"
+	"github.com/kopia/kopia/repo/logging"
+)
+
+func TestThrottlerWaitMetrics(t *testing.T) {
+	var logBuf bytes.Buffer
+
+	ctx := logging.WithLogger(context.Background(), logging.ToWriter(&logBuf))
+
+	ta := faketime.NewTimeAdvance(time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC))
+	mr := metrics.NewRegistry()
+
+	th, err := NewThrottlerWithOptions(Limits{
+		ReadsPerSecond:       10,
+		UploadBytesPerSecond: 1000,
+	}, time.Second, 0.0 /* start empty */, ThrottlerOptions{
+		TimeNow:              ta.NowFunc(),
+		WaitTimeNow:          ta.NowFunc(),
+		MetricsRegistry:      mr,
+		WaitWarningThreshold: 2 * time.Second,
+		WaitWarningWindow:    time.Minute,
+	})
+	require.NoError(t, err)
+
+	tt := th.(*tokenBucketBasedThrottler)
+
+	// make all token buckets wait by advancing fake time.
+	for _, b := range []*tokenBucket{tt.readOps, tt.writeOps, tt.listOps, tt.upload, tt.download} {
+		b.now = ta.NowFunc()
+		b.sleep = func(_ context.Context, d time.Duration) { ta.Advance(d) }
+	}
+
+	// each read waits 100ms for a token.
+	for range 5 {
+		th.BeforeOperation(ctx, operationGetBlob)
+		th.AfterOperation(ctx, operationGetBlob)
+	}
+
+	// uploading 500 bytes waits 500ms.
+	th.BeforeUpload(ctx, 500)
+
+	// unlimited operations don't wait.
+	th.BeforeOperation(ctx, operationListBlobs)
+
+	snap := mr.Snapshot(false)
+	require.EqualValues(t, 500*time.Millisecond, snap.Counters["blob_throttler_wait_nanos[verb:GetBlob]"])
+	require.EqualValues(t, 500*time.Millisecond, snap.Counters["blob_throttler_wait_nanos[verb:Upload]"])
+	require.EqualValues(t, 0, snap.Counters["blob_throttler_wait_nanos[verb:ListBlobs]"])
+	require.NotContains(t, logBuf.String(), "waited")
+
+	// sustained waits exceeding the threshold log a single warning per window.
+	th.BeforeUpload(ctx, 1500)
+	th.BeforeUpload(ctx, 100)
+	require.Equal(t, 1, strings.Count(logBuf.String(), "waited"), logBuf.String())
+	require.Contains(t, logBuf.String(), "waited 2.5s for throttling limits in the last 3s (GetBlob: 500ms, Upload: 2s)")
+
+	ta.Advance(time.Minute)
+	th.BeforeUpload(ctx, 3000)
+	require.Equal(t, 2, strings.Count(logBuf.String(), "waited"), logBuf.String())
+}
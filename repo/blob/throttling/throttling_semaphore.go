@@ -50,6 +50,11 @@ func (s *semaphore) SetLimit(limit int) error {
 	}
 
 	if limit > 0 {
+		if s.sem != nil && cap(s.sem) == limit {
+			// keep track of slots already acquired.
+			return nil
+		}
+
 		s.sem = make(chan struct{}, limit)
 	} else {
 		s.sem = nil
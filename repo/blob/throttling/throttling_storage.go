@@ -46,29 +46,47 @@ type throttlingStorage struct {
 	throttler Throttler
 }
 
+// throttlers returns the throttlers applicable to an operation in the provided context. Limits of the activity
+// are applied first, so that waiting for them does not hold up storage-wide limits.
+func (s *throttlingStorage) throttlers(ctx context.Context) []Throttler {
+	if t := throttlerFromContext(ctx); t != nil {
+		return []Throttler{t, s.throttler}
+	}
+
+	return []Throttler{s.throttler}
+}
+
 func (s *throttlingStorage) GetBlob(ctx context.Context, id blob.ID, offset, length int64, output blob.OutputBuffer) error {
 	acquired := length
 	if acquired < 0 {
 		acquired = unknownBlobAcquireLength
 	}
 
-	s.throttler.BeforeOperation(ctx, operationGetBlob)
-	defer s.throttler.AfterOperation(ctx, operationGetBlob)
+	throttlers := s.throttlers(ctx)
+
+	for _, t := range throttlers {
+		t.BeforeOperation(ctx, operationGetBlob)
+		defer t.AfterOperation(ctx, operationGetBlob)
 
-	s.throttler.BeforeDownload(ctx, acquired)
+		t.BeforeDownload(ctx, acquired)
+	}
 
 	output.Reset()
 
-	err := s.Storage.GetBlob(ctx, id, offset, length, output)
+	ctx, r := s.withBackoffReporter(ctx)
+
+	err := r.reportIfThrottled(ctx, s.Storage.GetBlob(ctx, id, offset, length, output))
 	downloaded := int64(output.Length())
 
-	if acquired != downloaded {
-		if downloaded > acquired {
-			// we downloaded more than initially acquired, acquire more which may pause for a bit.
-			s.throttler.BeforeDownload(ctx, downloaded-acquired)
-		} else {
-			// we downloaded less than initially acquired, release extra
-			s.throttler.ReturnUnusedDownloadBytes(ctx, acquired-downloaded)
+	for _, t := range throttlers {
+		if acquired != downloaded {
+			if downloaded > acquired {
+				// we downloaded more than initially acquired, acquire more which may pause for a bit.
+				t.BeforeDownload(ctx, downloaded-acquired)
+			} else {
+				// we downloaded less than initially acquired, release extra
+				t.ReturnUnusedDownloadBytes(ctx, acquired-downloaded)
+			}
 		}
 	}
 
@@ -76,40 +94,75 @@ func (s *throttlingStorage) GetBlob(ctx context.Context, id blob.ID, offset, len
 }
 
 func (s *throttlingStorage) GetMetadata(ctx context.Context, id blob.ID) (blob.Metadata, error) {
-	s.throttler.BeforeOperation(ctx, operationGetMetadata)
-	defer s.throttler.AfterOperation(ctx, operationGetMetadata)
+	for _, t := range s.throttlers(ctx) {
+		t.BeforeOperation(ctx, operationGetMetadata)
+		defer t.AfterOperation(ctx, operationGetMetadata)
+	}
+
+	ctx, r := s.withBackoffReporter(ctx)
 
-	return s.Storage.GetMetadata(ctx, id) //nolint:wrapcheck
+	m, err := s.Storage.GetMetadata(ctx, id)
+
+	return m, r.reportIfThrottled(ctx, err)
 }
 
 func (s *throttlingStorage) ListBlobs(ctx context.Context, blobIDPrefix blob.ID, cb func(bm blob.Metadata) error) error {
-	s.throttler.BeforeOperation(ctx, operationListBlobs)
-	defer s.throttler.AfterOperation(ctx, operationListBlobs)
+	for _, t := range s.throttlers(ctx) {
+		t.BeforeOperation(ctx, operationListBlobs)
+		defer t.AfterOperation(ctx, operationListBlobs)
+	}
+
+	ctx, r := s.withBackoffReporter(ctx)
 
-	return s.Storage.ListBlobs(ctx, blobIDPrefix, cb) //nolint:wrapcheck
+	return r.reportIfThrottled(ctx, s.Storage.ListBlobs(ctx, blobIDPrefix, cb))
 }
 
 func (s *throttlingStorage) PutBlob(ctx context.Context, id blob.ID, data blob.Bytes, opts blob.PutOptions) error {
-	s.throttler.BeforeOperation(ctx, operationPutBlob)
-	defer s.throttler.AfterOperation(ctx, operationPutBlob)
+	for _, t := range s.throttlers(ctx) {
+		t.BeforeOperation(ctx, operationPutBlob)
+		defer t.AfterOperation(ctx, operationPutBlob)
 
-	s.throttler.BeforeUpload(ctx, int64(data.Length()))
+		t.BeforeUpload(ctx, int64(data.Length()))
+	}
+
+	ctx, r := s.withBackoffReporter(ctx)
 
-	return s.Storage.PutBlob(ctx, id, data, opts) //nolint:wrapcheck
+	return r.reportIfThrottled(ctx, s.Storage.PutBlob(ctx, id, data, opts))
 }
 
 func (s *throttlingStorage) DeleteBlob(ctx context.Context, id blob.ID) error {
-	s.throttler.BeforeOperation(ctx, operationDeleteBlob)
-	defer s.throttler.AfterOperation(ctx, operationDeleteBlob)
+	for _, t := range s.throttlers(ctx) {
+		t.BeforeOperation(ctx, operationDeleteBlob)
+		defer t.AfterOperation(ctx, operationDeleteBlob)
+	}
 
-	return s.Storage.DeleteBlob(ctx, id) //nolint:wrapcheck
+	ctx, r := s.withBackoffReporter(ctx)
+
+	return r.reportIfThrottled(ctx, s.Storage.DeleteBlob(ctx, id))
 }
 
 func (s *throttlingStorage) ExtendBlobRetention(ctx context.Context, id blob.ID, opts blob.ExtendOptions) error {
-	s.throttler.BeforeOperation(ctx, operationExtendBlobRetention)
-	defer s.throttler.AfterOperation(ctx, operationExtendBlobRetention)
+	for _, t := range s.throttlers(ctx) {
+		t.BeforeOperation(ctx, operationExtendBlobRetention)
+		defer t.AfterOperation(ctx, operationExtendBlobRetention)
+	}
+
+	ctx, r := s.withBackoffReporter(ctx)
+
+	return r.reportIfThrottled(ctx, s.Storage.ExtendBlobRetention(ctx, id, opts))
+}
+
+// withBackoffReporter returns the context which allows the wrapped storage to report throttled attempts
+// of the operation to the throttler using ReportIfThrottled().
+func (s *throttlingStorage) withBackoffReporter(ctx context.Context) (context.Context, *backoffReporter) {
+	h, ok := s.throttler.(BackoffHandler)
+	if !ok {
+		return ctx, nil
+	}
+
+	r := &backoffReporter{handler: h}
 
-	return s.Storage.ExtendBlobRetention(ctx, id, opts) //nolint:wrapcheck
+	return context.WithValue(ctx, backoffReporterKey{}, r), r
 }
 
 // NewWrapper returns a Storage wrapper that adds retry loop around all operations of the underlying storage.
@@ -3,17 +3,21 @@
 import (
 	"bytes"
 	"context"
+	"errors"
 	"fmt"
 	"strings"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/require"
 
 	"github.com/kopia/kopia/internal/blobtesting"
+	"github.com/kopia/kopia/internal/fault"
 	"github.com/kopia/kopia/internal/gather"
 	"github.com/kopia/kopia/internal/testlogging"
 	"github.com/kopia/kopia/repo/blob"
 	bloblogging "github.com/kopia/kopia/repo/blob/logging"
+	"github.com/kopia/kopia/repo/blob/retrying"
 	"github.com/kopia/kopia/repo/blob/throttling"
 )
 
@@ -45,6 +49,10 @@ func (m *mockThrottler) ReturnUnusedDownloadBytes(ctx context.Context, numBytes
 	m.activity = append(m.activity, fmt.Sprintf("ReturnUnusedDownloadBytes(%v)", numBytes))
 }
 
+func (m *mockThrottler) OnProviderThrottled(ctx context.Context, retryAfter time.Duration) {
+	m.activity = append(m.activity, fmt.Sprintf("OnProviderThrottled(%v)", retryAfter))
+}
+
 func (m *mockThrottler) Printf(msg string, args ...interface{}) {
 	msg = fmt.Sprintf(msg, args...)
 	msg = strings.Split(msg, "\t")[0] // ignore parameters
@@ -148,3 +156,105 @@ func TestThrottling(t *testing.T) {
 		"AfterOperation(ListBlobs)",
 	}, m.activity)
 }
+
+func TestThrottlingWithContextThrottler(t *testing.T) {
+	ctx := testlogging.Context(t)
+	m := &mockThrottler{}
+	activity := &mockThrottler{}
+	st := blobtesting.NewMapStorage(blobtesting.DataMap{}, nil, nil)
+	wrapped := throttling.NewWrapper(st, m)
+
+	activityCtx := throttling.WithThrottler(ctx, activity)
+
+	require.NoError(t, wrapped.PutBlob(activityCtx, "blob1", gather.FromSlice([]byte{1, 2, 3}), blob.PutOptions{}))
+	require.Equal(t, []string{
+		"BeforeOperation(PutBlob)",
+		"BeforeUpload(3)",
+		"AfterOperation(PutBlob)",
+	}, m.activity)
+	require.Equal(t, []string{
+		"BeforeOperation(PutBlob)",
+		"BeforeUpload(3)",
+		"AfterOperation(PutBlob)",
+	}, activity.activity)
+
+	m.Reset()
+	activity.Reset()
+
+	var tmp gather.WriteBuffer
+	defer tmp.Close()
+
+	require.NoError(t, wrapped.GetBlob(activityCtx, "blob1", 0, 3, &tmp))
+	require.NoError(t, wrapped.DeleteBlob(activityCtx, "blob1"))
+	require.Equal(t, []string{
+		"BeforeOperation(GetBlob)",
+		"BeforeDownload(3)",
+		"AfterOperation(GetBlob)",
+		"BeforeOperation(DeleteBlob)",
+		"AfterOperation(DeleteBlob)",
+	}, activity.activity)
+	require.Equal(t, activity.activity, m.activity)
+
+	// operations without the context are not subject to the throttler of the activity.
+	m.Reset()
+	activity.Reset()
+
+	require.NoError(t, wrapped.PutBlob(ctx, "blob2", gather.FromSlice([]byte{1}), blob.PutOptions{}))
+	require.NotEmpty(t, m.activity)
+	require.Empty(t, activity.activity)
+}
+
+func TestThrottlingReportsProviderThrottling(t *testing.T) {
+	ctx := testlogging.Context(t)
+	m := &mockThrottler{}
+	fs := blobtesting.NewFaultyStorage(blobtesting.NewMapStorage(blobtesting.DataMap{}, nil, nil))
+
+	throttledErr := &throttling.ThrottledError{RetryAfter: 5 * time.Second, Err: errors.New("slow down")}
+
+	// throttling error returned by the storage is reported.
+	fs.AddFault(blobtesting.MethodPutBlob).ErrorInstead(throttledErr)
+
+	wrapped := throttling.NewWrapper(fs, m)
+	require.ErrorIs(t, wrapped.PutBlob(ctx, "blob1", gather.FromSlice([]byte{1}), blob.PutOptions{}), throttledErr)
+	require.Equal(t, []string{
+		"BeforeOperation(PutBlob)",
+		"BeforeUpload(1)",
+		"OnProviderThrottled(5s)",
+		"AfterOperation(PutBlob)",
+	}, m.activity)
+
+	// other errors are not reported.
+	m.Reset()
+	fs.AddFault(blobtesting.MethodDeleteBlob).ErrorInstead(errors.New("some error"))
+
+	require.Error(t, wrapped.DeleteBlob(ctx, "blob1"))
+	require.Equal(t, []string{
+		"BeforeOperation(DeleteBlob)",
+		"AfterOperation(DeleteBlob)",
+	}, m.activity)
+
+	// each throttled attempt retried by the storage is reported once.
+	m.Reset()
+	fs.AddFaults(blobtesting.MethodPutBlob, fault.New().ErrorInstead(throttledErr).Repeat(1))
+
+	wrapped = throttling.NewWrapper(retrying.NewWrapper(fs), m)
+	require.NoError(t, wrapped.PutBlob(ctx, "blob1", gather.FromSlice([]byte{1}), blob.PutOptions{}))
+	require.Equal(t, []string{
+		"BeforeOperation(PutBlob)",
+		"BeforeUpload(1)",
+		"OnProviderThrottled(5s)",
+		"OnProviderThrottled(5s)",
+		"AfterOperation(PutBlob)",
+	}, m.activity)
+
+	fs.VerifyAllFaultsExercised(t)
+}
+
+func TestIsThrottled(t *testing.T) {
+	_, ok := throttling.IsThrottled(errors.New("some error"))
+	require.False(t, ok)
+
+	retryAfter, ok := throttling.IsThrottled(fmt.Errorf("wrapped: %w", &throttling.ThrottledError{RetryAfter: time.Second, Err: blob.ErrBlobNotFound}))
+	require.True(t, ok)
+	require.Equal(t, time.Second, retryAfter)
+}
@@ -1,6 +1,7 @@
 package webdav
 
 import (
+	"github.com/kopia/kopia/repo/blob/retrypolicy"
 	"github.com/kopia/kopia/repo/blob/sharded"
 	"github.com/kopia/kopia/repo/blob/throttling"
 )
@@ -15,4 +16,5 @@ type Options struct {
 
 	sharded.Options
 	throttling.Limits
+	retrypolicy.Policy
 }
@@ -164,7 +164,7 @@ func (d *davStorageImpl) PutBlobInPath(ctx context.Context, dirPath, filePath st
 
 	b := buf.Bytes()
 
-	if err := retry.WithExponentialBackoffNoValue(ctx, "WriteTemporaryFileAndCreateParentDirs", func() error {
+	if err := retry.WithPolicyNoValue(ctx, d.Backoff(), "WriteTemporaryFileAndCreateParentDirs", func() error {
 		mkdirAttempted := false
 
 		for {
@@ -213,7 +213,7 @@ func (d *davStorageImpl) PutBlobInPath(ctx context.Context, dirPath, filePath st
 func (d *davStorageImpl) DeleteBlobInPath(ctx context.Context, dirPath, filePath string) error {
 	_ = dirPath
 
-	err := d.translateError(retry.WithExponentialBackoffNoValue(ctx, "DeleteBlobInPath", func() error {
+	err := d.translateError(retry.WithPolicyNoValue(ctx, d.Backoff(), "DeleteBlobInPath", func() error {
 		return d.cli.Remove(filePath)
 	}, isRetriable))
 	if errors.Is(err, blob.ErrBlobNotFound) {
@@ -262,12 +262,12 @@ func New(ctx context.Context, opts *Options, isCreate bool) (blob.Storage, error
 		cli.SetTransport(tlsutil.TransportTrustingSingleCertificate(opts.TrustedServerCertificateFingerprint))
 	}
 
-	s := retrying.NewWrapper(&davStorage{
+	s := retrying.NewWrapperWithPolicy(&davStorage{
 		Storage: sharded.New(&davStorageImpl{
 			Options: *opts,
 			cli:     cli,
 		}, "", opts.Options, isCreate),
-	})
+	}, opts.Policy, nil)
 
 	return s, nil
 }
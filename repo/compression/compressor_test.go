@@ -162,3 +162,30 @@ func decompressionBenchmark(b *testing.B, comp Compressor, input []byte, output
 		}
 	}
 }
+
+func TestEntropyEstimator(t *testing.T) {
+	random := make([]byte, 65536)
+	rand.Read(random)
+
+	cases := []struct {
+		desc               string
+		data               []byte
+		wantIncompressible bool
+	}{
+		{"empty", nil, false},
+		{"zeros", make([]byte, 65536), false},
+		{"text", bytes.Repeat([]byte("the quick brown fox jumps over the lazy dog\n"), 1000), false},
+		{"random", random, true},
+		{"small random", random[0:1000], false},
+	}
+
+	for _, tc := range cases {
+		var e EntropyEstimator
+
+		e.Write(tc.data)
+
+		if got := e.LikelyIncompressible(); got != tc.wantIncompressible {
+			t.Errorf("%v: unexpected result %v (entropy %v)", tc.desc, got, e.BitsPerByte())
+		}
+	}
+}
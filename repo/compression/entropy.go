@@ -0,0 +1,59 @@
+package compression
+
+import (
+	"math"
+)
+
+const (
+	// incompressibleBitsPerByte is the entropy above which the data is considered incompressible.
+	// Media files, archives and encrypted data typically exceed 7.9 bits per byte, while text,
+	// executables and most uncompressed formats stay well below 7.
+	incompressibleBitsPerByte = 7.5
+
+	// minEntropySampleBytes is the minimum number of bytes needed for the estimate to be meaningful,
+	// with fewer bytes even random data appears to have low entropy.
+	minEntropySampleBytes = 4096
+)
+
+// EntropyEstimator estimates compressibility of data written to it based on Shannon entropy of
+// its bytes. This is much cheaper than compressing the data, but does not detect redundancy in
+// repeated sequences of high-entropy bytes.
+type EntropyEstimator struct {
+	counts [256]int64
+	total  int64
+}
+
+// Write implements io.Writer.
+func (e *EntropyEstimator) Write(p []byte) (int, error) {
+	for _, b := range p {
+		e.counts[b]++
+	}
+
+	e.total += int64(len(p))
+
+	return len(p), nil
+}
+
+// BitsPerByte returns the entropy of bytes written so far, between 0 and 8.
+func (e *EntropyEstimator) BitsPerByte() float64 {
+	if e.total == 0 {
+		return 0
+	}
+
+	var result float64
+
+	for _, c := range e.counts {
+		if c > 0 {
+			p := float64(c) / float64(e.total)
+			result -= p * math.Log2(p)
+		}
+	}
+
+	return result
+}
+
+// LikelyIncompressible returns true if the bytes written so far appear not to be compressible,
+// which is never the case for samples too small to estimate their entropy reliably.
+func (e *EntropyEstimator) LikelyIncompressible() bool {
+	return e.total >= minEntropySampleBytes && e.BitsPerByte() >= incompressibleBitsPerByte
+}
@@ -3,6 +3,8 @@
 import (
 	"path/filepath"
 	"time"
+
+	"github.com/pkg/errors"
 )
 
 // DurationSeconds represents the duration in seconds.
@@ -28,9 +30,46 @@ type CachingOptions struct {
 	MinMetadataSweepAge         DurationSeconds `json:"minMetadataSweepAge,omitempty"`
 	MinContentSweepAge          DurationSeconds `json:"minContentSweepAge,omitempty"`
 	MinIndexSweepAge            DurationSeconds `json:"minIndexSweepAge,omitempty"`
+	ContentCacheEvictionPolicy  string          `json:"contentCacheEvictionPolicy,omitempty"`
+	MetadataCacheEvictionPolicy string          `json:"metadataCacheEvictionPolicy,omitempty"`
 	HMACSecret                  []byte          `json:"-"`
 }
 
+// CacheSizeLimits specifies sizes of local caches, which can be changed while the repository is open.
+type CacheSizeLimits struct {
+	ContentCacheSizeBytes       int64 `json:"contentCacheSizeBytes"`
+	ContentCacheSizeLimitBytes  int64 `json:"contentCacheSizeLimitBytes"`
+	MetadataCacheSizeBytes      int64 `json:"metadataCacheSizeBytes"`
+	MetadataCacheSizeLimitBytes int64 `json:"metadataCacheSizeLimitBytes"`
+}
+
+// Validate checks that the limits are valid.
+func (l CacheSizeLimits) Validate() error {
+	if l.ContentCacheSizeBytes < 0 || l.ContentCacheSizeLimitBytes < 0 || l.MetadataCacheSizeBytes < 0 || l.MetadataCacheSizeLimitBytes < 0 {
+		return errors.New("cache sizes must not be negative")
+	}
+
+	return nil
+}
+
+// SizeLimits returns the cache size limits.
+func (c *CachingOptions) SizeLimits() CacheSizeLimits {
+	return CacheSizeLimits{
+		ContentCacheSizeBytes:       c.ContentCacheSizeBytes,
+		ContentCacheSizeLimitBytes:  c.ContentCacheSizeLimitBytes,
+		MetadataCacheSizeBytes:      c.MetadataCacheSizeBytes,
+		MetadataCacheSizeLimitBytes: c.MetadataCacheSizeLimitBytes,
+	}
+}
+
+// SetSizeLimits sets the cache size limits.
+func (c *CachingOptions) SetSizeLimits(l CacheSizeLimits) {
+	c.ContentCacheSizeBytes = l.ContentCacheSizeBytes
+	c.ContentCacheSizeLimitBytes = l.ContentCacheSizeLimitBytes
+	c.MetadataCacheSizeBytes = l.MetadataCacheSizeBytes
+	c.MetadataCacheSizeLimitBytes = l.MetadataCacheSizeLimitBytes
+}
+
 // EffectiveMetadataCacheSizeBytes returns the effective metadata cache size.
 func (c *CachingOptions) EffectiveMetadataCacheSizeBytes() int64 {
 	if c.MetadataCacheSizeBytes == 0 {
@@ -53,6 +53,7 @@ type committedContentIndexCache interface {
 	addContentToCache(ctx context.Context, indexBlob blob.ID, data gather.Bytes) error
 	openIndex(ctx context.Context, indexBlob blob.ID) (index.Index, error)
 	expireUnused(ctx context.Context, used []blob.ID) error
+	close() error
 }
 
 func (c *committedContentIndex) revision() int64 {
@@ -296,7 +297,7 @@ func (c *committedContentIndex) close() error {
 		}
 	}
 
-	return nil
+	return errors.Wrap(c.cache.close(), "unable to close index cache")
 }
 
 func (c *committedContentIndex) fetchIndexBlobs(ctx context.Context, isPermissiveCacheLoading bool, indexBlobs []blob.ID) error {
@@ -374,16 +375,27 @@ func newCommittedContentIndex(caching *CachingOptions,
 	fetchOne func(ctx context.Context, blobID blob.ID, output *gather.WriteBuffer) error,
 	log logging.Logger,
 	minSweepAge time.Duration,
+	maxIndexMemoryBytes int64,
 ) *committedContentIndex {
 	var cache committedContentIndexCache
 
 	if caching.CacheDirectory != "" {
 		dirname := filepath.Join(caching.CacheDirectory, "indexes")
 		cache = &diskCommittedContentIndexCache{dirname, clock.Now, v1PerContentOverhead, log, minSweepAge}
+
+		if maxIndexMemoryBytes > 0 {
+			log.Debugf("index blobs are memory-mapped from the cache directory, ignoring the index memory limit")
+		}
 	} else {
+		if maxIndexMemoryBytes > 0 {
+			removeStaleIndexSpillDirectories(log)
+		}
+
 		cache = &memoryCommittedContentIndexCache{
 			contents:             map[blob.ID]index.Index{},
 			v1PerContentOverhead: v1PerContentOverhead,
+			maxMemoryBytes:       maxIndexMemoryBytes,
+			log:                  log,
 		}
 	}
 
@@ -2,11 +2,15 @@
 
 import (
 	"bytes"
+	"os"
+	"path/filepath"
 	"testing"
 	"time"
 
+	"github.com/gofrs/flock"
 	"github.com/stretchr/testify/require"
 
+	"github.com/kopia/kopia/internal/clock"
 	"github.com/kopia/kopia/internal/faketime"
 	"github.com/kopia/kopia/internal/gather"
 	"github.com/kopia/kopia/internal/testlogging"
@@ -32,6 +36,116 @@ func TestCommittedContentIndexCache_Memory(t *testing.T) {
 	}, nil)
 }
 
+func TestCommittedContentIndexCache_MemoryMapped(t *testing.T) {
+	t.Parallel()
+
+	// all index blobs exceed the limit and are memory-mapped from temporary files.
+	c := &memoryCommittedContentIndexCache{
+		contents:             map[blob.ID]index.Index{},
+		v1PerContentOverhead: func() int { return 3 },
+		maxMemoryBytes:       1,
+		log:                  testlogging.Printf(t.Logf, ""),
+	}
+
+	testCache(t, c, nil)
+
+	c.mu.Lock()
+	require.Empty(t, c.contents)
+	require.NotNil(t, c.spill)
+	dirname := c.spill.dirname
+	c.mu.Unlock()
+
+	require.DirExists(t, dirname)
+	require.NoError(t, c.close())
+	require.NoDirExists(t, dirname)
+}
+
+func TestRemoveStaleIndexSpillDirectories(t *testing.T) {
+	t.Setenv("TMPDIR", t.TempDir())
+
+	mkdir := func(withLockFile bool) string {
+		dirname, err := os.MkdirTemp("", indexSpillDirPrefix)
+		require.NoError(t, err)
+
+		if withLockFile {
+			require.NoError(t, os.WriteFile(filepath.Join(dirname, indexSpillLockFile), nil, 0o600))
+		}
+
+		return dirname
+	}
+
+	stale := mkdir(true)
+	inUse := mkdir(true)
+	justCreated := mkdir(false)
+	abandoned := mkdir(false)
+
+	old := clock.Now().Add(-2 * minUnlockedIndexSpillDirAge)
+	require.NoError(t, os.Chtimes(abandoned, old, old))
+
+	l := flock.New(filepath.Join(inUse, indexSpillLockFile))
+	require.NoError(t, l.Lock())
+
+	defer l.Unlock()
+
+	removeStaleIndexSpillDirectories(testlogging.Printf(t.Logf, ""))
+
+	require.NoDirExists(t, stale)
+	require.DirExists(t, inUse)
+	require.DirExists(t, justCreated)
+	require.NoDirExists(t, abandoned)
+}
+
+func TestCommittedContentIndexCache_MemoryLimit(t *testing.T) {
+	t.Parallel()
+
+	ctx := testlogging.Context(t)
+
+	ndx1 := mustBuildIndex(t, index.Builder{
+		mustParseID(t, "c1"): Info{PackBlobID: "p1234", ContentID: mustParseID(t, "c1")},
+	})
+
+	c := &memoryCommittedContentIndexCache{
+		contents:             map[blob.ID]index.Index{},
+		v1PerContentOverhead: func() int { return 3 },
+		maxMemoryBytes:       int64(ndx1.Length()) + 1,
+		log:                  testlogging.Printf(t.Logf, ""),
+	}
+
+	defer c.close()
+
+	require.NoError(t, c.addContentToCache(ctx, "ndx1", ndx1))
+	require.NoError(t, c.addContentToCache(ctx, "ndx2", mustBuildIndex(t, index.Builder{
+		mustParseID(t, "c2"): Info{PackBlobID: "p2345", ContentID: mustParseID(t, "c2")},
+	})))
+
+	c.mu.Lock()
+	require.Contains(t, c.contents, blob.ID("ndx1"))
+	require.NotContains(t, c.contents, blob.ID("ndx2"))
+	require.NotNil(t, c.spill)
+	c.mu.Unlock()
+
+	ndx2, err := c.openIndex(ctx, "ndx2")
+	require.NoError(t, err)
+
+	var i Info
+
+	ok, err := ndx2.GetInfo(mustParseID(t, "c2"), &i)
+	require.True(t, ok)
+	require.NoError(t, err)
+	require.Equal(t, blob.ID("p2345"), i.PackBlobID)
+	require.NoError(t, ndx2.Close())
+
+	// once ndx1 is no longer used, there is room for ndx3 in memory.
+	require.NoError(t, c.expireUnused(ctx, []blob.ID{"ndx2"}))
+	require.NoError(t, c.addContentToCache(ctx, "ndx3", mustBuildIndex(t, index.Builder{
+		mustParseID(t, "c3"): Info{PackBlobID: "p3456", ContentID: mustParseID(t, "c3")},
+	})))
+
+	c.mu.Lock()
+	require.Contains(t, c.contents, blob.ID("ndx3"))
+	c.mu.Unlock()
+}
+
 //nolint:thelper
 func testCache(t *testing.T, cache committedContentIndexCache, fakeTime *faketime.ClockTimeWithOffset) {
 	ctx := testlogging.Context(t)
@@ -140,6 +140,10 @@ func (c *diskCommittedContentIndexCache) addContentToCache(ctx context.Context,
 	return nil
 }
 
+func (c *diskCommittedContentIndexCache) close() error {
+	return nil
+}
+
 func writeTempFileAtomic(dirname string, data []byte) (string, error) {
 	// write to a temp file to avoid race where two processes are writing at the same time.
 	tf, err := os.CreateTemp(dirname, "tmp")
@@ -2,13 +2,30 @@
 
 import (
 	"context"
+	"os"
+	"path/filepath"
+	"strings"
 	"sync"
+	"time"
 
+	"github.com/gofrs/flock"
 	"github.com/pkg/errors"
 
+	"github.com/kopia/kopia/internal/clock"
 	"github.com/kopia/kopia/internal/gather"
 	"github.com/kopia/kopia/repo/blob"
 	"github.com/kopia/kopia/repo/content/index"
+	"github.com/kopia/kopia/repo/logging"
+)
+
+const (
+	indexSpillDirPrefix = "kopia-index-"
+
+	// indexSpillLockFile is locked by the process using the spill directory for as long as it's used.
+	indexSpillLockFile = "spill.lock"
+
+	// spill directories without a lock file are assumed to be being created until they're this old.
+	minUnlockedIndexSpillDirAge = time.Hour
 )
 
 type memoryCommittedContentIndexCache struct {
@@ -17,40 +34,112 @@ type memoryCommittedContentIndexCache struct {
 	// +checklocks:mu
 	contents map[blob.ID]index.Index
 
-	v1PerContentOverhead func() int // +checklocksignore
+	// +checklocks:mu
+	contentBytes map[blob.ID]int64
+
+	// +checklocks:mu
+	totalBytes int64
+
+	// index blobs that would exceed maxMemoryBytes are written to temporary files and memory-mapped,
+	// spill is created when that happens for the first time.
+	// +checklocks:mu
+	spill *diskCommittedContentIndexCache
+	// +checklocks:mu
+	spillLock *flock.Flock
+
+	v1PerContentOverhead func() int     // +checklocksignore
+	maxMemoryBytes       int64          // +checklocksignore
+	log                  logging.Logger // +checklocksignore
 }
 
 func (m *memoryCommittedContentIndexCache) hasIndexBlobID(ctx context.Context, indexBlobID blob.ID) (bool, error) {
 	m.mu.Lock()
 	defer m.mu.Unlock()
 
-	return m.contents[indexBlobID] != nil, nil
+	if m.contents[indexBlobID] != nil {
+		return true, nil
+	}
+
+	if m.spill != nil {
+		return m.spill.hasIndexBlobID(ctx, indexBlobID)
+	}
+
+	return false, nil
 }
 
 func (m *memoryCommittedContentIndexCache) addContentToCache(ctx context.Context, indexBlobID blob.ID, data gather.Bytes) error {
 	m.mu.Lock()
 	defer m.mu.Unlock()
 
+	if m.maxMemoryBytes > 0 && m.totalBytes+int64(data.Length()) > m.maxMemoryBytes {
+		return m.addSpilledLocked(ctx, indexBlobID, data)
+	}
+
 	ndx, err := index.Open(data.ToByteSlice(), nil, m.v1PerContentOverhead)
 	if err != nil {
 		return errors.Wrapf(err, "error opening index blob %v", indexBlobID)
 	}
 
+	if m.contentBytes == nil {
+		m.contentBytes = map[blob.ID]int64{}
+	}
+
+	m.totalBytes += int64(data.Length()) - m.contentBytes[indexBlobID]
 	m.contents[indexBlobID] = ndx
+	m.contentBytes[indexBlobID] = int64(data.Length())
 
 	return nil
 }
 
+// +checklocks:m.mu
+func (m *memoryCommittedContentIndexCache) addSpilledLocked(ctx context.Context, indexBlobID blob.ID, data gather.Bytes) error {
+	if m.spill == nil {
+		dirname, err := os.MkdirTemp("", indexSpillDirPrefix)
+		if err != nil {
+			return errors.Wrap(err, "unable to create temporary index directory")
+		}
+
+		// the lock prevents other processes from removing the directory as stale.
+		l := flock.New(filepath.Join(dirname, indexSpillLockFile))
+		if err := l.Lock(); err != nil {
+			return errors.Wrap(err, "unable to lock temporary index directory")
+		}
+
+		m.log.Debugf("index blobs exceed %v bytes, memory-mapping them from %v", m.maxMemoryBytes, dirname)
+
+		m.spillLock = l
+		m.spill = &diskCommittedContentIndexCache{
+			dirname:              dirname,
+			timeNow:              clock.Now,
+			v1PerContentOverhead: m.v1PerContentOverhead,
+			log:                  m.log,
+		}
+	}
+
+	return m.spill.addContentToCache(ctx, indexBlobID, data)
+}
+
 func (m *memoryCommittedContentIndexCache) openIndex(ctx context.Context, indexBlobID blob.ID) (index.Index, error) {
 	m.mu.Lock()
 	defer m.mu.Unlock()
 
 	v := m.contents[indexBlobID]
-	if v == nil {
-		return nil, errors.Errorf("content not found in cache: %v", indexBlobID)
+	if v != nil {
+		return v, nil
 	}
 
-	return v, nil
+	if m.spill != nil {
+		has, err := m.spill.hasIndexBlobID(ctx, indexBlobID)
+		if err != nil {
+			return nil, err
+		}
+
+		if has {
+			return m.spill.openIndex(ctx, indexBlobID)
+		}
+	}
+
+	return nil, errors.Errorf("content not found in cache: %v", indexBlobID)
 }
 
 func (m *memoryCommittedContentIndexCache) expireUnused(ctx context.Context, used []blob.ID) error {
@@ -58,14 +147,86 @@ func (m *memoryCommittedContentIndexCache) expireUnused(ctx context.Context, use
 	defer m.mu.Unlock()
 
 	n := map[blob.ID]index.Index{}
+	nb := map[blob.ID]int64{}
+
+	var total int64
 
 	for _, u := range used {
 		if v, ok := m.contents[u]; ok {
 			n[u] = v
+			nb[u] = m.contentBytes[u]
+			total += m.contentBytes[u]
 		}
 	}
 
 	m.contents = n
+	m.contentBytes = nb
+	m.totalBytes = total
+
+	if m.spill != nil {
+		return m.spill.expireUnused(ctx, used)
+	}
 
 	return nil
 }
+
+func (m *memoryCommittedContentIndexCache) close() error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if m.spill == nil {
+		return nil
+	}
+
+	dirname := m.spill.dirname
+	m.spill = nil
+
+	if err := m.spillLock.Unlock(); err != nil {
+		m.log.Debugf("unable to unlock %v: %v", dirname, err)
+	}
+
+	m.spillLock = nil
+
+	return errors.Wrap(os.RemoveAll(dirname), "unable to remove temporary index directory")
+}
+
+// removeStaleIndexSpillDirectories removes temporary index directories left behind by processes which exited
+// without removing them, skipping directories locked by running processes.
+func removeStaleIndexSpillDirectories(log logging.Logger) {
+	entries, err := os.ReadDir(os.TempDir())
+	if err != nil {
+		log.Debugf("unable to list temporary directory: %v", err)
+		return
+	}
+
+	for _, ent := range entries {
+		if !ent.IsDir() || !strings.HasPrefix(ent.Name(), indexSpillDirPrefix) {
+			continue
+		}
+
+		dirname := filepath.Join(os.TempDir(), ent.Name())
+		lockFile := filepath.Join(dirname, indexSpillLockFile)
+
+		if _, err := os.Stat(lockFile); os.IsNotExist(err) {
+			// the directory may have been just created and not locked yet.
+			if fi, err := ent.Info(); err != nil || clock.Now().Sub(fi.ModTime()) < minUnlockedIndexSpillDirAge {
+				continue
+			}
+		}
+
+		l := flock.New(lockFile)
+
+		if ok, err := l.TryLock(); err != nil || !ok {
+			continue
+		}
+
+		l.Unlock() //nolint:errcheck
+
+		if err := os.RemoveAll(dirname); err != nil {
+			log.Debugf("unable to remove stale temporary index directory %v: %v", dirname, err)
+			continue
+		}
+
+		log.Debugf("removed stale temporary index directory %v", dirname)
+	}
+}
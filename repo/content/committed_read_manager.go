@@ -2,6 +2,7 @@
 
 import (
 	"context"
+	"maps"
 	"os"
 	"path/filepath"
 	"sync"
@@ -81,14 +82,24 @@ type SharedManager struct {
 	contentCache      cache.ContentCache
 	metadataCache     cache.ContentCache
 	indexBlobCache    *cache.PersistentCache
+	ownWritesCache    *ownwrites.CacheStorage // nil if not caching
 	committedContents *committedContentIndex
 	timeNow           func() time.Time
 
+	// cacheDirectory is the directory where statistics of caches are accumulated, empty if not caching.
+	cacheDirectory string
+
+	cacheSizeLimitsMutex sync.Mutex
+	// +checklocks:cacheSizeLimitsMutex
+	cacheSizeLimits CacheSizeLimits
+
 	// lock to protect the set of committed indexes
 	// shared lock will be acquired when writing new content to allow it to happen in parallel
 	// exclusive lock will be acquired during compaction or refresh.
 	indexesLock            sync.RWMutex
 	permissiveCacheLoading bool
+	maxIndexMemoryBytes    int64
+	readAheadBytes         int64
 
 	// maybeRefreshIndexes() will call Refresh() after this point in ime.
 	// +checklocks:indexesLock
@@ -370,21 +381,26 @@ func (sm *SharedManager) IndexBlobs(ctx context.Context, includeInactive bool) (
 	return blobs, err
 }
 
-func newOwnWritesCache(ctx context.Context, st blob.Storage, caching *CachingOptions) (blob.Storage, error) {
+func newOwnWritesCache(ctx context.Context, st blob.Storage, caching *CachingOptions, mr *metrics.Registry) (blob.Storage, error) {
 	cacheSt, err := newCacheBackingStorage(ctx, caching, "own-writes")
 	if err != nil {
 		return nil, errors.Wrap(err, "unable to get list cache backing storage")
 	}
 
-	return ownwrites.NewWrapper(st, cacheSt, cachedIndexBlobPrefixes, ownWritesCacheDuration), nil
+	return ownwrites.NewWrapper(st, cacheSt, cachedIndexBlobPrefixes, ownWritesCacheDuration, mr), nil
 }
 
-func newListCache(ctx context.Context, st blob.Storage, caching *CachingOptions) (blob.Storage, error) {
+func newListCache(ctx context.Context, st blob.Storage, caching *CachingOptions, timeNow func() time.Time) (blob.Storage, error) {
 	cacheSt, err := newCacheBackingStorage(ctx, caching, "blob-list")
 	if err != nil {
 		return nil, errors.Wrap(err, "unable to get list cache backing storage")
 	}
 
+	if cacheSt == nil {
+		// without cache directory, cache list results in memory.
+		return listcache.NewInMemoryWrapper(st, cachedIndexBlobPrefixes, caching.MaxListCacheDuration.DurationOrDefault(0), timeNow), nil
+	}
+
 	return listcache.NewWrapper(st, cacheSt, cachedIndexBlobPrefixes, caching.HMACSecret, caching.MaxListCacheDuration.DurationOrDefault(0)), nil
 }
 
@@ -422,17 +438,19 @@ func (sm *SharedManager) namedLogger(n string) logging.Logger {
 
 func contentCacheSweepSettings(caching *CachingOptions) cache.SweepSettings {
 	return cache.SweepSettings{
-		MaxSizeBytes: caching.ContentCacheSizeBytes,
-		LimitBytes:   caching.ContentCacheSizeLimitBytes,
-		MinSweepAge:  caching.MinContentSweepAge.DurationOrDefault(DefaultDataCacheSweepAge),
+		MaxSizeBytes:   caching.ContentCacheSizeBytes,
+		LimitBytes:     caching.ContentCacheSizeLimitBytes,
+		MinSweepAge:    caching.MinContentSweepAge.DurationOrDefault(DefaultDataCacheSweepAge),
+		EvictionPolicy: cache.EvictionPolicy(caching.ContentCacheEvictionPolicy),
 	}
 }
 
 func metadataCacheSizeSweepSettings(caching *CachingOptions) cache.SweepSettings {
 	return cache.SweepSettings{
-		MaxSizeBytes: caching.EffectiveMetadataCacheSizeBytes(),
-		LimitBytes:   caching.MetadataCacheSizeLimitBytes,
-		MinSweepAge:  caching.MinMetadataSweepAge.DurationOrDefault(DefaultMetadataCacheSweepAge),
+		MaxSizeBytes:   caching.EffectiveMetadataCacheSizeBytes(),
+		LimitBytes:     caching.MetadataCacheSizeLimitBytes,
+		MinSweepAge:    caching.MinMetadataSweepAge.DurationOrDefault(DefaultMetadataCacheSweepAge),
+		EvictionPolicy: cache.EvictionPolicy(caching.MetadataCacheEvictionPolicy),
 	}
 }
 
@@ -443,11 +461,50 @@ func indexBlobCacheSweepSettings(caching *CachingOptions) cache.SweepSettings {
 	}
 }
 
+// CacheSizeLimits returns the current size limits of local caches.
+func (sm *SharedManager) CacheSizeLimits() CacheSizeLimits {
+	sm.cacheSizeLimitsMutex.Lock()
+	defer sm.cacheSizeLimitsMutex.Unlock()
+
+	return sm.cacheSizeLimits
+}
+
+// SetCacheSizeLimits changes size limits of local caches while the repository is open, immediately
+// removing items from caches which are above their new limits.
+func (sm *SharedManager) SetCacheSizeLimits(ctx context.Context, l CacheSizeLimits) error {
+	if err := l.Validate(); err != nil {
+		return err
+	}
+
+	sm.cacheSizeLimitsMutex.Lock()
+	defer sm.cacheSizeLimitsMutex.Unlock()
+
+	var caching CachingOptions
+
+	caching.SetSizeLimits(l)
+
+	cs := contentCacheSweepSettings(&caching)
+	sm.contentCache.SetSizeLimits(ctx, cs.MaxSizeBytes, cs.LimitBytes)
+
+	ms := metadataCacheSizeSweepSettings(&caching)
+	sm.metadataCache.SetSizeLimits(ctx, ms.MaxSizeBytes, ms.LimitBytes)
+
+	is := indexBlobCacheSweepSettings(&caching)
+	sm.indexBlobCache.SetSizeLimits(ctx, is.MaxSizeBytes, is.LimitBytes)
+
+	sm.cacheSizeLimits = l
+
+	return nil
+}
+
 func (sm *SharedManager) setupCachesAndIndexManagers(ctx context.Context, caching *CachingOptions, mr *metrics.Registry) error {
+	sm.cacheDirectory = caching.CacheDirectory
+
 	dataCache, err := cache.NewContentCache(ctx, sm.st, cache.Options{
 		BaseCacheDirectory: caching.CacheDirectory,
 		CacheSubDir:        "contents",
 		HMACSecret:         caching.HMACSecret,
+		ReadAheadBytes:     sm.readAheadBytes,
 		Sweep:              contentCacheSweepSettings(caching),
 	}, mr)
 	if err != nil {
@@ -479,12 +536,14 @@ func (sm *SharedManager) setupCachesAndIndexManagers(ctx context.Context, cachin
 		return errors.Wrap(err, "unable to create index blob cache")
 	}
 
-	ownWritesCachingSt, err := newOwnWritesCache(ctx, sm.st, caching)
+	ownWritesCachingSt, err := newOwnWritesCache(ctx, sm.st, caching, mr)
 	if err != nil {
 		return errors.Wrap(err, "unable to initialize own writes cache")
 	}
 
-	cachedSt, err := newListCache(ctx, ownWritesCachingSt, caching)
+	sm.ownWritesCache, _ = ownWritesCachingSt.(*ownwrites.CacheStorage)
+
+	cachedSt, err := newListCache(ctx, ownWritesCachingSt, caching, sm.timeNow)
 	if err != nil {
 		return errors.Wrap(err, "unable to initialize list cache")
 	}
@@ -524,13 +583,18 @@ func(ctx context.Context, blobIDs []blob.ID, outputPrefix blob.ID) error {
 	sm.contentCache = dataCache
 	sm.metadataCache = metadataCache
 	sm.indexBlobCache = indexBlobCache
+
+	sm.cacheSizeLimitsMutex.Lock()
+	sm.cacheSizeLimits = caching.SizeLimits()
+	sm.cacheSizeLimitsMutex.Unlock()
 	sm.committedContents = newCommittedContentIndex(caching,
 		sm.format.Encryptor().Overhead,
 		sm.format,
 		sm.permissiveCacheLoading,
 		enc.GetEncryptedBlob,
 		sm.namedLogger("committed-content-index"),
-		caching.MinIndexSweepAge.DurationOrDefault(DefaultIndexCacheSweepAge))
+		caching.MinIndexSweepAge.DurationOrDefault(DefaultIndexCacheSweepAge),
+		sm.maxIndexMemoryBytes)
 
 	return nil
 }
@@ -573,6 +637,9 @@ func (sm *SharedManager) CloseShared(ctx context.Context) error {
 	sm.metadataCache.Close(ctx)
 	sm.indexBlobCache.Close(ctx)
 
+	// caches are swept when closed, so record statistics afterwards to include evictions.
+	sm.recordCacheStats(ctx)
+
 	if sm.internalLogger != nil {
 		sm.internalLogger.Sync() //nolint:errcheck
 	}
@@ -582,6 +649,43 @@ func (sm *SharedManager) CloseShared(ctx context.Context) error {
 	return nil
 }
 
+// CacheStats returns statistics of caches used by the manager since it was opened, keyed by cache name.
+func (sm *SharedManager) CacheStats() map[string]cache.Stats {
+	result := map[string]cache.Stats{
+		"contents":    sm.contentCache.Stats(),
+		"metadata":    sm.metadataCache.Stats(),
+		"index-blobs": sm.indexBlobCache.Stats(),
+	}
+
+	if sm.ownWritesCache != nil {
+		result["own-writes"] = sm.ownWritesCache.Stats()
+	}
+
+	return result
+}
+
+// recordCacheStats adds statistics of caches to the ones accumulated in the cache directory,
+// so that they can be examined after the process exits.
+func (sm *SharedManager) recordCacheStats(ctx context.Context) {
+	if sm.cacheDirectory == "" {
+		return
+	}
+
+	stats := sm.CacheStats()
+
+	maps.DeleteFunc(stats, func(_ string, s cache.Stats) bool {
+		return s.IsZero()
+	})
+
+	if len(stats) == 0 {
+		return
+	}
+
+	if err := cache.AddStats(sm.cacheDirectory, stats); err != nil {
+		sm.log.Debugf("unable to record cache statistics: %v", err)
+	}
+}
+
 // AlsoLogToContentLog wraps the provided content so that all logs are also sent to
 // internal content log.
 func (sm *SharedManager) AlsoLogToContentLog(ctx context.Context) context.Context {
@@ -605,6 +709,14 @@ func (sm *SharedManager) PrepareUpgradeToIndexBlobManagerV1(ctx context.Context)
 	return sm.indexBlobManagerV1.PrepareUpgradeToIndexBlobManagerV1(ctx, sm.indexBlobManagerV0)
 }
 
+// MigrateIndexesToEpochFormat writes the contents of all legacy index blobs as the first epoch of
+// epoch-based indexes, reporting progress, and returns the IDs of blobs written. The repository
+// format must be updated separately for the migrated indexes to be used.
+func (sm *SharedManager) MigrateIndexesToEpochFormat(ctx context.Context, progress indexblob.MigrationProgress) ([]blob.ID, error) {
+	//nolint:wrapcheck
+	return sm.indexBlobManagerV1.MigrateFromV0(ctx, sm.indexBlobManagerV0, progress)
+}
+
 // NewSharedManager returns SharedManager that is used by SessionWriteManagers on top of a repository.
 func NewSharedManager(ctx context.Context, st blob.Storage, prov format.Provider, caching *CachingOptions, opts *ManagerOptions, repoLogManager *repodiag.LogManager, mr *metrics.Registry) (*SharedManager, error) {
 	opts = opts.CloneOrDefault()
@@ -618,6 +730,8 @@ func NewSharedManager(ctx context.Context, st blob.Storage, prov format.Provider
 		timeNow:                 opts.TimeNow,
 		format:                  prov,
 		permissiveCacheLoading:  opts.PermissiveCacheLoading,
+		maxIndexMemoryBytes:     opts.MaxIndexMemoryBytes,
+		readAheadBytes:          opts.ReadAheadBytes,
 		minPreambleLength:       defaultMinPreambleLength,
 		maxPreambleLength:       defaultMaxPreambleLength,
 		paddingUnit:             defaultPaddingUnit,
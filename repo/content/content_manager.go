@@ -539,6 +539,8 @@ func (bm *WriteManager) processWritePackResultLocked(pp *pendingPackInfo, packFi
 			bm.packIndexBuilder.Add(info)
 		}
 
+		bm.recordSessionProgressLocked(int64(pp.currentPackData.Length()))
+
 		pp.currentPackData.Close()
 
 		return nil
@@ -781,6 +783,14 @@ func (bm *WriteManager) SupportsContentCompression() bool {
 // WriteContent saves a given content of data to a pack group with a provided name and returns a contentID
 // that's based on the contents of data written.
 func (bm *WriteManager) WriteContent(ctx context.Context, data gather.Bytes, prefix index.IDPrefix, comp compression.HeaderID) (ID, error) {
+	contentID, _, err := bm.WriteContentWithDedupInfo(ctx, data, prefix, comp)
+
+	return contentID, err
+}
+
+// WriteContentWithDedupInfo is like WriteContent but also returns true if the content was already present
+// in the repository and was deduplicated instead of being written.
+func (bm *WriteManager) WriteContentWithDedupInfo(ctx context.Context, data gather.Bytes, prefix index.IDPrefix, comp compression.HeaderID) (contentID ID, deduplicated bool, err error) {
 	t0 := timetrack.StartTimer()
 	defer func() {
 		bm.writeContentBytes.Observe(int64(data.Length()), t0.Elapsed())
@@ -788,22 +798,22 @@ func (bm *WriteManager) WriteContent(ctx context.Context, data gather.Bytes, pre
 
 	mp, mperr := bm.format.GetMutableParameters(ctx)
 	if mperr != nil {
-		return EmptyID, errors.Wrap(mperr, "mutable parameters")
+		return EmptyID, false, errors.Wrap(mperr, "mutable parameters")
 	}
 
 	if err := bm.maybeRetryWritingFailedPacksUnlocked(ctx); err != nil {
-		return EmptyID, err
+		return EmptyID, false, err
 	}
 
 	if err := prefix.ValidateSingle(); err != nil {
-		return EmptyID, errors.Wrap(err, "invalid prefix")
+		return EmptyID, false, errors.Wrap(err, "invalid prefix")
 	}
 
 	var hashOutput [hashing.MaxHashSize]byte
 
-	contentID, err := IDFromHash(prefix, bm.hashData(hashOutput[:0], data))
+	contentID, err = IDFromHash(prefix, bm.hashData(hashOutput[:0], data))
 	if err != nil {
-		return EmptyID, errors.Wrap(err, "invalid hash")
+		return EmptyID, false, errors.Wrap(err, "invalid hash")
 	}
 
 	previousWriteTime := int64(-1)
@@ -824,7 +834,7 @@ func (bm *WriteManager) WriteContent(ctx context.Context, data gather.Bytes, pre
 			bm.deduplicatedContents.Add(1)
 			bm.deduplicatedBytes.Add(int64(data.Length()))
 
-			return contentID, nil
+			return contentID, true, nil
 		}
 
 		previousWriteTime = bi.TimestampSeconds
@@ -835,7 +845,7 @@ func (bm *WriteManager) WriteContent(ctx context.Context, data gather.Bytes, pre
 
 	bm.log.Debug(logbuf.String())
 
-	return contentID, bm.addToPackUnlocked(ctx, contentID, data, false, comp, previousWriteTime, mp)
+	return contentID, false, bm.addToPackUnlocked(ctx, contentID, data, false, comp, previousWriteTime, mp)
 }
 
 // GetContent gets the contents of a given content. If the content is not found returns ErrContentNotFound.
@@ -951,6 +961,15 @@ type ManagerOptions struct {
 	TimeNow                func() time.Time // Time provider
 	DisableInternalLog     bool
 	PermissiveCacheLoading bool
+
+	// MaxIndexMemoryBytes, if positive, limits the total size of index blobs kept in memory when
+	// caching is disabled, index blobs beyond this limit are memory-mapped from temporary files.
+	// It has no effect when caching is enabled, since index blobs are memory-mapped from the cache directory.
+	MaxIndexMemoryBytes int64
+
+	// ReadAheadBytes, if positive, is the number of bytes of pack blobs prefetched into the content cache
+	// when sequential reads of the same pack blob are detected.
+	ReadAheadBytes int64
 }
 
 // CloneOrDefault returns a clone of provided ManagerOptions or default empty struct if nil.
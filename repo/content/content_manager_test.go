@@ -1942,7 +1942,7 @@ func (s *contentManagerSuite) TestReadsOwnWritesWithEventualConsistencyPersisten
 		timeNow)
 
 	// disable own writes cache, will still be ok if store is strongly consistent
-	s.verifyReadsOwnWrites(t, ownwrites.NewWrapper(ecst, cacheSt, cachedIndexBlobPrefixes, ownWritesCacheDuration), timeNow)
+	s.verifyReadsOwnWrites(t, ownwrites.NewWrapper(ecst, cacheSt, cachedIndexBlobPrefixes, ownWritesCacheDuration, nil), timeNow)
 }
 
 func (s *contentManagerSuite) TestReadsOwnWritesWithStrongConsistencyAndNoCaching(t *testing.T) {
@@ -2372,6 +2372,44 @@ func (s *contentManagerSuite) TestContentIndexPermissiveReadsWithFault(t *testin
 	}
 }
 
+func (s *contentManagerSuite) TestSessionProgress(t *testing.T) {
+	data := blobtesting.DataMap{}
+	st := blobtesting.NewMapStorage(data, nil, nil)
+
+	ctx := testlogging.Context(t)
+
+	bm := s.newTestContentManager(t, st)
+
+	defer bm.CloseShared(ctx)
+
+	writeContentAndVerify(ctx, t, bm, seededRandomData(1, maxPackCapacity))
+	writeContentAndVerify(ctx, t, bm, seededRandomData(2, maxPackCapacity))
+
+	sessions, err := bm.ListActiveSessions(ctx)
+	require.NoError(t, err)
+	require.Len(t, sessions, 1)
+
+	for _, si := range sessions {
+		require.Positive(t, si.BytesWritten)
+	}
+
+	// progress is tracked in memory, the session marker is only written once.
+	var markers []blob.Metadata
+
+	require.NoError(t, st.ListBlobs(ctx, BlobIDPrefixSession, func(md blob.Metadata) error {
+		markers = append(markers, md)
+		return nil
+	}))
+	require.Len(t, markers, 1)
+
+	// committing the session removes all markers.
+	require.NoError(t, bm.Flush(ctx))
+
+	sessions, err = bm.ListActiveSessions(ctx)
+	require.NoError(t, err)
+	require.Empty(t, sessions)
+}
+
 func wipeCache(t *testing.T, st cache.Storage) {
 	t.Helper()
 
@@ -39,8 +39,6 @@ func (co *CompactOptions) maxEventualConsistencySettleTime() time.Duration {
 // It is less than 2^24, which lets V1 index use 24-bit/3-byte indexes.
 const DefaultIndexShardSize = 16e6
 
-const verySmallContentFraction = 20 // blobs less than 1/verySmallContentFraction of maxPackSize are considered 'very small'
-
 func addBlobsToIndex(ndx map[blob.ID]*Metadata, blobs []blob.Metadata) {
 	for _, it := range blobs {
 		if ndx[it.BlobID] == nil {
@@ -446,15 +446,24 @@ func (m *ManagerV0) getBlobsToCompact(indexBlobs []Metadata, opt CompactOptions,
 		mediumSizedBlobCount                                                           int
 	)
 
+	smallBlobSize := mp.IndexCompaction.EffectiveSmallBlobSizeBytes(mp.MaxPackSize)
+	minBlobAge := mp.IndexCompaction.EffectiveMinBlobAge()
+	now := m.timeNow()
+
 	for _, b := range indexBlobs {
 		if b.Length > int64(mp.MaxPackSize) && !opt.AllIndexes {
 			continue
 		}
 
+		if minBlobAge > 0 && now.Sub(b.Timestamp) < minBlobAge && !opt.AllIndexes {
+			// too new to be compacted
+			continue
+		}
+
 		nonCompactedBlobs = append(nonCompactedBlobs, b)
 		totalSizeNonCompactedBlobs += b.Length
 
-		if b.Length < int64(mp.MaxPackSize)/verySmallContentFraction {
+		if b.Length < smallBlobSize {
 			verySmallBlobs = append(verySmallBlobs, b)
 			totalSizeVerySmallBlobs += b.Length
 		} else {
@@ -777,6 +777,7 @@ func newIndexBlobManagerForTesting(t *testing.T, st blob.Storage, localTimeNow f
 		blobtesting.NewMapStorage(blobtesting.DataMap{}, nil, nil),
 		[]blob.ID{V0IndexBlobPrefix, V0CompactionLogBlobPrefix, V0CleanupBlobPrefix},
 		15*time.Minute,
+		nil,
 	)
 
 	log := testlogging.Printf(t.Logf, "")
@@ -795,3 +796,41 @@ func newIndexBlobManagerForTesting(t *testing.T, st blob.Storage, localTimeNow f
 
 	return m
 }
+
+func TestGetBlobsToCompactThresholds(t *testing.T) {
+	now := fakeLocalStartTime
+	m := &ManagerV0{
+		timeNow: func() time.Time { return now },
+		log:     testlogging.Printf(t.Logf, ""),
+	}
+
+	var blobs []Metadata
+
+	// 4 old small blobs, 2 new small blobs and 2 medium-sized blobs.
+	for i, age := range []time.Duration{time.Hour, time.Hour, time.Hour, time.Hour, time.Minute, time.Minute} {
+		blobs = append(blobs, Metadata{Metadata: blob.Metadata{BlobID: blob.ID(fmt.Sprintf("n%v", i)), Length: 1000, Timestamp: now.Add(-age)}})
+	}
+
+	for i := range 2 {
+		blobs = append(blobs, Metadata{Metadata: blob.Metadata{BlobID: blob.ID(fmt.Sprintf("m%v", i)), Length: 100000, Timestamp: now.Add(-time.Hour)}})
+	}
+
+	mp := format.MutableParameters{MaxPackSize: 1000000}
+
+	// below default count threshold.
+	require.Empty(t, m.getBlobsToCompact(blobs, CompactOptions{MaxSmallBlobs: format.DefaultIndexCompactionMaxSmallBlobs + 1}, mp))
+
+	// all blobs are compacted when the count threshold is reached and more than half of blobs are very small.
+	require.Len(t, m.getBlobsToCompact(blobs, CompactOptions{MaxSmallBlobs: 4}, mp), 6)
+
+	// larger small blob size makes medium-sized blobs small.
+	mp.IndexCompaction = &format.IndexCompactionParameters{SmallBlobSizeBytes: 200000}
+	require.Len(t, m.getBlobsToCompact(blobs, CompactOptions{MaxSmallBlobs: 4}, mp), 8)
+
+	// new blobs are not compacted.
+	mp.IndexCompaction = &format.IndexCompactionParameters{MinBlobAge: 30 * time.Minute}
+	require.Len(t, m.getBlobsToCompact(blobs, CompactOptions{MaxSmallBlobs: 4}, mp), 4)
+
+	// unless all indexes are compacted.
+	require.Len(t, m.getBlobsToCompact(blobs, CompactOptions{MaxSmallBlobs: 4, AllIndexes: true}, mp), 6)
+}
@@ -68,22 +68,34 @@ func (m *ManagerV1) Compact(ctx context.Context, opt CompactOptions) error {
 
 // CompactEpoch compacts the provided index blobs and writes a new set of blobs.
 func (m *ManagerV1) CompactEpoch(ctx context.Context, blobIDs []blob.ID, outputPrefix blob.ID) error {
+	_, err := m.compactEpoch(ctx, blobIDs, outputPrefix, nil)
+
+	return err
+}
+
+// compactEpoch compacts the provided index blobs, optionally reporting the number of blobs
+// processed so far, and returns the IDs of blobs written.
+func (m *ManagerV1) compactEpoch(ctx context.Context, blobIDs []blob.ID, outputPrefix blob.ID, progress MigrationProgress) ([]blob.ID, error) {
 	tmpbld := make(index.Builder)
 
-	for _, indexBlob := range blobIDs {
+	for i, indexBlob := range blobIDs {
 		if err := addIndexBlobsToBuilder(ctx, m.enc, tmpbld, indexBlob); err != nil {
-			return errors.Wrap(err, "error adding index to builder")
+			return nil, errors.Wrap(err, "error adding index to builder")
+		}
+
+		if progress != nil {
+			progress(i+1, len(blobIDs))
 		}
 	}
 
 	mp, mperr := m.formattingOptions.GetMutableParameters(ctx)
 	if mperr != nil {
-		return errors.Wrap(mperr, "mutable parameters")
+		return nil, errors.Wrap(mperr, "mutable parameters")
 	}
 
 	dataShards, cleanupShards, err := tmpbld.BuildShards(mp.IndexVersion, true, DefaultIndexShardSize)
 	if err != nil {
-		return errors.Wrap(err, "unable to build index dataShards")
+		return nil, errors.Wrap(err, "unable to build index dataShards")
 	}
 
 	defer cleanupShards()
@@ -91,12 +103,16 @@ func (m *ManagerV1) CompactEpoch(ctx context.Context, blobIDs []blob.ID, outputP
 	var rnd [8]byte
 
 	if _, err := rand.Read(rnd[:]); err != nil {
-		return errors.Wrap(err, "error getting random session ID")
+		return nil, errors.Wrap(err, "error getting random session ID")
 	}
 
 	sessionID := fmt.Sprintf("s%x-c%v", rnd[:], len(dataShards))
 
-	var data2 gather.WriteBuffer
+	var (
+		data2   gather.WriteBuffer
+		written []blob.ID
+	)
+
 	defer data2.Close()
 
 	for _, data := range dataShards {
@@ -104,15 +120,17 @@ func (m *ManagerV1) CompactEpoch(ctx context.Context, blobIDs []blob.ID, outputP
 
 		blobID, err := blobcrypto.Encrypt(m.enc.crypter, data, outputPrefix, blob.ID(sessionID), &data2)
 		if err != nil {
-			return errors.Wrap(err, "error encrypting")
+			return written, errors.Wrap(err, "error encrypting")
 		}
 
 		if err := m.st.PutBlob(ctx, blobID, data2.Bytes(), blob.PutOptions{}); err != nil {
-			return errors.Wrap(err, "error writing index blob")
+			return written, errors.Wrap(err, "error writing index blob")
 		}
+
+		written = append(written, blobID)
 	}
 
-	return nil
+	return written, nil
 }
 
 // WriteIndexBlobs writes dataShards into new index blobs with an optional blob name suffix.
@@ -148,9 +166,22 @@ func (m *ManagerV1) EpochManager() *epoch.Manager {
 
 // PrepareUpgradeToIndexBlobManagerV1 prepares the repository for migrating to IndexBlobManagerV1.
 func (m *ManagerV1) PrepareUpgradeToIndexBlobManagerV1(ctx context.Context, v0 *ManagerV0) error {
+	_, err := m.MigrateFromV0(ctx, v0, nil)
+
+	return err
+}
+
+// MigrationProgress is invoked during index migration with the number of source index blobs
+// processed so far and the total number of them.
+type MigrationProgress func(done, total int)
+
+// MigrateFromV0 writes the contents of all active index blobs of ManagerV0 as the first epoch of
+// ManagerV1 and returns the IDs of blobs written, which are also returned on failure so that
+// partial migration can be rolled back.
+func (m *ManagerV1) MigrateFromV0(ctx context.Context, v0 *ManagerV0, progress MigrationProgress) ([]blob.ID, error) {
 	ibl, _, err := v0.ListActiveIndexBlobs(ctx)
 	if err != nil {
-		return errors.Wrap(err, "error listing active index blobs")
+		return nil, errors.Wrap(err, "error listing active index blobs")
 	}
 
 	var blobIDs []blob.ID
@@ -159,11 +190,12 @@ func (m *ManagerV1) PrepareUpgradeToIndexBlobManagerV1(ctx context.Context, v0 *
 		blobIDs = append(blobIDs, ib.BlobID)
 	}
 
-	if err := m.CompactEpoch(ctx, blobIDs, epoch.UncompactedEpochBlobPrefix(epoch.FirstEpoch)); err != nil {
-		return errors.Wrap(err, "unable to generate initial epoch")
+	written, err := m.compactEpoch(ctx, blobIDs, epoch.UncompactedEpochBlobPrefix(epoch.FirstEpoch), progress)
+	if err != nil {
+		return written, errors.Wrap(err, "unable to generate initial epoch")
 	}
 
-	return nil
+	return written, nil
 }
 
 // NewManagerV1 creates new instance of ManagerV1 with all required parameters set.
@@ -31,6 +31,7 @@ type SessionInfo struct {
 	CheckpointTime time.Time `json:"checkpointTime"`
 	User           string    `json:"username"`
 	Host           string    `json:"hostname"`
+	BytesWritten   int64     `json:"bytesWritten,omitempty"` // bytes written in pack blobs, only known for sessions of this writer
 }
 
 //nolint:gochecknoglobals
@@ -97,6 +98,18 @@ func (bm *WriteManager) commitSession(ctx context.Context) error {
 	return nil
 }
 
+// recordSessionProgressLocked records the pack blob written as part of the current session.
+// The progress is only tracked in memory and is not persisted in session markers.
+//
+// +checklocks:bm.mu
+func (bm *WriteManager) recordSessionProgressLocked(packBytes int64) {
+	if bm.currentSessionInfo.ID == "" {
+		return
+	}
+
+	bm.currentSessionInfo.BytesWritten += packBytes
+}
+
 // writeSessionMarkerLocked writes a session marker indicating last time the session
 // was known to be alive.
 // TODO(jkowalski): write this periodically when sessions span the duration of an upload.
@@ -193,5 +206,13 @@ func (bm *WriteManager) ListActiveSessions(ctx context.Context) (map[SessionID]*
 		}
 	}
 
+	// progress of the current session of this writer is only known in memory.
+	bm.lock()
+	defer bm.unlock(ctx)
+
+	if si := m[bm.currentSessionInfo.ID]; si != nil {
+		si.BytesWritten = bm.currentSessionInfo.BytesWritten
+	}
+
 	return m, nil
 }
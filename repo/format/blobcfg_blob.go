@@ -3,10 +3,15 @@
 import (
 	"context"
 	"encoding/json"
+	"slices"
+	"sort"
+	"strings"
 	"time"
 
 	"github.com/pkg/errors"
+	"github.com/xhit/go-str2duration/v2"
 
+	"github.com/kopia/kopia/internal/feature"
 	"github.com/kopia/kopia/internal/gather"
 	"github.com/kopia/kopia/repo/blob"
 )
@@ -15,11 +20,172 @@
 // settings for the repository.
 const KopiaBlobCfgBlobID = "kopia.blobcfg"
 
+// minRetentionPeriod is the minimum supported blob retention period.
+const minRetentionPeriod = 24 * time.Hour
+
+// FeaturePrefixRetention is the feature required to open repositories with per-prefix blob retention overrides,
+// which must not be opened by clients that would apply the repository-wide retention to all blobs instead.
+const FeaturePrefixRetention feature.Feature = "prefix-retention"
+
 // BlobStorageConfiguration is the content for `kopia.blobcfg` blob which contains the blob
 // storage configuration options.
 type BlobStorageConfiguration struct {
 	RetentionMode   blob.RetentionMode `json:"retentionMode,omitempty"`
 	RetentionPeriod time.Duration      `json:"retentionPeriod,omitempty"`
+
+	// PrefixRetention overrides retention mode and/or period for blobs with particular prefixes.
+	PrefixRetention []PrefixRetention `json:"prefixRetention,omitempty"`
+}
+
+// PrefixRetention overrides blob retention for blobs whose IDs start with the given prefix.
+// Empty retention mode or zero retention period inherit the repository-wide setting.
+type PrefixRetention struct {
+	Prefix          blob.ID            `json:"prefix"`
+	RetentionMode   blob.RetentionMode `json:"retentionMode,omitempty"`
+	RetentionPeriod time.Duration      `json:"retentionPeriod,omitempty"`
+}
+
+// ParsePrefixRetention parses prefix retention override in the PREFIX=[MODE:]PERIOD format,
+// such as "kopia.=COMPLIANCE:8760h" or "p=720h".
+func ParsePrefixRetention(s string) (PrefixRetention, error) {
+	prefix, value, ok := strings.Cut(s, "=")
+	if !ok || prefix == "" || value == "" {
+		return PrefixRetention{}, errors.Errorf("invalid prefix retention %q, must be PREFIX=[MODE:]PERIOD", s)
+	}
+
+	pr := PrefixRetention{Prefix: blob.ID(prefix)}
+
+	if mode, period, hasMode := strings.Cut(value, ":"); hasMode {
+		pr.RetentionMode = blob.RetentionMode(mode)
+		value = period
+	}
+
+	if value != "" {
+		d, err := str2duration.ParseDuration(value)
+		if err != nil {
+			return PrefixRetention{}, errors.Wrapf(err, "invalid retention period in %q", s)
+		}
+
+		pr.RetentionPeriod = d
+	}
+
+	return pr, pr.validate()
+}
+
+// String returns string representation of the prefix retention override, which can be parsed using ParsePrefixRetention().
+func (pr PrefixRetention) String() string {
+	var sb strings.Builder
+
+	sb.WriteString(string(pr.Prefix))
+	sb.WriteString("=")
+
+	if pr.RetentionMode != "" {
+		sb.WriteString(string(pr.RetentionMode))
+		sb.WriteString(":")
+	}
+
+	if pr.RetentionPeriod != 0 {
+		sb.WriteString(pr.RetentionPeriod.String())
+	}
+
+	return sb.String()
+}
+
+func (pr PrefixRetention) validate() error {
+	if pr.Prefix == "" {
+		return errors.Errorf("prefix retention requires a prefix")
+	}
+
+	if pr.RetentionMode == "" && pr.RetentionPeriod == 0 {
+		return errors.Errorf("prefix retention for %q must override retention mode or period", pr.Prefix)
+	}
+
+	if pr.RetentionMode != "" && !pr.RetentionMode.IsValid() {
+		return errors.Errorf("invalid retention mode %q for prefix %q", pr.RetentionMode, pr.Prefix)
+	}
+
+	if pr.RetentionPeriod != 0 && pr.RetentionPeriod < minRetentionPeriod {
+		return errors.Errorf("invalid retention period %v for prefix %q, the minimum required is 1-day", pr.RetentionPeriod, pr.Prefix)
+	}
+
+	return nil
+}
+
+// RetentionForBlob returns the retention mode and period applicable to the provided blob,
+// taking into account the override with the longest matching prefix, if any.
+func (r *BlobStorageConfiguration) RetentionForBlob(id blob.ID) (blob.RetentionMode, time.Duration) {
+	mode, period := r.RetentionMode, r.RetentionPeriod
+
+	var best *PrefixRetention
+
+	for i := range r.PrefixRetention {
+		pr := &r.PrefixRetention[i]
+
+		if strings.HasPrefix(string(id), string(pr.Prefix)) && (best == nil || len(pr.Prefix) > len(best.Prefix)) {
+			best = pr
+		}
+	}
+
+	if best != nil {
+		if best.RetentionMode != "" {
+			mode = best.RetentionMode
+		}
+
+		if best.RetentionPeriod != 0 {
+			period = best.RetentionPeriod
+		}
+	}
+
+	return mode, period
+}
+
+// MinRetentionPeriod returns the shortest retention period applicable to any blob.
+func (r *BlobStorageConfiguration) MinRetentionPeriod() time.Duration {
+	result := r.RetentionPeriod
+
+	for _, pr := range r.PrefixRetention {
+		if pr.RetentionPeriod != 0 && pr.RetentionPeriod < result {
+			result = pr.RetentionPeriod
+		}
+	}
+
+	return result
+}
+
+// SetPrefixRetention adds or replaces the retention override for the prefix.
+func (r *BlobStorageConfiguration) SetPrefixRetention(pr PrefixRetention) {
+	r.RemovePrefixRetention(pr.Prefix)
+	r.PrefixRetention = append(r.PrefixRetention, pr)
+
+	sort.Slice(r.PrefixRetention, func(i, j int) bool {
+		return r.PrefixRetention[i].Prefix < r.PrefixRetention[j].Prefix
+	})
+}
+
+// RemovePrefixRetention removes the retention override for the prefix, if any.
+func (r *BlobStorageConfiguration) RemovePrefixRetention(prefix blob.ID) {
+	r.PrefixRetention = slices.DeleteFunc(r.PrefixRetention, func(pr PrefixRetention) bool {
+		return pr.Prefix == prefix
+	})
+}
+
+// UpdateRequiredFeatures returns the provided list of required features, including FeaturePrefixRetention
+// if and only if the configuration has any prefix retention overrides.
+func (r *BlobStorageConfiguration) UpdateRequiredFeatures(required []feature.Required) []feature.Required {
+	result := slices.DeleteFunc(slices.Clone(required), func(rf feature.Required) bool {
+		return rf.Feature == FeaturePrefixRetention
+	})
+
+	if len(r.PrefixRetention) > 0 {
+		result = append(result, feature.Required{
+			Feature: FeaturePrefixRetention,
+			IfNotUnderstood: feature.IfNotUnderstood{
+				Message: "The repository uses per-prefix blob retention overrides.",
+			},
+		})
+	}
+
+	return result
 }
 
 // IsRetentionEnabled returns true if retention is enabled on the blob-config
@@ -34,10 +200,28 @@ func (r *BlobStorageConfiguration) Validate() error {
 		return errors.Errorf("both retention mode and period must be provided when setting blob retention properties")
 	}
 
-	if r.RetentionPeriod != 0 && r.RetentionPeriod < 24*time.Hour {
+	if r.RetentionPeriod != 0 && r.RetentionPeriod < minRetentionPeriod {
 		return errors.Errorf("invalid retention-period, the minimum required is 1-day and there is no maximum limit")
 	}
 
+	if len(r.PrefixRetention) > 0 && !r.IsRetentionEnabled() {
+		return errors.Errorf("prefix retention requires retention mode and period to be set")
+	}
+
+	seen := map[blob.ID]bool{}
+
+	for _, pr := range r.PrefixRetention {
+		if err := pr.validate(); err != nil {
+			return err
+		}
+
+		if seen[pr.Prefix] {
+			return errors.Errorf("duplicate prefix retention for %q", pr.Prefix)
+		}
+
+		seen[pr.Prefix] = true
+	}
+
 	return nil
 }
 
@@ -99,9 +283,11 @@ func (f *KopiaRepositoryJSON) WriteBlobCfgBlob(ctx context.Context, st blob.Stor
 		return errors.Wrap(err, "unable to encrypt blobcfg bytes")
 	}
 
+	mode, period := blobcfg.RetentionForBlob(KopiaBlobCfgBlobID)
+
 	if err := st.PutBlob(ctx, KopiaBlobCfgBlobID, gather.FromSlice(blobCfgBytes), blob.PutOptions{
-		RetentionMode:   blobcfg.RetentionMode,
-		RetentionPeriod: blobcfg.RetentionPeriod,
+		RetentionMode:   mode,
+		RetentionPeriod: period,
 	}); err != nil {
 		return errors.Wrapf(err, "PutBlob() failed for %q", KopiaBlobCfgBlobID)
 	}
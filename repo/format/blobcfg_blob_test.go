@@ -0,0 +1,114 @@
+package format
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/kopia/kopia/internal/feature"
+	"github.com/kopia/kopia/repo/blob"
+)
+
+func TestParsePrefixRetention(t *testing.T) {
+	cases := []struct {
+		input   string
+		want    PrefixRetention
+		wantErr string
+	}{
+		{input: "p=720h", want: PrefixRetention{Prefix: "p", RetentionPeriod: 720 * time.Hour}},
+		{input: "kopia.=COMPLIANCE:365d", want: PrefixRetention{Prefix: "kopia.", RetentionMode: blob.Compliance, RetentionPeriod: 365 * 24 * time.Hour}},
+		{input: "q=GOVERNANCE:", want: PrefixRetention{Prefix: "q", RetentionMode: blob.Governance}},
+		{input: "p", wantErr: "must be PREFIX=[MODE:]PERIOD"},
+		{input: "=720h", wantErr: "must be PREFIX=[MODE:]PERIOD"},
+		{input: "p=", wantErr: "must be PREFIX=[MODE:]PERIOD"},
+		{input: "p=xyz", wantErr: "invalid retention period"},
+		{input: "p=12h", wantErr: "the minimum required is 1-day"},
+		{input: "p=BAD:720h", wantErr: "invalid retention mode"},
+		{input: "p=:", wantErr: "must override retention mode or period"},
+	}
+
+	for _, tc := range cases {
+		got, err := ParsePrefixRetention(tc.input)
+		if tc.wantErr != "" {
+			require.ErrorContains(t, err, tc.wantErr, tc.input)
+			continue
+		}
+
+		require.NoError(t, err, tc.input)
+		require.Equal(t, tc.want, got, tc.input)
+
+		// string representation round-trips.
+		got2, err := ParsePrefixRetention(got.String())
+		require.NoError(t, err)
+		require.Equal(t, got, got2)
+	}
+}
+
+func TestBlobStorageConfiguration_PrefixRetention(t *testing.T) {
+	r := BlobStorageConfiguration{
+		RetentionMode:   blob.Governance,
+		RetentionPeriod: 48 * time.Hour,
+	}
+
+	r.SetPrefixRetention(PrefixRetention{Prefix: "kopia.", RetentionMode: blob.Compliance, RetentionPeriod: 720 * time.Hour})
+	r.SetPrefixRetention(PrefixRetention{Prefix: "kopia.blob", RetentionPeriod: 1000 * time.Hour})
+	r.SetPrefixRetention(PrefixRetention{Prefix: "p", RetentionPeriod: 24 * time.Hour})
+	require.NoError(t, r.Validate())
+
+	cases := []struct {
+		id         blob.ID
+		wantMode   blob.RetentionMode
+		wantPeriod time.Duration
+	}{
+		{"xabcdef", blob.Governance, 48 * time.Hour},
+		{"pabcdef", blob.Governance, 24 * time.Hour},
+		{KopiaRepositoryBlobID, blob.Compliance, 720 * time.Hour},
+		// longest matching prefix wins and inherits the rest.
+		{KopiaBlobCfgBlobID, blob.Governance, 1000 * time.Hour},
+	}
+
+	for _, tc := range cases {
+		mode, period := r.RetentionForBlob(tc.id)
+		require.Equal(t, tc.wantMode, mode, tc.id)
+		require.Equal(t, tc.wantPeriod, period, tc.id)
+	}
+
+	require.Equal(t, 24*time.Hour, r.MinRetentionPeriod())
+
+	// replacing override for existing prefix.
+	r.SetPrefixRetention(PrefixRetention{Prefix: "p", RetentionPeriod: 96 * time.Hour})
+	require.Len(t, r.PrefixRetention, 3)
+	require.Equal(t, 48*time.Hour, r.MinRetentionPeriod())
+
+	r.RemovePrefixRetention("p")
+	require.Len(t, r.PrefixRetention, 2)
+
+	// duplicates are rejected.
+	r2 := r
+	r2.PrefixRetention = append(r2.PrefixRetention, r.PrefixRetention[0])
+	require.ErrorContains(t, r2.Validate(), "duplicate prefix retention")
+
+	// overrides require retention to be enabled.
+	r3 := BlobStorageConfiguration{PrefixRetention: r.PrefixRetention}
+	require.ErrorContains(t, r3.Validate(), "requires retention mode and period")
+}
+
+func TestBlobStorageConfiguration_UpdateRequiredFeatures(t *testing.T) {
+	other := feature.Required{Feature: "other"}
+
+	r := BlobStorageConfiguration{RetentionMode: blob.Governance, RetentionPeriod: 48 * time.Hour}
+	require.Equal(t, []feature.Required{other}, r.UpdateRequiredFeatures([]feature.Required{other}))
+
+	r.SetPrefixRetention(PrefixRetention{Prefix: "p", RetentionPeriod: 96 * time.Hour})
+
+	withOverrides := r.UpdateRequiredFeatures([]feature.Required{other})
+	require.Len(t, withOverrides, 2)
+	require.Equal(t, FeaturePrefixRetention, withOverrides[1].Feature)
+
+	// the feature is not duplicated and gets removed along with the overrides.
+	require.Equal(t, withOverrides, r.UpdateRequiredFeatures(withOverrides))
+
+	r.RemovePrefixRetention("p")
+	require.Equal(t, []feature.Required{other}, r.UpdateRequiredFeatures(withOverrides))
+}
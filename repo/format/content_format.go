@@ -67,6 +67,9 @@ type MutableParameters struct {
 	MaxPackSize     int              `json:"maxPackSize,omitempty"`     // maximum size of a pack object
 	IndexVersion    int              `json:"indexVersion,omitempty"`    // force particular index format version (1,2,..)
 	EpochParameters epoch.Parameters `json:"epochParameters,omitempty"` // epoch manager parameters
+
+	IndexCompaction *IndexCompactionParameters `json:"indexCompaction,omitempty"` // thresholds for compaction of small index blobs
+	StorageQuota    *StorageQuotaParameters    `json:"storageQuota,omitempty"`    // maximum size of the storage
 }
 
 // Validate validates the parameters.
@@ -87,6 +90,14 @@ func (v *MutableParameters) Validate() error {
 		return errors.Wrap(err, "invalid epoch parameters")
 	}
 
+	if err := v.IndexCompaction.Validate(); err != nil {
+		return errors.Wrap(err, "invalid index compaction parameters")
+	}
+
+	if err := v.StorageQuota.Validate(); err != nil {
+		return errors.Wrap(err, "invalid storage quota")
+	}
+
 	return nil
 }
 
@@ -185,9 +185,11 @@ func (f *KopiaRepositoryJSON) WriteKopiaRepositoryBlobWithID(ctx context.Context
 		return errors.Wrap(err, "unable to marshal format blob")
 	}
 
+	mode, period := blobCfg.RetentionForBlob(id)
+
 	if err := st.PutBlob(ctx, id, buf.Bytes(), blob.PutOptions{
-		RetentionMode:   blobCfg.RetentionMode,
-		RetentionPeriod: blobCfg.RetentionPeriod,
+		RetentionMode:   mode,
+		RetentionPeriod: period,
 	}); err != nil {
 		return errors.Wrapf(err, "unable to write format blob %q", id)
 	}
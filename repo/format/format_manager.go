@@ -54,6 +54,11 @@ type Manager struct {
 	refreshCounter int
 	// +checklocks:mu
 	ignoreCacheOnFirstRefresh bool
+
+	// +checklocks:mu
+	versionsUnsupported bool
+	// +checklocks:mu
+	revalidatedCounter int
 }
 
 func (m *Manager) getOrRefreshFormat(ctx context.Context) (Provider, error) {
@@ -77,23 +82,61 @@ func (m *Manager) maybeRefreshNotLocked(ctx context.Context) error {
 	}
 
 	// current format not valid anymore, kick off a refresh
-	return m.refresh(ctx)
+	return m.refresh(ctx, false)
+}
+
+// Refresh revalidates the format blobs with the storage regardless of whether their cached copies
+// are still valid. When the storage reports blob versions and they are unchanged, the blobs are not
+// downloaded again.
+func (m *Manager) Refresh(ctx context.Context) error {
+	return m.refresh(ctx, true)
 }
 
 // readAndCacheRepositoryBlobBytes reads the provided blob from the repository or cache directory.
+//
+// When the cached copy has expired, it's revalidated by comparing the version of the blob reported by
+// the storage with the one recorded in the cache, which only requires fetching blob metadata.
+// When the blob is not cached, it's read directly, without fetching its metadata first.
+//
 // +checklocks:m.mu
-func (m *Manager) readAndCacheRepositoryBlobBytes(ctx context.Context, blobID blob.ID) ([]byte, time.Time, error) {
-	if !m.ignoreCacheOnFirstRefresh {
-		if data, mtime, ok := m.cache.Get(ctx, blobID); ok {
-			// read from cache and still valid
-			age := m.timeNow().Sub(mtime)
-
-			if age < m.validDuration {
-				return data, mtime, nil
-			}
-		}
+func (m *Manager) readAndCacheRepositoryBlobBytes(ctx context.Context, blobID blob.ID, forceRevalidate bool) ([]byte, time.Time, error) {
+	if m.ignoreCacheOnFirstRefresh {
+		return m.readRepositoryBlobBytes(ctx, blobID, "")
 	}
 
+	data, mtime, ok := m.cache.Get(ctx, blobID)
+	if !ok {
+		return m.readRepositoryBlobBytes(ctx, blobID, "")
+	}
+
+	// read from cache and still valid
+	if age := m.timeNow().Sub(mtime); age < m.validDuration && !forceRevalidate {
+		return data, mtime, nil
+	}
+
+	version := m.currentBlobVersion(ctx, blobID)
+	if version == "" {
+		return m.readRepositoryBlobBytes(ctx, blobID, "")
+	}
+
+	if cached, _, ok := m.cache.Get(ctx, blobVersionCacheID(blobID)); ok && string(cached) == version {
+		m.revalidatedCounter++
+
+		mtime, err := m.cache.Put(ctx, blobID, data)
+
+		return data, mtime, errors.Wrapf(err, "error adding %s blob", blobID)
+	}
+
+	// the version was fetched before the contents, so if the blob is concurrently modified we record
+	// an older version and the next revalidation will fail.
+	return m.readRepositoryBlobBytes(ctx, blobID, version)
+}
+
+// readRepositoryBlobBytes reads the provided blob from the repository and stores it in the cache along with
+// the provided version, if known.
+//
+// +checklocks:m.mu
+func (m *Manager) readRepositoryBlobBytes(ctx context.Context, blobID blob.ID, version string) ([]byte, time.Time, error) {
 	var b gather.WriteBuffer
 	defer b.Close()
 
@@ -104,8 +147,45 @@ func (m *Manager) readAndCacheRepositoryBlobBytes(ctx context.Context, blobID bl
 	data := b.ToByteSlice()
 
 	mtime, err := m.cache.Put(ctx, blobID, data)
+	if err != nil {
+		return data, mtime, errors.Wrapf(err, "error adding %s blob", blobID)
+	}
 
-	return data, mtime, errors.Wrapf(err, "error adding %s blob", blobID)
+	if version == "" {
+		// the version of the cached blob is not known anymore.
+		if _, _, ok := m.cache.Get(ctx, blobVersionCacheID(blobID)); ok {
+			m.cache.Remove(ctx, []blob.ID{blobVersionCacheID(blobID)})
+		}
+	} else if _, err := m.cache.Put(ctx, blobVersionCacheID(blobID), []byte(version)); err != nil {
+		log(ctx).Debugf("unable to cache version of %v: %v", blobID, err)
+	}
+
+	return data, mtime, nil
+}
+
+// blobVersionCacheID returns the ID under which the version of the provided blob is cached.
+func blobVersionCacheID(blobID blob.ID) blob.ID {
+	return blobID + ".version"
+}
+
+// currentBlobVersion returns the version of the blob reported by the storage or an empty string if unknown.
+// +checklocks:m.mu
+func (m *Manager) currentBlobVersion(ctx context.Context, blobID blob.ID) string {
+	if m.versionsUnsupported {
+		return ""
+	}
+
+	bm, err := m.blobs.GetMetadata(ctx, blobID)
+	if err != nil {
+		return ""
+	}
+
+	if bm.Version == "" {
+		// storage does not report versions, don't bother asking again.
+		m.versionsUnsupported = true
+	}
+
+	return bm.Version
 }
 
 // ValidCacheDuration returns the duration for which each blob in the cache is valid.
@@ -121,12 +201,21 @@ func (m *Manager) RefreshCount() int {
 	return m.refreshCounter
 }
 
+// RevalidatedCount returns the number of times a cached format blob was found to be unchanged
+// in the storage and was not downloaded again.
+func (m *Manager) RevalidatedCount() int {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	return m.revalidatedCounter
+}
+
 // refresh reads `kopia.repository` blob, potentially from cache and decodes it.
-func (m *Manager) refresh(ctx context.Context) error {
+func (m *Manager) refresh(ctx context.Context, forceRevalidate bool) error {
 	m.mu.Lock()
 	defer m.mu.Unlock()
 
-	b, cacheMTime, err := m.readAndCacheRepositoryBlobBytes(ctx, KopiaRepositoryBlobID)
+	b, cacheMTime, err := m.readAndCacheRepositoryBlobBytes(ctx, KopiaRepositoryBlobID, forceRevalidate)
 	if err != nil {
 		return errors.Wrap(err, "unable to read format blob")
 	}
@@ -157,7 +246,7 @@ func (m *Manager) refresh(ctx context.Context) error {
 
 	var blobCfg BlobStorageConfiguration
 
-	if b2, _, err2 := m.readAndCacheRepositoryBlobBytes(ctx, KopiaBlobCfgBlobID); err2 == nil {
+	if b2, _, err2 := m.readAndCacheRepositoryBlobBytes(ctx, KopiaBlobCfgBlobID, forceRevalidate); err2 == nil {
 		var e2 error
 
 		blobCfg, e2 = deserializeBlobCfgBytes(j, b2, formatEncryptionKey)
@@ -414,7 +503,7 @@ func NewManagerWithCache(
 		ignoreCacheOnFirstRefresh: ignoreCacheOnFirstRefresh,
 	}
 
-	err := m.refresh(ctx)
+	err := m.refresh(ctx, false)
 
 	return m, err
 }
@@ -2,6 +2,10 @@
 
 import (
 	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"sync/atomic"
 	"testing"
 	"time"
 
@@ -399,6 +403,113 @@ func TestFormatManagerValidDuration(t *testing.T) {
 	}
 }
 
+func TestFormatManagerRevalidation(t *testing.T) {
+	ctx := testlogging.Context(t)
+
+	startTime := time.Date(2020, 1, 1, 12, 0, 0, 0, time.UTC)
+	ta := faketime.NewTimeAdvance(startTime)
+	nowFunc := ta.NowFunc()
+
+	st := &versionedStorage{Storage: blobtesting.NewMapStorage(blobtesting.DataMap{}, nil, nil)}
+	require.NoError(t, format.Initialize(ctx, st, &format.KopiaRepositoryJSON{}, rc, format.BlobStorageConfiguration{}, "some-password"))
+
+	cache := format.NewMemoryBlobCache(nowFunc)
+
+	mgr, err := format.NewManagerWithCache(ctx, st, cacheDuration, "some-password", nowFunc, cache)
+	require.NoError(t, err)
+
+	// blobs which are not cached are read without fetching their metadata.
+	mp0 := mustGetMutableParameters(t, mgr)
+	require.Zero(t, st.getMetadataCount.Load())
+
+	// after the first cache expiration, versions of blobs are fetched along with their contents.
+	ta.Advance(cacheDuration)
+
+	require.Equal(t, mp0, mustGetMutableParameters(t, mgr))
+	require.EqualValues(t, 2, st.getMetadataCount.Load())
+	require.Zero(t, mgr.RevalidatedCount())
+
+	// after subsequent cache expirations, unchanged blobs are revalidated without being downloaded.
+	getBlobCount := st.getBlobCount.Load()
+
+	ta.Advance(cacheDuration)
+
+	require.Equal(t, mp0, mustGetMutableParameters(t, mgr))
+	require.Equal(t, getBlobCount, st.getBlobCount.Load())
+	require.Equal(t, 3, mgr.RefreshCount())
+	require.Equal(t, 2, mgr.RevalidatedCount()) // kopia.repository and kopia.blobcfg
+	require.Equal(t, ta.NowFunc()(), mgr.LoadedTime())
+
+	// explicit refresh while the cache is still valid.
+	require.NoError(t, mgr.Refresh(ctx))
+	require.Equal(t, getBlobCount, st.getBlobCount.Load())
+	require.Equal(t, 4, mgr.RevalidatedCount())
+
+	// versions are persisted in the cache along with the blobs.
+	ta.Advance(cacheDuration)
+
+	mgr2, err := format.NewManagerWithCache(ctx, st, cacheDuration, "some-password", nowFunc, cache)
+	require.NoError(t, err)
+	require.Equal(t, getBlobCount, st.getBlobCount.Load())
+	require.Equal(t, 2, mgr2.RevalidatedCount())
+
+	// update parameters using another manager, explicit refresh notices the change immediately.
+	mgr3, err := format.NewManagerWithCache(ctx, st, cacheDuration, "some-password", nowFunc, format.NewMemoryBlobCache(nowFunc))
+	require.NoError(t, err)
+
+	mp := mustGetMutableParameters(t, mgr3)
+	mp.MaxPackSize++
+
+	require.NoError(t, mgr3.SetParameters(ctx, mp, mustGetBlobStorageConfiguration(t, mgr3), mustGetRequiredFeatures(t, mgr3)))
+
+	// mgr still trusts its cache.
+	require.Equal(t, mp0, mustGetMutableParameters(t, mgr))
+
+	getBlobCount = st.getBlobCount.Load()
+
+	require.NoError(t, mgr.Refresh(ctx))
+	require.Equal(t, mp, mustGetMutableParameters(t, mgr))
+	require.Greater(t, st.getBlobCount.Load(), getBlobCount)
+}
+
+// versionedStorage reports versions of blobs based on their contents and counts GetBlob() calls.
+type versionedStorage struct {
+	blob.Storage
+
+	getBlobCount     atomic.Int32
+	getMetadataCount atomic.Int32
+}
+
+func (s *versionedStorage) GetBlob(ctx context.Context, id blob.ID, offset, length int64, output blob.OutputBuffer) error {
+	s.getBlobCount.Add(1)
+
+	//nolint:wrapcheck
+	return s.Storage.GetBlob(ctx, id, offset, length, output)
+}
+
+func (s *versionedStorage) GetMetadata(ctx context.Context, id blob.ID) (blob.Metadata, error) {
+	s.getMetadataCount.Add(1)
+
+	bm, err := s.Storage.GetMetadata(ctx, id)
+	if err != nil {
+		//nolint:wrapcheck
+		return bm, err
+	}
+
+	var tmp gather.WriteBuffer
+	defer tmp.Close()
+
+	if err := s.Storage.GetBlob(ctx, id, 0, -1, &tmp); err != nil {
+		//nolint:wrapcheck
+		return bm, err
+	}
+
+	h := sha256.Sum256(tmp.ToByteSlice())
+	bm.Version = hex.EncodeToString(h[:])
+
+	return bm, nil
+}
+
 func mustGetMutableParameters(t *testing.T, mgr *format.Manager) format.MutableParameters {
 	t.Helper()
 
@@ -0,0 +1,74 @@
+package format
+
+import (
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// DefaultIndexCompactionMaxSmallBlobs is the default number of small index blobs above which they are compacted.
+const DefaultIndexCompactionMaxSmallBlobs = 8
+
+// by default index blobs less than 1/defaultSmallIndexBlobFraction of maxPackSize are considered small.
+const defaultSmallIndexBlobFraction = 20
+
+// IndexCompactionParameters controls when small index blobs are compacted together during maintenance.
+// Zero values cause defaults to be used.
+type IndexCompactionParameters struct {
+	// MaxSmallBlobs is the number of small index blobs above which they are compacted.
+	MaxSmallBlobs int `json:"maxSmallBlobs,omitempty"`
+
+	// SmallBlobSizeBytes is the size below which index blobs are considered small.
+	SmallBlobSizeBytes int64 `json:"smallBlobSizeBytes,omitempty"`
+
+	// MinBlobAge is the minimum age of an index blob before it is compacted.
+	MinBlobAge time.Duration `json:"minBlobAge,omitempty"`
+}
+
+// EffectiveMaxSmallBlobs returns the number of small index blobs above which they are compacted.
+func (p *IndexCompactionParameters) EffectiveMaxSmallBlobs() int {
+	if p == nil || p.MaxSmallBlobs == 0 {
+		return DefaultIndexCompactionMaxSmallBlobs
+	}
+
+	return p.MaxSmallBlobs
+}
+
+// EffectiveSmallBlobSizeBytes returns the size below which index blobs are considered small.
+func (p *IndexCompactionParameters) EffectiveSmallBlobSizeBytes(maxPackSize int) int64 {
+	if p == nil || p.SmallBlobSizeBytes == 0 {
+		return int64(maxPackSize) / defaultSmallIndexBlobFraction
+	}
+
+	return p.SmallBlobSizeBytes
+}
+
+// EffectiveMinBlobAge returns the minimum age of an index blob before it is compacted.
+func (p *IndexCompactionParameters) EffectiveMinBlobAge() time.Duration {
+	if p == nil {
+		return 0
+	}
+
+	return p.MinBlobAge
+}
+
+// Validate validates the parameters.
+func (p *IndexCompactionParameters) Validate() error {
+	if p == nil {
+		return nil
+	}
+
+	if p.MaxSmallBlobs < 0 {
+		return errors.New("max small blobs must not be negative")
+	}
+
+	if p.SmallBlobSizeBytes < 0 {
+		return errors.New("small blob size must not be negative")
+	}
+
+	if p.MinBlobAge < 0 {
+		return errors.New("min blob age must not be negative")
+	}
+
+	return nil
+}
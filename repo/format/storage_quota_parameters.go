@@ -0,0 +1,32 @@
+package format
+
+import (
+	"github.com/pkg/errors"
+)
+
+// StorageQuotaParameters limits the size of the storage, which is enforced by clients on writes of pack blobs.
+type StorageQuotaParameters struct {
+	// MaxBytes is the maximum total size of all blobs in the storage, 0 for unlimited.
+	MaxBytes int64 `json:"maxBytes,omitempty"`
+
+	// WarnOnly causes clients to only log a warning when the quota is exceeded, instead of failing writes.
+	WarnOnly bool `json:"warnOnly,omitempty"`
+}
+
+// IsEnabled returns true if the quota is set.
+func (p *StorageQuotaParameters) IsEnabled() bool {
+	return p != nil && p.MaxBytes > 0
+}
+
+// Validate validates the parameters.
+func (p *StorageQuotaParameters) Validate() error {
+	if p == nil {
+		return nil
+	}
+
+	if p.MaxBytes < 0 {
+		return errors.New("storage quota must not be negative")
+	}
+
+	return nil
+}
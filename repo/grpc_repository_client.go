@@ -477,6 +477,12 @@ func (r *grpcRepositoryClient) Refresh(ctx context.Context) error {
 	return nil
 }
 
+// RefreshFormat does nothing, since the format blobs are only read by the server, which refreshes
+// them on its own, and the client only uses the immutable parameters received when initializing the session.
+func (r *grpcRepositoryClient) RefreshFormat(ctx context.Context) error {
+	return nil
+}
+
 func (r *grpcRepositoryClient) Flush(ctx context.Context) error {
 	if err := r.asyncWritesWG.Wait(); err != nil {
 		return errors.Wrap(err, "error waiting for async writes")
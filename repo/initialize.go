@@ -35,13 +35,14 @@
 // NewRepositoryOptions specifies options that apply to newly created repositories.
 // All fields are optional, when not provided, reasonable defaults will be used.
 type NewRepositoryOptions struct {
-	UniqueID                          []byte               `json:"uniqueID"` // force the use of particular unique ID
-	BlockFormat                       format.ContentFormat `json:"blockFormat"`
-	DisableHMAC                       bool                 `json:"disableHMAC"`
-	ObjectFormat                      format.ObjectFormat  `json:"objectFormat"` // object format
-	RetentionMode                     blob.RetentionMode   `json:"retentionMode,omitempty"`
-	RetentionPeriod                   time.Duration        `json:"retentionPeriod,omitempty"`
-	FormatBlockKeyDerivationAlgorithm string               `json:"formatBlockKeyDerivationAlgorithm,omitempty"`
+	UniqueID                          []byte                   `json:"uniqueID"` // force the use of particular unique ID
+	BlockFormat                       format.ContentFormat     `json:"blockFormat"`
+	DisableHMAC                       bool                     `json:"disableHMAC"`
+	ObjectFormat                      format.ObjectFormat      `json:"objectFormat"` // object format
+	RetentionMode                     blob.RetentionMode       `json:"retentionMode,omitempty"`
+	RetentionPeriod                   time.Duration            `json:"retentionPeriod,omitempty"`
+	PrefixRetention                   []format.PrefixRetention `json:"prefixRetention,omitempty"`
+	FormatBlockKeyDerivationAlgorithm string                   `json:"formatBlockKeyDerivationAlgorithm,omitempty"`
 }
 
 // Initialize creates initial repository data structures in the specified storage with given credentials.
@@ -58,6 +59,12 @@ func Initialize(ctx context.Context, st blob.Storage, opt *NewRepositoryOptions,
 		return errors.Wrap(err, "invalid parameters")
 	}
 
+	if err := ValidatePrefixRetention(blobcfg.PrefixRetention); err != nil {
+		return errors.Wrap(err, "invalid parameters")
+	}
+
+	repoConfig.RequiredFeatures = blobcfg.UpdateRequiredFeatures(repoConfig.RequiredFeatures)
+
 	//nolint:wrapcheck
 	return format.Initialize(ctx, st, formatBlob, repoConfig, blobcfg, password)
 }
@@ -77,6 +84,7 @@ func blobCfgBlobFromOptions(opt *NewRepositoryOptions) format.BlobStorageConfigu
 	return format.BlobStorageConfiguration{
 		RetentionMode:   opt.RetentionMode,
 		RetentionPeriod: opt.RetentionPeriod,
+		PrefixRetention: opt.PrefixRetention,
 	}
 }
 
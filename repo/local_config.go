@@ -31,6 +31,10 @@ type ClientOptions struct {
 	ReadOnly               bool `json:"readonly,omitempty"`
 	PermissiveCacheLoading bool `json:"permissiveCacheLoading,omitempty"`
 
+	// WritablePrefixes are prefixes of blobs which can still be written when ReadOnly is set,
+	// such as logs.
+	WritablePrefixes []string `json:"writablePrefixes,omitempty"`
+
 	// Description is human-readable description of the repository to use in the UI.
 	Description string `json:"description,omitempty"`
 
@@ -1,6 +1,10 @@
 package repo
 
 import (
+	"strings"
+
+	"github.com/pkg/errors"
+
 	"github.com/kopia/kopia/internal/epoch"
 	"github.com/kopia/kopia/repo/content"
 	"github.com/kopia/kopia/repo/content/indexblob"
@@ -20,3 +24,26 @@ func GetLockingStoragePrefixes() []string {
 
 	return prefixes
 }
+
+// ValidatePrefixRetention ensures that all prefix retention overrides apply to blobs maintained by Object Locking.
+func ValidatePrefixRetention(prs []format.PrefixRetention) error {
+	for _, pr := range prs {
+		if !isLockingStoragePrefix(string(pr.Prefix)) {
+			return errors.Errorf("invalid prefix retention for %q, the prefix does not match any blobs protected by retention (%v)",
+				pr.Prefix, strings.Join(GetLockingStoragePrefixes(), ", "))
+		}
+	}
+
+	return nil
+}
+
+// isLockingStoragePrefix returns true if the provided prefix overlaps with any of the locking storage prefixes.
+func isLockingStoragePrefix(prefix string) bool {
+	for _, p := range GetLockingStoragePrefixes() {
+		if strings.HasPrefix(prefix, p) || strings.HasPrefix(p, prefix) {
+			return true
+		}
+	}
+
+	return false
+}
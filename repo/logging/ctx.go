@@ -7,7 +7,10 @@
 
 type contextKey string
 
-const loggerCacheKey contextKey = "logger"
+const (
+	loggerCacheKey contextKey = "logger"
+	spanIDsKey     contextKey = "span-ids"
+)
 
 type loggerCache struct {
 	createLoggerForModule LoggerFactory
@@ -5,6 +5,7 @@
 	"context"
 	"io"
 
+	"go.opentelemetry.io/otel/trace"
 	"go.uber.org/zap"
 	"go.uber.org/zap/zapcore"
 
@@ -21,7 +22,7 @@
 func Module(module string) func(ctx context.Context) Logger {
 	return func(ctx context.Context) Logger {
 		if l := ctx.Value(loggerCacheKey); l != nil {
-			return l.(*loggerCache).getLogger(module) //nolint:forcetypeassert
+			return withSpanID(ctx, l.(*loggerCache).getLogger(module)) //nolint:forcetypeassert
 		}
 
 		return NullLogger
@@ -34,3 +35,36 @@ func ToWriter(w io.Writer) LoggerFactory {
 		zaplogutil.NewStdConsoleEncoder(zaplogutil.StdConsoleEncoderConfig{}),
 		zapcore.AddSync(w), zap.DebugLevel), zap.WithClock(zaplogutil.Clock())).Sugar().Named
 }
+
+// ToJSONWriter returns LoggerFactory that uses given writer for log output, one JSON object per entry.
+func ToJSONWriter(w io.Writer) LoggerFactory {
+	return zap.New(zapcore.NewCore(
+		zapcore.NewJSONEncoder(zapcore.EncoderConfig{
+			LevelKey:       "l",
+			MessageKey:     "m",
+			NameKey:        "n",
+			LineEnding:     zapcore.DefaultLineEnding,
+			EncodeLevel:    zapcore.CapitalLevelEncoder,
+			EncodeName:     zapcore.FullNameEncoder,
+			EncodeDuration: zapcore.StringDurationEncoder,
+		}),
+		zapcore.AddSync(w), zap.DebugLevel), zap.WithClock(zaplogutil.Clock())).Sugar().Named
+}
+
+// WithSpanIDs returns a derived context in which loggers include the ID of the current trace span, if any.
+func WithSpanIDs(ctx context.Context) context.Context {
+	return context.WithValue(ctx, spanIDsKey, true)
+}
+
+func withSpanID(ctx context.Context, l Logger) Logger {
+	if ctx.Value(spanIDsKey) == nil {
+		return l
+	}
+
+	sc := trace.SpanContextFromContext(ctx)
+	if !sc.HasSpanID() {
+		return l
+	}
+
+	return l.With("span_id", sc.SpanID().String())
+}
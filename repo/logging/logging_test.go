@@ -7,6 +7,7 @@
 	"testing"
 
 	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel/trace"
 
 	"github.com/kopia/kopia/internal/testlogging"
 	"github.com/kopia/kopia/repo/logging"
@@ -109,3 +110,22 @@ func BenchmarkLogger(b *testing.B) {
 		mod1(ctx)
 	}
 }
+
+func TestSpanIDs(t *testing.T) {
+	var buf bytes.Buffer
+
+	ctx := logging.WithLogger(context.Background(), logging.ToWriter(&buf))
+	log := logging.Module("module1")
+
+	spanCtx := trace.ContextWithSpanContext(ctx, trace.NewSpanContext(trace.SpanContextConfig{
+		TraceID: trace.TraceID{1},
+		SpanID:  trace.SpanID{1, 2, 3, 4, 5, 6, 7, 8},
+	}))
+
+	// span IDs are only included when enabled.
+	log(spanCtx).Info("A")
+	log(logging.WithSpanIDs(ctx)).Info("B")
+	log(logging.WithSpanIDs(spanCtx)).Info("C")
+
+	require.Equal(t, "A\nB\nC\t{\"span_id\":\"0102030405060708\"}\n", buf.String())
+}
@@ -99,11 +99,9 @@ func DeleteUnreferencedBlobs(ctx context.Context, rep repo.DirectRepositoryWrite
 		}
 
 		sid := content.SessionIDFromBlobID(bm.BlobID)
-		if s, ok := activeSessions[sid]; ok {
-			if age := cutoffTime.Sub(s.CheckpointTime); age < safety.SessionExpirationAge {
-				log(ctx).Debugf("  preserving %v because it's part of an active session (%v)", bm.BlobID, sid)
-				return nil
-			}
+		if s, ok := activeSessions[sid]; ok && !IsSessionExpired(s, cutoffTime, safety) {
+			log(ctx).Debugf("  preserving %v because it's part of an active session (%v)", bm.BlobID, sid)
+			return nil
 		}
 
 		unreferenced.Add(bm.Length)
@@ -137,3 +135,9 @@ func DeleteUnreferencedBlobs(ctx context.Context, rep repo.DirectRepositoryWrite
 
 	return int(del), nil
 }
+
+// IsSessionExpired returns true if the write session has not been checkpointed for long enough
+// that its blobs are subject to garbage collection with the provided safety parameters.
+func IsSessionExpired(s *content.SessionInfo, now time.Time, safety SafetyParameters) bool {
+	return now.Sub(s.CheckpointTime) >= safety.SessionExpirationAge
+}
@@ -52,10 +52,6 @@ func ExtendBlobRetentionTime(ctx context.Context, rep repo.DirectRepositoryWrite
 	}
 
 	extend := make(chan blob.Metadata, extendQueueSize)
-	extendOpts := blob.ExtendOptions{
-		RetentionMode:   blobCfg.RetentionMode,
-		RetentionPeriod: blobCfg.RetentionPeriod,
-	}
 
 	if !opt.DryRun {
 		// start goroutines to extend blob retention as they come.
@@ -66,6 +62,10 @@ func ExtendBlobRetentionTime(ctx context.Context, rep repo.DirectRepositoryWrite
 				defer wg.Done()
 
 				for bm := range extend {
+					var extendOpts blob.ExtendOptions
+
+					extendOpts.RetentionMode, extendOpts.RetentionPeriod = blobCfg.RetentionForBlob(bm.BlobID)
+
 					if err1 := rep.BlobStorage().ExtendBlobRetention(ctx, bm.BlobID, extendOpts); err1 != nil {
 						log(ctx).Errorf("Failed to extend blob %v: %v", bm.BlobID, err1)
 						atomic.AddUint32(failedCnt, 1)
@@ -133,8 +133,8 @@ func CheckExtendRetention(ctx context.Context, blobCfg format.BlobStorageConfigu
 		log(ctx).Warn("Object Lock extension will not function because Full-Maintenance is disabled")
 	}
 
-	if blobCfg.RetentionPeriod > 0 && blobCfg.RetentionPeriod-p.FullCycle.Interval < minRetentionMaintenanceDiff {
-		return errors.Errorf("The repo RetentionPeriod must be %v greater than the Full Maintenance interval %v %v", minRetentionMaintenanceDiff, blobCfg.RetentionPeriod, p.FullCycle.Interval)
+	if minPeriod := blobCfg.MinRetentionPeriod(); minPeriod > 0 && minPeriod-p.FullCycle.Interval < minRetentionMaintenanceDiff {
+		return errors.Errorf("The repo RetentionPeriod must be %v greater than the Full Maintenance interval %v %v", minRetentionMaintenanceDiff, minPeriod, p.FullCycle.Interval)
 	}
 
 	return nil
@@ -15,6 +15,7 @@
 	"github.com/kopia/kopia/repo"
 	"github.com/kopia/kopia/repo/blob"
 	"github.com/kopia/kopia/repo/encryption"
+	"github.com/kopia/kopia/repo/format"
 	"github.com/kopia/kopia/repo/maintenance"
 	"github.com/kopia/kopia/repo/object"
 )
@@ -82,6 +83,64 @@ func (s *formatSpecificTestSuite) TestExtendBlobRetentionTime(t *testing.T) {
 	assert.WithinDuration(t, earliestExpiry, expiry, time.Minute)
 }
 
+func (s *formatSpecificTestSuite) TestExtendBlobRetentionTimePrefixRetention(t *testing.T) {
+	mode := blob.Governance
+	period := time.Hour * 24
+	formatPeriod := time.Hour * 72
+
+	ta := faketime.NewClockTimeWithOffset(0)
+
+	ctx, env := repotesting.NewEnvironment(t, s.formatVersion, repotesting.Options{
+		OpenOptions: func(o *repo.Options) {
+			o.TimeNowFunc = ta.NowFunc()
+		},
+		NewRepositoryOptions: func(nro *repo.NewRepositoryOptions) {
+			nro.BlockFormat.Encryption = encryption.DefaultAlgorithm
+			nro.BlockFormat.MasterKey = testMasterKey
+			nro.BlockFormat.Hash = blockFormatHash
+			nro.BlockFormat.HMACSecret = testHMACSecret
+			nro.RetentionMode = mode
+			nro.RetentionPeriod = period
+			nro.PrefixRetention = []format.PrefixRetention{
+				{Prefix: "kopia.", RetentionMode: blob.Compliance, RetentionPeriod: formatPeriod},
+			}
+		},
+	})
+	w := env.RepositoryWriter.NewObjectWriter(ctx, object.WriterOptions{})
+	io.WriteString(w, "hello world!")
+	w.Result()
+	w.Close()
+
+	env.RepositoryWriter.Flush(ctx)
+
+	st := env.RootStorage().(blobtesting.RetentionStorage)
+
+	verifyRetention := func(id blob.ID, wantMode blob.RetentionMode, wantPeriod time.Duration) {
+		t.Helper()
+
+		gotMode, expiry, err := st.GetRetention(ctx, id)
+		require.NoError(t, err, "getting blob retention info")
+
+		assert.Equal(t, wantMode, gotMode, id)
+		assert.WithinDuration(t, ta.NowFunc()().Add(wantPeriod), expiry, time.Minute, id)
+	}
+
+	packs, err := blob.ListAllBlobs(ctx, st, "p")
+	require.NoError(t, err)
+	require.NotEmpty(t, packs)
+
+	verifyRetention(format.KopiaRepositoryBlobID, blob.Compliance, formatPeriod)
+	verifyRetention(packs[0].BlobID, mode, period)
+
+	ta.Advance(7 * 24 * time.Hour)
+
+	_, err = maintenance.ExtendBlobRetentionTime(ctx, env.RepositoryWriter, maintenance.ExtendBlobRetentionTimeOptions{})
+	require.NoError(t, err)
+
+	verifyRetention(format.KopiaRepositoryBlobID, blob.Compliance, formatPeriod)
+	verifyRetention(packs[0].BlobID, mode, period)
+}
+
 func (s *formatSpecificTestSuite) TestExtendBlobRetentionTimeDisabled(t *testing.T) {
 	// set up fake clock which is initially synchronized to wall clock time
 	// and moved at the same speed but which can be moved forward.
@@ -3,6 +3,8 @@
 import (
 	"context"
 
+	"github.com/pkg/errors"
+
 	"github.com/kopia/kopia/repo/content/indexblob"
 )
 
@@ -11,11 +13,16 @@ func runTaskIndexCompactionQuick(ctx context.Context, runParams RunParameters, s
 	return ReportRun(ctx, runParams.rep, TaskIndexCompaction, s, func() error {
 		log(ctx).Info("Compacting indexes...")
 
-		const maxSmallBlobsForIndexCompaction = 8
+		mp, err := runParams.rep.FormatManager().GetMutableParameters(ctx)
+		if err != nil {
+			return errors.Wrap(err, "mutable parameters")
+		}
 
-		return runParams.rep.ContentManager().CompactIndexes(ctx, indexblob.CompactOptions{
-			MaxSmallBlobs:                    maxSmallBlobsForIndexCompaction,
-			DisableEventualConsistencySafety: safety.DisableEventualConsistencySafety,
+		return WithThrottling(ctx, runParams.Params.Throttling.IndexCompaction, func(ctx context.Context) error {
+			return runParams.rep.ContentManager().CompactIndexes(ctx, indexblob.CompactOptions{
+				MaxSmallBlobs:                    mp.IndexCompaction.EffectiveMaxSmallBlobs(),
+				DisableEventualConsistencySafety: safety.DisableEventualConsistencySafety,
+			})
 		})
 	})
 }
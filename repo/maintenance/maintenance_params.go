@@ -7,6 +7,7 @@
 	"github.com/pkg/errors"
 
 	"github.com/kopia/kopia/repo"
+	"github.com/kopia/kopia/repo/blob/throttling"
 	"github.com/kopia/kopia/repo/manifest"
 )
 
@@ -25,6 +26,8 @@ type Params struct {
 	LogRetention LogRetentionOptions `json:"logRetention"`
 
 	ExtendObjectLocks bool `json:"extendObjectLocks"`
+
+	Throttling ThrottlingParams `json:"throttling"`
 }
 
 // isOwnedByByThisUser determines whether current user is the maintenance owner.
@@ -56,6 +59,17 @@ func DefaultParams() Params {
 type CycleParams struct {
 	Enabled  bool          `json:"enabled"`
 	Interval time.Duration `json:"interval"`
+
+	// Windows restricts the times of day when the cycle is started automatically, any time is allowed when empty.
+	Windows []TimeWindow `json:"windows,omitempty"`
+}
+
+// ThrottlingParams specifies storage throttling limits applied during individual maintenance phases in addition
+// to repository throttling limits, so that maintenance does not saturate bandwidth shared with other traffic.
+type ThrottlingParams struct {
+	ContentRewrite  throttling.Limits `json:"contentRewrite"`
+	BlobDeletion    throttling.Limits `json:"blobDeletion"`
+	IndexCompaction throttling.Limits `json:"indexCompaction"`
 }
 
 // HasParams determines whether repository-wide maintenance parameters have been set.
@@ -67,24 +67,34 @@ func shouldRun(ctx context.Context, rep repo.DirectRepository, p *Params) (Mode,
 
 	// check full cycle first, as it does more than the quick cycle
 	if p.FullCycle.Enabled {
-		if !rep.Time().Before(s.NextFullMaintenanceTime) {
+		now := rep.Time()
+
+		switch {
+		case now.Before(s.NextFullMaintenanceTime):
+			log(ctx).Debugf("not due for full maintenance cycle until %v", s.NextFullMaintenanceTime)
+		case p.FullCycle.IsAllowedAt(now):
 			log(ctx).Debug("due for full maintenance cycle")
 			return ModeFull, nil
+		default:
+			log(ctx).Debugf("deferring full maintenance cycle until the next allowed time window at %v", p.FullCycle.NextAllowedTime(now))
 		}
-
-		log(ctx).Debugf("not due for full maintenance cycle until %v", s.NextFullMaintenanceTime)
 	} else {
 		log(ctx).Debug("full maintenance cycle not enabled")
 	}
 
 	// no time for full cycle, check quick cycle
 	if p.QuickCycle.Enabled {
-		if !rep.Time().Before(s.NextQuickMaintenanceTime) {
+		now := rep.Time()
+
+		switch {
+		case now.Before(s.NextQuickMaintenanceTime):
+			log(ctx).Debugf("not due for quick maintenance cycle until %v", s.NextQuickMaintenanceTime)
+		case p.QuickCycle.IsAllowedAt(now):
 			log(ctx).Debug("due for quick maintenance cycle")
 			return ModeQuick, nil
+		default:
+			log(ctx).Debugf("deferring quick maintenance cycle until the next allowed time window at %v", p.QuickCycle.NextAllowedTime(now))
 		}
-
-		log(ctx).Debugf("not due for quick maintenance cycle until %v", s.NextQuickMaintenanceTime)
 	} else {
 		log(ctx).Debug("quick maintenance cycle not enabled")
 	}
@@ -355,7 +365,10 @@ func runTaskEpochMaintenanceQuick(ctx context.Context, runParams RunParameters,
 
 	err := ReportRun(ctx, runParams.rep, TaskEpochCompactSingle, s, func() error {
 		log(ctx).Info("Compacting an eligible uncompacted epoch...")
-		return errors.Wrap(em.MaybeCompactSingleEpoch(ctx), "error compacting single epoch")
+
+		return WithThrottling(ctx, runParams.Params.Throttling.IndexCompaction, func(ctx context.Context) error {
+			return errors.Wrap(em.MaybeCompactSingleEpoch(ctx), "error compacting single epoch")
+		})
 	})
 	if err != nil {
 		return err
@@ -377,7 +390,10 @@ func runTaskEpochMaintenanceFull(ctx context.Context, runParams RunParameters, s
 	// compact a single epoch
 	if err := ReportRun(ctx, runParams.rep, TaskEpochCompactSingle, s, func() error {
 		log(ctx).Info("Compacting an eligible uncompacted epoch...")
-		return errors.Wrap(em.MaybeCompactSingleEpoch(ctx), "error compacting single epoch")
+
+		return WithThrottling(ctx, runParams.Params.Throttling.IndexCompaction, func(ctx context.Context) error {
+			return errors.Wrap(em.MaybeCompactSingleEpoch(ctx), "error compacting single epoch")
+		})
 	}); err != nil {
 		return err
 	}
@@ -390,7 +406,9 @@ func runTaskEpochMaintenanceFull(ctx context.Context, runParams RunParameters, s
 	if err := ReportRun(ctx, runParams.rep, TaskEpochGenerateRange, s, func() error {
 		log(ctx).Info("Attempting to compact a range of epoch indexes ...")
 
-		return errors.Wrap(em.MaybeGenerateRangeCheckpoint(ctx), "error creating epoch range indexes")
+		return WithThrottling(ctx, runParams.Params.Throttling.IndexCompaction, func(ctx context.Context) error {
+			return errors.Wrap(em.MaybeGenerateRangeCheckpoint(ctx), "error creating epoch range indexes")
+		})
 	}); err != nil {
 		return err
 	}
@@ -434,41 +452,49 @@ func runTaskDropDeletedContentsFull(ctx context.Context, runParams RunParameters
 
 func runTaskRewriteContentsQuick(ctx context.Context, runParams RunParameters, s *Schedule, safety SafetyParameters) error {
 	return ReportRun(ctx, runParams.rep, TaskRewriteContentsQuick, s, func() error {
-		return RewriteContents(ctx, runParams.rep, &RewriteContentsOptions{
-			ContentIDRange: index.AllPrefixedIDs,
-			PackPrefix:     content.PackBlobIDPrefixSpecial,
-			ShortPacks:     true,
-		}, safety)
+		return WithThrottling(ctx, runParams.Params.Throttling.ContentRewrite, func(ctx context.Context) error {
+			return RewriteContents(ctx, runParams.rep, &RewriteContentsOptions{
+				ContentIDRange: index.AllPrefixedIDs,
+				PackPrefix:     content.PackBlobIDPrefixSpecial,
+				ShortPacks:     true,
+			}, safety)
+		})
 	})
 }
 
 func runTaskRewriteContentsFull(ctx context.Context, runParams RunParameters, s *Schedule, safety SafetyParameters) error {
 	return ReportRun(ctx, runParams.rep, TaskRewriteContentsFull, s, func() error {
-		return RewriteContents(ctx, runParams.rep, &RewriteContentsOptions{
-			ContentIDRange: index.AllIDs,
-			ShortPacks:     true,
-		}, safety)
+		return WithThrottling(ctx, runParams.Params.Throttling.ContentRewrite, func(ctx context.Context) error {
+			return RewriteContents(ctx, runParams.rep, &RewriteContentsOptions{
+				ContentIDRange: index.AllIDs,
+				ShortPacks:     true,
+			}, safety)
+		})
 	})
 }
 
 func runTaskDeleteOrphanedBlobsFull(ctx context.Context, runParams RunParameters, s *Schedule, safety SafetyParameters) error {
 	return ReportRun(ctx, runParams.rep, TaskDeleteOrphanedBlobsFull, s, func() error {
-		_, err := DeleteUnreferencedBlobs(ctx, runParams.rep, DeleteUnreferencedBlobsOptions{
-			NotAfterTime: runParams.MaintenanceStartTime,
-		}, safety)
+		return WithThrottling(ctx, runParams.Params.Throttling.BlobDeletion, func(ctx context.Context) error {
+			_, err := DeleteUnreferencedBlobs(ctx, runParams.rep, DeleteUnreferencedBlobsOptions{
+				NotAfterTime: runParams.MaintenanceStartTime,
+			}, safety)
 
-		return err
+			return err
+		})
 	})
 }
 
 func runTaskDeleteOrphanedBlobsQuick(ctx context.Context, runParams RunParameters, s *Schedule, safety SafetyParameters) error {
 	return ReportRun(ctx, runParams.rep, TaskDeleteOrphanedBlobsQuick, s, func() error {
-		_, err := DeleteUnreferencedBlobs(ctx, runParams.rep, DeleteUnreferencedBlobsOptions{
-			NotAfterTime: runParams.MaintenanceStartTime,
-			Prefix:       content.PackBlobIDPrefixSpecial,
-		}, safety)
-
-		return err
+		return WithThrottling(ctx, runParams.Params.Throttling.BlobDeletion, func(ctx context.Context) error {
+			_, err := DeleteUnreferencedBlobs(ctx, runParams.rep, DeleteUnreferencedBlobsOptions{
+				NotAfterTime: runParams.MaintenanceStartTime,
+				Prefix:       content.PackBlobIDPrefixSpecial,
+			}, safety)
+
+			return err
+		})
 	})
 }
 
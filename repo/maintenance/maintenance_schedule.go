@@ -97,14 +97,20 @@ func TimeToAttemptNextMaintenance(ctx context.Context, rep repo.DirectRepository
 		if nextMaintenanceTime.IsZero() {
 			nextMaintenanceTime = clock.Now()
 		}
+
+		nextMaintenanceTime = mp.FullCycle.NextAllowedTime(nextMaintenanceTime)
 	}
 
 	if mp.QuickCycle.Enabled {
-		if nextMaintenanceTime.IsZero() || ms.NextQuickMaintenanceTime.Before(nextMaintenanceTime) {
-			nextMaintenanceTime = ms.NextQuickMaintenanceTime
-			if nextMaintenanceTime.IsZero() {
-				nextMaintenanceTime = clock.Now()
-			}
+		nextQuick := ms.NextQuickMaintenanceTime
+		if nextQuick.IsZero() {
+			nextQuick = clock.Now()
+		}
+
+		nextQuick = mp.QuickCycle.NextAllowedTime(nextQuick)
+
+		if nextMaintenanceTime.IsZero() || nextQuick.Before(nextMaintenanceTime) {
+			nextMaintenanceTime = nextQuick
 		}
 	}
 
@@ -10,6 +10,7 @@
 
 	"github.com/kopia/kopia/internal/clock"
 	"github.com/kopia/kopia/internal/repotesting"
+	"github.com/kopia/kopia/internal/timeofday"
 	"github.com/kopia/kopia/repo/maintenance"
 )
 
@@ -101,6 +102,20 @@ func TestTimeToAttemptNextMaintenance(t *testing.T) {
 			},
 			want: time.Time{},
 		},
+		{
+			desc: "full deferred until window",
+			params: maintenance.Params{
+				Owner:      env.Repository.ClientOptions().UsernameAtHost(),
+				QuickCycle: maintenance.CycleParams{Enabled: false},
+				FullCycle: maintenance.CycleParams{Enabled: true, Windows: []maintenance.TimeWindow{
+					{Start: timeofday.TimeOfDay{Hour: 22}, End: timeofday.TimeOfDay{Hour: 4}},
+				}},
+			},
+			sched: maintenance.Schedule{
+				NextFullMaintenanceTime: time.Date(2020, 1, 1, 7, 0, 0, 0, time.Local),
+			},
+			want: time.Date(2020, 1, 1, 22, 0, 0, 0, time.Local),
+		},
 		{
 			desc: "not owned",
 			params: maintenance.Params{
@@ -0,0 +1,28 @@
+package maintenance
+
+import (
+	"context"
+	"time"
+
+	"github.com/pkg/errors"
+
+	"github.com/kopia/kopia/repo/blob/throttling"
+)
+
+// throttlingWindow is the duration during which the token buckets limiting a maintenance phase fully replenish.
+const throttlingWindow = 10 * time.Second
+
+// WithThrottling invokes the provided callback with the context in which storage operations are subject to
+// the provided limits in addition to throttling limits of the repository. Other activities using the repository
+// at the same time are not affected.
+func WithThrottling(ctx context.Context, limits throttling.Limits, cb func(ctx context.Context) error) error {
+	t, err := throttling.NewThrottlerWithOptions(limits, throttlingWindow, 0, throttling.ThrottlerOptions{
+		// waits are expected when the phase is limited.
+		WaitWarningThreshold: -1,
+	})
+	if err != nil {
+		return errors.Wrap(err, "invalid maintenance throttling limits")
+	}
+
+	return cb(throttling.WithThrottler(ctx, t))
+}
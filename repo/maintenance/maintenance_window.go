@@ -0,0 +1,92 @@
+package maintenance
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+
+	"github.com/kopia/kopia/internal/timeofday"
+)
+
+// TimeWindow is a daily time window in local time during which a maintenance cycle is allowed to start.
+// Windows where the end is before the start span midnight and windows where both are equal span the entire day.
+type TimeWindow struct {
+	Start timeofday.TimeOfDay `json:"start"`
+	End   timeofday.TimeOfDay `json:"end"`
+}
+
+// ParseTimeWindow parses the time window in the HH:MM-HH:MM format, such as "22:00-06:00".
+func ParseTimeWindow(s string) (TimeWindow, error) {
+	var w TimeWindow
+
+	start, end, ok := strings.Cut(s, "-")
+	if !ok {
+		return w, errors.Errorf("invalid time window %q, must be HH:MM-HH:MM", s)
+	}
+
+	if err := w.Start.Parse(strings.TrimSpace(start)); err != nil {
+		return w, errors.Wrap(err, "start")
+	}
+
+	if err := w.End.Parse(strings.TrimSpace(end)); err != nil {
+		return w, errors.Wrap(err, "end")
+	}
+
+	return w, nil
+}
+
+// String returns string representation of the time window, which can be parsed using ParseTimeWindow().
+func (w TimeWindow) String() string {
+	return fmt.Sprintf("%v-%v", w.Start, w.End)
+}
+
+// Contains returns true if the provided time is within the time window.
+func (w TimeWindow) Contains(t time.Time) bool {
+	return timeofday.InWindow(w.Start, w.End, t.Local())
+}
+
+// nextStart returns the earliest start of the window that is not before the provided time.
+func (w TimeWindow) nextStart(t time.Time) time.Time {
+	t = t.Local()
+
+	start := time.Date(t.Year(), t.Month(), t.Day(), w.Start.Hour, w.Start.Minute, 0, 0, time.Local)
+	if start.Before(t) {
+		start = start.AddDate(0, 0, 1)
+	}
+
+	return start
+}
+
+// IsAllowedAt returns true if the cycle is allowed to start at the provided time according to its windows.
+func (p *CycleParams) IsAllowedAt(t time.Time) bool {
+	if len(p.Windows) == 0 {
+		return true
+	}
+
+	for _, w := range p.Windows {
+		if w.Contains(t) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// NextAllowedTime returns the earliest time not before the provided one at which the cycle is allowed to start.
+func (p *CycleParams) NextAllowedTime(t time.Time) time.Time {
+	if p.IsAllowedAt(t) {
+		return t
+	}
+
+	var result time.Time
+
+	for _, w := range p.Windows {
+		if s := w.nextStart(t); result.IsZero() || s.Before(result) {
+			result = s
+		}
+	}
+
+	return result
+}
@@ -0,0 +1,57 @@
+package maintenance
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/kopia/kopia/internal/timeofday"
+)
+
+func TestParseTimeWindow(t *testing.T) {
+	w, err := ParseTimeWindow("22:00-6:30")
+	require.NoError(t, err)
+	require.Equal(t, TimeWindow{timeofday.TimeOfDay{Hour: 22}, timeofday.TimeOfDay{Hour: 6, Minute: 30}}, w)
+	require.Equal(t, "22:00-6:30", w.String())
+
+	for _, s := range []string{"", "22:00", "22:00-", "25:00-06:00", "22:00-06:61", "x-y"} {
+		_, err := ParseTimeWindow(s)
+		require.Error(t, err, s)
+	}
+}
+
+func TestCycleParamsWindows(t *testing.T) {
+	at := func(hour, minute int) time.Time {
+		return time.Date(2020, 1, 1, hour, minute, 0, 0, time.Local)
+	}
+
+	cp := CycleParams{}
+
+	// no windows - always allowed.
+	require.True(t, cp.IsAllowedAt(at(12, 0)))
+	require.Equal(t, at(12, 0), cp.NextAllowedTime(at(12, 0)))
+
+	cp.Windows = []TimeWindow{
+		{timeofday.TimeOfDay{Hour: 22}, timeofday.TimeOfDay{Hour: 6}},
+		{timeofday.TimeOfDay{Hour: 12}, timeofday.TimeOfDay{Hour: 13}},
+	}
+
+	require.True(t, cp.IsAllowedAt(at(23, 0)))
+	require.True(t, cp.IsAllowedAt(at(5, 59)))
+	require.False(t, cp.IsAllowedAt(at(6, 0)))
+	require.True(t, cp.IsAllowedAt(at(12, 30)))
+	require.False(t, cp.IsAllowedAt(at(13, 0)))
+
+	require.Equal(t, at(3, 0), cp.NextAllowedTime(at(3, 0)))
+	require.Equal(t, at(12, 0), cp.NextAllowedTime(at(7, 0)))
+	require.Equal(t, at(22, 0), cp.NextAllowedTime(at(14, 0)))
+
+	// window spanning the entire day.
+	cp.Windows = []TimeWindow{{timeofday.TimeOfDay{Hour: 3}, timeofday.TimeOfDay{Hour: 3}}}
+	require.True(t, cp.IsAllowedAt(at(15, 0)))
+
+	// window starting on the next day.
+	cp.Windows = []TimeWindow{{timeofday.TimeOfDay{Hour: 1}, timeofday.TimeOfDay{Hour: 2}}}
+	require.Equal(t, time.Date(2020, 1, 2, 1, 0, 0, 0, time.Local), cp.NextAllowedTime(at(15, 0)))
+}
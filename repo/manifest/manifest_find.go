@@ -0,0 +1,198 @@
+package manifest
+
+import (
+	"context"
+	"encoding/base64"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// ErrInvalidPageToken is returned when the page token passed to FindPage is malformed.
+var ErrInvalidPageToken = errors.New("invalid page token")
+
+// FindOptions specifies which manifest entries are returned by FindPage and in what order.
+type FindOptions struct {
+	// Labels that all returned entries must have.
+	Labels map[string]string `json:"labels,omitempty"`
+
+	// Descending returns newest entries first, otherwise oldest entries are returned first.
+	Descending bool `json:"descending,omitempty"`
+
+	// Limit is the maximum number of entries returned, 0 means no limit.
+	Limit int `json:"limit,omitempty"`
+
+	// PageToken is the NextPageToken returned with the previous page, empty to get the first page.
+	PageToken string `json:"pageToken,omitempty"`
+}
+
+// FindResult contains a single page of entries returned by FindPage.
+type FindResult struct {
+	Entries []*EntryMetadata `json:"entries"`
+
+	// NextPageToken can be passed in FindOptions to get the next page, empty when there are no more entries.
+	NextPageToken string `json:"nextPageToken,omitempty"`
+}
+
+// pageCursor identifies the position of the last entry returned in a page.
+type pageCursor struct {
+	modTime time.Time
+	id      ID
+}
+
+func (c pageCursor) token() string {
+	return base64.RawURLEncoding.EncodeToString([]byte(strconv.FormatInt(c.modTime.UnixNano(), 10) + ":" + string(c.id)))
+}
+
+// before returns true if c sorts before the other cursor in ascending order.
+func (c pageCursor) before(other pageCursor) bool {
+	if !c.modTime.Equal(other.modTime) {
+		return c.modTime.Before(other.modTime)
+	}
+
+	return c.id < other.id
+}
+
+func parsePageToken(token string) (pageCursor, error) {
+	b, err := base64.RawURLEncoding.DecodeString(token)
+	if err != nil {
+		return pageCursor{}, ErrInvalidPageToken
+	}
+
+	ts, id, ok := strings.Cut(string(b), ":")
+	if !ok || id == "" {
+		return pageCursor{}, ErrInvalidPageToken
+	}
+
+	nanos, err := strconv.ParseInt(ts, 10, 64)
+	if err != nil {
+		return pageCursor{}, ErrInvalidPageToken
+	}
+
+	return pageCursor{time.Unix(0, nanos), ID(id)}, nil
+}
+
+// selectPage sorts n entries whose positions are provided by cursorAt according to opts and returns
+// the range of indexes of entries belonging to the requested page along with the next page token.
+func selectPage(n int, cursorAt func(i int) pageCursor, swap func(i, j int), opts FindOptions) (start, end int, nextPageToken string, err error) {
+	less := func(i, j int) bool {
+		if opts.Descending {
+			return cursorAt(j).before(cursorAt(i))
+		}
+
+		return cursorAt(i).before(cursorAt(j))
+	}
+
+	sort.Sort(funcSorter{n, less, swap})
+
+	if opts.PageToken != "" {
+		last, err := parsePageToken(opts.PageToken)
+		if err != nil {
+			return 0, 0, "", err
+		}
+
+		start = sort.Search(n, func(i int) bool {
+			if opts.Descending {
+				return cursorAt(i).before(last)
+			}
+
+			return last.before(cursorAt(i))
+		})
+	}
+
+	end = n
+	if opts.Limit > 0 && start+opts.Limit < n {
+		end = start + opts.Limit
+		nextPageToken = cursorAt(end - 1).token()
+	}
+
+	return start, end, nextPageToken, nil
+}
+
+type funcSorter struct {
+	n    int
+	less func(i, j int) bool
+	swap func(i, j int)
+}
+
+func (s funcSorter) Len() int           { return s.n }
+func (s funcSorter) Less(i, j int) bool { return s.less(i, j) }
+func (s funcSorter) Swap(i, j int)      { s.swap(i, j) }
+
+// PageEntries returns a single page of the provided entries, filtered and sorted according to the provided options. It is useful for repositories which can only provide all matching entries.
+func PageEntries(entries []*EntryMetadata, opts FindOptions) (*FindResult, error) {
+	var matches []*EntryMetadata
+
+	for _, e := range entries {
+		if matchesLabels(e.Labels, opts.Labels) {
+			matches = append(matches, e)
+		}
+	}
+
+	start, end, next, err := selectPage(
+		len(matches),
+		func(i int) pageCursor { return pageCursor{matches[i].ModTime, matches[i].ID} },
+		func(i, j int) { matches[i], matches[j] = matches[j], matches[i] },
+		opts)
+	if err != nil {
+		return nil, err
+	}
+
+	return &FindResult{
+		Entries:       matches[start:end],
+		NextPageToken: next,
+	}, nil
+}
+
+// FindPage returns a single page of EntryMetadata for manifest entries matching all provided labels
+// sorted by modification time. Unlike Find, metadata is only cloned for entries in the returned page,
+// which keeps memory usage low when paging through a large number of manifests.
+func (m *Manager) FindPage(ctx context.Context, opts FindOptions) (*FindResult, error) {
+	committedMatches, err := m.committed.findCommittedEntries(ctx, opts.Labels)
+	if err != nil {
+		return nil, err
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	matches := make([]*manifestEntry, 0, len(committedMatches))
+
+	for _, e := range m.pendingEntries {
+		if !e.Deleted && matchesLabels(e.Labels, opts.Labels) {
+			matches = append(matches, e)
+		}
+	}
+
+	for _, e := range committedMatches {
+		if m.pendingEntries[e.ID] != nil {
+			// ignore committed that are also in pending
+			continue
+		}
+
+		matches = append(matches, e)
+	}
+
+	start, end, next, err := selectPage(
+		len(matches),
+		func(i int) pageCursor { return pageCursor{matches[i].ModTime, matches[i].ID} },
+		func(i, j int) { matches[i], matches[j] = matches[j], matches[i] },
+		opts)
+	if err != nil {
+		return nil, err
+	}
+
+	result := &FindResult{
+		Entries:       make([]*EntryMetadata, 0, end-start),
+		NextPageToken: next,
+	}
+
+	for _, e := range matches[start:end] {
+		result.Entries = append(result.Entries, cloneEntryMetadata(e))
+	}
+
+	return result, nil
+}
@@ -0,0 +1,114 @@
+package manifest
+
+import (
+	"slices"
+	"sort"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/kopia/kopia/internal/blobtesting"
+	"github.com/kopia/kopia/internal/testlogging"
+)
+
+func TestManifestFindPage(t *testing.T) {
+	ctx := testlogging.Context(t)
+	data := blobtesting.DataMap{}
+
+	now := time.Date(2020, 1, 2, 3, 4, 5, 6, time.UTC)
+	mgr := newManagerForTesting(ctx, t, data, ManagerOptions{
+		TimeNow: func() time.Time { return now },
+	})
+
+	var all []*EntryMetadata
+
+	for i := range 20 {
+		// first few manifests have identical times to exercise ordering by ID.
+		if i >= 4 {
+			now = now.Add(time.Second)
+		}
+
+		id := addAndVerify(ctx, t, mgr, map[string]string{"type": "item"}, map[string]int{"i": i})
+		addAndVerify(ctx, t, mgr, map[string]string{"type": "other"}, map[string]int{"i": i})
+
+		md, err := mgr.GetMetadata(ctx, id)
+		require.NoError(t, err)
+
+		all = append(all, md)
+
+		// mix committed and pending entries.
+		if i == 10 {
+			require.NoError(t, mgr.Flush(ctx))
+		}
+	}
+
+	sort.Slice(all, func(i, j int) bool {
+		return pageCursor{all[i].ModTime, all[i].ID}.before(pageCursor{all[j].ModTime, all[j].ID})
+	})
+
+	descending := slices.Clone(all)
+	slices.Reverse(descending)
+
+	for _, limit := range []int{0, 1, 3, 7, 20, 25} {
+		require.Equal(t, entryIDs(all), findAllPages(t, mgr, FindOptions{Labels: map[string]string{"type": "item"}, Limit: limit}))
+		require.Equal(t, entryIDs(descending), findAllPages(t, mgr, FindOptions{Labels: map[string]string{"type": "item"}, Limit: limit, Descending: true}))
+	}
+
+	// paging through entries from Find gives the same results.
+	found, err := mgr.Find(ctx, map[string]string{"type": "item"})
+	require.NoError(t, err)
+
+	res, err := PageEntries(found, FindOptions{Limit: 5, Descending: true})
+	require.NoError(t, err)
+	require.Equal(t, entryIDs(descending[0:5]), entryIDs(res.Entries))
+
+	res, err = PageEntries(found, FindOptions{Limit: 5, Descending: true, PageToken: res.NextPageToken})
+	require.NoError(t, err)
+	require.Equal(t, entryIDs(descending[5:10]), entryIDs(res.Entries))
+
+	// deleted entries are not returned.
+	require.NoError(t, mgr.Delete(ctx, all[0].ID))
+
+	res, err = mgr.FindPage(ctx, FindOptions{Labels: map[string]string{"type": "item"}, Limit: 1})
+	require.NoError(t, err)
+	require.Equal(t, entryIDs(all[1:2]), entryIDs(res.Entries))
+
+	_, err = mgr.FindPage(ctx, FindOptions{PageToken: "invalid"})
+	require.ErrorIs(t, err, ErrInvalidPageToken)
+}
+
+func findAllPages(t *testing.T, mgr *Manager, opts FindOptions) []ID {
+	t.Helper()
+
+	ctx := testlogging.Context(t)
+
+	var result []ID
+
+	for {
+		res, err := mgr.FindPage(ctx, opts)
+		require.NoError(t, err)
+
+		if opts.Limit > 0 {
+			require.LessOrEqual(t, len(res.Entries), opts.Limit)
+		}
+
+		result = append(result, entryIDs(res.Entries)...)
+
+		if res.NextPageToken == "" {
+			return result
+		}
+
+		opts.PageToken = res.NextPageToken
+	}
+}
+
+func entryIDs(entries []*EntryMetadata) []ID {
+	var result []ID
+
+	for _, e := range entries {
+		result = append(result, e.ID)
+	}
+
+	return result
+}
@@ -27,6 +27,12 @@ type Reader interface {
 	Length() int64
 }
 
+// HasIndirectObjectEntries is implemented by readers of indirect objects and exposes the objects they consist of,
+// ordered by their offsets. The returned entries must not be modified.
+type HasIndirectObjectEntries interface {
+	IndirectObjectEntries() []IndirectObjectEntry
+}
+
 type contentReader interface {
 	ContentInfo(ctx context.Context, contentID content.ID) (content.Info, error)
 	GetContent(ctx context.Context, contentID content.ID) ([]byte, error)
@@ -70,6 +76,7 @@ func (om *Manager) NewWriter(ctx context.Context, opt WriterOptions) Writer {
 	w.description = opt.Description
 	w.prefix = opt.Prefix
 	w.compressor = compression.ByName[opt.Compressor]
+	w.adaptiveCompression = opt.AdaptiveCompression
 	w.totalLength = 0
 	w.currentPosition = 0
 
@@ -87,6 +94,10 @@ func (om *Manager) NewWriter(ctx context.Context, opt WriterOptions) Writer {
 	w.buffer.Reset()
 	w.contentWriteError = nil
 
+	w.statsMutex.Lock()
+	w.stats = WriterStats{}
+	w.statsMutex.Unlock()
+
 	return w
 }
 
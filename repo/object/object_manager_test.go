@@ -59,8 +59,14 @@ func (f *fakeContentManager) GetContent(ctx context.Context, contentID content.I
 }
 
 func (f *fakeContentManager) WriteContent(ctx context.Context, data gather.Bytes, prefix content.IDPrefix, comp compression.HeaderID) (content.ID, error) {
+	contentID, _, err := f.WriteContentWithDedupInfo(ctx, data, prefix, comp)
+
+	return contentID, err
+}
+
+func (f *fakeContentManager) WriteContentWithDedupInfo(ctx context.Context, data gather.Bytes, prefix content.IDPrefix, comp compression.HeaderID) (content.ID, bool, error) {
 	if f.writeContentError != nil {
-		return content.EmptyID, f.writeContentError
+		return content.EmptyID, false, f.writeContentError
 	}
 
 	h := sha256.New()
@@ -71,12 +77,16 @@ func (f *fakeContentManager) WriteContent(ctx context.Context, data gather.Bytes
 	f.mu.Lock()
 	defer f.mu.Unlock()
 
+	if _, ok := f.data[contentID]; ok {
+		return contentID, true, nil
+	}
+
 	f.data[contentID] = data.ToByteSlice()
 	if f.compresionIDs != nil {
 		f.compresionIDs[contentID] = comp
 	}
 
-	return contentID, nil
+	return contentID, false, nil
 }
 
 func (f *fakeContentManager) SupportsContentCompression() bool {
@@ -164,6 +174,63 @@ func TestWriters(t *testing.T) {
 	}
 }
 
+func TestWriterStats(t *testing.T) {
+	ctx := testlogging.Context(t)
+	_, _, om := setupTest(t, nil)
+
+	writer := om.NewWriter(ctx, WriterOptions{})
+
+	require.Equal(t, WriterStats{}, writer.Stats())
+
+	// FIXED-1M splitter produces 2 identical chunks of 1MB followed by 1 chunk of 512KB.
+	chunk := bytes.Repeat([]byte{1, 2, 3, 4}, 256<<10)
+
+	for range 2 {
+		_, err := writer.Write(chunk)
+		require.NoError(t, err)
+	}
+
+	_, err := writer.Write(chunk[0 : 512<<10])
+	require.NoError(t, err)
+
+	_, err = writer.Result()
+	require.NoError(t, err)
+
+	st := writer.Stats()
+	require.Equal(t, WriterStats{
+		ChunkCount:         3,
+		TotalChunkBytes:    5 << 19,
+		MinChunkSize:       512 << 10,
+		MaxChunkSize:       1 << 20,
+		DeduplicatedChunks: 1,
+		DeduplicatedBytes:  1 << 20,
+	}, st)
+	require.Equal(t, int64(2), st.NewChunks())
+	require.Equal(t, int64(5<<19)/3, st.AverageChunkSize())
+
+	// writers are reused after Close(), make sure stats are reset.
+	require.NoError(t, writer.Close())
+
+	writer2 := om.NewWriter(ctx, WriterOptions{})
+	defer writer2.Close()
+
+	_, err = writer2.Write(chunk[0:100])
+	require.NoError(t, err)
+
+	_, err = writer2.Result()
+	require.NoError(t, err)
+
+	var total WriterStats
+
+	total.Add(st)
+	total.Add(writer2.Stats())
+
+	require.Equal(t, int64(4), total.ChunkCount)
+	require.Equal(t, int64(100), total.MinChunkSize)
+	require.Equal(t, int64(1<<20), total.MaxChunkSize)
+	require.Equal(t, int64(1), total.DeduplicatedChunks)
+}
+
 func objectIDsEqual(o1, o2 ID) bool {
 	return o1 == o2
 }
@@ -187,6 +254,57 @@ func TestCompression_ContentCompressionEnabled(t *testing.T) {
 	require.Equal(t, compression.ByName["gzip"].HeaderID(), cmap[cid])
 }
 
+func TestCompression_Adaptive(t *testing.T) {
+	ctx := testlogging.Context(t)
+
+	random := make([]byte, 100000)
+	cryptorand.Read(random)
+
+	compressible := bytes.Repeat([]byte{1, 2, 3, 4}, 25000)
+
+	cases := []struct {
+		data     []byte
+		adaptive bool
+		want     compression.HeaderID
+	}{
+		{random, false, compression.ByName["gzip"].HeaderID()},
+		{random, true, content.NoCompression},
+		{compressible, true, compression.ByName["gzip"].HeaderID()},
+	}
+
+	for _, tc := range cases {
+		cmap := map[content.ID]compression.HeaderID{}
+		_, _, om := setupTest(t, cmap)
+
+		w := om.NewWriter(ctx, WriterOptions{
+			Compressor:          "gzip",
+			AdaptiveCompression: tc.adaptive,
+		})
+		w.Write(tc.data)
+		oid, err := w.Result()
+		require.NoError(t, err)
+
+		cid, _, ok := oid.ContentID()
+		require.True(t, ok)
+		require.Equal(t, tc.want, cmap[cid])
+	}
+
+	// without content compression, incompressible data is written uncompressed.
+	_, _, om := setupTest(t, nil)
+
+	w := om.NewWriter(ctx, WriterOptions{
+		Compressor:          "gzip",
+		AdaptiveCompression: true,
+	})
+	w.Write(random)
+	oid, err := w.Result()
+	require.NoError(t, err)
+
+	_, isCompressed, ok := oid.ContentID()
+	require.True(t, ok)
+	require.False(t, isCompressed)
+}
+
 func TestCompression_CustomSplitters(t *testing.T) {
 	cases := []struct {
 		wo          WriterOptions
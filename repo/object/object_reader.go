@@ -200,6 +200,10 @@ func (r *objectReader) Length() int64 {
 	return r.totalLength
 }
 
+func (r *objectReader) IndirectObjectEntries() []IndirectObjectEntry {
+	return r.seekTable
+}
+
 func openAndAssertLength(ctx context.Context, cr contentReader, objectID ID, assertLength int64) (Reader, error) {
 	if indexObjectID, ok := objectID.IndexObjectID(); ok {
 		// recursively calls openAndAssertLength
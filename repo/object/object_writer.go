@@ -19,6 +19,10 @@
 
 const indirectContentPrefix = "x"
 
+// CompressionSampleBytes is the number of bytes at the beginning of each chunk examined to determine
+// whether the chunk is compressible when adaptive compression is enabled.
+const CompressionSampleBytes = 64 << 10
+
 // Writer allows writing content to the storage and supports automatic deduplication and encryption
 // of written data.
 type Writer interface {
@@ -31,6 +35,9 @@ type Writer interface {
 
 	// Result returns object ID representing all bytes written to the writer.
 	Result() (ID, error)
+
+	// Stats returns statistics about chunks written so far, which is complete after Result() returns.
+	Stats() WriterStats
 }
 
 type contentIDTracker struct {
@@ -70,6 +77,9 @@ type objectWriter struct {
 
 	compressor compression.Compressor
 
+	// adaptiveCompression skips compression of chunks whose sample appears incompressible.
+	adaptiveCompression bool
+
 	prefix      content.IDPrefix
 	buffer      gather.WriteBuffer
 	totalLength int64
@@ -92,6 +102,10 @@ type objectWriter struct {
 
 	contentWriteErrorMutex sync.Mutex
 	contentWriteError      error // stores async write error, propagated in Result()
+
+	statsMutex sync.Mutex
+	// +checklocks:statsMutex
+	stats WriterStats
 }
 
 func (w *objectWriter) Close() error {
@@ -188,12 +202,16 @@ func (w *objectWriter) prepareAndWriteContentChunk(chunkID int, data gather.Byte
 	comp := content.NoCompression
 	objectComp := w.compressor
 
+	if objectComp != nil && w.adaptiveCompression && isLikelyIncompressible(data) {
+		objectComp = nil
+	}
+
 	// in super rare cases this may be stale, but if it is it will be false which is always safe.
 	supportsContentCompression := w.om.contentMgr.SupportsContentCompression()
 
 	// do not compress in this layer, instead pass comp to the content manager.
-	if supportsContentCompression && w.compressor != nil {
-		comp = w.compressor.HeaderID()
+	if supportsContentCompression && objectComp != nil {
+		comp = objectComp.HeaderID()
 		objectComp = nil
 	}
 
@@ -203,11 +221,15 @@ func (w *objectWriter) prepareAndWriteContentChunk(chunkID int, data gather.Byte
 		return errors.Wrap(err, "unable to prepare content bytes")
 	}
 
-	contentID, err := w.om.contentMgr.WriteContent(w.ctx, contentBytes, w.prefix, comp)
+	contentID, deduplicated, err := w.om.writeContent(w.ctx, contentBytes, w.prefix, comp)
 	if err != nil {
 		return errors.Wrapf(err, "unable to write content chunk %v of %v: %v", chunkID, w.description, err)
 	}
 
+	w.statsMutex.Lock()
+	w.stats.addChunk(int64(data.Length()), deduplicated)
+	w.statsMutex.Unlock()
+
 	// update index under a lock
 	w.indirectIndexGrowMutex.Lock()
 	w.indirectIndex[chunkID].Object = maybeCompressedObjectID(contentID, isCompressed)
@@ -237,6 +259,17 @@ func maybeCompressedObjectID(contentID content.ID, isCompressed bool) ID {
 	return oid
 }
 
+// isLikelyIncompressible estimates compressibility of the chunk based on the entropy of its first bytes.
+func isLikelyIncompressible(data gather.Bytes) bool {
+	var e compression.EntropyEstimator
+
+	if err := data.AppendSectionTo(&e, 0, min(data.Length(), CompressionSampleBytes)); err != nil {
+		return false
+	}
+
+	return e.LikelyIncompressible()
+}
+
 func maybeCompressedContentBytes(comp compression.Compressor, input gather.Bytes, output *gather.WriteBuffer) (data gather.Bytes, isCompressed bool, err error) {
 	if comp != nil {
 		if err := comp.Compress(output, input.Reader()); err != nil {
@@ -275,6 +308,14 @@ func (w *objectWriter) Checkpoint() (ID, error) {
 	return w.checkpointLocked()
 }
 
+// Stats returns statistics about chunks written so far. It does not include chunks of indirect objects.
+func (w *objectWriter) Stats() WriterStats {
+	w.statsMutex.Lock()
+	defer w.statsMutex.Unlock()
+
+	return w.stats
+}
+
 func (w *objectWriter) checkpointLocked() (ID, error) {
 	// wait for any in-flight asynchronous writes to finish
 	w.asyncWritesWG.Wait()
@@ -339,4 +380,8 @@ type WriterOptions struct {
 	Compressor  compression.Name
 	Splitter    string // use particular splitter instead of default
 	AsyncWrites int    // allow up to N content writes to be asynchronous
+
+	// AdaptiveCompression causes compression to be skipped for chunks whose first CompressionSampleBytes
+	// appear incompressible, such as media files or encrypted data.
+	AdaptiveCompression bool
 }
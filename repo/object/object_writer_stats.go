@@ -0,0 +1,95 @@
+package object
+
+import (
+	"context"
+
+	"github.com/kopia/kopia/internal/gather"
+	"github.com/kopia/kopia/repo/compression"
+	"github.com/kopia/kopia/repo/content"
+)
+
+// WriterStats contains statistics about chunks produced by the splitter and written by a Writer.
+type WriterStats struct {
+	// ChunkCount is the number of chunks written.
+	ChunkCount int64 `json:"chunkCount"`
+
+	// TotalChunkBytes is the total number of bytes in all chunks, before compression.
+	TotalChunkBytes int64 `json:"totalChunkBytes"`
+
+	// MinChunkSize and MaxChunkSize are the sizes of the smallest and largest chunk.
+	MinChunkSize int64 `json:"minChunkSize"`
+	MaxChunkSize int64 `json:"maxChunkSize"`
+
+	// DeduplicatedChunks is the number of chunks which were already present in the repository
+	// and DeduplicatedBytes is their total size. Chunks are only reported as deduplicated when
+	// the underlying content manager supports it.
+	DeduplicatedChunks int64 `json:"deduplicatedChunks"`
+	DeduplicatedBytes  int64 `json:"deduplicatedBytes"`
+}
+
+// NewChunks returns the number of chunks which were not deduplicated.
+func (s WriterStats) NewChunks() int64 {
+	return s.ChunkCount - s.DeduplicatedChunks
+}
+
+// AverageChunkSize returns the average size of a chunk or 0 if no chunks were written.
+func (s WriterStats) AverageChunkSize() int64 {
+	if s.ChunkCount == 0 {
+		return 0
+	}
+
+	return s.TotalChunkBytes / s.ChunkCount
+}
+
+// Add adds statistics of another writer to s.
+func (s *WriterStats) Add(other WriterStats) {
+	if other.ChunkCount == 0 {
+		return
+	}
+
+	if s.ChunkCount == 0 || other.MinChunkSize < s.MinChunkSize {
+		s.MinChunkSize = other.MinChunkSize
+	}
+
+	if other.MaxChunkSize > s.MaxChunkSize {
+		s.MaxChunkSize = other.MaxChunkSize
+	}
+
+	s.ChunkCount += other.ChunkCount
+	s.TotalChunkBytes += other.TotalChunkBytes
+	s.DeduplicatedChunks += other.DeduplicatedChunks
+	s.DeduplicatedBytes += other.DeduplicatedBytes
+}
+
+func (s *WriterStats) addChunk(length int64, deduplicated bool) {
+	s.Add(WriterStats{
+		ChunkCount:      1,
+		TotalChunkBytes: length,
+		MinChunkSize:    length,
+		MaxChunkSize:    length,
+	})
+
+	if deduplicated {
+		s.DeduplicatedChunks++
+		s.DeduplicatedBytes += length
+	}
+}
+
+// dedupReportingContentManager is implemented by content managers that can report whether
+// the written content was already present.
+type dedupReportingContentManager interface {
+	WriteContentWithDedupInfo(ctx context.Context, data gather.Bytes, prefix content.IDPrefix, comp compression.HeaderID) (content.ID, bool, error)
+}
+
+// writeContent writes the provided content and returns true if it was deduplicated, when known.
+func (om *Manager) writeContent(ctx context.Context, data gather.Bytes, prefix content.IDPrefix, comp compression.HeaderID) (content.ID, bool, error) {
+	if dr, ok := om.contentMgr.(dedupReportingContentManager); ok {
+		//nolint:wrapcheck
+		return dr.WriteContentWithDedupInfo(ctx, data, prefix, comp)
+	}
+
+	contentID, err := om.contentMgr.WriteContent(ctx, data, prefix, comp)
+
+	//nolint:wrapcheck
+	return contentID, false, err
+}
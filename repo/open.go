@@ -2,7 +2,9 @@
 
 import (
 	"context"
+	"encoding/hex"
 	"encoding/json"
+	"maps"
 	"os"
 	"path/filepath"
 	"strings"
@@ -20,7 +22,10 @@
 	"github.com/kopia/kopia/internal/retry"
 	"github.com/kopia/kopia/repo/blob"
 	"github.com/kopia/kopia/repo/blob/beforeop"
+	"github.com/kopia/kopia/repo/blob/checksum"
+	"github.com/kopia/kopia/repo/blob/circuitbreaker"
 	loggingwrapper "github.com/kopia/kopia/repo/blob/logging"
+	"github.com/kopia/kopia/repo/blob/quota"
 	"github.com/kopia/kopia/repo/blob/readonly"
 	"github.com/kopia/kopia/repo/blob/storagemetrics"
 	"github.com/kopia/kopia/repo/blob/throttling"
@@ -46,6 +51,7 @@
 var supportedFeatures = []feature.Feature{
 	"index-v1",
 	"index-v2",
+	format.FeaturePrefixRetention,
 }
 
 // throttlingWindow is the duration window during which the throttling token bucket fully replenishes.
@@ -63,14 +69,38 @@
 
 var log = logging.Module("kopia/repo")
 
+// TraceStorageOptions controls logging of storage access.
+type TraceStorageOptions struct {
+	Enabled       bool          // Logs storage access to the repository log
+	SampleEvery   int           // Logs only one in every N successful calls of each storage method
+	SlowThreshold time.Duration // Logs only successful calls taking at least this long
+	RedactBlobIDs bool          // Logs only the leading characters of blob IDs
+}
+
+// StorageCircuitBreakerOptions controls failing storage calls fast when the storage keeps failing.
+type StorageCircuitBreakerOptions struct {
+	CoolDown time.Duration // Duration during which failing calls fail immediately, 0 disables the circuit breaker
+}
+
 // Options provides configuration parameters for connection to a repository.
 type Options struct {
-	TraceStorage        bool                       // Logs all storage access using provided Printf-style function
-	TimeNowFunc         func() time.Time           // Time provider
-	DisableInternalLog  bool                       // Disable internal log
-	UpgradeOwnerID      string                     // Owner-ID of any upgrade in progress, when this is not set the access may be restricted
-	DoNotWaitForUpgrade bool                       // Disable the exponential forever backoff on an upgrade lock.
-	BeforeFlush         []RepositoryWriterCallback // list of callbacks to invoke before every flush
+	TraceStorage          TraceStorageOptions          // Controls logging of storage access
+	TimeNowFunc           func() time.Time             // Time provider
+	DisableInternalLog    bool                         // Disable internal log
+	UpgradeOwnerID        string                       // Owner-ID of any upgrade in progress, when this is not set the access may be restricted
+	DoNotWaitForUpgrade   bool                         // Disable the exponential forever backoff on an upgrade lock.
+	VerifyBlobChecksums   bool                         // Record digests of written blobs and verify them on read
+	BlobReadAheadBytes    int64                        // Prefetch this many bytes into the content cache when sequential reads of pack blobs are detected
+	StorageCircuitBreaker StorageCircuitBreakerOptions // Fails storage calls fast when the storage keeps failing
+	MaxIndexMemoryBytes   int64                        // When not caching, memory-map index blobs from temporary files beyond this total size
+	BeforeFlush           []RepositoryWriterCallback   // list of callbacks to invoke before every flush
+
+	// OTLPMetrics, if set, causes repository metrics to be periodically pushed to the OTLP collector.
+	OTLPMetrics *metrics.OTLPExporterOptions
+
+	// WrapStorage, if set, wraps the storage before it's used, for example with envelope encryption.
+	// Storage passed to Initialize() and Connect() must be wrapped the same way by the caller.
+	WrapStorage func(ctx context.Context, st blob.Storage) (blob.Storage, error)
 
 	OnFatalError func(err error) // function to invoke when repository encounters a fatal error, usually invokes os.Exit
 
@@ -156,9 +186,10 @@ func getContentCacheOrNil(ctx context.Context, si *APIServerInfo, opt *content.C
 	}
 
 	pc, err := cache.NewPersistentCache(ctx, "cache-storage", cs, prot, cache.SweepSettings{
-		MaxSizeBytes: opt.ContentCacheSizeBytes,
-		LimitBytes:   opt.ContentCacheSizeLimitBytes,
-		MinSweepAge:  opt.MinContentSweepAge.DurationOrDefault(content.DefaultDataCacheSweepAge),
+		MaxSizeBytes:   opt.ContentCacheSizeBytes,
+		LimitBytes:     opt.ContentCacheSizeLimitBytes,
+		MinSweepAge:    opt.MinContentSweepAge.DurationOrDefault(content.DefaultDataCacheSweepAge),
+		EvictionPolicy: cache.EvictionPolicy(opt.ContentCacheEvictionPolicy),
 	}, mr, timeNow)
 	if err != nil {
 		return nil, errors.Wrap(err, "unable to open persistent cache")
@@ -197,7 +228,14 @@ func(ctx context.Context) error {
 		beforeFlush:      options.BeforeFlush,
 	}
 
-	return openGRPCAPIRepository(ctx, si, password, par)
+	rep, err := openGRPCAPIRepository(ctx, si, password, par)
+	if err != nil {
+		return nil, err
+	}
+
+	maybeStartOTLPMetricsExporter(ctx, mr, options, cliOpts, nil)
+
+	return rep, nil
 }
 
 // openDirect opens the repository that directly manipulates blob storage..
@@ -211,12 +249,36 @@ func openDirect(ctx context.Context, configFile string, lc *LocalConfig, passwor
 		return nil, errors.Wrap(err, "cannot open storage")
 	}
 
-	if options.TraceStorage {
-		st = loggingwrapper.NewWrapper(st, log(ctx), "[STORAGE] ")
+	if options.WrapStorage != nil {
+		wrapped, err := options.WrapStorage(ctx, st)
+		if err != nil {
+			st.Close(ctx) //nolint:errcheck
+			return nil, errors.Wrap(err, "cannot wrap storage")
+		}
+
+		st = wrapped
+	}
+
+	if t := options.TraceStorage; t.Enabled {
+		st = loggingwrapper.NewWrapperWithOptions(st, log(ctx), "[STORAGE] ", loggingwrapper.Options{
+			SampleEvery:   t.SampleEvery,
+			SlowThreshold: t.SlowThreshold,
+			RedactBlobIDs: t.RedactBlobIDs,
+		})
+	}
+
+	if options.VerifyBlobChecksums {
+		st = checksum.NewWrapper(st, checksum.Options{})
 	}
 
 	if lc.ReadOnly {
-		st = readonly.NewWrapper(st)
+		var writablePrefixes []blob.ID
+
+		for _, p := range lc.WritablePrefixes {
+			writablePrefixes = append(writablePrefixes, blob.ID(p))
+		}
+
+		st = readonly.NewWrapperWithWritablePrefixes(st, writablePrefixes...)
 	}
 
 	cliOpts := lc.ApplyDefaults(ctx, "Repository in "+st.DisplayName())
@@ -239,11 +301,21 @@ func openWithConfig(ctx context.Context, st blob.Storage, cliOpts ClientOptions,
 		TimeNow:                defaultTime(options.TimeNowFunc),
 		DisableInternalLog:     options.DisableInternalLog,
 		PermissiveCacheLoading: cliOpts.PermissiveCacheLoading,
+		MaxIndexMemoryBytes:    options.MaxIndexMemoryBytes,
+		ReadAheadBytes:         options.BlobReadAheadBytes,
 	}
 
 	mr := metrics.NewRegistry()
 	st = storagemetrics.NewWrapper(st, mr)
 
+	if cb := options.StorageCircuitBreaker; cb.CoolDown > 0 {
+		// applied above storage metrics, so that calls which are not attempted are not measured.
+		st = circuitbreaker.NewWrapper(st, mr, circuitbreaker.Options{
+			CoolDown: cb.CoolDown,
+			TimeNow:  defaultTime(options.TimeNowFunc),
+		})
+	}
+
 	fmgr, ferr := format.NewManager(ctx, st, cacheOpts.CacheDirectory, cliOpts.FormatBlobCacheDuration, password, cmOpts.TimeNow)
 	if ferr != nil {
 		return nil, errors.Wrap(ferr, "unable to create format manager")
@@ -266,11 +338,28 @@ func openWithConfig(ctx context.Context, st blob.Storage, cliOpts ClientOptions,
 		limits = *cliOpts.Throttling
 	}
 
-	st, throttler, ferr := addThrottler(st, limits)
+	st, throttler, ferr := addThrottler(st, limits, defaultTime(options.TimeNowFunc), mr)
 	if ferr != nil {
 		return nil, errors.Wrap(ferr, "unable to add throttler")
 	}
 
+	mp, err := fmgr.GetMutableParameters(ctx)
+	if err != nil {
+		return nil, errors.Wrap(err, "mutable parameters")
+	}
+
+	if q := mp.StorageQuota; q.IsEnabled() {
+		// only writes of pack blobs are limited, so that index, manifest and log blobs can still be written
+		// and existing snapshots remain usable. Maintenance can still delete blobs, but it may fail to rewrite
+		// contents into new pack blobs while the quota is exceeded.
+		st = quota.NewWrapper(st, quota.Options{
+			MaxBytes: q.MaxBytes,
+			WarnOnly: q.WarnOnly,
+			Prefixes: content.PackBlobIDPrefixes,
+			TimeNow:  defaultTime(options.TimeNowFunc),
+		})
+	}
+
 	throttler.OnUpdate(func(l throttling.Limits) error {
 		lc2, err2 := LoadConfigFromFile(configFile)
 		if err2 != nil {
@@ -368,9 +457,38 @@ func openWithConfig(ctx context.Context, st blob.Storage, cliOpts ClientOptions,
 		},
 	}
 
+	maybeStartOTLPMetricsExporter(ctx, mr, options, cliOpts, fmgr.UniqueID())
+
 	return dr, nil
 }
 
+// maybeStartOTLPMetricsExporter starts pushing metrics to OTLP collector, if requested.
+// The exporter is stopped when the metrics registry is closed together with the repository.
+func maybeStartOTLPMetricsExporter(ctx context.Context, mr *metrics.Registry, options *Options, cliOpts ClientOptions, uniqueID []byte) {
+	if options.OTLPMetrics == nil {
+		return
+	}
+
+	opts := *options.OTLPMetrics
+	opts.ResourceAttributes = map[string]string{
+		metrics.OTLPAttributeServiceName:    "kopia",
+		metrics.OTLPAttributeServiceVersion: BuildVersion,
+		metrics.OTLPAttributeHostName:       cliOpts.Hostname,
+		metrics.OTLPAttributeUserName:       cliOpts.Username,
+	}
+
+	if len(uniqueID) > 0 {
+		opts.ResourceAttributes[metrics.OTLPAttributeRepositoryID] = hex.EncodeToString(uniqueID)
+	}
+
+	maps.Copy(opts.ResourceAttributes, options.OTLPMetrics.ResourceAttributes)
+
+	if err := mr.StartOTLPExporter(ctx, opts); err != nil {
+		// metrics are not essential, so don't prevent access to the repository.
+		log(ctx).Warnf("unable to start OTLP metrics exporter: %v", err)
+	}
+}
+
 func handleMissingRequiredFeatures(ctx context.Context, fmgr *format.Manager, ignoreErrors bool) error {
 	required, err := fmgr.RequiredFeatures(ctx)
 	if err != nil {
@@ -400,8 +518,7 @@ func wrapLockingStorage(st blob.Storage, r format.BlobStorageConfiguration) blob
 	return beforeop.NewWrapper(st, nil, nil, nil, func(ctx context.Context, id blob.ID, opts *blob.PutOptions) error {
 		for _, prefix := range prefixes {
 			if strings.HasPrefix(string(id), prefix) {
-				opts.RetentionMode = r.RetentionMode
-				opts.RetentionPeriod = r.RetentionPeriod
+				opts.RetentionMode, opts.RetentionPeriod = r.RetentionForBlob(id)
 
 				break
 			}
@@ -411,8 +528,11 @@ func wrapLockingStorage(st blob.Storage, r format.BlobStorageConfiguration) blob
 	})
 }
 
-func addThrottler(st blob.Storage, limits throttling.Limits) (blob.Storage, throttling.SettableThrottler, error) {
-	throttler, err := throttling.NewThrottler(limits, throttlingWindow, throttleBucketInitialFill)
+func addThrottler(st blob.Storage, limits throttling.Limits, timeNow func() time.Time, mr *metrics.Registry) (blob.Storage, throttling.SettableThrottler, error) {
+	throttler, err := throttling.NewThrottlerWithOptions(limits, throttlingWindow, throttleBucketInitialFill, throttling.ThrottlerOptions{
+		TimeNow:         timeNow,
+		MetricsRegistry: mr,
+	})
 	if err != nil {
 		return nil, nil, errors.Wrap(err, "unable to create throttler")
 	}
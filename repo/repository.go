@@ -39,6 +39,7 @@ type Repository interface {
 	NewWriter(ctx context.Context, opt WriteSessionOptions) (context.Context, RepositoryWriter, error)
 	UpdateDescription(d string)
 	Refresh(ctx context.Context) error
+	RefreshFormat(ctx context.Context) error
 	Close(ctx context.Context) error
 }
 
@@ -61,6 +62,12 @@ type RemoteRetentionPolicy interface {
 	ApplyRetentionPolicy(ctx context.Context, sourcePath string, reallyDelete bool) ([]manifest.ID, error)
 }
 
+// PagedManifestFinder is an interface implemented by repositories which can return a single page of
+// matching manifests without materializing metadata of all of them.
+type PagedManifestFinder interface {
+	FindManifestsPage(ctx context.Context, opts manifest.FindOptions) (*manifest.FindResult, error)
+}
+
 // DirectRepository provides additional low-level repository functionality.
 //
 //nolint:interfacebloat
@@ -81,6 +88,8 @@ type DirectRepository interface {
 	Token(password string) (string, error)
 	Throttler() throttling.SettableThrottler
 	DisableIndexRefresh()
+	CacheSizeLimits() content.CacheSizeLimits
+	SetCacheSizeLimits(ctx context.Context, l content.CacheSizeLimits) error
 }
 
 // DirectRepositoryWriter provides low-level write access to the repository.
@@ -190,6 +199,16 @@ func (r *directRepository) DisableIndexRefresh() {
 	r.cmgr.DisableIndexRefresh()
 }
 
+// CacheSizeLimits returns the current size limits of local caches.
+func (r *directRepository) CacheSizeLimits() content.CacheSizeLimits {
+	return r.cmgr.CacheSizeLimits()
+}
+
+// SetCacheSizeLimits changes size limits of local caches without reopening the repository.
+func (r *directRepository) SetCacheSizeLimits(ctx context.Context, l content.CacheSizeLimits) error {
+	return errors.Wrap(r.cmgr.SetCacheSizeLimits(ctx, l), "error setting cache size limits")
+}
+
 // OpenObject opens the reader for a given object, returns object.ErrNotFound.
 func (r *directRepository) OpenObject(ctx context.Context, id object.ID) (object.Reader, error) {
 	//nolint:wrapcheck
@@ -225,6 +244,12 @@ func (r *directRepository) FindManifests(ctx context.Context, labels map[string]
 	return r.mmgr.Find(ctx, labels)
 }
 
+// FindManifestsPage returns a single page of metadata for manifests matching given options.
+func (r *directRepository) FindManifestsPage(ctx context.Context, opts manifest.FindOptions) (*manifest.FindResult, error) {
+	//nolint:wrapcheck
+	return r.mmgr.FindPage(ctx, opts)
+}
+
 // DeleteManifest deletes the manifest with a given ID.
 func (r *directRepository) DeleteManifest(ctx context.Context, id manifest.ID) error {
 	//nolint:wrapcheck
@@ -367,6 +392,12 @@ func (r *directRepository) Refresh(ctx context.Context) error {
 	return errors.Wrap(r.cmgr.Refresh(ctx), "error refreshing content index")
 }
 
+// RefreshFormat revalidates the repository format blobs with the storage, only downloading them
+// again if they have changed.
+func (r *directRepository) RefreshFormat(ctx context.Context) error {
+	return errors.Wrap(r.fmgr.Refresh(ctx), "error refreshing repository format")
+}
+
 // Time returns the current local time for the repo.
 func (r *directRepository) Time() time.Time {
 	return defaultTime(r.timeNow)()
@@ -415,6 +446,22 @@ func DirectWriteSession(ctx context.Context, r DirectRepository, opt WriteSessio
 	return handleWriteSessionResult(ctx, w, opt, cb(ctx, w))
 }
 
+// FindManifestsPage returns a single page of metadata for manifests matching given options.
+// Repositories which don't implement PagedManifestFinder are paged after finding all matching manifests.
+func FindManifestsPage(ctx context.Context, rep Repository, opts manifest.FindOptions) (*manifest.FindResult, error) {
+	if pf, ok := rep.(PagedManifestFinder); ok {
+		return pf.FindManifestsPage(ctx, opts) //nolint:wrapcheck
+	}
+
+	entries, err := rep.FindManifests(ctx, opts.Labels)
+	if err != nil {
+		return nil, errors.Wrap(err, "unable to find manifests")
+	}
+
+	//nolint:wrapcheck
+	return manifest.PageEntries(entries, opts)
+}
+
 // replaceManifestsHelper is a helper that deletes all manifests matching provided labels and replaces them with the provided one.
 func replaceManifestsHelper(ctx context.Context, rep RepositoryWriter, labels map[string]string, payload interface{}) (manifest.ID, error) {
 	const minReplaceManifestTimeDelta = 100 * time.Millisecond
@@ -591,6 +591,41 @@ func TestObjectWritesWithRetention(t *testing.T) {
 	}))
 }
 
+func TestRefreshFormat(t *testing.T) {
+	ctx, env := repotesting.NewEnvironment(t, repotesting.FormatNotImportant)
+
+	fm := env.RepositoryWriter.FormatManager()
+
+	mp, err := fm.GetMutableParameters(ctx)
+	require.NoError(t, err)
+
+	origMaxPackSize := mp.MaxPackSize
+
+	// change the format blob using another client.
+	fm2 := env.MustOpenAnother(t).(repo.DirectRepositoryWriter).FormatManager()
+
+	mp.MaxPackSize = origMaxPackSize + 1000
+
+	blobCfg, err := fm2.BlobCfgBlob(ctx)
+	require.NoError(t, err)
+
+	feat, err := fm2.RequiredFeatures(ctx)
+	require.NoError(t, err)
+
+	require.NoError(t, fm2.SetParameters(ctx, mp, blobCfg, feat))
+
+	// the format is still cached.
+	mp, err = fm.GetMutableParameters(ctx)
+	require.NoError(t, err)
+	require.Equal(t, origMaxPackSize, mp.MaxPackSize)
+
+	require.NoError(t, env.Repository.RefreshFormat(ctx))
+
+	mp, err = fm.GetMutableParameters(ctx)
+	require.NoError(t, err)
+	require.Equal(t, origMaxPackSize+1000, mp.MaxPackSize)
+}
+
 func TestWriteSessionFlushOnSuccess(t *testing.T) {
 	var beforeFlushCount, afterFlushCount atomic.Int32
 
@@ -206,6 +206,25 @@ func ListSnapshotManifests(ctx context.Context, rep repo.Repository, src *Source
 	return entryIDs(entries), nil
 }
 
+// ListSnapshotManifestsPage returns a single page of snapshot manifests for a given source or all sources if nil,
+// sorted by the time they were saved. Labels in the provided options are replaced with ones matching the source.
+func ListSnapshotManifestsPage(ctx context.Context, rep repo.Repository, src *SourceInfo, opts manifest.FindOptions) (*manifest.FindResult, error) {
+	opts.Labels = map[string]string{
+		typeKey: ManifestType,
+	}
+
+	if src != nil {
+		opts.Labels = sourceInfoToLabels(*src)
+	}
+
+	res, err := repo.FindManifestsPage(ctx, rep, opts)
+	if err != nil {
+		return nil, errors.Wrap(err, "unable to find snapshot manifests")
+	}
+
+	return res, nil
+}
+
 // FindSnapshotsByRootObjectID returns the list of matching snapshots for a given rootID.
 func FindSnapshotsByRootObjectID(ctx context.Context, rep repo.Repository, rootID object.ID) ([]*Manifest, error) {
 	ids, err := ListSnapshotManifests(ctx, rep, nil, nil)
@@ -0,0 +1,99 @@
+package policy
+
+// mimeTypesByExtension maps lowercase file extensions to MIME types matched by compression rules.
+// The table is built in, rather than using the MIME database of the host, so that the same policy selects
+// the same compression algorithm for a file on all platforms.
+//
+//nolint:gochecknoglobals
+var mimeTypesByExtension = map[string]string{
+	// text
+	".c":    "text/x-c",
+	".cc":   "text/x-c",
+	".conf": "text/plain",
+	".cpp":  "text/x-c",
+	".css":  "text/css",
+	".csv":  "text/csv",
+	".go":   "text/x-go",
+	".h":    "text/x-c",
+	".htm":  "text/html",
+	".html": "text/html",
+	".ini":  "text/plain",
+	".java": "text/x-java",
+	".js":   "text/javascript",
+	".md":   "text/markdown",
+	".mjs":  "text/javascript",
+	".py":   "text/x-python",
+	".rs":   "text/x-rust",
+	".sh":   "text/x-shellscript",
+	".sql":  "text/x-sql",
+	".ts":   "text/x-typescript",
+	".tsv":  "text/tab-separated-values",
+	".txt":  "text/plain",
+	".xml":  "text/xml",
+	".yaml": "text/yaml",
+	".yml":  "text/yaml",
+
+	// images
+	".avif": "image/avif",
+	".bmp":  "image/bmp",
+	".gif":  "image/gif",
+	".heic": "image/heic",
+	".ico":  "image/vnd.microsoft.icon",
+	".jpeg": "image/jpeg",
+	".jpg":  "image/jpeg",
+	".png":  "image/png",
+	".svg":  "image/svg+xml",
+	".tif":  "image/tiff",
+	".tiff": "image/tiff",
+	".webp": "image/webp",
+
+	// audio
+	".aac":  "audio/aac",
+	".flac": "audio/flac",
+	".m4a":  "audio/mp4",
+	".mp3":  "audio/mpeg",
+	".ogg":  "audio/ogg",
+	".opus": "audio/opus",
+	".wav":  "audio/wav",
+
+	// video
+	".avi":  "video/x-msvideo",
+	".m4v":  "video/mp4",
+	".mkv":  "video/x-matroska",
+	".mov":  "video/quicktime",
+	".mp4":  "video/mp4",
+	".mpeg": "video/mpeg",
+	".mpg":  "video/mpeg",
+	".webm": "video/webm",
+	".wmv":  "video/x-ms-wmv",
+
+	// fonts
+	".otf":   "font/otf",
+	".ttf":   "font/ttf",
+	".woff":  "font/woff",
+	".woff2": "font/woff2",
+
+	// applications and archives
+	".7z":   "application/x-7z-compressed",
+	".br":   "application/x-brotli",
+	".bz2":  "application/x-bzip2",
+	".deb":  "application/vnd.debian.binary-package",
+	".docx": "application/vnd.openxmlformats-officedocument.wordprocessingml.document",
+	".epub": "application/epub+zip",
+	".gz":   "application/gzip",
+	".iso":  "application/x-iso9660-image",
+	".jar":  "application/java-archive",
+	".json": "application/json",
+	".lz4":  "application/x-lz4",
+	".pdf":  "application/pdf",
+	".pptx": "application/vnd.openxmlformats-officedocument.presentationml.presentation",
+	".rar":  "application/vnd.rar",
+	".rpm":  "application/x-rpm",
+	".tar":  "application/x-tar",
+	".tgz":  "application/gzip",
+	".wasm": "application/wasm",
+	".xlsx": "application/vnd.openxmlformats-officedocument.spreadsheetml.sheet",
+	".xz":   "application/x-xz",
+	".zip":  "application/zip",
+	".zst":  "application/zstd",
+}
@@ -3,6 +3,9 @@
 import (
 	"path/filepath"
 	"sort"
+	"strings"
+
+	"github.com/pkg/errors"
 
 	"github.com/kopia/kopia/fs"
 	"github.com/kopia/kopia/repo/compression"
@@ -18,6 +21,43 @@ type CompressionPolicy struct {
 	NoParentNeverCompress bool             `json:"noParentNeverCompress,omitempty"`
 	MinSize               int64            `json:"minSize,omitempty"`
 	MaxSize               int64            `json:"maxSize,omitempty"`
+
+	// Adaptive skips compression of parts of files which appear incompressible based on a sample of their data.
+	Adaptive *OptionalBool `json:"adaptive,omitempty"`
+
+	// Rules select compression algorithm for files by their extension or MIME type, taking precedence over
+	// CompressorName, OnlyCompress and NeverCompress. The first matching rule wins.
+	Rules []CompressionRule `json:"rules,omitempty"`
+}
+
+// CompressionRule selects compression algorithm for files matching the pattern.
+type CompressionRule struct {
+	// Pattern is either a file extension including the leading dot (".jpg") or a MIME type determined
+	// based on the extension using a built-in table of common file types, optionally with a wildcard
+	// subtype ("text/plain", "image/*").
+	Pattern string `json:"pattern"`
+
+	// Compressor is the name of the compression algorithm, "none" disables compression.
+	Compressor compression.Name `json:"compressor"`
+}
+
+// Matches returns true if the rule matches the provided file extension.
+func (r CompressionRule) Matches(ext string) bool {
+	if strings.HasPrefix(r.Pattern, ".") {
+		return strings.EqualFold(r.Pattern, ext)
+	}
+
+	mimeType, ok := mimeTypesByExtension[strings.ToLower(ext)]
+	if !ok {
+		return false
+	}
+
+	if prefix, ok := strings.CutSuffix(r.Pattern, "/*"); ok {
+		major, _, _ := strings.Cut(mimeType, "/")
+		return strings.EqualFold(prefix, major)
+	}
+
+	return strings.EqualFold(r.Pattern, mimeType)
 }
 
 // CompressionPolicyDefinition specifies which policy definition provided the value of a particular field.
@@ -27,6 +67,8 @@ type CompressionPolicyDefinition struct {
 	NeverCompress  snapshot.SourceInfo `json:"neverCompress,omitempty"`
 	MinSize        snapshot.SourceInfo `json:"minSize,omitempty"`
 	MaxSize        snapshot.SourceInfo `json:"maxSize,omitempty"`
+	Adaptive       snapshot.SourceInfo `json:"adaptive,omitempty"`
+	Rules          snapshot.SourceInfo `json:"rules,omitempty"`
 }
 
 // CompressorForFile returns compression name to be used for compressing a given file according to policy, using attributes such as name or size.
@@ -34,15 +76,25 @@ func (p *CompressionPolicy) CompressorForFile(e fs.Entry) compression.Name {
 	ext := filepath.Ext(e.Name())
 	size := e.Size()
 
-	if p.CompressorName == "none" {
+	if v := p.MinSize; v > 0 && size < v {
 		return ""
 	}
 
-	if v := p.MinSize; v > 0 && size < v {
+	if v := p.MaxSize; v > 0 && size > v {
 		return ""
 	}
 
-	if v := p.MaxSize; v > 0 && size > v {
+	for _, r := range p.Rules {
+		if r.Matches(ext) {
+			if r.Compressor == "none" {
+				return ""
+			}
+
+			return r.Compressor
+		}
+	}
+
+	if p.CompressorName == "none" {
 		return ""
 	}
 
@@ -62,11 +114,36 @@ func (p *CompressionPolicy) Merge(src CompressionPolicy, def *CompressionPolicyD
 	mergeCompressionName(&p.CompressorName, src.CompressorName, &def.CompressorName, si)
 	mergeInt64(&p.MinSize, src.MinSize, &def.MinSize, si)
 	mergeInt64(&p.MaxSize, src.MaxSize, &def.MaxSize, si)
+	mergeOptionalBool(&p.Adaptive, src.Adaptive, &def.Adaptive, si)
+	mergeCompressionRules(&p.Rules, src.Rules, &def.Rules, si)
 
 	mergeStrings(&p.OnlyCompress, &p.NoParentOnlyCompress, src.OnlyCompress, src.NoParentOnlyCompress, &def.OnlyCompress, si)
 	mergeStrings(&p.NeverCompress, &p.NoParentNeverCompress, src.NeverCompress, src.NoParentNeverCompress, &def.NeverCompress, si)
 }
 
+// ValidateCompressionPolicy returns an error if the compression policy is not valid.
+func ValidateCompressionPolicy(p CompressionPolicy) error {
+	seen := map[string]bool{}
+
+	for _, r := range p.Rules {
+		if !strings.HasPrefix(r.Pattern, ".") && !strings.Contains(r.Pattern, "/") {
+			return errors.Errorf("invalid compression rule pattern %q, must be a file extension starting with '.' or a MIME type", r.Pattern)
+		}
+
+		if _, ok := compression.ByName[r.Compressor]; !ok && r.Compressor != "none" {
+			return errors.Errorf("unsupported compression algorithm %q for %q", r.Compressor, r.Pattern)
+		}
+
+		if seen[strings.ToLower(r.Pattern)] {
+			return errors.Errorf("duplicate compression rule for %q", r.Pattern)
+		}
+
+		seen[strings.ToLower(r.Pattern)] = true
+	}
+
+	return nil
+}
+
 func isInSortedSlice(s string, slice []string) bool {
 	x := sort.SearchStrings(slice, s)
 	return x < len(slice) && slice[x] == s
@@ -0,0 +1,102 @@
+package policy_test
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/kopia/kopia/internal/mockfs"
+	"github.com/kopia/kopia/repo/compression"
+	"github.com/kopia/kopia/snapshot/policy"
+)
+
+func TestCompressorForFileWithRules(t *testing.T) {
+	p := policy.CompressionPolicy{
+		CompressorName: "zstd",
+		NeverCompress:  []string{".log"},
+		MinSize:        10,
+		Rules: []policy.CompressionRule{
+			{Pattern: ".JPG", Compressor: "none"},
+			{Pattern: "image/*", Compressor: "none"},
+			{Pattern: ".log", Compressor: "zstd-better-compression"},
+			{Pattern: "text/html", Compressor: "s2-default"},
+		},
+	}
+
+	dir := mockfs.NewDirectory()
+
+	cases := []struct {
+		name string
+		size int
+		want compression.Name
+	}{
+		{"photo.jpg", 100, ""},
+		{"photo.png", 100, ""},
+		{"server.log", 100, "zstd-better-compression"},
+		{"index.html", 100, "s2-default"},
+		{"main.go", 100, "zstd"},
+		{"noext", 100, "zstd"},
+		{"small.log", 5, ""},
+	}
+
+	for _, tc := range cases {
+		f := dir.AddFile(tc.name, make([]byte, tc.size), 0o644)
+		require.Equal(t, tc.want, p.CompressorForFile(f), tc.name)
+	}
+
+	// rules apply even when compression is otherwise disabled.
+	p.CompressorName = "none"
+
+	require.Equal(t, compression.Name("zstd-better-compression"), p.CompressorForFile(dir.AddFile("other.log", make([]byte, 100), 0o644)))
+	require.Equal(t, compression.Name(""), p.CompressorForFile(dir.AddFile("other.go", make([]byte, 100), 0o644)))
+}
+
+func TestCompressionRuleMatches(t *testing.T) {
+	cases := []struct {
+		pattern string
+		ext     string
+		want    bool
+	}{
+		{".jpg", ".JPG", true},
+		{".jpg", ".jpeg", false},
+		{"image/jpeg", ".jpeg", true},
+		{"image/*", ".PNG", true},
+		{"IMAGE/*", ".webp", true},
+		{"video/*", ".mkv", true},
+		{"text/*", ".go", true},
+		{"text/plain", ".txt", true},
+		{"text/plain", ".html", false},
+		{"application/*", ".zst", true},
+		// extensions which are not in the built-in table never match MIME types.
+		{"application/*", ".no-such-extension", false},
+		{"text/*", "", false},
+	}
+
+	for _, tc := range cases {
+		require.Equal(t, tc.want, policy.CompressionRule{Pattern: tc.pattern}.Matches(tc.ext), "%v %v", tc.pattern, tc.ext)
+	}
+}
+
+func TestValidateCompressionPolicy(t *testing.T) {
+	require.NoError(t, policy.ValidateCompressionPolicy(policy.CompressionPolicy{
+		Rules: []policy.CompressionRule{
+			{Pattern: ".jpg", Compressor: "none"},
+			{Pattern: "text/*", Compressor: "zstd-better-compression"},
+		},
+	}))
+
+	require.ErrorContains(t, policy.ValidateCompressionPolicy(policy.CompressionPolicy{
+		Rules: []policy.CompressionRule{{Pattern: "jpg", Compressor: "none"}},
+	}), "invalid compression rule pattern")
+
+	require.ErrorContains(t, policy.ValidateCompressionPolicy(policy.CompressionPolicy{
+		Rules: []policy.CompressionRule{{Pattern: ".jpg", Compressor: "no-such-algorithm"}},
+	}), "unsupported compression algorithm")
+
+	require.ErrorContains(t, policy.ValidateCompressionPolicy(policy.CompressionPolicy{
+		Rules: []policy.CompressionRule{
+			{Pattern: ".jpg", Compressor: "none"},
+			{Pattern: ".JPG", Compressor: "zstd"},
+		},
+	}), "duplicate compression rule")
+}
@@ -88,6 +88,10 @@ func ValidatePolicy(si snapshot.SourceInfo, pol *Policy) error {
 		return errors.Wrap(err, "invalid upload policy")
 	}
 
+	if err := ValidateCompressionPolicy(pol.CompressionPolicy); err != nil {
+		return errors.Wrap(err, "invalid compression policy")
+	}
+
 	return nil
 }
 
@@ -154,6 +154,13 @@ func mergeStringList(target *[]string, src []string, def *snapshot.SourceInfo, s
 	}
 }
 
+func mergeCompressionRules(target *[]CompressionRule, src []CompressionRule, def *snapshot.SourceInfo, si snapshot.SourceInfo) {
+	if len(*target) == 0 && len(src) != 0 {
+		*target = src
+		*def = si
+	}
+}
+
 func mergeLogLevel(target **LogDetail, src *LogDetail, def *snapshot.SourceInfo, si snapshot.SourceInfo) {
 	if *target == nil && src != nil {
 		b := *src
@@ -144,10 +144,14 @@ func testPolicyMergeSingleField(t *testing.T, fieldName string, typ reflect.Type
 		v0 = reflect.ValueOf((*policy.ActionCommand)(nil))
 		v1 = reflect.ValueOf(&policy.ActionCommand{Command: "foo"})
 		v2 = reflect.ValueOf(&policy.ActionCommand{Command: "bar"})
-	case "[]policy.TimeOfDay":
+	case "[]timeofday.TimeOfDay":
 		v0 = reflect.ValueOf([]policy.TimeOfDay{})
 		v1 = reflect.ValueOf([]policy.TimeOfDay{{Hour: 10}})
 		v2 = reflect.ValueOf([]policy.TimeOfDay{{Hour: 11}})
+	case "[]policy.CompressionRule":
+		v0 = reflect.ValueOf([]policy.CompressionRule{})
+		v1 = reflect.ValueOf([]policy.CompressionRule{{Pattern: ".txt", Compressor: "foo"}})
+		v2 = reflect.ValueOf([]policy.CompressionRule{{Pattern: "image/*", Compressor: "none"}})
 	case "compression.Name":
 		v0 = reflect.ValueOf(compression.Name(""))
 		v1 = reflect.ValueOf(compression.Name("foo"))
@@ -67,6 +67,9 @@
 
 		// upload large files in chunks of 2 GiB
 		ParallelUploadAboveSize: newOptionalInt64(2 << 30), //nolint:mnd
+
+		MaxUploadBytesPerSecond: nil, // unlimited
+		MaxConcurrentUploads:    nil, // unlimited
 	}
 
 	// DefaultPolicy is a default policy returned by policy tree in absence of other policies.
@@ -3,7 +3,6 @@
 import (
 	"cmp"
 	"context"
-	"fmt"
 	"reflect"
 	"slices"
 	"strings"
@@ -12,37 +11,13 @@
 	"github.com/hashicorp/cronexpr"
 	"github.com/pkg/errors"
 
+	"github.com/kopia/kopia/internal/timeofday"
 	"github.com/kopia/kopia/repo"
 	"github.com/kopia/kopia/snapshot"
 )
 
 // TimeOfDay represents the time of day (hh:mm) using 24-hour time format.
-type TimeOfDay struct {
-	Hour   int `json:"hour"`
-	Minute int `json:"min"`
-}
-
-// Parse parses the time of day.
-func (t *TimeOfDay) Parse(s string) error {
-	if _, err := fmt.Sscanf(s, "%v:%02v", &t.Hour, &t.Minute); err != nil {
-		return errors.New("invalid time of day, must be HH:MM")
-	}
-
-	if t.Hour < 0 || t.Hour > 23 {
-		return errors.Errorf("invalid hour %q, must be between 0 and 23", s)
-	}
-
-	if t.Minute < 0 || t.Minute > 59 {
-		return errors.Errorf("invalid minute %q, must be between 0 and 59", s)
-	}
-
-	return nil
-}
-
-// String returns string representation of time of day.
-func (t TimeOfDay) String() string {
-	return fmt.Sprintf("%v:%02v", t.Hour, t.Minute)
-}
+type TimeOfDay = timeofday.TimeOfDay
 
 // SortAndDedupeTimesOfDay sorts the slice of times of day and removes duplicates.
 func SortAndDedupeTimesOfDay(tod []TimeOfDay) []TimeOfDay {
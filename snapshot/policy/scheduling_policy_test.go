@@ -64,7 +64,7 @@ func TestNextSnapshotTime(t *testing.T) {
 		{
 			name: "multiple ToD schedules, next snapshot is the earliest",
 			pol: policy.SchedulingPolicy{
-				TimesOfDay: []policy.TimeOfDay{{11, 55}, {11, 57}},
+				TimesOfDay: []policy.TimeOfDay{{Hour: 11, Minute: 55}, {Hour: 11, Minute: 57}},
 			},
 			now:                  time.Date(2020, time.January, 1, 11, 50, 30, 0, time.Local),
 			previousSnapshotTime: time.Date(2020, time.January, 1, 11, 50, 0, 0, time.Local),
@@ -74,7 +74,7 @@ func TestNextSnapshotTime(t *testing.T) {
 		{
 			name: "multiple ToD snapshots, next is the 2nd one",
 			pol: policy.SchedulingPolicy{
-				TimesOfDay: []policy.TimeOfDay{{11, 55}, {11, 57}},
+				TimesOfDay: []policy.TimeOfDay{{Hour: 11, Minute: 55}, {Hour: 11, Minute: 57}},
 			},
 			now:      time.Date(2020, time.January, 1, 11, 55, 30, 0, time.Local),
 			wantTime: time.Date(2020, time.January, 1, 11, 57, 0, 0, time.Local),
@@ -84,7 +84,7 @@ func TestNextSnapshotTime(t *testing.T) {
 			name: "interval and ToD policies, next is 1st ToD",
 			pol: policy.SchedulingPolicy{
 				IntervalSeconds: 300, // every 5 minutes
-				TimesOfDay:      []policy.TimeOfDay{{11, 54}, {11, 57}},
+				TimesOfDay:      []policy.TimeOfDay{{Hour: 11, Minute: 54}, {Hour: 11, Minute: 57}},
 			},
 			previousSnapshotTime: time.Date(2020, time.January, 1, 11, 50, 0, 0, time.Local),
 			now:                  time.Date(2020, time.January, 1, 11, 53, 0, 0, time.Local),
@@ -95,7 +95,7 @@ func TestNextSnapshotTime(t *testing.T) {
 			name: "interval and ToD policies, next is now (1st ToD)",
 			pol: policy.SchedulingPolicy{
 				IntervalSeconds: 300, // every 5 minutes
-				TimesOfDay:      []policy.TimeOfDay{{11, 54}, {11, 57}},
+				TimesOfDay:      []policy.TimeOfDay{{Hour: 11, Minute: 54}, {Hour: 11, Minute: 57}},
 			},
 			previousSnapshotTime: time.Date(2020, time.January, 1, 11, 50, 0, 0, time.Local),
 			now:                  time.Date(2020, time.January, 1, 11, 54, 0, 0, time.Local),
@@ -106,7 +106,7 @@ func TestNextSnapshotTime(t *testing.T) {
 			name: "interval and ToD policies, next is interval",
 			pol: policy.SchedulingPolicy{
 				IntervalSeconds: 300, // every 5 minutes
-				TimesOfDay:      []policy.TimeOfDay{{11, 54}, {11, 57}},
+				TimesOfDay:      []policy.TimeOfDay{{Hour: 11, Minute: 54}, {Hour: 11, Minute: 57}},
 			},
 			previousSnapshotTime: time.Date(2020, time.January, 1, 11, 50, 0, 0, time.Local),
 			now:                  time.Date(2020, time.January, 1, 11, 54, 1, 0, time.Local),
@@ -117,7 +117,7 @@ func TestNextSnapshotTime(t *testing.T) {
 			name: "interval and ToD policies, next is now (interval)",
 			pol: policy.SchedulingPolicy{
 				IntervalSeconds: 300, // every 5 minutes
-				TimesOfDay:      []policy.TimeOfDay{{11, 54}, {11, 57}},
+				TimesOfDay:      []policy.TimeOfDay{{Hour: 11, Minute: 54}, {Hour: 11, Minute: 57}},
 			},
 			previousSnapshotTime: time.Date(2020, time.January, 1, 11, 50, 0, 0, time.Local),
 			now:                  time.Date(2020, time.January, 1, 11, 55, 0, 0, time.Local),
@@ -128,7 +128,7 @@ func TestNextSnapshotTime(t *testing.T) {
 			name: "interval and ToD policies, next is now (interval overdue)",
 			pol: policy.SchedulingPolicy{
 				IntervalSeconds: 300, // every 5 minutes
-				TimesOfDay:      []policy.TimeOfDay{{11, 54}, {11, 57}},
+				TimesOfDay:      []policy.TimeOfDay{{Hour: 11, Minute: 54}, {Hour: 11, Minute: 57}},
 			},
 			previousSnapshotTime: time.Date(2020, time.January, 1, 11, 50, 0, 0, time.Local),
 			now:                  time.Date(2020, time.January, 1, 11, 55, 1, 0, time.Local),
@@ -139,7 +139,7 @@ func TestNextSnapshotTime(t *testing.T) {
 		{
 			name: "multiple ToD policies, last missed, RunMissed is off, next is 2nd ToD",
 			pol: policy.SchedulingPolicy{
-				TimesOfDay: []policy.TimeOfDay{{11, 54}, {11, 57}},
+				TimesOfDay: []policy.TimeOfDay{{Hour: 11, Minute: 54}, {Hour: 11, Minute: 57}},
 			},
 			previousSnapshotTime: time.Date(2020, time.January, 1, 11, 50, 0, 0, time.Local),
 			now:                  time.Date(2020, time.January, 1, 11, 56, 0, 0, time.Local),
@@ -149,7 +149,7 @@ func TestNextSnapshotTime(t *testing.T) {
 		{
 			name: "multiple ToD policies, last missed, RunMissed is off, next is now (2nd ToD)",
 			pol: policy.SchedulingPolicy{
-				TimesOfDay: []policy.TimeOfDay{{11, 54}, {11, 57}},
+				TimesOfDay: []policy.TimeOfDay{{Hour: 11, Minute: 54}, {Hour: 11, Minute: 57}},
 			},
 			previousSnapshotTime: time.Date(2020, time.January, 1, 11, 50, 0, 0, time.Local),
 			now:                  time.Date(2020, time.January, 1, 11, 57, 0, 0, time.Local),
@@ -159,7 +159,7 @@ func TestNextSnapshotTime(t *testing.T) {
 		{
 			name: "multiple ToD policies, last missed, RunMissed is off, next is tomorrow",
 			pol: policy.SchedulingPolicy{
-				TimesOfDay: []policy.TimeOfDay{{11, 54}, {11, 57}},
+				TimesOfDay: []policy.TimeOfDay{{Hour: 11, Minute: 54}, {Hour: 11, Minute: 57}},
 			},
 			previousSnapshotTime: time.Date(2020, time.January, 1, 11, 50, 0, 0, time.Local),
 			now:                  time.Date(2020, time.January, 1, 11, 57, 0, 1, time.Local),
@@ -170,7 +170,7 @@ func TestNextSnapshotTime(t *testing.T) {
 			name: "interval and ToD policies, last 9hrs in the future, next is 1st ToD",
 			pol: policy.SchedulingPolicy{
 				IntervalSeconds: 43200,
-				TimesOfDay:      []policy.TimeOfDay{{19, 0}, {20, 0}},
+				TimesOfDay:      []policy.TimeOfDay{{Hour: 19, Minute: 0}, {Hour: 20, Minute: 0}},
 			},
 			previousSnapshotTime: time.Date(2020, time.January, 1, 19, 0, 0, 0, time.Local),
 			now:                  time.Date(2020, time.January, 1, 10, 0, 0, 0, time.Local),
@@ -181,7 +181,7 @@ func TestNextSnapshotTime(t *testing.T) {
 			name: "ToD policy and manual policies, manual wins",
 			pol: policy.SchedulingPolicy{
 				IntervalSeconds: 43200,
-				TimesOfDay:      []policy.TimeOfDay{{19, 0}, {20, 0}},
+				TimesOfDay:      []policy.TimeOfDay{{Hour: 19, Minute: 0}, {Hour: 20, Minute: 0}},
 				Manual:          true,
 			},
 			previousSnapshotTime: time.Date(2020, time.January, 1, 19, 0, 0, 0, time.Local),
@@ -214,7 +214,7 @@ func TestNextSnapshotTime(t *testing.T) {
 		{
 			name: "Run immediately since last run was missed and RunMissed is set",
 			pol: policy.SchedulingPolicy{
-				TimesOfDay: []policy.TimeOfDay{{11, 55}},
+				TimesOfDay: []policy.TimeOfDay{{Hour: 11, Minute: 55}},
 				RunMissed:  policy.NewOptionalBool(true),
 			},
 			now:                  time.Date(2020, time.January, 2, 11, 55, 30, 0, time.Local),
@@ -225,7 +225,7 @@ func TestNextSnapshotTime(t *testing.T) {
 		{
 			name: "Don't run immediately even though RunMissed is set, because next run is upcoming",
 			pol: policy.SchedulingPolicy{
-				TimesOfDay: []policy.TimeOfDay{{11, 55}},
+				TimesOfDay: []policy.TimeOfDay{{Hour: 11, Minute: 55}},
 				RunMissed:  policy.NewOptionalBool(true),
 			},
 			now:                  time.Date(2020, time.January, 3, 11, 30, 0, 0, time.Local),
@@ -236,7 +236,7 @@ func TestNextSnapshotTime(t *testing.T) {
 		{
 			name: "Run immediately because one of the TimeOfDays was missed",
 			pol: policy.SchedulingPolicy{
-				TimesOfDay: []policy.TimeOfDay{{11, 1}, {4, 1}},
+				TimesOfDay: []policy.TimeOfDay{{Hour: 11, Minute: 1}, {Hour: 4, Minute: 1}},
 				RunMissed:  policy.NewOptionalBool(true),
 			},
 			now:                  time.Date(2020, time.January, 2, 10, 0, 0, 0, time.Local),
@@ -247,7 +247,7 @@ func TestNextSnapshotTime(t *testing.T) {
 		{
 			name: "Don't run immediately even though RunMissed is set because last run was not missed",
 			pol: policy.SchedulingPolicy{
-				TimesOfDay: []policy.TimeOfDay{{11, 55}},
+				TimesOfDay: []policy.TimeOfDay{{Hour: 11, Minute: 55}},
 				RunMissed:  policy.NewOptionalBool(true),
 			},
 			now:                  time.Date(2020, time.January, 2, 11, 30, 0, 0, time.Local),
@@ -258,7 +258,7 @@ func TestNextSnapshotTime(t *testing.T) {
 		{
 			name: "Don't run immediately even though RunMissed is set because last run was not missed",
 			pol: policy.SchedulingPolicy{
-				TimesOfDay: []policy.TimeOfDay{{10, 0}},
+				TimesOfDay: []policy.TimeOfDay{{Hour: 10, Minute: 0}},
 				RunMissed:  policy.NewOptionalBool(true),
 			},
 			now:                  time.Date(2020, time.January, 2, 11, 0, 0, 0, time.Local),
@@ -269,7 +269,7 @@ func TestNextSnapshotTime(t *testing.T) {
 		{
 			name: "Run immediately because Cron was missed",
 			pol: policy.SchedulingPolicy{
-				TimesOfDay: []policy.TimeOfDay{{11, 55}},
+				TimesOfDay: []policy.TimeOfDay{{Hour: 11, Minute: 55}},
 				Cron:       []string{"0 * * * *"}, // Every hour
 				RunMissed:  policy.NewOptionalBool(true),
 			},
@@ -11,6 +11,11 @@ type UploadPolicy struct {
 	MaxParallelSnapshots    *OptionalInt   `json:"maxParallelSnapshots,omitempty"`
 	MaxParallelFileReads    *OptionalInt   `json:"maxParallelFileReads,omitempty"`
 	ParallelUploadAboveSize *OptionalInt64 `json:"parallelUploadAboveSize,omitempty"`
+
+	// MaxUploadBytesPerSecond and MaxConcurrentUploads limit blob uploads of a single snapshot of the source,
+	// in addition to throttling limits of the repository.
+	MaxUploadBytesPerSecond *OptionalInt64 `json:"maxUploadBytesPerSecond,omitempty"`
+	MaxConcurrentUploads    *OptionalInt   `json:"maxConcurrentUploads,omitempty"`
 }
 
 // UploadPolicyDefinition specifies which policy definition provided the value of a particular field.
@@ -18,6 +23,8 @@ type UploadPolicyDefinition struct {
 	MaxParallelSnapshots    snapshot.SourceInfo `json:"maxParallelSnapshots,omitempty"`
 	MaxParallelFileReads    snapshot.SourceInfo `json:"maxParallelFileReads,omitempty"`
 	ParallelUploadAboveSize snapshot.SourceInfo `json:"parallelUploadAboveSize,omitempty"`
+	MaxUploadBytesPerSecond snapshot.SourceInfo `json:"maxUploadBytesPerSecond,omitempty"`
+	MaxConcurrentUploads    snapshot.SourceInfo `json:"maxConcurrentUploads,omitempty"`
 }
 
 // Merge applies default values from the provided policy.
@@ -25,6 +32,8 @@ func (p *UploadPolicy) Merge(src UploadPolicy, def *UploadPolicyDefinition, si s
 	mergeOptionalInt(&p.MaxParallelSnapshots, src.MaxParallelSnapshots, &def.MaxParallelSnapshots, si)
 	mergeOptionalInt(&p.MaxParallelFileReads, src.MaxParallelFileReads, &def.MaxParallelFileReads, si)
 	mergeOptionalInt64(&p.ParallelUploadAboveSize, src.ParallelUploadAboveSize, &def.ParallelUploadAboveSize, si)
+	mergeOptionalInt64(&p.MaxUploadBytesPerSecond, src.MaxUploadBytesPerSecond, &def.MaxUploadBytesPerSecond, si)
+	mergeOptionalInt(&p.MaxConcurrentUploads, src.MaxConcurrentUploads, &def.MaxConcurrentUploads, si)
 }
 
 // ValidateUploadPolicy returns an error if manual field is set along with Upload fields.
@@ -33,5 +42,13 @@ func ValidateUploadPolicy(si snapshot.SourceInfo, p UploadPolicy) error {
 		return errors.Errorf("max parallel snapshots cannot be specified for paths, only global, username@hostname or @hostname")
 	}
 
+	if p.MaxUploadBytesPerSecond != nil && *p.MaxUploadBytesPerSecond < 0 {
+		return errors.Errorf("max upload bytes per second cannot be negative")
+	}
+
+	if p.MaxConcurrentUploads != nil && *p.MaxConcurrentUploads < 0 {
+		return errors.Errorf("max concurrent uploads cannot be negative")
+	}
+
 	return nil
 }
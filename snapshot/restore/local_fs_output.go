@@ -323,6 +323,11 @@ func isSymlink(e fs.Entry) bool {
 	return ok
 }
 
+func isRegularFile(e fs.Entry) bool {
+	_, ok := e.(fs.File)
+	return ok && !isSymlink(e)
+}
+
 func (o *FilesystemOutput) maybeIgnorePermissionError(err error) error {
 	if o.IgnorePermissionErrors && os.IsPermission(err) {
 		return nil
@@ -412,18 +417,28 @@ func write(targetPath string, r fs.Reader, size int64, c streamCopier) error {
 	return nil
 }
 
-func (o *FilesystemOutput) copyFileContent(ctx context.Context, targetPath string, f fs.File, progressCb FileWriteProgress) error {
+// checkFileTarget returns an error if the file at the provided path must not be overwritten.
+func (o *FilesystemOutput) checkFileTarget(ctx context.Context, targetPath string) error {
 	switch _, err := os.Stat(targetPath); {
-	case os.IsNotExist(err): // copy file below
+	case os.IsNotExist(err):
+		return nil
 	case err == nil:
 		if !o.OverwriteFiles {
 			return errors.Errorf("unable to create %q, it already exists", targetPath)
 		}
 
 		log(ctx).Debugf("Overwriting existing file: %v", targetPath)
+
+		return nil
 	default:
 		return errors.Wrap(err, "failed to stat "+targetPath)
 	}
+}
+
+func (o *FilesystemOutput) copyFileContent(ctx context.Context, targetPath string, f fs.File, progressCb FileWriteProgress) error {
+	if err := o.checkFileTarget(ctx, targetPath); err != nil {
+		return err
+	}
 
 	r, err := f.Open(ctx)
 	if err != nil {
@@ -447,6 +462,112 @@ func (o *FilesystemOutput) copyFileContent(ctx context.Context, targetPath strin
 	return write(targetPath, wr, f.Size(), o.copier)
 }
 
+// rangedFile is a file being restored whose contents are written in ranges, possibly by multiple workers
+// in parallel, each reading its range of the snapshot file independently.
+type rangedFile struct {
+	o     *FilesystemOutput
+	entry fs.File
+	path  string // path of the restored file
+	f     *os.File
+}
+
+// beginRangedFile creates the file at the provided relative path with the size of the provided file, to be
+// written with writeRange() and completed with finish() or abort(). When writing files atomically, a temporary
+// file is written instead, which replaces the target file in finish().
+func (o *FilesystemOutput) beginRangedFile(ctx context.Context, relativePath string, e fs.File) (*rangedFile, error) {
+	path := filepath.Join(o.TargetPath, filepath.FromSlash(relativePath))
+
+	if err := o.checkFileTarget(ctx, path); err != nil {
+		return nil, errors.Wrap(err, "error creating file")
+	}
+
+	targetPath := atomicfile.MaybePrefixLongFilenameOnWindows(path)
+
+	var (
+		f   *os.File
+		err error
+	)
+
+	if o.WriteFilesAtomically {
+		f, err = os.CreateTemp(filepath.Dir(targetPath), filepath.Base(targetPath))
+	} else {
+		f, err = os.OpenFile(targetPath, os.O_RDWR|os.O_CREATE|os.O_TRUNC, 0o600) //nolint:gosec,mnd
+	}
+
+	if err != nil {
+		return nil, errors.Wrap(err, "error creating file")
+	}
+
+	if err := f.Truncate(e.Size()); err != nil {
+		f.Close() //nolint:errcheck
+
+		return nil, errors.Wrap(err, "error setting file size")
+	}
+
+	return &rangedFile{o: o, entry: e, path: path, f: f}, nil
+}
+
+// writeRange writes the provided range of the file.
+func (r *rangedFile) writeRange(ctx context.Context, offset, length int64, progressCb FileWriteProgress) error {
+	rd, err := r.entry.Open(ctx)
+	if err != nil {
+		return errors.Wrap(err, "unable to open snapshot file for "+r.path)
+	}
+	defer rd.Close() //nolint:errcheck
+
+	if _, err := rd.Seek(offset, io.SeekStart); err != nil {
+		return errors.Wrapf(err, "unable to seek to %v in snapshot file for %v", offset, r.path)
+	}
+
+	wr := &progressReportingReader{
+		r:  rd,
+		cb: progressCb,
+	}
+
+	if _, err := r.o.copier(io.NewOffsetWriter(r.f, offset), io.LimitReader(wr, length)); err != nil {
+		return errors.Wrapf(err, "cannot write data to file %q", r.f.Name())
+	}
+
+	return nil
+}
+
+// finish completes writing of the file after all ranges have been written.
+func (r *rangedFile) finish() error {
+	if r.o.WriteFilesAtomically {
+		if err := r.f.Sync(); err != nil {
+			r.abort()
+			return errors.Wrap(err, "error flushing file")
+		}
+	}
+
+	if err := r.f.Close(); err != nil {
+		r.abort()
+		return errors.Wrap(err, "error closing file")
+	}
+
+	if r.o.WriteFilesAtomically {
+		if err := atomicfile.Replace(r.f.Name(), r.path); err != nil {
+			r.abort()
+			return errors.Wrap(err, "error replacing file")
+		}
+	}
+
+	if err := r.o.setAttributes(r.path, r.entry, os.FileMode(0)); err != nil {
+		return errors.Wrap(err, "error setting attributes")
+	}
+
+	return SafeRemoveAll(r.path)
+}
+
+// abort closes the file after a failure, removing it if it's a temporary file.
+func (r *rangedFile) abort() {
+	r.f.Close() //nolint:errcheck
+
+	if r.o.WriteFilesAtomically {
+		os.Remove(r.f.Name()) //nolint:errcheck
+	}
+}
+
 func isEmptyDirectory(name string) (bool, error) {
 	f, err := os.Open(name) //nolint:gosec
 	if err != nil {
@@ -4,19 +4,28 @@
 	"context"
 	"path"
 	"runtime"
+	"sync"
 	"sync/atomic"
 
 	"github.com/pkg/errors"
+	"golang.org/x/sync/errgroup"
 
 	"github.com/kopia/kopia/fs"
 	"github.com/kopia/kopia/internal/parallelwork"
 	"github.com/kopia/kopia/repo"
 	"github.com/kopia/kopia/repo/logging"
+	"github.com/kopia/kopia/repo/object"
 	"github.com/kopia/kopia/snapshot"
+	"github.com/kopia/kopia/snapshot/snapshotfs"
 )
 
 var log = logging.Module("restore")
 
+const (
+	defaultFileRangeSize    = 16 << 20
+	defaultMaxPrefetchBytes = 256 << 20
+)
+
 // FileWriteProgress is a callback used to report amount of data sent to the output.
 type FileWriteProgress func(chunkSize int64)
 
@@ -33,6 +42,14 @@ type Output interface {
 	Close(ctx context.Context) error
 }
 
+// PoolStats represents statistics of a single restore worker pool.
+type PoolStats struct {
+	Workers   int   `json:"workers"`
+	Enqueued  int64 `json:"enqueued"`
+	Active    int64 `json:"active"`
+	Completed int64 `json:"completed"`
+}
+
 // Stats represents restore statistics.
 type Stats struct {
 	RestoredTotalFileSize int64
@@ -47,6 +64,10 @@ type Stats struct {
 	EnqueuedSymlinkCount int32
 	SkippedCount         int32
 	IgnoredErrorCount    int32
+
+	MetadataPool PoolStats // creation of directories and symlinks, as well as writing of files without a separate write pool
+	DownloadPool PoolStats // prefetching of file contents, in batches
+	WritePool    PoolStats // writing of files and ranges of large files
 }
 
 // stats represents restore statistics.
@@ -88,11 +109,16 @@ func (s *statsInternal) clone() Stats {
 type Options struct {
 	// NOTE: this structure is passed as-is from the UI, make sure to add
 	// required bindings in the UI.
-	Parallel               int   `json:"parallel"`
+	Parallel               int   `json:"parallel"`          // total number of restore workers
+	ParallelDownloads      int   `json:"parallelDownloads"` // number of batches of file contents downloaded in parallel, implies Prefetch
+	ParallelWrites         int   `json:"parallelWrites"`    // number of the Parallel workers dedicated to writing files, 0 means all workers do everything
+	MaxPrefetchBytes       int64 `json:"maxPrefetchBytes"`  // maximum total size of files prefetched ahead of being written
+	FileRangeSize          int64 `json:"fileRangeSize"`     // size of ranges of large files which are written in parallel
 	Incremental            bool  `json:"incremental"`
 	IgnoreErrors           bool  `json:"ignoreErrors"`
 	RestoreDirEntryAtDepth int32 `json:"restoreDirEntryAtDepth"`
 	MinSizeForPlaceholder  int32 `json:"minSizeForPlaceholder"`
+	Prefetch               bool  `json:"prefetch"` // prefetch contents of files in each directory before restoring them
 
 	ProgressCallback ProgressCallback `json:"-"`
 	Cancel           chan struct{}    `json:"-"` // channel that can be externally closed to signal cancellation
@@ -112,27 +138,71 @@ func Entry(ctx context.Context, rep repo.Repository, output Output, rootEntry fs
 		progressCallback: options.ProgressCallback,
 	}
 
+	if options.Prefetch || options.ParallelDownloads > 0 {
+		maxBytes := options.MaxPrefetchBytes
+		if maxBytes == 0 {
+			maxBytes = defaultMaxPrefetchBytes
+		}
+
+		c.prefetcher = snapshotfs.NewObjectPrefetcher(rep, snapshotfs.ObjectPrefetcherOptions{
+			Parallelism: options.ParallelDownloads,
+			MaxBytes:    maxBytes,
+		})
+		defer c.prefetcher.Close(ctx)
+	}
+
+	parallel := options.Parallel
+	if parallel == 0 {
+		parallel = runtime.NumCPU()
+	}
+
+	if !output.Parallelizable() {
+		// all work must be done sequentially by a single worker.
+		parallel = 1
+	}
+
+	c.fileq = c.q
+	c.metadataWorkers = parallel
+
+	// dedicate some of the workers to writing files, leaving at least one for directories and symlinks.
+	if w := min(options.ParallelWrites, parallel-1); w > 0 {
+		c.fileq = parallelwork.NewQueue()
+		c.writeWorkers = w
+		c.metadataWorkers = parallel - w
+	}
+
+	if fo, ok := output.(*FilesystemOutput); ok && max(c.metadataWorkers, c.writeWorkers) > 1 {
+		c.rangedOutput = fo
+
+		c.fileRangeSize = options.FileRangeSize
+		if c.fileRangeSize <= 0 {
+			c.fileRangeSize = defaultFileRangeSize
+		}
+	}
+
 	c.q.ProgressCallback = func(ctx context.Context, enqueued, active, completed int64) {
+		c.metadataPool.update(enqueued, active, completed)
 		c.reportProgress(ctx)
 	}
 
+	if c.fileq != c.q {
+		c.fileq.ProgressCallback = func(ctx context.Context, enqueued, active, completed int64) {
+			c.writePool.update(enqueued, active, completed)
+			c.reportProgress(ctx)
+		}
+	}
+
 	// Control the depth of a restore. Default (options.MaxDepth = 0) is to restore to full depth.
 	currentdepth := int32(0)
 
-	c.q.EnqueueFront(ctx, func() error {
+	c.maybePrefetch(ctx, rootEntry, "", currentdepth, options.RestoreDirEntryAtDepth)
+	c.prefetcher.Flush(ctx)
+
+	c.queueFor(rootEntry).EnqueueFront(ctx, func() error {
 		return errors.Wrap(c.copyEntry(ctx, rootEntry, "", currentdepth, options.RestoreDirEntryAtDepth, func() error { return nil }), "error copying")
 	})
 
-	numWorkers := options.Parallel
-	if numWorkers == 0 {
-		numWorkers = runtime.NumCPU()
-	}
-
-	if !output.Parallelizable() {
-		numWorkers = 1
-	}
-
-	if err := c.q.Process(ctx, numWorkers); err != nil {
+	if err := c.process(ctx); err != nil {
 		return Stats{}, errors.Wrap(err, "restore error")
 	}
 
@@ -140,28 +210,118 @@ func Entry(ctx context.Context, rep repo.Repository, output Output, rootEntry fs
 		return Stats{}, errors.Wrap(err, "error closing output")
 	}
 
-	return c.stats.clone(), nil
+	return c.currentStats(), nil
 }
 
 type copier struct {
 	stats         statsInternal
 	output        Output
 	shallowoutput Output
-	q             *parallelwork.Queue
+	q             *parallelwork.Queue // directories and symlinks
+	fileq         *parallelwork.Queue // files, same as q if output is not parallelizable
 	incremental   bool
 	ignoreErrors  bool
 	cancel        chan struct{}
+	prefetcher    *snapshotfs.ObjectPrefetcher // nil if not prefetching
+	rangedOutput  *FilesystemOutput            // nil if large files are not written in ranges
+	fileRangeSize int64
+
+	metadataWorkers int
+	writeWorkers    int
+	metadataPool    poolCounters
+	writePool       poolCounters
 
 	progressCallback ProgressCallback
 }
 
+// process runs the metadata and file write worker pools until all work has been completed.
+func (c *copier) process(ctx context.Context) error {
+	if c.fileq == c.q {
+		//nolint:wrapcheck
+		return c.q.Process(ctx, c.metadataWorkers)
+	}
+
+	// files are only enqueued by metadata workers, keep file workers running until they are all done.
+	releaseFileQueue := c.fileq.Hold()
+
+	eg, ctx := errgroup.WithContext(ctx)
+
+	eg.Go(func() error {
+		defer releaseFileQueue()
+
+		//nolint:wrapcheck
+		return c.q.Process(ctx, c.metadataWorkers)
+	})
+
+	eg.Go(func() error {
+		//nolint:wrapcheck
+		return c.fileq.Process(ctx, c.writeWorkers)
+	})
+
+	//nolint:wrapcheck
+	return eg.Wait()
+}
+
+// queueFor returns the queue that the provided entry should be restored by.
+func (c *copier) queueFor(e fs.Entry) *parallelwork.Queue {
+	if isRegularFile(e) {
+		return c.fileq
+	}
+
+	return c.q
+}
+
+func (c *copier) currentStats() Stats {
+	s := c.stats.clone()
+
+	s.MetadataPool = c.metadataPool.stats(c.metadataWorkers)
+	s.WritePool = c.writePool.stats(c.writeWorkers)
+
+	ps := c.prefetcher.Stats()
+	s.DownloadPool = PoolStats{
+		Workers:   c.prefetcher.Parallelism(),
+		Enqueued:  ps.Enqueued,
+		Active:    ps.Active,
+		Completed: ps.Completed,
+	}
+
+	return s
+}
+
+// poolCounters holds the most recent counters reported by a work queue.
+type poolCounters struct {
+	enqueued  atomic.Int64
+	active    atomic.Int64
+	completed atomic.Int64
+}
+
+func (p *poolCounters) update(enqueued, active, completed int64) {
+	p.enqueued.Store(enqueued)
+	p.active.Store(active)
+	p.completed.Store(completed)
+}
+
+func (p *poolCounters) stats(workers int) PoolStats {
+	return PoolStats{
+		Workers:   workers,
+		Enqueued:  p.enqueued.Load(),
+		Active:    p.active.Load(),
+		Completed: p.completed.Load(),
+	}
+}
+
 func (c *copier) reportProgress(ctx context.Context) {
 	if c.progressCallback != nil {
-		c.progressCallback(ctx, c.stats.clone())
+		c.progressCallback(ctx, c.currentStats())
 	}
 }
 
 func (c *copier) copyEntry(ctx context.Context, e fs.Entry, targetPath string, currentdepth, maxdepth int32, onCompletion func() error) error {
+	if oid, ok := e.(object.HasObjectID); ok && isRegularFile(e) {
+		// release prefetched contents of the file once it's been restored or skipped.
+		defer c.prefetcher.Done(ctx, oid.ObjectID())
+	}
+
 	if c.cancel != nil {
 		select {
 		case <-c.cancel:
@@ -193,7 +353,10 @@ func (c *copier) copyEntry(ctx context.Context, e fs.Entry, targetPath string, c
 		}
 	}
 
-	err := c.copyEntryInternal(ctx, e, targetPath, currentdepth, maxdepth, onCompletion)
+	return c.maybeIgnoreError(ctx, c.copyEntryInternal(ctx, e, targetPath, currentdepth, maxdepth, onCompletion), targetPath)
+}
+
+func (c *copier) maybeIgnoreError(ctx context.Context, err error, targetPath string) error {
 	if err == nil {
 		return nil
 	}
@@ -216,6 +379,10 @@ func (c *copier) copyEntryInternal(ctx context.Context, e fs.Entry, targetPath s
 	case fs.File:
 		log(ctx).Debugf("file: '%v'", targetPath)
 
+		if currentdepth <= maxdepth && c.writesRanges(e) {
+			return c.copyFileRanges(ctx, e, targetPath, onCompletion)
+		}
+
 		bytesExpected := e.Size()
 		bytesWritten := int64(0)
 		progressCallback := func(chunkSize int64) {
@@ -254,6 +421,80 @@ func (c *copier) copyEntryInternal(ctx context.Context, e fs.Entry, targetPath s
 	}
 }
 
+// writesRanges returns true if the file is large enough to be written in ranges by multiple workers in parallel.
+func (c *copier) writesRanges(f fs.File) bool {
+	return c.rangedOutput != nil && f.Size() >= 2*c.fileRangeSize
+}
+
+// copyFileRanges enqueues writing of ranges of the file, each reading its range of the file independently,
+// and completes the file once all ranges have been written.
+func (c *copier) copyFileRanges(ctx context.Context, f fs.File, targetPath string, onCompletion parallelwork.CallbackFunc) error {
+	rf, err := c.rangedOutput.beginRangedFile(ctx, targetPath, f)
+	if err != nil {
+		return errors.Wrap(err, "copy file")
+	}
+
+	var (
+		mu           sync.Mutex
+		firstErr     error
+		bytesWritten atomic.Int64
+	)
+
+	progressCallback := func(chunkSize int64) {
+		bytesWritten.Add(chunkSize)
+		c.stats.RestoredTotalFileSize.Add(chunkSize)
+		c.reportProgress(ctx)
+	}
+
+	size := f.Size()
+	numRanges := int((size + c.fileRangeSize - 1) / c.fileRangeSize)
+
+	onRangeCompletion := parallelwork.OnNthCompletion(numRanges, func() error {
+		mu.Lock()
+		err := firstErr
+		mu.Unlock()
+
+		if err != nil {
+			rf.abort()
+			return c.maybeIgnoreError(ctx, errors.Wrap(err, "copy file"), targetPath)
+		}
+
+		if err := rf.finish(); err != nil {
+			return c.maybeIgnoreError(ctx, errors.Wrap(err, "copy file"), targetPath)
+		}
+
+		c.stats.RestoredFileCount.Add(1)
+		c.stats.RestoredTotalFileSize.Add(size - bytesWritten.Load())
+
+		return onCompletion()
+	})
+
+	for offset := int64(0); offset < size; offset += c.fileRangeSize {
+		length := min(c.fileRangeSize, size-offset)
+
+		// ranges are enqueued at the front, so that files are completed before others are started.
+		c.fileq.EnqueueFront(ctx, func() error {
+			mu.Lock()
+			failed := firstErr != nil
+			mu.Unlock()
+
+			if !failed {
+				if err := rf.writeRange(ctx, offset, length, progressCallback); err != nil {
+					mu.Lock()
+					if firstErr == nil {
+						firstErr = err
+					}
+					mu.Unlock()
+				}
+			}
+
+			return onRangeCompletion()
+		})
+	}
+
+	return nil
+}
+
 func (c *copier) copyDirectory(ctx context.Context, d fs.Directory, targetPath string, currentdepth, maxdepth int32, onCompletion parallelwork.CallbackFunc) error {
 	c.stats.RestoredDirCount.Add(1)
 
@@ -283,6 +524,35 @@ func (c *copier) copyDirectory(ctx context.Context, d fs.Directory, targetPath s
 	}), "copy directory contents")
 }
 
+// maybePrefetch schedules prefetching of contents of the file, unless it won't be restored from them,
+// because it's restored as a placeholder, skipped since it already exists or written in ranges.
+func (c *copier) maybePrefetch(ctx context.Context, e fs.Entry, targetPath string, currentdepth, maxdepth int32) {
+	if c.prefetcher == nil || currentdepth > maxdepth {
+		return
+	}
+
+	f, ok := e.(fs.File)
+	if !ok {
+		return
+	}
+
+	oid, ok := f.(object.HasObjectID)
+	if !ok || isSymlink(f) {
+		return
+	}
+
+	if c.writesRanges(f) {
+		// ranges of large files are read directly, in parallel.
+		return
+	}
+
+	if c.incremental && c.output.FileExists(ctx, targetPath, f) {
+		return
+	}
+
+	c.prefetcher.AddWithLength(ctx, oid.ObjectID(), f.Size())
+}
+
 func (c *copier) copyDirectoryContent(ctx context.Context, d fs.Directory, targetPath string, currentdepth, maxdepth int32, onCompletion parallelwork.CallbackFunc) error {
 	entries, err := fs.GetAllEntries(ctx, d)
 	if err != nil {
@@ -311,11 +581,16 @@ func (c *copier) copyDirectoryContent(ctx context.Context, d fs.Directory, targe
 
 			c.stats.EnqueuedTotalFileSize.Add(e.Size())
 
-			c.q.EnqueueBack(ctx, func() error {
+			c.maybePrefetch(ctx, e, path.Join(targetPath, e.Name()), currentdepth, maxdepth)
+
+			c.queueFor(e).EnqueueBack(ctx, func() error {
 				return c.copyEntry(ctx, e, path.Join(targetPath, e.Name()), currentdepth, maxdepth, onItemCompletion)
 			})
 		}
 	}
 
+	// start prefetching files of this directory before workers get to them.
+	c.prefetcher.Flush(ctx)
+
 	return nil
 }
@@ -0,0 +1,175 @@
+package restore_test
+
+import (
+	"bytes"
+	"math"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/kopia/kopia/internal/mockfs"
+	"github.com/kopia/kopia/internal/repotesting"
+	"github.com/kopia/kopia/internal/testutil"
+	"github.com/kopia/kopia/snapshot"
+	"github.com/kopia/kopia/snapshot/restore"
+	"github.com/kopia/kopia/snapshot/snapshotfs"
+)
+
+func TestRestorePrefetchSkipsExistingFiles(t *testing.T) {
+	ctx, env := repotesting.NewEnvironment(t, repotesting.FormatNotImportant)
+
+	sourceRoot := mockfs.NewDirectory()
+	sourceRoot.AddFile("file1", []byte{1, 2, 3}, 0o644)
+	sourceRoot.AddDir("dir1", 0o755).AddFile("file2", []byte{1, 2, 3, 4}, 0o644)
+
+	man, err := snapshotfs.NewUploader(env.RepositoryWriter).Upload(ctx, sourceRoot, nil, snapshot.SourceInfo{})
+	require.NoError(t, err)
+	require.NoError(t, env.RepositoryWriter.Flush(ctx))
+
+	rootEntry, err := snapshotfs.SnapshotRoot(env.Repository, man)
+	require.NoError(t, err)
+
+	output := &restore.FilesystemOutput{
+		TargetPath:           testutil.TempDirectory(t),
+		OverwriteDirectories: true,
+		OverwriteFiles:       true,
+		SkipOwners:           true,
+	}
+	require.NoError(t, output.Init(ctx))
+
+	opts := restore.Options{
+		RestoreDirEntryAtDepth: math.MaxInt32,
+		Prefetch:               true,
+	}
+
+	st, err := restore.Entry(ctx, env.Repository, output, rootEntry, opts)
+	require.NoError(t, err)
+	require.EqualValues(t, 2, st.RestoredFileCount)
+	require.Positive(t, st.DownloadPool.Enqueued)
+
+	// all files exist, so none of them should be prefetched.
+	opts.Incremental = true
+
+	st, err = restore.Entry(ctx, env.Repository, output, rootEntry, opts)
+	require.NoError(t, err)
+	require.EqualValues(t, 2, st.SkippedCount)
+	require.Zero(t, st.DownloadPool.Enqueued)
+}
+
+func TestRestoreLargeFileInRanges(t *testing.T) {
+	ctx, env := repotesting.NewEnvironment(t, repotesting.FormatNotImportant)
+
+	data := make([]byte, 1<<20+12345)
+	for i := range data {
+		data[i] = byte(i * 31 / 7)
+	}
+
+	// leave a hole to exercise sparse writes.
+	clear(data[300000:500000])
+
+	sourceRoot := mockfs.NewDirectory()
+	sourceRoot.AddFile("large", data, 0o644)
+	sourceRoot.AddFile("small", []byte{1, 2, 3}, 0o644)
+
+	man, err := snapshotfs.NewUploader(env.RepositoryWriter).Upload(ctx, sourceRoot, nil, snapshot.SourceInfo{})
+	require.NoError(t, err)
+	require.NoError(t, env.RepositoryWriter.Flush(ctx))
+
+	rootEntry, err := snapshotfs.SnapshotRoot(env.Repository, man)
+	require.NoError(t, err)
+
+	for _, tc := range []struct {
+		name   string
+		output restore.FilesystemOutput
+	}{
+		{"regular", restore.FilesystemOutput{}},
+		{"atomic", restore.FilesystemOutput{WriteFilesAtomically: true}},
+		{"sparse", restore.FilesystemOutput{WriteSparseFiles: true}},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			output := tc.output
+			output.TargetPath = testutil.TempDirectory(t)
+			output.OverwriteDirectories = true
+			output.OverwriteFiles = true
+			output.SkipOwners = true
+			require.NoError(t, output.Init(ctx))
+
+			st, err := restore.Entry(ctx, env.Repository, &output, rootEntry, restore.Options{
+				Parallel:               4,
+				ParallelWrites:         2,
+				FileRangeSize:          64 << 10,
+				RestoreDirEntryAtDepth: math.MaxInt32,
+				Prefetch:               true,
+			})
+			require.NoError(t, err)
+			require.EqualValues(t, 2, st.RestoredFileCount)
+			require.EqualValues(t, len(data)+3, st.RestoredTotalFileSize)
+
+			// both files and each of the ranges of the large file are written separately.
+			require.EqualValues(t, 2+17, st.WritePool.Completed)
+
+			// the large file is read in ranges, so only the small one is prefetched.
+			require.EqualValues(t, 1, st.DownloadPool.Enqueued)
+
+			got, err := os.ReadFile(filepath.Join(output.TargetPath, "large"))
+			require.NoError(t, err)
+			require.True(t, bytes.Equal(data, got))
+
+			entries, err := os.ReadDir(output.TargetPath)
+			require.NoError(t, err)
+			require.Len(t, entries, 2)
+		})
+	}
+}
+
+func TestRestoreWorkerPools(t *testing.T) {
+	ctx, env := repotesting.NewEnvironment(t, repotesting.FormatNotImportant)
+
+	sourceRoot := mockfs.NewDirectory()
+	sourceRoot.AddFile("file1", []byte{1, 2, 3}, 0o644)
+	sourceRoot.AddDir("dir1", 0o755).AddFile("file2", []byte{1, 2, 3, 4}, 0o644)
+
+	man, err := snapshotfs.NewUploader(env.RepositoryWriter).Upload(ctx, sourceRoot, nil, snapshot.SourceInfo{})
+	require.NoError(t, err)
+	require.NoError(t, env.RepositoryWriter.Flush(ctx))
+
+	rootEntry, err := snapshotfs.SnapshotRoot(env.Repository, man)
+	require.NoError(t, err)
+
+	cases := []struct {
+		parallel, parallelWrites                   int
+		wantMetadata, wantWrites                   int
+		wantMetadataCompleted, wantWritesCompleted int64
+	}{
+		// all workers do everything.
+		{parallel: 4, parallelWrites: 0, wantMetadata: 4, wantWrites: 0, wantMetadataCompleted: 4},
+		{parallel: 4, parallelWrites: 1, wantMetadata: 3, wantWrites: 1, wantMetadataCompleted: 2, wantWritesCompleted: 2},
+		// write workers are part of the total, at least one worker is left for directories and symlinks.
+		{parallel: 4, parallelWrites: 10, wantMetadata: 1, wantWrites: 3, wantMetadataCompleted: 2, wantWritesCompleted: 2},
+		{parallel: 1, parallelWrites: 10, wantMetadata: 1, wantWrites: 0, wantMetadataCompleted: 4},
+	}
+
+	for _, tc := range cases {
+		output := &restore.FilesystemOutput{
+			TargetPath:           testutil.TempDirectory(t),
+			OverwriteDirectories: true,
+			OverwriteFiles:       true,
+			SkipOwners:           true,
+		}
+		require.NoError(t, output.Init(ctx))
+
+		st, err := restore.Entry(ctx, env.Repository, output, rootEntry, restore.Options{
+			Parallel:               tc.parallel,
+			ParallelWrites:         tc.parallelWrites,
+			RestoreDirEntryAtDepth: math.MaxInt32,
+		})
+		require.NoError(t, err)
+		require.EqualValues(t, 2, st.RestoredFileCount)
+		require.Equal(t, tc.wantMetadata, st.MetadataPool.Workers)
+		require.Equal(t, tc.wantWrites, st.WritePool.Workers)
+		require.Equal(t, tc.wantMetadataCompleted, st.MetadataPool.Completed)
+		require.Equal(t, tc.wantWritesCompleted, st.WritePool.Completed)
+	}
+}
@@ -0,0 +1,225 @@
+package snapshotfs
+
+import (
+	"context"
+	"path"
+	"sort"
+
+	"github.com/pkg/errors"
+
+	"github.com/kopia/kopia/fs"
+	"github.com/kopia/kopia/repo/object"
+	"github.com/kopia/kopia/snapshot"
+)
+
+// EntryChangeType describes how an entry differs between two snapshot trees.
+type EntryChangeType string
+
+// Supported entry change types.
+const (
+	EntryAdded    EntryChangeType = "added"
+	EntryRemoved  EntryChangeType = "removed"
+	EntryModified EntryChangeType = "modified"
+)
+
+// Names of entry fields reported in EntryChange.ChangedFields.
+const (
+	ChangedFieldType     = "type"
+	ChangedFieldMode     = "mode"
+	ChangedFieldSize     = "size"
+	ChangedFieldModTime  = "mtime"
+	ChangedFieldOwner    = "owner"
+	ChangedFieldContents = "contents"
+)
+
+// EntryChange describes a single entry that differs between two snapshot trees.
+type EntryChange struct {
+	// Path of the entry relative to the compared roots, using forward slashes. The roots themselves are ".".
+	Path   string          `json:"path"`
+	Change EntryChangeType `json:"change"`
+
+	// Old and New hold metadata of the entry in the first and second tree respectively, nil if the entry does not exist there.
+	Old *snapshot.DirEntry `json:"old,omitempty"`
+	New *snapshot.DirEntry `json:"new,omitempty"`
+
+	// ChangedFields lists the fields that differ for modified entries.
+	ChangedFields []string `json:"changedFields,omitempty"`
+}
+
+// SizeDelta returns the change in size of the entry.
+func (c *EntryChange) SizeDelta() int64 {
+	var d int64
+
+	if c.Old != nil {
+		d -= c.Old.FileSize
+	}
+
+	if c.New != nil {
+		d += c.New.FileSize
+	}
+
+	return d
+}
+
+// Diff compares two snapshot trees and returns the list of entries that were added, removed or modified,
+// in depth-first order sorted by name. Subtrees with identical object IDs are not traversed, contents
+// of added and removed directories are reported individually. Modified directories are only reported
+// when their own metadata differs.
+func Diff(ctx context.Context, rootA, rootB fs.Entry) ([]EntryChange, error) {
+	var result []EntryChange
+
+	if err := diffEntry(ctx, rootA, rootB, ".", &result); err != nil {
+		return nil, err
+	}
+
+	return result, nil
+}
+
+func diffEntry(ctx context.Context, e1, e2 fs.Entry, relPath string, result *[]EntryChange) error {
+	if e1 != nil && e2 != nil && sameObject(e1, e2) {
+		return nil
+	}
+
+	de1, err := maybeDirEntry(e1)
+	if err != nil {
+		return errors.Wrapf(err, "error reading %v", relPath)
+	}
+
+	de2, err := maybeDirEntry(e2)
+	if err != nil {
+		return errors.Wrapf(err, "error reading %v", relPath)
+	}
+
+	dir1, _ := e1.(fs.Directory)
+	dir2, _ := e2.(fs.Directory)
+
+	switch {
+	case e1 == nil:
+		*result = append(*result, EntryChange{Path: relPath, Change: EntryAdded, New: de2})
+
+	case e2 == nil:
+		*result = append(*result, EntryChange{Path: relPath, Change: EntryRemoved, Old: de1})
+
+	default:
+		if changed := changedFields(de1, de2, dir1 == nil && dir2 == nil); len(changed) > 0 {
+			*result = append(*result, EntryChange{Path: relPath, Change: EntryModified, Old: de1, New: de2, ChangedFields: changed})
+		}
+
+		if (dir1 == nil) != (dir2 == nil) {
+			// type change, report contents of the directory as added or removed.
+			if dir1 != nil {
+				return diffDirectories(ctx, dir1, nil, relPath, result)
+			}
+
+			return diffDirectories(ctx, nil, dir2, relPath, result)
+		}
+	}
+
+	if dir1 == nil && dir2 == nil {
+		return nil
+	}
+
+	return diffDirectories(ctx, dir1, dir2, relPath, result)
+}
+
+func diffDirectories(ctx context.Context, dir1, dir2 fs.Directory, relPath string, result *[]EntryChange) error {
+	var entries1, entries2 []fs.Entry
+
+	var err error
+
+	if dir1 != nil {
+		entries1, err = fs.GetAllEntries(ctx, dir1)
+		if err != nil {
+			return errors.Wrapf(err, "unable to read first directory %v", relPath)
+		}
+	}
+
+	if dir2 != nil {
+		entries2, err = fs.GetAllEntries(ctx, dir2)
+		if err != nil {
+			return errors.Wrapf(err, "unable to read second directory %v", relPath)
+		}
+	}
+
+	e1byname := map[string]fs.Entry{}
+	e2byname := map[string]fs.Entry{}
+
+	var names []string
+
+	for _, e1 := range entries1 {
+		e1byname[e1.Name()] = e1
+		names = append(names, e1.Name())
+	}
+
+	for _, e2 := range entries2 {
+		e2byname[e2.Name()] = e2
+
+		if e1byname[e2.Name()] == nil {
+			names = append(names, e2.Name())
+		}
+	}
+
+	sort.Strings(names)
+
+	for _, name := range names {
+		if err := diffEntry(ctx, e1byname[name], e2byname[name], path.Join(relPath, name), result); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func sameObject(e1, e2 fs.Entry) bool {
+	h1, ok1 := e1.(object.HasObjectID)
+	h2, ok2 := e2.(object.HasObjectID)
+
+	return ok1 && ok2 && h1.ObjectID() == h2.ObjectID()
+}
+
+func maybeDirEntry(e fs.Entry) (*snapshot.DirEntry, error) {
+	if e == nil {
+		return nil, nil
+	}
+
+	var oid object.ID
+
+	if h, ok := e.(object.HasObjectID); ok {
+		oid = h.ObjectID()
+	}
+
+	return newDirEntry(e, e.Name(), oid)
+}
+
+// changedFields returns the names of fields that differ between two entries, compareContents
+// indicates whether object IDs should be compared, which is only meaningful for non-directories.
+func changedFields(de1, de2 *snapshot.DirEntry, compareContents bool) []string {
+	var result []string
+
+	if de1.Type != de2.Type {
+		result = append(result, ChangedFieldType)
+	}
+
+	if de1.Permissions != de2.Permissions {
+		result = append(result, ChangedFieldMode)
+	}
+
+	// directory sizes are derived from their contents, which are compared separately.
+	if de1.FileSize != de2.FileSize && de1.Type != snapshot.EntryTypeDirectory {
+		result = append(result, ChangedFieldSize)
+	}
+
+	if !de1.ModTime.Equal(de2.ModTime) {
+		result = append(result, ChangedFieldModTime)
+	}
+
+	if de1.UserID != de2.UserID || de1.GroupID != de2.GroupID {
+		result = append(result, ChangedFieldOwner)
+	}
+
+	if compareContents && de1.ObjectID != de2.ObjectID {
+		result = append(result, ChangedFieldContents)
+	}
+
+	return result
+}
@@ -0,0 +1,91 @@
+package snapshotfs_test
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/kopia/kopia/fs"
+	"github.com/kopia/kopia/internal/mockfs"
+	"github.com/kopia/kopia/internal/repotesting"
+	"github.com/kopia/kopia/snapshot"
+	"github.com/kopia/kopia/snapshot/snapshotfs"
+)
+
+func TestDiff(t *testing.T) {
+	ctx, env := repotesting.NewEnvironment(t, repotesting.FormatNotImportant)
+
+	sourceRoot := mockfs.NewDirectory()
+	dir1 := sourceRoot.AddDir("dir1", 0o755)
+	dir2 := sourceRoot.AddDir("dir2", 0o755)
+
+	dir1.AddFile("file11", []byte{1, 2, 3}, 0o644)
+	dir2.AddFile("file21", []byte{1, 2, 3, 4}, 0o644)
+	dir2.AddFile("file22", []byte{1, 2}, 0o644)
+	sourceRoot.AddFile("file3", []byte{1}, 0o644)
+
+	u := snapshotfs.NewUploader(env.RepositoryWriter)
+
+	uploadRoot := func() fs.Entry {
+		t.Helper()
+
+		man, err := u.Upload(ctx, sourceRoot, nil, snapshot.SourceInfo{})
+		require.NoError(t, err)
+
+		root, err := snapshotfs.SnapshotRoot(env.RepositoryWriter, man)
+		require.NoError(t, err)
+
+		return root
+	}
+
+	root1 := uploadRoot()
+
+	dir1.Remove("file11")
+	dir2.Remove("file21")
+	dir2.AddFile("file21", []byte{4, 3, 2, 1}, 0o644)
+	dir2.Remove("file22")
+	dir2.AddFile("file22", []byte{1, 2, 3, 4, 5}, 0o600)
+	sourceRoot.AddDir("dir3", 0o755).AddFile("file31", []byte{1, 2, 3}, 0o644)
+
+	root2 := uploadRoot()
+
+	changes, err := snapshotfs.Diff(ctx, root1, root1)
+	require.NoError(t, err)
+	require.Empty(t, changes)
+
+	changes, err = snapshotfs.Diff(ctx, root1, root2)
+	require.NoError(t, err)
+
+	type change struct {
+		path     string
+		change   snapshotfs.EntryChangeType
+		fields   []string
+		sizeDiff int64
+	}
+
+	var got []change
+	for _, c := range changes {
+		got = append(got, change{c.Path, c.Change, c.ChangedFields, c.SizeDelta()})
+	}
+
+	require.Equal(t, []change{
+		{"dir1/file11", snapshotfs.EntryRemoved, nil, -3},
+		{"dir2/file21", snapshotfs.EntryModified, []string{snapshotfs.ChangedFieldContents}, 0},
+		{"dir2/file22", snapshotfs.EntryModified, []string{snapshotfs.ChangedFieldMode, snapshotfs.ChangedFieldSize, snapshotfs.ChangedFieldContents}, 3},
+		{"dir3", snapshotfs.EntryAdded, nil, 3},
+		{"dir3/file31", snapshotfs.EntryAdded, nil, 3},
+	}, got)
+
+	require.Equal(t, snapshot.EntryTypeDirectory, changes[3].New.Type)
+	require.Nil(t, changes[3].Old)
+	require.NotEmpty(t, changes[4].New.ObjectID)
+
+	// reverse comparison reports the opposite changes.
+	changes, err = snapshotfs.Diff(ctx, root2, root1)
+	require.NoError(t, err)
+	require.Len(t, changes, 5)
+	require.Equal(t, "dir1/file11", changes[0].Path)
+	require.Equal(t, snapshotfs.EntryAdded, changes[0].Change)
+	require.Equal(t, snapshotfs.EntryRemoved, changes[3].Change)
+	require.Equal(t, snapshotfs.EntryRemoved, changes[4].Change)
+}
@@ -0,0 +1,262 @@
+package snapshotfs
+
+import (
+	"context"
+	"path/filepath"
+
+	"github.com/pkg/errors"
+
+	"github.com/kopia/kopia/fs"
+	"github.com/kopia/kopia/fs/ignorefs"
+	"github.com/kopia/kopia/repo"
+	"github.com/kopia/kopia/snapshot"
+	"github.com/kopia/kopia/snapshot/policy"
+)
+
+// DryRunExcludedEntry describes a file or directory which would be excluded from a snapshot.
+type DryRunExcludedEntry struct {
+	Path   string                `json:"path"`
+	IsDir  bool                  `json:"isDir,omitempty"`
+	Size   int64                 `json:"size,omitempty"`
+	Reason ignorefs.IgnoreReason `json:"reason"`
+}
+
+// DryRunResult describes what a snapshot of a directory would contain and upload.
+type DryRunResult struct {
+	DirectoryCount int   `json:"directoryCount"`
+	FileCount      int   `json:"fileCount"`
+	TotalFileSize  int64 `json:"totalFileSize"`
+
+	// files that are unchanged since the previous snapshot and would not be uploaded.
+	UnchangedFileCount int   `json:"unchangedFileCount"`
+	UnchangedFileSize  int64 `json:"unchangedFileSize"`
+
+	// files that are new or changed since the previous snapshot, their size is an upper bound of
+	// the number of bytes that would be uploaded.
+	NewFileCount int   `json:"newFileCount"`
+	NewFileSize  int64 `json:"newFileSize"`
+
+	// DedupEstimated is true when contents of new or changed files were split and hashed to determine how
+	// much of their data is not already present in the repository, which requires direct repository access.
+	DedupEstimated bool `json:"dedupEstimated"`
+
+	// EstimatedUploadSize is the size of the data of new or changed files not already present in the repository
+	// and not duplicated between the files, before compression. Only valid when DedupEstimated is true.
+	EstimatedUploadSize int64 `json:"estimatedUploadSize,omitempty"`
+
+	ExcludedFileCount int   `json:"excludedFileCount"`
+	ExcludedFileSize  int64 `json:"excludedFileSize"`
+	ExcludedDirCount  int   `json:"excludedDirCount"`
+
+	// Excluded contains examples of excluded entries, up to the requested maximum.
+	Excluded []DryRunExcludedEntry `json:"excluded,omitempty"`
+
+	ErrorCount        int `json:"errorCount"`
+	IgnoredErrorCount int `json:"ignoredErrorCount"`
+}
+
+// DryRun walks the provided filesystem entry (file or directory) applying policies the same way as Uploader
+// and reports what a snapshot would contain, without writing anything to the repository. Previous snapshot
+// manifests, when provided, are used to determine which files are unchanged and would not be uploaded.
+func DryRun(
+	ctx context.Context,
+	rep repo.Repository,
+	source fs.Entry,
+	policyTree *policy.Tree,
+	maxExcludedEntries int,
+	previousManifests ...*snapshot.Manifest,
+) (*DryRunResult, error) {
+	res := &DryRunResult{}
+
+	dr := &dryRunner{
+		rep:                rep,
+		res:                res,
+		maxExcludedEntries: maxExcludedEntries,
+	}
+
+	if dr2, ok := rep.(repo.DirectRepository); ok {
+		est, err := newUploadEstimator(ctx, dr2, nil)
+		if err != nil {
+			return nil, err
+		}
+
+		dr.estimator = est
+		res.DedupEstimated = true
+	}
+
+	var previous []fs.Entry
+
+	for _, m := range previousManifests {
+		previous = append(previous, EntryFromDirEntry(rep, m.RootEntry))
+	}
+
+	switch entry := source.(type) {
+	case fs.Directory:
+		var previousDirs []fs.Directory
+
+		for _, p := range previous {
+			if d, ok := p.(fs.Directory); ok {
+				previousDirs = append(previousDirs, d)
+			}
+		}
+
+		onIgnored := func(_ context.Context, relativePath string, e fs.Entry, _ *policy.Tree, reason ignorefs.IgnoreReason) {
+			res.addExcluded(relativePath, e, reason, maxExcludedEntries)
+		}
+
+		wrapped := ignorefs.New(entry, policyTree, ignorefs.ReportIgnoredFilesWithReason(onIgnored))
+
+		if err := dr.directory(ctx, ".", wrapped, policyTree, previousDirs); err != nil {
+			return nil, err
+		}
+
+	case fs.File:
+		unchanged := false
+
+		for _, p := range previous {
+			if metadataEquals(entry, p) {
+				unchanged = true
+			}
+		}
+
+		if err := dr.file(ctx, ".", entry, policyTree, unchanged); err != nil {
+			return nil, err
+		}
+
+	default:
+		return nil, errors.Errorf("unsupported source: %v", source.Name())
+	}
+
+	if dr.estimator != nil {
+		res.EstimatedUploadSize += dr.estimator.result.NewBytes()
+	}
+
+	return res, nil
+}
+
+func (r *DryRunResult) addFile(e fs.Entry, unchanged bool) {
+	r.FileCount++
+	r.TotalFileSize += e.Size()
+
+	if unchanged {
+		r.UnchangedFileCount++
+		r.UnchangedFileSize += e.Size()
+	} else {
+		r.NewFileCount++
+		r.NewFileSize += e.Size()
+	}
+}
+
+func (r *DryRunResult) addExcluded(relativePath string, e fs.Entry, reason ignorefs.IgnoreReason, maxExcludedEntries int) {
+	ee := DryRunExcludedEntry{Path: relativePath, IsDir: e.IsDir(), Reason: reason}
+
+	if e.IsDir() {
+		r.ExcludedDirCount++
+	} else {
+		r.ExcludedFileCount++
+		r.ExcludedFileSize += e.Size()
+		ee.Size = e.Size()
+	}
+
+	if len(r.Excluded) < maxExcludedEntries {
+		r.Excluded = append(r.Excluded, ee)
+	}
+}
+
+// dryRunner walks the directory tree accumulating DryRunResult.
+type dryRunner struct {
+	rep                repo.Repository
+	res                *DryRunResult
+	maxExcludedEntries int
+
+	// when set, contents of new files are split and hashed to estimate deduplication,
+	// the same way as by EstimateUpload().
+	estimator *uploadEstimator
+}
+
+func (dr *dryRunner) directory(ctx context.Context, relativePath string, dir fs.Directory, policyTree *policy.Tree, previous []fs.Directory) error {
+	if err := ctx.Err(); err != nil {
+		//nolint:wrapcheck
+		return err
+	}
+
+	dr.res.DirectoryCount++
+
+	err := fs.IterateEntries(ctx, dir, func(ctx context.Context, child fs.Entry) error {
+		childPath := filepath.Join(relativePath, child.Name())
+
+		switch child := child.(type) {
+		case fs.Directory:
+			return dr.directory(ctx, childPath, child, policyTree.Child(child.Name()), previousChildDirs(ctx, previous, child.Name()))
+
+		case fs.File, fs.StreamingFile:
+			return dr.file(ctx, childPath, child, policyTree, findCachedEntry(ctx, childPath, child, previous, policyTree) != nil)
+		}
+
+		return nil
+	})
+	if err != nil {
+		if ctx.Err() != nil {
+			//nolint:wrapcheck
+			return ctx.Err()
+		}
+
+		dr.addError(ctx, relativePath, err, policyTree.EffectivePolicy().ErrorHandlingPolicy.IgnoreDirectoryErrors.OrDefault(false))
+	}
+
+	return nil
+}
+
+func (dr *dryRunner) file(ctx context.Context, relativePath string, e fs.Entry, policyTree *policy.Tree, unchanged bool) error {
+	dr.res.addFile(e, unchanged)
+
+	if unchanged || dr.estimator == nil {
+		return nil
+	}
+
+	f, ok := e.(fs.File)
+	if !ok {
+		// streaming files can't be read without consuming them, assume all their data is new.
+		dr.res.EstimatedUploadSize += e.Size()
+		return nil
+	}
+
+	pol := policyTree.EffectivePolicy()
+
+	if err := dr.estimator.hashFile(ctx, f, pol); err != nil {
+		if ctx.Err() != nil {
+			//nolint:wrapcheck
+			return ctx.Err()
+		}
+
+		// unreadable files would fail the snapshot or be skipped, just like here.
+		dr.addError(ctx, relativePath, err, pol.ErrorHandlingPolicy.IgnoreFileErrors.OrDefault(false))
+	}
+
+	return nil
+}
+
+func (dr *dryRunner) addError(ctx context.Context, relativePath string, err error, isIgnored bool) {
+	if isIgnored {
+		dr.res.IgnoredErrorCount++
+	} else {
+		dr.res.ErrorCount++
+	}
+
+	uploadLog(ctx).Errorf("error reading %v: %v", relativePath, err)
+}
+
+// previousChildDirs returns subdirectories with the provided name of previous snapshot directories.
+func previousChildDirs(ctx context.Context, previous []fs.Directory, name string) []fs.Directory {
+	var result []fs.Directory
+
+	for _, p := range previous {
+		if e, err := p.Child(ctx, name); err == nil {
+			if d, ok := e.(fs.Directory); ok {
+				result = append(result, d)
+			}
+		}
+	}
+
+	return result
+}
@@ -0,0 +1,49 @@
+package snapshotfs_test
+
+import (
+	"crypto/rand"
+	"slices"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/kopia/kopia/internal/mockfs"
+	"github.com/kopia/kopia/internal/repotesting"
+	"github.com/kopia/kopia/snapshot/policy"
+	"github.com/kopia/kopia/snapshot/snapshotfs"
+)
+
+func TestDryRunEstimatesUploadSizeLikeEstimateUpload(t *testing.T) {
+	ctx, env := repotesting.NewEnvironment(t, repotesting.FormatNotImportant)
+
+	const partSize = 6 << 20
+
+	part := make([]byte, partSize)
+	_, err := rand.Read(part)
+	require.NoError(t, err)
+
+	other := make([]byte, 100000)
+	_, err = rand.Read(other)
+	require.NoError(t, err)
+
+	dir := mockfs.NewDirectory()
+	// the second part of the file is deduplicated against the first one, since parts are split separately.
+	dir.AddFile("parts", slices.Concat(part, part), 0o644)
+	dir.AddFile("small", other, 0o644)
+	dir.AddFile("copy", other, 0o644)
+
+	pol := *policy.DefaultPolicy
+	n := policy.OptionalInt64(partSize)
+	pol.UploadPolicy.ParallelUploadAboveSize = &n
+
+	policyTree := policy.BuildTree(nil, &pol)
+
+	ue, err := snapshotfs.EstimateUpload(ctx, env.RepositoryWriter, dir, policyTree, nil, &fakeProgress{t: t})
+	require.NoError(t, err)
+	require.Equal(t, int64(partSize+len(other)), ue.NewBytes())
+
+	res, err := snapshotfs.DryRun(ctx, env.RepositoryWriter, dir, policyTree, 0)
+	require.NoError(t, err)
+	require.True(t, res.DedupEstimated)
+	require.Equal(t, ue.NewBytes(), res.EstimatedUploadSize)
+}
@@ -0,0 +1,264 @@
+package snapshotfs
+
+import (
+	"context"
+	"io"
+	"path/filepath"
+	"sync"
+
+	"github.com/pkg/errors"
+
+	"github.com/kopia/kopia/fs"
+	"github.com/kopia/kopia/fs/ignorefs"
+	"github.com/kopia/kopia/internal/gather"
+	"github.com/kopia/kopia/repo"
+	"github.com/kopia/kopia/repo/compression"
+	"github.com/kopia/kopia/repo/content"
+	"github.com/kopia/kopia/repo/hashing"
+	"github.com/kopia/kopia/repo/object"
+	"github.com/kopia/kopia/snapshot"
+	"github.com/kopia/kopia/snapshot/policy"
+)
+
+// UploadEstimate describes the amount of data that a snapshot of a directory would upload.
+type UploadEstimate struct {
+	TotalFileCount int64 `json:"totalFileCount"`
+	TotalFileSize  int64 `json:"totalFileSize"`
+
+	// UnchangedFileCount and UnchangedFileSize describe files whose metadata matches one of the
+	// previous snapshots, which the uploader reuses without reading.
+	UnchangedFileCount int64 `json:"unchangedFileCount"`
+	UnchangedFileSize  int64 `json:"unchangedFileSize"`
+
+	// HashedFileCount and HashedFileSize describe new or modified files that must be read and hashed.
+	HashedFileCount int64 `json:"hashedFileCount"`
+	HashedFileSize  int64 `json:"hashedFileSize"`
+
+	// Chunks contains statistics about chunks of hashed files, deduplicated chunks are those
+	// already present in the repository or appearing earlier in the snapshot.
+	Chunks object.WriterStats `json:"chunks"`
+
+	ErrorCount        int `json:"errorCount"`
+	IgnoredErrorCount int `json:"ignoredErrorCount"`
+}
+
+// NewBytes returns the estimated number of bytes, before compression, that need to be uploaded.
+func (e *UploadEstimate) NewBytes() int64 {
+	return e.Chunks.TotalChunkBytes - e.Chunks.DeduplicatedBytes
+}
+
+// EstimateUpload walks the provided directory tree the way the uploader would and estimates the amount of
+// new data that a snapshot would upload. Files matching the previous snapshots are assumed to be unchanged,
+// remaining files are split and hashed and their chunks are looked up in the repository without writing anything.
+func EstimateUpload(ctx context.Context, rep repo.DirectRepository, entry fs.Directory, policyTree *policy.Tree, previousManifests []*snapshot.Manifest, progress EstimateProgress) (*UploadEstimate, error) {
+	ue, err := newUploadEstimator(ctx, rep, progress)
+	if err != nil {
+		return nil, err
+	}
+
+	var prevDirs []fs.Directory
+
+	for _, m := range previousManifests {
+		if d, ok := EntryFromDirEntry(rep, m.RootEntry).(fs.Directory); ok {
+			prevDirs = append(prevDirs, d)
+		}
+	}
+
+	if err := ue.estimateDir(ctx, ".", ignorefs.New(entry, policyTree), policyTree, uniqueDirectories(prevDirs)); err != nil {
+		return &ue.result, err
+	}
+
+	return &ue.result, nil
+}
+
+type uploadEstimator struct {
+	om       *object.Manager
+	progress EstimateProgress
+	result   UploadEstimate
+}
+
+// newUploadEstimator returns an estimator, which hashes files using the object format of the repository
+// and looks up their chunks in the repository without writing anything.
+func newUploadEstimator(ctx context.Context, rep repo.DirectRepository, progress EstimateProgress) (*uploadEstimator, error) {
+	cm := &dedupEstimatingContentManager{
+		Reader:   rep.ContentReader(),
+		hashFunc: rep.ContentReader().ContentFormat().HashFunc(),
+		seen:     map[content.ID]bool{},
+	}
+
+	om, err := object.NewObjectManager(ctx, cm, rep.ObjectFormat(), nil)
+	if err != nil {
+		return nil, errors.Wrap(err, "unable to create object manager")
+	}
+
+	return &uploadEstimator{
+		om:       om,
+		progress: progress,
+	}, nil
+}
+
+func (e *uploadEstimator) estimateDir(ctx context.Context, relativePath string, dir fs.Directory, policyTree *policy.Tree, prevDirs []fs.Directory) error {
+	if !dir.SupportsMultipleIterations() {
+		return nil
+	}
+
+	e.progress.Processing(ctx, relativePath)
+
+	err := fs.IterateEntries(ctx, dir, func(ctx context.Context, child fs.Entry) error {
+		childPath := filepath.Join(relativePath, child.Name())
+		childTree := policyTree.Child(child.Name())
+
+		switch child := child.(type) {
+		case fs.Directory:
+			return e.estimateDir(ctx, childPath, child, childTree, uniqueChildDirectories(ctx, prevDirs, child.Name()))
+
+		case fs.File:
+			return e.estimateFile(ctx, childPath, child, childTree, prevDirs)
+
+		default:
+			return nil
+		}
+	})
+
+	return e.maybeIgnoreError(ctx, relativePath, err, policyTree.EffectivePolicy().ErrorHandlingPolicy.IgnoreDirectoryErrors.OrDefault(false))
+}
+
+func (e *uploadEstimator) estimateFile(ctx context.Context, relativePath string, f fs.File, policyTree *policy.Tree, prevDirs []fs.Directory) error {
+	// see if the context got canceled
+	if err := ctx.Err(); err != nil {
+		//nolint:wrapcheck
+		return err
+	}
+
+	e.result.TotalFileCount++
+	e.result.TotalFileSize += f.Size()
+
+	if findCachedEntry(ctx, relativePath, f, prevDirs, policyTree) != nil {
+		e.result.UnchangedFileCount++
+		e.result.UnchangedFileSize += f.Size()
+
+		return nil
+	}
+
+	e.result.HashedFileCount++
+	e.result.HashedFileSize += f.Size()
+
+	pol := policyTree.EffectivePolicy()
+
+	return e.maybeIgnoreError(ctx, relativePath, e.hashFile(ctx, f, pol), pol.ErrorHandlingPolicy.IgnoreFileErrors.OrDefault(false))
+}
+
+// hashFile splits and hashes the file the same way uploadFileInternal() would, including splitting large files into parts.
+func (e *uploadEstimator) hashFile(ctx context.Context, f fs.File, pol *policy.Policy) error {
+	r, err := f.Open(ctx)
+	if err != nil {
+		return errors.Wrap(err, "unable to open file")
+	}
+	defer r.Close() //nolint:errcheck
+
+	opt := object.WriterOptions{
+		Description:         "FILE:" + f.Name(),
+		Compressor:          pol.CompressionPolicy.CompressorForFile(f),
+		AdaptiveCompression: pol.CompressionPolicy.Adaptive.OrDefault(false),
+		Splitter:            pol.SplitterPolicy.SplitterForFile(f),
+	}
+
+	partSize := pol.UploadPolicy.ParallelUploadAboveSize.OrDefault(-1)
+	if partSize < 0 || f.Size() <= partSize {
+		_, err := e.hashPart(ctx, opt, r)
+
+		return err
+	}
+
+	for {
+		n, err := e.hashPart(ctx, opt, io.LimitReader(r, partSize))
+		if err != nil {
+			return err
+		}
+
+		if n < partSize {
+			return nil
+		}
+	}
+}
+
+func (e *uploadEstimator) hashPart(ctx context.Context, opt object.WriterOptions, r io.Reader) (int64, error) {
+	w := e.om.NewWriter(ctx, opt)
+	defer w.Close() //nolint:errcheck
+
+	n, err := io.Copy(w, r)
+	if err != nil {
+		return n, errors.Wrap(err, "error reading file")
+	}
+
+	if _, err := w.Result(); err != nil {
+		return n, errors.Wrap(err, "error hashing file")
+	}
+
+	e.result.Chunks.Add(w.Stats())
+
+	return n, nil
+}
+
+func (e *uploadEstimator) maybeIgnoreError(ctx context.Context, relativePath string, err error, isIgnored bool) error {
+	if err == nil || errors.Is(err, ctx.Err()) {
+		return err
+	}
+
+	e.progress.Error(ctx, relativePath, err, isIgnored)
+
+	if isIgnored {
+		e.result.IgnoredErrorCount++
+		return nil
+	}
+
+	e.result.ErrorCount++
+
+	return err
+}
+
+// dedupEstimatingContentManager computes content IDs of written contents and reports whether they would
+// have been deduplicated, without writing anything to the repository.
+type dedupEstimatingContentManager struct {
+	content.Reader
+
+	hashFunc hashing.HashFunc
+
+	mu sync.Mutex
+	// +checklocks:mu
+	seen map[content.ID]bool
+}
+
+func (cm *dedupEstimatingContentManager) PrefetchContents(ctx context.Context, contentIDs []content.ID, prefetchHint string) []content.ID {
+	return nil
+}
+
+func (cm *dedupEstimatingContentManager) WriteContent(ctx context.Context, data gather.Bytes, prefix content.IDPrefix, comp compression.HeaderID) (content.ID, error) {
+	contentID, _, err := cm.WriteContentWithDedupInfo(ctx, data, prefix, comp)
+
+	return contentID, err
+}
+
+func (cm *dedupEstimatingContentManager) WriteContentWithDedupInfo(ctx context.Context, data gather.Bytes, prefix content.IDPrefix, comp compression.HeaderID) (content.ID, bool, error) {
+	var hashOutput [hashing.MaxHashSize]byte
+
+	contentID, err := content.IDFromHash(prefix, cm.hashFunc(hashOutput[:0], data))
+	if err != nil {
+		return content.EmptyID, false, errors.Wrap(err, "invalid hash")
+	}
+
+	cm.mu.Lock()
+	defer cm.mu.Unlock()
+
+	if cm.seen[contentID] {
+		return contentID, true, nil
+	}
+
+	cm.seen[contentID] = true
+
+	if bi, err := cm.ContentInfo(ctx, contentID); err == nil && !bi.Deleted {
+		return contentID, true, nil
+	}
+
+	return contentID, false, nil
+}
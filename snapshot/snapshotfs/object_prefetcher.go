@@ -0,0 +1,282 @@
+package snapshotfs
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+
+	"github.com/kopia/kopia/repo"
+	"github.com/kopia/kopia/repo/logging"
+	"github.com/kopia/kopia/repo/object"
+)
+
+var prefetchLog = logging.Module("prefetch")
+
+const (
+	defaultPrefetchBatchSize   = 100
+	defaultPrefetchParallelism = 2
+)
+
+// ObjectPrefetcherOptions provides options for ObjectPrefetcher.
+type ObjectPrefetcherOptions struct {
+	Hint        string // prefetch hint passed to the repository
+	BatchSize   int    // number of objects prefetched together
+	Parallelism int    // number of batches prefetched in parallel
+
+	// MaxBytes limits the total length of objects added with AddWithLength() which have been
+	// scheduled for prefetching, but not yet released with Done(), so that prefetching does not get
+	// so far ahead that prefetched contents are evicted from the cache before they are read.
+	// Zero means no limit.
+	MaxBytes int64
+}
+
+// ObjectPrefetcherStats reports the number of batches scheduled, being prefetched and completed by ObjectPrefetcher.
+type ObjectPrefetcherStats struct {
+	Enqueued  int64
+	Active    int64
+	Completed int64
+}
+
+// ObjectPrefetcher brings contents of objects which are about to be read into the cache in the background,
+// in batches, so that subsequent reads don't need to fetch each content from the storage one at a time.
+//
+// All methods can be called on a nil ObjectPrefetcher, in which case they do nothing.
+type ObjectPrefetcher struct {
+	rep  repo.Repository
+	opts ObjectPrefetcherOptions
+
+	sem chan struct{}
+	wg  sync.WaitGroup
+
+	enqueuedBatches  atomic.Int64
+	activeBatches    atomic.Int64
+	completedBatches atomic.Int64
+
+	mu sync.Mutex
+	// +checklocks:mu
+	pending []object.ID
+	// +checklocks:mu
+	deferred []prefetchItem // objects which did not fit within MaxBytes, in the order they were added
+	// +checklocks:mu
+	scheduled map[object.ID][]int64 // lengths of scheduled objects which have not been released yet
+	// +checklocks:mu
+	scheduledBytes int64
+}
+
+type prefetchItem struct {
+	oid    object.ID
+	length int64
+}
+
+// Add schedules the provided object to be prefetched, which will start once a full batch has been accumulated
+// or when Flush() is called.
+func (p *ObjectPrefetcher) Add(ctx context.Context, oid object.ID) {
+	if p == nil {
+		return
+	}
+
+	p.mu.Lock()
+	p.pending = append(p.pending, oid)
+
+	if len(p.pending) < p.opts.BatchSize {
+		p.mu.Unlock()
+		return
+	}
+
+	batch := p.pending
+	p.pending = nil
+	p.mu.Unlock()
+
+	p.prefetch(ctx, batch, true)
+}
+
+// AddWithLength schedules the provided object of the provided length to be prefetched, like Add(), but
+// only while the total length of scheduled objects is within MaxBytes. Objects which don't fit are
+// deferred until enough objects have been released with Done(), objects longer than MaxBytes are not
+// prefetched at all.
+func (p *ObjectPrefetcher) AddWithLength(ctx context.Context, oid object.ID, length int64) {
+	if p == nil {
+		return
+	}
+
+	if p.opts.MaxBytes <= 0 {
+		p.Add(ctx, oid)
+		return
+	}
+
+	if length > p.opts.MaxBytes {
+		return
+	}
+
+	p.mu.Lock()
+
+	if len(p.deferred) > 0 || p.scheduledBytes+length > p.opts.MaxBytes {
+		p.deferred = append(p.deferred, prefetchItem{oid, length})
+		p.mu.Unlock()
+
+		return
+	}
+
+	p.scheduleLocked(oid, length)
+	p.mu.Unlock()
+
+	p.Add(ctx, oid)
+}
+
+// Done indicates that the provided object, previously added with AddWithLength(), has been read and no
+// longer needs to be kept in the cache, which allows deferred objects to be prefetched. It never blocks.
+func (p *ObjectPrefetcher) Done(ctx context.Context, oid object.ID) {
+	if p == nil || p.opts.MaxBytes <= 0 {
+		return
+	}
+
+	p.mu.Lock()
+
+	if lengths := p.scheduled[oid]; len(lengths) > 0 {
+		p.scheduledBytes -= lengths[0]
+
+		if len(lengths) == 1 {
+			delete(p.scheduled, oid)
+		} else {
+			p.scheduled[oid] = lengths[1:]
+		}
+	} else {
+		// the object has been read before it was prefetched, don't prefetch it anymore.
+		for i, it := range p.deferred {
+			if it.oid == oid {
+				p.deferred = append(p.deferred[:i], p.deferred[i+1:]...)
+				break
+			}
+		}
+	}
+
+	var batch []object.ID
+
+	for len(p.deferred) > 0 && p.scheduledBytes+p.deferred[0].length <= p.opts.MaxBytes {
+		it := p.deferred[0]
+		p.deferred = p.deferred[1:]
+
+		p.scheduleLocked(it.oid, it.length)
+		batch = append(batch, it.oid)
+	}
+
+	p.mu.Unlock()
+
+	if len(batch) > 0 {
+		// callers of Done() may be the ones which release the prefetch slots, never wait for them.
+		p.prefetch(ctx, batch, false)
+	}
+}
+
+// +checklocks:p.mu
+func (p *ObjectPrefetcher) scheduleLocked(oid object.ID, length int64) {
+	if p.scheduled == nil {
+		p.scheduled = map[object.ID][]int64{}
+	}
+
+	p.scheduled[oid] = append(p.scheduled[oid], length)
+	p.scheduledBytes += length
+}
+
+// Flush starts prefetching all objects added so far.
+func (p *ObjectPrefetcher) Flush(ctx context.Context) {
+	if p == nil {
+		return
+	}
+
+	p.mu.Lock()
+	batch := p.pending
+	p.pending = nil
+	p.mu.Unlock()
+
+	if len(batch) > 0 {
+		p.prefetch(ctx, batch, true)
+	}
+}
+
+// Close flushes pending objects and waits for all prefetches to complete.
+func (p *ObjectPrefetcher) Close(ctx context.Context) {
+	if p == nil {
+		return
+	}
+
+	p.Flush(ctx)
+	p.wg.Wait()
+}
+
+// Stats returns the current batch counters.
+func (p *ObjectPrefetcher) Stats() ObjectPrefetcherStats {
+	if p == nil {
+		return ObjectPrefetcherStats{}
+	}
+
+	return ObjectPrefetcherStats{
+		Enqueued:  p.enqueuedBatches.Load(),
+		Active:    p.activeBatches.Load(),
+		Completed: p.completedBatches.Load(),
+	}
+}
+
+// Parallelism returns the maximum number of batches prefetched in parallel.
+func (p *ObjectPrefetcher) Parallelism() int {
+	if p == nil {
+		return 0
+	}
+
+	return p.opts.Parallelism
+}
+
+// prefetch starts prefetching the provided batch in the background. When wait is true, it blocks while
+// the maximum number of batches is already being prefetched, so that the caller does not get too far ahead,
+// otherwise the batch waits for its turn in the background.
+func (p *ObjectPrefetcher) prefetch(ctx context.Context, batch []object.ID, wait bool) {
+	p.enqueuedBatches.Add(1)
+
+	if wait {
+		p.sem <- struct{}{}
+	}
+
+	p.wg.Add(1)
+
+	go func() {
+		defer p.wg.Done()
+
+		if !wait {
+			p.sem <- struct{}{}
+		}
+
+		defer func() { <-p.sem }()
+
+		p.activeBatches.Add(1)
+
+		defer func() {
+			p.activeBatches.Add(-1)
+			p.completedBatches.Add(1)
+		}()
+
+		cids, err := p.rep.PrefetchObjects(ctx, batch, p.opts.Hint)
+		if err != nil {
+			prefetchLog(ctx).Debugf("error prefetching %v objects: %v", len(batch), err)
+			return
+		}
+
+		prefetchLog(ctx).Debugf("prefetched %v contents of %v objects", len(cids), len(batch))
+	}()
+}
+
+// NewObjectPrefetcher creates an ObjectPrefetcher.
+func NewObjectPrefetcher(rep repo.Repository, opts ObjectPrefetcherOptions) *ObjectPrefetcher {
+	if opts.BatchSize <= 0 {
+		opts.BatchSize = defaultPrefetchBatchSize
+	}
+
+	if opts.Parallelism <= 0 {
+		opts.Parallelism = defaultPrefetchParallelism
+	}
+
+	return &ObjectPrefetcher{
+		rep:  rep,
+		opts: opts,
+		sem:  make(chan struct{}, opts.Parallelism),
+	}
+}
@@ -0,0 +1,127 @@
+package snapshotfs_test
+
+import (
+	"context"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/kopia/kopia/internal/testlogging"
+	"github.com/kopia/kopia/repo"
+	"github.com/kopia/kopia/repo/content"
+	"github.com/kopia/kopia/repo/object"
+	"github.com/kopia/kopia/snapshot/snapshotfs"
+)
+
+type prefetchRecordingRepository struct {
+	repo.Repository
+
+	mu      sync.Mutex
+	batches [][]object.ID
+	hints   []string
+}
+
+func (r *prefetchRecordingRepository) PrefetchObjects(ctx context.Context, objectIDs []object.ID, hint string) ([]content.ID, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.batches = append(r.batches, objectIDs)
+	r.hints = append(r.hints, hint)
+
+	return nil, nil
+}
+
+func TestObjectPrefetcher(t *testing.T) {
+	ctx := testlogging.Context(t)
+	r := &prefetchRecordingRepository{}
+
+	p := snapshotfs.NewObjectPrefetcher(r, snapshotfs.ObjectPrefetcherOptions{
+		Hint:      "some-hint",
+		BatchSize: 3,
+	})
+
+	var oids []object.ID
+
+	for i := range 7 {
+		oid := object.DirectObjectID(content.EmptyID)
+		if i%2 == 1 {
+			oid = object.IndirectObjectID(oid)
+		}
+
+		oids = append(oids, oid)
+		p.Add(ctx, oid)
+	}
+
+	p.Close(ctx)
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	require.Len(t, r.batches, 3)
+
+	var all []object.ID
+
+	for i, b := range r.batches {
+		require.Equal(t, "some-hint", r.hints[i])
+
+		all = append(all, b...)
+	}
+
+	require.ElementsMatch(t, oids, all)
+
+	// nil prefetcher does nothing
+	var np *snapshotfs.ObjectPrefetcher
+
+	np.Add(ctx, oids[0])
+	np.Flush(ctx)
+	np.Close(ctx)
+}
+
+func TestObjectPrefetcherMaxBytes(t *testing.T) {
+	ctx := testlogging.Context(t)
+	r := &prefetchRecordingRepository{}
+
+	p := snapshotfs.NewObjectPrefetcher(r, snapshotfs.ObjectPrefetcherOptions{
+		BatchSize: 1,
+		MaxBytes:  100,
+	})
+
+	oid := func(b byte) object.ID {
+		cid, err := content.IDFromHash("", []byte{b, 1, 2, 3, 4, 5, 6, 7, 8, 9, 10, 11, 12, 13, 14, 15})
+		require.NoError(t, err)
+
+		return object.DirectObjectID(cid)
+	}
+
+	a, b, c, d, e, f := oid(1), oid(2), oid(3), oid(4), oid(5), oid(6)
+
+	p.AddWithLength(ctx, a, 60)
+	p.AddWithLength(ctx, b, 60)  // deferred, over budget
+	p.AddWithLength(ctx, c, 200) // larger than budget, never prefetched
+	p.AddWithLength(ctx, d, 10)  // deferred, behind b
+	require.EqualValues(t, 1, p.Stats().Enqueued)
+
+	// releasing a makes room for both deferred objects.
+	p.Done(ctx, a)
+	require.EqualValues(t, 2, p.Stats().Enqueued)
+
+	p.Done(ctx, c)
+	p.Done(ctx, b)
+	p.Done(ctx, d)
+
+	p.AddWithLength(ctx, e, 100)
+	p.AddWithLength(ctx, f, 50) // deferred
+
+	// f is read before it was prefetched, so it's not prefetched anymore.
+	p.Done(ctx, f)
+	p.Done(ctx, e)
+
+	p.Close(ctx)
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	// batches are prefetched in parallel, in no particular order.
+	require.ElementsMatch(t, [][]object.ID{{a}, {b, d}, {e}}, r.batches)
+}
@@ -0,0 +1,247 @@
+package snapshotfs
+
+import (
+	"context"
+	"sort"
+
+	"github.com/pkg/errors"
+
+	"github.com/kopia/kopia/fs"
+	"github.com/kopia/kopia/repo"
+	"github.com/kopia/kopia/repo/object"
+	"github.com/kopia/kopia/snapshot"
+)
+
+// DefaultReadAheadWindow is the default number of bytes prefetched ahead of sequential reads.
+const DefaultReadAheadWindow = 64 << 20
+
+// ReadAheadOptions provides options for WithReadAhead.
+type ReadAheadOptions struct {
+	Window      int64 // number of bytes ahead of the current position of sequential reads to prefetch
+	Parallelism int   // number of prefetch batches running in parallel for each open file
+}
+
+type readAheadContext struct {
+	rep  repo.Repository
+	opts ReadAheadOptions
+}
+
+type readAheadDirectory struct {
+	ctx *readAheadContext
+	fs.Directory
+}
+
+func (d *readAheadDirectory) Child(ctx context.Context, name string) (fs.Entry, error) {
+	e, err := d.Directory.Child(ctx, name)
+	if err != nil {
+		//nolint:wrapcheck
+		return nil, err
+	}
+
+	return d.ctx.wrap(e), nil
+}
+
+func (d *readAheadDirectory) Iterate(ctx context.Context) (fs.DirectoryIterator, error) {
+	inner, err := d.Directory.Iterate(ctx)
+	if err != nil {
+		//nolint:wrapcheck
+		return nil, err
+	}
+
+	return &readAheadDirectoryIterator{d.ctx, inner}, nil
+}
+
+type readAheadDirectoryIterator struct {
+	ctx   *readAheadContext
+	inner fs.DirectoryIterator
+}
+
+func (it *readAheadDirectoryIterator) Next(ctx context.Context) (fs.Entry, error) {
+	e, err := it.inner.Next(ctx)
+	if e == nil || err != nil {
+		//nolint:wrapcheck
+		return nil, err
+	}
+
+	return it.ctx.wrap(e), nil
+}
+
+func (it *readAheadDirectoryIterator) Close() {
+	it.inner.Close()
+}
+
+type readAheadFile struct {
+	ctx *readAheadContext
+	fs.File
+}
+
+func (f *readAheadFile) Open(ctx context.Context) (fs.Reader, error) {
+	h, ok := f.File.(object.HasObjectID)
+	if !ok {
+		//nolint:wrapcheck
+		return f.File.Open(ctx)
+	}
+
+	r, err := f.ctx.rep.OpenObject(ctx, h.ObjectID())
+	if err != nil {
+		return nil, errors.Wrapf(err, "unable to open object: %v", h.ObjectID())
+	}
+
+	ind, ok := r.(object.HasIndirectObjectEntries)
+	if !ok {
+		// object consists of a single content, nothing to prefetch.
+		return withFileInfo(r, f.File), nil
+	}
+
+	return &readAheadReader{
+		Reader: withFileInfo(r, f.File),
+		// prefetching continues in the background after the request which opened the file completes.
+		ctx:     context.WithoutCancel(ctx),
+		entries: ind.IndirectObjectEntries(),
+		window:  f.ctx.opts.Window,
+		prefetcher: NewObjectPrefetcher(f.ctx.rep, ObjectPrefetcherOptions{
+			Parallelism: f.ctx.opts.Parallelism,
+		}),
+		nextEntry: -1,
+	}, nil
+}
+
+// readAheadReader prefetches objects comprising an indirect object in the background, up to the read-ahead
+// window ahead of the current position, as long as the object is being read sequentially.
+type readAheadReader struct {
+	fs.Reader
+
+	ctx        context.Context //nolint:containedctx
+	entries    []object.IndirectObjectEntry
+	window     int64
+	prefetcher *ObjectPrefetcher
+
+	position    int64
+	lastReadEnd int64
+
+	// index of the first entry that was not scheduled to be prefetched, -1 if read-ahead is not active.
+	nextEntry int
+}
+
+func (r *readAheadReader) Read(b []byte) (int, error) {
+	if r.position == r.lastReadEnd {
+		r.readAhead()
+	} else {
+		// random access, restart read-ahead on next sequential read.
+		r.nextEntry = -1
+	}
+
+	n, err := r.Reader.Read(b)
+	r.position += int64(n)
+	r.lastReadEnd = r.position
+
+	//nolint:wrapcheck
+	return n, err
+}
+
+func (r *readAheadReader) Seek(offset int64, whence int) (int64, error) {
+	pos, err := r.Reader.Seek(offset, whence)
+	if err != nil {
+		//nolint:wrapcheck
+		return pos, err
+	}
+
+	r.position = pos
+
+	return pos, nil
+}
+
+func (r *readAheadReader) Close() error {
+	r.prefetcher.Close(r.ctx)
+
+	//nolint:wrapcheck
+	return r.Reader.Close()
+}
+
+func (r *readAheadReader) readAhead() {
+	// the entry at the current position is being read anyway, start prefetching with the next one.
+	current := sort.Search(len(r.entries), func(i int) bool {
+		return r.entries[i].Start+r.entries[i].Length > r.position
+	})
+
+	if r.nextEntry <= current {
+		r.nextEntry = current + 1
+	}
+
+	limit := r.position + r.window
+	scheduled := false
+
+	for r.nextEntry < len(r.entries) && r.entries[r.nextEntry].Start < limit {
+		r.prefetcher.Add(r.ctx, r.entries[r.nextEntry].Object)
+		r.nextEntry++
+		scheduled = true
+	}
+
+	if scheduled {
+		r.prefetcher.Flush(r.ctx)
+	}
+}
+
+// repositoryObject is implemented by entries stored in the repository.
+type repositoryObject interface {
+	object.HasObjectID
+	snapshot.HasDirEntry
+}
+
+// readAheadRepositoryDirectory is a readAheadDirectory which preserves the object ID and the directory entry
+// of the wrapped directory, so that wrappers such as cachefs can identify it.
+type readAheadRepositoryDirectory struct {
+	*readAheadDirectory
+	repositoryObject
+}
+
+// readAheadRepositoryFile is a readAheadFile which preserves the object ID and the directory entry of the wrapped file.
+type readAheadRepositoryFile struct {
+	*readAheadFile
+	repositoryObject
+}
+
+func (c *readAheadContext) wrap(e fs.Entry) fs.Entry {
+	ro, isRepositoryObject := e.(repositoryObject)
+
+	switch e := e.(type) {
+	case fs.Directory:
+		d := &readAheadDirectory{c, e}
+		if isRepositoryObject {
+			return fs.Directory(&readAheadRepositoryDirectory{d, ro})
+		}
+
+		return fs.Directory(d)
+
+	case fs.File:
+		f := &readAheadFile{c, e}
+		if isRepositoryObject {
+			return fs.File(&readAheadRepositoryFile{f, ro})
+		}
+
+		return fs.File(f)
+
+	default:
+		return e
+	}
+}
+
+// WithReadAhead wraps the provided entry, so that reading files stored in the repository sequentially
+// prefetches their contents ahead of the current position in the background, instead of fetching each
+// content from the storage as it is being read.
+func WithReadAhead(rep repo.Repository, e fs.Entry, opts ReadAheadOptions) fs.Entry {
+	if opts.Window <= 0 {
+		return e
+	}
+
+	return (&readAheadContext{rep, opts}).wrap(e)
+}
+
+var (
+	_ fs.Directory = &readAheadDirectory{}
+	_ fs.File      = &readAheadFile{}
+
+	_ repositoryObject = &readAheadRepositoryDirectory{}
+	_ repositoryObject = &readAheadRepositoryFile{}
+	_ fs.Reader        = &readAheadReader{}
+)
@@ -0,0 +1,151 @@
+package snapshotfs_test
+
+import (
+	"bytes"
+	"io"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/kopia/kopia/fs"
+	"github.com/kopia/kopia/internal/mockfs"
+	"github.com/kopia/kopia/internal/repotesting"
+	"github.com/kopia/kopia/repo/object"
+	"github.com/kopia/kopia/snapshot"
+	"github.com/kopia/kopia/snapshot/snapshotfs"
+)
+
+func TestReadAhead(t *testing.T) {
+	ctx, env := repotesting.NewEnvironment(t, repotesting.FormatNotImportant)
+
+	data := make([]byte, 8<<20)
+	for i := range data {
+		data[i] = byte(i * 31 / 7)
+	}
+
+	w := env.RepositoryWriter.NewObjectWriter(ctx, object.WriterOptions{Splitter: "FIXED-1M"})
+	_, err := w.Write(data)
+	require.NoError(t, err)
+
+	oid, err := w.Result()
+	require.NoError(t, err)
+	require.NoError(t, w.Close())
+
+	r := &prefetchRecordingRepository{Repository: env.RepositoryWriter}
+
+	f := snapshotfs.WithReadAhead(r, snapshotfs.EntryFromDirEntry(r, &snapshot.DirEntry{
+		Name:     "f",
+		Type:     snapshot.EntryTypeFile,
+		FileSize: int64(len(data)),
+		ObjectID: oid,
+	}), snapshotfs.ReadAheadOptions{Window: 3 << 20}).(fs.File)
+
+	rd, err := f.Open(ctx)
+	require.NoError(t, err)
+
+	var got bytes.Buffer
+
+	// read in small chunks, like the filesystem would.
+	_, err = io.CopyBuffer(struct{ io.Writer }{&got}, rd, make([]byte, 128<<10))
+	require.NoError(t, err)
+	require.NoError(t, rd.Close())
+	require.Equal(t, data, got.Bytes())
+
+	r.mu.Lock()
+
+	var prefetched []object.ID
+	for _, b := range r.batches {
+		prefetched = append(prefetched, b...)
+	}
+
+	r.mu.Unlock()
+
+	// all chunks except the first one are prefetched exactly once.
+	require.Len(t, prefetched, len(data)>>20-1)
+
+	// random access does not trigger read-ahead.
+	r.batches = nil
+
+	rd, err = f.Open(ctx)
+	require.NoError(t, err)
+
+	buf := make([]byte, 100)
+
+	for _, off := range []int64{5 << 20, 1 << 20, 7 << 20} {
+		_, err = rd.Seek(off, io.SeekStart)
+		require.NoError(t, err)
+
+		_, err = io.ReadFull(rd, buf)
+		require.NoError(t, err)
+		require.Equal(t, data[off:off+100], buf)
+	}
+
+	require.NoError(t, rd.Close())
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	require.Empty(t, r.batches)
+
+	// read-ahead is disabled with zero window.
+	e := snapshotfs.EntryFromDirEntry(r, &snapshot.DirEntry{Name: "f", Type: snapshot.EntryTypeFile, ObjectID: oid})
+	require.Equal(t, e, snapshotfs.WithReadAhead(r, e, snapshotfs.ReadAheadOptions{}))
+}
+
+func TestReadAheadPreservesObjectIDs(t *testing.T) {
+	ctx, env := repotesting.NewEnvironment(t, repotesting.FormatNotImportant)
+
+	sourceRoot := mockfs.NewDirectory()
+	sourceRoot.AddFile("f", []byte{1, 2, 3}, 0o644)
+	sourceRoot.AddDir("d", 0o755).AddFile("g", []byte{4, 5, 6}, 0o644)
+
+	man, err := snapshotfs.NewUploader(env.RepositoryWriter).Upload(ctx, sourceRoot, nil, snapshot.SourceInfo{})
+	require.NoError(t, err)
+
+	root, err := snapshotfs.SnapshotRoot(env.RepositoryWriter, man)
+	require.NoError(t, err)
+
+	wrapped := snapshotfs.WithReadAhead(env.RepositoryWriter, root, snapshotfs.ReadAheadOptions{Window: 3 << 20})
+
+	requireSameObject := func(want, got fs.Entry) {
+		t.Helper()
+
+		wantOID, ok := want.(object.HasObjectID)
+		require.True(t, ok)
+
+		gotOID, ok := got.(object.HasObjectID)
+		require.True(t, ok, "%v does not have object ID", got.Name())
+		require.Equal(t, wantOID.ObjectID(), gotOID.ObjectID())
+
+		gotDE, ok := got.(snapshot.HasDirEntry)
+		require.True(t, ok, "%v does not have directory entry", got.Name())
+		require.Equal(t, want.(snapshot.HasDirEntry).DirEntry(), gotDE.DirEntry())
+	}
+
+	requireSameObject(root, wrapped)
+
+	for _, name := range []string{"f", "d"} {
+		want, err := root.(fs.Directory).Child(ctx, name)
+		require.NoError(t, err)
+
+		got, err := wrapped.(fs.Directory).Child(ctx, name)
+		require.NoError(t, err)
+
+		requireSameObject(want, got)
+	}
+
+	entries, err := fs.GetAllEntries(ctx, wrapped.(fs.Directory))
+	require.NoError(t, err)
+	require.Len(t, entries, 2)
+
+	for _, e := range entries {
+		want, err := root.(fs.Directory).Child(ctx, e.Name())
+		require.NoError(t, err)
+
+		requireSameObject(want, e)
+	}
+
+	// entries which are not stored in the repository don't pretend to be.
+	_, ok := snapshotfs.WithReadAhead(env.RepositoryWriter, sourceRoot, snapshotfs.ReadAheadOptions{Window: 3 << 20}).(object.HasObjectID)
+	require.False(t, ok)
+}
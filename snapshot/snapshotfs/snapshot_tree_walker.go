@@ -5,6 +5,7 @@
 	"path"
 	"runtime"
 	"sync"
+	"sync/atomic"
 
 	"github.com/pkg/errors"
 
@@ -73,6 +74,14 @@ func (w *TreeWalker) Err() error {
 	}
 }
 
+// ErrorCount returns the number of errors reported so far.
+func (w *TreeWalker) ErrorCount() int {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	return w.numErrors
+}
+
 // TooManyErrors reports true if there are too many errors already reported.
 func (w *TreeWalker) TooManyErrors() bool {
 	if w.options.MaxErrors <= 0 {
@@ -92,29 +101,50 @@ func (w *TreeWalker) alreadyProcessed(ctx context.Context, e fs.Entry) bool {
 	return !w.enqueued.Put(ctx, oidOf(e).Append(idbuf[:0]))
 }
 
-func (w *TreeWalker) processEntry(ctx context.Context, e fs.Entry, entryPath string) {
+// markProcessed causes entries with the provided object ID not to be processed.
+func (w *TreeWalker) markProcessed(ctx context.Context, oid object.ID) {
+	var idbuf [128]byte
+
+	w.enqueued.Put(ctx, oid.Append(idbuf[:0]))
+}
+
+// processEntry processes the entry and returns true if it and all entries below it have been processed without errors.
+func (w *TreeWalker) processEntry(ctx context.Context, e fs.Entry, entryPath string) bool {
 	if ec := w.options.EntryCallback; ec != nil {
 		err := ec(ctx, e, oidOf(e), entryPath)
 		if err != nil {
 			w.ReportError(ctx, entryPath, err)
-			return
+			return false
 		}
 	}
 
-	if dir, ok := e.(fs.Directory); ok {
-		w.processDirEntry(ctx, dir, entryPath)
+	dir, ok := e.(fs.Directory)
+	if !ok {
+		return true
 	}
+
+	complete := w.processDirEntry(ctx, dir, entryPath)
+
+	if dc := w.options.DirectoryDoneCallback; dc != nil {
+		dc(ctx, oidOf(e), entryPath, complete)
+	}
+
+	return complete
 }
 
-func (w *TreeWalker) processDirEntry(ctx context.Context, dir fs.Directory, entryPath string) {
-	var ag workshare.AsyncGroup[any]
+func (w *TreeWalker) processDirEntry(ctx context.Context, dir fs.Directory, entryPath string) bool {
+	var (
+		ag         workshare.AsyncGroup[any]
+		incomplete atomic.Bool
+	)
+
 	defer ag.Close()
 
 	iter, err := dir.Iterate(ctx)
 	if err != nil {
 		w.ReportError(ctx, entryPath, errors.Wrap(err, "error reading directory"))
 
-		return
+		return false
 	}
 
 	defer iter.Close()
@@ -124,6 +154,7 @@ func (w *TreeWalker) processDirEntry(ctx context.Context, dir fs.Directory, entr
 		ent2 := ent
 
 		if w.TooManyErrors() {
+			incomplete.Store(true)
 			break
 		}
 
@@ -132,10 +163,12 @@ func (w *TreeWalker) processDirEntry(ctx context.Context, dir fs.Directory, entr
 
 			if ag.CanShareWork(w.wp) {
 				ag.RunAsync(w.wp, func(_ *workshare.Pool[any], _ any) {
-					w.processEntry(ctx, ent2, childPath)
+					if !w.processEntry(ctx, ent2, childPath) {
+						incomplete.Store(true)
+					}
 				}, nil)
-			} else {
-				w.processEntry(ctx, ent2, childPath)
+			} else if !w.processEntry(ctx, ent2, childPath) {
+				incomplete.Store(true)
 			}
 		}
 
@@ -144,7 +177,13 @@ func (w *TreeWalker) processDirEntry(ctx context.Context, dir fs.Directory, entr
 
 	if err != nil {
 		w.ReportError(ctx, entryPath, errors.Wrap(err, "error reading directory"))
+		incomplete.Store(true)
 	}
+
+	// wait for entries processed asynchronously before reporting whether all of them succeeded.
+	ag.Close()
+
+	return !incomplete.Load()
 }
 
 // Process processes the snapshot tree entry.
@@ -168,9 +207,14 @@ func (w *TreeWalker) Close(ctx context.Context) {
 	w.enqueued.Close(ctx)
 }
 
+// DirectoryDoneCallback is invoked after all entries of a directory have been processed, complete is false
+// if errors were encountered while processing the directory or entries below it.
+type DirectoryDoneCallback func(ctx context.Context, oid object.ID, entryPath string, complete bool)
+
 // TreeWalkerOptions provides optional fields for TreeWalker.
 type TreeWalkerOptions struct {
-	EntryCallback EntryCallback
+	EntryCallback         EntryCallback
+	DirectoryDoneCallback DirectoryDoneCallback
 
 	Parallelism int
 	MaxErrors   int
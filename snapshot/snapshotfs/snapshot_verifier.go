@@ -4,6 +4,7 @@
 	"context"
 	"io"
 	"math/rand"
+	"path"
 	"runtime"
 	"sync"
 	"sync/atomic"
@@ -23,8 +24,29 @@
 var verifierLog = logging.Module("verifier")
 
 type verifyFileWorkItem struct {
+	oid        object.ID
+	entryPath  string
+	readObject bool
+	dir        *verifiedDirectory // nil when not tracking verified objects or the file is a root
+}
+
+// verifiedDirectory tracks verification of a directory tree, which completes once the directory has been
+// iterated and all objects below it have been verified.
+type verifiedDirectory struct {
+	parent    *verifiedDirectory
 	oid       object.ID
 	entryPath string
+
+	pending         int         // number of files and subdirectories being verified, plus one while iterating
+	failed          bool        // errors were encountered in the directory tree
+	verifiedSubdirs []object.ID // subdirectories reported as verified
+}
+
+// VerifierStats contains statistics of the verification.
+type VerifierStats struct {
+	QueuedObjects    int32 `json:"queuedObjects"`
+	ProcessedObjects int32 `json:"processedObjects"`
+	Errors           int   `json:"errors"`
 }
 
 // Verifier allows efficient verification of large amounts of filesystem entries in parallel.
@@ -38,8 +60,14 @@ type Verifier struct {
 	rep           repo.Repository
 	opts          VerifierOptions
 	workersWG     sync.WaitGroup
+	prefetcher    *ObjectPrefetcher // nil if not prefetching
+	tw            *TreeWalker
 
 	blobMap map[blob.ID]blob.Metadata // when != nil, will check that each backing blob exists
+
+	dirsMu sync.Mutex
+	// +checklocks:dirsMu
+	dirs map[string]*verifiedDirectory // directories being verified keyed by path, when tracking verified objects
 }
 
 // ShowStats logs verification statistics.
@@ -49,6 +77,20 @@ func (v *Verifier) ShowStats(ctx context.Context) {
 	verifierLog(ctx).Infof("Processed %v objects.", processed)
 }
 
+// Stats returns the current verification statistics.
+func (v *Verifier) Stats() VerifierStats {
+	s := VerifierStats{
+		QueuedObjects:    v.queued.Load(),
+		ProcessedObjects: v.processed.Load(),
+	}
+
+	if v.tw != nil {
+		s.Errors = v.tw.ErrorCount()
+	}
+
+	return s
+}
+
 // ShowFinalStats logs final verification statistics.
 func (v *Verifier) ShowFinalStats(ctx context.Context) {
 	processed := v.processed.Load()
@@ -58,6 +100,16 @@ func (v *Verifier) ShowFinalStats(ctx context.Context) {
 
 // VerifyFile verifies a single file object (using content check, blob map check or full read).
 func (v *Verifier) VerifyFile(ctx context.Context, oid object.ID, entryPath string) error {
+	return v.verifyFile(ctx, oid, entryPath, v.shouldReadObject())
+}
+
+// shouldReadObject randomly determines whether an object should be read based on VerifyFilesPercent.
+func (v *Verifier) shouldReadObject() bool {
+	//nolint:gosec
+	return 100*rand.Float64() < v.opts.VerifyFilesPercent
+}
+
+func (v *Verifier) verifyFile(ctx context.Context, oid object.ID, entryPath string, readObject bool) error {
 	verifierLog(ctx).Debugf("verifying object %v", oid)
 
 	defer func() {
@@ -82,8 +134,7 @@ func (v *Verifier) VerifyFile(ctx context.Context, oid object.ID, entryPath stri
 		}
 	}
 
-	//nolint:gosec
-	if 100*rand.Float64() < v.opts.VerifyFilesPercent {
+	if readObject {
 		if err := v.readEntireObject(ctx, oid, entryPath); err != nil {
 			return errors.Wrapf(err, "error reading object %v", oid)
 		}
@@ -96,10 +147,22 @@ func (v *Verifier) VerifyFile(ctx context.Context, oid object.ID, entryPath stri
 func (v *Verifier) verifyObject(ctx context.Context, e fs.Entry, oid object.ID, entryPath string) error {
 	if v.throttle.ShouldOutput(time.Second) {
 		v.ShowStats(ctx)
+
+		if v.opts.OnProgress != nil {
+			v.opts.OnProgress(ctx, v.Stats())
+		}
 	}
 
+	parent := v.startVerifying(e, oid, entryPath)
+
 	if !e.IsDir() {
-		v.fileWorkQueue <- verifyFileWorkItem{oid, entryPath}
+		readObject := v.shouldReadObject()
+		if readObject {
+			// objects which will be read are prefetched while they wait in the queue.
+			v.prefetcher.Add(ctx, oid)
+		}
+
+		v.fileWorkQueue <- verifyFileWorkItem{oid, entryPath, readObject, parent}
 		v.queued.Add(1)
 	} else {
 		v.queued.Add(1)
@@ -109,6 +172,117 @@ func (v *Verifier) verifyObject(ctx context.Context, e fs.Entry, oid object.ID,
 	return nil
 }
 
+// startVerifying registers the entry as pending in its parent directory, and directories as being iterated,
+// when tracking verified objects. It returns the parent directory, which is nil for roots.
+func (v *Verifier) startVerifying(e fs.Entry, oid object.ID, entryPath string) *verifiedDirectory {
+	if v.opts.OnVerified == nil {
+		return nil
+	}
+
+	v.dirsMu.Lock()
+	defer v.dirsMu.Unlock()
+
+	// entries are processed after their parent directory, so it's either already known or the entry is a root.
+	parent := v.dirs[path.Dir(entryPath)]
+	if parent != nil {
+		parent.pending++
+	}
+
+	if e.IsDir() {
+		v.dirs[entryPath] = &verifiedDirectory{
+			parent:    parent,
+			oid:       oid,
+			entryPath: entryPath,
+			pending:   1,
+		}
+	}
+
+	return parent
+}
+
+// verifiedObject is an object reported by VerifierOptions.OnVerified.
+type verifiedObject struct {
+	oid       object.ID
+	entryPath string
+	covers    []object.ID
+}
+
+// fileVerified records the result of verification of a file in its parent directory.
+func (v *Verifier) fileVerified(ctx context.Context, wi verifyFileWorkItem, ok bool) {
+	if v.opts.OnVerified == nil {
+		return
+	}
+
+	if wi.dir == nil {
+		if ok {
+			v.opts.OnVerified(ctx, wi.oid, wi.entryPath, nil)
+		}
+
+		return
+	}
+
+	v.dirsMu.Lock()
+	verified := v.finishedPendingLocked(wi.dir, !ok)
+	v.dirsMu.Unlock()
+
+	v.reportVerified(ctx, verified)
+}
+
+// directoryDone records that the directory has been iterated.
+func (v *Verifier) directoryDone(ctx context.Context, _ object.ID, entryPath string, complete bool) {
+	v.dirsMu.Lock()
+
+	var verified []verifiedObject
+
+	if d := v.dirs[entryPath]; d != nil {
+		verified = v.finishedPendingLocked(d, !complete)
+	}
+
+	v.dirsMu.Unlock()
+
+	v.reportVerified(ctx, verified)
+}
+
+// finishedPendingLocked decrements the number of pending objects of the directory, and of its parents
+// once it's been verified, returning directories verified without errors.
+//
+// +checklocks:v.dirsMu
+func (v *Verifier) finishedPendingLocked(d *verifiedDirectory, failed bool) []verifiedObject {
+	var verified []verifiedObject
+
+	for d != nil {
+		if failed {
+			d.failed = true
+		}
+
+		d.pending--
+		if d.pending > 0 {
+			break
+		}
+
+		delete(v.dirs, d.entryPath)
+
+		failed = d.failed
+		if !failed {
+			verified = append(verified, verifiedObject{d.oid, d.entryPath, d.verifiedSubdirs})
+
+			if d.parent != nil {
+				d.parent.verifiedSubdirs = append(d.parent.verifiedSubdirs, d.oid)
+			}
+		}
+
+		d = d.parent
+	}
+
+	return verified
+}
+
+func (v *Verifier) reportVerified(ctx context.Context, verified []verifiedObject) {
+	for _, vo := range verified {
+		v.opts.OnVerified(ctx, vo.oid, vo.entryPath, vo.covers)
+	}
+}
+
 func (v *Verifier) readEntireObject(ctx context.Context, oid object.ID, path string) error {
 	verifierLog(ctx).Debugf("reading object %v %v", oid, path)
 
@@ -129,23 +303,52 @@ type VerifierOptions struct {
 	Parallelism        int
 	MaxErrors          int
 	BlobMap            map[blob.ID]blob.Metadata
+	Prefetch           bool // prefetch contents of files which will be read
+
+	// Verified contains objects verified earlier, which are skipped along with objects below them.
+	Verified []object.ID
+
+	// OnVerified, when set, is invoked for each directory once all objects below it have been verified
+	// without errors and for each verified root file. For directories, covers contains subdirectories
+	// reported earlier.
+	OnVerified func(ctx context.Context, oid object.ID, entryPath string, covers []object.ID)
+
+	// OnProgress, when set, is periodically invoked with verification statistics.
+	OnProgress func(ctx context.Context, stats VerifierStats)
 }
 
 // InParallel starts parallel verification and invokes the provided function which can
 // call Process() on in the provided TreeWalker.
 func (v *Verifier) InParallel(ctx context.Context, enqueue func(tw *TreeWalker) error) error {
-	tw, twerr := NewTreeWalker(ctx, TreeWalkerOptions{
+	two := TreeWalkerOptions{
 		Parallelism:   v.opts.Parallelism,
 		EntryCallback: v.verifyObject,
 		MaxErrors:     v.opts.MaxErrors,
-	})
+	}
+
+	if v.opts.OnVerified != nil {
+		v.dirs = map[string]*verifiedDirectory{}
+		two.DirectoryDoneCallback = v.directoryDone
+	}
+
+	tw, twerr := NewTreeWalker(ctx, two)
 	if twerr != nil {
 		return errors.Wrap(twerr, "tree walker")
 	}
 	defer tw.Close(ctx)
 
+	for _, oid := range v.opts.Verified {
+		tw.markProcessed(ctx, oid)
+	}
+
+	v.tw = tw
+
 	v.fileWorkQueue = make(chan verifyFileWorkItem, v.opts.FileQueueLength)
 
+	if v.opts.Prefetch && v.opts.VerifyFilesPercent > 0 {
+		v.prefetcher = NewObjectPrefetcher(v.rep, ObjectPrefetcherOptions{})
+	}
+
 	for range v.opts.Parallelism {
 		v.workersWG.Add(1)
 
@@ -154,22 +357,31 @@ func (v *Verifier) InParallel(ctx context.Context, enqueue func(tw *TreeWalker)
 
 			for wi := range v.fileWorkQueue {
 				if tw.TooManyErrors() {
+					v.fileVerified(ctx, wi, false)
 					continue
 				}
 
-				if err := v.VerifyFile(ctx, wi.oid, wi.entryPath); err != nil {
+				err := v.verifyFile(ctx, wi.oid, wi.entryPath, wi.readObject)
+				if err != nil {
 					tw.ReportError(ctx, wi.entryPath, err)
 				}
+
+				v.fileVerified(ctx, wi, err == nil)
 			}
 		}()
 	}
 
 	err := enqueue(tw)
 
+	v.prefetcher.Flush(ctx)
+
 	close(v.fileWorkQueue)
 	v.workersWG.Wait()
 	v.fileWorkQueue = nil
 
+	v.prefetcher.Close(ctx)
+	v.prefetcher = nil
+
 	if err != nil {
 		return err
 	}
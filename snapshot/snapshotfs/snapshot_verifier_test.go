@@ -108,6 +108,21 @@ func TestSnapshotVerifier(t *testing.T) {
 		}), "encountered 3 errors")
 	})
 
+	t.Run("FullFileReadsWithPrefetch", func(t *testing.T) {
+		opts := snapshotfs.VerifierOptions{
+			VerifyFilesPercent: 100,
+			MaxErrors:          30,
+			Prefetch:           true,
+		}
+
+		v := snapshotfs.NewVerifier(ctx, te2, opts)
+
+		require.NoError(t, v.InParallel(ctx, func(tw *snapshotfs.TreeWalker) error {
+			tw.Process(ctx, snapshotfs.DirectoryEntry(te.Repository, obj1, nil), ".")
+			return nil
+		}))
+	})
+
 	t.Run("MaxErrors", func(t *testing.T) {
 		// now set max errors to 1 where we have 3
 		opts := snapshotfs.VerifierOptions{
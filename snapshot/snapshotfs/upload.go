@@ -26,6 +26,7 @@
 	"github.com/kopia/kopia/internal/timetrack"
 	"github.com/kopia/kopia/internal/workshare"
 	"github.com/kopia/kopia/repo"
+	"github.com/kopia/kopia/repo/blob/throttling"
 	"github.com/kopia/kopia/repo/compression"
 	"github.com/kopia/kopia/repo/logging"
 	"github.com/kopia/kopia/repo/object"
@@ -36,6 +37,9 @@
 // DefaultCheckpointInterval is the default frequency of mid-upload checkpointing.
 const DefaultCheckpointInterval = 45 * time.Minute
 
+// sourceThrottlingWindow is the duration during which the token bucket limiting upload speed of a source fully replenishes.
+const sourceThrottlingWindow = 10 * time.Second
+
 var (
 	uploadLog   = logging.Module("uploader")
 	estimateLog = logging.Module("estimate")
@@ -76,6 +80,10 @@ type Uploader struct {
 	// 100=never use cached entries
 	ForceHashPercentage float64
 
+	// When set to true, never use cached entries from previous snapshots and re-hash contents of all files,
+	// warning about files whose contents differ from the previous snapshot even though their metadata does not.
+	ForceRehash bool
+
 	// Number of files to hash and upload in parallel.
 	ParallelUploads int
 
@@ -92,9 +100,16 @@ type Uploader struct {
 	// How frequently to create checkpoint snapshot entries.
 	CheckpointInterval time.Duration
 
+	// When non-zero, additionally create a checkpoint after every CheckpointEntries entries of a single directory,
+	// so that an interrupted snapshot of a directory with millions of entries can resume close to where it stopped.
+	CheckpointEntries int
+
 	// When set to true, do not ignore any files, regardless of policy settings.
 	DisableIgnoreRules bool
 
+	// When set to true, log the reason and the ignore rule for every excluded file and directory.
+	ExplainExcludes bool
+
 	// Labels to apply to every checkpoint made for this snapshot.
 	CheckpointLabels map[string]string
 
@@ -107,6 +122,9 @@ type Uploader struct {
 
 	getTicker func(time.Duration) <-chan time.Time
 
+	// checkpoints the snapshot being uploaded, set while periodic checkpointing is active.
+	checkpointNow atomic.Pointer[func() error]
+
 	// for testing only, when set will write to a given channel whenever checkpoint completes
 	checkpointFinished chan struct{}
 
@@ -161,12 +179,13 @@ func (u *Uploader) uploadFileInternal(ctx context.Context, parentCheckpointRegis
 	}
 
 	comp := pol.CompressionPolicy.CompressorForFile(f)
+	adaptiveComp := pol.CompressionPolicy.Adaptive.OrDefault(false)
 	splitterName := pol.SplitterPolicy.SplitterForFile(f)
 
 	chunkSize := pol.UploadPolicy.ParallelUploadAboveSize.OrDefault(-1)
 	if chunkSize < 0 || f.Size() <= chunkSize {
 		// all data fits in 1 full chunks, upload directly
-		return u.uploadFileData(ctx, parentCheckpointRegistry, f, f.Name(), 0, -1, comp, splitterName)
+		return u.uploadFileData(ctx, parentCheckpointRegistry, f, f.Name(), 0, -1, comp, adaptiveComp, splitterName)
 	}
 
 	// we always have N+1 parts, first N are exactly chunkSize, last one has undetermined length
@@ -191,11 +210,11 @@ func (u *Uploader) uploadFileInternal(ctx context.Context, parentCheckpointRegis
 		if wg.CanShareWork(u.workerPool) {
 			// another goroutine is available, delegate to them
 			wg.RunAsync(u.workerPool, func(_ *workshare.Pool[*uploadWorkItem], _ *uploadWorkItem) {
-				parts[i], partErrors[i] = u.uploadFileData(ctx, parentCheckpointRegistry, f, uuid.NewString(), offset, length, comp, splitterName)
+				parts[i], partErrors[i] = u.uploadFileData(ctx, parentCheckpointRegistry, f, uuid.NewString(), offset, length, comp, adaptiveComp, splitterName)
 			}, nil)
 		} else {
 			// just do the work in the current goroutine
-			parts[i], partErrors[i] = u.uploadFileData(ctx, parentCheckpointRegistry, f, uuid.NewString(), offset, length, comp, splitterName)
+			parts[i], partErrors[i] = u.uploadFileData(ctx, parentCheckpointRegistry, f, uuid.NewString(), offset, length, comp, adaptiveComp, splitterName)
 		}
 	}
 
@@ -234,7 +253,7 @@ func concatenateParts(ctx context.Context, rep repo.RepositoryWriter, name strin
 	return de, nil
 }
 
-func (u *Uploader) uploadFileData(ctx context.Context, parentCheckpointRegistry *checkpointRegistry, f fs.File, fname string, offset, length int64, compressor compression.Name, splitterName string) (*snapshot.DirEntry, error) {
+func (u *Uploader) uploadFileData(ctx context.Context, parentCheckpointRegistry *checkpointRegistry, f fs.File, fname string, offset, length int64, compressor compression.Name, adaptiveCompression bool, splitterName string) (*snapshot.DirEntry, error) {
 	file, err := f.Open(ctx)
 	if err != nil {
 		return nil, errors.Wrap(err, "unable to open file")
@@ -242,10 +261,11 @@ func (u *Uploader) uploadFileData(ctx context.Context, parentCheckpointRegistry
 	defer file.Close() //nolint:errcheck
 
 	writer := u.repo.NewObjectWriter(ctx, object.WriterOptions{
-		Description: "FILE:" + fname,
-		Compressor:  compressor,
-		Splitter:    splitterName,
-		AsyncWrites: 1, // upload chunk in parallel to writing another chunk
+		Description:         "FILE:" + fname,
+		Compressor:          compressor,
+		AdaptiveCompression: adaptiveCompression,
+		Splitter:            splitterName,
+		AsyncWrites:         1, // upload chunk in parallel to writing another chunk
 	})
 	defer writer.Close() //nolint:errcheck
 
@@ -285,6 +305,8 @@ func (u *Uploader) uploadFileData(ctx context.Context, parentCheckpointRegistry
 		return nil, errors.Wrap(err, "unable to get result")
 	}
 
+	u.stats.AddWriterStats(writer.Stats())
+
 	de, err := newDirEntry(f, fname, r)
 	if err != nil {
 		return nil, errors.Wrap(err, "unable to create dir entry")
@@ -355,9 +377,10 @@ func (u *Uploader) uploadStreamingFileInternal(ctx context.Context, relativePath
 	comp := pol.CompressionPolicy.CompressorForFile(f)
 
 	writer := u.repo.NewObjectWriter(ctx, object.WriterOptions{
-		Description: "STREAMFILE:" + f.Name(),
-		Compressor:  comp,
-		Splitter:    pol.SplitterPolicy.SplitterForFile(f),
+		Description:         "STREAMFILE:" + f.Name(),
+		Compressor:          comp,
+		AdaptiveCompression: pol.CompressionPolicy.Adaptive.OrDefault(false),
+		Splitter:            pol.SplitterPolicy.SplitterForFile(f),
 	})
 
 	defer writer.Close() //nolint:errcheck
@@ -372,6 +395,8 @@ func (u *Uploader) uploadStreamingFileInternal(ctx context.Context, relativePath
 		return nil, errors.Wrap(err, "unable to get result")
 	}
 
+	u.stats.AddWriterStats(writer.Stats())
+
 	de, err := newDirEntry(f, f.Name(), r)
 	if err != nil {
 		return nil, errors.Wrap(err, "unable to create dir entry")
@@ -554,6 +579,18 @@ func (u *Uploader) periodicallyCheckpoint(ctx context.Context, cp *checkpointReg
 	shutdown := make(chan struct{})
 	ch := u.getTicker(u.CheckpointInterval)
 
+	// checkpoints requested by maybeCheckpointAfterEntries are serialized with periodic ones.
+	var mu sync.Mutex
+
+	checkpoint := func() error {
+		mu.Lock()
+		defer mu.Unlock()
+
+		return u.checkpointRoot(ctx, cp, prototypeManifest)
+	}
+
+	u.checkpointNow.Store(&checkpoint)
+
 	go func() {
 		for {
 			select {
@@ -561,7 +598,7 @@ func (u *Uploader) periodicallyCheckpoint(ctx context.Context, cp *checkpointReg
 				return
 
 			case <-ch:
-				if err := u.checkpointRoot(ctx, cp, prototypeManifest); err != nil {
+				if err := checkpoint(); err != nil {
 					uploadLog(ctx).Errorf("error checkpointing: %v", err)
 					u.Cancel()
 
@@ -577,10 +614,31 @@ func (u *Uploader) periodicallyCheckpoint(ctx context.Context, cp *checkpointReg
 	}()
 
 	return func() {
+		u.checkpointNow.Store(nil)
 		close(shutdown)
 	}
 }
 
+// maybeCheckpointAfterEntries creates a checkpoint when the provided number of completed entries of a directory
+// is a multiple of CheckpointEntries.
+func (u *Uploader) maybeCheckpointAfterEntries(ctx context.Context, dirRelativePath string, entryCount int64) {
+	if u.CheckpointEntries <= 0 || entryCount%int64(u.CheckpointEntries) != 0 {
+		return
+	}
+
+	checkpoint := u.checkpointNow.Load()
+	if checkpoint == nil {
+		return
+	}
+
+	uploadLog(ctx).Debugw("checkpointing large directory", "dir", dirRelativePath, "entries", entryCount)
+
+	if err := (*checkpoint)(); err != nil {
+		uploadLog(ctx).Errorf("error checkpointing: %v", err)
+		u.Cancel()
+	}
+}
+
 // uploadDirWithCheckpointing uploads the specified Directory to the repository.
 func (u *Uploader) uploadDirWithCheckpointing(ctx context.Context, rootDir fs.Directory, policyTree *policy.Tree, previousDirs []fs.Directory, sourceInfo snapshot.SourceInfo) (*snapshot.DirEntry, error) {
 	var (
@@ -753,6 +811,10 @@ func findCachedEntry(ctx context.Context, entryRelativePath string, entry fs.Ent
 }
 
 func (u *Uploader) maybeIgnoreCachedEntry(ctx context.Context, ent fs.Entry) fs.Entry {
+	if u.ForceRehash {
+		return nil
+	}
+
 	if h, ok := ent.(object.HasObjectID); ok {
 		if 100*rand.Float64() < u.ForceHashPercentage { //nolint:gosec
 			uploadLog(ctx).Debugw("re-hashing cached object", "oid", h.ObjectID())
@@ -765,6 +827,20 @@ func (u *Uploader) maybeIgnoreCachedEntry(ctx context.Context, ent fs.Entry) fs.
 	return nil
 }
 
+// verifyRehashedFile warns when the contents of a re-hashed file differ from its entry in the previous
+// snapshot despite identical metadata, which indicates silent corruption of the source.
+func verifyRehashedFile(ctx context.Context, entryRelativePath string, prevEntry fs.Entry, de *snapshot.DirEntry) {
+	h, ok := prevEntry.(object.HasObjectID)
+	if !ok || de == nil || h.ObjectID() == de.ObjectID {
+		return
+	}
+
+	uploadLog(ctx).Warnw("file contents changed without change to its metadata",
+		"path", entryRelativePath,
+		"previous", h.ObjectID(),
+		"current", de.ObjectID)
+}
+
 func (u *Uploader) effectiveParallelFileReads(pol *policy.Policy) int {
 	p := u.ParallelUploads
 	if p > 0 {
@@ -781,6 +857,31 @@ func (u *Uploader) effectiveParallelFileReads(pol *policy.Policy) int {
 	return p
 }
 
+// withSourceThrottler returns the context in which blob uploads are subject to upload speed and concurrency
+// limits from the upload policy of the source, which prevents a single source from using all available bandwidth.
+func withSourceThrottler(ctx context.Context, pol *policy.Policy) (context.Context, error) {
+	limits := throttling.Limits{
+		UploadBytesPerSecond: float64(pol.UploadPolicy.MaxUploadBytesPerSecond.OrDefault(0)),
+		ConcurrentWrites:     pol.UploadPolicy.MaxConcurrentUploads.OrDefault(0),
+	}
+
+	if limits.UploadBytesPerSecond == 0 && limits.ConcurrentWrites == 0 {
+		return ctx, nil
+	}
+
+	t, err := throttling.NewThrottlerWithOptions(limits, sourceThrottlingWindow, 0, throttling.ThrottlerOptions{
+		// waits are expected when the source is limited.
+		WaitWarningThreshold: -1,
+	})
+	if err != nil {
+		return nil, errors.Wrap(err, "invalid upload limits")
+	}
+
+	uploadLog(ctx).Debugw("limiting uploads", "maxUploadBytesPerSecond", limits.UploadBytesPerSecond, "maxConcurrentUploads", limits.ConcurrentWrites)
+
+	return throttling.WithThrottler(ctx, t), nil
+}
+
 func (u *Uploader) processDirectoryEntries(
 	ctx context.Context,
 	parentCheckpointRegistry *checkpointRegistry,
@@ -801,6 +902,13 @@ func (u *Uploader) processDirectoryEntries(
 
 	entry, err := iter.Next(ctx)
 
+	// entries may complete asynchronously, count them once they have been added to the directory.
+	var completedEntries atomic.Int64
+
+	onEntryCompleted := func() {
+		u.maybeCheckpointAfterEntries(ctx, dirRelativePath, completedEntries.Add(1))
+	}
+
 	for entry != nil {
 		entry2 := entry
 
@@ -813,11 +921,16 @@ func (u *Uploader) processDirectoryEntries(
 		if wg.CanShareWork(u.workerPool) {
 			wg.RunAsync(u.workerPool, func(_ *workshare.Pool[*uploadWorkItem], wi *uploadWorkItem) {
 				wi.err = u.processSingle(ctx, entry2, entryRelativePath, parentDirBuilder, policyTree, prevDirs, localDirPathOrEmpty, parentCheckpointRegistry)
+				if wi.err == nil {
+					onEntryCompleted()
+				}
 			}, &uploadWorkItem{})
 		} else {
 			if err2 := u.processSingle(ctx, entry2, entryRelativePath, parentDirBuilder, policyTree, prevDirs, localDirPathOrEmpty, parentCheckpointRegistry); err2 != nil {
 				return err2
 			}
+
+			onEntryCompleted()
 		}
 
 		entry, err = iter.Next(ctx)
@@ -846,9 +959,13 @@ func (u *Uploader) processSingle(
 	// note this function runs in parallel and updates 'u.stats', which must be done using atomic operations.
 	t0 := timetrack.StartTimer()
 
+	var prevEntry fs.Entry
+
 	if _, ok := entry.(fs.Directory); !ok {
 		// See if we had this name during either of previous passes.
-		if cachedEntry := u.maybeIgnoreCachedEntry(ctx, findCachedEntry(ctx, entryRelativePath, entry, prevDirs, policyTree)); cachedEntry != nil {
+		prevEntry = findCachedEntry(ctx, entryRelativePath, entry, prevDirs, policyTree)
+
+		if cachedEntry := u.maybeIgnoreCachedEntry(ctx, prevEntry); cachedEntry != nil {
 			atomic.AddInt32(&u.stats.CachedFiles, 1)
 			atomic.AddInt64(&u.stats.TotalFileSize, cachedEntry.Size())
 			u.Progress.CachedFile(entryRelativePath, cachedEntry.Size())
@@ -914,6 +1031,9 @@ func (u *Uploader) processSingle(
 		atomic.AddInt32(&u.stats.NonCachedFiles, 1)
 
 		de, err := u.uploadFileInternal(ctx, parentCheckpointRegistry, entryRelativePath, entry, policyTree.Child(entry.Name()).EffectivePolicy())
+		if err == nil && u.ForceRehash {
+			verifyRehashedFile(ctx, entryRelativePath, prevEntry, de)
+		}
 
 		return u.processEntryUploadResult(ctx, de, err, entryRelativePath, parentDirBuilder,
 			policyTree.EffectivePolicy().ErrorHandlingPolicy.IgnoreFileErrors.OrDefault(false),
@@ -1258,6 +1378,11 @@ func (u *Uploader) Upload(
 
 	uploadLog(ctx).Debugw("uploading", "source", sourceInfo, "previousManifests", len(previousManifests), "parallel", parallel)
 
+	ctx, err := withSourceThrottler(ctx, policyTree.EffectivePolicy())
+	if err != nil {
+		return nil, err
+	}
+
 	s := &snapshot.Manifest{
 		Source: sourceInfo,
 	}
@@ -1268,8 +1393,6 @@ func (u *Uploader) Upload(
 	u.stats = &snapshot.Stats{}
 	u.totalWrittenBytes.Store(0)
 
-	var err error
-
 	s.StartTime = fs.UTCTimestampFromTime(u.repo.Time())
 
 	var scanWG sync.WaitGroup
@@ -1331,7 +1454,7 @@ func (u *Uploader) wrapIgnorefs(logger logging.Logger, entry fs.Directory, polic
 		return entry
 	}
 
-	return ignorefs.New(entry, policyTree, ignorefs.ReportIgnoredFiles(func(ctx context.Context, fname string, md fs.Entry, policyTree *policy.Tree) {
+	opts := []ignorefs.Option{ignorefs.ReportIgnoredFiles(func(ctx context.Context, fname string, md fs.Entry, policyTree *policy.Tree) {
 		if md.IsDir() {
 			maybeLogEntryProcessed(
 				logger,
@@ -1353,5 +1476,17 @@ func (u *Uploader) wrapIgnorefs(logger logging.Logger, entry fs.Directory, polic
 		}
 
 		u.stats.AddExcluded(md)
-	}))
+	})}
+
+	if u.ExplainExcludes && reportIgnoreStats {
+		opts = append(opts, ignorefs.ReportIgnoredFilesWithExplanation(func(ctx context.Context, fname string, _ fs.Entry, _ *policy.Tree, reason ignorefs.IgnoreReason, rule *ignorefs.IgnoreRule) {
+			if rule != nil {
+				logger.Infof("excluded %v (%v): %v", fname, reason, rule)
+			} else {
+				logger.Infof("excluded %v (%v)", fname, reason)
+			}
+		}))
+	}
+
+	return ignorefs.New(entry, policyTree, opts...)
 }
@@ -9,6 +9,11 @@
 
 // UploadProgress is invoked by uploader to report status of file and directory uploads.
 //
+// Programs embedding the uploader can provide their own implementation via Uploader.Progress
+// or use CountingUploadProgress, which accumulates the events into UploadCounters.
+// Methods may be invoked concurrently from multiple goroutines when files are uploaded in parallel.
+// Paths are relative to the root of the snapshot.
+//
 //nolint:interfacebloat
 type UploadProgress interface {
 	// UploadStarted is emitted once at the start of an upload
@@ -45,6 +50,8 @@ type UploadProgress interface {
 	Error(path string, err error, isIgnored bool)
 
 	// UploadedBytes is emitted whenever bytes are written to the blob storage.
+	// The uploader does not emit it by itself, callers must pass UploadedBytes as
+	// repo.WriteSessionOptions.OnUpload when opening the repository writer.
 	UploadedBytes(numBytes int64)
 
 	// StartedDirectory is emitted whenever a directory starts being uploaded.
@@ -158,6 +165,7 @@ type UploadCounters struct {
 	EstimatedFiles int32 `json:"estimatedFiles"`
 
 	CurrentDirectory string `json:"directory"`
+	CurrentFile      string `json:"currentFile,omitempty"`
 
 	LastErrorPath string `json:"lastErrorPath"`
 	LastError     string `json:"lastError"`
@@ -174,6 +182,9 @@ type CountingUploadProgress struct {
 
 // UploadStarted implements UploadProgress.
 func (p *CountingUploadProgress) UploadStarted() {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
 	// reset counters to all-zero values.
 	p.counters = UploadCounters{}
 }
@@ -202,11 +213,27 @@ func (p *CountingUploadProgress) CachedFile(fname string, numBytes int64) {
 	atomic.AddInt64(&p.counters.TotalCachedBytes, numBytes)
 }
 
+// HashingFile implements UploadProgress.
+func (p *CountingUploadProgress) HashingFile(fname string) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	p.counters.CurrentFile = fname
+}
+
 // FinishedHashingFile implements UploadProgress.
 //
 //nolint:revive
 func (p *CountingUploadProgress) FinishedHashingFile(fname string, numBytes int64) {
 	atomic.AddInt32(&p.counters.TotalHashedFiles, 1)
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	// with parallel uploads, another file may have started hashing in the meantime.
+	if p.counters.CurrentFile == fname {
+		p.counters.CurrentFile = ""
+	}
 }
 
 // FinishedFile implements UploadProgress.
@@ -257,17 +284,21 @@ func (p *CountingUploadProgress) Snapshot() UploadCounters {
 	defer p.mu.Unlock()
 
 	return UploadCounters{
-		TotalCachedFiles:  atomic.LoadInt32(&p.counters.TotalCachedFiles),
-		TotalHashedFiles:  atomic.LoadInt32(&p.counters.TotalHashedFiles),
-		TotalCachedBytes:  atomic.LoadInt64(&p.counters.TotalCachedBytes),
-		TotalHashedBytes:  atomic.LoadInt64(&p.counters.TotalHashedBytes),
-		EstimatedBytes:    atomic.LoadInt64(&p.counters.EstimatedBytes),
-		EstimatedFiles:    atomic.LoadInt32(&p.counters.EstimatedFiles),
-		IgnoredErrorCount: atomic.LoadInt32(&p.counters.IgnoredErrorCount),
-		FatalErrorCount:   atomic.LoadInt32(&p.counters.FatalErrorCount),
-		CurrentDirectory:  p.counters.CurrentDirectory,
-		LastErrorPath:     p.counters.LastErrorPath,
-		LastError:         p.counters.LastError,
+		TotalCachedFiles:   atomic.LoadInt32(&p.counters.TotalCachedFiles),
+		TotalHashedFiles:   atomic.LoadInt32(&p.counters.TotalHashedFiles),
+		TotalCachedBytes:   atomic.LoadInt64(&p.counters.TotalCachedBytes),
+		TotalHashedBytes:   atomic.LoadInt64(&p.counters.TotalHashedBytes),
+		TotalUploadedBytes: atomic.LoadInt64(&p.counters.TotalUploadedBytes),
+		TotalExcludedFiles: atomic.LoadInt32(&p.counters.TotalExcludedFiles),
+		TotalExcludedDirs:  atomic.LoadInt32(&p.counters.TotalExcludedDirs),
+		EstimatedBytes:     atomic.LoadInt64(&p.counters.EstimatedBytes),
+		EstimatedFiles:     atomic.LoadInt32(&p.counters.EstimatedFiles),
+		IgnoredErrorCount:  atomic.LoadInt32(&p.counters.IgnoredErrorCount),
+		FatalErrorCount:    atomic.LoadInt32(&p.counters.FatalErrorCount),
+		CurrentDirectory:   p.counters.CurrentDirectory,
+		CurrentFile:        p.counters.CurrentFile,
+		LastErrorPath:      p.counters.LastErrorPath,
+		LastError:          p.counters.LastError,
 	}
 }
 
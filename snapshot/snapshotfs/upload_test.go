@@ -24,6 +24,7 @@
 	"github.com/stretchr/testify/require"
 	"go.uber.org/zap"
 	"go.uber.org/zap/zapcore"
+	"go.uber.org/zap/zaptest/observer"
 
 	"github.com/kopia/kopia/fs"
 	"github.com/kopia/kopia/fs/localfs"
@@ -179,6 +180,14 @@ func TestUpload(t *testing.T) {
 		t.Errorf("unexpected non-cached files: %v", got)
 	}
 
+	// each non-cached file is written as a single chunk, cached files are not written at all.
+	s1Chunks := s1.Stats.ChunkStats()
+	require.Equal(t, int64(atomic.LoadInt32(&s1.Stats.NonCachedFiles)), s1Chunks.ChunkCount)
+	require.Equal(t, int64(37), s1Chunks.TotalChunkBytes)
+	require.Equal(t, int64(3), s1Chunks.MinChunkSize)
+	require.Equal(t, int64(5), s1Chunks.MaxChunkSize)
+	require.Equal(t, object.WriterStats{}, s2.Stats.ChunkStats())
+
 	// Add one more file, the s1.RootObjectID should change.
 	th.sourceDir.AddFile("d2/d1/f3", []byte{1, 2, 3, 4, 5}, defaultPermissions)
 
@@ -196,6 +205,16 @@ func TestUpload(t *testing.T) {
 		t.Errorf("unexpected s3 stats: %+v", s3.Stats)
 	}
 
+	// contents of the new file are identical to ./f3 and got deduplicated.
+	require.Equal(t, object.WriterStats{
+		ChunkCount:         1,
+		TotalChunkBytes:    5,
+		MinChunkSize:       5,
+		MaxChunkSize:       5,
+		DeduplicatedChunks: 1,
+		DeduplicatedBytes:  5,
+	}, s3.Stats.ChunkStats())
+
 	// Now remove the added file, OID should be identical to the original before the file got added.
 	th.sourceDir.Subdir("d2", "d1").Remove("f3")
 
@@ -286,6 +305,42 @@ func TestUploadDoesNotReportProgressForIgnoredFilesTwice(t *testing.T) {
 	require.EqualValues(t, 1, cup.counters.TotalExcludedDirs)
 }
 
+func TestUpload_CountingProgress(t *testing.T) {
+	ctx := testlogging.Context(t)
+	th := newUploadTestHarness(ctx, t)
+
+	defer th.cleanup()
+
+	sourceDir := mockfs.NewDirectory()
+	sourceDir.AddFile("f1", []byte{1, 2, 3}, defaultPermissions)
+	sourceDir.AddDir("d1", defaultPermissions)
+	sourceDir.AddFile("d1/f1", []byte{1, 2, 3, 4}, defaultPermissions)
+	sourceDir.AddFile("d1/f2", []byte{1, 2, 3, 4, 5}, defaultPermissions)
+
+	u := NewUploader(th.repo)
+	cup := &CountingUploadProgress{}
+	u.Progress = cup
+
+	policyTree := policy.BuildTree(nil, policy.DefaultPolicy)
+
+	_, err := u.Upload(ctx, sourceDir, policyTree, snapshot.SourceInfo{})
+	require.NoError(t, err)
+
+	c := cup.Snapshot()
+	require.EqualValues(t, 3, c.TotalHashedFiles)
+	require.EqualValues(t, 12, c.TotalHashedBytes)
+	require.Empty(t, c.CurrentFile)
+
+	cup.UploadedBytes(100)
+	cup.HashingFile("d1/f1")
+	cup.HashingFile("d1/f2")
+	cup.FinishedHashingFile("d1/f1", 4)
+
+	c = cup.Snapshot()
+	require.EqualValues(t, 100, c.TotalUploadedBytes)
+	require.Equal(t, "d1/f2", c.CurrentFile)
+}
+
 func TestUpload_SubDirectoryReadFailureFailFast(t *testing.T) {
 	ctx := testlogging.Context(t)
 	th := newUploadTestHarness(ctx, t)
@@ -1634,3 +1689,204 @@ func verifyLogDetails(t *testing.T, desc string, wantDetailKeys []string, keysAn
 	sort.Strings(wantDetailKeys)
 	require.Equal(t, wantDetailKeys, gotDetailKeys, "invalid details for "+desc)
 }
+
+func TestWithSourceThrottler(t *testing.T) {
+	ctx := testlogging.Context(t)
+
+	pol := *policy.DefaultPolicy
+
+	ctx2, err := withSourceThrottler(ctx, &pol)
+	require.NoError(t, err)
+	require.Equal(t, ctx, ctx2)
+
+	speed := policy.OptionalInt64(1 << 20)
+	pol.UploadPolicy.MaxUploadBytesPerSecond = &speed
+
+	ctx2, err = withSourceThrottler(ctx, &pol)
+	require.NoError(t, err)
+	require.NotEqual(t, ctx, ctx2)
+
+	pol.UploadPolicy.MaxUploadBytesPerSecond = nil
+	invalid := policy.OptionalInt(-1)
+	pol.UploadPolicy.MaxConcurrentUploads = &invalid
+
+	_, err = withSourceThrottler(ctx, &pol)
+	require.Error(t, err)
+}
+
+func TestUploadWithEntryCheckpointing(t *testing.T) {
+	ctx := testlogging.Context(t)
+	th := newUploadTestHarness(ctx, t)
+
+	defer th.cleanup()
+
+	const numFiles = 25
+
+	th.sourceDir.AddDir("d3", defaultPermissions)
+
+	for i := range numFiles {
+		th.sourceDir.AddFile(fmt.Sprintf("d3/f%02d", i), []byte{1, 2, byte(i)}, defaultPermissions)
+	}
+
+	u := NewUploader(th.repo)
+
+	// periodic checkpoints never fire.
+	u.getTicker = func(d time.Duration) <-chan time.Time {
+		return nil
+	}
+
+	u.disableEstimation = true
+	u.ParallelUploads = 1
+	u.CheckpointEntries = 10
+
+	policyTree := policy.BuildTree(nil, policy.DefaultPolicy)
+
+	si := snapshot.SourceInfo{
+		UserName: "user",
+		Host:     "host",
+		Path:     "path",
+	}
+
+	_, err := u.Upload(ctx, th.sourceDir, policyTree, si)
+	require.NoError(t, err)
+
+	checkpoints, err := snapshot.ListSnapshots(ctx, th.repo, si)
+	require.NoError(t, err)
+
+	// checkpoints after 10 and 20 entries of d3
+	require.Len(t, checkpoints, 2)
+
+	var entryCounts []int
+
+	for _, man := range checkpoints {
+		require.Equal(t, IncompleteReasonCheckpoint, man.IncompleteReason)
+
+		root, cerr := SnapshotRoot(th.repo, man)
+		require.NoError(t, cerr)
+
+		d3, cerr := root.(fs.Directory).Child(ctx, "d3")
+		require.NoError(t, cerr)
+
+		entries, cerr := fs.GetAllEntries(ctx, d3.(fs.Directory))
+		require.NoError(t, cerr)
+
+		entryCounts = append(entryCounts, len(entries))
+	}
+
+	sort.Ints(entryCounts)
+	require.Equal(t, []int{10, 20}, entryCounts)
+
+	// resuming from checkpoints does not hash files which were already checkpointed.
+	u = NewUploader(th.repo)
+	u.disableEstimation = true
+
+	man, err := u.Upload(ctx, th.sourceDir, policyTree, si, checkpoints...)
+	require.NoError(t, err)
+	require.GreaterOrEqual(t, int(man.Stats.CachedFiles), 20)
+	require.Empty(t, man.IncompleteReason)
+}
+
+func TestUploadWithForceRehash(t *testing.T) {
+	const mismatchMessage = "file contents changed without change to its metadata"
+
+	core, logs := observer.New(zapcore.WarnLevel)
+
+	ctx := logging.WithLogger(testlogging.Context(t), func(module string) logging.Logger {
+		return zap.New(core).Sugar()
+	})
+
+	th := newUploadTestHarness(ctx, t)
+
+	defer th.cleanup()
+
+	u := NewUploader(th.repo)
+	policyTree := policy.BuildTree(nil, policy.DefaultPolicy)
+
+	man1, err := u.Upload(ctx, th.sourceDir, policyTree, snapshot.SourceInfo{})
+	require.NoError(t, err)
+	require.Zero(t, man1.Stats.CachedFiles)
+
+	// replace file contents without changing its size or modification time.
+	th.sourceDir.Remove("f1")
+	th.sourceDir.AddFile("f1", []byte{9, 9, 9}, defaultPermissions)
+
+	man2, err := u.Upload(ctx, th.sourceDir, policyTree, snapshot.SourceInfo{}, man1)
+	require.NoError(t, err)
+	require.Equal(t, man1.Stats.NonCachedFiles, man2.Stats.CachedFiles)
+	require.Equal(t, man1.RootObjectID(), man2.RootObjectID(), "changed contents must not be detected without re-hashing")
+	require.Zero(t, logs.FilterMessage(mismatchMessage).Len())
+
+	u.ForceRehash = true
+
+	man3, err := u.Upload(ctx, th.sourceDir, policyTree, snapshot.SourceInfo{}, man2)
+	require.NoError(t, err)
+	require.Zero(t, man3.Stats.CachedFiles)
+	require.Equal(t, man1.Stats.NonCachedFiles, man3.Stats.NonCachedFiles)
+	require.NotEqual(t, man2.RootObjectID(), man3.RootObjectID())
+
+	warnings := logs.FilterMessage(mismatchMessage).All()
+	require.Len(t, warnings, 1)
+	require.Equal(t, "f1", warnings[0].ContextMap()["path"])
+}
+
+func TestUploadWithEntryCheckpointingInParallel(t *testing.T) {
+	ctx := testlogging.Context(t)
+	th := newUploadTestHarness(ctx, t)
+
+	defer th.cleanup()
+
+	const numFiles = 25
+
+	th.sourceDir.AddDir("d3", defaultPermissions)
+
+	for i := range numFiles {
+		th.sourceDir.AddFile(fmt.Sprintf("d3/f%02d", i), []byte{1, 2, byte(i)}, defaultPermissions)
+	}
+
+	u := NewUploader(th.repo)
+
+	// periodic checkpoints never fire.
+	u.getTicker = func(d time.Duration) <-chan time.Time {
+		return nil
+	}
+
+	u.disableEstimation = true
+	u.ParallelUploads = 4
+	u.CheckpointEntries = 10
+
+	policyTree := policy.BuildTree(nil, policy.DefaultPolicy)
+
+	si := snapshot.SourceInfo{
+		UserName: "user",
+		Host:     "host",
+		Path:     "path",
+	}
+
+	_, err := u.Upload(ctx, th.sourceDir, policyTree, si)
+	require.NoError(t, err)
+
+	checkpoints, err := snapshot.ListSnapshots(ctx, th.repo, si)
+	require.NoError(t, err)
+	require.Len(t, checkpoints, 2)
+
+	var entryCounts []int
+
+	for _, man := range checkpoints {
+		root, cerr := SnapshotRoot(th.repo, man)
+		require.NoError(t, cerr)
+
+		d3, cerr := root.(fs.Directory).Child(ctx, "d3")
+		require.NoError(t, cerr)
+
+		entries, cerr := fs.GetAllEntries(ctx, d3.(fs.Directory))
+		require.NoError(t, cerr)
+
+		entryCounts = append(entryCounts, len(entries))
+	}
+
+	sort.Ints(entryCounts)
+
+	// checkpoints are only made once entries have completed, others may complete in the meantime.
+	require.GreaterOrEqual(t, entryCounts[0], 10)
+	require.GreaterOrEqual(t, entryCounts[1], 20)
+}
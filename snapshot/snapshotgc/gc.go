@@ -73,11 +73,18 @@ func findInUseContentIDs(ctx context.Context, rep repo.Repository, used *bigmap.
 }
 
 // Run performs garbage collection on all the snapshots in the repository.
-func Run(ctx context.Context, rep repo.DirectRepositoryWriter, gcDelete bool, safety maintenance.SafetyParameters, maintenanceStartTime time.Time) (Stats, error) {
-	var st Stats
+func Run(ctx context.Context, rep repo.DirectRepositoryWriter, gcDelete bool, safety maintenance.SafetyParameters, maintenanceStartTime time.Time, opts ...Option) (Stats, error) {
+	var (
+		st Stats
+		o  options
+	)
+
+	for _, opt := range opts {
+		opt(&o)
+	}
 
 	err := maintenance.ReportRun(ctx, rep, maintenance.TaskSnapshotGarbageCollection, nil, func() error {
-		if err := runInternal(ctx, rep, gcDelete, safety, maintenanceStartTime, &st); err != nil {
+		if err := runInternal(ctx, rep, gcDelete, safety, maintenanceStartTime, &o, &st); err != nil {
 			return err
 		}
 
@@ -98,7 +105,7 @@ func Run(ctx context.Context, rep repo.DirectRepositoryWriter, gcDelete bool, sa
 	return st, errors.Wrap(err, "error running snapshot gc")
 }
 
-func runInternal(ctx context.Context, rep repo.DirectRepositoryWriter, gcDelete bool, safety maintenance.SafetyParameters, maintenanceStartTime time.Time, st *Stats) error {
+func runInternal(ctx context.Context, rep repo.DirectRepositoryWriter, gcDelete bool, safety maintenance.SafetyParameters, maintenanceStartTime time.Time, o *options, st *Stats) error {
 	var unused, inUse, system, tooRecent, undeleted stats.CountSum
 
 	used, serr := bigmap.NewSet(ctx)
@@ -111,6 +118,12 @@ func runInternal(ctx context.Context, rep repo.DirectRepositoryWriter, gcDelete
 		return errors.Wrap(err, "unable to find in-use content ID")
 	}
 
+	if o.unreferencedReport != nil {
+		if _, err := writeUnreferencedContentReport(ctx, rep, used, safety, maintenanceStartTime, o.unreferencedReport); err != nil {
+			return errors.Wrap(err, "unable to write report of unreferenced contents")
+		}
+	}
+
 	log(ctx).Info("Looking for unreferenced contents...")
 
 	// Ensure that the iteration includes deleted contents, so those can be
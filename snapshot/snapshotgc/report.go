@@ -0,0 +1,176 @@
+package snapshotgc
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"io"
+	"time"
+
+	"github.com/pkg/errors"
+
+	"github.com/kopia/kopia/internal/bigmap"
+	"github.com/kopia/kopia/internal/stats"
+	"github.com/kopia/kopia/repo"
+	"github.com/kopia/kopia/repo/content"
+	"github.com/kopia/kopia/repo/maintenance"
+	"github.com/kopia/kopia/repo/manifest"
+)
+
+// UnreferencedContent describes a content that is not referenced by any snapshot.
+type UnreferencedContent struct {
+	ContentID    content.ID `json:"contentID"`
+	PackedLength uint32     `json:"packedLength"`
+	Timestamp    time.Time  `json:"timestamp"`
+	AgeSeconds   int64      `json:"ageSeconds"`
+
+	// TooRecent is set when the content is not old enough to be deleted by garbage collection yet.
+	TooRecent bool `json:"tooRecent,omitempty"`
+
+	// Deleted is set when the content has already been marked as deleted.
+	Deleted bool `json:"deleted,omitempty"`
+}
+
+// UnreferencedContentSummary summarizes the contents listed in the report.
+type UnreferencedContentSummary struct {
+	UnusedCount    uint32 `json:"unusedCount"`
+	UnusedBytes    int64  `json:"unusedBytes"`
+	TooRecentCount uint32 `json:"tooRecentCount"`
+	TooRecentBytes int64  `json:"tooRecentBytes"`
+}
+
+// Option customizes the behavior of Run.
+type Option func(o *options)
+
+type options struct {
+	unreferencedReport io.Writer
+}
+
+// WithUnreferencedContentReport returns an option that writes a JSON report listing all contents not referenced
+// by any snapshot to the provided writer, before any of them are deleted.
+func WithUnreferencedContentReport(w io.Writer) Option {
+	return func(o *options) {
+		o.unreferencedReport = w
+	}
+}
+
+// ExportUnreferencedContents writes a JSON report listing all contents not referenced by any snapshot
+// to the provided writer without modifying the repository, which shows what garbage collection
+// running at the provided time would delete.
+func ExportUnreferencedContents(ctx context.Context, rep repo.DirectRepository, safety maintenance.SafetyParameters, maintenanceStartTime time.Time, w io.Writer) (UnreferencedContentSummary, error) {
+	used, err := bigmap.NewSet(ctx)
+	if err != nil {
+		return UnreferencedContentSummary{}, errors.Wrap(err, "unable to create new set")
+	}
+	defer used.Close(ctx)
+
+	if err := findInUseContentIDs(ctx, rep, used); err != nil {
+		return UnreferencedContentSummary{}, errors.Wrap(err, "unable to find in-use content ID")
+	}
+
+	return writeUnreferencedContentReport(ctx, rep, used, safety, maintenanceStartTime, w)
+}
+
+func writeUnreferencedContentReport(ctx context.Context, rep repo.DirectRepository, used *bigmap.Set, safety maintenance.SafetyParameters, maintenanceStartTime time.Time, w io.Writer) (UnreferencedContentSummary, error) {
+	var (
+		unused, tooRecent stats.CountSum
+		summary           UnreferencedContentSummary
+	)
+
+	log(ctx).Info("Writing report of unreferenced contents...")
+
+	bw := bufio.NewWriter(w)
+
+	if err := writeReportHeader(bw, maintenanceStartTime, safety); err != nil {
+		return summary, err
+	}
+
+	first := true
+
+	err := rep.ContentReader().IterateContents(ctx, content.IterateOptions{IncludeDeleted: true}, func(ci content.Info) error {
+		if manifest.ContentPrefix == ci.ContentID.Prefix() {
+			return nil
+		}
+
+		var cidbuf [128]byte
+
+		if used.Contains(ci.ContentID.Append(cidbuf[:0])) {
+			return nil
+		}
+
+		age := maintenanceStartTime.Sub(ci.Timestamp())
+
+		uc := UnreferencedContent{
+			ContentID:    ci.ContentID,
+			PackedLength: ci.PackedLength,
+			Timestamp:    ci.Timestamp(),
+			AgeSeconds:   int64(age.Seconds()),
+			TooRecent:    age < safety.MinContentAgeSubjectToGC,
+			Deleted:      ci.Deleted,
+		}
+
+		if uc.TooRecent {
+			tooRecent.Add(int64(ci.PackedLength))
+		} else {
+			unused.Add(int64(ci.PackedLength))
+		}
+
+		b, err := json.Marshal(uc)
+		if err != nil {
+			return errors.Wrap(err, "unable to marshal content")
+		}
+
+		sep := ",\n    "
+		if first {
+			sep = "\n    "
+			first = false
+		}
+
+		if _, err := bw.WriteString(sep); err != nil {
+			return errors.Wrap(err, "error writing report")
+		}
+
+		_, err = bw.Write(b)
+
+		return errors.Wrap(err, "error writing report")
+	})
+	if err != nil {
+		return summary, errors.Wrap(err, "error iterating contents")
+	}
+
+	summary.UnusedCount, summary.UnusedBytes = unused.Approximate()
+	summary.TooRecentCount, summary.TooRecentBytes = tooRecent.Approximate()
+
+	if err := writeReportFooter(bw, summary); err != nil {
+		return summary, err
+	}
+
+	return summary, errors.Wrap(bw.Flush(), "error writing report")
+}
+
+func writeReportHeader(w *bufio.Writer, maintenanceStartTime time.Time, safety maintenance.SafetyParameters) error {
+	st, err := json.Marshal(maintenanceStartTime)
+	if err != nil {
+		return errors.Wrap(err, "unable to marshal time")
+	}
+
+	minAge, err := json.Marshal(safety.MinContentAgeSubjectToGC.String())
+	if err != nil {
+		return errors.Wrap(err, "unable to marshal duration")
+	}
+
+	_, err = w.WriteString("{\n  \"maintenanceStartTime\": " + string(st) + ",\n  \"minContentAgeSubjectToGC\": " + string(minAge) + ",\n  \"contents\": [")
+
+	return errors.Wrap(err, "error writing report")
+}
+
+func writeReportFooter(w *bufio.Writer, summary UnreferencedContentSummary) error {
+	b, err := json.Marshal(summary)
+	if err != nil {
+		return errors.Wrap(err, "unable to marshal summary")
+	}
+
+	_, err = w.WriteString("\n  ],\n  \"summary\": " + string(b) + "\n}\n")
+
+	return errors.Wrap(err, "error writing report")
+}
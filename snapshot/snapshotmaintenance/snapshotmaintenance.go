@@ -11,14 +11,14 @@
 	"github.com/kopia/kopia/snapshot/snapshotgc"
 )
 
-// Run runs the complete snapshot and repository maintenance.
-func Run(ctx context.Context, dr repo.DirectRepositoryWriter, mode maintenance.Mode, force bool, safety maintenance.SafetyParameters) error {
+// Run runs the complete snapshot and repository maintenance, the provided options are passed to snapshot GC.
+func Run(ctx context.Context, dr repo.DirectRepositoryWriter, mode maintenance.Mode, force bool, safety maintenance.SafetyParameters, gcOptions ...snapshotgc.Option) error {
 	//nolint:wrapcheck
 	return maintenance.RunExclusive(ctx, dr, mode, force,
 		func(ctx context.Context, runParams maintenance.RunParameters) error {
 			// run snapshot GC before full maintenance
 			if runParams.Mode == maintenance.ModeFull {
-				if _, err := snapshotgc.Run(ctx, dr, true, safety, runParams.MaintenanceStartTime); err != nil {
+				if _, err := snapshotgc.Run(ctx, dr, true, safety, runParams.MaintenanceStartTime, gcOptions...); err != nil {
 					return errors.Wrap(err, "snapshot GC failure")
 				}
 			}
@@ -4,6 +4,7 @@
 	"sync/atomic"
 
 	"github.com/kopia/kopia/fs"
+	"github.com/kopia/kopia/repo/object"
 )
 
 // Stats keeps track of snapshot generation statistics.
@@ -14,6 +15,20 @@ type Stats struct {
 	// +checkatomic
 	ExcludedTotalFileSize int64 `json:"excludedTotalSize"`
 
+	// statistics of chunks written by the splitter, which can be used to tune splitter parameters.
+	// +checkatomic
+	ChunkCount int64 `json:"chunkCount,omitempty"`
+	// +checkatomic
+	TotalChunkBytes int64 `json:"totalChunkBytes,omitempty"`
+	// +checkatomic
+	MinChunkSize int64 `json:"minChunkSize,omitempty"`
+	// +checkatomic
+	MaxChunkSize int64 `json:"maxChunkSize,omitempty"`
+	// +checkatomic
+	DeduplicatedChunks int64 `json:"deduplicatedChunks,omitempty"`
+	// +checkatomic
+	DeduplicatedChunkBytes int64 `json:"deduplicatedChunkBytes,omitempty"`
+
 	// keep all int32 aligned because they will be atomically updated
 	// +checkatomic
 	TotalFileCount int32 `json:"fileCount"`
@@ -45,3 +60,42 @@ func (s *Stats) AddExcluded(md fs.Entry) {
 		atomic.AddInt64(&s.ExcludedTotalFileSize, md.Size())
 	}
 }
+
+// AddWriterStats adds chunk statistics of an object writer to the statistics.
+func (s *Stats) AddWriterStats(ws object.WriterStats) {
+	if ws.ChunkCount == 0 {
+		return
+	}
+
+	atomic.AddInt64(&s.TotalChunkBytes, ws.TotalChunkBytes)
+	atomic.AddInt64(&s.DeduplicatedChunks, ws.DeduplicatedChunks)
+	atomic.AddInt64(&s.DeduplicatedChunkBytes, ws.DeduplicatedBytes)
+
+	for {
+		v := atomic.LoadInt64(&s.MaxChunkSize)
+		if v >= ws.MaxChunkSize || atomic.CompareAndSwapInt64(&s.MaxChunkSize, v, ws.MaxChunkSize) {
+			break
+		}
+	}
+
+	for {
+		v := atomic.LoadInt64(&s.MinChunkSize)
+		if (v != 0 && v <= ws.MinChunkSize) || atomic.CompareAndSwapInt64(&s.MinChunkSize, v, ws.MinChunkSize) {
+			break
+		}
+	}
+
+	atomic.AddInt64(&s.ChunkCount, ws.ChunkCount)
+}
+
+// ChunkStats returns aggregated chunk statistics.
+func (s *Stats) ChunkStats() object.WriterStats {
+	return object.WriterStats{
+		ChunkCount:         atomic.LoadInt64(&s.ChunkCount),
+		TotalChunkBytes:    atomic.LoadInt64(&s.TotalChunkBytes),
+		MinChunkSize:       atomic.LoadInt64(&s.MinChunkSize),
+		MaxChunkSize:       atomic.LoadInt64(&s.MaxChunkSize),
+		DeduplicatedChunks: atomic.LoadInt64(&s.DeduplicatedChunks),
+		DeduplicatedBytes:  atomic.LoadInt64(&s.DeduplicatedChunkBytes),
+	}
+}
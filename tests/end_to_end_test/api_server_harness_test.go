@@ -0,0 +1,67 @@
+package endtoend_test
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/kopia/kopia/internal/servertesting"
+	"github.com/kopia/kopia/internal/testlogging"
+	"github.com/kopia/kopia/repo"
+	"github.com/kopia/kopia/repo/content"
+	"github.com/kopia/kopia/snapshot"
+	"github.com/kopia/kopia/tests/clitestutil"
+	"github.com/kopia/kopia/tests/testenv"
+)
+
+func TestStartAPIServer(t *testing.T) {
+	t.Parallel()
+
+	ctx := testlogging.Context(t)
+
+	serverEnvironment := testenv.NewCLITest(t, testenv.RepoFormatNotImportant, testenv.NewInProcRunner(t))
+
+	defer serverEnvironment.RunAndExpectSuccess(t, "repo", "disconnect")
+
+	serverEnvironment.RunAndExpectSuccess(t, "repo", "create", "filesystem", "--path", serverEnvironment.RepoDir)
+
+	srv := serverEnvironment.StartAPIServer(t, testenv.APIServerOptions{
+		Users: map[string]string{
+			"foo@bar":          "baz",
+			"alice@wonderland": "qux",
+		},
+		EnableACL: true,
+		ACLs: []testenv.APIServerACL{
+			{User: "foo@bar", Target: "type=snapshot", Access: "READ"},
+		},
+	})
+
+	fooBar := testenv.NewCLITest(t, testenv.RepoFormatNotImportant, testenv.NewInProcRunner(t))
+	srv.ConnectClient(t, fooBar, "foo", "bar", "baz")
+
+	alice := testenv.NewCLITest(t, testenv.RepoFormatNotImportant, testenv.NewInProcRunner(t))
+	srv.ConnectClient(t, alice, "alice", "wonderland", "qux")
+
+	alice.RunAndExpectSuccess(t, "snapshot", "create", sharedTestDataDir1)
+
+	// foo@bar can read snapshots of all users, alice@wonderland only her own.
+	require.Len(t, clitestutil.ListSnapshotsAndExpectSuccess(t, fooBar, "-a"), 1)
+
+	fooBar.RunAndExpectSuccess(t, "snapshot", "create", sharedTestDataDir1)
+
+	require.Len(t, clitestutil.ListSnapshotsAndExpectSuccess(t, fooBar, "-a"), 2)
+	require.Len(t, clitestutil.ListSnapshotsAndExpectSuccess(t, alice, "-a"), 1)
+
+	// the server info is usable by repository clients.
+	rep, err := servertesting.ConnectAndOpenAPIServer(t, ctx, srv.Info, repo.ClientOptions{
+		Username: "alice",
+		Hostname: "wonderland",
+	}, content.CachingOptions{}, "qux", &repo.Options{})
+	require.NoError(t, err)
+
+	defer rep.Close(ctx)
+
+	manifests, err := snapshot.ListSnapshotManifests(ctx, rep, nil, nil)
+	require.NoError(t, err)
+	require.Len(t, manifests, 1)
+}
@@ -1,6 +1,8 @@
 package endtoend_test
 
 import (
+	"bytes"
+	"crypto/rand"
 	"os"
 	"path/filepath"
 	"reflect"
@@ -96,3 +98,114 @@ func containsLineStartingWith(lines []string, prefix string) bool {
 
 	return false
 }
+
+func (s *formatSpecificTestSuite) TestAdaptiveCompression(t *testing.T) {
+	t.Parallel()
+
+	runner := testenv.NewInProcRunner(t)
+	e := testenv.NewCLITest(t, s.formatFlags, runner)
+
+	defer e.RunAndExpectSuccess(t, "repo", "disconnect")
+
+	e.RunAndExpectSuccess(t, "repo", "create", "filesystem", "--path", e.RepoDir)
+
+	if !containsLineStartingWith(e.RunAndExpectSuccess(t, "repo", "status"), "Content compression: true") {
+		t.Skip("content compression not supported")
+	}
+
+	e.RunAndExpectSuccess(t, "policy", "set", "--global", "--compression", "pgzip", "--compression-adaptive=true")
+	require.Contains(t, strings.Join(e.RunAndExpectSuccess(t, "policy", "show", "--global"), "\n"), "Skip incompressible data:")
+
+	dataDir := testutil.TempDirectory(t)
+
+	random := make([]byte, 100000)
+	rand.Read(random)
+
+	require.NoError(t, os.WriteFile(filepath.Join(dataDir, "random"), random, 0o600))
+	require.NoError(t, os.WriteFile(filepath.Join(dataDir, "text"), bytes.Repeat([]byte("hello world\n"), 10000), 0o600))
+
+	e.RunAndExpectSuccess(t, "snapshot", "create", dataDir)
+	sources := clitestutil.ListSnapshotsAndExpectSuccess(t, e)
+	entries := clitestutil.ListDirectory(t, e, sources[0].Snapshots[0].ObjectID)
+
+	compressionByObjectID := map[string]bool{}
+
+	for _, l := range e.RunAndExpectSuccess(t, "content", "ls", "-c") {
+		for _, ent := range entries {
+			if strings.HasPrefix(l, ent.ObjectID) {
+				compressionByObjectID[ent.ObjectID] = strings.Contains(l, "pgzip")
+			}
+		}
+	}
+
+	require.Len(t, compressionByObjectID, 2)
+
+	for _, ent := range entries {
+		require.Equal(t, ent.Name == "text", compressionByObjectID[ent.ObjectID], ent.Name)
+	}
+}
+
+func (s *formatSpecificTestSuite) TestCompressionRules(t *testing.T) {
+	t.Parallel()
+
+	runner := testenv.NewInProcRunner(t)
+	e := testenv.NewCLITest(t, s.formatFlags, runner)
+
+	defer e.RunAndExpectSuccess(t, "repo", "disconnect")
+
+	e.RunAndExpectSuccess(t, "repo", "create", "filesystem", "--path", e.RepoDir)
+
+	if !containsLineStartingWith(e.RunAndExpectSuccess(t, "repo", "status"), "Content compression: true") {
+		t.Skip("content compression not supported")
+	}
+
+	e.RunAndExpectSuccess(t, "policy", "set", "--global", "--compression", "pgzip",
+		"--add-compression-rule", ".txt=zstd",
+		"--add-compression-rule", "image/*=none")
+	e.RunAndExpectFailure(t, "policy", "set", "--global", "--add-compression-rule", ".txt=no-such-algorithm")
+	e.RunAndExpectFailure(t, "policy", "set", "--global", "--add-compression-rule", "txt")
+
+	policyLines := strings.Join(e.RunAndExpectSuccess(t, "policy", "show", "--global"), "\n")
+	require.Contains(t, policyLines, "Compression rules (first match applies):")
+	require.Contains(t, policyLines, "image/*")
+
+	dataDir := testutil.TempDirectory(t)
+
+	for i, name := range []string{"file.txt", "photo.jpg", "other"} {
+		require.NoError(t, os.WriteFile(filepath.Join(dataDir, name), bytes.Repeat([]byte(name+" hello world\n"), 1000*(i+1)), 0o600))
+	}
+
+	e.RunAndExpectSuccess(t, "snapshot", "create", dataDir)
+	sources := clitestutil.ListSnapshotsAndExpectSuccess(t, e)
+	entries := clitestutil.ListDirectory(t, e, sources[0].Snapshots[0].ObjectID)
+
+	contentLines := e.RunAndExpectSuccess(t, "content", "ls", "-c")
+
+	compressionByName := map[string]string{}
+
+	for _, ent := range entries {
+		for _, l := range contentLines {
+			if !strings.HasPrefix(l, ent.ObjectID) {
+				continue
+			}
+
+			switch {
+			case strings.Contains(l, "zstd"):
+				compressionByName[ent.Name] = "zstd"
+			case strings.Contains(l, "pgzip"):
+				compressionByName[ent.Name] = "pgzip"
+			default:
+				compressionByName[ent.Name] = "none"
+			}
+		}
+	}
+
+	require.Equal(t, map[string]string{
+		"file.txt":  "zstd",
+		"photo.jpg": "none",
+		"other":     "pgzip",
+	}, compressionByName)
+
+	e.RunAndExpectSuccess(t, "policy", "set", "--global", "--remove-compression-rule", "image/*")
+	require.NotContains(t, strings.Join(e.RunAndExpectSuccess(t, "policy", "show", "--global"), "\n"), "image/*")
+}
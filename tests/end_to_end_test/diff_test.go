@@ -8,6 +8,7 @@
 	"github.com/stretchr/testify/require"
 
 	"github.com/kopia/kopia/internal/testutil"
+	"github.com/kopia/kopia/snapshot/snapshotfs"
 	"github.com/kopia/kopia/tests/clitestutil"
 	"github.com/kopia/kopia/tests/testenv"
 )
@@ -71,4 +72,24 @@ func TestDiff(t *testing.T) {
 			e.RunAndExpectSuccess(t, "diff", "-f", s1.ObjectID, s2.ObjectID)
 		}
 	}
+
+	var changes []snapshotfs.EntryChange
+
+	testutil.MustParseJSONLines(t, e.RunAndExpectSuccess(t, "diff", "--json", snapshots[0].ObjectID, snapshots[1].ObjectID), &changes)
+
+	changeTypes := map[string]snapshotfs.EntryChangeType{}
+	for _, ch := range changes {
+		changeTypes[ch.Path] = ch.Change
+	}
+
+	require.Equal(t, snapshotfs.EntryAdded, changeTypes["foo"])
+	require.Equal(t, snapshotfs.EntryAdded, changeTypes["some-file1"])
+	require.Equal(t, snapshotfs.EntryAdded, changeTypes["some-file2"])
+
+	testutil.MustParseJSONLines(t, e.RunAndExpectSuccess(t, "diff", "--json", snapshots[2].ObjectID, snapshots[3].ObjectID), &changes)
+
+	last := changes[len(changes)-1]
+	require.Equal(t, "some-file1", last.Path)
+	require.Equal(t, snapshotfs.EntryRemoved, last.Change)
+	require.EqualValues(t, -25, last.SizeDelta())
 }
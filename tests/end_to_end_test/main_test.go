@@ -11,6 +11,7 @@
 
 	"github.com/kopia/kopia/internal/testutil"
 	"github.com/kopia/kopia/tests/testdirtree"
+	"github.com/kopia/kopia/tests/testenv"
 )
 
 var (
@@ -79,5 +80,5 @@ func TestMain(m *testing.M) {
 		log.Fatalf("error setting up test: %v", err)
 	}
 
-	testutil.MyTestMain(m, oneTimeCleanup)
+	testutil.MyTestMain(m, oneTimeCleanup, testenv.CleanupRepositoryFixtures)
 }
@@ -10,8 +10,13 @@
 	"github.com/google/uuid"
 	"github.com/stretchr/testify/require"
 
+	"github.com/kopia/kopia/cli"
+	"github.com/kopia/kopia/internal/fsstress"
 	"github.com/kopia/kopia/internal/testutil"
+	"github.com/kopia/kopia/repo/blob"
+	"github.com/kopia/kopia/repo/blob/sqlite"
 	"github.com/kopia/kopia/repo/format"
+	"github.com/kopia/kopia/tests/clitestutil"
 	"github.com/kopia/kopia/tests/testenv"
 )
 
@@ -43,6 +48,35 @@ func TestFilesystemRequiresAbsolutePaths(t *testing.T) {
 	e.RunAndExpectFailure(t, "repo", "create", "filesystem", "--path", "./relative-path")
 }
 
+func TestSQLiteRepository(t *testing.T) {
+	t.Parallel()
+
+	if !sqlite.Supported {
+		t.Skip("SQLite is not supported on this platform")
+	}
+
+	runner := testenv.NewInProcRunner(t)
+	e := testenv.NewCLITest(t, testenv.RepoFormatNotImportant, runner)
+
+	dbPath := filepath.Join(e.RepoDir, "repo.db")
+
+	e.RunAndExpectFailure(t, "repo", "connect", "sqlite", "--path", dbPath)
+	e.RunAndExpectSuccess(t, "repo", "create", "sqlite", "--path", dbPath)
+	e.RunAndExpectSuccess(t, "snapshot", "create", testutil.TempDirectory(t))
+	e.RunAndExpectSuccess(t, "repo", "disconnect")
+
+	e.RunAndExpectSuccess(t, "repo", "connect", "sqlite", "--path", dbPath)
+	require.Len(t, clitestutil.ListSnapshotsAndExpectSuccess(t, e), 1)
+
+	// the repository consists of the database file and its SQLite journal files only.
+	entries, err := os.ReadDir(e.RepoDir)
+	require.NoError(t, err)
+
+	for _, ent := range entries {
+		require.True(t, strings.HasPrefix(ent.Name(), "repo.db"), ent.Name())
+	}
+}
+
 func TestFilesystemSupportsTildeToReferToHome(t *testing.T) {
 	t.Parallel()
 
@@ -127,6 +161,34 @@ func TestRepoConnectKeyDerivationAlgorithm(t *testing.T) {
 	}
 }
 
+func TestRepoConnectArgon2idKeyDerivation(t *testing.T) {
+	t.Parallel()
+
+	runner := testenv.NewInProcRunner(t)
+	e := testenv.NewCLITest(t, testenv.RepoFormatNotImportant, runner)
+
+	e.RunAndExpectFailure(t, "repo", "create", "filesystem", "--path", e.RepoDir, "--kdf=argon2id", "--kdf-argon2id-memory=1MiB")
+	e.RunAndExpectFailure(t, "repo", "create", "filesystem", "--path", e.RepoDir, "--kdf=argon2id", "--kdf-argon2id-memory=4194305KiB")
+	e.RunAndExpectFailure(t, "repo", "create", "filesystem", "--path", e.RepoDir, "--kdf=argon2id", "--kdf-argon2id-memory=4PiB")
+
+	// argon2id parameters require argon2id key derivation.
+	e.RunAndExpectFailure(t, "repo", "create", "filesystem", "--path", e.RepoDir, "--kdf-argon2id-memory=16MiB")
+	e.RunAndExpectFailure(t, "repo", "create", "filesystem", "--path", e.RepoDir, "--kdf=scrypt", "--kdf-argon2id-iterations=2")
+	e.RunAndExpectSuccess(t, "repo", "create", "filesystem", "--path", e.RepoDir, "--kdf=argon2id",
+		"--kdf-argon2id-memory=16MiB", "--kdf-argon2id-iterations=2", "--kdf-argon2id-parallelism=1")
+
+	e.RunAndExpectSuccess(t, "repo", "disconnect")
+	e.RunAndExpectSuccess(t, "repo", "connect", "filesystem", "--path", e.RepoDir)
+
+	dat, err := os.ReadFile(filepath.Join(e.RepoDir, "kopia.repository.f"))
+	require.NoError(t, err)
+
+	var repoJSON format.KopiaRepositoryJSON
+
+	require.NoError(t, json.Unmarshal(dat, &repoJSON))
+	require.Equal(t, "argon2id-16384-2-1", repoJSON.KeyDerivationAlgorithm)
+}
+
 func TestRepoConnectBadKeyDerivationAlgorithm(t *testing.T) {
 	t.Parallel()
 	runner := testenv.NewInProcRunner(t)
@@ -148,3 +210,80 @@ func TestRepoConnectBadKeyDerivationAlgorithm(t *testing.T) {
 
 	e.RunAndExpectFailure(t, "repo", "connect", "filesystem", "--path", e.RepoDir)
 }
+
+func TestS3RepositoryOnMinIO(t *testing.T) {
+	t.Parallel()
+
+	bucket := testenv.StartMinIO(t)
+
+	runner := testenv.NewInProcRunner(t)
+	e := testenv.NewCLITest(t, testenv.RepoFormatNotImportant, runner)
+
+	e.RunAndExpectSuccess(t, append([]string{"repo", "create"}, bucket.RepoArgs("repo1/")...)...)
+	e.RunAndExpectSuccess(t, "snapshot", "create", sharedTestDataDir1)
+	e.RunAndExpectSuccess(t, "repo", "disconnect")
+
+	e.RunAndExpectSuccess(t, append([]string{"repo", "connect"}, bucket.RepoArgs("repo1/")...)...)
+
+	var snapshots []cli.SnapshotManifest
+
+	testutil.MustParseJSONLines(t, e.RunAndExpectSuccess(t, "snapshot", "list", "--json", sharedTestDataDir1), &snapshots)
+	require.Len(t, snapshots, 1)
+}
+
+func TestS3RepositoryOnObjectLockedMinIO(t *testing.T) {
+	t.Parallel()
+
+	bucket := testenv.StartMinIOWithOptions(t, testenv.S3BucketOptions{
+		ObjectLocking: true,
+		RetentionMode: blob.Governance,
+		RetentionDays: 1,
+	})
+
+	runner := testenv.NewInProcRunner(t)
+	e := testenv.NewCLITest(t, testenv.RepoFormatNotImportant, runner)
+
+	e.RunAndExpectSuccess(t, append([]string{
+		"repo", "create",
+		"--retention-mode", blob.Governance.String(),
+		"--retention-period", "24h",
+	}, bucket.RepoArgs("repo1/")...)...)
+
+	// repeatedly snapshot a changing tree and run maintenance, so that blobs under retention
+	// get rewritten and deleted.
+	g := fsstress.NewGenerator(testutil.TempDirectory(t), 1)
+	require.NoError(t, g.CreateTree(fsstress.TreeSpec{FileCount: 100, DirCount: 5, MaxFileSize: 10000}))
+
+	for range 5 {
+		e.RunAndExpectSuccess(t, "snapshot", "create", g.Dir())
+
+		_, err := g.Tweak(fsstress.ChurnModel{AddPercent: 10, DeletePercent: 10, ModifyPercent: 10, RenamePercent: 5})
+		require.NoError(t, err)
+
+		e.RunAndExpectSuccess(t, "maintenance", "run", "--full", "--force", "--safety=none")
+	}
+
+	e.RunAndExpectSuccess(t, "snapshot", "verify", "--verify-files-percent=100")
+	e.RunAndExpectSuccess(t, "content", "verify")
+}
+
+func TestReadonlyConnectWithWritablePrefix(t *testing.T) {
+	t.Parallel()
+
+	runner := testenv.NewInProcRunner(t)
+	e := testenv.NewCLITest(t, testenv.RepoFormatNotImportant, runner)
+
+	e.RunAndExpectSuccess(t, "repo", "create", "filesystem", "--path", e.RepoDir)
+	e.RunAndExpectSuccess(t, "snapshot", "create", testutil.TempDirectory(t))
+	e.RunAndExpectSuccess(t, "repo", "disconnect")
+
+	e.RunAndExpectSuccess(t, "repo", "connect", "filesystem", "--path", e.RepoDir, "--readonly", "--readonly-writable-prefix=_log")
+
+	logBlobs := e.RunAndExpectSuccess(t, "blob", "list", "--prefix=_log")
+
+	// logs are written, but the repository remains read-only otherwise.
+	e.RunAndExpectSuccess(t, "snapshot", "list")
+	e.RunAndExpectFailure(t, "snapshot", "create", testutil.TempDirectory(t))
+
+	require.Greater(t, len(e.RunAndExpectSuccess(t, "blob", "list", "--prefix=_log")), len(logBlobs))
+}
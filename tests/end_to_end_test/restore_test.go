@@ -153,6 +153,18 @@ func TestRestoreCommand(t *testing.T) {
 	require.NoError(t, os.Chmod(restoreDir, 0o700))
 	compareDirs(t, source, restoreDir)
 
+	// Restore with prefetching of file contents
+	prefetchRestoreDir := testutil.TempDirectory(t)
+	e.RunAndExpectSuccess(t, "restore", rootID, prefetchRestoreDir, "--prefetch")
+	require.NoError(t, os.Chmod(prefetchRestoreDir, 0o700))
+	compareDirs(t, source, prefetchRestoreDir)
+
+	// Restore with separately sized download and write pools
+	poolsRestoreDir := testutil.TempDirectory(t)
+	e.RunAndExpectSuccess(t, "restore", rootID, poolsRestoreDir, "--parallel=6", "--parallel-downloads=3", "--parallel-writes=3")
+	require.NoError(t, os.Chmod(poolsRestoreDir, 0o700))
+	compareDirs(t, source, poolsRestoreDir)
+
 	// Attempt to restore into a target directory that already exists
 	e.RunAndExpectFailure(t, "restore", rootID, restoreDir, "--no-overwrite-directories")
 
@@ -0,0 +1,24 @@
+package endtoend_test
+
+import (
+	"testing"
+
+	"github.com/kopia/kopia/tests/testenv"
+)
+
+func TestSnapshotLifecycleWithAllRunners(t *testing.T) {
+	t.Parallel()
+
+	testenv.RunWithAllRunners(t, func(t *testing.T, runner testenv.CLIRunner) {
+		e := testenv.NewCLITest(t, testenv.RepoFormatNotImportant, runner)
+
+		e.RunAndExpectSuccess(t, "repo", "create", "filesystem", "--path", e.RepoDir)
+		e.RunAndExpectSuccess(t, "snapshot", "create", sharedTestDataDir1)
+		e.RunAndExpectSuccess(t, "snapshot", "list", "--json-indent", "--json")
+		e.RunAndExpectSuccess(t, "policy", "set", "--global", "--keep-latest=5")
+		e.RunAndExpectSuccess(t, "policy", "show", "--global")
+		e.RunAndExpectFailure(t, "snapshot", "list", "--no-such-flag")
+		e.RunAndExpectFailure(t, "snapshot", "delete", "no-such-snapshot", "--delete")
+		e.RunAndExpectSuccess(t, "repo", "disconnect")
+	})
+}
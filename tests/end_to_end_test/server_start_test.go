@@ -256,8 +256,7 @@ func TestServerStartAsyncRepoConnect(t *testing.T) {
 func TestServerCreateAndConnectViaAPI(t *testing.T) {
 	t.Parallel()
 
-	//nolint:tenv
-	os.Setenv("KOPIA_UPGRADE_LOCK_ENABLED", "true")
+	testutil.EnvScope(t, map[string]string{"KOPIA_UPGRADE_LOCK_ENABLED": "true"})
 
 	ctx := testlogging.Context(t)
 
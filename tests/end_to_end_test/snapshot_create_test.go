@@ -1,6 +1,8 @@
 package endtoend_test
 
 import (
+	"archive/tar"
+	"bytes"
 	"os"
 	"path"
 	"path/filepath"
@@ -641,6 +643,66 @@ func TestSnapshotCreateWithStdinStream(t *testing.T) {
 	}
 }
 
+func TestSnapshotCreateWithStdinTarStream(t *testing.T) {
+	t.Parallel()
+
+	runner := testenv.NewInProcRunner(t)
+	e := testenv.NewCLITest(t, testenv.RepoFormatNotImportant, runner)
+
+	defer e.RunAndExpectSuccess(t, "repo", "disconnect")
+	e.RunAndExpectSuccess(t, "repo", "create", "filesystem", "--path", e.RepoDir)
+
+	files := map[string]string{
+		"dump/schema.sql":        "create table t (x int);",
+		"dump/data/part1.csv":    "1\n2\n3\n",
+		"dump/data/part2.csv":    "4\n5\n6\n",
+		"dump/README":            "database dump",
+		"dump/data/empty.csv":    "",
+		"dump/data/nested/x.sql": "select 1;",
+	}
+
+	var buf bytes.Buffer
+
+	tw := tar.NewWriter(&buf)
+
+	for _, name := range []string{"dump/schema.sql", "dump/data/part1.csv", "dump/data/part2.csv", "dump/data/empty.csv", "dump/data/nested/x.sql", "dump/README"} {
+		require.NoError(t, tw.WriteHeader(&tar.Header{
+			Name:     name,
+			Typeflag: tar.TypeReg,
+			Mode:     0o644,
+			Size:     int64(len(files[name])),
+			ModTime:  time.Date(2021, 1, 2, 3, 4, 5, 0, time.UTC),
+		}))
+
+		_, err := tw.Write([]byte(files[name]))
+		require.NoError(t, err)
+	}
+
+	require.NoError(t, tw.Close())
+
+	runner.SetNextStdin(&buf)
+
+	e.RunAndExpectSuccess(t, "snapshot", "create", "rootdir", "--stdin-file", "backup.tar", "--stdin-format", "tar")
+
+	si := clitestutil.ListSnapshotsAndExpectSuccess(t, e)
+	require.Len(t, si, 1)
+	require.Len(t, si[0].Snapshots, 1)
+
+	rootID := si[0].Snapshots[0].ObjectID
+
+	// contents of the tar stream are browsable as a directory tree.
+	require.Contains(t, e.RunAndExpectSuccess(t, "ls", rootID+"/backup.tar/dump/data"), "nested")
+
+	restoreDir := testutil.TempDirectory(t)
+	e.RunAndExpectSuccess(t, "snapshot", "restore", rootID+"/backup.tar", restoreDir)
+
+	for name, content := range files {
+		got, err := os.ReadFile(filepath.Join(restoreDir, filepath.FromSlash(name)))
+		require.NoError(t, err)
+		require.Equal(t, content, string(got), name)
+	}
+}
+
 func appendIfMissing(slice []string, i string) []string {
 	for _, ele := range slice {
 		if ele == i {
@@ -9,10 +9,15 @@
 	"runtime"
 	"strconv"
 	"strings"
+	"sync/atomic"
+	"syscall"
 	"testing"
 
 	"github.com/stretchr/testify/require"
 
+	"github.com/kopia/kopia/fs"
+	"github.com/kopia/kopia/internal/fault"
+	"github.com/kopia/kopia/internal/faultyfs"
 	"github.com/kopia/kopia/internal/testutil"
 	"github.com/kopia/kopia/snapshot"
 	"github.com/kopia/kopia/tests/testdirtree"
@@ -460,3 +465,94 @@ func parseSnapshotResultJSON(t *testing.T, stdOut, _ []string) parsedSnapshotRes
 		partial:           m.IncompleteReason != "",
 	}
 }
+
+func TestSnapshotFail_InjectedFilesystemErrors(t *testing.T) {
+	t.Parallel()
+
+	for _, tc := range []struct {
+		desc          string
+		entry         string
+		method        fault.Method
+		err           error
+		ignoreFlag    string
+		wantErrorText string
+	}{
+		{
+			desc:          "file cannot be opened",
+			entry:         "dir1/file2",
+			method:        faultyfs.MethodOpen,
+			err:           &os.PathError{Op: "open", Path: "file2", Err: syscall.EACCES},
+			ignoreFlag:    "--ignore-file-errors",
+			wantErrorText: "permission denied",
+		},
+		{
+			desc:          "file cannot be read",
+			entry:         "dir1/file2",
+			method:        faultyfs.MethodRead,
+			err:           &os.PathError{Op: "read", Path: "file2", Err: syscall.EIO},
+			ignoreFlag:    "--ignore-file-errors",
+			wantErrorText: "input/output error",
+		},
+		{
+			desc:          "directory cannot be listed",
+			entry:         "dir1/dir2",
+			method:        faultyfs.MethodIterate,
+			err:           &os.PathError{Op: "readdir", Path: "dir2", Err: syscall.EACCES},
+			ignoreFlag:    "--ignore-dir-errors",
+			wantErrorText: "permission denied",
+		},
+	} {
+		t.Run(tc.desc, func(t *testing.T) {
+			t.Parallel()
+
+			var ffs atomic.Pointer[faultyfs.FS]
+
+			runner := testenv.NewInProcRunner(t, testenv.WithSnapshotSourceWrapper(func(_ string, e fs.Entry) fs.Entry {
+				return ffs.Load().Wrap(e)
+			}))
+			e := testenv.NewCLITest(t, testenv.RepoFormatNotImportant, runner)
+
+			defer e.RunAndExpectSuccess(t, "repo", "disconnect")
+
+			e.RunAndExpectSuccess(t, "repo", "create", "filesystem", "--path", e.RepoDir)
+
+			baseDir := testutil.TempDirectory(t)
+			createSimplestFileTree(t, 3, 0, baseDir)
+
+			source := filepath.Join(baseDir, "dir0")
+
+			// errors are fatal by default.
+			ffs.Store(faultyfs.New())
+			ffs.Load().AddFault(tc.entry, tc.method).ErrorInstead(tc.err)
+
+			_, stderr := e.RunAndExpectFailure(t, "snapshot", "create", source)
+			ffs.Load().VerifyAllFaultsExercised(t)
+
+			require.Equal(t, 1, parseSnapshotResultFromLog(t, nil, stderr).errorCount)
+			require.Contains(t, strings.Join(stderr, "\n"), tc.wantErrorText)
+
+			// the same error is ignored according to the policy.
+			e.RunAndExpectSuccess(t, "policy", "set", source, tc.ignoreFlag+"=true")
+
+			ffs.Store(faultyfs.New())
+			ffs.Load().AddFault(tc.entry, tc.method).ErrorInstead(tc.err)
+
+			_, stderr = e.RunAndExpectSuccessWithErrOut(t, "snapshot", "create", source)
+			ffs.Load().VerifyAllFaultsExercised(t)
+
+			parsed := parseSnapshotResultFromLog(t, nil, stderr)
+			require.Equal(t, 0, parsed.errorCount)
+			require.Equal(t, 1, parsed.ignoredErrorCount)
+			require.NotEmpty(t, parsed.manifestID)
+
+			// without faults, the snapshot does not report any errors.
+			ffs.Store(faultyfs.New())
+
+			_, stderr = e.RunAndExpectSuccessWithErrOut(t, "snapshot", "create", source)
+
+			parsed = parseSnapshotResultFromLog(t, nil, stderr)
+			require.Equal(t, 0, parsed.errorCount)
+			require.Equal(t, 0, parsed.ignoredErrorCount)
+		})
+	}
+}
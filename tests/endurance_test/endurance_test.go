@@ -16,7 +16,7 @@
 
 	"github.com/kopia/kopia/internal/clock"
 	"github.com/kopia/kopia/internal/faketime"
-	"github.com/kopia/kopia/tests/testdirtree"
+	"github.com/kopia/kopia/internal/fsstress"
 	"github.com/kopia/kopia/tests/testenv"
 )
 
@@ -35,7 +35,7 @@
 type webdavDirWithFakeClock struct {
 	webdav.Dir
 
-	fts *testenv.FakeTimeServer
+	fts *faketime.Server
 }
 
 func (d webdavDirWithFakeClock) OpenFile(ctx context.Context, fname string, flags int, mode os.FileMode) (webdav.File, error) {
@@ -51,7 +51,7 @@ func (d webdavDirWithFakeClock) OpenFile(ctx context.Context, fname string, flag
 
 	// change file time after creation to simulate fake time scale.
 	osf := f.(*os.File)
-	now := d.fts.Now()
+	now := d.fts.ClientTime(faketime.StorageClient)
 
 	if err := os.Chtimes(osf.Name(), now, now); err != nil {
 		log.Printf("unable to change file time: %v", err)
@@ -72,12 +72,12 @@ func TestEndurance(t *testing.T) {
 	defer os.RemoveAll(tmpDir)
 
 	testTime := faketime.NewClockTimeWithOffset(startTime.Sub(clock.Now()))
-	fts := testenv.NewFakeTimeServer(testTime.NowFunc())
+	fts := faketime.NewServer(testTime.NowFunc())
 
 	ft := httptest.NewServer(fts)
 	defer ft.Close()
 
-	e.Environment["KOPIA_FAKE_CLOCK_ENDPOINT"] = ft.URL
+	e.Environment[faketime.EndpointEnvVar] = ft.URL
 
 	sts := httptest.NewServer(&webdav.Handler{
 		FileSystem: webdavDirWithFakeClock{webdav.Dir(tmpDir), fts},
@@ -111,7 +111,7 @@ func TestEndurance(t *testing.T) {
 }
 
 type runnerState struct {
-	dirs                []string
+	sources             []*fsstress.Generator
 	snapshottedAnything bool
 	runnerID            int
 	fakeClock           *faketime.ClockTimeWithOffset
@@ -143,7 +143,7 @@ type actionInfo struct {
 func actionSnapshotExisting(t *testing.T, e *testenv.CLITest, s *runnerState) {
 	t.Helper()
 
-	randomPath := s.dirs[rand.Intn(len(s.dirs))]
+	randomPath := s.sources[rand.Intn(len(s.sources))].Dir()
 	e.RunAndExpectSuccess(t, "snapshot", "create", randomPath)
 
 	s.snapshottedAnything = true
@@ -208,7 +208,7 @@ func actionLargeClockJump(t *testing.T, e *testenv.CLITest, s *runnerState) {
 func actionAddNewSource(t *testing.T, e *testenv.CLITest, s *runnerState) {
 	t.Helper()
 
-	if len(s.dirs) >= maxSourcesPerEnduranceRunner {
+	if len(s.sources) >= maxSourcesPerEnduranceRunner {
 		return
 	}
 
@@ -217,27 +217,36 @@ func actionAddNewSource(t *testing.T, e *testenv.CLITest, s *runnerState) {
 		t.Fatalf("err: %v", err)
 	}
 
-	s.dirs = append(s.dirs, srcDir)
+	g := fsstress.NewGenerator(srcDir, rand.Int63())
+	if err := g.CreateTree(fsstress.TreeSpec{
+		FileCount:   100,
+		DirCount:    10,
+		MaxFileSize: 100,
+	}); err != nil {
+		t.Fatalf("unable to create source tree: %v", err)
+	}
 
-	testdirtree.CreateDirectoryTree(srcDir, testdirtree.MaybeSimplifyFilesystem(testdirtree.DirectoryTreeOptions{
-		Depth:                  3,
-		MaxSubdirsPerDirectory: 10,
-		MaxFilesPerDirectory:   10,
-		MaxFileSize:            100,
-	}), &testdirtree.DirectoryTreeCounters{})
+	s.sources = append(s.sources, g)
 }
 
 func actionMutateDirectoryTree(t *testing.T, e *testenv.CLITest, s *runnerState) {
 	t.Helper()
 
-	randomPath := s.dirs[rand.Intn(len(s.dirs))]
+	g := s.sources[rand.Intn(len(s.sources))]
+
+	stats, err := g.Tweak(fsstress.ChurnModel{
+		AddPercent:    10,
+		DeletePercent: 5,
+		ModifyPercent: 10,
+		RenamePercent: 2,
+		AppendPercent: 50,
+		MaxModifySize: 100,
+	})
+	if err != nil {
+		t.Fatalf("unable to mutate source tree: %v", err)
+	}
 
-	testdirtree.CreateDirectoryTree(randomPath, testdirtree.DirectoryTreeOptions{
-		Depth:                  2,
-		MaxSubdirsPerDirectory: 10,
-		MaxFilesPerDirectory:   10,
-		MaxFileSize:            100,
-	}, &testdirtree.DirectoryTreeCounters{})
+	t.Logf("mutated %v: %v", g.Dir(), stats)
 }
 
 func pickRandomEnduranceTestAction() *actionInfo {
@@ -266,7 +275,7 @@ func enduranceRunner(t *testing.T, runnerID int, fakeTimeServer, webdavServer st
 	runner := testenv.NewExeRunner(t)
 	e := testenv.NewCLITest(t, testenv.RepoFormatNotImportant, runner)
 
-	e.Environment["KOPIA_FAKE_CLOCK_ENDPOINT"] = fakeTimeServer
+	e.Environment[faketime.EndpointEnvVar] = fakeTimeServer
 	e.Environment["KOPIA_CHECK_FOR_UPDATES"] = "false"
 
 	e.RunAndExpectSuccess(t, "repo", "connect", "webdav", "--url", webdavServer, "--override-username="+fmt.Sprintf("runner-%v", runnerID))
@@ -6,11 +6,8 @@
 
 import (
 	"context"
-	"crypto/rand"
-	"encoding/hex"
 	"errors"
 	"fmt"
-	"io"
 	"io/fs"
 	"log"
 	"math"
@@ -21,8 +18,6 @@
 	"testing"
 	"time"
 
-	"github.com/minio/minio-go/v7"
-	"github.com/minio/minio-go/v7/pkg/credentials"
 	"github.com/stretchr/testify/require"
 
 	"github.com/kopia/kopia/internal/clock"
@@ -31,6 +26,7 @@
 	"github.com/kopia/kopia/tests/robustness"
 	"github.com/kopia/kopia/tests/robustness/fiofilewriter"
 	"github.com/kopia/kopia/tests/robustness/snapmeta"
+	"github.com/kopia/kopia/tests/testenv"
 	"github.com/kopia/kopia/tests/tools/fio"
 	"github.com/kopia/kopia/tests/tools/fswalker"
 	"github.com/kopia/kopia/tests/tools/kopiarunner"
@@ -96,17 +92,9 @@ func TestEngineWritefilesBasicFS(t *testing.T) {
 	}
 }
 
-func randomString(n int) string {
-	b := make([]byte, n)
-	io.ReadFull(rand.Reader, b)
-
-	return hex.EncodeToString(b)
-}
-
-func makeTempS3Bucket(t *testing.T) (bucketName string, cleanupCB func()) {
+func makeTempS3Bucket(t *testing.T) (bucketName string) {
 	t.Helper()
 
-	endpoint := "s3.amazonaws.com"
 	accessKeyID := os.Getenv("AWS_ACCESS_KEY_ID")
 	secretAccessKey := os.Getenv("AWS_SECRET_ACCESS_KEY")
 	sessionToken := os.Getenv("AWS_SESSION_TOKEN")
@@ -115,58 +103,16 @@ func makeTempS3Bucket(t *testing.T) (bucketName string, cleanupCB func()) {
 		t.Skip("Skipping S3 tests if no creds provided")
 	}
 
-	ctx := testlogging.Context(t)
-
-	cli, err := minio.New(endpoint, &minio.Options{
-		Creds:  credentials.NewStaticV4(accessKeyID, secretAccessKey, sessionToken),
-		Secure: true,
-		Region: "",
-	})
-	require.NoError(t, err)
-
-	bucketName = fmt.Sprintf("engine-unit-tests-%s", randomString(4))
-	err = cli.MakeBucket(ctx, bucketName, minio.MakeBucketOptions{})
-	require.NoError(t, err)
-
-	return bucketName, func() {
-		objChan := make(chan minio.ObjectInfo)
-		errCh := cli.RemoveObjects(ctx, bucketName, objChan, minio.RemoveObjectsOptions{})
-
-		go func() {
-			for removeErr := range errCh {
-				t.Errorf("error removing key %s from bucket: %s", removeErr.ObjectName, removeErr.Err)
-			}
-		}()
-
-		for obj := range cli.ListObjects(ctx, bucketName, minio.ListObjectsOptions{
-			Prefix:    "",
-			Recursive: true,
-		}) {
-			objChan <- obj
-		}
-
-		close(objChan)
-
-		retries := 10
-		retryPeriod := 1 * time.Second
-
-		var err error
-
-		for range retries {
-			time.Sleep(retryPeriod)
-
-			err = cli.RemoveBucket(ctx, bucketName)
-			if err == nil {
-				break
-			}
-		}
-		require.NoError(t, err)
-	}
+	return testenv.CreateTempS3Bucket(t, testenv.S3Bucket{
+		Endpoint:        "s3.amazonaws.com",
+		AccessKeyID:     accessKeyID,
+		SecretAccessKey: secretAccessKey,
+		SessionToken:    sessionToken,
+	}, "engine-unit-tests").BucketName
 }
 
 func TestWriteFilesBasicS3(t *testing.T) {
-	bucketName, cleanupCB := makeTempS3Bucket(t)
-	defer cleanupCB()
+	bucketName := makeTempS3Bucket(t)
 
 	t.Setenv(snapmeta.EngineModeEnvKey, snapmeta.EngineModeBasic)
 	t.Setenv(snapmeta.S3BucketNameEnvKey, bucketName)
@@ -212,8 +158,7 @@ func TestWriteFilesBasicS3(t *testing.T) {
 }
 
 func TestDeleteSnapshotS3(t *testing.T) {
-	bucketName, cleanupCB := makeTempS3Bucket(t)
-	defer cleanupCB()
+	bucketName := makeTempS3Bucket(t)
 
 	t.Setenv(snapmeta.EngineModeEnvKey, snapmeta.EngineModeBasic)
 	t.Setenv(snapmeta.S3BucketNameEnvKey, bucketName)
@@ -260,8 +205,7 @@ func TestDeleteSnapshotS3(t *testing.T) {
 }
 
 func TestSnapshotVerificationFail(t *testing.T) {
-	bucketName, cleanupCB := makeTempS3Bucket(t)
-	defer cleanupCB()
+	bucketName := makeTempS3Bucket(t)
 
 	t.Setenv(snapmeta.EngineModeEnvKey, snapmeta.EngineModeBasic)
 	t.Setenv(snapmeta.S3BucketNameEnvKey, bucketName)
@@ -548,8 +492,7 @@ func TestActionsFilesystem(t *testing.T) {
 }
 
 func TestActionsS3(t *testing.T) {
-	bucketName, cleanupCB := makeTempS3Bucket(t)
-	defer cleanupCB()
+	bucketName := makeTempS3Bucket(t)
 
 	t.Setenv(snapmeta.EngineModeEnvKey, snapmeta.EngineModeBasic)
 	t.Setenv(snapmeta.S3BucketNameEnvKey, bucketName)
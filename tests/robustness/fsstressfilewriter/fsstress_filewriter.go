@@ -0,0 +1,261 @@
+//go:build darwin || (linux && amd64)
+// +build darwin linux,amd64
+
+// Package fsstressfilewriter provides a FileWriter based on the synthetic tree generator
+// in internal/fsstress, which does not require FIO.
+package fsstressfilewriter
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"math/rand"
+	"os"
+	"path/filepath"
+	"strconv"
+	"sync"
+
+	"github.com/pkg/errors"
+
+	"github.com/kopia/kopia/internal/fsstress"
+	"github.com/kopia/kopia/tests/robustness"
+	"github.com/kopia/kopia/tests/robustness/fiofilewriter"
+)
+
+// Option defaults.
+const (
+	defaultDeletePercentOfContents = 20
+	defaultMaxDirDepth             = 20
+	defaultMaxFileSize             = 1 << 20 // 1MB
+	defaultMaxNumFilesPerWrite     = 100
+	defaultMinNumFilesPerWrite     = 1
+)
+
+// FileWriter implements a FileWriter over fsstress.Generator. It understands the same
+// options as fiofilewriter.FileWriter, except for the ones specific to FIO.
+type FileWriter struct {
+	dataDir string
+
+	mu sync.Mutex
+	// +checklocks:mu
+	gen *fsstress.Generator
+	// +checklocks:mu
+	rnd *rand.Rand
+}
+
+var _ robustness.FileWriter = (*FileWriter)(nil)
+
+// New returns a FileWriter writing to a new temporary directory, using the provided seed.
+func New(seed int64) (*FileWriter, error) {
+	dataDir, err := os.MkdirTemp("", "fsstress-data-")
+	if err != nil {
+		return nil, errors.Wrap(err, "unable to create data directory")
+	}
+
+	return &FileWriter{
+		dataDir: dataDir,
+		gen:     fsstress.NewGenerator(dataDir, seed),
+		rnd:     rand.New(rand.NewSource(seed)), //nolint:gosec
+	}, nil
+}
+
+// DataDirectory returns the data directory.
+func (fw *FileWriter) DataDirectory(ctx context.Context) string {
+	return fw.dataDir
+}
+
+// WriteRandomFiles writes a number of files at some filesystem depth, based
+// on its input options.
+//
+//   - MaxDirDepthField
+//   - MaxFileSizeField
+//   - MaxNumFilesPerWriteField
+//   - MinNumFilesPerWriteField
+//
+// Default values are used for missing options. The method
+// returns the effective options used along with the selected depth
+// and the error if any.
+func (fw *FileWriter) WriteRandomFiles(ctx context.Context, opts map[string]string) (map[string]string, error) {
+	fw.mu.Lock()
+	defer fw.mu.Unlock()
+
+	maxDirDepth := robustness.GetOptAsIntOrDefault(fiofilewriter.MaxDirDepthField, opts, defaultMaxDirDepth)
+	dirDepth := fw.rnd.Intn(maxDirDepth + 1)
+
+	maxFileSizeB := robustness.GetOptAsIntOrDefault(fiofilewriter.MaxFileSizeField, opts, defaultMaxFileSize)
+
+	maxNumFiles := robustness.GetOptAsIntOrDefault(fiofilewriter.MaxNumFilesPerWriteField, opts, defaultMaxNumFilesPerWrite)
+	minNumFiles := robustness.GetOptAsIntOrDefault(fiofilewriter.MinNumFilesPerWriteField, opts, defaultMinNumFilesPerWrite)
+
+	numFiles := fw.rnd.Intn(maxNumFiles-minNumFiles+1) + minNumFiles
+
+	if err := fw.gen.Rescan(); err != nil {
+		return nil, err
+	}
+
+	relPath, err := fw.randomDirAtDepth(dirDepth, true)
+	if err != nil {
+		return nil, err
+	}
+
+	log.Printf("Writing %v files at depth %v (maxFileSize: %v, path: %v)\n", numFiles, dirDepth, maxFileSizeB, relPath)
+
+	retOpts := copyOpts(opts)
+	retOpts["dirDepth"] = strconv.Itoa(dirDepth)
+	retOpts["relPath"] = relPath
+
+	_, err = fw.gen.AddFiles(relPath, numFiles, maxFileSizeB)
+
+	return retOpts, err
+}
+
+// DeleteRandomSubdirectory deletes a random directory up to a specified depth,
+// based on its input options:
+//
+//   - MaxDirDepthField
+//
+// Default values are used for missing options. The method
+// returns the effective options used along with the selected depth
+// and the error if any. ErrNoOp is returned if no directory is found.
+func (fw *FileWriter) DeleteRandomSubdirectory(ctx context.Context, opts map[string]string) (map[string]string, error) {
+	fw.mu.Lock()
+	defer fw.mu.Unlock()
+
+	maxDirDepth := robustness.GetOptAsIntOrDefault(fiofilewriter.MaxDirDepthField, opts, defaultMaxDirDepth)
+	if maxDirDepth <= 0 {
+		return nil, robustness.ErrInvalidOption
+	}
+
+	dirDepth := fw.rnd.Intn(maxDirDepth) + 1
+
+	log.Printf("Deleting directory at depth %v\n", dirDepth)
+
+	retOpts := copyOpts(opts)
+	retOpts["dirDepth"] = strconv.Itoa(dirDepth)
+
+	relPath, err := fw.randomDirAtDepth(dirDepth, false)
+	if err != nil {
+		return retOpts, err
+	}
+
+	if err := os.RemoveAll(filepath.Join(fw.dataDir, relPath)); err != nil {
+		return retOpts, errors.Wrap(err, "unable to delete directory")
+	}
+
+	return retOpts, fw.gen.Rescan()
+}
+
+// DeleteDirectoryContents deletes some of the contents of random directory up to a specified depth,
+// based on its input options:
+//
+//   - MaxDirDepthField
+//   - DeletePercentOfContentsField
+//
+// Default values are used for missing options. The method
+// returns the effective options used along with the selected depth
+// and the error if any. ErrNoOp is returned if no directory is found.
+func (fw *FileWriter) DeleteDirectoryContents(ctx context.Context, opts map[string]string) (map[string]string, error) {
+	fw.mu.Lock()
+	defer fw.mu.Unlock()
+
+	maxDirDepth := robustness.GetOptAsIntOrDefault(fiofilewriter.MaxDirDepthField, opts, defaultMaxDirDepth)
+	dirDepth := fw.rnd.Intn(maxDirDepth + 1)
+
+	pcnt := robustness.GetOptAsIntOrDefault(fiofilewriter.DeletePercentOfContentsField, opts, defaultDeletePercentOfContents)
+
+	log.Printf("Deleting %d%% of directory contents at depth %v\n", pcnt, dirDepth)
+
+	retOpts := copyOpts(opts)
+	retOpts["dirDepth"] = strconv.Itoa(dirDepth)
+	retOpts["percent"] = strconv.Itoa(pcnt)
+
+	relPath, err := fw.randomDirAtDepth(dirDepth, false)
+	if err != nil {
+		return retOpts, err
+	}
+
+	entries, err := os.ReadDir(filepath.Join(fw.dataDir, relPath))
+	if err != nil {
+		return retOpts, errors.Wrap(err, "unable to read directory")
+	}
+
+	const pcntConv = 100
+
+	fw.rnd.Shuffle(len(entries), func(i, j int) { entries[i], entries[j] = entries[j], entries[i] })
+
+	for _, e := range entries[:len(entries)*pcnt/pcntConv] {
+		if err := os.RemoveAll(filepath.Join(fw.dataDir, relPath, e.Name())); err != nil {
+			return retOpts, errors.Wrap(err, "unable to delete directory entry")
+		}
+	}
+
+	return retOpts, fw.gen.Rescan()
+}
+
+// DeleteEverything deletes all content.
+func (fw *FileWriter) DeleteEverything(ctx context.Context) error {
+	_, err := fw.DeleteDirectoryContents(ctx, map[string]string{
+		fiofilewriter.MaxDirDepthField:             strconv.Itoa(0),
+		fiofilewriter.DeletePercentOfContentsField: strconv.Itoa(100),
+	})
+
+	return err
+}
+
+// Cleanup is part of FileWriter.
+func (fw *FileWriter) Cleanup() {
+	os.RemoveAll(fw.dataDir) //nolint:errcheck
+}
+
+// randomDirAtDepth returns the relative path of a random directory at the provided depth,
+// picking a random existing subdirectory at each level. When create is true, new
+// subdirectories are created as needed, otherwise ErrNoOp is returned if there are no
+// directories at that depth.
+//
+// +checklocks:fw.mu
+func (fw *FileWriter) randomDirAtDepth(depth int, create bool) (string, error) {
+	relPath := ""
+	isNew := false
+
+	for range depth {
+		var subdirs []string
+
+		if !isNew {
+			entries, err := os.ReadDir(filepath.Join(fw.dataDir, relPath))
+			if err != nil {
+				return "", errors.Wrap(err, "unable to read directory")
+			}
+
+			for _, e := range entries {
+				if e.IsDir() {
+					subdirs = append(subdirs, e.Name())
+				}
+			}
+		}
+
+		switch {
+		case create && fw.rnd.Intn(len(subdirs)+1) == 0:
+			// directories below a new directory are new as well.
+			relPath = filepath.Join(relPath, fmt.Sprintf("dir-%08x", fw.rnd.Uint32()))
+			isNew = true
+
+		case len(subdirs) == 0:
+			log.Printf("no directory found at depth %v\n", depth)
+			return "", robustness.ErrNoOp
+
+		default:
+			relPath = filepath.Join(relPath, subdirs[fw.rnd.Intn(len(subdirs))])
+		}
+	}
+
+	return relPath, nil
+}
+
+func copyOpts(opts map[string]string) map[string]string {
+	retOpts := make(map[string]string, len(opts))
+	for k, v := range opts {
+		retOpts[k] = v
+	}
+
+	return retOpts
+}
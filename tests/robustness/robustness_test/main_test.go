@@ -14,9 +14,11 @@
 	"testing"
 	"time"
 
+	"github.com/kopia/kopia/internal/clock"
 	"github.com/kopia/kopia/tests/robustness"
 	"github.com/kopia/kopia/tests/robustness/engine"
 	"github.com/kopia/kopia/tests/robustness/fiofilewriter"
+	"github.com/kopia/kopia/tests/robustness/fsstressfilewriter"
 	"github.com/kopia/kopia/tests/robustness/snapmeta"
 	"github.com/kopia/kopia/tests/tools/fio"
 	"github.com/kopia/kopia/tests/tools/kopiarunner"
@@ -89,7 +91,7 @@ type kopiaRobustnessTestHarness struct {
 	metaRepoPath string
 	baseDirPath  string
 
-	fileWriter  *fiofilewriter.FileWriter
+	fileWriter  fileWriter
 	snapshotter *snapmeta.KopiaSnapshotter
 	persister   *snapmeta.KopiaPersisterLight
 	upgrader    *kopiarunner.KopiaSnapshotter
@@ -129,21 +131,32 @@ func (th *kopiaRobustnessTestHarness) makeBaseDir() bool {
 	return true
 }
 
+type fileWriter interface {
+	robustness.FileWriter
+	Cleanup()
+}
+
 func (th *kopiaRobustnessTestHarness) getFileWriter() bool {
 	fw, err := fiofilewriter.New()
-	if err != nil {
-		if errors.Is(err, fio.ErrEnvNotSet) {
-			log.Println("Skipping robustness tests because FIO environment is not set")
+	if err == nil {
+		th.fileWriter = fw
+		return true
+	}
 
-			th.skipTest = true
-		} else {
-			log.Println("Error creating fio FileWriter:", err)
-		}
+	if !errors.Is(err, fio.ErrEnvNotSet) {
+		log.Println("Error creating fio FileWriter:", err)
+		return false
+	}
 
+	log.Println("FIO environment is not set, using synthetic file writer")
+
+	sfw, err := fsstressfilewriter.New(clock.Now().UnixNano())
+	if err != nil {
+		log.Println("Error creating synthetic FileWriter:", err)
 		return false
 	}
 
-	th.fileWriter = fw
+	th.fileWriter = sfw
 
 	return true
 }
@@ -0,0 +1,214 @@
+package stress_test
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"math/rand"
+	"os"
+	"runtime"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/kopia/kopia/internal/clock"
+	"github.com/kopia/kopia/internal/repotesting"
+	"github.com/kopia/kopia/internal/servertesting"
+	"github.com/kopia/kopia/internal/testlogging"
+	"github.com/kopia/kopia/internal/testutil"
+	"github.com/kopia/kopia/repo"
+	"github.com/kopia/kopia/repo/content"
+	"github.com/kopia/kopia/repo/object"
+)
+
+//nolint:gochecknoglobals
+var apiServerClientCount = flag.Int("stress_test.api-server-clients", 8, "Number of concurrent clients connected to the API server")
+
+const (
+	maxAPIServerObjectSize = 100000
+	memorySampleInterval   = 100 * time.Millisecond
+)
+
+// storageReadsMetric is the name of the distribution of latencies of GetBlob calls to the storage
+// of the server repository, which counts reads which were not served from any cache.
+const storageReadsMetric = "blob_storage_latency[method:GetBlob]"
+
+type apiServerStressStats struct {
+	writes          atomic.Int64
+	objectsVerified atomic.Int64
+	bytesWritten    atomic.Int64
+	flushes         atomic.Int64
+}
+
+func TestStressAPIServer(t *testing.T) {
+	if os.Getenv("KOPIA_STRESS_TEST") == "" {
+		t.Skip("skipping stress test")
+	}
+
+	if testing.Short() {
+		return
+	}
+
+	duration := 3 * time.Second
+	if os.Getenv("CI") != "" {
+		duration = 30 * time.Second
+	}
+
+	_, env := repotesting.NewEnvironment(t, repotesting.FormatNotImportant)
+	asi := servertesting.StartServer(t, env, true)
+
+	var (
+		stats         apiServerStressStats
+		maxHeapInUse  atomic.Uint64
+		samplerDone   = make(chan struct{})
+		samplerWG     sync.WaitGroup
+		startTime     = clock.Now()
+		deadline      = startTime.Add(duration)
+		seed0         = clock.Now().Nanosecond()
+		clientsFailed atomic.Int32
+	)
+
+	t.Logf("running %v clients with seed %v and limits %+v", *apiServerClientCount, seed0, stressResourceLimits)
+
+	storageReadsBefore := storageReadCount(env)
+
+	samplerWG.Add(1)
+
+	go func() {
+		defer samplerWG.Done()
+
+		sampleHeapInUse(samplerDone, &maxHeapInUse)
+	}()
+
+	t.Run("clients", func(t *testing.T) {
+		for i := range *apiServerClientCount {
+			t.Run(fmt.Sprintf("client-%v", i), func(t *testing.T) {
+				t.Parallel()
+
+				defer func() {
+					if t.Failed() {
+						clientsFailed.Add(1)
+					}
+				}()
+
+				apiServerStressClient(t, asi, deadline, int64(seed0+i), &stats)
+			})
+		}
+	})
+
+	close(samplerDone)
+	samplerWG.Wait()
+
+	elapsed := clock.Now().Sub(startTime).Seconds()
+	storageReads := storageReadCount(env) - storageReadsBefore
+
+	t.Logf("API server stress results: clients=%v failed=%v duration=%.1fs writes=%v (%.1f/s) storage reads=%v (%.1f/s) objects verified=%v flushes=%v bytes written=%v max heap in use=%v",
+		*apiServerClientCount,
+		clientsFailed.Load(),
+		elapsed,
+		stats.writes.Load(), float64(stats.writes.Load())/elapsed,
+		storageReads, float64(storageReads)/elapsed,
+		stats.objectsVerified.Load(),
+		stats.flushes.Load(),
+		stats.bytesWritten.Load(),
+		maxHeapInUse.Load())
+}
+
+// storageReadCount returns the number of reads that reached the storage of the server repository,
+// excluding reads served from caches.
+func storageReadCount(env *repotesting.Environment) int64 {
+	if d := env.RepositoryMetrics().Snapshot(false).DurationDistributions[storageReadsMetric]; d != nil {
+		return d.Count
+	}
+
+	return 0
+}
+
+// sampleHeapInUse periodically records the high-water mark of the heap in use until done is closed.
+func sampleHeapInUse(done <-chan struct{}, maxHeapInUse *atomic.Uint64) {
+	var ms runtime.MemStats
+
+	ticker := time.NewTicker(memorySampleInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-done:
+			return
+
+		case <-ticker.C:
+			runtime.ReadMemStats(&ms)
+
+			if ms.HeapInuse > maxHeapInUse.Load() {
+				// only this goroutine updates the value.
+				maxHeapInUse.Store(ms.HeapInuse)
+			}
+		}
+	}
+}
+
+//nolint:thelper
+func apiServerStressClient(t *testing.T, asi *repo.APIServerInfo, deadline time.Time, seed int64, stats *apiServerStressStats) {
+	ctx := testlogging.Context(t)
+	rnd := rand.New(rand.NewSource(seed))
+
+	rep, err := servertesting.ConnectAndOpenAPIServer(t, ctx, asi, repo.ClientOptions{
+		Username: servertesting.TestUsername,
+		Hostname: servertesting.TestHostname,
+	}, content.CachingOptions{
+		CacheDirectory: testutil.TempDirectory(t),
+	}, servertesting.TestPassword, &repo.Options{})
+	require.NoError(t, err)
+
+	defer rep.Close(ctx) //nolint:errcheck
+
+	// each writer holds a streaming session with the server until the repository is closed,
+	// so use a single long-lived writer and flush it periodically.
+	ctx, w, err := rep.NewWriter(ctx, repo.WriteSessionOptions{
+		Purpose: "api-server-stress",
+	})
+	require.NoError(t, err)
+
+	defer w.Close(ctx) //nolint:errcheck
+
+	for clock.Now().Before(deadline) {
+		written := map[object.ID][]byte{}
+
+		for range rnd.Intn(10) + 1 {
+			data := make([]byte, rnd.Intn(maxAPIServerObjectSize))
+			rnd.Read(data)
+
+			ow := w.NewObjectWriter(ctx, object.WriterOptions{})
+
+			_, err := ow.Write(data)
+			require.NoError(t, err)
+
+			oid, err := ow.Result()
+			require.NoError(t, err)
+			require.NoError(t, ow.Close())
+
+			written[oid] = data
+
+			stats.writes.Add(1)
+			stats.bytesWritten.Add(int64(len(data)))
+		}
+
+		require.NoError(t, w.Flush(ctx))
+		stats.flushes.Add(1)
+
+		for oid, data := range written {
+			r, err := w.OpenObject(ctx, oid)
+			require.NoError(t, err)
+
+			got, err := io.ReadAll(r)
+			require.NoError(t, err)
+			require.NoError(t, r.Close())
+			require.Equal(t, data, got)
+
+			stats.objectsVerified.Add(1)
+		}
+	}
+}
@@ -0,0 +1,162 @@
+package stress_test
+
+import (
+	"flag"
+	"log"
+	"math"
+	"os"
+	"path/filepath"
+	"runtime"
+	"runtime/debug"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/kopia/kopia/internal/testutil"
+)
+
+const (
+	cgroupRoot = "/sys/fs/cgroup"
+
+	// fraction of the cgroup memory limit used as GOMEMLIMIT, leaving headroom for non-heap memory.
+	memoryLimitFraction = 0.9
+)
+
+// resourceLimits describes the resource limits of the container the stress test is running in,
+// and the runtime settings that were derived from them.
+type resourceLimits struct {
+	CgroupVersion   int     `json:"cgroupVersion,omitempty"`
+	MemoryLimit     int64   `json:"memoryLimit,omitempty"`
+	CPULimit        float64 `json:"cpuLimit,omitempty"`
+	GoMemLimit      int64   `json:"goMemLimit"`
+	GoMaxProcs      int     `json:"goMaxProcs"`
+	NumCPU          int     `json:"numCPU"`
+	AppliedMemLimit bool    `json:"appliedMemLimit"`
+	AppliedMaxProcs bool    `json:"appliedMaxProcs"`
+}
+
+//nolint:gochecknoglobals
+var stressResourceLimits resourceLimits
+
+func TestMain(m *testing.M) {
+	flag.Parse()
+
+	if err := applyStressPreset(*stressPresetName); err != nil {
+		log.Fatalf("error applying stress test preset: %v", err)
+	}
+
+	stressResourceLimits = applyResourceLimits(readCgroupLimits(cgroupRoot))
+
+	testutil.MyTestMain(m)
+}
+
+// readCgroupLimits reads memory and CPU limits from cgroup v2 or v1 hierarchy mounted at the provided root.
+func readCgroupLimits(root string) resourceLimits {
+	var l resourceLimits
+
+	if b, err := os.ReadFile(filepath.Join(root, "cgroup.controllers")); err == nil && len(b) > 0 {
+		l.CgroupVersion = 2
+		l.MemoryLimit = readCgroupInt(filepath.Join(root, "memory.max"))
+
+		if f := strings.Fields(readCgroupString(filepath.Join(root, "cpu.max"))); len(f) == 2 { //nolint:mnd
+			l.CPULimit = cpuLimit(parseCgroupInt(f[0]), parseCgroupInt(f[1]))
+		}
+
+		return l
+	}
+
+	if _, err := os.Stat(filepath.Join(root, "memory")); err == nil {
+		l.CgroupVersion = 1
+		l.MemoryLimit = readCgroupInt(filepath.Join(root, "memory", "memory.limit_in_bytes"))
+		l.CPULimit = cpuLimit(
+			readCgroupInt(filepath.Join(root, "cpu", "cpu.cfs_quota_us")),
+			readCgroupInt(filepath.Join(root, "cpu", "cpu.cfs_period_us")))
+	}
+
+	return l
+}
+
+func cpuLimit(quota, period int64) float64 {
+	if quota <= 0 || period <= 0 {
+		return 0
+	}
+
+	return float64(quota) / float64(period)
+}
+
+func readCgroupString(fname string) string {
+	b, err := os.ReadFile(fname) //nolint:gosec
+	if err != nil {
+		return ""
+	}
+
+	return strings.TrimSpace(string(b))
+}
+
+func readCgroupInt(fname string) int64 {
+	return parseCgroupInt(readCgroupString(fname))
+}
+
+// parseCgroupInt parses cgroup numeric value, returning 0 for unlimited or invalid values.
+func parseCgroupInt(s string) int64 {
+	v, err := strconv.ParseInt(s, 10, 64)
+	if err != nil || v < 0 {
+		return 0
+	}
+
+	// cgroup v1 reports unlimited memory as a huge page-aligned number.
+	if v >= math.MaxInt64/2 {
+		return 0
+	}
+
+	return v
+}
+
+// applyResourceLimits sets GOMEMLIMIT and GOMAXPROCS based on the provided limits, unless they
+// have been explicitly set using environment variables.
+func applyResourceLimits(l resourceLimits) resourceLimits {
+	if l.MemoryLimit > 0 && os.Getenv("GOMEMLIMIT") == "" {
+		debug.SetMemoryLimit(int64(float64(l.MemoryLimit) * memoryLimitFraction))
+		l.AppliedMemLimit = true
+	}
+
+	if l.CPULimit > 0 && os.Getenv("GOMAXPROCS") == "" {
+		runtime.GOMAXPROCS(max(1, int(math.Ceil(l.CPULimit))))
+		l.AppliedMaxProcs = true
+	}
+
+	l.GoMemLimit = debug.SetMemoryLimit(-1)
+	l.GoMaxProcs = runtime.GOMAXPROCS(0)
+	l.NumCPU = runtime.NumCPU()
+
+	return l
+}
+
+func TestReadCgroupLimits(t *testing.T) {
+	v2 := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(v2, "cgroup.controllers"), []byte("cpu memory\n"), 0o600))
+	require.NoError(t, os.WriteFile(filepath.Join(v2, "memory.max"), []byte("1073741824\n"), 0o600))
+	require.NoError(t, os.WriteFile(filepath.Join(v2, "cpu.max"), []byte("150000 100000\n"), 0o600))
+
+	require.Equal(t, resourceLimits{CgroupVersion: 2, MemoryLimit: 1 << 30, CPULimit: 1.5}, readCgroupLimits(v2))
+
+	v2unlimited := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(v2unlimited, "cgroup.controllers"), []byte("cpu memory\n"), 0o600))
+	require.NoError(t, os.WriteFile(filepath.Join(v2unlimited, "memory.max"), []byte("max\n"), 0o600))
+	require.NoError(t, os.WriteFile(filepath.Join(v2unlimited, "cpu.max"), []byte("max 100000\n"), 0o600))
+
+	require.Equal(t, resourceLimits{CgroupVersion: 2}, readCgroupLimits(v2unlimited))
+
+	v1 := t.TempDir()
+	require.NoError(t, os.MkdirAll(filepath.Join(v1, "memory"), 0o700))
+	require.NoError(t, os.MkdirAll(filepath.Join(v1, "cpu"), 0o700))
+	require.NoError(t, os.WriteFile(filepath.Join(v1, "memory", "memory.limit_in_bytes"), []byte("9223372036854771712\n"), 0o600))
+	require.NoError(t, os.WriteFile(filepath.Join(v1, "cpu", "cpu.cfs_quota_us"), []byte("200000\n"), 0o600))
+	require.NoError(t, os.WriteFile(filepath.Join(v1, "cpu", "cpu.cfs_period_us"), []byte("100000\n"), 0o600))
+
+	require.Equal(t, resourceLimits{CgroupVersion: 1, CPULimit: 2}, readCgroupLimits(v1))
+
+	require.Equal(t, resourceLimits{}, readCgroupLimits(t.TempDir()))
+}
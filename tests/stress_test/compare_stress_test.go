@@ -0,0 +1,219 @@
+package stress_test
+
+import (
+	"encoding/json"
+	"flag"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/require"
+
+	"github.com/kopia/kopia/internal/clock"
+	"github.com/kopia/kopia/internal/repotesting"
+	"github.com/kopia/kopia/repo"
+	"github.com/kopia/kopia/snapshot"
+	"github.com/kopia/kopia/tests/testenv"
+)
+
+//nolint:gochecknoglobals
+var (
+	compareConfigA = flag.String("stress_test.compare-config-a", "", "Repository configuration A, e.g. 'hash=BLAKE3-256,encryption=AES256-GCM-HMAC-SHA256,splitter=DYNAMIC-4M-BUZHASH'")
+	compareConfigB = flag.String("stress_test.compare-config-b", "", "Repository configuration B")
+	compareExeA    = flag.String("stress_test.compare-exe-a", "", "Path to kopia executable A, takes precedence over configuration A")
+	compareExeB    = flag.String("stress_test.compare-exe-b", "", "Path to kopia executable B, takes precedence over configuration B")
+)
+
+// metricDiff describes the difference of a single metric between two runs.
+type metricDiff struct {
+	Metric       string  `json:"metric"`
+	A            float64 `json:"a"`
+	B            float64 `json:"b"`
+	DeltaPercent float64 `json:"deltaPercent"`
+}
+
+// compareResults is the results report of the comparison mode.
+type compareResults struct {
+	A    *stressResults `json:"a"`
+	B    *stressResults `json:"b"`
+	Diff []metricDiff   `json:"diff"`
+}
+
+// TestStressCompare runs the same seeded churn workload against two repository configurations
+// or two kopia executables and reports the differences between them.
+func TestStressCompare(t *testing.T) {
+	if os.Getenv("KOPIA_STRESS_TEST") == "" {
+		t.Skip("skipping stress test")
+	}
+
+	if testing.Short() {
+		return
+	}
+
+	seed := *churnSeed
+	if seed == 0 {
+		seed = int64(clock.Now().Nanosecond())
+	}
+
+	schedule := churnScheduleFromFlags()
+
+	var res compareResults
+
+	t.Run("A", func(t *testing.T) {
+		res.A = runSnapshotChurn(t, seed, schedule, newCompareTarget(t, *compareExeA, *compareConfigA))
+	})
+
+	t.Run("B", func(t *testing.T) {
+		res.B = runSnapshotChurn(t, seed, schedule, newCompareTarget(t, *compareExeB, *compareConfigB))
+	})
+
+	require.NotNil(t, res.A)
+	require.NotNil(t, res.B)
+
+	res.Diff = diffStressResults(res.A, res.B)
+
+	for _, d := range res.Diff {
+		t.Logf("%-20v A=%-15.0f B=%-15.0f delta=%+.2f%%", d.Metric, d.A, d.B, d.DeltaPercent)
+	}
+
+	writeStressResults(t, res)
+}
+
+//nolint:thelper
+func newCompareTarget(t *testing.T, exe, config string) churnTarget {
+	if exe != "" {
+		return newCLIChurnTarget(t, testenv.NewExeRunnerWithBinary(t, exe))
+	}
+
+	opt, err := parseRepositoryConfig(config)
+	require.NoError(t, err)
+
+	_, env := repotesting.NewEnvironment(t, repotesting.FormatNotImportant, repotesting.Options{
+		NewRepositoryOptions: opt,
+	})
+
+	return newInProcChurnTarget(env)
+}
+
+// parseRepositoryConfig parses comma-separated key=value repository configuration.
+func parseRepositoryConfig(config string) (func(o *repo.NewRepositoryOptions), error) {
+	var mods []func(o *repo.NewRepositoryOptions)
+
+	for _, kv := range strings.Split(config, ",") {
+		if kv == "" {
+			continue
+		}
+
+		k, v, _ := strings.Cut(kv, "=")
+
+		switch k {
+		case "hash":
+			mods = append(mods, func(o *repo.NewRepositoryOptions) { o.BlockFormat.Hash = v })
+		case "encryption":
+			mods = append(mods, func(o *repo.NewRepositoryOptions) { o.BlockFormat.Encryption = v })
+		case "splitter":
+			mods = append(mods, func(o *repo.NewRepositoryOptions) { o.ObjectFormat.Splitter = v })
+		default:
+			return nil, errors.Errorf("unsupported configuration key: %q", k)
+		}
+	}
+
+	return func(o *repo.NewRepositoryOptions) {
+		for _, m := range mods {
+			m(o)
+		}
+	}, nil
+}
+
+func totalDuration(r *stressResults) time.Duration {
+	var d time.Duration
+
+	for _, ir := range r.Iterations {
+		d += ir.Duration
+	}
+
+	return d
+}
+
+func lastIteration(r *stressResults) iterationResult {
+	if len(r.Iterations) == 0 {
+		return iterationResult{}
+	}
+
+	return r.Iterations[len(r.Iterations)-1]
+}
+
+func diffStressResults(a, b *stressResults) []metricDiff {
+	metrics := []struct {
+		name string
+		get  func(r *stressResults) float64
+	}{
+		{"total duration (ms)", func(r *stressResults) float64 { return float64(totalDuration(r).Milliseconds()) }},
+		{"blob count", func(r *stressResults) float64 { return float64(lastIteration(r).BlobCount) }},
+		{"blob bytes", func(r *stressResults) float64 { return float64(lastIteration(r).BlobBytes) }},
+	}
+
+	var result []metricDiff
+
+	for _, m := range metrics {
+		d := metricDiff{
+			Metric: m.name,
+			A:      m.get(a),
+			B:      m.get(b),
+		}
+
+		if d.A != 0 {
+			d.DeltaPercent = 100 * (d.B - d.A) / d.A //nolint:mnd
+		}
+
+		result = append(result, d)
+	}
+
+	return result
+}
+
+// cliChurnTarget snapshots using kopia CLI and a filesystem repository.
+type cliChurnTarget struct {
+	e *testenv.CLITest
+}
+
+//nolint:thelper
+func (c *cliChurnTarget) snapshot(t *testing.T, dir string) *snapshot.Manifest {
+	lines := c.e.RunAndExpectSuccess(t, "snapshot", "create", dir, "--json")
+
+	var man snapshot.Manifest
+
+	require.NoError(t, json.Unmarshal([]byte(strings.Join(lines, "\n")), &man))
+
+	return &man
+}
+
+//nolint:thelper
+func (c *cliChurnTarget) blobStats(t *testing.T) (count int, totalBytes int64) {
+	require.NoError(t, filepath.Walk(c.e.RepoDir, func(_ string, fi os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+
+		if fi.Mode().IsRegular() {
+			count++
+			totalBytes += fi.Size()
+		}
+
+		return nil
+	}))
+
+	return count, totalBytes
+}
+
+//nolint:thelper
+func newCLIChurnTarget(t *testing.T, runner testenv.CLIRunner) *cliChurnTarget {
+	e := testenv.NewCLITest(t, testenv.RepoFormatNotImportant, runner)
+
+	e.RunAndExpectSuccess(t, "repo", "create", "filesystem", "--path", e.RepoDir)
+
+	return &cliChurnTarget{e}
+}
@@ -0,0 +1,294 @@
+package stress_test
+
+import (
+	"flag"
+	"fmt"
+	"html/template"
+	"os"
+	"path/filepath"
+	"runtime/pprof"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/require"
+)
+
+//nolint:gochecknoglobals
+var (
+	htmlReportFile = flag.String("stress_test.html-report", "", "Write self-contained HTML report with per-iteration charts to the provided file")
+	profileDir     = flag.String("stress_test.profile-dir", "", "Capture CPU and heap profiles of each iteration in the provided directory")
+)
+
+const (
+	chartWidth   = 640
+	chartHeight  = 200
+	chartPadding = 40
+)
+
+// iterationProfiler captures heap high-water mark and optionally CPU and heap profiles of a single iteration.
+type iterationProfiler struct {
+	iteration    int
+	maxHeapInUse atomic.Uint64
+	done         chan struct{}
+	wg           sync.WaitGroup
+	cpuProfile   *os.File
+}
+
+//nolint:thelper
+func startIterationProfiler(t *testing.T, iteration int) *iterationProfiler {
+	p := &iterationProfiler{
+		iteration: iteration,
+		done:      make(chan struct{}),
+	}
+
+	if *profileDir != "" {
+		require.NoError(t, os.MkdirAll(*profileDir, 0o700))
+
+		f, err := os.Create(filepath.Join(*profileDir, fmt.Sprintf("cpu-%04d.pprof", iteration)))
+		require.NoError(t, err)
+
+		if err := pprof.StartCPUProfile(f); err != nil {
+			// CPU profiling may already be enabled using -cpuprofile.
+			t.Logf("unable to start CPU profile: %v", err)
+			f.Close()           //nolint:errcheck
+			os.Remove(f.Name()) //nolint:errcheck
+		} else {
+			p.cpuProfile = f
+		}
+	}
+
+	p.wg.Add(1)
+
+	go func() {
+		defer p.wg.Done()
+
+		sampleHeapInUse(p.done, &p.maxHeapInUse)
+	}()
+
+	return p
+}
+
+// stop stops profiling and records the results in the provided iteration result.
+//
+//nolint:thelper
+func (p *iterationProfiler) stop(t *testing.T, ir *iterationResult) {
+	close(p.done)
+	p.wg.Wait()
+
+	ir.MaxHeapInUse = p.maxHeapInUse.Load()
+
+	if p.cpuProfile != nil {
+		pprof.StopCPUProfile()
+		require.NoError(t, p.cpuProfile.Close())
+
+		ir.CPUProfile = p.cpuProfile.Name()
+	}
+
+	if *profileDir != "" {
+		fname := filepath.Join(*profileDir, fmt.Sprintf("heap-%04d.pprof", p.iteration))
+
+		f, err := os.Create(fname) //nolint:gosec
+		require.NoError(t, err)
+		require.NoError(t, pprof.WriteHeapProfile(f))
+		require.NoError(t, f.Close())
+
+		ir.HeapProfile = fname
+	}
+}
+
+// reportChart is a single chart in the HTML report.
+type reportChart struct {
+	Title string
+	Unit  string
+	SVG   template.HTML
+}
+
+// reportProfiles are links to profiles captured in a single iteration.
+type reportProfiles struct {
+	Iteration int
+	CPU       string
+	Heap      string
+}
+
+//nolint:gochecknoglobals
+var htmlReportTemplate = template.Must(template.New("report").Parse(`<!DOCTYPE html>
+<html>
+<head>
+<meta charset="utf-8">
+<title>{{.Title}}</title>
+<style>
+body { font-family: sans-serif; margin: 2em; }
+table { border-collapse: collapse; }
+td, th { border: 1px solid #ccc; padding: 4px 8px; text-align: right; }
+svg { background: #fafafa; border: 1px solid #ddd; }
+</style>
+</head>
+<body>
+<h1>{{.Title}}</h1>
+<p>Seed: {{.Results.Seed}}, files: {{.Results.Tree.FileCount}}, churn: {{.Results.TotalChurn}}</p>
+{{range .Charts}}
+<h2>{{.Title}} ({{.Unit}})</h2>
+{{.SVG}}
+{{end}}
+<h2>Iterations</h2>
+<table>
+<tr><th>Iteration</th><th>Duration</th><th>Hashed files</th><th>Cached files</th><th>Upload throughput (B/s)</th><th>Max heap in use</th><th>Blobs</th><th>Blob bytes</th></tr>
+{{range .Results.Iterations}}<tr><td>{{.Iteration}}</td><td>{{.Duration}}</td><td>{{.HashedFiles}}</td><td>{{.CachedFiles}}</td><td>{{printf "%.0f" .UploadBytesPerSecond}}</td><td>{{.MaxHeapInUse}}</td><td>{{.BlobCount}}</td><td>{{.BlobBytes}}</td></tr>
+{{end}}
+</table>
+{{if .Profiles}}
+<h2>Profiles</h2>
+<ul>
+{{range .Profiles}}<li>Iteration {{.Iteration}}:{{if .CPU}} <a href="{{.CPU}}">CPU</a>{{end}}{{if .Heap}} <a href="{{.Heap}}">heap</a>{{end}}</li>
+{{end}}
+</ul>
+{{end}}
+</body>
+</html>
+`))
+
+// writeHTMLReport writes a self-contained HTML report charting per-iteration metrics of the provided results.
+func writeHTMLReport(fname string, res *stressResults) error {
+	var durations, throughput, heap []float64
+
+	for _, ir := range res.Iterations {
+		durations = append(durations, float64(ir.Duration.Milliseconds()))
+		throughput = append(throughput, ir.UploadBytesPerSecond/(1<<20)) //nolint:mnd
+		heap = append(heap, float64(ir.MaxHeapInUse)/(1<<20))            //nolint:mnd
+	}
+
+	data := struct {
+		Title    string
+		Results  *stressResults
+		Charts   []reportChart
+		Profiles []reportProfiles
+	}{
+		Title:   "Kopia stress test report",
+		Results: res,
+		Charts: []reportChart{
+			{"Iteration duration", "ms", svgLineChart(durations)},
+			{"Upload throughput", "MiB/s", svgLineChart(throughput)},
+			{"Max heap in use", "MiB", svgLineChart(heap)},
+		},
+	}
+
+	reportDir := filepath.Dir(fname)
+
+	for _, ir := range res.Iterations {
+		if ir.CPUProfile == "" && ir.HeapProfile == "" {
+			continue
+		}
+
+		data.Profiles = append(data.Profiles, reportProfiles{
+			Iteration: ir.Iteration,
+			CPU:       relativeLink(reportDir, ir.CPUProfile),
+			Heap:      relativeLink(reportDir, ir.HeapProfile),
+		})
+	}
+
+	var sb strings.Builder
+
+	if err := htmlReportTemplate.Execute(&sb, data); err != nil {
+		return errors.Wrap(err, "unable to render report")
+	}
+
+	return errors.Wrap(os.WriteFile(fname, []byte(sb.String()), 0o600), "unable to write report")
+}
+
+// relativeLink returns the path of the file relative to the report directory, if possible.
+func relativeLink(reportDir, fname string) string {
+	if fname == "" {
+		return ""
+	}
+
+	absReportDir, err1 := filepath.Abs(reportDir)
+	absFile, err2 := filepath.Abs(fname)
+
+	if err1 != nil || err2 != nil {
+		return fname
+	}
+
+	rel, err := filepath.Rel(absReportDir, absFile)
+	if err != nil {
+		return absFile
+	}
+
+	return filepath.ToSlash(rel)
+}
+
+// svgLineChart renders the provided values as an inline SVG line chart with one point per iteration.
+func svgLineChart(values []float64) template.HTML {
+	maxValue := 0.0
+	for _, v := range values {
+		maxValue = max(maxValue, v)
+	}
+
+	if maxValue == 0 {
+		maxValue = 1
+	}
+
+	plotWidth := float64(chartWidth - 2*chartPadding)
+	plotHeight := float64(chartHeight - 2*chartPadding)
+
+	x := func(i int) float64 {
+		if len(values) < 2 { //nolint:mnd
+			return chartPadding + plotWidth/2 //nolint:mnd
+		}
+
+		return chartPadding + plotWidth*float64(i)/float64(len(values)-1)
+	}
+
+	y := func(v float64) float64 {
+		return chartPadding + plotHeight*(1-v/maxValue)
+	}
+
+	var sb strings.Builder
+
+	fmt.Fprintf(&sb, `<svg xmlns="http://www.w3.org/2000/svg" width="%d" height="%d">`, chartWidth, chartHeight)
+	fmt.Fprintf(&sb, `<line x1="%d" y1="%d" x2="%d" y2="%d" stroke="#999"/>`, chartPadding, chartHeight-chartPadding, chartWidth-chartPadding, chartHeight-chartPadding)
+	fmt.Fprintf(&sb, `<line x1="%d" y1="%d" x2="%d" y2="%d" stroke="#999"/>`, chartPadding, chartPadding, chartPadding, chartHeight-chartPadding)
+	fmt.Fprintf(&sb, `<text x="2" y="%d" font-size="10">%.1f</text>`, chartPadding, maxValue)
+	fmt.Fprintf(&sb, `<text x="2" y="%d" font-size="10">0</text>`, chartHeight-chartPadding)
+
+	var points []string
+
+	for i, v := range values {
+		points = append(points, fmt.Sprintf("%.1f,%.1f", x(i), y(v)))
+		fmt.Fprintf(&sb, `<circle cx="%.1f" cy="%.1f" r="3" fill="#36c"><title>iteration %d: %.2f</title></circle>`, x(i), y(v), i, v)
+		fmt.Fprintf(&sb, `<text x="%.1f" y="%d" font-size="10" text-anchor="middle">%d</text>`, x(i), chartHeight-chartPadding+15, i) //nolint:mnd
+	}
+
+	fmt.Fprintf(&sb, `<polyline points="%s" fill="none" stroke="#36c" stroke-width="2"/>`, strings.Join(points, " "))
+	sb.WriteString(`</svg>`)
+
+	//nolint:gosec
+	return template.HTML(sb.String())
+}
+
+func TestWriteHTMLReport(t *testing.T) {
+	dir := t.TempDir()
+	fname := filepath.Join(dir, "report.html")
+
+	res := &stressResults{
+		Seed: 1,
+		Iterations: []iterationResult{
+			{Iteration: 0, Duration: 1000, UploadBytesPerSecond: 1 << 20, MaxHeapInUse: 1 << 20},
+			{Iteration: 1, Duration: 2000, UploadBytesPerSecond: 2 << 20, MaxHeapInUse: 3 << 20, CPUProfile: filepath.Join(dir, "profiles", "cpu-0001.pprof")},
+		},
+	}
+
+	require.NoError(t, writeHTMLReport(fname, res))
+
+	b, err := os.ReadFile(fname)
+	require.NoError(t, err)
+
+	html := string(b)
+	require.Contains(t, html, "Iteration duration")
+	require.Contains(t, html, "Upload throughput")
+	require.Contains(t, html, "Max heap in use")
+	require.Contains(t, html, "<polyline")
+	require.Contains(t, html, `href="profiles/cpu-0001.pprof"`)
+}
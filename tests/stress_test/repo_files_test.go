@@ -0,0 +1,34 @@
+package stress_test
+
+import (
+	"flag"
+
+	"github.com/kopia/kopia/internal/fsstress"
+)
+
+//nolint:gochecknoglobals
+var (
+	churnAddPercent    = flag.Float64("stress_test.churn-add", 5, "Percentage of files added in each iteration")
+	churnDeletePercent = flag.Float64("stress_test.churn-delete", 5, "Percentage of files deleted in each iteration")
+	churnModifyPercent = flag.Float64("stress_test.churn-modify", 10, "Percentage of files modified in each iteration")
+	churnRenamePercent = flag.Float64("stress_test.churn-rename", 2, "Percentage of files renamed in each iteration")
+	churnAppendPercent = flag.Float64("stress_test.churn-append", 50, "Percentage of modifications appending to files, the remaining ones overwrite a random range")
+)
+
+func churnModelFromFlags() fsstress.ChurnModel {
+	return fsstress.ChurnModel{
+		AddPercent:    *churnAddPercent,
+		DeletePercent: *churnDeletePercent,
+		ModifyPercent: *churnModifyPercent,
+		RenamePercent: *churnRenamePercent,
+		AppendPercent: *churnAppendPercent,
+		MaxModifySize: 10000,
+	}
+}
+
+func treeSpecFromFlags() fsstress.TreeSpec {
+	return fsstress.TreeSpec{
+		FileCount:   *churnFileCount,
+		MaxFileSize: 100000,
+	}
+}
@@ -0,0 +1,230 @@
+package stress_test
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/kopia/kopia/fs/localfs"
+	"github.com/kopia/kopia/internal/clock"
+	"github.com/kopia/kopia/internal/fsstress"
+	"github.com/kopia/kopia/internal/repotesting"
+	"github.com/kopia/kopia/internal/testlogging"
+	"github.com/kopia/kopia/internal/testutil"
+	"github.com/kopia/kopia/repo/blob"
+	"github.com/kopia/kopia/snapshot"
+	"github.com/kopia/kopia/snapshot/snapshotfs"
+	"github.com/kopia/kopia/tests/testenv"
+)
+
+//nolint:gochecknoglobals
+var (
+	churnIterations = flag.Int("stress_test.iterations", 5, "Number of churn iterations")
+	churnFileCount  = flag.Int("stress_test.files", 1000, "Number of files in the initial tree")
+	churnSeed       = flag.Int64("stress_test.seed", 0, "Random seed, 0 picks a random one")
+	resultsFile     = flag.String("stress_test.results-file", "", "Write JSON results report to the provided file")
+)
+
+// iterationResult describes a single iteration of the snapshot churn stress test.
+type iterationResult struct {
+	Iteration     int                 `json:"iteration"`
+	Duration      time.Duration       `json:"duration"`
+	Churn         fsstress.ChurnStats `json:"churn"`
+	HashedFiles   int32               `json:"hashedFiles"`
+	CachedFiles   int32               `json:"cachedFiles"`
+	TotalFileSize int64               `json:"totalFileSize"`
+	BlobCount     int                 `json:"blobCount"`
+	BlobBytes     int64               `json:"blobBytes"`
+
+	// UploadBytesPerSecond is the rate at which the iteration grew the storage.
+	UploadBytesPerSecond float64 `json:"uploadBytesPerSecond"`
+	MaxHeapInUse         uint64  `json:"maxHeapInUse"`
+	CPUProfile           string  `json:"cpuProfile,omitempty"`
+	HeapProfile          string  `json:"heapProfile,omitempty"`
+}
+
+// stressResults is the results report of the snapshot churn stress test.
+type stressResults struct {
+	Seed       int64                 `json:"seed"`
+	Limits     resourceLimits        `json:"limits"`
+	Preset     string                `json:"preset,omitempty"`
+	Tree       fsstress.TreeSpec     `json:"tree"`
+	Schedule   []fsstress.ChurnModel `json:"churnSchedule"`
+	TotalChurn fsstress.ChurnStats   `json:"totalChurn"`
+	Iterations []iterationResult     `json:"iterations"`
+}
+
+func TestStressSnapshotChurn(t *testing.T) {
+	// presets are meant to be run explicitly, e.g. in CI, without setting KOPIA_STRESS_TEST.
+	if os.Getenv("KOPIA_STRESS_TEST") == "" && *stressPresetName == "" {
+		t.Skip("skipping stress test")
+	}
+
+	if testing.Short() {
+		return
+	}
+
+	seed := *churnSeed
+	if seed == 0 {
+		seed = int64(clock.Now().Nanosecond())
+	}
+
+	var target churnTarget
+
+	if p := currentStressPreset(); p != nil && p.filesystemStorage {
+		target = newCLIChurnTarget(t, testenv.NewInProcRunner(t))
+	} else {
+		_, env := repotesting.NewEnvironment(t, repotesting.FormatNotImportant)
+		target = newInProcChurnTarget(env)
+	}
+
+	res := runSnapshotChurn(t, seed, churnScheduleFromFlags(), target)
+
+	writeStressResults(t, res)
+
+	if *htmlReportFile != "" {
+		require.NoError(t, writeHTMLReport(*htmlReportFile, res))
+		t.Logf("HTML report written to %v", *htmlReportFile)
+	}
+}
+
+// churnTarget takes snapshots of a directory and reports the state of the underlying storage.
+type churnTarget interface {
+	snapshot(t *testing.T, dir string) *snapshot.Manifest
+	blobStats(t *testing.T) (count int, totalBytes int64)
+}
+
+// runSnapshotChurn creates a tree of files and snapshots it, then repeatedly mutates it according to
+// consecutive churn models of the schedule and snapshots it again, returning the results.
+//
+//nolint:thelper
+func runSnapshotChurn(t *testing.T, seed int64, schedule []fsstress.ChurnModel, target churnTarget) *stressResults {
+	t.Logf("running with seed %v, churn schedule %+v and limits %+v", seed, schedule, stressResourceLimits)
+
+	spec := treeSpecFromFlags()
+	dir := filepath.Join(testutil.TempDirectory(t), "src")
+	gen := fsstress.NewGenerator(dir, seed)
+	require.NoError(t, gen.CreateTree(spec))
+
+	res := &stressResults{
+		Seed:     seed,
+		Limits:   stressResourceLimits,
+		Preset:   *stressPresetName,
+		Tree:     spec,
+		Schedule: schedule,
+	}
+
+	_, prevBlobBytes := target.blobStats(t)
+
+	for i := range len(schedule) + 1 {
+		var churn fsstress.ChurnStats
+
+		if i > 0 {
+			var err error
+
+			churn, err = gen.Tweak(schedule[i-1])
+			require.NoError(t, err)
+		}
+
+		prof := startIterationProfiler(t, i)
+		start := clock.Now()
+
+		man := target.snapshot(t, dir)
+
+		ir := iterationResult{
+			Iteration:     i,
+			Duration:      clock.Now().Sub(start),
+			Churn:         churn,
+			HashedFiles:   man.Stats.NonCachedFiles,
+			CachedFiles:   man.Stats.CachedFiles,
+			TotalFileSize: man.Stats.TotalFileSize,
+		}
+
+		prof.stop(t, &ir)
+
+		ir.BlobCount, ir.BlobBytes = target.blobStats(t)
+
+		if secs := ir.Duration.Seconds(); secs > 0 {
+			ir.UploadBytesPerSecond = float64(ir.BlobBytes-prevBlobBytes) / secs
+		}
+
+		prevBlobBytes = ir.BlobBytes
+
+		t.Logf("iteration %v: duration=%v churn=[%v] hashed=%v cached=%v blobs=%v (%v bytes) throughput=%.0f B/s max heap=%v",
+			i, ir.Duration, churn, ir.HashedFiles, ir.CachedFiles, ir.BlobCount, ir.BlobBytes, ir.UploadBytesPerSecond, ir.MaxHeapInUse)
+
+		res.TotalChurn.Add(churn)
+		res.Iterations = append(res.Iterations, ir)
+	}
+
+	t.Logf("total churn: %v", res.TotalChurn)
+
+	return res
+}
+
+// inProcChurnTarget snapshots using the uploader in the current process.
+type inProcChurnTarget struct {
+	env      *repotesting.Environment
+	previous []*snapshot.Manifest
+}
+
+//nolint:thelper
+func (c *inProcChurnTarget) snapshot(t *testing.T, dir string) *snapshot.Manifest {
+	ctx := testlogging.Context(t)
+
+	root, err := localfs.Directory(dir)
+	require.NoError(t, err)
+
+	u := snapshotfs.NewUploader(c.env.RepositoryWriter)
+
+	man, err := u.Upload(ctx, root, nil, c.env.LocalPathSourceInfo(dir), c.previous...)
+	require.NoError(t, err)
+
+	_, err = snapshot.SaveSnapshot(ctx, c.env.RepositoryWriter, man)
+	require.NoError(t, err)
+	require.NoError(t, c.env.RepositoryWriter.Flush(ctx))
+
+	c.previous = []*snapshot.Manifest{man}
+
+	return man
+}
+
+//nolint:thelper
+func (c *inProcChurnTarget) blobStats(t *testing.T) (count int, totalBytes int64) {
+	return blobCountAndSize(testlogging.Context(t), t, c.env.RootStorage())
+}
+
+func newInProcChurnTarget(env *repotesting.Environment) *inProcChurnTarget {
+	return &inProcChurnTarget{env: env}
+}
+
+//nolint:thelper
+func blobCountAndSize(ctx context.Context, t *testing.T, st blob.Storage) (count int, totalBytes int64) {
+	require.NoError(t, st.ListBlobs(ctx, "", func(bm blob.Metadata) error {
+		count++
+		totalBytes += bm.Length
+
+		return nil
+	}))
+
+	return count, totalBytes
+}
+
+//nolint:thelper
+func writeStressResults(t *testing.T, res any) {
+	if *resultsFile == "" {
+		return
+	}
+
+	b, err := json.MarshalIndent(res, "", "  ")
+	require.NoError(t, err)
+	require.NoError(t, os.WriteFile(*resultsFile, b, 0o600))
+
+	t.Logf("results written to %v", *resultsFile)
+}
@@ -0,0 +1,256 @@
+package stress_test
+
+import (
+	"flag"
+	"fmt"
+	"math/rand"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/kopia/kopia/cli"
+	"github.com/kopia/kopia/fs/localfs"
+	"github.com/kopia/kopia/internal/clock"
+	"github.com/kopia/kopia/internal/fshasher"
+	"github.com/kopia/kopia/internal/fsstress"
+	"github.com/kopia/kopia/internal/testlogging"
+	"github.com/kopia/kopia/internal/testutil"
+	"github.com/kopia/kopia/snapshot"
+	"github.com/kopia/kopia/tests/testenv"
+)
+
+//nolint:gochecknoglobals
+var (
+	soakDuration          = flag.Duration("stress_test.duration", 0, "Duration of the soak test, which is skipped when zero")
+	soakInvariantInterval = flag.Duration("stress_test.invariant-interval", 10*time.Minute, "Interval between invariant checks in the soak test")
+)
+
+// soakState is the state of the soak test shared by all actions.
+type soakState struct {
+	e       *testenv.CLITest
+	rnd     *rand.Rand
+	gen     *fsstress.Generator
+	m       fsstress.ChurnModel
+	srcDir  string
+	tmpDir  string
+	counts  map[string]int
+	created int
+	deleted int
+}
+
+type soakAction struct {
+	name   string
+	weight int
+	act    func(t *testing.T, s *soakState)
+}
+
+// soakActions is a weighted mix of actions performed by the soak test.
+//
+//nolint:gochecknoglobals
+var soakActions = []soakAction{
+	{"snapshot-create", 40, soakSnapshotCreate},
+	{"snapshot-delete", 10, soakSnapshotDelete},
+	{"restore", 10, soakRestore},
+	{"snapshot-verify", 10, soakSnapshotVerify},
+	{"policy-edit", 10, soakPolicyEdit},
+	{"maintenance-quick", 10, soakMaintenanceQuick},
+	{"maintenance-full", 3, soakMaintenanceFull},
+}
+
+// TestStressSoak continuously performs a weighted random mix of operations against a repository
+// for the duration specified using -stress_test.duration, periodically checking invariants.
+func TestStressSoak(t *testing.T) {
+	if *soakDuration == 0 {
+		t.Skip("soak test duration not specified")
+	}
+
+	seed := *churnSeed
+	if seed == 0 {
+		seed = int64(clock.Now().Nanosecond())
+	}
+
+	t.Logf("running soak test for %v with seed %v and limits %+v", *soakDuration, seed, stressResourceLimits)
+
+	e := testenv.NewCLITest(t, testenv.RepoFormatNotImportant, testenv.NewInProcRunner(t))
+	e.RunAndExpectSuccess(t, "repo", "create", "filesystem", "--path", e.RepoDir)
+
+	s := &soakState{
+		e:      e,
+		rnd:    rand.New(rand.NewSource(seed)),
+		m:      churnModelFromFlags(),
+		srcDir: filepath.Join(testutil.TempDirectory(t), "src"),
+		tmpDir: testutil.TempDirectory(t),
+		counts: map[string]int{},
+	}
+
+	s.gen = fsstress.NewGenerator(s.srcDir, seed)
+	require.NoError(t, s.gen.CreateTree(treeSpecFromFlags()))
+
+	deadline := clock.Now().Add(*soakDuration)
+	nextInvariantCheck := clock.Now().Add(*soakInvariantInterval)
+
+	for clock.Now().Before(deadline) {
+		a := pickSoakAction(s.rnd)
+
+		a.act(t, s)
+		s.counts[a.name]++
+
+		if clock.Now().After(nextInvariantCheck) {
+			checkSoakInvariants(t, s)
+
+			nextInvariantCheck = clock.Now().Add(*soakInvariantInterval)
+		}
+	}
+
+	checkSoakInvariants(t, s)
+
+	t.Logf("soak test finished: actions=%v snapshots created=%v deleted=%v", s.counts, s.created, s.deleted)
+}
+
+func pickSoakAction(rnd *rand.Rand) soakAction {
+	sum := 0
+	for _, a := range soakActions {
+		sum += a.weight
+	}
+
+	n := rnd.Intn(sum)
+	for _, a := range soakActions {
+		if n < a.weight {
+			return a
+		}
+
+		n -= a.weight
+	}
+
+	panic("impossible")
+}
+
+// listSoakSnapshots returns the snapshots of the source directory, oldest first.
+//
+//nolint:thelper
+func listSoakSnapshots(t *testing.T, s *soakState) []*snapshot.Manifest {
+	var cliSnapshots []cli.SnapshotManifest
+
+	testutil.MustParseJSONLines(t, s.e.RunAndExpectSuccess(t, "snapshot", "list", s.srcDir, "--json"), &cliSnapshots)
+
+	mans := make([]*snapshot.Manifest, 0, len(cliSnapshots))
+
+	for _, sm := range cliSnapshots {
+		mans = append(mans, sm.Manifest)
+	}
+
+	return mans
+}
+
+//nolint:thelper
+func soakSnapshotCreate(t *testing.T, s *soakState) {
+	_, err := s.gen.Tweak(s.m)
+	require.NoError(t, err)
+
+	s.e.RunAndExpectSuccess(t, "snapshot", "create", s.srcDir)
+	s.created++
+}
+
+//nolint:thelper
+func soakSnapshotDelete(t *testing.T, s *soakState) {
+	mans := listSoakSnapshots(t, s)
+
+	// never delete the latest snapshot, which is used to check invariants.
+	if len(mans) < 2 { //nolint:mnd
+		return
+	}
+
+	victim := mans[s.rnd.Intn(len(mans)-1)]
+	s.e.RunAndExpectSuccess(t, "snapshot", "delete", string(victim.ID), "--delete")
+	s.deleted++
+}
+
+//nolint:thelper
+func soakRestore(t *testing.T, s *soakState) {
+	mans := listSoakSnapshots(t, s)
+	if len(mans) == 0 {
+		return
+	}
+
+	m := mans[s.rnd.Intn(len(mans))]
+	restoreDir := filepath.Join(s.tmpDir, "restored")
+
+	s.e.RunAndExpectSuccess(t, "snapshot", "restore", string(m.ID), restoreDir)
+
+	// remove restored files right away to avoid running out of disk space during long runs.
+	require.NoError(t, os.RemoveAll(restoreDir))
+}
+
+//nolint:thelper
+func soakSnapshotVerify(t *testing.T, s *soakState) {
+	s.e.RunAndExpectSuccess(t, "snapshot", "verify", "--verify-files-percent=10")
+}
+
+//nolint:thelper
+func soakPolicyEdit(t *testing.T, s *soakState) {
+	compressors := []string{"none", "zstd-fastest", "s2-default", "pgzip"}
+	splitters := []string{"FIXED-1M", "DYNAMIC-4M-BUZHASH", "DYNAMIC-1M-RABINKARP"}
+
+	switch s.rnd.Intn(2) { //nolint:mnd
+	case 0:
+		s.e.RunAndExpectSuccess(t, "policy", "set", s.srcDir, "--compression", compressors[s.rnd.Intn(len(compressors))])
+	default:
+		s.e.RunAndExpectSuccess(t, "policy", "set", s.srcDir, "--splitter", splitters[s.rnd.Intn(len(splitters))])
+	}
+}
+
+//nolint:thelper
+func soakMaintenanceQuick(t *testing.T, s *soakState) {
+	s.e.RunAndExpectSuccess(t, "maintenance", "run", "--force")
+}
+
+//nolint:thelper
+func soakMaintenanceFull(t *testing.T, s *soakState) {
+	s.e.RunAndExpectSuccess(t, "maintenance", "run", "--full", "--force", "--safety=none")
+}
+
+// checkSoakInvariants verifies the consistency of the repository and that the latest
+// snapshot matches the source directory.
+//
+//nolint:thelper
+func checkSoakInvariants(t *testing.T, s *soakState) {
+	ctx := testlogging.Context(t)
+
+	t.Logf("checking invariants after %v snapshots created, %v deleted", s.created, s.deleted)
+
+	s.e.RunAndExpectSuccess(t, "content", "verify")
+	s.e.RunAndExpectSuccess(t, "snapshot", "verify", "--verify-files-percent=100")
+
+	mans := listSoakSnapshots(t, s)
+
+	// retention policy may have removed some snapshots, but never more than were created.
+	require.LessOrEqual(t, len(mans), s.created-s.deleted, "unexpected number of snapshots")
+
+	if s.created == 0 {
+		return
+	}
+
+	require.NotEmpty(t, mans, "no snapshots found")
+
+	restoreDir := filepath.Join(s.tmpDir, fmt.Sprintf("latest-%v", s.created))
+	s.e.RunAndExpectSuccess(t, "snapshot", "restore", string(mans[len(mans)-1].ID), restoreDir)
+
+	defer os.RemoveAll(restoreDir) //nolint:errcheck
+
+	src, err := localfs.Directory(s.srcDir)
+	require.NoError(t, err)
+
+	restored, err := localfs.Directory(restoreDir)
+	require.NoError(t, err)
+
+	wantHash, err := fshasher.Hash(ctx, src)
+	require.NoError(t, err)
+
+	gotHash, err := fshasher.Hash(ctx, restored)
+	require.NoError(t, err)
+
+	require.Equal(t, wantHash, gotHash, "latest snapshot does not match source directory")
+}
@@ -0,0 +1,114 @@
+package stress_test
+
+import (
+	"flag"
+	"sort"
+	"strings"
+
+	"github.com/pkg/errors"
+
+	"github.com/kopia/kopia/internal/fsstress"
+)
+
+//nolint:gochecknoglobals
+var stressPresetName = flag.String("stress_test.preset", "", "Named preset of stress test parameters: "+strings.Join(stressPresetNames(), ", "))
+
+// stressPreset is a named set of stress test parameters. Flags explicitly provided on the command line
+// take precedence over the values of the preset.
+type stressPreset struct {
+	// flags are the default values of stress test flags.
+	flags map[string]string
+
+	// schedule, when set, is the sequence of churn models applied in consecutive iterations
+	// instead of the model specified using flags.
+	schedule []fsstress.ChurnModel
+
+	// filesystemStorage runs the snapshot churn test against a filesystem repository.
+	filesystemStorage bool
+}
+
+//nolint:gochecknoglobals
+var stressPresets = map[string]stressPreset{
+	// quick-race is small enough to run under the race detector in CI in under two minutes,
+	// while still covering concurrent snapshot and upload paths with each kind of mutation.
+	"quick-race": {
+		flags: map[string]string{
+			"stress_test.files": "200",
+		},
+		schedule: []fsstress.ChurnModel{
+			{AddPercent: 10},
+			{DeletePercent: 10},
+			{ModifyPercent: 10, AppendPercent: 100},
+			{ModifyPercent: 10, AppendPercent: 0},
+			{RenamePercent: 10},
+		},
+		filesystemStorage: true,
+	},
+}
+
+func stressPresetNames() []string {
+	var names []string
+
+	for n := range stressPresets {
+		names = append(names, n)
+	}
+
+	sort.Strings(names)
+
+	return names
+}
+
+// currentStressPreset returns the preset selected using -stress_test.preset or nil.
+func currentStressPreset() *stressPreset {
+	p, ok := stressPresets[*stressPresetName]
+	if !ok {
+		return nil
+	}
+
+	return &p
+}
+
+// applyStressPreset sets the values of flags that have not been explicitly provided to the values of the preset.
+func applyStressPreset(name string) error {
+	if name == "" {
+		return nil
+	}
+
+	p, ok := stressPresets[name]
+	if !ok {
+		return errors.Errorf("unknown stress test preset %q, must be one of: %v", name, strings.Join(stressPresetNames(), ", "))
+	}
+
+	explicit := map[string]bool{}
+
+	flag.Visit(func(f *flag.Flag) {
+		explicit[f.Name] = true
+	})
+
+	for k, v := range p.flags {
+		if explicit[k] {
+			continue
+		}
+
+		if err := flag.Set(k, v); err != nil {
+			return errors.Wrapf(err, "unable to set %v", k)
+		}
+	}
+
+	return nil
+}
+
+// churnScheduleFromFlags returns the sequence of churn models applied in iterations following the initial snapshot.
+func churnScheduleFromFlags() []fsstress.ChurnModel {
+	if p := currentStressPreset(); p != nil && p.schedule != nil {
+		return p.schedule
+	}
+
+	var result []fsstress.ChurnModel
+
+	for range *churnIterations - 1 {
+		result = append(result, churnModelFromFlags())
+	}
+
+	return result
+}
@@ -0,0 +1,209 @@
+package testenv
+
+import (
+	"context"
+	"net/http"
+	"path/filepath"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/require"
+
+	"github.com/kopia/kopia/internal/apiclient"
+	"github.com/kopia/kopia/internal/retry"
+	"github.com/kopia/kopia/internal/serverapi"
+	"github.com/kopia/kopia/internal/testlogging"
+	"github.com/kopia/kopia/internal/testutil"
+	"github.com/kopia/kopia/repo"
+)
+
+const (
+	apiServerUIUsername      = "ui-user"
+	apiServerUIPassword      = "ui-password"
+	apiServerControlUsername = "control-user"
+	apiServerControlPassword = "control-password"
+
+	// shorter than the default key size to speed up certificate generation.
+	apiServerRSAKeySize = 2048
+
+	apiServerStartupPollInterval = 100 * time.Millisecond
+	apiServerStartupPollCount    = 600
+)
+
+// APIServerACL describes an access control rule added using 'server acl add'.
+type APIServerACL struct {
+	User   string // user@host pattern, such as "*@*"
+	Target string // comma-separated key=value pairs, such as "type=snapshot,username=OWN_USER"
+	Access string // READ, APPEND or FULL
+}
+
+// APIServerOptions customizes the server started by StartAPIServer.
+type APIServerOptions struct {
+	// Users maps names of repository users (user@host) to their passwords.
+	Users map[string]string
+
+	// EnableACL inserts the default access control rules before adding ACLs.
+	EnableACL bool
+	ACLs      []APIServerACL
+
+	// ServerStartArgs are additional arguments to 'server start'.
+	ServerStartArgs []string
+}
+
+// APIServer describes a running 'kopia server' started by StartAPIServer.
+type APIServer struct {
+	// Info describes how repository clients connect to the server.
+	Info *repo.APIServerInfo
+
+	TLSCertFile string
+	TLSKeyFile  string
+
+	UIUsername string
+	UIPassword string
+
+	// Control is a client of the server control API.
+	Control *apiclient.KopiaAPIClient
+
+	wait func() error
+	kill func()
+}
+
+// StartAPIServer starts 'kopia server' using the repository which the environment is connected to,
+// with a generated TLS certificate, the provided users and access control rules. It returns once the
+// server is ready to accept repository clients. The server is shut down when the test completes.
+func (e *CLITest) StartAPIServer(t *testing.T, opts APIServerOptions) *APIServer {
+	t.Helper()
+
+	ctx := testlogging.Context(t)
+
+	for user, password := range opts.Users {
+		e.RunAndExpectSuccess(t, "server", "users", "add", user, "--user-password", password)
+	}
+
+	if opts.EnableACL {
+		e.RunAndExpectSuccess(t, "server", "acl", "enable")
+	}
+
+	for _, a := range opts.ACLs {
+		e.RunAndExpectSuccess(t, "server", "acl", "add", "--user", a.User, "--target", a.Target, "--access", a.Access, "--overwrite")
+	}
+
+	certDir := testutil.TempDirectory(t)
+
+	s := &APIServer{
+		TLSCertFile: filepath.Join(certDir, "tls.cert"),
+		TLSKeyFile:  filepath.Join(certDir, "tls.key"),
+		UIUsername:  apiServerUIUsername,
+		UIPassword:  apiServerUIPassword,
+	}
+
+	var sp testutil.ServerParameters
+
+	s.wait, s.kill = e.RunAndProcessStderr(t, sp.ProcessOutput,
+		append([]string{
+			"server", "start",
+			"--address=localhost:0",
+			"--tls-generate-cert",
+			"--tls-generate-rsa-key-size=" + strconv.Itoa(apiServerRSAKeySize),
+			"--tls-cert-file", s.TLSCertFile,
+			"--tls-key-file", s.TLSKeyFile,
+			"--server-username", apiServerUIUsername,
+			"--server-password", apiServerUIPassword,
+			"--server-control-username", apiServerControlUsername,
+			"--server-control-password", apiServerControlPassword,
+			"--shutdown-grace-period", "100ms",
+		}, opts.ServerStartArgs...)...)
+
+	t.Logf("detected server parameters %#v", sp)
+
+	s.Info = &repo.APIServerInfo{
+		BaseURL:                             sp.BaseURL,
+		TrustedServerCertificateFingerprint: sp.SHA256Fingerprint,
+		LocalCacheKeyDerivationAlgorithm:    repo.DefaultServerRepoCacheKeyDerivationAlgorithm,
+	}
+
+	control, err := apiclient.NewKopiaAPIClient(apiclient.Options{
+		BaseURL:                             sp.BaseURL,
+		Username:                            apiServerControlUsername,
+		Password:                            apiServerControlPassword,
+		TrustedServerCertificateFingerprint: sp.SHA256Fingerprint,
+	})
+	require.NoError(t, err)
+
+	s.Control = control
+
+	t.Cleanup(func() {
+		s.Shutdown(t)
+	})
+
+	require.NoError(t, waitForAPIServerReady(ctx, control))
+
+	return s
+}
+
+// Shutdown stops the server and waits for it to exit. It is safe to call more than once.
+func (s *APIServer) Shutdown(t *testing.T) {
+	t.Helper()
+
+	if s.wait == nil {
+		return
+	}
+
+	if err := serverapi.Shutdown(testlogging.Context(t), s.Control); err != nil {
+		t.Logf("unable to shut down server gracefully: %v", err)
+		s.kill()
+	}
+
+	s.wait() //nolint:errcheck
+
+	s.wait = nil
+}
+
+// ConnectArgs returns arguments to 'repo connect' connecting to the server as the provided user.
+func (s *APIServer) ConnectArgs(username, hostname, password string) []string {
+	return []string{
+		"repo", "connect", "server",
+		"--url", s.Info.BaseURL + "/",
+		"--server-cert-fingerprint", s.Info.TrustedServerCertificateFingerprint,
+		"--override-username", username,
+		"--override-hostname", hostname,
+		"--password", password,
+	}
+}
+
+// ConnectClient connects the provided environment to the server as the provided user and disconnects
+// it when the test completes, before the server is shut down.
+func (s *APIServer) ConnectClient(t *testing.T, client *CLITest, username, hostname, password string) {
+	t.Helper()
+
+	// the password provided on the command line must not be overridden by the environment.
+	delete(client.Environment, "KOPIA_PASSWORD")
+
+	client.RunAndExpectSuccess(t, s.ConnectArgs(username, hostname, password)...)
+
+	t.Cleanup(func() {
+		client.RunAndExpectSuccess(t, "repo", "disconnect")
+	})
+}
+
+// waitForAPIServerReady waits until the server responds to status requests.
+func waitForAPIServerReady(ctx context.Context, control *apiclient.KopiaAPIClient) error {
+	//nolint:wrapcheck
+	return retry.PeriodicallyNoValue(ctx, apiServerStartupPollInterval, apiServerStartupPollCount, "wait for server start", func() error {
+		_, err := serverapi.Status(ctx, control)
+		return err
+	}, func(err error) bool {
+		var hs apiclient.HTTPStatusError
+
+		if errors.As(err, &hs) {
+			switch hs.HTTPStatusCode {
+			case http.StatusBadRequest, http.StatusForbidden:
+				return false
+			}
+		}
+
+		return true
+	})
+}
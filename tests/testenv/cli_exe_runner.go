@@ -27,6 +27,8 @@ func (e *CLIExeRunner) Start(t *testing.T, args []string, env map[string]string)
 		"--log-dir", e.LogsDir,
 	}, args...)...)
 
+	prepareExeCommand(c)
+
 	c.Env = append(c.Env, os.Environ()...)
 
 	for k, v := range env {
@@ -58,7 +60,9 @@ func (e *CLIExeRunner) Start(t *testing.T, args []string, env map[string]string)
 			return
 		}
 
-		c.Process.Signal(sig)
+		if err := signalExeProcess(c.Process, sig); err != nil {
+			t.Logf("unable to deliver %v to %v: %v", sig, c.Process.Pid, err)
+		}
 	}
 }
 
@@ -0,0 +1,22 @@
+//go:build !windows
+// +build !windows
+
+package testenv
+
+import (
+	"os"
+	"os/exec"
+)
+
+// prepareExeCommand configures the command, so that signals can be delivered to it.
+//
+//nolint:revive
+func prepareExeCommand(c *exec.Cmd) {
+	// signals are delivered directly to the process.
+}
+
+// signalExeProcess delivers the provided signal to the process.
+func signalExeProcess(p *os.Process, sig os.Signal) error {
+	//nolint:wrapcheck
+	return p.Signal(sig)
+}
@@ -0,0 +1,44 @@
+package testenv
+
+import (
+	"os"
+	"os/exec"
+	"syscall"
+
+	"github.com/pkg/errors"
+	"golang.org/x/sys/windows"
+
+	"github.com/kopia/kopia/cli"
+)
+
+// prepareExeCommand configures the command, so that signals can be delivered to it.
+func prepareExeCommand(c *exec.Cmd) {
+	// console control events can only be sent to process groups, start the command in a new one
+	// so that the test process itself does not receive them.
+	c.SysProcAttr = &syscall.SysProcAttr{
+		CreationFlags: windows.CREATE_NEW_PROCESS_GROUP,
+	}
+}
+
+// signalExeProcess delivers the equivalent of the provided signal to the process. Windows does not support
+// sending signals to other processes, so graceful shutdown is requested by sending CTRL_BREAK_EVENT, which
+// the process observes as os.Interrupt, and profile dumps by setting a named event.
+func signalExeProcess(p *os.Process, sig os.Signal) error {
+	if sig == cli.ProfileDumpSignal {
+		name, err := windows.UTF16PtrFromString(cli.ProfileDumpEventName(p.Pid))
+		if err != nil {
+			return errors.Wrap(err, "invalid event name")
+		}
+
+		ev, err := windows.OpenEvent(windows.EVENT_MODIFY_STATE, false, name)
+		if err != nil {
+			return errors.Wrap(err, "unable to open profile dump event")
+		}
+
+		defer windows.CloseHandle(ev) //nolint:errcheck
+
+		return errors.Wrap(windows.SetEvent(ev), "unable to set profile dump event")
+	}
+
+	return errors.Wrap(windows.GenerateConsoleCtrlEvent(windows.CTRL_BREAK_EVENT, uint32(p.Pid)), "unable to send console control event")
+}
@@ -4,6 +4,8 @@
 	"fmt"
 	"io"
 	"os"
+	"path/filepath"
+	"strings"
 	"sync"
 	"sync/atomic"
 	"testing"
@@ -11,11 +13,16 @@
 	"github.com/alecthomas/kingpin/v2"
 
 	"github.com/kopia/kopia/cli"
+	"github.com/kopia/kopia/internal/metrics"
 	"github.com/kopia/kopia/internal/testlogging"
+	"github.com/kopia/kopia/internal/testutil"
 )
 
 var envPrefixCounter = new(int32)
 
+// maximum number of command words used to name directory with captured profiles.
+const maxProfileDirCommandWords = 3
+
 // CLIInProcRunner is a CLIRunner that invokes provided commands in the current process.
 type CLIInProcRunner struct {
 	mu sync.Mutex
@@ -23,9 +30,51 @@ type CLIInProcRunner struct {
 	// +checklocks:mu
 	nextCommandStdin io.Reader // this is used for stdin source tests
 
+	// +checklocks:mu
+	nextCommandPrompts cli.PromptFunc // this is used for interactive tests
+
+	// +checklocks:mu
+	nextCommandMetrics *metrics.Snapshot // this is used for tests asserting on repository metrics
+
+	// +checklocks:mu
+	profileDir string // per-test directory receiving captured profiles
+
+	// +checklocks:mu
+	commandCount int
+
+	// ProfileBuffers, when set, enables capturing profile buffers in the format of KOPIA_PPROF_LOGGING_CONFIG
+	// for each command, which are written into the profile directory of the test.
+	ProfileBuffers string
+
+	// SnapshotSourceWrapper, when set, wraps local directories snapshotted by commands, which allows
+	// injecting filesystem errors (see faultyfs package).
+	SnapshotSourceWrapper cli.SnapshotSourceWrapper
+
 	CustomizeApp func(a *cli.App, kp *kingpin.Application)
 }
 
+// InProcRunnerOption customizes CLIInProcRunner.
+type InProcRunnerOption func(r *CLIInProcRunner)
+
+// WithProfileCapture causes each command to capture profiles using the provided configuration, such as
+// "cpu:heap=forcegc:mutex", and write them into a directory named after the test, which is preserved
+// when the test fails (see testutil.TempProfileDirectory).
+//
+// Profile buffers are global to the process, so profiles are not captured for commands executing while
+// profiles of another command are being captured.
+func WithProfileCapture(config string) InProcRunnerOption {
+	return func(r *CLIInProcRunner) {
+		r.ProfileBuffers = config
+	}
+}
+
+// WithSnapshotSourceWrapper causes local directories snapshotted by commands to be wrapped using the provided function.
+func WithSnapshotSourceWrapper(f cli.SnapshotSourceWrapper) InProcRunnerOption {
+	return func(r *CLIInProcRunner) {
+		r.SnapshotSourceWrapper = f
+	}
+}
+
 // Start implements CLIRunner.
 func (e *CLIInProcRunner) Start(t *testing.T, args []string, env map[string]string) (stdout, stderr io.Reader, wait func() error, interrupt func(os.Signal)) {
 	t.Helper()
@@ -40,20 +89,72 @@ func (e *CLIInProcRunner) Start(t *testing.T, args []string, env map[string]stri
 
 	kpapp := kingpin.New("test", "test")
 
-	if e.CustomizeApp != nil {
-		e.CustomizeApp(a, kpapp)
-	}
-
 	e.mu.Lock()
 	stdin := e.nextCommandStdin
 	e.nextCommandStdin = nil
+	prompts := e.nextCommandPrompts
+	e.nextCommandPrompts = nil
+	metricsSnapshot := e.nextCommandMetrics
+	e.nextCommandMetrics = nil
+	args = e.maybeAddProfileFlagsLocked(t, args)
 	e.mu.Unlock()
 
-	for k, v := range env {
-		os.Setenv(envPrefix+k, v)
+	opts := []cli.SubcommandOption{cli.WithEnvironment(env)}
+
+	if e.CustomizeApp != nil {
+		// customizations apply to the App parsing flags of the command.
+		opts = append(opts, func(ia *cli.App) {
+			e.CustomizeApp(ia, kpapp)
+		})
+	}
+	if prompts != nil {
+		opts = append(opts, cli.WithPrompts(prompts))
+	}
+
+	if metricsSnapshot != nil {
+		opts = append(opts, cli.WithMetricsSnapshot(metricsSnapshot))
+	}
+
+	if e.SnapshotSourceWrapper != nil {
+		opts = append(opts, cli.WithSnapshotSourceWrapper(e.SnapshotSourceWrapper))
+	}
+
+	return a.RunSubcommand(ctx, kpapp, stdin, args, opts...)
+}
+
+// maybeAddProfileFlagsLocked returns the command arguments with flags capturing profiles into a subdirectory
+// of the test profile directory named after the command, if profile capture is enabled.
+//
+// +checklocks:e.mu
+func (e *CLIInProcRunner) maybeAddProfileFlagsLocked(t *testing.T, args []string) []string {
+	t.Helper()
+
+	if e.ProfileBuffers == "" {
+		return args
+	}
+
+	if e.profileDir == "" {
+		e.profileDir = testutil.TempProfileDirectory(t)
 	}
 
-	return a.RunSubcommand(ctx, kpapp, stdin, args)
+	e.commandCount++
+
+	var words []string
+
+	// name the directory after the command, skipping flags and arguments that do not look like command names.
+	for _, a := range args {
+		if strings.HasPrefix(a, "-") || strings.Trim(a, "abcdefghijklmnopqrstuvwxyz-") != "" {
+			continue
+		}
+
+		if words = append(words, a); len(words) == maxProfileDirCommandWords {
+			break
+		}
+	}
+
+	commandDir := filepath.Join(e.profileDir, fmt.Sprintf("%03d-%v", e.commandCount, strings.Join(words, "-")))
+
+	return append([]string{"--profile-buffers=" + e.ProfileBuffers, "--profile-dir=" + commandDir}, args...)
 }
 
 // SetNextStdin sets the stdin to be used on next command execution.
@@ -64,15 +165,35 @@ func (e *CLIInProcRunner) SetNextStdin(stdin io.Reader) {
 	e.nextCommandStdin = stdin
 }
 
+// SetNextPrompts sets the function answering interactive prompts of the next command execution.
+func (e *CLIInProcRunner) SetNextPrompts(f cli.PromptFunc) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	e.nextCommandPrompts = f
+}
+
+// SetNextMetricsSnapshot sets the snapshot which will receive final repository metrics of the next command execution.
+func (e *CLIInProcRunner) SetNextMetricsSnapshot(dst *metrics.Snapshot) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	e.nextCommandMetrics = dst
+}
+
 // NewInProcRunner returns a runner that executes CLI subcommands in the current process using cli.RunSubcommand().
-func NewInProcRunner(t *testing.T) *CLIInProcRunner {
+func NewInProcRunner(t *testing.T, opts ...InProcRunnerOption) *CLIInProcRunner {
 	t.Helper()
 
 	if os.Getenv("KOPIA_EXE") != "" && os.Getenv("KOPIA_RUN_ALL_INTEGRATION_TESTS") == "" {
 		t.Skip("not running test since it's also included in the unit tests")
 	}
 
-	return &CLIInProcRunner{
+	return newInProcRunner(opts...)
+}
+
+func newInProcRunner(opts ...InProcRunnerOption) *CLIInProcRunner {
+	r := &CLIInProcRunner{
 		CustomizeApp: func(a *cli.App, kp *kingpin.Application) {
 			a.AddStorageProvider(cli.StorageProvider{
 				Name:        "in-memory",
@@ -80,7 +201,14 @@ func NewInProcRunner(t *testing.T) *CLIInProcRunner {
 				NewFlags:    func() cli.StorageFlags { return &storageInMemoryFlags{} },
 			})
 		},
+		ProfileBuffers: os.Getenv("KOPIA_TESTS_PROFILE_BUFFERS"),
 	}
+
+	for _, o := range opts {
+		o(r)
+	}
+
+	return r
 }
 
 var _ CLIRunner = (*CLIInProcRunner)(nil)
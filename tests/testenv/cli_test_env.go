@@ -17,6 +17,7 @@
 
 	"github.com/stretchr/testify/require"
 
+	"github.com/kopia/kopia/cli"
 	"github.com/kopia/kopia/internal/clock"
 	"github.com/kopia/kopia/internal/testutil"
 	"github.com/kopia/kopia/internal/timetrack"
@@ -242,6 +243,18 @@ func (e *CLITest) RunAndExpectFailure(t *testing.T, args ...string) (stdout, std
 	return stdout, stderr
 }
 
+// RunAndExpectExitCode runs the given command, expects it to exit with the provided exit code and returns its output lines.
+func (e *CLITest) RunAndExpectExitCode(t *testing.T, wantCode int, args ...string) (stdout, stderr []string) {
+	t.Helper()
+
+	stdout, stderr, err := e.Run(t, wantCode != cli.ExitCodeSuccess, args...)
+	if got := cli.ExitCode(err); got != wantCode {
+		t.Fatalf("'kopia %v' exited with code %v (%v), but expected %v", strings.Join(args, " "), got, err, wantCode)
+	}
+
+	return stdout, stderr
+}
+
 // RunAndVerifyOutputLineCount runs the given command and asserts it returns the given number of output lines, then returns them.
 func (e *CLITest) RunAndVerifyOutputLineCount(t *testing.T, wantLines int, args ...string) []string {
 	t.Helper()
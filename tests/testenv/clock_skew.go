@@ -0,0 +1,60 @@
+package testenv
+
+import (
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/kopia/kopia/internal/faketime"
+)
+
+const currentEpochPrefix = "Current Epoch: "
+
+// CurrentEpoch returns the current write epoch of the repository as reported by 'index epoch list'.
+func (e *CLITest) CurrentEpoch(t *testing.T) int {
+	t.Helper()
+
+	for _, l := range e.RunAndExpectSuccess(t, "index", "epoch", "list") {
+		if s, ok := strings.CutPrefix(l, currentEpochPrefix); ok {
+			n, err := strconv.Atoi(strings.TrimSpace(s))
+			require.NoError(t, err)
+
+			return n
+		}
+	}
+
+	require.FailNow(t, "current epoch not found in 'index epoch list' output")
+
+	return -1
+}
+
+// RequireEpochAdvanced asserts that the current write epoch of the repository is greater than
+// the provided one and returns it.
+func (e *CLITest) RequireEpochAdvanced(t *testing.T, previous int) int {
+	t.Helper()
+
+	current := e.CurrentEpoch(t)
+	require.Greater(t, current, previous, "epoch did not advance")
+
+	return current
+}
+
+// RequireEpochNotAdvanced asserts that the current write epoch of the repository is equal to
+// the provided one.
+func (e *CLITest) RequireEpochNotAdvanced(t *testing.T, previous int) {
+	t.Helper()
+
+	require.Equal(t, previous, e.CurrentEpoch(t), "epoch unexpectedly advanced")
+}
+
+// RequireClientSkew asserts that the time seen by the provided client of the fake time server
+// differs from the storage time by the expected skew, within the provided tolerance.
+func RequireClientSkew(t *testing.T, fts *faketime.Server, clientID string, want, tolerance time.Duration) {
+	t.Helper()
+
+	got := fts.ClientTime(clientID).Sub(fts.ClientTime(faketime.StorageClient))
+	require.InDelta(t, float64(want), float64(got), float64(tolerance), "unexpected skew of %q: %v, want %v", clientID, got, want)
+}
@@ -0,0 +1,114 @@
+package testenv
+
+import (
+	"context"
+	"flag"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/kopia/kopia/repo"
+)
+
+//nolint:gochecknoglobals
+var updateGolden = flag.Bool("update-golden", false, "Update golden files instead of comparing command output against them")
+
+const (
+	goldenDirPerm  = 0o700
+	goldenFilePerm = 0o600
+)
+
+// GoldenNormalizer rewrites volatile parts of a line of command output, so that it can be compared against a golden file.
+type GoldenNormalizer func(line string) string
+
+// ReplaceRegexp returns a GoldenNormalizer replacing all matches of the provided regular expression.
+func ReplaceRegexp(re *regexp.Regexp, replacement string) GoldenNormalizer {
+	return func(line string) string {
+		return re.ReplaceAllString(line, replacement)
+	}
+}
+
+// ReplaceLiteral returns a GoldenNormalizer replacing all occurrences of the provided string.
+func ReplaceLiteral(old, replacement string) GoldenNormalizer {
+	return func(line string) string {
+		if old == "" {
+			return line
+		}
+
+		return strings.ReplaceAll(line, old, replacement)
+	}
+}
+
+// DefaultGoldenNormalizers replace timestamps and hexadecimal identifiers (content, object, snapshot and repository IDs).
+//
+//nolint:gochecknoglobals
+var DefaultGoldenNormalizers = []GoldenNormalizer{
+	ReplaceRegexp(regexp.MustCompile(`\d{4}-\d{2}-\d{2}[T ]\d{2}:\d{2}:\d{2}(\.\d+)?(Z|[+-]\d{2}:?\d{2}| [A-Z]{3,5})?`), "<TIME>"),
+	ReplaceRegexp(regexp.MustCompile(`\b[g-z]?[0-9a-f]{16,}\b`), "<ID>"),
+}
+
+// NormalizeOutput applies the provided normalizers followed by DefaultGoldenNormalizers to each line of output.
+func NormalizeOutput(lines []string, normalizers ...GoldenNormalizer) []string {
+	all := append(append([]GoldenNormalizer(nil), normalizers...), DefaultGoldenNormalizers...)
+
+	result := make([]string, 0, len(lines))
+
+	for _, l := range lines {
+		for _, n := range all {
+			l = n(l)
+		}
+
+		result = append(result, l)
+	}
+
+	return result
+}
+
+// AssertGolden normalizes the provided output lines and compares them against the contents of the golden file.
+// When tests are run with -update-golden, the golden file is rewritten instead.
+func AssertGolden(t *testing.T, goldenFile string, lines []string, normalizers ...GoldenNormalizer) {
+	t.Helper()
+
+	got := strings.Join(NormalizeOutput(lines, normalizers...), "\n") + "\n"
+
+	if *updateGolden {
+		require.NoError(t, os.MkdirAll(filepath.Dir(goldenFile), goldenDirPerm))
+		require.NoError(t, os.WriteFile(goldenFile, []byte(got), goldenFilePerm))
+		t.Logf("updated golden file %v", goldenFile)
+
+		return
+	}
+
+	want, err := os.ReadFile(goldenFile) //nolint:gosec
+	require.NoError(t, err, "unable to read golden file, run tests with -update-golden to create it")
+
+	require.Equal(t, string(want), got, "output does not match %v, run tests with -update-golden to update it", goldenFile)
+}
+
+// GoldenNormalizers returns normalizers replacing directories, user and host names specific to the test environment.
+func (e *CLITest) GoldenNormalizers() []GoldenNormalizer {
+	ctx := context.Background()
+
+	return []GoldenNormalizer{
+		ReplaceLiteral(e.RepoDir, "<REPO_DIR>"),
+		ReplaceLiteral(e.ConfigDir, "<CONFIG_DIR>"),
+		ReplaceLiteral(repo.GetDefaultUserName(ctx)+"@"+repo.GetDefaultHostName(ctx), "<USER>@<HOST>"),
+	}
+}
+
+// RunAndExpectGoldenOutput runs the given command, expects it to succeed and compares its normalized output
+// against the golden file. Additional normalizers can be provided to replace test-specific values, such as
+// names of temporary directories. Returns the original output lines.
+func (e *CLITest) RunAndExpectGoldenOutput(t *testing.T, goldenFile string, normalizers []GoldenNormalizer, args ...string) []string {
+	t.Helper()
+
+	lines := e.RunAndExpectSuccess(t, args...)
+
+	AssertGolden(t, goldenFile, lines, append(e.GoldenNormalizers(), normalizers...)...)
+
+	return lines
+}
@@ -0,0 +1,250 @@
+package testenv
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"testing"
+	"time"
+
+	"github.com/minio/minio-go/v7"
+	"github.com/minio/minio-go/v7/pkg/credentials"
+	"github.com/stretchr/testify/require"
+
+	"github.com/kopia/kopia/internal/clock"
+	"github.com/kopia/kopia/internal/testlogging"
+	"github.com/kopia/kopia/internal/testutil"
+	"github.com/kopia/kopia/repo/blob"
+	"github.com/kopia/kopia/repo/blob/s3"
+)
+
+const (
+	// fake credentials used by ephemeral MinIO servers.
+	minioRootAccessKeyID     = "fake-key"
+	minioRootSecretAccessKey = "fake-secret"
+	minioRegion              = "fake-region-1"
+
+	minioStartupTimeout     = 30 * time.Second
+	s3BucketRetryPeriod     = 1 * time.Second
+	s3BucketDeleteRetries   = 10
+	s3BucketNameRandomBytes = 4
+)
+
+// S3Bucket describes connection parameters of a bucket on an S3-compatible endpoint.
+type S3Bucket struct {
+	Endpoint        string
+	AccessKeyID     string
+	SecretAccessKey string
+	SessionToken    string
+	Region          string
+	BucketName      string
+	DoNotUseTLS     bool
+}
+
+// Options returns options of S3 storage stored in the bucket under the provided prefix.
+func (b *S3Bucket) Options(prefix string) *s3.Options {
+	return &s3.Options{
+		Endpoint:        b.Endpoint,
+		AccessKeyID:     b.AccessKeyID,
+		SecretAccessKey: b.SecretAccessKey,
+		SessionToken:    b.SessionToken,
+		Region:          b.Region,
+		BucketName:      b.BucketName,
+		Prefix:          prefix,
+		DoNotUseTLS:     b.DoNotUseTLS,
+	}
+}
+
+// RepoArgs returns arguments to 'repo create' and 'repo connect' selecting the repository stored
+// in the bucket under the provided prefix.
+func (b *S3Bucket) RepoArgs(prefix string) []string {
+	args := []string{
+		"s3",
+		"--endpoint", b.Endpoint,
+		"--bucket", b.BucketName,
+		"--access-key", b.AccessKeyID,
+		"--secret-access-key", b.SecretAccessKey,
+		"--prefix", prefix,
+	}
+
+	if b.SessionToken != "" {
+		args = append(args, "--session-token", b.SessionToken)
+	}
+
+	if b.Region != "" {
+		args = append(args, "--region", b.Region)
+	}
+
+	if b.DoNotUseTLS {
+		args = append(args, "--disable-tls")
+	}
+
+	return args
+}
+
+// S3BucketOptions describes optional properties of buckets created by CreateTempS3BucketWithOptions.
+type S3BucketOptions struct {
+	// Versioned enables versioning on the bucket, which is implied by ObjectLocking.
+	Versioned bool
+
+	// ObjectLocking creates the bucket with object locking enabled.
+	ObjectLocking bool
+
+	// RetentionMode and RetentionDays set the default retention of the object-locked bucket.
+	RetentionMode blob.RetentionMode
+	RetentionDays uint
+}
+
+func (b *S3Bucket) client(t *testing.T) *minio.Client {
+	t.Helper()
+
+	cli, err := minio.New(b.Endpoint, &minio.Options{
+		Creds:  credentials.NewStaticV4(b.AccessKeyID, b.SecretAccessKey, b.SessionToken),
+		Secure: !b.DoNotUseTLS,
+		Region: b.Region,
+	})
+	require.NoError(t, err)
+
+	return cli
+}
+
+// StartMinIO starts an ephemeral MinIO server and returns parameters of a newly-created bucket on it.
+// The server is killed when the test completes. Skips the test if Docker is not available.
+func StartMinIO(t *testing.T) *S3Bucket {
+	t.Helper()
+
+	return StartMinIOWithOptions(t, S3BucketOptions{})
+}
+
+// StartMinIOWithOptions is like StartMinIO but creates the bucket with the provided options.
+func StartMinIOWithOptions(t *testing.T, opt S3BucketOptions) *S3Bucket {
+	t.Helper()
+
+	testutil.TestSkipOnCIUnlessLinuxAMD64(t)
+
+	containerID := testutil.RunContainerAndKillOnCloseOrSkip(t,
+		"run", "--rm", "-p", "0:9000",
+		"-e", "MINIO_ROOT_USER="+minioRootAccessKeyID,
+		"-e", "MINIO_ROOT_PASSWORD="+minioRootSecretAccessKey,
+		"-e", "MINIO_REGION_NAME="+minioRegion,
+		"-d", "minio/minio", "server", "/data")
+
+	return CreateTempS3BucketWithOptions(t, S3Bucket{
+		Endpoint:        testutil.GetContainerMappedPortAddress(t, containerID, "9000"),
+		AccessKeyID:     minioRootAccessKeyID,
+		SecretAccessKey: minioRootSecretAccessKey,
+		Region:          minioRegion,
+		DoNotUseTLS:     true,
+	}, "kopia-test", opt)
+}
+
+// CreateTempS3Bucket creates a uniquely-named bucket with the provided name prefix on the endpoint described
+// by the provided parameters and removes the bucket with all its contents when the test completes.
+// The bucket name in the provided parameters is ignored.
+func CreateTempS3Bucket(t *testing.T, endpoint S3Bucket, namePrefix string) *S3Bucket {
+	t.Helper()
+
+	return CreateTempS3BucketWithOptions(t, endpoint, namePrefix, S3BucketOptions{})
+}
+
+// CreateTempS3BucketWithOptions is like CreateTempS3Bucket but creates the bucket with the provided options.
+// Objects in buckets with compliance retention cannot be removed, so such buckets are left in place.
+func CreateTempS3BucketWithOptions(t *testing.T, endpoint S3Bucket, namePrefix string, opt S3BucketOptions) *S3Bucket {
+	t.Helper()
+
+	ctx := testlogging.Context(t)
+
+	b := endpoint
+	b.BucketName = namePrefix + "-" + randomHexString(s3BucketNameRandomBytes)
+
+	cli := b.client(t)
+
+	// the server may still be starting, retry until it accepts requests.
+	deadline := clock.Now().Add(minioStartupTimeout)
+
+	for {
+		err := cli.MakeBucket(ctx, b.BucketName, minio.MakeBucketOptions{
+			Region:        b.Region,
+			ObjectLocking: opt.ObjectLocking,
+		})
+		if err == nil {
+			break
+		}
+
+		if clock.Now().After(deadline) {
+			require.NoError(t, err, "unable to create bucket")
+		}
+
+		time.Sleep(s3BucketRetryPeriod)
+	}
+
+	// object locking implicitly enables versioning.
+	if opt.Versioned && !opt.ObjectLocking {
+		require.NoError(t, cli.EnableVersioning(ctx, b.BucketName), "unable to enable versioning")
+	}
+
+	if opt.RetentionMode != "" {
+		mode := minio.RetentionMode(opt.RetentionMode)
+		validity := opt.RetentionDays
+		unit := minio.Days
+
+		require.NoError(t, cli.SetBucketObjectLockConfig(ctx, b.BucketName, &mode, &validity, &unit), "unable to set object lock config")
+	}
+
+	t.Logf("created bucket %v on %v", b.BucketName, b.Endpoint)
+
+	if opt.RetentionMode == blob.Compliance {
+		return &b
+	}
+
+	t.Cleanup(func() {
+		removeS3Bucket(ctx, t, cli, b.BucketName, opt.Versioned || opt.ObjectLocking)
+	})
+
+	return &b
+}
+
+func removeS3Bucket(ctx context.Context, t *testing.T, cli *minio.Client, bucketName string, versioned bool) {
+	t.Helper()
+
+	objChan := make(chan minio.ObjectInfo)
+	errCh := cli.RemoveObjects(ctx, bucketName, objChan, minio.RemoveObjectsOptions{
+		GovernanceBypass: versioned,
+	})
+
+	go func() {
+		for removeErr := range errCh {
+			t.Errorf("error removing key %s from bucket: %s", removeErr.ObjectName, removeErr.Err)
+		}
+	}()
+
+	for obj := range cli.ListObjects(ctx, bucketName, minio.ListObjectsOptions{
+		Prefix:       "",
+		Recursive:    true,
+		WithVersions: versioned,
+	}) {
+		objChan <- obj
+	}
+
+	close(objChan)
+
+	var err error
+
+	for range s3BucketDeleteRetries {
+		time.Sleep(s3BucketRetryPeriod)
+
+		err = cli.RemoveBucket(ctx, bucketName)
+		if err == nil {
+			break
+		}
+	}
+
+	require.NoError(t, err)
+}
+
+func randomHexString(n int) string {
+	b := make([]byte, n)
+	rand.Read(b) //nolint:errcheck
+
+	return hex.EncodeToString(b)
+}
@@ -0,0 +1,302 @@
+package testenv
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"math/rand"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/require"
+
+	"github.com/kopia/kopia/repo/format"
+)
+
+// RepositoryFixturesDirEnvVar names the environment variable pointing at a directory where repository
+// fixtures are cached between test runs. When not set, fixtures are built once per test binary run.
+const RepositoryFixturesDirEnvVar = "KOPIA_TEST_FIXTURES_DIR"
+
+const (
+	fixtureRepoSubdir   = "repo"
+	fixtureSourceSubdir = "source"
+	fixtureDirPerm      = 0o700
+	fixtureFilePerm     = 0o600
+)
+
+// RepositoryFixture describes a pre-built repository, which is created once and cloned by tests that need it
+// instead of running 'repo create' and taking initial snapshots on their own.
+type RepositoryFixture struct {
+	// Name identifies the fixture, it is part of the cache key.
+	Name string
+
+	// Version must be changed whenever CreateSource or Populate change in a way that affects the fixture.
+	Version int
+
+	// CreateSource creates deterministic source data in the provided empty directory.
+	CreateSource func(t *testing.T, dir string)
+
+	// Populate adds source data to the repository, using a CLITest connected to the newly-created
+	// fixture repository.
+	Populate func(t *testing.T, e *CLITest, sourceDir string)
+
+	mu sync.Mutex
+	// +checklocks:mu
+	instances map[string]*repositoryFixtureInstance
+}
+
+type repositoryFixtureInstance struct {
+	once  sync.Once
+	dir   string
+	built bool
+}
+
+// StandardRepositoryFixture is a repository with a single snapshot of a small deterministic directory tree.
+//
+//nolint:gochecknoglobals
+var StandardRepositoryFixture = &RepositoryFixture{
+	Name:         "standard",
+	Version:      1,
+	CreateSource: createStandardDataset,
+	Populate: func(t *testing.T, e *CLITest, sourceDir string) {
+		t.Helper()
+
+		e.RunAndExpectSuccess(t, "snapshot", "create", sourceDir)
+	},
+}
+
+//nolint:gochecknoglobals
+var (
+	repositoryFixturesDirOnce sync.Once
+	repositoryFixturesDir     string
+	repositoryFixturesTempDir bool
+)
+
+// CleanupRepositoryFixtures removes fixtures built by the current test binary run, unless they
+// are cached in the directory pointed at by KOPIA_TEST_FIXTURES_DIR. Intended to be passed to testutil.MyTestMain.
+func CleanupRepositoryFixtures() {
+	if repositoryFixturesTempDir {
+		os.RemoveAll(repositoryFixturesDir) //nolint:errcheck
+	}
+}
+
+func getRepositoryFixturesDir(t *testing.T) string {
+	t.Helper()
+
+	repositoryFixturesDirOnce.Do(func() {
+		if d := os.Getenv(RepositoryFixturesDirEnvVar); d != "" {
+			repositoryFixturesDir = d
+			return
+		}
+
+		d, err := os.MkdirTemp("", "kopia-fixtures")
+		if err == nil {
+			repositoryFixturesDir = d
+			repositoryFixturesTempDir = true
+		}
+	})
+
+	if repositoryFixturesDir == "" {
+		t.Fatal("unable to create repository fixtures directory")
+	}
+
+	return repositoryFixturesDir
+}
+
+// cacheKey returns the name of the directory holding the fixture built with the provided
+// repository creation flags by the current format version.
+func (f *RepositoryFixture) cacheKey(repoCreateFlags []string) string {
+	h := sha256.New()
+
+	fmt.Fprintf(h, "%v\n%v\n%v\n", f.Version, format.MaxFormatVersion, strings.Join(repoCreateFlags, "\n"))
+
+	//nolint:mnd
+	return fmt.Sprintf("%v-v%v-%v", f.Name, f.Version, hex.EncodeToString(h.Sum(nil))[0:16])
+}
+
+func (f *RepositoryFixture) instance(key string) *repositoryFixtureInstance {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if f.instances == nil {
+		f.instances = map[string]*repositoryFixtureInstance{}
+	}
+
+	inst := f.instances[key]
+	if inst == nil {
+		inst = &repositoryFixtureInstance{}
+		f.instances[key] = inst
+	}
+
+	return inst
+}
+
+// get returns the directory containing the fixture compatible with the provided environment, building it if needed.
+func (f *RepositoryFixture) get(t *testing.T, e *CLITest) string {
+	t.Helper()
+
+	key := f.cacheKey(e.DefaultRepositoryCreateFlags)
+	inst := f.instance(key)
+
+	inst.once.Do(func() {
+		dir := filepath.Join(getRepositoryFixturesDir(t), key)
+
+		if _, err := os.Stat(filepath.Join(dir, fixtureRepoSubdir)); err != nil {
+			f.build(t, e, dir)
+		} else {
+			t.Logf("using cached repository fixture %v", dir)
+		}
+
+		inst.dir = dir
+		inst.built = true
+	})
+
+	if !inst.built {
+		t.Fatalf("repository fixture %v could not be built", key)
+	}
+
+	return inst.dir
+}
+
+// build creates the fixture source directory and repository in temporary directories and atomically moves
+// them to their final locations, so that fixtures concurrently built by other test binaries are never observed half-done.
+// The source directory is created first, because snapshots in the fixture refer to its final path.
+func (f *RepositoryFixture) build(t *testing.T, e *CLITest, dir string) {
+	t.Helper()
+
+	t.Logf("building repository fixture %v", dir)
+
+	require.NoError(t, os.MkdirAll(dir, fixtureDirPerm))
+
+	sourceDir := filepath.Join(dir, fixtureSourceSubdir)
+
+	createAtomically(t, sourceDir, func(tmpDir string) {
+		if f.CreateSource != nil {
+			f.CreateSource(t, tmpDir)
+		}
+	})
+
+	createAtomically(t, filepath.Join(dir, fixtureRepoSubdir), func(tmpDir string) {
+		be := NewCLITest(t, nil, e.Runner)
+		be.DefaultRepositoryCreateFlags = e.DefaultRepositoryCreateFlags
+		be.RepoDir = tmpDir
+
+		be.RunAndExpectSuccess(t, "repo", "create", "filesystem", "--path", be.RepoDir)
+
+		if f.Populate != nil {
+			f.Populate(t, be, sourceDir)
+		}
+
+		be.RunAndExpectSuccess(t, "repo", "disconnect")
+	})
+}
+
+// createAtomically invokes the provided function to populate a temporary directory, which is then renamed to dir,
+// unless dir has been created in the meantime.
+func createAtomically(t *testing.T, dir string, populate func(tmpDir string)) {
+	t.Helper()
+
+	if _, err := os.Stat(dir); err == nil {
+		return
+	}
+
+	tmpDir, err := os.MkdirTemp(filepath.Dir(dir), filepath.Base(dir)+".tmp")
+	require.NoError(t, err)
+
+	defer os.RemoveAll(tmpDir) //nolint:errcheck
+
+	populate(tmpDir)
+
+	if err := os.Rename(tmpDir, dir); err != nil {
+		if _, err2 := os.Stat(dir); err2 != nil {
+			require.NoError(t, err)
+		}
+
+		// another process has created the same directory in the meantime.
+		t.Logf("%v was concurrently created elsewhere", dir)
+	}
+}
+
+// UseRepositoryFixture makes the test repository a private copy of the provided fixture, building the fixture
+// first if needed, connects to it and returns the directory whose snapshots the fixture contains.
+// Tests must not modify the returned directory.
+func (e *CLITest) UseRepositoryFixture(t *testing.T, f *RepositoryFixture) (sourceDir string) {
+	t.Helper()
+
+	dir := f.get(t, e)
+
+	require.NoError(t, copyDirectory(filepath.Join(dir, fixtureRepoSubdir), e.RepoDir))
+
+	e.Environment["KOPIA_PASSWORD"] = TestRepoPassword
+	e.RunAndExpectSuccess(t, "repo", "connect", "filesystem", "--path", e.RepoDir)
+
+	return filepath.Join(dir, fixtureSourceSubdir)
+}
+
+func createStandardDataset(t *testing.T, dir string) {
+	t.Helper()
+
+	//nolint:gosec
+	rnd := rand.New(rand.NewSource(1))
+
+	for i := range 3 {
+		subdir := filepath.Join(dir, fmt.Sprintf("dir%v", i))
+		require.NoError(t, os.MkdirAll(subdir, fixtureDirPerm))
+
+		for j := range 10 {
+			data := make([]byte, rnd.Intn(100000))
+			rnd.Read(data)
+
+			require.NoError(t, os.WriteFile(filepath.Join(subdir, fmt.Sprintf("file%v.bin", j)), data, fixtureFilePerm))
+		}
+	}
+
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "README.txt"), []byte("standard repository fixture\n"), fixtureFilePerm))
+}
+
+func copyDirectory(src, dst string) error {
+	//nolint:wrapcheck
+	return filepath.WalkDir(src, func(path string, d os.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+
+		rel, err := filepath.Rel(src, path)
+		if err != nil {
+			return err
+		}
+
+		target := filepath.Join(dst, rel)
+
+		if d.IsDir() {
+			return os.MkdirAll(target, fixtureDirPerm)
+		}
+
+		return copyFile(path, target)
+	})
+}
+
+func copyFile(src, dst string) error {
+	s, err := os.Open(src) //nolint:gosec
+	if err != nil {
+		return errors.Wrap(err, "unable to open source file")
+	}
+	defer s.Close() //nolint:errcheck
+
+	d, err := os.OpenFile(dst, os.O_CREATE|os.O_EXCL|os.O_WRONLY, fixtureFilePerm) //nolint:gosec
+	if err != nil {
+		return errors.Wrap(err, "unable to create target file")
+	}
+
+	if _, err := io.Copy(d, s); err != nil {
+		d.Close() //nolint:errcheck
+		return errors.Wrap(err, "unable to copy file")
+	}
+
+	return errors.Wrap(d.Close(), "unable to close target file")
+}
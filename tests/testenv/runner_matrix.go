@@ -0,0 +1,149 @@
+package testenv
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/kopia/kopia/cli"
+	"github.com/kopia/kopia/internal/ospath"
+)
+
+// RunWithAllRunners runs the provided test as subtests executing kopia commands in-process and using
+// the kopia executable (when KOPIA_EXE is set). When both subtests succeed, outputs and exit codes of
+// the commands executed by each of them are compared, to detect divergences between execution modes.
+//
+// Absolute paths passed as command arguments, timestamps and identifiers are normalized before comparison,
+// additional normalizers can be provided to replace values expected to be different between runs.
+func RunWithAllRunners(t *testing.T, test func(t *testing.T, runner CLIRunner), normalizers ...GoldenNormalizer) {
+	t.Helper()
+
+	inProc := &recordingRunner{}
+	exe := &recordingRunner{}
+
+	t.Cleanup(func() {
+		if inProc.completed && exe.completed {
+			compareTranscripts(t, inProc.transcript(normalizers), exe.transcript(normalizers))
+		}
+	})
+
+	t.Run("InProc", func(t *testing.T) {
+		inProc.CLIRunner = newInProcRunner()
+
+		test(t, inProc)
+
+		t.Cleanup(func() { inProc.completed = !t.Failed() && !t.Skipped() })
+	})
+
+	t.Run("Exe", func(t *testing.T) {
+		exe.CLIRunner = NewExeRunner(t)
+
+		test(t, exe)
+
+		t.Cleanup(func() { exe.completed = !t.Failed() && !t.Skipped() })
+	})
+}
+
+// recordingRunner is a CLIRunner which records commands executed by the wrapped runner, along with their
+// output and exit codes.
+type recordingRunner struct {
+	CLIRunner
+
+	mu sync.Mutex
+	// +checklocks:mu
+	commands []*recordedCommand
+
+	completed bool
+}
+
+type recordedCommand struct {
+	args     []string
+	stdout   bytes.Buffer
+	exitCode int
+}
+
+// Start implements CLIRunner.
+func (r *recordingRunner) Start(t *testing.T, args []string, env map[string]string) (stdout, stderr io.Reader, wait func() error, interrupt func(os.Signal)) {
+	t.Helper()
+
+	rc := &recordedCommand{args: args}
+
+	r.mu.Lock()
+	r.commands = append(r.commands, rc)
+	r.mu.Unlock()
+
+	innerStdout, innerStderr, innerWait, innerInterrupt := r.CLIRunner.Start(t, args, env)
+
+	return io.TeeReader(innerStdout, &rc.stdout), innerStderr, func() error {
+		err := innerWait()
+
+		r.mu.Lock()
+		rc.exitCode = cli.ExitCode(err)
+		r.mu.Unlock()
+
+		return err
+	}, innerInterrupt
+}
+
+// transcript returns normalized descriptions of executed commands, their exit codes and output.
+func (r *recordingRunner) transcript(normalizers []GoldenNormalizer) []string {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	var result []string
+
+	for _, rc := range r.commands {
+		var argNormalizers []GoldenNormalizer
+
+		for i, a := range rc.args {
+			// handle --flag=/some/path as well as plain paths.
+			if p := strings.Index(a, "="); p >= 0 && strings.HasPrefix(a, "-") {
+				a = a[p+1:]
+			}
+
+			if ospath.IsAbs(a) {
+				argNormalizers = append(argNormalizers, ReplaceLiteral(a, fmt.Sprintf("<ARG%v>", i)))
+			}
+		}
+
+		all := append(append([]GoldenNormalizer(nil), argNormalizers...), normalizers...)
+
+		result = append(result, NormalizeOutput([]string{
+			fmt.Sprintf("$ kopia %v", strings.Join(rc.args, " ")),
+			fmt.Sprintf("exit code: %v", rc.exitCode),
+		}, all...)...)
+
+		if out := strings.TrimRight(rc.stdout.String(), "\n"); out != "" {
+			result = append(result, NormalizeOutput(strings.Split(out, "\n"), all...)...)
+		}
+	}
+
+	return result
+}
+
+func compareTranscripts(t *testing.T, inProc, exe []string) {
+	t.Helper()
+
+	for i := 0; i < len(inProc) || i < len(exe); i++ {
+		var a, b string
+
+		if i < len(inProc) {
+			a = inProc[i]
+		}
+
+		if i < len(exe) {
+			b = exe[i]
+		}
+
+		if a != b {
+			t.Errorf("in-process and executable runs diverge at line %v:\n  in-process: %q\n  executable: %q\n\nin-process:\n%v\n\nexecutable:\n%v",
+				i, a, b, strings.Join(inProc, "\n"), strings.Join(exe, "\n"))
+
+			return
+		}
+	}
+}
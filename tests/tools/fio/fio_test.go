@@ -6,6 +6,8 @@
 	"testing"
 
 	"github.com/stretchr/testify/require"
+
+	"github.com/kopia/kopia/internal/testutil"
 )
 
 func TestFIORun(t *testing.T) {
@@ -95,11 +97,7 @@ func TestFIODockerRunner(t *testing.T) {
 	}
 
 	// Unset FIO_EXE for duration of test
-	prevExeEnv := os.Getenv(FioExeEnvKey)
-	defer os.Setenv(FioExeEnvKey, prevExeEnv)
-
-	err := os.Unsetenv(FioExeEnvKey)
-	require.NoError(t, err)
+	testutil.EnvScope(t, map[string]string{FioExeEnvKey: testutil.EnvUnset})
 
 	r, err := NewRunner()
 	require.NoError(t, err)